@@ -0,0 +1,30 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// ChainHash вычисляет хеш транзакции: SHA-256 (hex) от хеша предыдущей транзакции кошелька
+// и канонических полей текущей, разделённых переводом строки:
+//
+//	prevHash \n valletId \n seq \n operationType \n amount \n balanceAfter
+//
+// Суммы записываются с двумя знаками после точки, как их хранит NUMERIC(18, 2).
+// У первой транзакции кошелька prevHash - пустая строка.
+// Та же формула используется в миграции schema/000004_hash_chain.up.sql.
+func ChainHash(prevHash string, WT WalletTransactions) string {
+	canonical := strings.Join([]string{
+		prevHash,
+		WT.ValletId.UUID.String(),
+		strconv.FormatInt(WT.Seq, 10),
+		WT.OperationType,
+		strconv.FormatFloat(WT.Amount, 'f', 2, 64),
+		strconv.FormatFloat(WT.BalanceAfter, 'f', 2, 64),
+	}, "\n")
+
+	sum := sha256.Sum256([]byte(canonical))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,17 +2,31 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/fee"
+	"github.com/KatenkaKet/wallet/pkg/grpchandler"
 	"github.com/KatenkaKet/wallet/pkg/handler"
+	"github.com/KatenkaKet/wallet/pkg/interest"
+	"github.com/KatenkaKet/wallet/pkg/publisher"
+	"github.com/KatenkaKet/wallet/pkg/receipt"
 	"github.com/KatenkaKet/wallet/pkg/repository"
 	"github.com/KatenkaKet/wallet/pkg/service"
+	"github.com/KatenkaKet/wallet/pkg/storage"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
+	"github.com/nats-io/nats.go"
 	"github.com/spf13/viper"
 )
 
@@ -25,27 +39,171 @@ import (
 // @description API для управления кошельком (просмотр баланса, пополнение и снятие). В данном проекте была заполнена теблица wallets тестовами данными. Вот UUID кошелька, который точно есть: 11111111-1111-1111-1111-111111111111
 // @host localhost:8080
 // @BasePath /api/v1
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
+// @description Bearer <ADMIN_TOKEN>
 func main() {
 	if err := initConfig(); err != nil {
 		log.Fatal("error initializing config: ", err.Error())
 	}
 
-	db, err := repository.NewPostgresDB(repository.Config{
-		Host:     viper.GetString("DB_HOST"),
-		Port:     viper.GetString("DB_PORT"),
-		Username: viper.GetString("DB_USER"),
-		Password: viper.GetString("DB_PASSWORD"),
-		DBName:   viper.GetString("DB_NAME"),
-		SSLMode:  viper.GetString("DB_SSLMODE"),
-	})
+	var (
+		repos *repository.Repository
+		db    *sqlx.DB
+	)
+
+	switch viper.GetString("DB_DRIVER") {
+	case "memory":
+		repos = repository.NewMemoryRepository(newDemoMemory())
+		log.Println("Using in-memory storage")
+	default:
+		var err error
+		dbCfg := repository.Config{
+			Host:     viper.GetString("DB_HOST"),
+			Port:     viper.GetString("DB_PORT"),
+			Username: viper.GetString("DB_USER"),
+			Password: viper.GetString("DB_PASSWORD"),
+			DBName:   viper.GetString("DB_NAME"),
+			SSLMode:  viper.GetString("DB_SSLMODE"),
+		}
+		db, err = repository.NewPostgresDB(dbCfg)
+
+		if err != nil {
+			log.Fatal("error initializing database: ", err.Error())
+		}
+
+		isolation, err := repository.ParseIsolationLevel(viper.GetString("TX_ISOLATION"))
+		if err != nil {
+			log.Fatal("error parsing TX_ISOLATION: ", err.Error())
+		}
+
+		repos = repository.NewRepository(db, dbCfg.DSN(), repository.TxOptions{
+			Isolation:  isolation,
+			MaxRetries: viper.GetInt("TX_MAX_RETRIES"),
+		})
+	}
 
+	hotWallets, err := parseWalletIDs(viper.GetString("HOT_WALLETS"))
 	if err != nil {
-		log.Fatal("error initializing database: ", err.Error())
+		log.Fatal("error parsing HOT_WALLETS: ", err.Error())
+	}
+
+	receiptKey, retiredKeys, err := parseReceiptKeys(viper.GetString("RECEIPT_SIGNING_KEY"), viper.GetString("RECEIPT_RETIRED_KEYS"))
+	if err != nil {
+		log.Fatal("error parsing receipt keys: ", err.Error())
+	}
+	if receiptKey == nil {
+		log.Println("RECEIPT_SIGNING_KEY is not set, transaction receipts are disabled")
+	}
+
+	publishers, closePublishers, err := newEventPublishers(viper.GetString("EVENT_PUBLISHERS"))
+	if err != nil {
+		log.Fatal("error initializing event publishers: ", err.Error())
+	}
+	defer closePublishers()
+
+	var (
+		fees      *fee.Schedule
+		feeWallet uuid.UUID
+	)
+	if path := viper.GetString("FEE_SCHEDULE_FILE"); path != "" {
+		if fees, err = fee.Load(path); err != nil {
+			log.Fatal("error loading fee schedule: ", err.Error())
+		}
+		if err := feeWallet.Scan(strings.TrimSpace(viper.GetString("FEE_WALLET"))); err != nil {
+			log.Fatal("error parsing FEE_WALLET: ", err.Error())
+		}
+	}
+
+	var products *interest.Products
+	if path := viper.GetString("INTEREST_PRODUCTS_FILE"); path != "" {
+		if products, err = interest.Load(path); err != nil {
+			log.Fatal("error loading interest products: ", err.Error())
+		}
+	}
+
+	var statementStorage storage.Storage
+	if dir := viper.GetString("STATEMENTS_DIR"); dir != "" {
+		disk, err := storage.NewDisk(dir)
+		if err != nil {
+			log.Fatal("error initializing statement storage: ", err.Error())
+		}
+		statementStorage = disk
+	}
+
+	service := service.NewService(repos, service.Config{
+		HotWallets:         hotWallets,
+		HotWalletMaxBatch:  viper.GetInt("HOT_WALLET_MAX_BATCH"),
+		CheckpointKey:      []byte(viper.GetString("CHECKPOINT_KEY")),
+		ReceiptKeyID:       viper.GetString("RECEIPT_KEY_ID"),
+		ReceiptKey:         receiptKey,
+		ReceiptRetiredKeys: retiredKeys,
+		Webhooks: service.WebhookConfig{
+			MaxAttempts: viper.GetInt("WEBHOOK_MAX_ATTEMPTS"),
+			BaseDelay:   viper.GetDuration("WEBHOOK_BASE_DELAY"),
+			MaxDelay:    viper.GetDuration("WEBHOOK_MAX_DELAY"),
+			Timeout:     viper.GetDuration("WEBHOOK_TIMEOUT"),
+		},
+		EventPublishers:  publishers,
+		OutboxBatchSize:  viper.GetInt("OUTBOX_BATCH_SIZE"),
+		StatementStorage: statementStorage,
+		Currency:         viper.GetString("CURRENCY"),
+		Fees:             fees,
+		FeeWallet:        feeWallet,
+		InterestProducts: products,
+	})
+	hdl := handler.NewHandler(service, handler.Config{
+		AdminToken:          viper.GetString("ADMIN_TOKEN"),
+		StreamHeartbeat:     viper.GetDuration("STREAM_HEARTBEAT"),
+		BatchMaxItems:       viper.GetInt("BATCH_MAX_ITEMS"),
+		BatchMaxStreamItems: viper.GetInt("BATCH_MAX_STREAM_ITEMS"),
+		ImportMaxRows:       viper.GetInt("IMPORT_MAX_ROWS"),
+		Currency:            viper.GetString("CURRENCY"),
+	})
+
+	workers, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	if viper.GetString("CHECKPOINT_KEY") != "" {
+		interval := viper.GetDuration("CHECKPOINT_INTERVAL")
+		if interval <= 0 {
+			log.Fatal("CHECKPOINT_INTERVAL must be positive")
+		}
+		go runCheckpoints(workers, service.Audit, interval)
+	} else {
+		log.Println("CHECKPOINT_KEY is not set, hash chain checkpoints are disabled")
 	}
 
-	repos := repository.NewRepository(db)
-	service := service.NewService(repos)
-	hdl := handler.NewHandler(service)
+	if statementStorage != nil {
+		interval := viper.GetDuration("STATEMENT_INTERVAL")
+		if interval <= 0 {
+			log.Fatal("STATEMENT_INTERVAL must be positive")
+		}
+		go runStatements(workers, service.Statement, interval)
+	} else {
+		log.Println("STATEMENTS_DIR is not set, monthly statements are disabled")
+	}
+
+	if !products.Empty() {
+		interval := viper.GetDuration("INTEREST_INTERVAL")
+		if interval <= 0 {
+			log.Fatal("INTEREST_INTERVAL must be positive")
+		}
+		go runInterest(workers, service.Interest, interval)
+	} else {
+		log.Println("INTEREST_PRODUCTS_FILE is not set, interest accrual is disabled")
+	}
+
+	go runOutboxRelay(workers, service.Outbox, viper.GetDuration("OUTBOX_POLL_INTERVAL"), viper.GetDuration("OUTBOX_RETENTION"))
+	go runWebhookDispatcher(workers, service.Webhook, viper.GetDuration("WEBHOOK_POLL_INTERVAL"))
+	go runScheduled(workers, service.Scheduled, viper.GetDuration("SCHEDULED_POLL_INTERVAL"))
+	go runRecurring(workers, service.Recurring, viper.GetDuration("RECURRING_POLL_INTERVAL"))
+	go func() {
+		if err := service.Stream.Run(workers); err != nil && workers.Err() == nil {
+			log.Println("error listening for wallet changes: ", err.Error())
+		}
+	}()
 
 	//fmt.Println(viper.GetString("PORT"))
 
@@ -61,17 +219,39 @@ func main() {
 
 	log.Println("Listening on " + viper.GetString("PORT"))
 
+	var grpcSrv *wallet.GRPCServer
+	if port := viper.GetString("GRPC_PORT"); port != "" {
+		grpcSrv = new(wallet.GRPCServer)
+		go func() {
+			if err := grpcSrv.Run(port, grpchandler.NewHandler(service).Register); err != nil {
+				log.Fatal("error occurred while running grpc server: ", err.Error())
+			}
+		}()
+		log.Println("gRPC listening on " + port)
+	}
+
 	quet := make(chan os.Signal, 1)
 	signal.Notify(quet, syscall.SIGINT, syscall.SIGTERM)
 	<-quet
 
 	log.Println("Shutting down...")
+	stopWorkers()
 	if err := srv.Shutdown(context.Background()); err != nil {
 		log.Fatal("error occured while shutting down http server: ", err.Error())
 	}
+	if grpcSrv != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := grpcSrv.Shutdown(ctx); err != nil {
+			log.Println("grpc server stopped forcibly: ", err.Error())
+		}
+		cancel()
+	}
+	service.Close()
 
-	if err := db.Close(); err != nil {
-		log.Fatal("error occured while closing database: ", err.Error())
+	if db != nil {
+		if err := db.Close(); err != nil {
+			log.Fatal("error occured while closing database: ", err.Error())
+		}
 	}
 
 }
@@ -82,3 +262,284 @@ func initConfig() error {
 	viper.SetConfigType("env")
 	return viper.ReadInConfig()
 }
+
+// parseWalletIDs разбирает список UUID кошельков через запятую.
+func parseWalletIDs(list string) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for _, id := range strings.Split(list, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+
+		var uid uuid.UUID
+		if err := uid.Scan(id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, uid)
+	}
+	return ids, nil
+}
+
+// parseReceiptKeys разбирает ключ подписи квитанций (может быть пустым) и публичные ключи прошлых ротаций.
+func parseReceiptKeys(signing, retired string) (ed25519.PrivateKey, []receipt.PublicKey, error) {
+	var key ed25519.PrivateKey
+	if signing != "" {
+		var err error
+		if key, err = receipt.ParsePrivateKey(signing); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	keys, err := receipt.ParsePublicKeys(retired)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, keys, nil
+}
+
+// runCheckpoints подписывает головы цепочек хешей каждые interval, пока не отменён ctx.
+func runCheckpoints(ctx context.Context, audit service.Audit, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n, err := audit.CreateCheckpoints(ctx)
+		if err != nil {
+			log.Println("error creating checkpoints: ", err.Error())
+			continue
+		}
+		if n > 0 {
+			log.Printf("Created %d checkpoints", n)
+		}
+	}
+}
+
+// runStatements формирует выписки за прошедший месяц сразу при запуске и затем каждые interval, пока не отменён ctx.
+// Уже сформированные выписки пропускаются, поэтому частые запуски дёшевы и выписки появляются вскоре после начала месяца.
+func runStatements(ctx context.Context, statements service.Statement, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := statements.GenerateMonthly(ctx, time.Now())
+		if err != nil && ctx.Err() == nil {
+			log.Println("error generating statements: ", err.Error())
+		}
+		if n > 0 {
+			log.Printf("Generated %d statements", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runInterest начисляет проценты за прошедшие дни и капитализирует их за прошедший месяц сразу при запуске
+// и затем каждые interval, пока не отменён ctx. Уже начисленные дни пропускаются.
+func runInterest(ctx context.Context, interest service.Interest, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := interest.RunInterest(ctx, time.Now())
+		if err != nil && ctx.Err() == nil {
+			log.Println("error accruing interest: ", err.Error())
+		}
+		if n > 0 {
+			log.Printf("Accrued interest for %d days", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// newEventPublishers создаёт получателей событий outbox из списка через запятую: log, http, nats, kafka.
+// Вебхуки получают события всегда. Возвращённая функция закрывает соединения с брокерами.
+func newEventPublishers(list string) ([]service.EventPublisher, func(), error) {
+	var (
+		publishers []service.EventPublisher
+		closers    []func()
+	)
+	closeAll := func() {
+		for _, c := range closers {
+			c()
+		}
+	}
+	client := &http.Client{Timeout: viper.GetDuration("EVENT_PUBLISH_TIMEOUT")}
+
+	for _, name := range strings.Split(list, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "":
+		case "log":
+			publishers = append(publishers, publisher.NewLog(nil))
+		case "http":
+			if viper.GetString("EVENT_HTTP_URL") == "" {
+				closeAll()
+				return nil, nil, errors.New("EVENT_HTTP_URL is not set")
+			}
+			publishers = append(publishers, publisher.NewHTTP(viper.GetString("EVENT_HTTP_URL"), client))
+		case "nats":
+			conn, err := nats.Connect(viper.GetString("NATS_URL"), nats.Name("wallet"), nats.MaxReconnects(-1))
+			if err != nil {
+				closeAll()
+				return nil, nil, fmt.Errorf("failed to connect to nats: %w", err)
+			}
+			closers = append(closers, func() { conn.Drain() })
+			publishers = append(publishers, publisher.NewNATS(conn, viper.GetString("NATS_SUBJECT")))
+		case "kafka":
+			if viper.GetString("KAFKA_REST_URL") == "" {
+				closeAll()
+				return nil, nil, errors.New("KAFKA_REST_URL is not set")
+			}
+			publishers = append(publishers, publisher.NewKafkaREST(viper.GetString("KAFKA_REST_URL"), viper.GetString("KAFKA_TOPIC"), client))
+		default:
+			closeAll()
+			return nil, nil, fmt.Errorf("unknown event publisher %q", name)
+		}
+	}
+	return publishers, closeAll, nil
+}
+
+// runOutboxRelay публикует события из outbox, пока не отменён ctx, и раз в час удаляет опубликованные старше retention.
+// Пока в outbox есть события, проходы идут без пауз.
+func runOutboxRelay(ctx context.Context, outbox service.Outbox, interval, retention time.Duration) {
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+	var lastPurge time.Time
+
+	for {
+		if retention > 0 && time.Since(lastPurge) > time.Hour {
+			if n, err := outbox.Purge(ctx, time.Now().Add(-retention)); err != nil {
+				log.Println("error purging outbox: ", err.Error())
+			} else if n > 0 {
+				log.Printf("Purged %d published outbox events", n)
+			}
+			lastPurge = time.Now()
+		}
+
+		relayCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		n, err := outbox.Relay(relayCtx)
+		cancel()
+		if err != nil {
+			log.Println("error relaying outbox: ", err.Error())
+		}
+		if n > 0 && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// runWebhookDispatcher отправляет вебхуки, пока не отменён ctx. Пока очередь не пуста, проходы идут без пауз.
+func runWebhookDispatcher(ctx context.Context, webhooks service.Webhook, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	for {
+		n, err := webhooks.DispatchDue(ctx)
+		if err != nil {
+			log.Println("error dispatching webhooks: ", err.Error())
+		}
+		if n > 0 && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// runScheduled выполняет отложенные операции, срок которых наступил, пока не отменён ctx.
+// Пока есть готовые к выполнению операции, проходы идут без пауз.
+func runScheduled(ctx context.Context, scheduled service.Scheduled, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	for {
+		n, err := scheduled.ExecuteDue(ctx)
+		if err != nil {
+			log.Println("error executing scheduled transactions: ", err.Error())
+		}
+		if n > 0 && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// runRecurring выполняет регулярные переводы, срок которых наступил, пока не отменён ctx.
+// Пока есть правила к выполнению (в том числе пропущенные сроки при catchUp=all), проходы идут без пауз.
+func runRecurring(ctx context.Context, recurring service.Recurring, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	for {
+		n, err := recurring.ExecuteDueRecurring(ctx)
+		if err != nil {
+			log.Println("error executing recurring transfers: ", err.Error())
+		}
+		if n > 0 && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// newDemoMemory заполняет хранилище в памяти теми же тестовыми кошельками, что и миграции в schema.
+func newDemoMemory() *repository.WalletMemory {
+	mem := repository.NewWalletMemory()
+
+	demo := map[string]float64{
+		"11111111-1111-1111-1111-111111111111": 1000.00,
+		"22222222-2222-2222-2222-222222222222": 500.50,
+		"33333333-3333-3333-3333-333333333333": 0.00,
+		"44444444-4444-4444-4444-444444444444": 250.75,
+		// Кошелёк комиссий из schema/000013_fees.up.sql
+		"00000000-0000-0000-0000-000000000fee": 0.00,
+	}
+	for id, balance := range demo {
+		var uid uuid.UUID
+		if err := uid.Scan(id); err != nil {
+			log.Fatal("invalid demo wallet id: ", err.Error())
+		}
+		mem.AddWallet(uid, balance)
+	}
+
+	return mem
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Генератор нагрузки для API кошелька.
+// Пример: go run ./cmd/loadgen -url http://localhost:8080 -rate 1000 -duration 30s -hot 0.8
+//
+// Кошельки должны существовать заранее (по умолчанию - тестовые кошельки из schema/000001_wallet.up.sql).
+// Проверка согласованности итоговых балансов корректна, только если кроме генератора в API никто не пишет.
+
+var demoWallets = []string{
+	"11111111-1111-1111-1111-111111111111",
+	"22222222-2222-2222-2222-222222222222",
+	"33333333-3333-3333-3333-333333333333",
+	"44444444-4444-4444-4444-444444444444",
+}
+
+type config struct {
+	baseURL       string
+	rate          int
+	duration      time.Duration
+	workers       int
+	readRatio     float64
+	withdrawRatio float64
+	hotRatio      float64
+	amount        float64
+	timeout       time.Duration
+	wallets       []string
+}
+
+func main() {
+	cfg, err := parseFlags()
+	if err != nil {
+		log.Fatal("invalid flags: ", err.Error())
+	}
+
+	client := &http.Client{
+		Timeout:   cfg.timeout,
+		Transport: &http.Transport{MaxIdleConnsPerHost: cfg.workers},
+	}
+
+	before, err := readBalances(client, cfg)
+	if err != nil {
+		log.Fatal("error reading initial balances: ", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.duration)
+	defer cancel()
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("Sending %d req/s to %s for %s across %d wallets", cfg.rate, cfg.baseURL, cfg.duration, len(cfg.wallets))
+	rep := run(ctx, client, cfg)
+
+	after, err := readBalances(client, cfg)
+	if err != nil {
+		log.Fatal("error reading final balances: ", err.Error())
+	}
+
+	rep.print(os.Stdout)
+	if !rep.checkConsistency(os.Stdout, cfg.wallets, before, after) {
+		os.Exit(1)
+	}
+}
+
+func parseFlags() (config, error) {
+	var (
+		cfg         config
+		wallets     string
+		walletsFile string
+		walletCount int
+	)
+
+	flag.StringVar(&cfg.baseURL, "url", "http://localhost:8080", "base URL of the wallet API")
+	flag.IntVar(&cfg.rate, "rate", 100, "target request rate, req/s")
+	flag.DurationVar(&cfg.duration, "duration", 10*time.Second, "test duration")
+	flag.IntVar(&cfg.workers, "workers", 64, "max concurrent in-flight requests")
+	flag.Float64Var(&cfg.readRatio, "read", 0.2, "share of GET /wallets/:id requests, 0..1")
+	flag.Float64Var(&cfg.withdrawRatio, "withdraw", 0.5, "share of WITHDRAW among write requests, 0..1")
+	flag.Float64Var(&cfg.hotRatio, "hot", 0, "share of requests sent to the first (hot) wallet, 0..1; the rest is spread uniformly")
+	flag.Float64Var(&cfg.amount, "amount", 1, "amount of every deposit/withdrawal")
+	flag.DurationVar(&cfg.timeout, "timeout", 5*time.Second, "per-request timeout")
+	flag.StringVar(&wallets, "wallets", strings.Join(demoWallets, ","), "comma-separated wallet UUIDs")
+	flag.StringVar(&walletsFile, "wallets-file", "", "file with wallet UUIDs, one per line (overrides -wallets)")
+	flag.IntVar(&walletCount, "n", 0, "use only the first n wallets (0 - all)")
+	flag.Parse()
+
+	if walletsFile != "" {
+		body, err := os.ReadFile(walletsFile)
+		if err != nil {
+			return cfg, err
+		}
+		wallets = strings.ReplaceAll(string(body), "\n", ",")
+	}
+
+	for _, id := range strings.Split(wallets, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			cfg.wallets = append(cfg.wallets, id)
+		}
+	}
+	if walletCount > 0 && walletCount < len(cfg.wallets) {
+		cfg.wallets = cfg.wallets[:walletCount]
+	}
+
+	switch {
+	case len(cfg.wallets) == 0:
+		return cfg, fmt.Errorf("no wallets given")
+	case cfg.rate <= 0 || cfg.workers <= 0:
+		return cfg, fmt.Errorf("rate and workers must be positive")
+	case cfg.amount <= 0:
+		return cfg, fmt.Errorf("amount must be positive")
+	case !isRatio(cfg.readRatio) || !isRatio(cfg.withdrawRatio) || !isRatio(cfg.hotRatio):
+		return cfg, fmt.Errorf("ratios must be within 0..1")
+	}
+
+	cfg.baseURL = strings.TrimRight(cfg.baseURL, "/")
+	return cfg, nil
+}
+
+func isRatio(v float64) bool {
+	return v >= 0 && v <= 1
+}
+
+type request struct {
+	kind   string // GET, DEPOSIT или WITHDRAW
+	wallet string
+}
+
+// run отправляет запросы с постоянной частотой (открытая модель нагрузки): если все воркеры заняты,
+// запрос не ставится в очередь, а учитывается как пропущенный, чтобы не искажать задержки.
+func run(ctx context.Context, client *http.Client, cfg config) *report {
+	rep := newReport()
+	reqs := make(chan request)
+
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range reqs {
+				rep.record(do(client, cfg, r))
+			}
+		}()
+	}
+
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	// Тикер срабатывает не чаще раза в миллисекунду, поэтому на каждом тике досылаем столько запросов,
+	// сколько должно было уйти к текущему моменту при заданной частоте.
+	interval := time.Second / time.Duration(cfg.rate)
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	sent := 0
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case now := <-ticker.C:
+			due := int(now.Sub(start).Seconds() * float64(cfg.rate))
+			for ; sent < due; sent++ {
+				select {
+				case reqs <- nextRequest(rnd, cfg):
+				default:
+					rep.skip()
+				}
+			}
+		}
+	}
+	close(reqs)
+	wg.Wait()
+	rep.elapsed = time.Since(start)
+
+	return rep
+}
+
+func nextRequest(rnd *rand.Rand, cfg config) request {
+	wallet := cfg.wallets[0]
+	if rnd.Float64() >= cfg.hotRatio {
+		wallet = cfg.wallets[rnd.Intn(len(cfg.wallets))]
+	}
+
+	switch {
+	case rnd.Float64() < cfg.readRatio:
+		return request{kind: "GET", wallet: wallet}
+	case rnd.Float64() < cfg.withdrawRatio:
+		return request{kind: "WITHDRAW", wallet: wallet}
+	default:
+		return request{kind: "DEPOSIT", wallet: wallet}
+	}
+}
+
+func do(client *http.Client, cfg config, r request) result {
+	var (
+		resp *http.Response
+		err  error
+	)
+
+	start := time.Now()
+	if r.kind == "GET" {
+		resp, err = client.Get(cfg.baseURL + "/api/v1/wallets/" + r.wallet)
+	} else {
+		payload, _ := json.Marshal(map[string]interface{}{
+			"valletId":      r.wallet,
+			"operationType": r.kind,
+			"amount":        cfg.amount,
+		})
+		resp, err = client.Post(cfg.baseURL+"/api/v1/wallet", "application/json", bytes.NewReader(payload))
+	}
+
+	res := result{req: r, amount: cfg.amount}
+	if err != nil {
+		res.latency = time.Since(start)
+		res.err = err
+		return res
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	res.latency = time.Since(start)
+	res.status = resp.StatusCode
+	return res
+}
+
+// walletBalance - баланс кошелька и его кредитный лимит: баланс может опускаться до -CreditLimit.
+type walletBalance struct {
+	Balance     float64 `json:"balance"`
+	CreditLimit float64 `json:"creditLimit"`
+}
+
+func readBalances(client *http.Client, cfg config) (map[string]walletBalance, error) {
+	balances := make(map[string]walletBalance, len(cfg.wallets))
+	for _, id := range cfg.wallets {
+		if _, ok := balances[id]; ok {
+			continue
+		}
+
+		resp, err := client.Get(cfg.baseURL + "/api/v1/wallets/" + id)
+		if err != nil {
+			return nil, err
+		}
+
+		var body struct {
+			walletBalance
+			Error string `json:"error"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("wallet %s: %w", id, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("wallet %s: status %d: %s", id, resp.StatusCode, body.Error)
+		}
+
+		balances[id] = body.walletBalance
+	}
+	return balances, nil
+}
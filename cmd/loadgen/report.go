@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+type result struct {
+	req     request
+	amount  float64
+	status  int
+	err     error
+	latency time.Duration
+}
+
+// report накапливает результаты запросов; безопасен для использования из нескольких воркеров.
+type report struct {
+	mu        sync.Mutex
+	elapsed   time.Duration
+	skipped   int
+	latencies map[string][]time.Duration
+	statuses  map[string]map[int]int
+	errors    map[string]int
+	// deltas - сумма успешных операций по кошелькам для проверки согласованности.
+	deltas map[string]float64
+}
+
+func newReport() *report {
+	return &report{
+		latencies: make(map[string][]time.Duration),
+		statuses:  make(map[string]map[int]int),
+		errors:    make(map[string]int),
+		deltas:    make(map[string]float64),
+	}
+}
+
+func (r *report) skip() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.skipped++
+}
+
+func (r *report) record(res result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kind := res.req.kind
+	r.latencies[kind] = append(r.latencies[kind], res.latency)
+
+	if res.err != nil {
+		r.errors[res.err.Error()]++
+		return
+	}
+
+	if r.statuses[kind] == nil {
+		r.statuses[kind] = make(map[int]int)
+	}
+	r.statuses[kind][res.status]++
+
+	if res.status != http.StatusOK {
+		return
+	}
+	switch kind {
+	case "DEPOSIT":
+		r.deltas[res.req.wallet] += res.amount
+	case "WITHDRAW":
+		r.deltas[res.req.wallet] -= res.amount
+	}
+}
+
+func (r *report) print(w io.Writer) {
+	total := 0
+	for _, l := range r.latencies {
+		total += len(l)
+	}
+
+	fmt.Fprintf(w, "\nDuration:   %s\n", r.elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "Requests:   %d (skipped, all workers busy: %d)\n", total, r.skipped)
+	fmt.Fprintf(w, "Throughput: %.1f req/s\n\n", float64(total)/r.elapsed.Seconds())
+
+	fmt.Fprintf(w, "%-9s %8s %10s %10s %10s %10s %10s\n", "op", "count", "p50", "p90", "p99", "p99.9", "max")
+	for _, kind := range sortedKeys(r.latencies) {
+		l := r.latencies[kind]
+		sort.Slice(l, func(i, j int) bool { return l[i] < l[j] })
+		fmt.Fprintf(w, "%-9s %8d %10s %10s %10s %10s %10s\n", kind, len(l),
+			percentile(l, 50), percentile(l, 90), percentile(l, 99), percentile(l, 99.9), l[len(l)-1].Round(time.Microsecond))
+	}
+
+	fmt.Fprintln(w, "\nStatus codes:")
+	for _, kind := range sortedKeys(r.statuses) {
+		codes := make([]int, 0, len(r.statuses[kind]))
+		for code := range r.statuses[kind] {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			fmt.Fprintf(w, "  %-9s %d %s: %d\n", kind, code, http.StatusText(code), r.statuses[kind][code])
+		}
+	}
+
+	if len(r.errors) > 0 {
+		fmt.Fprintln(w, "\nTransport errors:")
+		for _, msg := range sortedKeys(r.errors) {
+			fmt.Fprintf(w, "  %s: %d\n", msg, r.errors[msg])
+		}
+	}
+}
+
+// checkConsistency сверяет итоговый баланс каждого кошелька с начальным балансом плюс сумма успешных операций
+// и проверяет, что баланс не ушёл ниже кредитного лимита.
+func (r *report) checkConsistency(w io.Writer, wallets []string, before, after map[string]walletBalance) bool {
+	fmt.Fprintln(w, "\nConsistency check:")
+	if len(r.errors) > 0 {
+		// Запрос, оборвавшийся по таймауту, мог успеть примениться на сервере.
+		fmt.Fprintln(w, "  (transport errors occurred, write outcomes are unknown - mismatches are possible)")
+	}
+
+	ok := true
+	seen := make(map[string]bool, len(wallets))
+	for _, id := range wallets {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		start, actual := before[id].Balance, after[id].Balance
+		expected := start + r.deltas[id]
+		status := "OK"
+		if math.Abs(expected-actual) > 0.005 {
+			status = "MISMATCH"
+			ok = false
+		}
+		if actual < -after[id].CreditLimit {
+			status = "NEGATIVE"
+			ok = false
+		}
+		fmt.Fprintf(w, "  %s start=%.2f expected=%.2f actual=%.2f %s\n", id, start, expected, actual, status)
+	}
+	return ok
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx].Round(time.Microsecond)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	"github.com/KatenkaKet/wallet/pkg/service"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	_ "github.com/lib/pq"
+	"github.com/spf13/viper"
+)
+
+// Проверка цепочки хешей истории кошельков прямо по БД (настройки подключения - из configs/config.env).
+// Запускать из корня проекта:
+// go run ./cmd/verifychain 11111111-1111-1111-1111-111111111111 22222222-2222-2222-2222-222222222222
+// Для каждого кошелька печатается отчёт в JSON; код выхода 1, если хотя бы одна цепочка нарушена.
+func main() {
+	timeout := flag.Duration("timeout", time.Minute, "таймаут проверки одного кошелька")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: verifychain [-timeout 1m] <wallet id>...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	viper.AddConfigPath("configs")
+	viper.SetConfigName("config")
+	viper.SetConfigType("env")
+	if err := viper.ReadInConfig(); err != nil {
+		log.Fatal("error initializing config: ", err.Error())
+	}
+
+	dbCfg := repository.Config{
+		Host:     viper.GetString("DB_HOST"),
+		Port:     viper.GetString("DB_PORT"),
+		Username: viper.GetString("DB_USER"),
+		Password: viper.GetString("DB_PASSWORD"),
+		DBName:   viper.GetString("DB_NAME"),
+		SSLMode:  viper.GetString("DB_SSLMODE"),
+	}
+	db, err := repository.NewPostgresDB(dbCfg)
+	if err != nil {
+		log.Fatal("error initializing database: ", err.Error())
+	}
+	defer db.Close()
+
+	repos := repository.NewRepository(db, dbCfg.DSN(), repository.TxOptions{})
+	audit := service.NewAuditService(repos.Wallet, repos.Checkpoint, []byte(viper.GetString("CHECKPOINT_KEY")))
+
+	enc := json.NewEncoder(os.Stdout)
+	failed := false
+	for _, arg := range flag.Args() {
+		var walletID uuid.UUID
+		if err := walletID.Scan(arg); err != nil {
+			log.Fatalf("invalid wallet id %q: %s", arg, err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		report, err := audit.VerifyChain(ctx, walletID)
+		cancel()
+		if err != nil {
+			log.Printf("wallet %s: %s", arg, err.Error())
+			failed = true
+			continue
+		}
+
+		if err := enc.Encode(report); err != nil {
+			log.Fatal(err)
+		}
+		if !report.Valid {
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
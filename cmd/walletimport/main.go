@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/csvimport"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	"github.com/KatenkaKet/wallet/pkg/service"
+	_ "github.com/lib/pq"
+	"github.com/spf13/viper"
+)
+
+// Массовая корректировка балансов из CSV прямо по БД (настройки подключения - из configs/config.env).
+// Запускать из корня проекта:
+// go run ./cmd/walletimport check adjustments.csv                    - пробный прогон, ничего не записывает
+// go run ./cmd/walletimport apply -out results.csv adjustments.csv   - применение
+// Результаты по строкам пишутся в CSV (в stdout или в -out), итоги по статусам - в stderr.
+// Код выхода 1, если есть строки не со статусом ok (check) или applied (apply).
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd := flag.Arg(0)
+	if cmd != "check" && cmd != "apply" {
+		usage()
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	out := fs.String("out", "", "файл для результатов по строкам (по умолчанию stdout)")
+	maxRows := fs.Int("max-rows", 100000, "максимум строк в файле")
+	timeout := fs.Duration("timeout", 10*time.Minute, "таймаут обработки файла")
+	fs.Parse(flag.Args()[1:])
+	if fs.NArg() != 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	rows, err := csvimport.Read(f, *maxRows)
+	f.Close()
+	if err != nil {
+		log.Fatalf("%s: %s", fs.Arg(0), err.Error())
+	}
+
+	viper.AddConfigPath("configs")
+	viper.SetConfigName("config")
+	viper.SetConfigType("env")
+	if err := viper.ReadInConfig(); err != nil {
+		log.Fatal("error initializing config: ", err.Error())
+	}
+
+	dbCfg := repository.Config{
+		Host:     viper.GetString("DB_HOST"),
+		Port:     viper.GetString("DB_PORT"),
+		Username: viper.GetString("DB_USER"),
+		Password: viper.GetString("DB_PASSWORD"),
+		DBName:   viper.GetString("DB_NAME"),
+		SSLMode:  viper.GetString("DB_SSLMODE"),
+	}
+	db, err := repository.NewPostgresDB(dbCfg)
+	if err != nil {
+		log.Fatal("error initializing database: ", err.Error())
+	}
+	defer db.Close()
+
+	repos := repository.NewRepository(db, dbCfg.DSN(), repository.TxOptions{})
+	imports := service.NewImportService(service.NewWalletService(repos.Wallet, service.Config{}), repos.Wallet)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var report wallet.ImportReport
+	expected := wallet.BatchApplied
+	if cmd == "check" {
+		report, err = imports.Validate(ctx, rows)
+		expected = wallet.ImportOK
+	} else {
+		report, err = imports.Apply(ctx, rows)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		w = file
+	}
+	if err := csvimport.WriteResults(w, report.Results); err != nil {
+		log.Fatal(err)
+	}
+
+	statuses := make([]string, 0, len(report.Counts))
+	for status := range report.Counts {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	fmt.Fprintf(os.Stderr, "%d rows\n", report.Rows)
+	for _, status := range statuses {
+		fmt.Fprintf(os.Stderr, "  %-16s %d\n", status, report.Counts[status])
+	}
+
+	if report.Counts[expected] != report.Rows {
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "usage: walletimport check [-out results.csv] [-max-rows N] [-timeout 10m] <file.csv>")
+	fmt.Fprintln(out, "       walletimport apply [-out results.csv] [-max-rows N] [-timeout 10m] <file.csv>")
+}
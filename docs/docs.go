@@ -15,8 +15,170 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/wallet": {
+        "/admin/imports": {
             "post": {
+                "description": "CSV с колонками wallet_id, operation_type (DEPOSIT/WITHDRAW), amount, reference; заголовок необязателен.\nФайл передаётся полем file (multipart/form-data) или телом запроса (text/csv).\nПо умолчанию выполняется пробный прогон (dry_run=true): ничего не записывается, а для каждой строки\nсообщается статус ok, invalid, unknown_wallet, duplicate или would_overdraft.\nС dry_run=false строки применяются, статусы - applied, duplicate, rejected или invalid.\nreference служит ключом идемпотентности, поэтому повторная загрузка того же файла безопасна.\nformat=csv возвращает результаты по строкам файлом CSV.",
+                "consumes": [
+                    "text/csv",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Массовая корректировка балансов из CSV",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "default": true,
+                        "description": "Только проверка",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "json",
+                        "description": "json или csv",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "file",
+                        "description": "CSV-файл",
+                        "name": "file",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/wallet.ImportReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Файл не разбирается",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Слишком большой файл",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ]
+            }
+        },
+        "/admin/stats": {
+            "get": {
+                "description": "Параметры и ответ - как у /wallets/{id}/stats, но по всем кошелькам.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Статистика операций по всем кошелькам",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Начало периода",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Конец периода",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "day",
+                        "description": "day, week или month",
+                        "name": "interval",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/wallet.Stats"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные параметры",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ]
+            }
+        },
+        "/admin/wallets/{id}/credit-limit": {
+            "put": {
+                "description": "Баланс кошелька может опускаться до -creditLimit; 0 - только предоплата. Лимит нельзя сделать меньше\nтекущей задолженности. Изменение лимита меняет версию кошелька (ETag).",
                 "consumes": [
                     "application/json"
                 ],
@@ -24,23 +186,36 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "wallet"
+                    "admin"
                 ],
-                "summary": "Пополнить или снять деньги с кошелька, а также записать историю выполненных операций",
+                "summary": "Изменить кредитный лимит кошелька",
                 "parameters": [
                     {
-                        "description": "Данные транзакции",
-                        "name": "transaction",
+                        "type": "string",
+                        "description": "ID кошелька",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Кредитный лимит",
+                        "name": "creditLimit",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/wallet.WalletTransactions"
+                            "$ref": "#/definitions/handler.creditLimitRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "status: success",
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/wallet.Wallet"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные данные",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -48,8 +223,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "Ошибка валидации или неверные данные",
+                    "401": {
+                        "description": "Неверный токен",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -57,8 +232,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Ошибка при обновлении баланса",
+                    "404": {
+                        "description": "Кошелёк не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Лимит меньше текущей задолженности",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -66,18 +250,27 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ]
             }
         },
-        "/wallets/{id}": {
-            "get": {
+        "/admin/wallets/{id}/product": {
+            "put": {
+                "description": "Продукт задаёт годовую ставку и соглашение о числе дней (INTEREST_PRODUCTS_FILE). Пустой продукт\nотключает начисление; уже накопленные проценты капитализируются в конце месяца.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "wallet"
+                    "admin"
                 ],
-                "summary": "Получить баланс кошелька по ID",
+                "summary": "Изменить процентный продукт кошелька",
                 "parameters": [
                     {
                         "type": "string",
@@ -85,21 +278,35 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Имя продукта или пустая строка",
+                        "name": "product",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.productRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Баланс кошелька",
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/wallet.Wallet"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные данные",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
-                                "type": "number",
-                                "format": "float64"
+                                "type": "string"
                             }
                         }
                     },
-                    "400": {
-                        "description": "Неверный ID кошелька",
+                    "401": {
+                        "description": "Неверный токен",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -107,8 +314,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Ошибка сервера",
+                    "404": {
+                        "description": "Кошелёк не найден",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -116,36 +323,2664 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
-            }
-        }
-    },
-    "definitions": {
-        "wallet.WalletTransactions": {
-            "type": "object",
-            "required": [
-                "amount",
-                "operationType",
-                "valletId"
-            ],
-            "properties": {
-                "amount": {
-                    "type": "number"
                 },
-                "id": {
-                    "type": "integer"
-                },
-                "operationType": {
-                    "type": "string",
-                    "enum": [
-                        "DEPOSIT",
-                        "WITHDRAW"
-                    ]
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ]
+            }
+        },
+        "/admin/wallets/{id}/tier": {
+            "put": {
+                "description": "По уровню выбирается расписание комиссий за снятие и переводы (FEE_SCHEDULE_FILE).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Изменить уровень кошелька",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID кошелька",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Уровень: 1-32 символа a-z, 0-9, '_' и '-'",
+                        "name": "tier",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.tierRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/wallet.Wallet"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные данные",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Кошелёк не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
                 },
-                "valletId": {
-                    "type": "string"
-                }
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ]
             }
+        },
+        "/admin/webhooks": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Список подписок на вебхуки",
+                "responses": {
+                    "200": {
+                        "description": "subscriptions: подписки (без секретов)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/wallet.WebhookSubscription"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ]
+            },
+            "post": {
+                "description": "Без valletId - события всех кошельков, без eventTypes - все типы событий\n(transaction.deposited, transaction.withdrawn, transaction.rejected).\nДоставки подписываются HMAC-SHA256 секретом подписки (заголовок X-Wallet-Signature, см. pkg/webhook).\nЕсли secret не передан, он генерируется; секрет возвращается только в этом ответе.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Подписаться на события кошельков",
+                "parameters": [
+                    {
+                        "description": "Подписка",
+                        "name": "subscription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/wallet.WebhookSubscription"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/wallet.WebhookSubscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные данные",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Кошелёк не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ]
+            }
+        },
+        "/admin/webhooks/deliveries/dead": {
+            "get": {
+                "description": "Доставки, которые не удалось выполнить за все попытки; их можно отправить повторно через replay.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Доставки в dead-letter",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Максимальное число доставок (до 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "deliveries: доставки",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/wallet.WebhookDelivery"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ]
+            }
+        },
+        "/admin/webhooks/deliveries/{id}/replay": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Повторно отправить доставку из dead-letter",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID доставки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "status: queued",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Доставка не найдена или не в dead-letter",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ]
+            }
+        },
+        "/admin/webhooks/{id}": {
+            "delete": {
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Удалить подписку вместе с её доставками",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Неверный токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ]
+            }
+        },
+        "/receipts/keys": {
+            "get": {
+                "description": "Квитанции об операциях подписываются Ed25519; keyId квитанции указывает, каким ключом.\nСписок включает ключи из прошлых ротаций. Проверка офлайн - пакет pkg/receipt.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "receipts"
+                ],
+                "summary": "Публичные ключи для проверки квитанций",
+                "responses": {
+                    "200": {
+                        "description": "keys: публичные ключи",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/receipt.PublicKey"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/wallet": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Пополнить или снять деньги с кошелька, а также записать историю выполненных операций",
+                "parameters": [
+                    {
+                        "description": "Данные транзакции",
+                        "name": "transaction",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/wallet.WalletTransactions"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Версия кошелька (ETag); операция выполнится, только если кошелёк не менялся",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "status: success; receipt - подписанная квитанция, если настроен ключ",
+                        "schema": {
+                            "$ref": "#/definitions/handler.transactionResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия кошелька после операции"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации или неверные данные",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "412": {
+                        "description": "Версия кошелька не совпадает с If-Match",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Недостаточно средств",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка при обновлении баланса",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/batch": {
+            "post": {
+                "description": "Тело - JSON-массив операций или поток NDJSON (Content-Type: application/x-ndjson), по операции в строке.\nmode=atomic (по умолчанию): все операции в одной транзакции БД, кошельки блокируются в порядке ID;\nпри любом отказе не применяется ничего (422), остальные операции получают статус aborted.\nmode=best-effort: операции применяются независимо, у каждой свой результат.\nПоток NDJSON в режиме best-effort применяется частями по мере чтения, а результаты возвращаются\nтакже потоком NDJSON по строке на операцию; при превышении лимита последней строкой идёт {\"error\": ...}.\nПовтор операции с тем же idempotencyKey для того же кошелька не применяется (статус duplicate).",
+                "consumes": [
+                    "application/json",
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Пакет операций по многим кошелькам",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "atomic",
+                        "description": "atomic или best-effort",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Операции",
+                        "name": "transactions",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/wallet.WalletTransactions"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Результаты операций",
+                        "schema": {
+                            "$ref": "#/definitions/handler.batchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный запрос",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Слишком много операций",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Пакет atomic не применён",
+                        "schema": {
+                            "$ref": "#/definitions/handler.batchResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Получить баланс кошелька по ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID кошелька",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag из предыдущего ответа; при совпадении вернётся 304 без тела",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Баланс кошелька, остатки корзин, кредитный лимит и доступная к списанию сумма",
+                        "schema": {
+                            "$ref": "#/definitions/handler.balanceResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия кошелька"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Кошелёк не изменился"
+                    },
+                    "400": {
+                        "description": "Неверный ID кошелька",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Кошелёк не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{id}/interest": {
+            "get": {
+                "description": "Дневные начисления процентов кошелька за дни из [from, to) (UTC). from и to - дата (YYYY-MM-DD,\nto включительно) или время RFC 3339; без to - по текущий день, без from - последние 31 день.\nНачисления копятся в accruedInterest кошелька и в конце месяца зачисляются операцией DEPOSIT\nвида interest. Период - не больше 366 дней.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Начисления процентов на остаток",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID кошелька",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Начало периода",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Конец периода",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/wallet.InterestAccrual"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные параметры",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Кошелёк не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{id}/recurring": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "recurring"
+                ],
+                "summary": "Регулярные переводы кошелька",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID кошелька-источника",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Максимальное число правил (до 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "recurring: правила в порядке создания",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/wallet.RecurringTransfer"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные параметры",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Кошелёк не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Перевод amount на toWalletId по расписанию schedule: cron из пяти полей или RRULE\n(FREQ=DAILY|WEEKLY|MONTHLY, INTERVAL, BYDAY, BYMONTHDAY, BYHOUR, BYMINUTE, UNTIL), время - в UTC.\ncatchUp - что делать со сроками, пропущенными, пока сервис не работал: all, latest (по умолчанию) или skip.\nПри нехватке средств перевод повторяется до maxRetries раз через retryIntervalSeconds (по умолчанию час).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "recurring"
+                ],
+                "summary": "Создать регулярный перевод",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID кошелька-источника",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Правило",
+                        "name": "rule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.recurringRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/wallet.RecurringTransfer"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные данные",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Кошелёк не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{id}/recurring/{ruleId}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "recurring"
+                ],
+                "summary": "Регулярный перевод",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID кошелька-источника",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID правила",
+                        "name": "ruleId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/wallet.RecurringTransfer"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные параметры",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Правило не найдено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{id}/recurring/{ruleId}/cancel": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "recurring"
+                ],
+                "summary": "Отменить регулярный перевод",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID кошелька-источника",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID правила",
+                        "name": "ruleId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/wallet.RecurringTransfer"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные параметры",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Правило не найдено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Правило уже завершено или отменено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{id}/recurring/{ruleId}/pause": {
+            "post": {
+                "description": "Уже начатый перевод завершится; сроки, прошедшие за время паузы, не выполняются.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "recurring"
+                ],
+                "summary": "Приостановить регулярный перевод",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID кошелька-источника",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID правила",
+                        "name": "ruleId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/wallet.RecurringTransfer"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные параметры",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Правило не найдено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Правило не активно",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{id}/recurring/{ruleId}/resume": {
+            "post": {
+                "description": "Перевод продолжается со следующего срока после текущего момента.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "recurring"
+                ],
+                "summary": "Возобновить регулярный перевод",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID кошелька-источника",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID правила",
+                        "name": "ruleId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/wallet.RecurringTransfer"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные параметры",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Правило не найдено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Правило не приостановлено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{id}/recurring/{ruleId}/runs": {
+            "get": {
+                "description": "Каждая попытка перевода: срок, номер попытки, результат и ID записанных транзакций.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "recurring"
+                ],
+                "summary": "История запусков регулярного перевода",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID кошелька-источника",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID правила",
+                        "name": "ruleId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Вернуть запуски с id больше указанного",
+                        "name": "afterId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Максимальное число запусков (до 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "runs: запуски по порядку",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/wallet.RecurringRun"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные параметры",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Правило не найдено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{id}/scheduled": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Отложенные операции кошелька",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID кошелька",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "pending, succeeded, failed или cancelled; без него - все",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Максимальное число операций (до 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "scheduled: операции в порядке executeAt",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/wallet.ScheduledTransaction"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные параметры",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Кошелёк не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Пополнение или списание выполняется в executeAt (RFC 3339) или вскоре после.\nРезультат - в статусе операции: succeeded, failed (недостаточно средств) или cancelled.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Запланировать операцию",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID кошелька",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Операция",
+                        "name": "transaction",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.scheduleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/wallet.ScheduledTransaction"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные данные",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Кошелёк не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{id}/scheduled/{scheduledId}/cancel": {
+            "post": {
+                "description": "Отменить можно только ожидающую операцию, которую обработчик ещё не начал выполнять.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Отменить отложенную операцию",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID кошелька",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID отложенной операции",
+                        "name": "scheduledId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/wallet.ScheduledTransaction"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные параметры",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Операция не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Операция уже выполнена, отменена или выполняется",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{id}/statements": {
+            "get": {
+                "description": "Выписки формируются в начале месяца за предыдущий календарный месяц (UTC) для кошельков с операциями в нём.\nКаждая выписка содержит балансы на начало и конец месяца, итоги по типам операций и форматы файлов,\nкоторые можно скачать через /wallets/{id}/statements/{period}.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Месячные выписки кошелька",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID кошелька",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "statements: выписки, последние первыми",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/wallet.Statement"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Кошелёк не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{id}/statements/{period}": {
+            "get": {
+                "produces": [
+                    "text/html",
+                    "text/csv"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Файл месячной выписки кошелька",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID кошелька",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Месяц выписки, YYYY-MM",
+                        "name": "period",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "html",
+                        "description": "html или csv",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Выписка",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные параметры",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Выписка не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{id}/stats": {
+            "get": {
+                "description": "Число и сумма пополнений и списаний по интервалам (day, week или month; UTC, неделя - с понедельника)\nза период [from, to), включая интервалы без операций. from и to - дата (YYYY-MM-DD, to включительно)\nили время RFC 3339; без to - по конец текущего дня, без from - последние 30 дней, 12 недель или 12 месяцев.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Статистика операций кошелька",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID кошелька",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Начало периода",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Конец периода",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "day",
+                        "description": "day, week или month",
+                        "name": "interval",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/wallet.Stats"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные параметры",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Кошелёк не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{id}/stream": {
+            "get": {
+                "description": "Первое событие - snapshot с текущим балансом, далее событие transaction на каждую операцию:\nновый баланс и транзакция. id события - seq; после переподключения клиент передаёт его в\nLast-Event-ID (или ?afterSeq=) и получает пропущенные транзакции по порядку.\nРаз в 15 секунд отправляется комментарий-heartbeat.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Поток изменений баланса кошелька (Server-Sent Events)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID кошелька",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "seq последнего полученного события",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "seq, после которого продолжить поток",
+                        "name": "afterSeq",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Поток событий snapshot и transaction",
+                        "schema": {
+                            "$ref": "#/definitions/wallet.BalanceUpdate"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные параметры",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Кошелёк не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{id}/transactions": {
+            "get": {
+                "description": "Транзакции кошелька нумеруются 1, 2, 3... без пропусков и содержат баланс после операции,\nпоэтому клиент может проверить непрерывность истории и пересчитывать баланс инкрементально.\nС bucket возвращаются только транзакции, затронувшие корзину; остаток корзины после них - в buckets.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "История операций кошелька по порядковым номерам",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID кошелька",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Корзина: main, bonus, cashback или promo",
+                        "name": "bucket",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Вернуть транзакции с seq больше указанного",
+                        "name": "afterSeq",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Максимальное число транзакций (до 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "transactions: история",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/wallet.WalletTransactions"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные параметры",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Кошелёк не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{id}/transactions/export": {
+            "get": {
+                "description": "Файл с историей за период [from, to): строка начального баланса, транзакции в порядке seq и строка конечного баланса.\nФорматы: csv, jsonl (JSON Lines), ofx (OFX 2.2 для бухгалтерских программ) и html.\nfrom и to - дата (YYYY-MM-DD, to включительно) или время RFC 3339 (to не включительно); без них - вся история.\nСуммы - с точкой и двумя знаками после неё, время - в UTC. История отдаётся потоком, без ограничения размера.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/x-ofx",
+                    "text/html"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Выгрузка истории операций кошелька",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID кошелька",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "csv",
+                        "description": "csv, jsonl, ofx или html",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Начало периода",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Конец периода",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Выгрузка",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные параметры",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Кошелёк не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{id}/verify": {
+            "get": {
+                "description": "Проходит историю кошелька по порядку seq, пересчитывает хеши и сверяет их с подписанными контрольными точками.\nЕсли цепочка нарушена, в ответе valid=false, brokenSeq - номер первого нарушенного звена, reason - причина.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "audit"
+                ],
+                "summary": "Проверить цепочку хешей истории кошелька",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID кошелька",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/wallet.ChainReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Кошелёк не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{id}/ws": {
+            "get": {
+                "description": "Те же сообщения, что и в /wallets/{id}/stream, по одному JSON (wallet.BalanceUpdate) в текстовом кадре;\nтип сообщения - в поле type. Для возобновления передайте seq последнего сообщения в ?afterSeq=.",
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Поток изменений баланса кошелька (WebSocket)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID кошелька",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "seq, после которого продолжить поток",
+                        "name": "afterSeq",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Переключение на WebSocket",
+                        "schema": {
+                            "$ref": "#/definitions/wallet.BalanceUpdate"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные параметры",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Кошелёк не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handler.balanceResponse": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "type": "number"
+                },
+                "balance": {
+                    "type": "number"
+                },
+                "buckets": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number",
+                        "format": "float64"
+                    }
+                },
+                "creditLimit": {
+                    "type": "number"
+                }
+            }
+        },
+        "handler.batchResponse": {
+            "type": "object",
+            "properties": {
+                "applied": {
+                    "type": "integer"
+                },
+                "duplicates": {
+                    "type": "integer"
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "rejected": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/wallet.BatchResult"
+                    }
+                }
+            }
+        },
+        "handler.creditLimitRequest": {
+            "type": "object",
+            "required": [
+                "creditLimit"
+            ],
+            "properties": {
+                "creditLimit": {
+                    "type": "number"
+                }
+            }
+        },
+        "handler.productRequest": {
+            "type": "object",
+            "required": [
+                "product"
+            ],
+            "properties": {
+                "product": {
+                    "description": "Product - пустая строка отключает начисление процентов.",
+                    "type": "string"
+                }
+            }
+        },
+        "handler.recurringRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "schedule",
+                "toWalletId"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "catchUp": {
+                    "type": "string",
+                    "enum": [
+                        "all",
+                        "latest",
+                        "skip"
+                    ]
+                },
+                "endAt": {
+                    "type": "string"
+                },
+                "maxRetries": {
+                    "type": "integer"
+                },
+                "retryIntervalSeconds": {
+                    "type": "integer"
+                },
+                "schedule": {
+                    "description": "Schedule - выражение cron (\"0 9 1 * *\") или RRULE (\"FREQ=MONTHLY;BYMONTHDAY=1;BYHOUR=9;BYMINUTE=0\"), в UTC.",
+                    "type": "string"
+                },
+                "startAt": {
+                    "type": "string"
+                },
+                "toWalletId": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.scheduleRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "executeAt",
+                "operationType"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "executeAt": {
+                    "type": "string"
+                },
+                "operationType": {
+                    "type": "string",
+                    "enum": [
+                        "DEPOSIT",
+                        "WITHDRAW"
+                    ]
+                }
+            }
+        },
+        "handler.tierRequest": {
+            "type": "object",
+            "required": [
+                "tier"
+            ],
+            "properties": {
+                "tier": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.transactionResponse": {
+            "type": "object",
+            "properties": {
+                "receipt": {
+                    "$ref": "#/definitions/receipt.Receipt"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "receipt.PublicKey": {
+            "type": "object",
+            "properties": {
+                "algorithm": {
+                    "type": "string"
+                },
+                "keyId": {
+                    "type": "string"
+                },
+                "publicKey": {
+                    "description": "base64 (std), 32 байта",
+                    "type": "string"
+                }
+            }
+        },
+        "receipt.Receipt": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "balanceAfter": {
+                    "type": "number"
+                },
+                "keyId": {
+                    "type": "string"
+                },
+                "operationType": {
+                    "type": "string"
+                },
+                "seq": {
+                    "type": "integer"
+                },
+                "signature": {
+                    "description": "base64 (std)",
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "transactionId": {
+                    "type": "integer"
+                },
+                "valletId": {
+                    "type": "string"
+                }
+            }
+        },
+        "wallet.BalanceUpdate": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "number"
+                },
+                "seq": {
+                    "type": "integer"
+                },
+                "transaction": {
+                    "$ref": "#/definitions/wallet.WalletTransactions"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "valletId": {
+                    "type": "string"
+                }
+            }
+        },
+        "wallet.BatchResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "transaction": {
+                    "$ref": "#/definitions/wallet.WalletTransactions"
+                }
+            }
+        },
+        "wallet.BucketEntry": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "balanceAfter": {
+                    "type": "number"
+                },
+                "bucket": {
+                    "type": "string"
+                }
+            }
+        },
+        "wallet.ChainReport": {
+            "type": "object",
+            "properties": {
+                "brokenSeq": {
+                    "description": "BrokenSeq - seq первого нарушенного звена, Reason - что именно не сошлось.",
+                    "type": "integer"
+                },
+                "checked": {
+                    "description": "Checked - число проверенных транзакций, Head - хеш последней из них.",
+                    "type": "integer"
+                },
+                "checkpoints": {
+                    "type": "integer"
+                },
+                "head": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "valid": {
+                    "type": "boolean"
+                },
+                "valletId": {
+                    "type": "string"
+                }
+            }
+        },
+        "wallet.ImportReport": {
+            "type": "object",
+            "properties": {
+                "counts": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "dryRun": {
+                    "type": "boolean"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/wallet.ImportResult"
+                    }
+                },
+                "rows": {
+                    "type": "integer"
+                }
+            }
+        },
+        "wallet.ImportResult": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "line": {
+                    "type": "integer"
+                },
+                "operationType": {
+                    "type": "string"
+                },
+                "reference": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "transaction": {
+                    "$ref": "#/definitions/wallet.WalletTransactions"
+                },
+                "walletId": {
+                    "type": "string"
+                }
+            }
+        },
+        "wallet.InterestAccrual": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "balance": {
+                    "type": "number"
+                },
+                "dailyRate": {
+                    "type": "number"
+                },
+                "day": {
+                    "type": "string"
+                },
+                "product": {
+                    "type": "string"
+                },
+                "valletId": {
+                    "type": "string"
+                }
+            }
+        },
+        "wallet.RecurringRun": {
+            "type": "object",
+            "properties": {
+                "attempt": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "fromTransactionId": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "occurrenceAt": {
+                    "type": "string"
+                },
+                "ruleId": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "toTransactionId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "wallet.RecurringTransfer": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "attempt": {
+                    "type": "integer"
+                },
+                "catchUp": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "endAt": {
+                    "type": "string"
+                },
+                "fromWalletId": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "maxRetries": {
+                    "type": "integer"
+                },
+                "nextRunAt": {
+                    "type": "string"
+                },
+                "occurrenceAt": {
+                    "type": "string"
+                },
+                "retryIntervalSeconds": {
+                    "type": "integer"
+                },
+                "schedule": {
+                    "type": "string"
+                },
+                "startAt": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "toWalletId": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "wallet.ScheduledTransaction": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "executeAt": {
+                    "type": "string"
+                },
+                "finishedAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "operationType": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "transactionId": {
+                    "description": "TransactionId - ID записанной транзакции у выполненной операции.",
+                    "type": "integer"
+                },
+                "valletId": {
+                    "type": "string"
+                }
+            }
+        },
+        "wallet.Statement": {
+            "type": "object",
+            "properties": {
+                "closingBalance": {
+                    "type": "number"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "deposits": {
+                    "$ref": "#/definitions/wallet.StatementTotal"
+                },
+                "formats": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "openingBalance": {
+                    "type": "number"
+                },
+                "period": {
+                    "description": "YYYY-MM",
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "valletId": {
+                    "type": "string"
+                },
+                "withdrawals": {
+                    "$ref": "#/definitions/wallet.StatementTotal"
+                }
+            }
+        },
+        "wallet.StatementTotal": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "sum": {
+                    "type": "number"
+                }
+            }
+        },
+        "wallet.Stats": {
+            "type": "object",
+            "properties": {
+                "buckets": {
+                    "description": "Buckets - интервалы по порядку, включая интервалы без операций. Первый интервал может начинаться раньше From.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/wallet.StatsBucket"
+                    }
+                },
+                "deposits": {
+                    "$ref": "#/definitions/wallet.StatementTotal"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "interval": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "withdrawals": {
+                    "$ref": "#/definitions/wallet.StatementTotal"
+                }
+            }
+        },
+        "wallet.StatsBucket": {
+            "type": "object",
+            "properties": {
+                "deposits": {
+                    "$ref": "#/definitions/wallet.StatementTotal"
+                },
+                "start": {
+                    "type": "string"
+                },
+                "withdrawals": {
+                    "$ref": "#/definitions/wallet.StatementTotal"
+                }
+            }
+        },
+        "wallet.Wallet": {
+            "type": "object",
+            "properties": {
+                "accruedInterest": {
+                    "description": "AccruedInterest - начисленные, но ещё не капитализированные проценты (с точностью до 1e-8).",
+                    "type": "number"
+                },
+                "available": {
+                    "description": "Available - сколько можно списать без явной корзины: остатки корзин WithdrawOrder + CreditLimit.",
+                    "type": "number"
+                },
+                "balance": {
+                    "type": "number"
+                },
+                "buckets": {
+                    "description": "Buckets - остатки корзин кошелька; их сумма равна Balance.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number",
+                        "format": "float64"
+                    }
+                },
+                "creditLimit": {
+                    "description": "CreditLimit - одобренный кредитный лимит: баланс может опускаться до -CreditLimit (овердрафт).",
+                    "type": "number"
+                },
+                "lastHash": {
+                    "type": "string"
+                },
+                "lastSeq": {
+                    "description": "LastSeq и LastHash - номер и хеш последней транзакции (голова цепочки хешей).",
+                    "type": "integer"
+                },
+                "product": {
+                    "description": "Product - процентный продукт кошелька; пустой - проценты не начисляются.",
+                    "type": "string"
+                },
+                "tier": {
+                    "description": "Tier - уровень кошелька, по которому выбирается расписание комиссий.",
+                    "type": "string"
+                },
+                "valletId": {
+                    "description": "Id       int       ` + "`" + `json:\"id\"` + "`" + `",
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "wallet.WalletTransactions": {
+            "type": "object",
+            "required": [
+                "amount",
+                "operationType",
+                "valletId"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "balanceAfter": {
+                    "type": "number"
+                },
+                "bucket": {
+                    "description": "Bucket - корзина операции: DEPOSIT зачисляется в неё, WITHDRAW списывается только из неё.\nБез корзины DEPOSIT зачисляется в main, а WITHDRAW списывается по порядку WithdrawOrder.",
+                    "type": "string",
+                    "enum": [
+                        "main",
+                        "bonus",
+                        "cashback",
+                        "promo"
+                    ]
+                },
+                "buckets": {
+                    "description": "Buckets - как применённая операция разложилась по корзинам, в порядке Buckets.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/wallet.BucketEntry"
+                    }
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "hash": {
+                    "description": "Hash - звено цепочки хешей истории кошелька, см. ChainHash.",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "idempotencyKey": {
+                    "description": "IdempotencyKey - необязательный ключ идемпотентности: повтор операции с тем же ключом для того же кошелька\nне применяется, а возвращает уже записанную транзакцию.",
+                    "type": "string",
+                    "maxLength": 128
+                },
+                "kind": {
+                    "description": "Kind - вид транзакции, заполняется сервисом: пусто - операция клиента, KindFee - комиссия, KindInterest - проценты.",
+                    "type": "string"
+                },
+                "operationId": {
+                    "description": "OperationId связывает транзакции одной операции с комиссией: саму операцию, списание комиссии\nи её зачисление на кошелёк комиссий. Заполняется сервисом.",
+                    "type": "string"
+                },
+                "operationType": {
+                    "type": "string",
+                    "enum": [
+                        "DEPOSIT",
+                        "WITHDRAW"
+                    ]
+                },
+                "seq": {
+                    "description": "Seq - порядковый номер транзакции внутри кошелька: 1, 2, 3... без пропусков.",
+                    "type": "integer"
+                },
+                "valletId": {
+                    "type": "string"
+                }
+            }
+        },
+        "wallet.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "eventId": {
+                    "type": "string"
+                },
+                "eventType": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lastError": {
+                    "type": "string"
+                },
+                "nextAttemptAt": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "subscriptionId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "wallet.WebhookSubscription": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "eventTypes": {
+                    "description": "EventTypes - типы событий; пустой список - все события.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "secret": {
+                    "description": "Secret - ключ HMAC для подписи доставок; возвращается только при создании подписки.",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "valletId": {
+                    "type": "string"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "AdminToken": {
+            "description": "Bearer \u003cADMIN_TOKEN\u003e",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -0,0 +1,76 @@
+package wallet
+
+import (
+	"encoding/json"
+	"time"
+
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// Типы событий кошелька.
+const (
+	EventDeposited = "transaction.deposited"
+	EventWithdrawn = "transaction.withdrawn"
+	// EventRejected - операция отклонена: недостаточно средств или не совпала версия кошелька (If-Match).
+	EventRejected = "transaction.rejected"
+	// EventOverdraftEntered и EventOverdraftExited - операция перевела баланс кошелька с кредитным лимитом
+	// ниже нуля или вернула его к нулю и выше; Transaction - эта операция.
+	EventOverdraftEntered = "wallet.overdraft_entered"
+	EventOverdraftExited  = "wallet.overdraft_exited"
+)
+
+// EventTypes - все типы событий, на которые можно подписаться.
+var EventTypes = []string{EventDeposited, EventWithdrawn, EventRejected, EventOverdraftEntered, EventOverdraftExited}
+
+type WalletEvent struct {
+	Id         string    `json:"id"`
+	Type       string    `json:"type"`
+	ValletId   uuid.UUID `json:"valletId"`
+	OccurredAt time.Time `json:"occurredAt"`
+	// Transaction - записанная транзакция, а для отклонённой операции - запрошенная.
+	Transaction WalletTransactions `json:"transaction"`
+	// Reason - причина отказа для transaction.rejected.
+	Reason string `json:"reason,omitempty"`
+}
+
+// OutboxEvent - событие из transactional outbox, ожидающее публикации; Id задаёт порядок публикации.
+type OutboxEvent struct {
+	Id    int64
+	Event WalletEvent
+}
+
+// WebhookSubscription - подписка на события одного кошелька (ValletId) или всех кошельков (nil).
+type WebhookSubscription struct {
+	Id       int64      `json:"id"`
+	ValletId *uuid.UUID `json:"valletId,omitempty"`
+	URL      string     `json:"url" binding:"required,url"`
+	// EventTypes - типы событий; пустой список - все события.
+	EventTypes []string `json:"eventTypes"`
+	// Secret - ключ HMAC для подписи доставок; возвращается только при создании подписки.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Статусы доставки вебхука.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// WebhookDelivery - доставка одного события одной подписке.
+type WebhookDelivery struct {
+	Id             int64           `json:"id"`
+	SubscriptionId int64           `json:"subscriptionId"`
+	EventId        string          `json:"eventId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	LastError      string          `json:"lastError,omitempty"`
+	NextAttemptAt  time.Time       `json:"nextAttemptAt"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	// URL и Secret - из подписки, нужны для отправки.
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
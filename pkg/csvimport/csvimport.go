@@ -0,0 +1,166 @@
+// Package csvimport - чтение CSV массовой корректировки кошельков и запись результатов по строкам в CSV.
+//
+// Формат файла - колонки wallet_id, operation_type, amount, reference:
+//
+//	wallet_id,operation_type,amount,reference
+//	11111111-1111-1111-1111-111111111111,DEPOSIT,100.50,ADJ-2024-001
+//
+// Строка заголовка необязательна; если она есть, колонки могут идти в любом порядке.
+// Разделитель - запятая или точка с запятой (как сохраняет Excel), BOM в начале файла пропускается.
+package csvimport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/KatenkaKet/wallet"
+)
+
+var ErrTooManyRows = errors.New("too many rows")
+
+// Columns - колонки файла в порядке по умолчанию.
+var Columns = []string{"wallet_id", "operation_type", "amount", "reference"}
+
+// ResultColumns - колонки CSV с результатами: исходная строка, статус и записанная транзакция.
+var ResultColumns = []string{"line", "wallet_id", "operation_type", "amount", "reference",
+	"status", "error", "transaction_id", "seq", "balance_after"}
+
+// Read читает строки файла, не проверяя их содержимое (это делает сервис). Ошибка возвращается,
+// только если файл не разбирается как CSV, в строке не то число колонок или строк больше maxRows (ErrTooManyRows).
+func Read(r io.Reader, maxRows int) ([]wallet.ImportRow, error) {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		br.Discard(3)
+	}
+
+	// Разделитель определяется по первой строке.
+	comma := ','
+	head, _ := br.Peek(4096)
+	if line, _, _ := bytes.Cut(head, []byte("\n")); bytes.Count(line, []byte(";")) > bytes.Count(line, []byte(",")) {
+		comma = ';'
+	}
+
+	cr := csv.NewReader(br)
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.ReuseRecord = true
+
+	columns := map[string]int{}
+	for i, name := range Columns {
+		columns[name] = i
+	}
+
+	var rows []wallet.ImportRow
+	for first := true; ; first = false {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+
+		if first && isHeader(record) {
+			if columns, err = headerColumns(record); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			continue
+		}
+		if len(record) != len(Columns) {
+			return nil, fmt.Errorf("line %d: expected %d fields, got %d", line, len(Columns), len(record))
+		}
+		if len(rows) >= maxRows {
+			return nil, fmt.Errorf("%w: more than %d", ErrTooManyRows, maxRows)
+		}
+
+		field := func(name string) string {
+			return strings.TrimSpace(record[columns[name]])
+		}
+		rows = append(rows, wallet.ImportRow{
+			Line:          line,
+			WalletID:      field("wallet_id"),
+			OperationType: field("operation_type"),
+			Amount:        field("amount"),
+			Reference:     field("reference"),
+		})
+	}
+	return rows, nil
+}
+
+func isHeader(record []string) bool {
+	for _, field := range record {
+		if normalizeColumn(field) == Columns[0] {
+			return true
+		}
+	}
+	return false
+}
+
+func headerColumns(record []string) (map[string]int, error) {
+	if len(record) != len(Columns) {
+		return nil, fmt.Errorf("expected header %s", strings.Join(Columns, ","))
+	}
+
+	columns := make(map[string]int, len(Columns))
+	for i, field := range record {
+		columns[normalizeColumn(field)] = i
+	}
+	for _, name := range Columns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %s", name)
+		}
+	}
+	return columns, nil
+}
+
+// normalizeColumn приводит "Wallet ID", "walletId" и т.п. к виду wallet_id.
+func normalizeColumn(name string) string {
+	name = strings.TrimSpace(name)
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r == ' ' || r == '-':
+			b.WriteByte('_')
+		case r >= 'A' && r <= 'Z':
+			if i > 0 && name[i-1] >= 'a' && name[i-1] <= 'z' {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r + 'a' - 'A')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// WriteResults записывает результаты по строкам в CSV с заголовком ResultColumns.
+func WriteResults(w io.Writer, results []wallet.ImportResult) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(ResultColumns); err != nil {
+		return err
+	}
+
+	for _, res := range results {
+		record := []string{strconv.Itoa(res.Line), res.WalletID, res.OperationType, res.Amount, res.Reference,
+			res.Status, res.Error, "", "", ""}
+		if WT := res.Transaction; WT != nil {
+			record[7] = strconv.Itoa(WT.Id)
+			record[8] = strconv.FormatInt(WT.Seq, 10)
+			record[9] = strconv.FormatFloat(WT.BalanceAfter, 'f', 2, 64)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
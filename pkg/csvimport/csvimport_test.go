@@ -0,0 +1,100 @@
+package csvimport
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const walletA = "11111111-1111-1111-1111-111111111111"
+
+func TestRead(t *testing.T) {
+	testTable := []struct {
+		name     string
+		input    string
+		maxRows  int
+		expected []wallet.ImportRow
+		errorMsg string
+	}{
+		{
+			name:  "without header",
+			input: walletA + ",DEPOSIT,10.5,ADJ-1\n\n" + walletA + ", withdraw ,1,ADJ-2\n",
+			expected: []wallet.ImportRow{
+				{Line: 1, WalletID: walletA, OperationType: "DEPOSIT", Amount: "10.5", Reference: "ADJ-1"},
+				{Line: 3, WalletID: walletA, OperationType: "withdraw", Amount: "1", Reference: "ADJ-2"},
+			},
+		},
+		{
+			name:  "header in any order",
+			input: "Reference,Amount,Wallet ID,operationType\nADJ-1,10.5," + walletA + ",DEPOSIT\n",
+			expected: []wallet.ImportRow{
+				{Line: 2, WalletID: walletA, OperationType: "DEPOSIT", Amount: "10.5", Reference: "ADJ-1"},
+			},
+		},
+		{
+			name:  "excel with BOM and semicolons",
+			input: "\xef\xbb\xbfwallet_id;operation_type;amount;reference\r\n" + walletA + ";DEPOSIT;\"10,5\";ADJ-1\r\n",
+			expected: []wallet.ImportRow{
+				{Line: 2, WalletID: walletA, OperationType: "DEPOSIT", Amount: "10,5", Reference: "ADJ-1"},
+			},
+		},
+		{
+			name:     "wrong number of fields",
+			input:    walletA + ",DEPOSIT,10.5,ADJ-1\n" + walletA + ",DEPOSIT,10.5\n",
+			errorMsg: "line 2: expected 4 fields, got 3",
+		},
+		{
+			name:     "missing column in header",
+			input:    "wallet_id,operation_type,amount,comment\n",
+			errorMsg: "line 1: missing column reference",
+		},
+		{
+			name:     "too many rows",
+			input:    strings.Repeat(walletA+",DEPOSIT,1,ADJ\n", 3),
+			maxRows:  2,
+			errorMsg: "too many rows: more than 2",
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			maxRows := test.maxRows
+			if maxRows == 0 {
+				maxRows = 100
+			}
+
+			rows, err := Read(strings.NewReader(test.input), maxRows)
+			if test.errorMsg != "" {
+				assert.EqualError(t, err, test.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, rows)
+		})
+	}
+}
+
+func TestWriteResults(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteResults(&buf, []wallet.ImportResult{
+		{
+			ImportRow:   wallet.ImportRow{Line: 2, WalletID: walletA, OperationType: "DEPOSIT", Amount: "10.5", Reference: "ADJ-1"},
+			Status:      wallet.BatchApplied,
+			Transaction: &wallet.WalletTransactions{Id: 7, Seq: 3, BalanceAfter: 110.5},
+		},
+		{
+			ImportRow: wallet.ImportRow{Line: 3, WalletID: "bad", OperationType: "DEPOSIT", Amount: "1", Reference: "ADJ, 2"},
+			Status:    wallet.ImportInvalid,
+			Error:     `invalid wallet id "bad"`,
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "line,wallet_id,operation_type,amount,reference,status,error,transaction_id,seq,balance_after\n"+
+		"2,"+walletA+",DEPOSIT,10.5,ADJ-1,applied,,7,3,110.50\n"+
+		`3,bad,DEPOSIT,1,"ADJ, 2",invalid,"invalid wallet id ""bad""",,,`+"\n", buf.String())
+}
@@ -0,0 +1,54 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+)
+
+// Виды строк CSV и JSON Lines.
+const (
+	rowOpeningBalance = "opening_balance"
+	rowTransaction    = "transaction"
+	rowClosingBalance = "closing_balance"
+)
+
+var csvColumns = []string{"row_type", "date", "seq", "transaction_id", "operation_type", "amount", "balance"}
+
+// csvWriter пишет строку на транзакцию; начальный и конечный баланс - отдельными строками в начале и в конце.
+type csvWriter struct {
+	w  *csv.Writer
+	to time.Time
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (w *csvWriter) Begin(h Header) error {
+	w.to = h.To
+	if err := w.w.Write(csvColumns); err != nil {
+		return err
+	}
+	return w.w.Write([]string{rowOpeningBalance, formatTime(h.From), "", "", "", "", formatAmount(h.OpeningBalance)})
+}
+
+func (w *csvWriter) Transaction(WT wallet.WalletTransactions) error {
+	return w.w.Write([]string{rowTransaction, formatTime(WT.CreatedAt), strconv.FormatInt(WT.Seq, 10), strconv.Itoa(WT.Id),
+		WT.OperationType, formatAmount(signedAmount(WT)), formatAmount(WT.BalanceAfter)})
+}
+
+func (w *csvWriter) End(closingBalance float64) error {
+	if err := w.w.Write([]string{rowClosingBalance, formatTime(w.to), "", "", "", "", formatAmount(closingBalance)}); err != nil {
+		return err
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,87 @@
+// Package export - выгрузка истории кошелька в CSV, JSON Lines, OFX и HTML.
+//
+// Writer получает строки по одной (начальный баланс, транзакции по порядку, конечный баланс)
+// и пишет их сразу в io.Writer, поэтому история любой длины не загружается в память целиком.
+// Числа форматируются независимо от локали: точка как разделитель дробной части, без разделителей разрядов,
+// время - в UTC.
+package export
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// Форматы выгрузки.
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+	FormatOFX   = "ofx"
+	FormatHTML  = "html"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Header - шапка выгрузки: кошелёк, период [From, To) и баланс на его начало.
+type Header struct {
+	ValletId       uuid.UUID
+	From           time.Time
+	To             time.Time
+	OpeningBalance float64
+}
+
+type Writer interface {
+	Begin(h Header) error
+	Transaction(WT wallet.WalletTransactions) error
+	// End записывает баланс на конец периода и сбрасывает буферы.
+	End(closingBalance float64) error
+}
+
+// NewWriter возвращает Writer формата format, пишущий в w. currency - код валюты ISO 4217 для OFX и HTML.
+func NewWriter(format string, w io.Writer, currency string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatJSONL:
+		return newJSONLWriter(w), nil
+	case FormatOFX:
+		return newOFXWriter(w, currency), nil
+	case FormatHTML:
+		return newHTMLWriter(w, currency), nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// ContentType возвращает MIME-тип формата.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSONL:
+		return "application/x-ndjson"
+	case FormatOFX:
+		return "application/x-ofx"
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// formatAmount форматирует сумму с двумя знаками после точки независимо от локали.
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// signedAmount - сумма со знаком: списание отрицательно.
+func signedAmount(WT wallet.WalletTransactions) float64 {
+	if WT.OperationType == "WITHDRAW" {
+		return -WT.Amount
+	}
+	return WT.Amount
+}
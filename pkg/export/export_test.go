@@ -0,0 +1,137 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriters(t *testing.T) {
+	var id uuid.UUID
+	require.NoError(t, id.Scan("11111111-1111-1111-1111-111111111111"))
+
+	moscow := time.FixedZone("MSK", 3*60*60)
+	h := Header{
+		ValletId:       id,
+		From:           time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:             time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		OpeningBalance: 1000,
+	}
+	history := []wallet.WalletTransactions{
+		{Id: 17, Seq: 4, OperationType: "DEPOSIT", Amount: 1234.5, BalanceAfter: 2234.5, CreatedAt: time.Date(2024, 1, 10, 15, 4, 5, 0, moscow)},
+		{Id: 21, Seq: 5, OperationType: "WITHDRAW", Amount: 0.1, BalanceAfter: 2234.4, CreatedAt: time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC)},
+	}
+
+	testTable := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format: FormatCSV,
+			expected: "row_type,date,seq,transaction_id,operation_type,amount,balance\n" +
+				"opening_balance,2024-01-01T00:00:00Z,,,,,1000.00\n" +
+				"transaction,2024-01-10T12:04:05Z,4,17,DEPOSIT,1234.50,2234.50\n" +
+				"transaction,2024-01-11T00:00:00Z,5,21,WITHDRAW,-0.10,2234.40\n" +
+				"closing_balance,2024-02-01T00:00:00Z,,,,,2234.40\n",
+		},
+		{
+			format: FormatJSONL,
+			expected: `{"type":"opening_balance","valletId":"11111111-1111-1111-1111-111111111111","date":"2024-01-01T00:00:00Z","balance":"1000.00"}
+{"type":"transaction","date":"2024-01-10T12:04:05Z","seq":4,"transactionId":17,"operationType":"DEPOSIT","amount":"1234.50","balance":"2234.50"}
+{"type":"transaction","date":"2024-01-11T00:00:00Z","seq":5,"transactionId":21,"operationType":"WITHDRAW","amount":"-0.10","balance":"2234.40"}
+{"type":"closing_balance","valletId":"11111111-1111-1111-1111-111111111111","date":"2024-02-01T00:00:00Z","balance":"2234.40"}
+`,
+		},
+		{
+			format: FormatOFX,
+			expected: `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS><DTSERVER>20240201120000.000[0:GMT]</DTSERVER><LANGUAGE>ENG</LANGUAGE></SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1><STMTTRNRS><TRNUID>0</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>
+<STMTRS><CURDEF>EUR</CURDEF>
+<BANKACCTFROM><BANKID>WALLET</BANKID><ACCTID>11111111-1111-1111-1111-111111111111</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>
+<BANKTRANLIST><DTSTART>20240101000000.000[0:GMT]</DTSTART><DTEND>20240201000000.000[0:GMT]</DTEND>
+<STMTTRN><TRNTYPE>CREDIT</TRNTYPE><DTPOSTED>20240110120405.000[0:GMT]</DTPOSTED><TRNAMT>1234.50</TRNAMT><FITID>17</FITID><NAME>Deposit</NAME><MEMO>seq 4</MEMO></STMTTRN>
+<STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20240111000000.000[0:GMT]</DTPOSTED><TRNAMT>-0.10</TRNAMT><FITID>21</FITID><NAME>Withdrawal</NAME><MEMO>seq 5</MEMO></STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>2234.40</BALAMT><DTASOF>20240201000000.000[0:GMT]</DTASOF></LEDGERBAL>
+<BALLIST><BAL><NAME>Opening balance</NAME><DESC>Balance at the start of the period</DESC><BALTYPE>DOLLAR</BALTYPE><VALUE>1000.00</VALUE><DTASOF>20240101000000.000[0:GMT]</DTASOF></BAL></BALLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(test.format, &buf, "EUR")
+			require.NoError(t, err)
+			if ofx, ok := w.(*ofxWriter); ok {
+				ofx.now = func() time.Time { return time.Date(2024, 2, 1, 15, 0, 0, 0, moscow) }
+			}
+
+			require.NoError(t, w.Begin(h))
+			for _, WT := range history {
+				require.NoError(t, w.Transaction(WT))
+			}
+			require.NoError(t, w.End(2234.4))
+
+			assert.Equal(t, test.expected, buf.String())
+		})
+	}
+
+	_, err := NewWriter("xlsx", &bytes.Buffer{}, "")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestHTMLWriterAndTotals(t *testing.T) {
+	var id uuid.UUID
+	require.NoError(t, id.Scan("11111111-1111-1111-1111-111111111111"))
+
+	h := Header{
+		ValletId:       id,
+		From:           time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:             time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		OpeningBalance: 10,
+	}
+	history := []wallet.WalletTransactions{
+		{Id: 1, Seq: 1, OperationType: "DEPOSIT", Amount: 5, BalanceAfter: 15},
+		{Id: 2, Seq: 2, OperationType: "DEPOSIT", Amount: 2.5, BalanceAfter: 17.5},
+		{Id: 3, Seq: 3, OperationType: "WITHDRAW", Amount: 7, BalanceAfter: 10.5},
+	}
+
+	var buf bytes.Buffer
+	html, err := NewWriter(FormatHTML, &buf, "EUR")
+	require.NoError(t, err)
+	totals := &Totals{}
+	w := MultiWriter(html, totals)
+
+	require.NoError(t, w.Begin(h))
+	for _, WT := range history {
+		require.NoError(t, w.Transaction(WT))
+	}
+	require.NoError(t, w.End(10.5))
+
+	assert.Equal(t, Totals{
+		OpeningBalance: 10,
+		ClosingBalance: 10.5,
+		Deposits:       wallet.StatementTotal{Count: 2, Sum: 7.5},
+		Withdrawals:    wallet.StatementTotal{Count: 1, Sum: 7},
+	}, *totals)
+
+	out := buf.String()
+	assert.Contains(t, out, "<title>Wallet 11111111-1111-1111-1111-111111111111 statement</title>")
+	assert.Contains(t, out, "Opening balance: 10.00")
+	assert.Contains(t, out, `<tr><td>0001-01-01T00:00:00Z</td><td>3</td><td>3</td><td>WITHDRAW</td><td class="amount">-7.00</td><td class="amount">10.50</td></tr>`)
+	assert.Contains(t, out, `<tr><td>DEPOSIT</td><td>2</td><td class="amount">7.50</td></tr>`)
+	assert.Contains(t, out, `<tr><td>WITHDRAW</td><td>1</td><td class="amount">7.00</td></tr>`)
+	assert.Contains(t, out, "Closing balance: 10.50")
+	assert.Contains(t, out, "Currency: EUR")
+}
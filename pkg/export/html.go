@@ -0,0 +1,102 @@
+package export
+
+import (
+	"bufio"
+	"html/template"
+	"io"
+
+	"github.com/KatenkaKet/wallet"
+)
+
+// Шаблоны выписки HTML. Документ самодостаточен (стили встроены), чтобы его можно было открыть или распечатать без сервера.
+var htmlTemplate = template.Must(template.New("head").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Wallet {{.Wallet}} statement</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; }
+td.amount { text-align: right; }
+</style>
+</head>
+<body>
+<h1>Wallet statement</h1>
+<p>Wallet: {{.Wallet}}<br>Period: {{.From}} &ndash; {{.To}}<br>Currency: {{.Currency}}</p>
+<p>Opening balance: {{.OpeningBalance}}</p>
+<table>
+<thead><tr><th>Date</th><th>Seq</th><th>Transaction</th><th>Type</th><th>Amount</th><th>Balance</th></tr></thead>
+<tbody>
+`))
+
+func init() {
+	template.Must(htmlTemplate.New("row").Parse(`<tr><td>{{.Date}}</td><td>{{.Seq}}</td><td>{{.Id}}</td><td>{{.Type}}</td><td class="amount">{{.Amount}}</td><td class="amount">{{.Balance}}</td></tr>
+`))
+	template.Must(htmlTemplate.New("foot").Parse(`</tbody>
+</table>
+<h2>Totals</h2>
+<table>
+<thead><tr><th>Type</th><th>Count</th><th>Sum</th></tr></thead>
+<tbody>
+<tr><td>DEPOSIT</td><td>{{.Totals.Deposits.Count}}</td><td class="amount">{{.DepositsSum}}</td></tr>
+<tr><td>WITHDRAW</td><td>{{.Totals.Withdrawals.Count}}</td><td class="amount">{{.WithdrawalsSum}}</td></tr>
+</tbody>
+</table>
+<p>Closing balance: {{.ClosingBalance}}</p>
+</body>
+</html>
+`))
+}
+
+// htmlWriter пишет выписку в виде HTML-страницы: шапка, таблица транзакций, итоги по типам операций и конечный баланс.
+type htmlWriter struct {
+	buf      *bufio.Writer
+	currency string
+	h        Header
+	totals   Totals
+}
+
+func newHTMLWriter(w io.Writer, currency string) *htmlWriter {
+	if currency == "" {
+		currency = "RUB"
+	}
+	return &htmlWriter{buf: bufio.NewWriter(w), currency: currency}
+}
+
+func (w *htmlWriter) Begin(h Header) error {
+	w.h = h
+	w.totals = Totals{}
+	return htmlTemplate.ExecuteTemplate(w.buf, "head", map[string]string{
+		"Wallet":         h.ValletId.UUID.String(),
+		"From":           formatTime(h.From),
+		"To":             formatTime(h.To),
+		"Currency":       w.currency,
+		"OpeningBalance": formatAmount(h.OpeningBalance),
+	})
+}
+
+func (w *htmlWriter) Transaction(WT wallet.WalletTransactions) error {
+	w.totals.add(WT)
+	return htmlTemplate.ExecuteTemplate(w.buf, "row", map[string]any{
+		"Date":    formatTime(WT.CreatedAt),
+		"Seq":     WT.Seq,
+		"Id":      WT.Id,
+		"Type":    WT.OperationType,
+		"Amount":  formatAmount(signedAmount(WT)),
+		"Balance": formatAmount(WT.BalanceAfter),
+	})
+}
+
+func (w *htmlWriter) End(closingBalance float64) error {
+	err := htmlTemplate.ExecuteTemplate(w.buf, "foot", map[string]any{
+		"Totals":         w.totals,
+		"DepositsSum":    formatAmount(w.totals.Deposits.Sum),
+		"WithdrawalsSum": formatAmount(w.totals.Withdrawals.Sum),
+		"ClosingBalance": formatAmount(closingBalance),
+	})
+	if err != nil {
+		return err
+	}
+	return w.buf.Flush()
+}
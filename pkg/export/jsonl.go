@@ -0,0 +1,60 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// jsonlLine - строка JSON Lines. Суммы - строки с двумя знаками после точки, чтобы не терять копейки
+// при разборе в числа с плавающей точкой.
+type jsonlLine struct {
+	Type          string     `json:"type"`
+	ValletId      *uuid.UUID `json:"valletId,omitempty"`
+	Date          time.Time  `json:"date"`
+	Seq           int64      `json:"seq,omitempty"`
+	TransactionID int        `json:"transactionId,omitempty"`
+	OperationType string     `json:"operationType,omitempty"`
+	Amount        string     `json:"amount,omitempty"`
+	Balance       string     `json:"balance"`
+}
+
+type jsonlWriter struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+	id  uuid.UUID
+	to  time.Time
+}
+
+func newJSONLWriter(w io.Writer) *jsonlWriter {
+	buf := bufio.NewWriter(w)
+	return &jsonlWriter{buf: buf, enc: json.NewEncoder(buf)}
+}
+
+func (w *jsonlWriter) Begin(h Header) error {
+	w.id, w.to = h.ValletId, h.To
+	return w.enc.Encode(jsonlLine{Type: rowOpeningBalance, ValletId: &w.id, Date: h.From.UTC(), Balance: formatAmount(h.OpeningBalance)})
+}
+
+func (w *jsonlWriter) Transaction(WT wallet.WalletTransactions) error {
+	return w.enc.Encode(jsonlLine{
+		Type:          rowTransaction,
+		Date:          WT.CreatedAt.UTC(),
+		Seq:           WT.Seq,
+		TransactionID: WT.Id,
+		OperationType: WT.OperationType,
+		Amount:        formatAmount(signedAmount(WT)),
+		Balance:       formatAmount(WT.BalanceAfter),
+	})
+}
+
+func (w *jsonlWriter) End(closingBalance float64) error {
+	if err := w.enc.Encode(jsonlLine{Type: rowClosingBalance, ValletId: &w.id, Date: w.to.UTC(), Balance: formatAmount(closingBalance)}); err != nil {
+		return err
+	}
+	return w.buf.Flush()
+}
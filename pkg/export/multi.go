@@ -0,0 +1,71 @@
+package export
+
+import "github.com/KatenkaKet/wallet"
+
+// Totals - Writer, который ничего не пишет, а считает количество и сумму операций каждого типа
+// и запоминает начальный и конечный баланс.
+type Totals struct {
+	OpeningBalance float64
+	ClosingBalance float64
+	Deposits       wallet.StatementTotal
+	Withdrawals    wallet.StatementTotal
+}
+
+func (t *Totals) Begin(h Header) error {
+	*t = Totals{OpeningBalance: h.OpeningBalance}
+	return nil
+}
+
+func (t *Totals) Transaction(WT wallet.WalletTransactions) error {
+	t.add(WT)
+	return nil
+}
+
+func (t *Totals) End(closingBalance float64) error {
+	t.ClosingBalance = closingBalance
+	return nil
+}
+
+func (t *Totals) add(WT wallet.WalletTransactions) {
+	total := &t.Deposits
+	if WT.OperationType == "WITHDRAW" {
+		total = &t.Withdrawals
+	}
+	total.Count++
+	total.Sum += WT.Amount
+}
+
+type multiWriter []Writer
+
+// MultiWriter возвращает Writer, передающий каждую строку всем writers по очереди.
+// На первой ошибке запись прекращается и ошибка возвращается.
+func MultiWriter(writers ...Writer) Writer {
+	return multiWriter(append([]Writer(nil), writers...))
+}
+
+func (m multiWriter) Begin(h Header) error {
+	for _, w := range m {
+		if err := w.Begin(h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m multiWriter) Transaction(WT wallet.WalletTransactions) error {
+	for _, w := range m {
+		if err := w.Transaction(WT); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m multiWriter) End(closingBalance float64) error {
+	for _, w := range m {
+		if err := w.End(closingBalance); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+)
+
+// ofxWriter пишет выписку OFX 2.2 (XML). Кошелёк оформляется как банковский счёт с BANKID "WALLET" и ACCTID - ID кошелька.
+// Конечный баланс - LEDGERBAL; у OFX нет отдельного элемента для начального баланса, поэтому он идёт в BALLIST.
+type ofxWriter struct {
+	buf      *bufio.Writer
+	currency string
+	h        Header
+	now      func() time.Time
+}
+
+func newOFXWriter(w io.Writer, currency string) *ofxWriter {
+	if currency == "" {
+		currency = "RUB"
+	}
+	return &ofxWriter{buf: bufio.NewWriter(w), currency: currency, now: time.Now}
+}
+
+func (w *ofxWriter) Begin(h Header) error {
+	w.h = h
+
+	_, err := fmt.Fprintf(w.buf, `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS><DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE></SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1><STMTTRNRS><TRNUID>0</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>
+<STMTRS><CURDEF>%s</CURDEF>
+<BANKACCTFROM><BANKID>WALLET</BANKID><ACCTID>%s</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>
+<BANKTRANLIST><DTSTART>%s</DTSTART><DTEND>%s</DTEND>
+`, ofxTime(w.now()), w.currency, h.ValletId.UUID.String(), ofxTime(h.From), ofxTime(h.To))
+	return err
+}
+
+func (w *ofxWriter) Transaction(WT wallet.WalletTransactions) error {
+	trnType, name := "CREDIT", "Deposit"
+	if WT.OperationType == "WITHDRAW" {
+		trnType, name = "DEBIT", "Withdrawal"
+	}
+
+	_, err := fmt.Fprintf(w.buf, "<STMTTRN><TRNTYPE>%s</TRNTYPE><DTPOSTED>%s</DTPOSTED><TRNAMT>%s</TRNAMT><FITID>%s</FITID><NAME>%s</NAME><MEMO>seq %d</MEMO></STMTTRN>\n",
+		trnType, ofxTime(WT.CreatedAt), formatAmount(signedAmount(WT)), strconv.Itoa(WT.Id), name, WT.Seq)
+	return err
+}
+
+func (w *ofxWriter) End(closingBalance float64) error {
+	_, err := fmt.Fprintf(w.buf, `</BANKTRANLIST>
+<LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>
+<BALLIST><BAL><NAME>Opening balance</NAME><DESC>Balance at the start of the period</DESC><BALTYPE>DOLLAR</BALTYPE><VALUE>%s</VALUE><DTASOF>%s</DTASOF></BAL></BALLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`, formatAmount(closingBalance), ofxTime(w.h.To), formatAmount(w.h.OpeningBalance), ofxTime(w.h.From))
+	if err != nil {
+		return err
+	}
+	return w.buf.Flush()
+}
+
+// ofxTime - дата OFX в UTC: YYYYMMDDHHMMSS.XXX[0:GMT].
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405.000") + "[0:GMT]"
+}
@@ -0,0 +1,116 @@
+// Package fee - расписание комиссий за операции: фиксированная часть, процент от суммы и ограничения
+// снизу и сверху, заданные по уровню (tier) кошелька и виду операции.
+package fee
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+var ErrInvalidSchedule = errors.New("invalid fee schedule")
+
+// Виды операций, за которые берётся комиссия.
+const (
+	Withdraw = "WITHDRAW"
+	Transfer = "TRANSFER"
+)
+
+// Rule - комиссия за операцию Operation с кошелька уровня Tier (пустой Tier - любого уровня):
+// Fixed + Percent% от суммы, но не меньше Min и, если Max больше нуля, не больше Max.
+type Rule struct {
+	Tier      string  `json:"tier"`
+	Operation string  `json:"operation"`
+	Fixed     float64 `json:"fixed"`
+	Percent   float64 `json:"percent"`
+	Min       float64 `json:"min"`
+	Max       float64 `json:"max"`
+}
+
+// Schedule - набор правил. Для операции выбирается правило её уровня, а если его нет - правило без уровня.
+// Нулевой (nil) Schedule комиссий не берёт.
+type Schedule struct {
+	rules map[[2]string]Rule // (уровень, операция) -> правило
+}
+
+// New проверяет правила и собирает из них расписание.
+func New(rules []Rule) (*Schedule, error) {
+	s := &Schedule{rules: make(map[[2]string]Rule, len(rules))}
+	for i, r := range rules {
+		if r.Operation != Withdraw && r.Operation != Transfer {
+			return nil, fmt.Errorf("%w: rule %d: operation must be %s or %s", ErrInvalidSchedule, i+1, Withdraw, Transfer)
+		}
+		for _, v := range []float64{r.Fixed, r.Percent, r.Min, r.Max} {
+			if v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+				return nil, fmt.Errorf("%w: rule %d: amounts must be non-negative", ErrInvalidSchedule, i+1)
+			}
+		}
+		if r.Percent > 100 {
+			return nil, fmt.Errorf("%w: rule %d: percent must not exceed 100", ErrInvalidSchedule, i+1)
+		}
+		if r.Max > 0 && r.Max < r.Min {
+			return nil, fmt.Errorf("%w: rule %d: max must not be less than min", ErrInvalidSchedule, i+1)
+		}
+
+		key := [2]string{r.Tier, r.Operation}
+		if _, ok := s.rules[key]; ok {
+			return nil, fmt.Errorf("%w: rule %d: duplicate rule for tier %q and operation %s",
+				ErrInvalidSchedule, i+1, r.Tier, r.Operation)
+		}
+		s.rules[key] = r
+	}
+	return s, nil
+}
+
+// Load читает расписание из JSON-файла вида {"rules": [{"tier": "standard", "operation": "WITHDRAW", "percent": 1}]}.
+func Load(path string) (*Schedule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fee schedule: %w", err)
+	}
+
+	var file struct {
+		Rules []Rule `json:"rules"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSchedule, err.Error())
+	}
+	return New(file.Rules)
+}
+
+// Charges сообщает, есть ли в расписании правила для операции; без них уровень кошелька можно не запрашивать.
+func (s *Schedule) Charges(operation string) bool {
+	if s == nil {
+		return false
+	}
+	for key := range s.rules {
+		if key[1] == operation {
+			return true
+		}
+	}
+	return false
+}
+
+// Fee возвращает комиссию за операцию на сумму amount с кошелька уровня tier, округлённую до копеек.
+func (s *Schedule) Fee(tier, operation string, amount float64) float64 {
+	if s == nil {
+		return 0
+	}
+	r, ok := s.rules[[2]string{tier, operation}]
+	if !ok {
+		if r, ok = s.rules[[2]string{"", operation}]; !ok {
+			return 0
+		}
+	}
+
+	fee := r.Fixed + amount*r.Percent/100
+	if fee < r.Min {
+		fee = r.Min
+	}
+	if r.Max > 0 && fee > r.Max {
+		fee = r.Max
+	}
+	return math.Round(fee*100) / 100
+}
@@ -0,0 +1,73 @@
+package fee
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule_Fee(t *testing.T) {
+	s, err := New([]Rule{
+		{Operation: Withdraw, Fixed: 10},
+		{Tier: "standard", Operation: Withdraw, Fixed: 5, Percent: 1, Max: 50},
+		{Tier: "standard", Operation: Transfer, Percent: 0.5, Min: 1},
+		{Tier: "premium", Operation: Withdraw},
+	})
+	require.NoError(t, err)
+
+	testTable := []struct {
+		name      string
+		tier      string
+		operation string
+		amount    float64
+		expected  float64
+	}{
+		{name: "fixed and percent", tier: "standard", operation: Withdraw, amount: 1000, expected: 15},
+		{name: "max cap", tier: "standard", operation: Withdraw, amount: 10000, expected: 50},
+		{name: "min cap", tier: "standard", operation: Transfer, amount: 10, expected: 1},
+		{name: "rounded to cents", tier: "standard", operation: Transfer, amount: 333.33, expected: 1.67},
+		{name: "tier rule without fee", tier: "premium", operation: Withdraw, amount: 1000, expected: 0},
+		{name: "fallback to any tier", tier: "business", operation: Withdraw, amount: 1000, expected: 10},
+		{name: "no rule", tier: "premium", operation: Transfer, amount: 1000, expected: 0},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, s.Fee(test.tier, test.operation, test.amount))
+		})
+	}
+
+	assert.True(t, s.Charges(Transfer))
+	var none *Schedule
+	assert.False(t, none.Charges(Withdraw))
+	assert.Zero(t, none.Fee("standard", Withdraw, 100))
+}
+
+func TestNew_Invalid(t *testing.T) {
+	for name, rules := range map[string][]Rule{
+		"unknown operation": {{Operation: "DEPOSIT", Fixed: 1}},
+		"negative amount":   {{Operation: Withdraw, Fixed: -1}},
+		"percent over 100":  {{Operation: Withdraw, Percent: 101}},
+		"max below min":     {{Operation: Withdraw, Min: 5, Max: 1}},
+		"duplicate":         {{Operation: Withdraw, Fixed: 1}, {Operation: Withdraw, Fixed: 2}},
+	} {
+		_, err := New(rules)
+		assert.ErrorIs(t, err, ErrInvalidSchedule, name)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fees.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"rules": [{"tier": "standard", "operation": "WITHDRAW", "percent": 2}]}`), 0o600))
+
+	s, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, s.Fee("standard", Withdraw, 100))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"rules": {}}`), 0o600))
+	_, err = Load(path)
+	assert.ErrorIs(t, err, ErrInvalidSchedule)
+}
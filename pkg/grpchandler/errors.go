@@ -0,0 +1,42 @@
+package grpchandler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	"github.com/KatenkaKet/wallet/pkg/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorStatus переводит ошибку сервиса в статус gRPC (аналог errorStatus в pkg/handler).
+func errorStatus(err error) error {
+	return status.Error(errorCode(err), err.Error())
+}
+
+func errorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, repository.ErrWalletNotFound):
+		return codes.NotFound
+	case errors.Is(err, repository.ErrInsufficientFunds),
+		errors.Is(err, repository.ErrCreditLimitBelowDebt):
+		return codes.FailedPrecondition
+	case errors.Is(err, repository.ErrDuplicateTransaction):
+		return codes.AlreadyExists
+	case errors.Is(err, repository.ErrVersionMismatch):
+		// Как при несовпадении etag: клиент перечитывает кошелёк и повторяет операцию.
+		return codes.Aborted
+	case errors.Is(err, service.ErrInvalidTransfer),
+		errors.Is(err, service.ErrInvalidTier),
+		errors.Is(err, service.ErrInvalidCreditLimit),
+		errors.Is(err, repository.ErrInvalidBucket):
+		return codes.InvalidArgument
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	default:
+		return codes.Internal
+	}
+}
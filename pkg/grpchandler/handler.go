@@ -0,0 +1,161 @@
+package grpchandler
+
+import (
+	"context"
+	"strings"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/service"
+	"github.com/KatenkaKet/wallet/pkg/walletpb"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+// Handler реализует walletpb.WalletServiceServer поверх service.Service, как pkg/handler - REST API.
+type Handler struct {
+	walletpb.UnimplementedWalletServiceServer
+
+	service *service.Service
+}
+
+func NewHandler(service *service.Service) *Handler {
+	return &Handler{service: service}
+}
+
+// Register регистрирует API кошельков на gRPC-сервере.
+func (h *Handler) Register(s *grpc.Server) {
+	walletpb.RegisterWalletServiceServer(s, h)
+}
+
+func (h *Handler) GetBalance(ctx context.Context, req *walletpb.GetBalanceRequest) (*walletpb.GetBalanceResponse, error) {
+	walletID, err := parseWalletID(req.GetWalletId())
+	if err != nil {
+		return nil, err
+	}
+
+	wlt, err := h.service.Wallet.GetWallet(ctx, walletID)
+	if err != nil {
+		return nil, errorStatus(err)
+	}
+
+	return &walletpb.GetBalanceResponse{
+		WalletId: wlt.ValletId.UUID.String(),
+		Balance:  wlt.Balance,
+		Version:  wlt.Version,
+		LastSeq:  wlt.LastSeq,
+	}, nil
+}
+
+func (h *Handler) Deposit(ctx context.Context, req *walletpb.OperationRequest) (*walletpb.OperationResponse, error) {
+	return h.updateBalance(ctx, "DEPOSIT", req)
+}
+
+func (h *Handler) Withdraw(ctx context.Context, req *walletpb.OperationRequest) (*walletpb.OperationResponse, error) {
+	return h.updateBalance(ctx, "WITHDRAW", req)
+}
+
+func (h *Handler) updateBalance(ctx context.Context, operationType string, req *walletpb.OperationRequest) (*walletpb.OperationResponse, error) {
+	walletID, err := parseWalletID(req.GetWalletId())
+	if err != nil {
+		return nil, err
+	}
+	if req.GetAmount() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "amount must be positive")
+	}
+	if req.GetExpectedVersion() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid expected version")
+	}
+
+	recorded, err := h.service.Wallet.UpdateBalance(ctx, wallet.WalletTransactions{
+		ValletId:        walletID,
+		OperationType:   operationType,
+		Amount:          req.GetAmount(),
+		ExpectedVersion: req.GetExpectedVersion(),
+	})
+	if err != nil {
+		return nil, errorStatus(err)
+	}
+
+	return &walletpb.OperationResponse{Transaction: toTransaction(recorded)}, nil
+}
+
+func (h *Handler) ListTransactions(ctx context.Context, req *walletpb.ListTransactionsRequest) (*walletpb.ListTransactionsResponse, error) {
+	walletID, err := parseWalletID(req.GetWalletId())
+	if err != nil {
+		return nil, err
+	}
+	if req.GetAfterSeq() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid after_seq")
+	}
+
+	limit := int(req.GetLimit())
+	if limit == 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit < 0 || limit > maxHistoryLimit {
+		return nil, status.Errorf(codes.InvalidArgument, "limit must be between 1 and %d", maxHistoryLimit)
+	}
+
+	history, err := h.service.Wallet.ListTransactions(ctx, walletID, req.GetAfterSeq(), limit)
+	if err != nil {
+		return nil, errorStatus(err)
+	}
+
+	resp := &walletpb.ListTransactionsResponse{Transactions: make([]*walletpb.Transaction, 0, len(history))}
+	for _, WT := range history {
+		resp.Transactions = append(resp.Transactions, toTransaction(WT))
+	}
+	return resp, nil
+}
+
+func (h *Handler) Transfer(ctx context.Context, req *walletpb.TransferRequest) (*walletpb.TransferResponse, error) {
+	from, err := parseWalletID(req.GetFromWalletId())
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseWalletID(req.GetToWalletId())
+	if err != nil {
+		return nil, err
+	}
+
+	transfer, err := h.service.Wallet.Transfer(ctx, from, to, req.GetAmount())
+	if err != nil {
+		return nil, errorStatus(err)
+	}
+
+	resp := &walletpb.TransferResponse{From: toTransaction(transfer.From), To: toTransaction(transfer.To)}
+	if transfer.Fee != nil {
+		resp.Fee = toTransaction(*transfer.Fee)
+	}
+	return resp, nil
+}
+
+func parseWalletID(s string) (uuid.UUID, error) {
+	var walletID uuid.UUID
+	if err := walletID.Scan(strings.TrimSpace(s)); err != nil {
+		return walletID, status.Error(codes.InvalidArgument, "invalid wallet id")
+	}
+	return walletID, nil
+}
+
+func toTransaction(WT wallet.WalletTransactions) *walletpb.Transaction {
+	return &walletpb.Transaction{
+		Id:            int64(WT.Id),
+		WalletId:      WT.ValletId.UUID.String(),
+		OperationType: WT.OperationType,
+		Amount:        WT.Amount,
+		Version:       WT.Version,
+		Seq:           WT.Seq,
+		BalanceAfter:  WT.BalanceAfter,
+		Hash:          WT.Hash,
+		CreatedAt:     timestamppb.New(WT.CreatedAt),
+	}
+}
@@ -0,0 +1,294 @@
+package grpchandler
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	"github.com/KatenkaKet/wallet/pkg/service"
+	mock_service "github.com/KatenkaKet/wallet/pkg/service/mocks"
+	"github.com/KatenkaKet/wallet/pkg/walletpb"
+	"github.com/golang/mock/gomock"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	walletA = "11111111-1111-1111-1111-111111111111"
+	walletB = "22222222-2222-2222-2222-222222222222"
+)
+
+func uuidFromString(s string) uuid.UUID {
+	var id uuid.UUID
+	if err := id.Scan(s); err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// newClient поднимает gRPC-сервер в памяти с Handler поверх mockWallet.
+func newClient(t *testing.T, mockWallet service.Wallet) walletpb.WalletServiceClient {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	NewHandler(&service.Service{Wallet: mockWallet}).Register(srv)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return walletpb.NewWalletServiceClient(conn)
+}
+
+func TestHandler_GetBalance(t *testing.T) {
+	testTable := []struct {
+		name         string
+		walletID     string
+		mockBehavior func(s *mock_service.MockWallet)
+		expectedCode codes.Code
+	}{
+		{
+			name:     "ok",
+			walletID: walletA,
+			mockBehavior: func(s *mock_service.MockWallet) {
+				s.EXPECT().GetWallet(gomock.Any(), uuidFromString(walletA)).
+					Return(wallet.Wallet{ValletId: uuidFromString(walletA), Balance: 10.5, Version: 3, LastSeq: 2}, nil)
+			},
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "invalid wallet id",
+			walletID:     "nope",
+			mockBehavior: func(s *mock_service.MockWallet) {},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:     "not found",
+			walletID: walletA,
+			mockBehavior: func(s *mock_service.MockWallet) {
+				s.EXPECT().GetWallet(gomock.Any(), gomock.Any()).
+					Return(wallet.Wallet{}, fmt.Errorf("failed to get wallet: %w", repository.ErrWalletNotFound))
+			},
+			expectedCode: codes.NotFound,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockWallet := mock_service.NewMockWallet(ctrl)
+			test.mockBehavior(mockWallet)
+			client := newClient(t, mockWallet)
+
+			resp, err := client.GetBalance(context.Background(), &walletpb.GetBalanceRequest{WalletId: test.walletID})
+
+			assert.Equal(t, test.expectedCode, status.Code(err))
+			if test.expectedCode == codes.OK {
+				assert.Equal(t, walletA, resp.GetWalletId())
+				assert.Equal(t, 10.5, resp.GetBalance())
+				assert.Equal(t, int64(3), resp.GetVersion())
+				assert.Equal(t, int64(2), resp.GetLastSeq())
+			}
+		})
+	}
+}
+
+func TestHandler_Withdraw(t *testing.T) {
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	testTable := []struct {
+		name         string
+		req          *walletpb.OperationRequest
+		mockBehavior func(s *mock_service.MockWallet)
+		expectedCode codes.Code
+	}{
+		{
+			name: "ok",
+			req:  &walletpb.OperationRequest{WalletId: walletA, Amount: 4.5, ExpectedVersion: 3},
+			mockBehavior: func(s *mock_service.MockWallet) {
+				s.EXPECT().UpdateBalance(gomock.Any(), wallet.WalletTransactions{
+					ValletId: uuidFromString(walletA), OperationType: "WITHDRAW", Amount: 4.5, ExpectedVersion: 3,
+				}).Return(wallet.WalletTransactions{
+					Id: 7, ValletId: uuidFromString(walletA), OperationType: "WITHDRAW", Amount: 4.5,
+					Version: 4, Seq: 3, BalanceAfter: 6, Hash: "abc", CreatedAt: createdAt,
+				}, nil)
+			},
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "non-positive amount",
+			req:          &walletpb.OperationRequest{WalletId: walletA, Amount: 0},
+			mockBehavior: func(s *mock_service.MockWallet) {},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "insufficient funds",
+			req:  &walletpb.OperationRequest{WalletId: walletA, Amount: 100},
+			mockBehavior: func(s *mock_service.MockWallet) {
+				s.EXPECT().UpdateBalance(gomock.Any(), gomock.Any()).Return(wallet.WalletTransactions{}, repository.ErrInsufficientFunds)
+			},
+			expectedCode: codes.FailedPrecondition,
+		},
+		{
+			name: "version mismatch",
+			req:  &walletpb.OperationRequest{WalletId: walletA, Amount: 1, ExpectedVersion: 2},
+			mockBehavior: func(s *mock_service.MockWallet) {
+				s.EXPECT().UpdateBalance(gomock.Any(), gomock.Any()).Return(wallet.WalletTransactions{}, repository.ErrVersionMismatch)
+			},
+			expectedCode: codes.Aborted,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockWallet := mock_service.NewMockWallet(ctrl)
+			test.mockBehavior(mockWallet)
+			client := newClient(t, mockWallet)
+
+			resp, err := client.Withdraw(context.Background(), test.req)
+
+			assert.Equal(t, test.expectedCode, status.Code(err))
+			if test.expectedCode == codes.OK {
+				tx := resp.GetTransaction()
+				assert.Equal(t, int64(7), tx.GetId())
+				assert.Equal(t, walletA, tx.GetWalletId())
+				assert.Equal(t, "WITHDRAW", tx.GetOperationType())
+				assert.Equal(t, int64(4), tx.GetVersion())
+				assert.Equal(t, int64(3), tx.GetSeq())
+				assert.Equal(t, 6.0, tx.GetBalanceAfter())
+				assert.Equal(t, "abc", tx.GetHash())
+				assert.Equal(t, createdAt, tx.GetCreatedAt().AsTime())
+			}
+		})
+	}
+}
+
+func TestHandler_ListTransactions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockWallet := mock_service.NewMockWallet(ctrl)
+	mockWallet.EXPECT().ListTransactions(gomock.Any(), uuidFromString(walletA), int64(1), defaultHistoryLimit).
+		Return([]wallet.WalletTransactions{
+			{Id: 2, ValletId: uuidFromString(walletA), OperationType: "DEPOSIT", Amount: 1, Seq: 2, BalanceAfter: 2},
+			{Id: 3, ValletId: uuidFromString(walletA), OperationType: "WITHDRAW", Amount: 1, Seq: 3, BalanceAfter: 1},
+		}, nil)
+	client := newClient(t, mockWallet)
+
+	resp, err := client.ListTransactions(context.Background(), &walletpb.ListTransactionsRequest{WalletId: walletA, AfterSeq: 1})
+	require.NoError(t, err)
+	require.Len(t, resp.GetTransactions(), 2)
+	assert.Equal(t, int64(2), resp.GetTransactions()[0].GetSeq())
+	assert.Equal(t, int64(3), resp.GetTransactions()[1].GetSeq())
+
+	_, err = client.ListTransactions(context.Background(), &walletpb.ListTransactionsRequest{WalletId: walletA, Limit: maxHistoryLimit + 1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestHandler_Transfer(t *testing.T) {
+	testTable := []struct {
+		name         string
+		req          *walletpb.TransferRequest
+		mockBehavior func(s *mock_service.MockWallet)
+		expectedCode codes.Code
+	}{
+		{
+			name: "ok",
+			req:  &walletpb.TransferRequest{FromWalletId: walletA, ToWalletId: walletB, Amount: 5},
+			mockBehavior: func(s *mock_service.MockWallet) {
+				s.EXPECT().Transfer(gomock.Any(), uuidFromString(walletA), uuidFromString(walletB), 5.0).Return(wallet.Transfer{
+					From: wallet.WalletTransactions{Id: 1, ValletId: uuidFromString(walletA), OperationType: "WITHDRAW", Amount: 5, BalanceAfter: 5},
+					To:   wallet.WalletTransactions{Id: 2, ValletId: uuidFromString(walletB), OperationType: "DEPOSIT", Amount: 5, BalanceAfter: 15},
+					Fee:  &wallet.WalletTransactions{Id: 3, ValletId: uuidFromString(walletA), OperationType: "WITHDRAW", Amount: 0.5, BalanceAfter: 4.5},
+				}, nil)
+			},
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "invalid destination",
+			req:          &walletpb.TransferRequest{FromWalletId: walletA, ToWalletId: "nope", Amount: 5},
+			mockBehavior: func(s *mock_service.MockWallet) {},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "invalid transfer",
+			req:  &walletpb.TransferRequest{FromWalletId: walletA, ToWalletId: walletA, Amount: 5},
+			mockBehavior: func(s *mock_service.MockWallet) {
+				s.EXPECT().Transfer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(wallet.Transfer{}, fmt.Errorf("%w: source and destination wallets must differ", service.ErrInvalidTransfer))
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "insufficient funds",
+			req:  &walletpb.TransferRequest{FromWalletId: walletA, ToWalletId: walletB, Amount: 500},
+			mockBehavior: func(s *mock_service.MockWallet) {
+				s.EXPECT().Transfer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(wallet.Transfer{}, repository.ErrInsufficientFunds)
+			},
+			expectedCode: codes.FailedPrecondition,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockWallet := mock_service.NewMockWallet(ctrl)
+			test.mockBehavior(mockWallet)
+			client := newClient(t, mockWallet)
+
+			resp, err := client.Transfer(context.Background(), test.req)
+
+			assert.Equal(t, test.expectedCode, status.Code(err))
+			if test.expectedCode == codes.OK {
+				assert.Equal(t, walletA, resp.GetFrom().GetWalletId())
+				assert.Equal(t, 5.0, resp.GetFrom().GetBalanceAfter())
+				assert.Equal(t, walletB, resp.GetTo().GetWalletId())
+				assert.Equal(t, 15.0, resp.GetTo().GetBalanceAfter())
+				assert.Equal(t, 0.5, resp.GetFee().GetAmount())
+				assert.Equal(t, 4.5, resp.GetFee().GetBalanceAfter())
+			}
+		})
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	testTable := []struct {
+		err          error
+		expectedCode codes.Code
+	}{
+		{err: fmt.Errorf("get wallet: %w", repository.ErrWalletNotFound), expectedCode: codes.NotFound},
+		{err: repository.ErrInvalidBucket, expectedCode: codes.InvalidArgument},
+		{err: service.ErrInvalidTier, expectedCode: codes.InvalidArgument},
+		{err: service.ErrInvalidCreditLimit, expectedCode: codes.InvalidArgument},
+		{err: repository.ErrCreditLimitBelowDebt, expectedCode: codes.FailedPrecondition},
+		{err: repository.ErrDuplicateTransaction, expectedCode: codes.AlreadyExists},
+		{err: fmt.Errorf("connection refused"), expectedCode: codes.Internal},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.err.Error(), func(t *testing.T) {
+			assert.Equal(t, test.expectedCode, errorCode(test.err))
+		})
+	}
+}
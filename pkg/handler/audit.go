@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// verifyWalletChain godoc
+// @Summary Проверить цепочку хешей истории кошелька
+// @Description Проходит историю кошелька по порядку seq, пересчитывает хеши и сверяет их с подписанными контрольными точками.
+// @Description Если цепочка нарушена, в ответе valid=false, brokenSeq - номер первого нарушенного звена, reason - причина.
+// @Tags audit
+// @Produce json
+// @Param id path string true "ID кошелька"
+// @Success 200 {object} wallet.ChainReport
+// @Failure 400 {object} map[string]string "Неверный ID"
+// @Failure 404 {object} map[string]string "Кошелёк не найден"
+// @Failure 500 {object} map[string]string "Ошибка сервера"
+// @Router /wallets/{id}/verify [get]
+func (h *Handler) verifyWalletChain(c *gin.Context) {
+	var walletID uuid.UUID
+	if err := walletID.Scan(strings.TrimSpace(c.Param("id"))); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet id"})
+		return
+	}
+
+	report, err := h.service.Audit.VerifyChain(c.Request.Context(), walletID)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
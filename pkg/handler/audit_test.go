@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	"github.com/KatenkaKet/wallet/pkg/service"
+	mock_service "github.com/KatenkaKet/wallet/pkg/service/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestHandler_verifyWalletChain(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockAudit, walletID uuid.UUID)
+
+	walletID := uuidFromString("11111111-1111-1111-1111-111111111111")
+
+	testTable := []struct {
+		name         string
+		id           string
+		mockBehavior mockBehavior
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "valid chain",
+			id:   walletID.UUID.String(),
+			mockBehavior: func(s *mock_service.MockAudit, walletID uuid.UUID) {
+				s.EXPECT().VerifyChain(gomock.Any(), walletID).Return(wallet.ChainReport{
+					ValletId: walletID, Valid: true, Checked: 2, Head: "ab", Checkpoints: 1,
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"valletId":"11111111-1111-1111-1111-111111111111","valid":true,"checked":2,"head":"ab","checkpoints":1}`,
+		},
+		{
+			name: "broken chain",
+			id:   walletID.UUID.String(),
+			mockBehavior: func(s *mock_service.MockAudit, walletID uuid.UUID) {
+				s.EXPECT().VerifyChain(gomock.Any(), walletID).Return(wallet.ChainReport{
+					ValletId: walletID, Checked: 1, Head: "ab", BrokenSeq: 2, Reason: "hash mismatch",
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"valletId":"11111111-1111-1111-1111-111111111111","valid":false,"checked":1,"head":"ab","checkpoints":0,` +
+				`"brokenSeq":2,"reason":"hash mismatch"}`,
+		},
+		{
+			name: "wallet not found",
+			id:   walletID.UUID.String(),
+			mockBehavior: func(s *mock_service.MockAudit, walletID uuid.UUID) {
+				s.EXPECT().VerifyChain(gomock.Any(), walletID).Return(wallet.ChainReport{}, repository.ErrWalletNotFound)
+			},
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"error":"wallet not found"}`,
+		},
+		{
+			name:         "invalid id",
+			id:           "not-a-uuid",
+			mockBehavior: func(s *mock_service.MockAudit, walletID uuid.UUID) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid wallet id"}`,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAudit := mock_service.NewMockAudit(ctrl)
+			test.mockBehavior(mockAudit, walletID)
+
+			h := NewHandler(&service.Service{Audit: mockAudit}, Config{})
+
+			r := gin.New()
+			r.GET("/api/v1/wallets/:id/verify", h.verifyWalletChain)
+
+			req := httptest.NewRequest("GET", "/api/v1/wallets/"+test.id+"/verify", nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.Equal(t, test.expectedBody, w.Body.String())
+		})
+	}
+}
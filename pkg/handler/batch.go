@@ -0,0 +1,300 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/service"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+const (
+	batchModeAtomic     = "atomic"
+	batchModeBestEffort = "best-effort"
+
+	defaultBatchMaxItems       = 1000
+	defaultBatchMaxStreamItems = 100000
+	// batchChunk - сколько операций потока NDJSON применяется за раз.
+	batchChunk = 500
+	// batchMaxLine - максимальная длина строки NDJSON.
+	batchMaxLine = 64 * 1024
+)
+
+var errBatchEnd = errors.New("end of batch")
+
+type batchResponse struct {
+	Mode       string               `json:"mode"`
+	Applied    int                  `json:"applied"`
+	Duplicates int                  `json:"duplicates"`
+	Rejected   int                  `json:"rejected"`
+	Results    []wallet.BatchResult `json:"results"`
+}
+
+// createWalletBatch godoc
+// @Summary Пакет операций по многим кошелькам
+// @Description Тело - JSON-массив операций или поток NDJSON (Content-Type: application/x-ndjson), по операции в строке.
+// @Description mode=atomic (по умолчанию): все операции в одной транзакции БД, кошельки блокируются в порядке ID;
+// @Description при любом отказе не применяется ничего (422), остальные операции получают статус aborted.
+// @Description mode=best-effort: операции применяются независимо, у каждой свой результат.
+// @Description Поток NDJSON в режиме best-effort применяется частями по мере чтения, а результаты возвращаются
+// @Description также потоком NDJSON по строке на операцию; при превышении лимита последней строкой идёт {"error": ...}.
+// @Description Повтор операции с тем же idempotencyKey для того же кошелька не применяется (статус duplicate).
+// @Tags wallet
+// @Accept json
+// @Accept application/x-ndjson
+// @Produce json
+// @Produce application/x-ndjson
+// @Param mode query string false "atomic или best-effort" default(atomic)
+// @Param transactions body []wallet.WalletTransactions true "Операции"
+// @Success 200 {object} batchResponse "Результаты операций"
+// @Failure 400 {object} map[string]string "Неверный запрос"
+// @Failure 413 {object} map[string]string "Слишком много операций"
+// @Failure 422 {object} batchResponse "Пакет atomic не применён"
+// @Failure 500 {object} map[string]string "Ошибка сервера"
+// @Router /wallets/batch [post]
+func (h *Handler) createWalletBatch(c *gin.Context) {
+	mode := c.DefaultQuery("mode", batchModeAtomic)
+	if mode != batchModeAtomic && mode != batchModeBestEffort {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be atomic or best-effort"})
+		return
+	}
+
+	next, err := batchReader(c.Request.Body, c.ContentType())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if mode == batchModeBestEffort && isNDJSON(c.ContentType()) {
+		h.streamWalletBatch(c, next)
+		return
+	}
+
+	var (
+		results = make([]wallet.BatchResult, 0)
+		ops     []wallet.WalletTransactions
+		index   []int // позиции ops в запросе
+		invalid bool
+	)
+	for i := 0; ; i++ {
+		raw, err := next()
+		if errors.Is(err, errBatchEnd) {
+			break
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if i >= h.batchMaxItems() {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("batch exceeds %d transactions", h.batchMaxItems())})
+			return
+		}
+
+		WT, err := decodeBatchItem(raw)
+		if err != nil {
+			results = append(results, wallet.BatchResult{Index: i, Status: wallet.BatchRejected, Error: err.Error()})
+			invalid = true
+			continue
+		}
+		results = append(results, wallet.BatchResult{Index: i})
+		ops = append(ops, WT)
+		index = append(index, i)
+	}
+
+	atomic := mode == batchModeAtomic
+	if atomic && invalid {
+		// Пакет с ошибочной операцией не применяется целиком.
+		for _, i := range index {
+			results[i].Status = wallet.BatchAborted
+		}
+		c.JSON(http.StatusUnprocessableEntity, newBatchResponse(mode, results))
+		return
+	}
+
+	var applied []wallet.BatchResult
+	if len(ops) > 0 {
+		applied, err = h.service.Wallet.ApplyBatch(c.Request.Context(), ops, atomic)
+		if err != nil && !errors.Is(err, service.ErrBatchAborted) {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+	}
+	for j, res := range applied {
+		res.Index = index[j]
+		results[index[j]] = res
+	}
+
+	status := http.StatusOK
+	if errors.Is(err, service.ErrBatchAborted) {
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, newBatchResponse(mode, results))
+}
+
+// streamWalletBatch применяет поток NDJSON частями по batchChunk и отвечает потоком результатов.
+func (h *Handler) streamWalletBatch(c *gin.Context, next func() (json.RawMessage, error)) {
+	// Тайм-ауты сервера рассчитаны на обычные запросы, а поток может читаться и писаться долго.
+	rc := http.NewResponseController(c.Writer)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	enc := json.NewEncoder(c.Writer)
+
+	results := make([]wallet.BatchResult, 0, batchChunk)
+	ops := make([]wallet.WalletTransactions, 0, batchChunk)
+	index := make([]int, 0, batchChunk)
+
+	flush := func() error {
+		if len(ops) > 0 {
+			applied, err := h.service.Wallet.ApplyBatch(c.Request.Context(), ops, false)
+			if err != nil {
+				return err
+			}
+			// results - подряд идущие операции части, начиная с results[0].Index.
+			for j, res := range applied {
+				res.Index = index[j]
+				results[index[j]-results[0].Index] = res
+			}
+		}
+		for _, res := range results {
+			if err := enc.Encode(res); err != nil {
+				return err
+			}
+		}
+		c.Writer.Flush()
+
+		results, ops, index = results[:0], ops[:0], index[:0]
+		return nil
+	}
+
+	for i := 0; ; i++ {
+		raw, err := next()
+		if errors.Is(err, errBatchEnd) {
+			break
+		}
+		if err == nil && i >= h.batchMaxStreamItems() {
+			err = fmt.Errorf("batch exceeds %d transactions", h.batchMaxStreamItems())
+		}
+		if err != nil {
+			if flush() == nil {
+				enc.Encode(gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		if WT, err := decodeBatchItem(raw); err != nil {
+			results = append(results, wallet.BatchResult{Index: i, Status: wallet.BatchRejected, Error: err.Error()})
+		} else {
+			results = append(results, wallet.BatchResult{Index: i})
+			ops = append(ops, WT)
+			index = append(index, i)
+		}
+
+		if len(results) == batchChunk {
+			if err := flush(); err != nil {
+				enc.Encode(gin.H{"error": err.Error()})
+				return
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		enc.Encode(gin.H{"error": err.Error()})
+	}
+}
+
+// batchReader возвращает функцию, читающую операции пакета по одной: из JSON-массива или из строк NDJSON.
+// Тело не читается целиком, поэтому пакет может быть сколь угодно длинным. В конце возвращается errBatchEnd.
+func batchReader(body io.Reader, contentType string) (func() (json.RawMessage, error), error) {
+	if isNDJSON(contentType) {
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 0, 4096), batchMaxLine)
+		return func() (json.RawMessage, error) {
+			for scanner.Scan() {
+				if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+					return append(json.RawMessage(nil), line...), nil
+				}
+			}
+			if err := scanner.Err(); err != nil {
+				return nil, fmt.Errorf("invalid NDJSON: %w", err)
+			}
+			return nil, errBatchEnd
+		}, nil
+	}
+
+	dec := json.NewDecoder(body)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, errors.New("body must be a JSON array of transactions")
+	}
+	return func() (json.RawMessage, error) {
+		if !dec.More() {
+			if _, err := dec.Token(); err != nil {
+				return nil, fmt.Errorf("invalid JSON: %w", err)
+			}
+			return nil, errBatchEnd
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		return raw, nil
+	}, nil
+}
+
+// decodeBatchItem разбирает и проверяет операцию пакета так же, как одиночную в createWalletTransaction.
+func decodeBatchItem(raw json.RawMessage) (wallet.WalletTransactions, error) {
+	var WT wallet.WalletTransactions
+	if err := json.Unmarshal(raw, &WT); err != nil {
+		return WT, err
+	}
+	if err := binding.Validator.ValidateStruct(&WT); err != nil {
+		return WT, err
+	}
+	if WT.Amount <= 0 {
+		return WT, errors.New("amount must be positive")
+	}
+	return WT, nil
+}
+
+func newBatchResponse(mode string, results []wallet.BatchResult) batchResponse {
+	resp := batchResponse{Mode: mode, Results: results}
+	for _, res := range results {
+		switch res.Status {
+		case wallet.BatchApplied:
+			resp.Applied++
+		case wallet.BatchDuplicate:
+			resp.Duplicates++
+		case wallet.BatchRejected:
+			resp.Rejected++
+		}
+	}
+	return resp
+}
+
+func isNDJSON(contentType string) bool {
+	return contentType == "application/x-ndjson" || contentType == "application/jsonl"
+}
+
+func (h *Handler) batchMaxItems() int {
+	if h.cfg.BatchMaxItems > 0 {
+		return h.cfg.BatchMaxItems
+	}
+	return defaultBatchMaxItems
+}
+
+func (h *Handler) batchMaxStreamItems() int {
+	if h.cfg.BatchMaxStreamItems > 0 {
+		return h.cfg.BatchMaxStreamItems
+	}
+	return defaultBatchMaxStreamItems
+}
@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/service"
+	mock_service "github.com/KatenkaKet/wallet/pkg/service/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/magiconair/properties/assert"
+)
+
+const (
+	batchWalletA = "11111111-1111-1111-1111-111111111111"
+	batchWalletB = "22222222-2222-2222-2222-222222222222"
+)
+
+func TestHandler_createWalletBatch(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockWallet)
+
+	deposit := fmt.Sprintf(`{"valletId":%q,"operationType":"DEPOSIT","amount":10}`, batchWalletA)
+	withdraw := fmt.Sprintf(`{"valletId":%q,"operationType":"WITHDRAW","amount":5,"idempotencyKey":"k1"}`, batchWalletB)
+	invalid := fmt.Sprintf(`{"valletId":%q,"operationType":"REFUND","amount":1}`, batchWalletA)
+
+	testTable := []struct {
+		name             string
+		query            string
+		body             string
+		cfg              Config
+		mockBehavior     mockBehavior
+		expectedCode     int
+		expectedStatuses []string
+		expectedIndexes  []int
+	}{
+		{
+			name: "atomic",
+			body: "[" + deposit + "," + withdraw + "]",
+			mockBehavior: func(s *mock_service.MockWallet) {
+				s.EXPECT().ApplyBatch(gomock.Any(), gomock.Len(2), true).Return([]wallet.BatchResult{
+					{Index: 0, Status: wallet.BatchApplied},
+					{Index: 1, Status: wallet.BatchDuplicate},
+				}, nil)
+			},
+			expectedCode:     http.StatusOK,
+			expectedStatuses: []string{wallet.BatchApplied, wallet.BatchDuplicate},
+			expectedIndexes:  []int{0, 1},
+		},
+		{
+			name: "atomic aborted",
+			body: "[" + deposit + "," + withdraw + "]",
+			mockBehavior: func(s *mock_service.MockWallet) {
+				s.EXPECT().ApplyBatch(gomock.Any(), gomock.Len(2), true).Return([]wallet.BatchResult{
+					{Index: 0, Status: wallet.BatchAborted},
+					{Index: 1, Status: wallet.BatchRejected, Error: "insufficient funds"},
+				}, service.ErrBatchAborted)
+			},
+			expectedCode:     http.StatusUnprocessableEntity,
+			expectedStatuses: []string{wallet.BatchAborted, wallet.BatchRejected},
+			expectedIndexes:  []int{0, 1},
+		},
+		{
+			name:             "atomic with invalid item",
+			body:             "[" + deposit + "," + invalid + "]",
+			mockBehavior:     func(s *mock_service.MockWallet) {},
+			expectedCode:     http.StatusUnprocessableEntity,
+			expectedStatuses: []string{wallet.BatchAborted, wallet.BatchRejected},
+			expectedIndexes:  []int{0, 1},
+		},
+		{
+			name:  "best effort",
+			query: "?mode=best-effort",
+			body:  "[" + invalid + "," + deposit + "," + withdraw + "]",
+			mockBehavior: func(s *mock_service.MockWallet) {
+				// Сервис получает только корректные операции, индексы восстанавливаются по запросу.
+				s.EXPECT().ApplyBatch(gomock.Any(), gomock.Len(2), false).Return([]wallet.BatchResult{
+					{Index: 0, Status: wallet.BatchApplied},
+					{Index: 1, Status: wallet.BatchRejected, Error: "insufficient funds"},
+				}, nil)
+			},
+			expectedCode:     http.StatusOK,
+			expectedStatuses: []string{wallet.BatchRejected, wallet.BatchApplied, wallet.BatchRejected},
+			expectedIndexes:  []int{0, 1, 2},
+		},
+		{
+			name:         "too many items",
+			body:         "[" + deposit + "," + deposit + "," + deposit + "]",
+			cfg:          Config{BatchMaxItems: 2},
+			mockBehavior: func(s *mock_service.MockWallet) {},
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:         "invalid mode",
+			query:        "?mode=partial",
+			body:         "[" + deposit + "]",
+			mockBehavior: func(s *mock_service.MockWallet) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "not an array",
+			body:         deposit,
+			mockBehavior: func(s *mock_service.MockWallet) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "broken JSON",
+			body:         "[" + deposit + ",{",
+			mockBehavior: func(s *mock_service.MockWallet) {},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockWallet := mock_service.NewMockWallet(ctrl)
+			test.mockBehavior(mockWallet)
+
+			srv := &service.Service{Wallet: mockWallet}
+			h := NewHandler(srv, test.cfg)
+
+			r := gin.New()
+			r.POST("/api/v1/wallets/batch", h.createWalletBatch)
+
+			req := httptest.NewRequest("POST", "/api/v1/wallets/batch"+test.query, strings.NewReader(test.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedCode, w.Code)
+			if test.expectedStatuses == nil {
+				return
+			}
+
+			var resp batchResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid response %q: %s", w.Body.String(), err)
+			}
+			var statuses []string
+			var indexes []int
+			for _, res := range resp.Results {
+				statuses = append(statuses, res.Status)
+				indexes = append(indexes, res.Index)
+			}
+			assert.Equal(t, test.expectedStatuses, statuses)
+			assert.Equal(t, test.expectedIndexes, indexes)
+		})
+	}
+}
+
+func TestHandler_createWalletBatch_NDJSON(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// batchChunk + 10 операций: две части, каждая со своим вызовом сервиса.
+	total := batchChunk + 10
+	var body strings.Builder
+	for i := 0; i < total; i++ {
+		if i == 3 {
+			body.WriteString("\n{\"valletId\":\"" + batchWalletA + "\",\"operationType\":\"DEPOSIT\",\"amount\":-1}\n")
+			continue
+		}
+		fmt.Fprintf(&body, "{\"valletId\":%q,\"operationType\":\"DEPOSIT\",\"amount\":1}\n", batchWalletA)
+	}
+
+	mockWallet := mock_service.NewMockWallet(ctrl)
+	applyAll := func(_ any, ops []wallet.WalletTransactions, _ bool) ([]wallet.BatchResult, error) {
+		results := make([]wallet.BatchResult, len(ops))
+		for i := range ops {
+			results[i] = wallet.BatchResult{Index: i, Status: wallet.BatchApplied}
+		}
+		return results, nil
+	}
+	gomock.InOrder(
+		mockWallet.EXPECT().ApplyBatch(gomock.Any(), gomock.Len(batchChunk-1), false).DoAndReturn(applyAll),
+		mockWallet.EXPECT().ApplyBatch(gomock.Any(), gomock.Len(10), false).DoAndReturn(applyAll),
+	)
+
+	h := NewHandler(&service.Service{Wallet: mockWallet}, Config{})
+	r := gin.New()
+	r.POST("/api/v1/wallets/batch", h.createWalletBatch)
+
+	req := httptest.NewRequest("POST", "/api/v1/wallets/batch?mode=best-effort", strings.NewReader(body.String()))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+
+	scanner := bufio.NewScanner(w.Body)
+	i := 0
+	for ; scanner.Scan(); i++ {
+		var res wallet.BatchResult
+		if err := json.Unmarshal(scanner.Bytes(), &res); err != nil {
+			t.Fatalf("invalid line %q: %s", scanner.Text(), err)
+		}
+		assert.Equal(t, i, res.Index)
+		if i == 3 {
+			assert.Equal(t, wallet.BatchRejected, res.Status)
+		} else {
+			assert.Equal(t, wallet.BatchApplied, res.Status)
+		}
+	}
+	assert.Equal(t, total, i)
+}
+
+func TestHandler_createWalletBatch_NDJSONLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockWallet := mock_service.NewMockWallet(ctrl)
+	mockWallet.EXPECT().ApplyBatch(gomock.Any(), gomock.Len(2), false).Return([]wallet.BatchResult{
+		{Index: 0, Status: wallet.BatchApplied},
+		{Index: 1, Status: wallet.BatchApplied},
+	}, nil)
+
+	h := NewHandler(&service.Service{Wallet: mockWallet}, Config{BatchMaxStreamItems: 2})
+	r := gin.New()
+	r.POST("/api/v1/wallets/batch", h.createWalletBatch)
+
+	line := fmt.Sprintf("{\"valletId\":%q,\"operationType\":\"DEPOSIT\",\"amount\":1}\n", batchWalletA)
+	req := httptest.NewRequest("POST", "/api/v1/wallets/batch?mode=best-effort", strings.NewReader(strings.Repeat(line, 3)))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	// Первые две операции применены, затем поток прерывается строкой с ошибкой.
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	assert.Equal(t, 3, len(lines))
+	assert.Equal(t, `{"error":"batch exceeds 2 transactions"}`, lines[2])
+}
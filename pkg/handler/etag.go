@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	"github.com/KatenkaKet/wallet/pkg/service"
+)
+
+// ETag кошелька - его версия в кавычках, например "42".
+
+func formatETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseIfMatch возвращает ожидаемую версию кошелька из If-Match; 0 - заголовка нет или он равен "*".
+func parseIfMatch(header string) (int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(header, "W/"), `"`), 10, 64)
+	if err != nil || version <= 0 {
+		return 0, errors.New("invalid If-Match header")
+	}
+	return version, nil
+}
+
+// ifNoneMatch сообщает, совпадает ли один из ETag заголовка If-None-Match с текущим.
+func ifNoneMatch(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// errorStatus подбирает HTTP-код для ошибки сервиса.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrVersionMismatch):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, repository.ErrWalletNotFound),
+		errors.Is(err, repository.ErrStatementNotFound),
+		errors.Is(err, repository.ErrScheduledNotFound),
+		errors.Is(err, repository.ErrRecurringNotFound),
+		errors.Is(err, repository.ErrSubscriptionNotFound),
+		errors.Is(err, repository.ErrDeliveryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrScheduledNotPending),
+		errors.Is(err, repository.ErrRecurringStatus),
+		errors.Is(err, repository.ErrCreditLimitBelowDebt):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrInvalidSubscription),
+		errors.Is(err, service.ErrInvalidStats),
+		errors.Is(err, service.ErrInvalidScheduled),
+		errors.Is(err, service.ErrInvalidRecurring),
+		errors.Is(err, service.ErrInvalidTier),
+		errors.Is(err, service.ErrInvalidInterest),
+		errors.Is(err, service.ErrInvalidCreditLimit),
+		errors.Is(err, repository.ErrInvalidBucket):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrInsufficientFunds):
+		// Запрос корректен, но не может быть исполнен при текущем балансе - как отказ в пакете операций.
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KatenkaKet/wallet/pkg/export"
+	"github.com/gin-gonic/gin"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// exportWalletTransactions godoc
+// @Summary Выгрузка истории операций кошелька
+// @Description Файл с историей за период [from, to): строка начального баланса, транзакции в порядке seq и строка конечного баланса.
+// @Description Форматы: csv, jsonl (JSON Lines), ofx (OFX 2.2 для бухгалтерских программ) и html.
+// @Description from и to - дата (YYYY-MM-DD, to включительно) или время RFC 3339 (to не включительно); без них - вся история.
+// @Description Суммы - с точкой и двумя знаками после неё, время - в UTC. История отдаётся потоком, без ограничения размера.
+// @Tags wallet
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/x-ofx
+// @Produce text/html
+// @Param id path string true "ID кошелька"
+// @Param format query string false "csv, jsonl, ofx или html" default(csv)
+// @Param from query string false "Начало периода"
+// @Param to query string false "Конец периода"
+// @Success 200 {file} file "Выгрузка"
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 404 {object} map[string]string "Кошелёк не найден"
+// @Failure 500 {object} map[string]string "Ошибка сервера"
+// @Router /wallets/{id}/transactions/export [get]
+func (h *Handler) exportWalletTransactions(c *gin.Context) {
+	var walletID uuid.UUID
+	if err := walletID.Scan(strings.TrimSpace(c.Param("id"))); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet id"})
+		return
+	}
+
+	format := c.DefaultQuery("format", export.FormatCSV)
+	from, err := parsePeriodBound(c.Query("from"), false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from: " + err.Error()})
+		return
+	}
+	to, err := parsePeriodBound(c.Query("to"), true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to: " + err.Error()})
+		return
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+
+	w, err := export.NewWriter(format, c.Writer, h.cfg.Currency)
+	if errors.Is(err, export.ErrUnknownFormat) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv, jsonl, ofx or html"})
+		return
+	}
+
+	// Большая выгрузка пишется дольше, чем WriteTimeout сервера.
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", exportFilename(walletID, from, to, format)))
+
+	err = h.service.Wallet.ExportTransactions(c.Request.Context(), walletID, from, to, w)
+	if err == nil {
+		return
+	}
+	if !c.Writer.Written() {
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	// Заголовки уже отправлены: клиент получит файл без строки конечного баланса.
+	log.Printf("export of wallet %s interrupted: %s", walletID.UUID.String(), err.Error())
+}
+
+// parsePeriodBound разбирает границу периода: время RFC 3339 или дату YYYY-MM-DD (начало дня в UTC;
+// для конца периода - начало следующего дня, чтобы дата входила в период).
+func parsePeriodBound(s string, end bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, errors.New("expected YYYY-MM-DD or RFC 3339 time")
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// exportFilename - имя файла выгрузки, например wallet-1111...-from-20240101-to-20240131.csv
+// (to - последний день, попавший в период).
+func exportFilename(walletID uuid.UUID, from, to time.Time, format string) string {
+	name := "wallet-" + walletID.UUID.String()
+	if !from.IsZero() {
+		name += "-from-" + from.UTC().Format("20060102")
+	}
+	if !to.IsZero() {
+		name += "-to-" + to.Add(-time.Nanosecond).UTC().Format("20060102")
+	}
+	return name + "." + format
+}
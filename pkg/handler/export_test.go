@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/export"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	"github.com/KatenkaKet/wallet/pkg/service"
+	mock_service "github.com/KatenkaKet/wallet/pkg/service/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestHandler_exportWalletTransactions(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockWallet, walletID uuid.UUID)
+
+	walletID := uuidFromString("11111111-1111-1111-1111-111111111111")
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	writeHistory := func(_ context.Context, id uuid.UUID, from, to time.Time, w export.Writer) error {
+		w.Begin(export.Header{ValletId: id, From: from, To: to, OpeningBalance: 10})
+		w.Transaction(wallet.WalletTransactions{Id: 3, Seq: 1, OperationType: "DEPOSIT", Amount: 2.5, BalanceAfter: 12.5, CreatedAt: jan.Add(time.Hour)})
+		return w.End(12.5)
+	}
+
+	testTable := []struct {
+		name                string
+		query               string
+		mockBehavior        mockBehavior
+		expectedCode        int
+		expectedContentType string
+		expectedDisposition string
+		expectedBody        string
+	}{
+		{
+			name:  "csv for dates",
+			query: "?from=2024-01-01&to=2024-01-31",
+			mockBehavior: func(s *mock_service.MockWallet, walletID uuid.UUID) {
+				s.EXPECT().ExportTransactions(gomock.Any(), walletID, jan, feb, gomock.Any()).DoAndReturn(writeHistory)
+			},
+			expectedCode:        http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedDisposition: `attachment; filename="wallet-11111111-1111-1111-1111-111111111111-from-20240101-to-20240131.csv"`,
+			expectedBody: "row_type,date,seq,transaction_id,operation_type,amount,balance\n" +
+				"opening_balance,2024-01-01T00:00:00Z,,,,,10.00\n" +
+				"transaction,2024-01-01T01:00:00Z,1,3,DEPOSIT,2.50,12.50\n" +
+				"closing_balance,2024-02-01T00:00:00Z,,,,,12.50\n",
+		},
+		{
+			name:  "jsonl for whole history",
+			query: "?format=jsonl",
+			mockBehavior: func(s *mock_service.MockWallet, walletID uuid.UUID) {
+				s.EXPECT().ExportTransactions(gomock.Any(), walletID, time.Time{}, time.Time{}, gomock.Any()).
+					DoAndReturn(func(_ context.Context, id uuid.UUID, _, _ time.Time, w export.Writer) error {
+						return writeHistory(nil, id, jan, feb, w)
+					})
+			},
+			expectedCode:        http.StatusOK,
+			expectedContentType: "application/x-ndjson",
+			expectedDisposition: `attachment; filename="wallet-11111111-1111-1111-1111-111111111111.jsonl"`,
+			expectedBody: `{"type":"opening_balance","valletId":"11111111-1111-1111-1111-111111111111","date":"2024-01-01T00:00:00Z","balance":"10.00"}
+{"type":"transaction","date":"2024-01-01T01:00:00Z","seq":1,"transactionId":3,"operationType":"DEPOSIT","amount":"2.50","balance":"12.50"}
+{"type":"closing_balance","valletId":"11111111-1111-1111-1111-111111111111","date":"2024-02-01T00:00:00Z","balance":"12.50"}
+`,
+		},
+		{
+			name: "wallet not found",
+			mockBehavior: func(s *mock_service.MockWallet, walletID uuid.UUID) {
+				s.EXPECT().ExportTransactions(gomock.Any(), walletID, time.Time{}, time.Time{}, gomock.Any()).
+					Return(fmt.Errorf("failed to get wallet: %w", repository.ErrWalletNotFound))
+			},
+			expectedCode:        http.StatusNotFound,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"error":"failed to get wallet: wallet not found"}`,
+		},
+		{
+			name:  "error after the first rows",
+			query: "?format=csv",
+			mockBehavior: func(s *mock_service.MockWallet, walletID uuid.UUID) {
+				s.EXPECT().ExportTransactions(gomock.Any(), walletID, time.Time{}, time.Time{}, gomock.Any()).
+					DoAndReturn(func(_ context.Context, id uuid.UUID, _, _ time.Time, w export.Writer) error {
+						w.Begin(export.Header{ValletId: id, From: jan, To: feb})
+						w.End(0)
+						return errors.New("connection reset")
+					})
+			},
+			expectedCode:        http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedDisposition: `attachment; filename="wallet-11111111-1111-1111-1111-111111111111.csv"`,
+			expectedBody: "row_type,date,seq,transaction_id,operation_type,amount,balance\n" +
+				"opening_balance,2024-01-01T00:00:00Z,,,,,0.00\n" +
+				"closing_balance,2024-02-01T00:00:00Z,,,,,0.00\n",
+		},
+		{
+			name:                "unknown format",
+			query:               "?format=xlsx",
+			mockBehavior:        func(s *mock_service.MockWallet, walletID uuid.UUID) {},
+			expectedCode:        http.StatusBadRequest,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"error":"format must be csv, jsonl, ofx or html"}`,
+		},
+		{
+			name:                "invalid date",
+			query:               "?from=01.01.2024",
+			mockBehavior:        func(s *mock_service.MockWallet, walletID uuid.UUID) {},
+			expectedCode:        http.StatusBadRequest,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"error":"invalid from: expected YYYY-MM-DD or RFC 3339 time"}`,
+		},
+		{
+			name:                "empty period",
+			query:               "?from=2024-02-01T00:00:00Z&to=2024-02-01T00:00:00Z",
+			mockBehavior:        func(s *mock_service.MockWallet, walletID uuid.UUID) {},
+			expectedCode:        http.StatusBadRequest,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"error":"from must be before to"}`,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockWallet := mock_service.NewMockWallet(ctrl)
+			test.mockBehavior(mockWallet, walletID)
+
+			srv := &service.Service{Wallet: mockWallet}
+			h := NewHandler(srv, Config{})
+
+			r := gin.New()
+			r.GET("/api/v1/wallets/:id/transactions/export", h.exportWalletTransactions)
+
+			req := httptest.NewRequest("GET", "/api/v1/wallets/"+walletID.UUID.String()+"/transactions/export"+test.query, nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.Equal(t, test.expectedContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, test.expectedDisposition, w.Header().Get("Content-Disposition"))
+			assert.Equal(t, test.expectedBody, w.Body.String())
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package handler
 
 import (
+	"expvar"
+	"time"
+
 	"github.com/KatenkaKet/wallet/pkg/service"
 	"github.com/gin-gonic/gin"
 
@@ -12,10 +15,26 @@ import (
 
 type Handler struct {
 	service *service.Service
+	cfg     Config
+}
+
+type Config struct {
+	// AdminToken - токен для /api/v1/admin (заголовок Authorization: Bearer <token>); пустой - админ-API отключено.
+	AdminToken string
+	// StreamHeartbeat - интервал heartbeat (SSE) и ping (WebSocket) в потоках изменений; 0 - 15 секунд.
+	StreamHeartbeat time.Duration
+	// BatchMaxItems - максимум операций в пакете JSON и в пакете atomic; 0 - 1000.
+	BatchMaxItems int
+	// BatchMaxStreamItems - максимум операций в потоке NDJSON в режиме best-effort; 0 - 100000.
+	BatchMaxStreamItems int
+	// ImportMaxRows - максимум строк в CSV массовой корректировки; 0 - 10000.
+	ImportMaxRows int
+	// Currency - код валюты ISO 4217 для выгрузки в OFX и HTML; пустой - RUB.
+	Currency string
 }
 
-func NewHandler(service *service.Service) *Handler {
-	return &Handler{service: service}
+func NewHandler(service *service.Service, cfg Config) *Handler {
+	return &Handler{service: service, cfg: cfg}
 }
 
 func (h *Handler) InitRoutes() *gin.Engine {
@@ -24,9 +43,47 @@ func (h *Handler) InitRoutes() *gin.Engine {
 	r := router.Group("/api/v1")
 	{
 		r.POST("/wallet", h.createWalletTransaction)
+		r.POST("/wallets/batch", h.createWalletBatch)
 		r.GET("/wallets/:id", h.getWalletBalance)
+		r.GET("/wallets/:id/transactions", h.listWalletTransactions)
+		r.GET("/wallets/:id/transactions/export", h.exportWalletTransactions)
+		r.GET("/wallets/:id/stats", h.getWalletStats)
+		r.POST("/wallets/:id/scheduled", h.createScheduledTransaction)
+		r.GET("/wallets/:id/scheduled", h.listScheduledTransactions)
+		r.POST("/wallets/:id/scheduled/:scheduledId/cancel", h.cancelScheduledTransaction)
+		r.POST("/wallets/:id/recurring", h.createRecurringTransfer)
+		r.GET("/wallets/:id/recurring", h.listRecurringTransfers)
+		r.GET("/wallets/:id/recurring/:ruleId", h.getRecurringTransfer)
+		r.GET("/wallets/:id/recurring/:ruleId/runs", h.listRecurringRuns)
+		r.POST("/wallets/:id/recurring/:ruleId/pause", h.pauseRecurringTransfer)
+		r.POST("/wallets/:id/recurring/:ruleId/resume", h.resumeRecurringTransfer)
+		r.POST("/wallets/:id/recurring/:ruleId/cancel", h.cancelRecurringTransfer)
+		r.GET("/wallets/:id/interest", h.listInterestAccruals)
+		r.GET("/wallets/:id/statements", h.listWalletStatements)
+		r.GET("/wallets/:id/statements/:period", h.getWalletStatement)
+		r.GET("/wallets/:id/verify", h.verifyWalletChain)
+		r.GET("/wallets/:id/stream", h.streamWallet)
+		r.GET("/wallets/:id/ws", h.streamWalletWS)
+		r.GET("/receipts/keys", h.listReceiptKeys)
+
+		admin := r.Group("/admin", h.adminAuth)
+		{
+			admin.POST("/webhooks", h.createWebhook)
+			admin.GET("/webhooks", h.listWebhooks)
+			admin.DELETE("/webhooks/:id", h.deleteWebhook)
+			admin.GET("/webhooks/deliveries/dead", h.listDeadDeliveries)
+			admin.POST("/webhooks/deliveries/:id/replay", h.replayDelivery)
+			admin.POST("/imports", h.createImport)
+			admin.GET("/stats", h.getGlobalStats)
+			admin.PUT("/wallets/:id/tier", h.setWalletTier)
+			admin.PUT("/wallets/:id/credit-limit", h.setWalletCreditLimit)
+			admin.PUT("/wallets/:id/product", h.setWalletProduct)
+		}
 	}
 
+	// Метрики (expvar): в том числе число повторов транзакций БД
+	router.GET("/debug/vars", gin.WrapH(expvar.Handler()))
+
 	// Swagger UI
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/csvimport"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultImportMaxRows = 10000
+	// importMaxBytes ограничивает размер загружаемого файла.
+	importMaxBytes = 10 << 20
+)
+
+// createImport godoc
+// @Summary Массовая корректировка балансов из CSV
+// @Description CSV с колонками wallet_id, operation_type (DEPOSIT/WITHDRAW), amount, reference; заголовок необязателен.
+// @Description Файл передаётся полем file (multipart/form-data) или телом запроса (text/csv).
+// @Description По умолчанию выполняется пробный прогон (dry_run=true): ничего не записывается, а для каждой строки
+// @Description сообщается статус ok, invalid, unknown_wallet, duplicate или would_overdraft.
+// @Description С dry_run=false строки применяются, статусы - applied, duplicate, rejected или invalid.
+// @Description reference служит ключом идемпотентности, поэтому повторная загрузка того же файла безопасна.
+// @Description format=csv возвращает результаты по строкам файлом CSV.
+// @Tags admin
+// @Accept text/csv
+// @Accept mpfd
+// @Produce json
+// @Produce text/csv
+// @Security AdminToken
+// @Param dry_run query bool false "Только проверка" default(true)
+// @Param format query string false "json или csv" default(json)
+// @Param file formData file false "CSV-файл"
+// @Success 200 {object} wallet.ImportReport
+// @Failure 400 {object} map[string]string "Файл не разбирается"
+// @Failure 401 {object} map[string]string "Неверный токен"
+// @Failure 413 {object} map[string]string "Слишком большой файл"
+// @Failure 500 {object} map[string]string "Ошибка сервера"
+// @Router /admin/imports [post]
+func (h *Handler) createImport(c *gin.Context) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "true"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be true or false"})
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, importMaxBytes)
+
+	var body io.Reader = c.Request.Body
+	if c.ContentType() == gin.MIMEMultipartPOSTForm {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(importErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+
+	rows, err := csvimport.Read(body, h.importMaxRows())
+	if err != nil {
+		c.JSON(importErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	var report wallet.ImportReport
+	if dryRun {
+		report, err = h.service.Import.Validate(c.Request.Context(), rows)
+	} else {
+		report, err = h.service.Import.Apply(c.Request.Context(), rows)
+	}
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	name := "import-results.csv"
+	if dryRun {
+		name = "import-check.csv"
+	}
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	c.Status(http.StatusOK)
+	if err := csvimport.WriteResults(c.Writer, report.Results); err != nil {
+		c.Error(err)
+	}
+}
+
+func importErrorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) || errors.Is(err, csvimport.ErrTooManyRows) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+func (h *Handler) importMaxRows() int {
+	if h.cfg.ImportMaxRows > 0 {
+		return h.cfg.ImportMaxRows
+	}
+	return defaultImportMaxRows
+}
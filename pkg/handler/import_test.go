@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/service"
+	mock_service "github.com/KatenkaKet/wallet/pkg/service/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestHandler_createImport(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockImport, rows []wallet.ImportRow)
+
+	const file = "wallet_id,operation_type,amount,reference\n11111111-1111-1111-1111-111111111111,DEPOSIT,10,ADJ-1\n"
+	rows := []wallet.ImportRow{
+		{Line: 2, WalletID: "11111111-1111-1111-1111-111111111111", OperationType: "DEPOSIT", Amount: "10", Reference: "ADJ-1"},
+	}
+
+	testTable := []struct {
+		name                string
+		query               string
+		body                string
+		multipart           bool
+		cfg                 Config
+		mockBehavior        mockBehavior
+		expectedCode        int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name: "dry run by default",
+			body: file,
+			mockBehavior: func(s *mock_service.MockImport, rows []wallet.ImportRow) {
+				s.EXPECT().Validate(gomock.Any(), rows).Return(wallet.ImportReport{
+					DryRun:  true,
+					Rows:    1,
+					Counts:  map[string]int{wallet.ImportOK: 1},
+					Results: []wallet.ImportResult{{ImportRow: rows[0], Status: wallet.ImportOK}},
+				}, nil)
+			},
+			expectedCode:        http.StatusOK,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody: `{"dryRun":true,"rows":1,"counts":{"ok":1},"results":[{"line":2,"walletId":"11111111-1111-1111-1111-111111111111",` +
+				`"operationType":"DEPOSIT","amount":"10","reference":"ADJ-1","status":"ok"}]}`,
+		},
+		{
+			name:      "apply multipart as csv",
+			query:     "?dry_run=false&format=csv",
+			body:      file,
+			multipart: true,
+			mockBehavior: func(s *mock_service.MockImport, rows []wallet.ImportRow) {
+				s.EXPECT().Apply(gomock.Any(), rows).Return(wallet.ImportReport{
+					Rows:   1,
+					Counts: map[string]int{wallet.BatchApplied: 1},
+					Results: []wallet.ImportResult{{ImportRow: rows[0], Status: wallet.BatchApplied,
+						Transaction: &wallet.WalletTransactions{Id: 5, Seq: 2, BalanceAfter: 110}}},
+				}, nil)
+			},
+			expectedCode:        http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedBody: "line,wallet_id,operation_type,amount,reference,status,error,transaction_id,seq,balance_after\n" +
+				"2,11111111-1111-1111-1111-111111111111,DEPOSIT,10,ADJ-1,applied,,5,2,110.00\n",
+		},
+		{
+			name:                "too many rows",
+			body:                file + strings.Repeat("11111111-1111-1111-1111-111111111111,DEPOSIT,10,ADJ-2\n", 2),
+			cfg:                 Config{ImportMaxRows: 2},
+			mockBehavior:        func(s *mock_service.MockImport, rows []wallet.ImportRow) {},
+			expectedCode:        http.StatusRequestEntityTooLarge,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"error":"too many rows: more than 2"}`,
+		},
+		{
+			name:                "malformed file",
+			body:                "11111111-1111-1111-1111-111111111111,DEPOSIT\n",
+			mockBehavior:        func(s *mock_service.MockImport, rows []wallet.ImportRow) {},
+			expectedCode:        http.StatusBadRequest,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"error":"line 1: expected 4 fields, got 2"}`,
+		},
+		{
+			name:                "invalid dry_run",
+			query:               "?dry_run=maybe",
+			body:                file,
+			mockBehavior:        func(s *mock_service.MockImport, rows []wallet.ImportRow) {},
+			expectedCode:        http.StatusBadRequest,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"error":"dry_run must be true or false"}`,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockImport := mock_service.NewMockImport(ctrl)
+			test.mockBehavior(mockImport, rows)
+
+			srv := &service.Service{Import: mockImport}
+			h := NewHandler(srv, test.cfg)
+
+			r := gin.New()
+			r.POST("/api/v1/admin/imports", h.createImport)
+
+			var body bytes.Buffer
+			contentType := "text/csv"
+			if test.multipart {
+				mw := multipart.NewWriter(&body)
+				part, _ := mw.CreateFormFile("file", "adjustments.csv")
+				part.Write([]byte(test.body))
+				mw.Close()
+				contentType = mw.FormDataContentType()
+			} else {
+				body.WriteString(test.body)
+			}
+
+			req := httptest.NewRequest("POST", "/api/v1/admin/imports"+test.query, &body)
+			req.Header.Set("Content-Type", contentType)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.Equal(t, test.expectedContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, test.expectedBody, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+type productRequest struct {
+	// Product - пустая строка отключает начисление процентов.
+	Product *string `json:"product" binding:"required"`
+}
+
+// setWalletProduct godoc
+// @Summary Изменить процентный продукт кошелька
+// @Description Продукт задаёт годовую ставку и соглашение о числе дней (INTEREST_PRODUCTS_FILE). Пустой продукт
+// @Description отключает начисление; уже накопленные проценты капитализируются в конце месяца.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param id path string true "ID кошелька"
+// @Param product body productRequest true "Имя продукта или пустая строка"
+// @Success 200 {object} wallet.Wallet
+// @Failure 400 {object} map[string]string "Неверные данные"
+// @Failure 401 {object} map[string]string "Неверный токен"
+// @Failure 404 {object} map[string]string "Кошелёк не найден"
+// @Router /admin/wallets/{id}/product [put]
+func (h *Handler) setWalletProduct(c *gin.Context) {
+	var walletID uuid.UUID
+	if err := walletID.Scan(strings.TrimSpace(c.Param("id"))); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet id"})
+		return
+	}
+
+	var req productRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	wlt, err := h.service.Interest.SetProduct(c.Request.Context(), walletID, *req.Product)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, wlt)
+}
+
+// listInterestAccruals godoc
+// @Summary Начисления процентов на остаток
+// @Description Дневные начисления процентов кошелька за дни из [from, to) (UTC). from и to - дата (YYYY-MM-DD,
+// @Description to включительно) или время RFC 3339; без to - по текущий день, без from - последние 31 день.
+// @Description Начисления копятся в accruedInterest кошелька и в конце месяца зачисляются операцией DEPOSIT
+// @Description вида interest. Период - не больше 366 дней.
+// @Tags wallet
+// @Produce json
+// @Param id path string true "ID кошелька"
+// @Param from query string false "Начало периода"
+// @Param to query string false "Конец периода"
+// @Success 200 {array} wallet.InterestAccrual
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 404 {object} map[string]string "Кошелёк не найден"
+// @Failure 500 {object} map[string]string "Ошибка сервера"
+// @Router /wallets/{id}/interest [get]
+func (h *Handler) listInterestAccruals(c *gin.Context) {
+	var walletID uuid.UUID
+	if err := walletID.Scan(strings.TrimSpace(c.Param("id"))); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet id"})
+		return
+	}
+
+	from, to, ok := statsPeriod(c)
+	if !ok {
+		return
+	}
+
+	accruals, err := h.service.Interest.ListAccruals(c.Request.Context(), walletID, from, to)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, accruals)
+}
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	"github.com/KatenkaKet/wallet/pkg/service"
+	mock_service "github.com/KatenkaKet/wallet/pkg/service/mocks"
+	"github.com/golang/mock/gomock"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestHandler_setWalletProduct(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockInterest, walletID uuid.UUID)
+
+	walletID := uuidFromString("11111111-1111-1111-1111-111111111111")
+
+	testTable := []struct {
+		name         string
+		body         string
+		mockBehavior mockBehavior
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "success",
+			body: `{"product":"savings"}`,
+			mockBehavior: func(s *mock_service.MockInterest, walletID uuid.UUID) {
+				s.EXPECT().SetProduct(gomock.Any(), walletID, "savings").
+					Return(wallet.Wallet{ValletId: walletID, Balance: 10, Version: 3, LastSeq: 2, LastHash: "ab", Tier: "standard",
+						Product: "savings", AccruedInterest: 0.0042, Available: 10}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"valletId":"11111111-1111-1111-1111-111111111111","balance":10,"version":3,"lastSeq":2,` +
+				`"lastHash":"ab","tier":"standard","product":"savings","accruedInterest":0.0042,"creditLimit":0,"available":10}`,
+		},
+		{
+			name: "clear product",
+			body: `{"product":""}`,
+			mockBehavior: func(s *mock_service.MockInterest, walletID uuid.UUID) {
+				s.EXPECT().SetProduct(gomock.Any(), walletID, "").
+					Return(wallet.Wallet{ValletId: walletID, Balance: 10, Version: 3, LastSeq: 2, LastHash: "ab", Tier: "standard",
+						Available: 10}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"valletId":"11111111-1111-1111-1111-111111111111","balance":10,"version":3,"lastSeq":2,` +
+				`"lastHash":"ab","tier":"standard","creditLimit":0,"available":10}`,
+		},
+		{
+			name:         "missing product",
+			body:         `{}`,
+			mockBehavior: func(s *mock_service.MockInterest, walletID uuid.UUID) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"Key: 'productRequest.Product' Error:Field validation for 'Product' failed on the 'required' tag"}`,
+		},
+		{
+			name: "unknown product",
+			body: `{"product":"deposit"}`,
+			mockBehavior: func(s *mock_service.MockInterest, walletID uuid.UUID) {
+				s.EXPECT().SetProduct(gomock.Any(), walletID, "deposit").
+					Return(wallet.Wallet{}, fmt.Errorf("%w: unknown interest product %q", service.ErrInvalidInterest, "deposit"))
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid interest request: unknown interest product \"deposit\""}`,
+		},
+		{
+			name: "wallet not found",
+			body: `{"product":"savings"}`,
+			mockBehavior: func(s *mock_service.MockInterest, walletID uuid.UUID) {
+				s.EXPECT().SetProduct(gomock.Any(), walletID, "savings").Return(wallet.Wallet{}, repository.ErrWalletNotFound)
+			},
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"error":"wallet not found"}`,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockInterest := mock_service.NewMockInterest(ctrl)
+			test.mockBehavior(mockInterest, walletID)
+
+			h := NewHandler(&service.Service{Interest: mockInterest}, Config{AdminToken: "token"})
+			r := h.InitRoutes()
+
+			req := httptest.NewRequest("PUT", "/api/v1/admin/wallets/"+walletID.UUID.String()+"/product", strings.NewReader(test.body))
+			req.Header.Set("Authorization", "Bearer token")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.Equal(t, test.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_listInterestAccruals(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockInterest, walletID uuid.UUID)
+
+	walletID := uuidFromString("11111111-1111-1111-1111-111111111111")
+	jan := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name         string
+		walletID     string
+		query        string
+		mockBehavior mockBehavior
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:     "ok",
+			walletID: walletID.UUID.String(),
+			query:    "?from=2026-01-01&to=2026-01-31",
+			mockBehavior: func(s *mock_service.MockInterest, walletID uuid.UUID) {
+				s.EXPECT().ListAccruals(gomock.Any(), walletID, jan, jan.AddDate(0, 1, 0)).Return([]wallet.InterestAccrual{
+					{ValletId: walletID, Day: jan, Product: "savings", Balance: 1000, DailyRate: 0.0001, Amount: 0.1},
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `[{"valletId":"11111111-1111-1111-1111-111111111111","day":"2026-01-01T00:00:00Z","product":"savings",` +
+				`"balance":1000,"dailyRate":0.0001,"amount":0.1}]`,
+		},
+		{
+			name:     "empty",
+			walletID: walletID.UUID.String(),
+			mockBehavior: func(s *mock_service.MockInterest, walletID uuid.UUID) {
+				s.EXPECT().ListAccruals(gomock.Any(), walletID, time.Time{}, time.Time{}).Return([]wallet.InterestAccrual{}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `[]`,
+		},
+		{
+			name:         "invalid from",
+			walletID:     walletID.UUID.String(),
+			query:        "?from=yesterday",
+			mockBehavior: func(s *mock_service.MockInterest, walletID uuid.UUID) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid from: expected YYYY-MM-DD or RFC 3339 time"}`,
+		},
+		{
+			name:         "invalid wallet id",
+			walletID:     "not-a-uuid",
+			mockBehavior: func(s *mock_service.MockInterest, walletID uuid.UUID) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid wallet id"}`,
+		},
+		{
+			name:     "wallet not found",
+			walletID: walletID.UUID.String(),
+			mockBehavior: func(s *mock_service.MockInterest, walletID uuid.UUID) {
+				s.EXPECT().ListAccruals(gomock.Any(), walletID, time.Time{}, time.Time{}).Return(nil, repository.ErrWalletNotFound)
+			},
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"error":"wallet not found"}`,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockInterest := mock_service.NewMockInterest(ctrl)
+			test.mockBehavior(mockInterest, walletID)
+
+			h := NewHandler(&service.Service{Interest: mockInterest}, Config{})
+			r := h.InitRoutes()
+
+			req := httptest.NewRequest("GET", "/api/v1/wallets/"+test.walletID+"/interest"+test.query, nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.Equal(t, test.expectedBody, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listReceiptKeys godoc
+// @Summary Публичные ключи для проверки квитанций
+// @Description Квитанции об операциях подписываются Ed25519; keyId квитанции указывает, каким ключом.
+// @Description Список включает ключи из прошлых ротаций. Проверка офлайн - пакет pkg/receipt.
+// @Tags receipts
+// @Produce json
+// @Success 200 {object} map[string][]receipt.PublicKey "keys: публичные ключи"
+// @Router /receipts/keys [get]
+func (h *Handler) listReceiptKeys(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"keys": h.service.Receipt.PublicKeys(),
+	})
+}
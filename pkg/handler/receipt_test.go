@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/receipt"
+	"github.com/KatenkaKet/wallet/pkg/service"
+	mock_service "github.com/KatenkaKet/wallet/pkg/service/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_createWalletTransaction_Receipt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	walletID := uuidFromString("11111111-1111-1111-1111-111111111111")
+
+	mockWallet := mock_service.NewMockWallet(ctrl)
+	mockWallet.EXPECT().UpdateBalance(gomock.Any(), gomock.Any()).Return(wallet.WalletTransactions{
+		Id:            42,
+		ValletId:      walletID,
+		OperationType: "DEPOSIT",
+		Amount:        100.5,
+		Version:       8,
+		Seq:           7,
+		BalanceAfter:  1100.5,
+		CreatedAt:     time.Date(2025, 1, 2, 3, 4, 5, 123456000, time.UTC),
+	}, nil)
+
+	h := NewHandler(&service.Service{
+		Wallet:  mockWallet,
+		Receipt: service.NewReceiptService("k1", key, nil),
+	}, Config{})
+
+	r := gin.New()
+	r.POST("/wallet", h.createWalletTransaction)
+	r.GET("/receipts/keys", h.listReceiptKeys)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/wallet",
+		strings.NewReader(`{"valletId":"11111111-1111-1111-1111-111111111111","operationType":"DEPOSIT","amount":100.5}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Status  string          `json:"status"`
+		Receipt receipt.Receipt `json:"receipt"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "success", body.Status)
+	assert.Equal(t, 42, body.Receipt.TransactionId)
+	assert.Equal(t, walletID.UUID.String(), body.Receipt.ValletId)
+	assert.Equal(t, 1100.5, body.Receipt.BalanceAfter)
+	assert.Equal(t, "k1", body.Receipt.KeyId)
+
+	// Клиент проверяет квитанцию по опубликованным ключам.
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/receipts/keys", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var published struct {
+		Keys []receipt.PublicKey `json:"keys"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &published))
+	keys, err := receipt.ParseKeySet(published.Keys)
+	require.NoError(t, err)
+
+	assert.NoError(t, receipt.Verify(body.Receipt, keys))
+
+	body.Receipt.Amount = 1000.5
+	assert.ErrorIs(t, receipt.Verify(body.Receipt, keys), receipt.ErrInvalidSignature)
+}
+
+func TestHandler_listReceiptKeys(t *testing.T) {
+	retired := receipt.Public("k0", ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)).Public().(ed25519.PublicKey))
+
+	testTable := []struct {
+		name     string
+		receipts *service.ReceiptService
+		expected []receipt.PublicKey
+	}{
+		{
+			name:     "disabled",
+			receipts: service.NewReceiptService("", nil, nil),
+			expected: []receipt.PublicKey{},
+		},
+		{
+			name:     "retired keys only",
+			receipts: service.NewReceiptService("", nil, []receipt.PublicKey{retired}),
+			expected: []receipt.PublicKey{retired},
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			h := NewHandler(&service.Service{Receipt: test.receipts}, Config{})
+
+			r := gin.New()
+			r.GET("/receipts/keys", h.listReceiptKeys)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/receipts/keys", nil))
+
+			var body struct {
+				Keys []receipt.PublicKey `json:"keys"`
+			}
+			require.Equal(t, http.StatusOK, w.Code)
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, test.expected, body.Keys)
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/gin-gonic/gin"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+type recurringRequest struct {
+	ToWalletId uuid.UUID `json:"toWalletId" binding:"required"`
+	Amount     float64   `json:"amount" binding:"required"`
+	// Schedule - выражение cron ("0 9 1 * *") или RRULE ("FREQ=MONTHLY;BYMONTHDAY=1;BYHOUR=9;BYMINUTE=0"), в UTC.
+	Schedule             string     `json:"schedule" binding:"required"`
+	StartAt              *time.Time `json:"startAt"`
+	EndAt                *time.Time `json:"endAt"`
+	CatchUp              string     `json:"catchUp" binding:"omitempty,oneof=all latest skip"`
+	MaxRetries           int        `json:"maxRetries"`
+	RetryIntervalSeconds int64      `json:"retryIntervalSeconds"`
+}
+
+// createRecurringTransfer godoc
+// @Summary Создать регулярный перевод
+// @Description Перевод amount на toWalletId по расписанию schedule: cron из пяти полей или RRULE
+// @Description (FREQ=DAILY|WEEKLY|MONTHLY, INTERVAL, BYDAY, BYMONTHDAY, BYHOUR, BYMINUTE, UNTIL), время - в UTC.
+// @Description catchUp - что делать со сроками, пропущенными, пока сервис не работал: all, latest (по умолчанию) или skip.
+// @Description При нехватке средств перевод повторяется до maxRetries раз через retryIntervalSeconds (по умолчанию час).
+// @Tags recurring
+// @Accept json
+// @Produce json
+// @Param id path string true "ID кошелька-источника"
+// @Param rule body recurringRequest true "Правило"
+// @Success 201 {object} wallet.RecurringTransfer
+// @Failure 400 {object} map[string]string "Неверные данные"
+// @Failure 404 {object} map[string]string "Кошелёк не найден"
+// @Failure 500 {object} map[string]string "Ошибка сервера"
+// @Router /wallets/{id}/recurring [post]
+func (h *Handler) createRecurringTransfer(c *gin.Context) {
+	var walletID uuid.UUID
+	if err := walletID.Scan(strings.TrimSpace(c.Param("id"))); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet id"})
+		return
+	}
+
+	var req recurringRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	r := wallet.RecurringTransfer{
+		FromWalletId:         walletID,
+		ToWalletId:           req.ToWalletId,
+		Amount:               req.Amount,
+		Schedule:             req.Schedule,
+		EndAt:                req.EndAt,
+		CatchUp:              req.CatchUp,
+		MaxRetries:           req.MaxRetries,
+		RetryIntervalSeconds: req.RetryIntervalSeconds,
+	}
+	if req.StartAt != nil {
+		r.StartAt = *req.StartAt
+	}
+
+	created, err := h.service.Recurring.CreateRecurring(c.Request.Context(), r)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// listRecurringTransfers godoc
+// @Summary Регулярные переводы кошелька
+// @Tags recurring
+// @Produce json
+// @Param id path string true "ID кошелька-источника"
+// @Param limit query int false "Максимальное число правил (до 1000)" default(100)
+// @Success 200 {object} map[string][]wallet.RecurringTransfer "recurring: правила в порядке создания"
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 404 {object} map[string]string "Кошелёк не найден"
+// @Failure 500 {object} map[string]string "Ошибка сервера"
+// @Router /wallets/{id}/recurring [get]
+func (h *Handler) listRecurringTransfers(c *gin.Context) {
+	var walletID uuid.UUID
+	if err := walletID.Scan(strings.TrimSpace(c.Param("id"))); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet id"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultHistoryLimit)))
+	if err != nil || limit <= 0 || limit > maxHistoryLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	rules, err := h.service.Recurring.ListRecurring(c.Request.Context(), walletID, limit)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recurring": rules,
+	})
+}
+
+// getRecurringTransfer godoc
+// @Summary Регулярный перевод
+// @Tags recurring
+// @Produce json
+// @Param id path string true "ID кошелька-источника"
+// @Param ruleId path int true "ID правила"
+// @Success 200 {object} wallet.RecurringTransfer
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 404 {object} map[string]string "Правило не найдено"
+// @Failure 500 {object} map[string]string "Ошибка сервера"
+// @Router /wallets/{id}/recurring/{ruleId} [get]
+func (h *Handler) getRecurringTransfer(c *gin.Context) {
+	h.recurringAction(c, h.service.Recurring.GetRecurring)
+}
+
+// listRecurringRuns godoc
+// @Summary История запусков регулярного перевода
+// @Description Каждая попытка перевода: срок, номер попытки, результат и ID записанных транзакций.
+// @Tags recurring
+// @Produce json
+// @Param id path string true "ID кошелька-источника"
+// @Param ruleId path int true "ID правила"
+// @Param afterId query int false "Вернуть запуски с id больше указанного" default(0)
+// @Param limit query int false "Максимальное число запусков (до 1000)" default(100)
+// @Success 200 {object} map[string][]wallet.RecurringRun "runs: запуски по порядку"
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 404 {object} map[string]string "Правило не найдено"
+// @Failure 500 {object} map[string]string "Ошибка сервера"
+// @Router /wallets/{id}/recurring/{ruleId}/runs [get]
+func (h *Handler) listRecurringRuns(c *gin.Context) {
+	walletID, id, ok := recurringParams(c)
+	if !ok {
+		return
+	}
+
+	afterID, err := strconv.ParseInt(c.DefaultQuery("afterId", "0"), 10, 64)
+	if err != nil || afterID < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid afterId"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultHistoryLimit)))
+	if err != nil || limit <= 0 || limit > maxHistoryLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	runs, err := h.service.Recurring.ListRecurringRuns(c.Request.Context(), walletID, id, afterID, limit)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"runs": runs,
+	})
+}
+
+// pauseRecurringTransfer godoc
+// @Summary Приостановить регулярный перевод
+// @Description Уже начатый перевод завершится; сроки, прошедшие за время паузы, не выполняются.
+// @Tags recurring
+// @Produce json
+// @Param id path string true "ID кошелька-источника"
+// @Param ruleId path int true "ID правила"
+// @Success 200 {object} wallet.RecurringTransfer
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 404 {object} map[string]string "Правило не найдено"
+// @Failure 409 {object} map[string]string "Правило не активно"
+// @Failure 500 {object} map[string]string "Ошибка сервера"
+// @Router /wallets/{id}/recurring/{ruleId}/pause [post]
+func (h *Handler) pauseRecurringTransfer(c *gin.Context) {
+	h.recurringAction(c, h.service.Recurring.PauseRecurring)
+}
+
+// resumeRecurringTransfer godoc
+// @Summary Возобновить регулярный перевод
+// @Description Перевод продолжается со следующего срока после текущего момента.
+// @Tags recurring
+// @Produce json
+// @Param id path string true "ID кошелька-источника"
+// @Param ruleId path int true "ID правила"
+// @Success 200 {object} wallet.RecurringTransfer
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 404 {object} map[string]string "Правило не найдено"
+// @Failure 409 {object} map[string]string "Правило не приостановлено"
+// @Failure 500 {object} map[string]string "Ошибка сервера"
+// @Router /wallets/{id}/recurring/{ruleId}/resume [post]
+func (h *Handler) resumeRecurringTransfer(c *gin.Context) {
+	h.recurringAction(c, h.service.Recurring.ResumeRecurring)
+}
+
+// cancelRecurringTransfer godoc
+// @Summary Отменить регулярный перевод
+// @Tags recurring
+// @Produce json
+// @Param id path string true "ID кошелька-источника"
+// @Param ruleId path int true "ID правила"
+// @Success 200 {object} wallet.RecurringTransfer
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 404 {object} map[string]string "Правило не найдено"
+// @Failure 409 {object} map[string]string "Правило уже завершено или отменено"
+// @Failure 500 {object} map[string]string "Ошибка сервера"
+// @Router /wallets/{id}/recurring/{ruleId}/cancel [post]
+func (h *Handler) cancelRecurringTransfer(c *gin.Context) {
+	h.recurringAction(c, h.service.Recurring.CancelRecurring)
+}
+
+// recurringAction выполняет action над правилом из пути запроса и возвращает правило.
+func (h *Handler) recurringAction(c *gin.Context,
+	action func(ctx context.Context, walletID uuid.UUID, id int64) (wallet.RecurringTransfer, error)) {
+	walletID, id, ok := recurringParams(c)
+	if !ok {
+		return
+	}
+
+	r, err := action(c.Request.Context(), walletID, id)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, r)
+}
+
+// recurringParams разбирает ID кошелька и правила из пути; при ошибке отвечает 400.
+func recurringParams(c *gin.Context) (uuid.UUID, int64, bool) {
+	var walletID uuid.UUID
+	if err := walletID.Scan(strings.TrimSpace(c.Param("id"))); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet id"})
+		return walletID, 0, false
+	}
+
+	id, err := strconv.ParseInt(c.Param("ruleId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid recurring transfer id"})
+		return walletID, 0, false
+	}
+	return walletID, id, true
+}
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	"github.com/KatenkaKet/wallet/pkg/service"
+	mock_service "github.com/KatenkaKet/wallet/pkg/service/mocks"
+	"github.com/golang/mock/gomock"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestHandler_recurringTransfers(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockRecurring, walletID uuid.UUID)
+
+	walletID := uuidFromString("11111111-1111-1111-1111-111111111111")
+	toWalletID := uuidFromString("22222222-2222-2222-2222-222222222222")
+	created := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+	next := time.Date(2026, 2, 1, 9, 0, 0, 0, time.UTC)
+	rule := wallet.RecurringTransfer{Id: 3, FromWalletId: walletID, ToWalletId: toWalletID, Amount: 100, Schedule: "0 9 1 * *",
+		StartAt: created, CatchUp: wallet.CatchUpLatest, Status: wallet.RecurringActive, OccurrenceAt: &next, NextRunAt: &next,
+		CreatedAt: created, UpdatedAt: created}
+	ruleJSON := func(status string) string {
+		return `{"id":3,"fromWalletId":"11111111-1111-1111-1111-111111111111","toWalletId":"22222222-2222-2222-2222-222222222222",` +
+			`"amount":100,"schedule":"0 9 1 * *","startAt":"2026-01-15T12:00:00Z","catchUp":"latest","maxRetries":0,` +
+			`"retryIntervalSeconds":0,"status":"` + status + `","occurrenceAt":"2026-02-01T09:00:00Z","nextRunAt":"2026-02-01T09:00:00Z",` +
+			`"attempt":0,"createdAt":"2026-01-15T12:00:00Z","updatedAt":"2026-01-15T12:00:00Z"}`
+	}
+	txFrom, txTo := 7, 8
+
+	testTable := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		mockBehavior mockBehavior
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:   "create",
+			method: "POST",
+			path:   "/recurring",
+			body:   `{"toWalletId":"22222222-2222-2222-2222-222222222222","amount":100,"schedule":"0 9 1 * *","maxRetries":2}`,
+			mockBehavior: func(s *mock_service.MockRecurring, walletID uuid.UUID) {
+				s.EXPECT().CreateRecurring(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ any, r wallet.RecurringTransfer) (wallet.RecurringTransfer, error) {
+						if r.FromWalletId != walletID || r.ToWalletId != toWalletID || r.Amount != 100 || r.MaxRetries != 2 ||
+							!r.StartAt.IsZero() || r.EndAt != nil {
+							return wallet.RecurringTransfer{}, fmt.Errorf("unexpected %+v", r)
+						}
+						return rule, nil
+					})
+			},
+			expectedCode: http.StatusCreated,
+			expectedBody: ruleJSON("active"),
+		},
+		{
+			name:         "create with unknown catch-up policy",
+			method:       "POST",
+			path:         "/recurring",
+			body:         `{"toWalletId":"22222222-2222-2222-2222-222222222222","amount":100,"schedule":"@daily","catchUp":"some"}`,
+			mockBehavior: func(s *mock_service.MockRecurring, walletID uuid.UUID) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"Key: 'recurringRequest.CatchUp' Error:Field validation for 'CatchUp' failed on the 'oneof' tag"}`,
+		},
+		{
+			name:   "create with invalid schedule",
+			method: "POST",
+			path:   "/recurring",
+			body:   `{"toWalletId":"22222222-2222-2222-2222-222222222222","amount":100,"schedule":"monthly"}`,
+			mockBehavior: func(s *mock_service.MockRecurring, walletID uuid.UUID) {
+				s.EXPECT().CreateRecurring(gomock.Any(), gomock.Any()).
+					Return(wallet.RecurringTransfer{}, fmt.Errorf("%w: invalid schedule: cron expression must have 5 fields", service.ErrInvalidRecurring))
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid recurring transfer: invalid schedule: cron expression must have 5 fields"}`,
+		},
+		{
+			name:   "list",
+			method: "GET",
+			path:   "/recurring",
+			mockBehavior: func(s *mock_service.MockRecurring, walletID uuid.UUID) {
+				s.EXPECT().ListRecurring(gomock.Any(), walletID, 100).Return([]wallet.RecurringTransfer{rule}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"recurring":[` + ruleJSON("active") + `]}`,
+		},
+		{
+			name:   "get missing",
+			method: "GET",
+			path:   "/recurring/4",
+			mockBehavior: func(s *mock_service.MockRecurring, walletID uuid.UUID) {
+				s.EXPECT().GetRecurring(gomock.Any(), walletID, int64(4)).Return(wallet.RecurringTransfer{}, repository.ErrRecurringNotFound)
+			},
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"error":"recurring transfer not found"}`,
+		},
+		{
+			name:   "runs",
+			method: "GET",
+			path:   "/recurring/3/runs?afterId=5&limit=2",
+			mockBehavior: func(s *mock_service.MockRecurring, walletID uuid.UUID) {
+				s.EXPECT().ListRecurringRuns(gomock.Any(), walletID, int64(3), int64(5), 2).Return([]wallet.RecurringRun{
+					{Id: 6, RuleId: 3, OccurrenceAt: next, Attempt: 1, Status: wallet.RecurringRunRetrying, Error: "insufficient funds", CreatedAt: next},
+					{Id: 7, RuleId: 3, OccurrenceAt: next, Attempt: 2, Status: wallet.RecurringRunSucceeded,
+						FromTransactionId: &txFrom, ToTransactionId: &txTo, CreatedAt: next.Add(time.Hour)},
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"runs":[{"id":6,"ruleId":3,"occurrenceAt":"2026-02-01T09:00:00Z","attempt":1,"status":"retrying",` +
+				`"error":"insufficient funds","createdAt":"2026-02-01T09:00:00Z"},{"id":7,"ruleId":3,"occurrenceAt":"2026-02-01T09:00:00Z",` +
+				`"attempt":2,"status":"succeeded","fromTransactionId":7,"toTransactionId":8,"createdAt":"2026-02-01T10:00:00Z"}]}`,
+		},
+		{
+			name:         "runs with invalid cursor",
+			method:       "GET",
+			path:         "/recurring/3/runs?afterId=-1",
+			mockBehavior: func(s *mock_service.MockRecurring, walletID uuid.UUID) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid afterId"}`,
+		},
+		{
+			name:   "pause",
+			method: "POST",
+			path:   "/recurring/3/pause",
+			mockBehavior: func(s *mock_service.MockRecurring, walletID uuid.UUID) {
+				paused := rule
+				paused.Status = wallet.RecurringPaused
+				s.EXPECT().PauseRecurring(gomock.Any(), walletID, int64(3)).Return(paused, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: ruleJSON("paused"),
+		},
+		{
+			name:   "resume active",
+			method: "POST",
+			path:   "/recurring/3/resume",
+			mockBehavior: func(s *mock_service.MockRecurring, walletID uuid.UUID) {
+				s.EXPECT().ResumeRecurring(gomock.Any(), walletID, int64(3)).Return(wallet.RecurringTransfer{}, repository.ErrRecurringStatus)
+			},
+			expectedCode: http.StatusConflict,
+			expectedBody: `{"error":"recurring transfer status does not allow this action"}`,
+		},
+		{
+			name:   "cancel",
+			method: "POST",
+			path:   "/recurring/3/cancel",
+			mockBehavior: func(s *mock_service.MockRecurring, walletID uuid.UUID) {
+				cancelled := rule
+				cancelled.Status = wallet.RecurringCancelled
+				s.EXPECT().CancelRecurring(gomock.Any(), walletID, int64(3)).Return(cancelled, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: ruleJSON("cancelled"),
+		},
+		{
+			name:         "cancel with invalid id",
+			method:       "POST",
+			path:         "/recurring/x/cancel",
+			mockBehavior: func(s *mock_service.MockRecurring, walletID uuid.UUID) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid recurring transfer id"}`,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRecurring := mock_service.NewMockRecurring(ctrl)
+			test.mockBehavior(mockRecurring, walletID)
+
+			srv := &service.Service{Recurring: mockRecurring}
+			h := NewHandler(srv, Config{})
+			r := h.InitRoutes()
+
+			req := httptest.NewRequest(test.method, "/api/v1/wallets/"+walletID.UUID.String()+test.path, bytes.NewBufferString(test.body))
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.Equal(t, test.expectedBody, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/gin-gonic/gin"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+type scheduleRequest struct {
+	OperationType string    `json:"operationType" binding:"required,oneof=DEPOSIT WITHDRAW"`
+	Amount        float64   `json:"amount" binding:"required"`
+	ExecuteAt     time.Time `json:"executeAt" binding:"required"`
+}
+
+// createScheduledTransaction godoc
+// @Summary Запланировать операцию
+// @Description Пополнение или списание выполняется в executeAt (RFC 3339) или вскоре после.
+// @Description Результат - в статусе операции: succeeded, failed (недостаточно средств) или cancelled.
+// @Tags wallet
+// @Accept json
+// @Produce json
+// @Param id path string true "ID кошелька"
+// @Param transaction body scheduleRequest true "Операция"
+// @Success 201 {object} wallet.ScheduledTransaction
+// @Failure 400 {object} map[string]string "Неверные данные"
+// @Failure 404 {object} map[string]string "Кошелёк не найден"
+// @Failure 500 {object} map[string]string "Ошибка сервера"
+// @Router /wallets/{id}/scheduled [post]
+func (h *Handler) createScheduledTransaction(c *gin.Context) {
+	var walletID uuid.UUID
+	if err := walletID.Scan(strings.TrimSpace(c.Param("id"))); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet id"})
+		return
+	}
+
+	var req scheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	st, err := h.service.Scheduled.Schedule(c.Request.Context(), wallet.ScheduledTransaction{
+		ValletId:      walletID,
+		OperationType: req.OperationType,
+		Amount:        req.Amount,
+		ExecuteAt:     req.ExecuteAt,
+	})
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, st)
+}
+
+// listScheduledTransactions godoc
+// @Summary Отложенные операции кошелька
+// @Tags wallet
+// @Produce json
+// @Param id path string true "ID кошелька"
+// @Param status query string false "pending, succeeded, failed или cancelled; без него - все"
+// @Param limit query int false "Максимальное число операций (до 1000)" default(100)
+// @Success 200 {object} map[string][]wallet.ScheduledTransaction "scheduled: операции в порядке executeAt"
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 404 {object} map[string]string "Кошелёк не найден"
+// @Failure 500 {object} map[string]string "Ошибка сервера"
+// @Router /wallets/{id}/scheduled [get]
+func (h *Handler) listScheduledTransactions(c *gin.Context) {
+	var walletID uuid.UUID
+	if err := walletID.Scan(strings.TrimSpace(c.Param("id"))); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet id"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultHistoryLimit)))
+	if err != nil || limit <= 0 || limit > maxHistoryLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	scheduled, err := h.service.Scheduled.ListScheduled(c.Request.Context(), walletID, c.Query("status"), limit)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"scheduled": scheduled,
+	})
+}
+
+// cancelScheduledTransaction godoc
+// @Summary Отменить отложенную операцию
+// @Description Отменить можно только ожидающую операцию, которую обработчик ещё не начал выполнять.
+// @Tags wallet
+// @Produce json
+// @Param id path string true "ID кошелька"
+// @Param scheduledId path int true "ID отложенной операции"
+// @Success 200 {object} wallet.ScheduledTransaction
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 404 {object} map[string]string "Операция не найдена"
+// @Failure 409 {object} map[string]string "Операция уже выполнена, отменена или выполняется"
+// @Failure 500 {object} map[string]string "Ошибка сервера"
+// @Router /wallets/{id}/scheduled/{scheduledId}/cancel [post]
+func (h *Handler) cancelScheduledTransaction(c *gin.Context) {
+	var walletID uuid.UUID
+	if err := walletID.Scan(strings.TrimSpace(c.Param("id"))); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet id"})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("scheduledId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid scheduled transaction id"})
+		return
+	}
+
+	st, err := h.service.Scheduled.Cancel(c.Request.Context(), walletID, id)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, st)
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	"github.com/KatenkaKet/wallet/pkg/service"
+	mock_service "github.com/KatenkaKet/wallet/pkg/service/mocks"
+	"github.com/golang/mock/gomock"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestHandler_scheduledTransactions(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockScheduled, walletID uuid.UUID)
+
+	walletID := uuidFromString("11111111-1111-1111-1111-111111111111")
+	executeAt := time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)
+	created := time.Date(2029, 12, 31, 9, 0, 0, 0, time.UTC)
+	pending := wallet.ScheduledTransaction{Id: 7, ValletId: walletID, OperationType: "WITHDRAW", Amount: 5,
+		ExecuteAt: executeAt, Status: wallet.ScheduledPending, CreatedAt: created}
+	pendingJSON := `{"id":7,"valletId":"11111111-1111-1111-1111-111111111111","operationType":"WITHDRAW","amount":5,` +
+		`"executeAt":"2030-01-01T09:00:00Z","status":"pending","createdAt":"2029-12-31T09:00:00Z"}`
+
+	testTable := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		mockBehavior mockBehavior
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:   "schedule",
+			method: "POST",
+			path:   "/scheduled",
+			body:   `{"operationType":"WITHDRAW","amount":5,"executeAt":"2030-01-01T12:00:00+03:00"}`,
+			mockBehavior: func(s *mock_service.MockScheduled, walletID uuid.UUID) {
+				s.EXPECT().Schedule(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ any, st wallet.ScheduledTransaction) (wallet.ScheduledTransaction, error) {
+						if st.ValletId != walletID || !st.ExecuteAt.Equal(executeAt) || st.Amount != 5 || st.OperationType != "WITHDRAW" {
+							return wallet.ScheduledTransaction{}, fmt.Errorf("unexpected %+v", st)
+						}
+						return pending, nil
+					})
+			},
+			expectedCode: http.StatusCreated,
+			expectedBody: pendingJSON,
+		},
+		{
+			name:         "schedule without time",
+			method:       "POST",
+			path:         "/scheduled",
+			body:         `{"operationType":"DEPOSIT","amount":5}`,
+			mockBehavior: func(s *mock_service.MockScheduled, walletID uuid.UUID) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"Key: 'scheduleRequest.ExecuteAt' Error:Field validation for 'ExecuteAt' failed on the 'required' tag"}`,
+		},
+		{
+			name:   "schedule in the past",
+			method: "POST",
+			path:   "/scheduled",
+			body:   `{"operationType":"DEPOSIT","amount":5,"executeAt":"2020-01-01T00:00:00Z"}`,
+			mockBehavior: func(s *mock_service.MockScheduled, walletID uuid.UUID) {
+				s.EXPECT().Schedule(gomock.Any(), gomock.Any()).
+					Return(wallet.ScheduledTransaction{}, fmt.Errorf("%w: executeAt must be in the future", service.ErrInvalidScheduled))
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid scheduled transaction: executeAt must be in the future"}`,
+		},
+		{
+			name:   "list",
+			method: "GET",
+			path:   "/scheduled?status=pending",
+			mockBehavior: func(s *mock_service.MockScheduled, walletID uuid.UUID) {
+				s.EXPECT().ListScheduled(gomock.Any(), walletID, "pending", 100).Return([]wallet.ScheduledTransaction{pending}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"scheduled":[` + pendingJSON + `]}`,
+		},
+		{
+			name:         "list with invalid limit",
+			method:       "GET",
+			path:         "/scheduled?limit=0",
+			mockBehavior: func(s *mock_service.MockScheduled, walletID uuid.UUID) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid limit"}`,
+		},
+		{
+			name:   "cancel",
+			method: "POST",
+			path:   "/scheduled/7/cancel",
+			mockBehavior: func(s *mock_service.MockScheduled, walletID uuid.UUID) {
+				cancelled := pending
+				cancelled.Status = wallet.ScheduledCancelled
+				s.EXPECT().Cancel(gomock.Any(), walletID, int64(7)).Return(cancelled, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"id":7,"valletId":"11111111-1111-1111-1111-111111111111","operationType":"WITHDRAW","amount":5,` +
+				`"executeAt":"2030-01-01T09:00:00Z","status":"cancelled","createdAt":"2029-12-31T09:00:00Z"}`,
+		},
+		{
+			name:   "cancel finished",
+			method: "POST",
+			path:   "/scheduled/7/cancel",
+			mockBehavior: func(s *mock_service.MockScheduled, walletID uuid.UUID) {
+				s.EXPECT().Cancel(gomock.Any(), walletID, int64(7)).Return(wallet.ScheduledTransaction{}, repository.ErrScheduledNotPending)
+			},
+			expectedCode: http.StatusConflict,
+			expectedBody: `{"error":"scheduled transaction is not pending"}`,
+		},
+		{
+			name:   "cancel missing",
+			method: "POST",
+			path:   "/scheduled/8/cancel",
+			mockBehavior: func(s *mock_service.MockScheduled, walletID uuid.UUID) {
+				s.EXPECT().Cancel(gomock.Any(), walletID, int64(8)).Return(wallet.ScheduledTransaction{}, repository.ErrScheduledNotFound)
+			},
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"error":"scheduled transaction not found"}`,
+		},
+		{
+			name:         "cancel with invalid id",
+			method:       "POST",
+			path:         "/scheduled/abc/cancel",
+			mockBehavior: func(s *mock_service.MockScheduled, walletID uuid.UUID) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid scheduled transaction id"}`,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockScheduled := mock_service.NewMockScheduled(ctrl)
+			test.mockBehavior(mockScheduled, walletID)
+
+			srv := &service.Service{Scheduled: mockScheduled}
+			h := NewHandler(srv, Config{})
+			r := h.InitRoutes()
+
+			req := httptest.NewRequest(test.method, "/api/v1/wallets/"+walletID.UUID.String()+test.path, bytes.NewBufferString(test.body))
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.Equal(t, test.expectedBody, w.Body.String())
+		})
+	}
+}
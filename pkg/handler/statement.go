@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KatenkaKet/wallet/pkg/export"
+	"github.com/gin-gonic/gin"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// listWalletStatements godoc
+// @Summary Месячные выписки кошелька
+// @Description Выписки формируются в начале месяца за предыдущий календарный месяц (UTC) для кошельков с операциями в нём.
+// @Description Каждая выписка содержит балансы на начало и конец месяца, итоги по типам операций и форматы файлов,
+// @Description которые можно скачать через /wallets/{id}/statements/{period}.
+// @Tags wallet
+// @Produce json
+// @Param id path string true "ID кошелька"
+// @Success 200 {object} map[string][]wallet.Statement "statements: выписки, последние первыми"
+// @Failure 400 {object} map[string]string "Неверный ID"
+// @Failure 404 {object} map[string]string "Кошелёк не найден"
+// @Failure 500 {object} map[string]string "Ошибка сервера"
+// @Router /wallets/{id}/statements [get]
+func (h *Handler) listWalletStatements(c *gin.Context) {
+	var walletID uuid.UUID
+	if err := walletID.Scan(strings.TrimSpace(c.Param("id"))); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet id"})
+		return
+	}
+
+	statements, err := h.service.Statement.ListStatements(c.Request.Context(), walletID)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"statements": statements})
+}
+
+// getWalletStatement godoc
+// @Summary Файл месячной выписки кошелька
+// @Tags wallet
+// @Produce text/html
+// @Produce text/csv
+// @Param id path string true "ID кошелька"
+// @Param period path string true "Месяц выписки, YYYY-MM"
+// @Param format query string false "html или csv" default(html)
+// @Success 200 {file} file "Выписка"
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 404 {object} map[string]string "Выписка не найдена"
+// @Failure 500 {object} map[string]string "Ошибка сервера"
+// @Router /wallets/{id}/statements/{period} [get]
+func (h *Handler) getWalletStatement(c *gin.Context) {
+	var walletID uuid.UUID
+	if err := walletID.Scan(strings.TrimSpace(c.Param("id"))); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet id"})
+		return
+	}
+
+	period := c.Param("period")
+	from, err := time.Parse("2006-01", period)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be YYYY-MM"})
+		return
+	}
+
+	format := c.DefaultQuery("format", export.FormatHTML)
+	if format != export.FormatHTML && format != export.FormatCSV {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be html or csv"})
+		return
+	}
+
+	r, err := h.service.Statement.OpenStatement(c.Request.Context(), walletID, from, format)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	defer r.Close()
+
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q",
+		fmt.Sprintf("wallet-%s-statement-%s.%s", walletID.UUID.String(), period, format)))
+	c.Status(http.StatusOK)
+	if _, err := io.Copy(c.Writer, r); err != nil {
+		log.Printf("statement %s of wallet %s interrupted: %s", period, walletID.UUID.String(), err.Error())
+	}
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	"github.com/KatenkaKet/wallet/pkg/service"
+	mock_service "github.com/KatenkaKet/wallet/pkg/service/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestHandler_listWalletStatements(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockStatement, walletID uuid.UUID)
+
+	walletID := uuidFromString("11111111-1111-1111-1111-111111111111")
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name         string
+		walletID     string
+		mockBehavior mockBehavior
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:     "ok",
+			walletID: walletID.UUID.String(),
+			mockBehavior: func(s *mock_service.MockStatement, walletID uuid.UUID) {
+				s.EXPECT().ListStatements(gomock.Any(), walletID).Return([]wallet.Statement{{
+					ValletId:       walletID,
+					Period:         "2024-01",
+					From:           jan,
+					To:             jan.AddDate(0, 1, 0),
+					OpeningBalance: 10,
+					ClosingBalance: 12.5,
+					Deposits:       wallet.StatementTotal{Count: 1, Sum: 2.5},
+					Formats:        []string{"html", "csv"},
+					CreatedAt:      jan.AddDate(0, 1, 0),
+				}}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"statements":[{"valletId":"11111111-1111-1111-1111-111111111111","period":"2024-01",` +
+				`"from":"2024-01-01T00:00:00Z","to":"2024-02-01T00:00:00Z","openingBalance":10,"closingBalance":12.5,` +
+				`"deposits":{"count":1,"sum":2.5},"withdrawals":{"count":0,"sum":0},"formats":["html","csv"],` +
+				`"createdAt":"2024-02-01T00:00:00Z"}]}`,
+		},
+		{
+			name:     "no statements",
+			walletID: walletID.UUID.String(),
+			mockBehavior: func(s *mock_service.MockStatement, walletID uuid.UUID) {
+				s.EXPECT().ListStatements(gomock.Any(), walletID).Return([]wallet.Statement{}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"statements":[]}`,
+		},
+		{
+			name:     "wallet not found",
+			walletID: walletID.UUID.String(),
+			mockBehavior: func(s *mock_service.MockStatement, walletID uuid.UUID) {
+				s.EXPECT().ListStatements(gomock.Any(), walletID).Return(nil, repository.ErrWalletNotFound)
+			},
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"error":"wallet not found"}`,
+		},
+		{
+			name:         "invalid wallet id",
+			walletID:     "not-a-uuid",
+			mockBehavior: func(s *mock_service.MockStatement, walletID uuid.UUID) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid wallet id"}`,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStatement := mock_service.NewMockStatement(ctrl)
+			test.mockBehavior(mockStatement, walletID)
+
+			srv := &service.Service{Statement: mockStatement}
+			h := NewHandler(srv, Config{})
+
+			r := gin.New()
+			r.GET("/api/v1/wallets/:id/statements", h.listWalletStatements)
+
+			req := httptest.NewRequest("GET", "/api/v1/wallets/"+test.walletID+"/statements", nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.Equal(t, test.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_getWalletStatement(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockStatement, walletID uuid.UUID)
+
+	walletID := uuidFromString("11111111-1111-1111-1111-111111111111")
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                string
+		path                string
+		mockBehavior        mockBehavior
+		expectedCode        int
+		expectedContentType string
+		expectedDisposition string
+		expectedBody        string
+	}{
+		{
+			name: "html by default",
+			path: "/2024-01",
+			mockBehavior: func(s *mock_service.MockStatement, walletID uuid.UUID) {
+				s.EXPECT().OpenStatement(gomock.Any(), walletID, jan, "html").
+					Return(io.NopCloser(strings.NewReader("<html></html>")), nil)
+			},
+			expectedCode:        http.StatusOK,
+			expectedContentType: "text/html; charset=utf-8",
+			expectedDisposition: `attachment; filename="wallet-11111111-1111-1111-1111-111111111111-statement-2024-01.html"`,
+			expectedBody:        "<html></html>",
+		},
+		{
+			name: "csv",
+			path: "/2024-01?format=csv",
+			mockBehavior: func(s *mock_service.MockStatement, walletID uuid.UUID) {
+				s.EXPECT().OpenStatement(gomock.Any(), walletID, jan, "csv").
+					Return(io.NopCloser(strings.NewReader("row_type\n")), nil)
+			},
+			expectedCode:        http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedDisposition: `attachment; filename="wallet-11111111-1111-1111-1111-111111111111-statement-2024-01.csv"`,
+			expectedBody:        "row_type\n",
+		},
+		{
+			name: "not found",
+			path: "/2023-12",
+			mockBehavior: func(s *mock_service.MockStatement, walletID uuid.UUID) {
+				s.EXPECT().OpenStatement(gomock.Any(), walletID, jan.AddDate(0, -1, 0), "html").
+					Return(nil, fmt.Errorf("failed to get statement: %w", repository.ErrStatementNotFound))
+			},
+			expectedCode:        http.StatusNotFound,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"error":"failed to get statement: statement not found"}`,
+		},
+		{
+			name: "storage error",
+			path: "/2024-01",
+			mockBehavior: func(s *mock_service.MockStatement, walletID uuid.UUID) {
+				s.EXPECT().OpenStatement(gomock.Any(), walletID, jan, "html").Return(nil, errors.New("disk error"))
+			},
+			expectedCode:        http.StatusInternalServerError,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"error":"disk error"}`,
+		},
+		{
+			name:                "invalid period",
+			path:                "/2024-1",
+			mockBehavior:        func(s *mock_service.MockStatement, walletID uuid.UUID) {},
+			expectedCode:        http.StatusBadRequest,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"error":"period must be YYYY-MM"}`,
+		},
+		{
+			name:                "unknown format",
+			path:                "/2024-01?format=pdf",
+			mockBehavior:        func(s *mock_service.MockStatement, walletID uuid.UUID) {},
+			expectedCode:        http.StatusBadRequest,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"error":"format must be html or csv"}`,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStatement := mock_service.NewMockStatement(ctrl)
+			test.mockBehavior(mockStatement, walletID)
+
+			srv := &service.Service{Statement: mockStatement}
+			h := NewHandler(srv, Config{})
+
+			r := gin.New()
+			r.GET("/api/v1/wallets/:id/statements/:period", h.getWalletStatement)
+
+			req := httptest.NewRequest("GET", "/api/v1/wallets/"+walletID.UUID.String()+"/statements"+test.path, nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.Equal(t, test.expectedContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, test.expectedDisposition, w.Header().Get("Content-Disposition"))
+			assert.Equal(t, test.expectedBody, w.Body.String())
+		})
+	}
+}
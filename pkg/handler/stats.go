@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// getWalletStats godoc
+// @Summary Статистика операций кошелька
+// @Description Число и сумма пополнений и списаний по интервалам (day, week или month; UTC, неделя - с понедельника)
+// @Description за период [from, to), включая интервалы без операций. from и to - дата (YYYY-MM-DD, to включительно)
+// @Description или время RFC 3339; без to - по конец текущего дня, без from - последние 30 дней, 12 недель или 12 месяцев.
+// @Tags wallet
+// @Produce json
+// @Param id path string true "ID кошелька"
+// @Param from query string false "Начало периода"
+// @Param to query string false "Конец периода"
+// @Param interval query string false "day, week или month" default(day)
+// @Success 200 {object} wallet.Stats
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 404 {object} map[string]string "Кошелёк не найден"
+// @Failure 500 {object} map[string]string "Ошибка сервера"
+// @Router /wallets/{id}/stats [get]
+func (h *Handler) getWalletStats(c *gin.Context) {
+	var walletID uuid.UUID
+	if err := walletID.Scan(strings.TrimSpace(c.Param("id"))); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet id"})
+		return
+	}
+
+	from, to, ok := statsPeriod(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.service.Stats.WalletStats(c.Request.Context(), walletID, from, to, c.Query("interval"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// getGlobalStats godoc
+// @Summary Статистика операций по всем кошелькам
+// @Description Параметры и ответ - как у /wallets/{id}/stats, но по всем кошелькам.
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param from query string false "Начало периода"
+// @Param to query string false "Конец периода"
+// @Param interval query string false "day, week или month" default(day)
+// @Success 200 {object} wallet.Stats
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 401 {object} map[string]string "Неверный токен"
+// @Failure 500 {object} map[string]string "Ошибка сервера"
+// @Router /admin/stats [get]
+func (h *Handler) getGlobalStats(c *gin.Context) {
+	from, to, ok := statsPeriod(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.service.Stats.GlobalStats(c.Request.Context(), from, to, c.Query("interval"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// statsPeriod разбирает from и to; при ошибке отвечает 400 и возвращает ok = false.
+func statsPeriod(c *gin.Context) (from, to time.Time, ok bool) {
+	from, err := parsePeriodBound(c.Query("from"), false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from: " + err.Error()})
+		return from, to, false
+	}
+	to, err = parsePeriodBound(c.Query("to"), true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to: " + err.Error()})
+		return from, to, false
+	}
+	return from, to, true
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	"github.com/KatenkaKet/wallet/pkg/service"
+	mock_service "github.com/KatenkaKet/wallet/pkg/service/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestHandler_getWalletStats(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockStats, walletID uuid.UUID)
+
+	walletID := uuidFromString("11111111-1111-1111-1111-111111111111")
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name         string
+		walletID     string
+		query        string
+		mockBehavior mockBehavior
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:     "ok",
+			walletID: walletID.UUID.String(),
+			query:    "?from=2024-01-01&to=2024-01-02&interval=day",
+			mockBehavior: func(s *mock_service.MockStats, walletID uuid.UUID) {
+				s.EXPECT().WalletStats(gomock.Any(), walletID, jan, jan.AddDate(0, 0, 2), "day").Return(wallet.Stats{
+					From:     jan,
+					To:       jan.AddDate(0, 0, 2),
+					Interval: "day",
+					Deposits: wallet.StatementTotal{Count: 1, Sum: 2.5},
+					Buckets: []wallet.StatsBucket{
+						{Start: jan, Deposits: wallet.StatementTotal{Count: 1, Sum: 2.5}},
+						{Start: jan.AddDate(0, 0, 1)},
+					},
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"from":"2024-01-01T00:00:00Z","to":"2024-01-03T00:00:00Z","interval":"day",` +
+				`"deposits":{"count":1,"sum":2.5},"withdrawals":{"count":0,"sum":0},"buckets":[` +
+				`{"start":"2024-01-01T00:00:00Z","deposits":{"count":1,"sum":2.5},"withdrawals":{"count":0,"sum":0}},` +
+				`{"start":"2024-01-02T00:00:00Z","deposits":{"count":0,"sum":0},"withdrawals":{"count":0,"sum":0}}]}`,
+		},
+		{
+			name:     "invalid interval",
+			walletID: walletID.UUID.String(),
+			query:    "?interval=hour",
+			mockBehavior: func(s *mock_service.MockStats, walletID uuid.UUID) {
+				s.EXPECT().WalletStats(gomock.Any(), walletID, time.Time{}, time.Time{}, "hour").
+					Return(wallet.Stats{}, service.ErrInvalidStats)
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid stats request"}`,
+		},
+		{
+			name:     "wallet not found",
+			walletID: walletID.UUID.String(),
+			mockBehavior: func(s *mock_service.MockStats, walletID uuid.UUID) {
+				s.EXPECT().WalletStats(gomock.Any(), walletID, time.Time{}, time.Time{}, "").
+					Return(wallet.Stats{}, repository.ErrWalletNotFound)
+			},
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"error":"wallet not found"}`,
+		},
+		{
+			name:         "invalid date",
+			walletID:     walletID.UUID.String(),
+			query:        "?to=tomorrow",
+			mockBehavior: func(s *mock_service.MockStats, walletID uuid.UUID) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid to: expected YYYY-MM-DD or RFC 3339 time"}`,
+		},
+		{
+			name:         "invalid wallet id",
+			walletID:     "not-a-uuid",
+			mockBehavior: func(s *mock_service.MockStats, walletID uuid.UUID) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid wallet id"}`,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStats := mock_service.NewMockStats(ctrl)
+			test.mockBehavior(mockStats, walletID)
+
+			srv := &service.Service{Stats: mockStats}
+			h := NewHandler(srv, Config{})
+
+			r := gin.New()
+			r.GET("/api/v1/wallets/:id/stats", h.getWalletStats)
+
+			req := httptest.NewRequest("GET", "/api/v1/wallets/"+test.walletID+"/stats"+test.query, nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.Equal(t, test.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_getGlobalStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mockStats := mock_service.NewMockStats(ctrl)
+	mockStats.EXPECT().GlobalStats(gomock.Any(), jan, time.Time{}, "month").
+		Return(wallet.Stats{From: jan, To: jan.AddDate(0, 1, 0), Interval: "month", Buckets: []wallet.StatsBucket{}}, nil)
+
+	h := NewHandler(&service.Service{Stats: mockStats}, Config{AdminToken: "token"})
+	r := h.InitRoutes()
+
+	req := httptest.NewRequest("GET", "/api/v1/admin/stats?from=2024-01-01&interval=month", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req = httptest.NewRequest("GET", "/api/v1/admin/stats?from=2024-01-01&interval=month", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"from":"2024-01-01T00:00:00Z","to":"2024-02-01T00:00:00Z","interval":"month",`+
+		`"deposits":{"count":0,"sum":0},"withdrawals":{"count":0,"sum":0},"buckets":[]}`, w.Body.String())
+}
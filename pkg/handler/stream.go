@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+const (
+	defaultStreamHeartbeat = 15 * time.Second
+	wsWriteTimeout         = 10 * time.Second
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+}
+
+// streamWallet godoc
+// @Summary Поток изменений баланса кошелька (Server-Sent Events)
+// @Description Первое событие - snapshot с текущим балансом, далее событие transaction на каждую операцию:
+// @Description новый баланс и транзакция. id события - seq; после переподключения клиент передаёт его в
+// @Description Last-Event-ID (или ?afterSeq=) и получает пропущенные транзакции по порядку.
+// @Description Раз в 15 секунд отправляется комментарий-heartbeat.
+// @Tags wallet
+// @Produce text/event-stream
+// @Param id path string true "ID кошелька"
+// @Param Last-Event-ID header int false "seq последнего полученного события"
+// @Param afterSeq query int false "seq, после которого продолжить поток"
+// @Success 200 {object} wallet.BalanceUpdate "Поток событий snapshot и transaction"
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 404 {object} map[string]string "Кошелёк не найден"
+// @Router /wallets/{id}/stream [get]
+func (h *Handler) streamWallet(c *gin.Context) {
+	walletID, afterSeq, ok := streamParams(c)
+	if !ok {
+		return
+	}
+
+	updates, err := h.service.Stream.Subscribe(c.Request.Context(), walletID, afterSeq)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // без буферизации в nginx
+
+	// WriteTimeout сервера рассчитан на обычные запросы, поток ограничен только отключением клиента.
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	heartbeat := time.NewTicker(h.streamHeartbeat())
+	defer heartbeat.Stop()
+
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+			c.Render(-1, sse.Event{Id: strconv.FormatInt(update.Seq, 10), Event: update.Type, Data: update})
+		case <-heartbeat.C:
+			if _, err := io.WriteString(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+		case <-c.Request.Context().Done():
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// streamWalletWS godoc
+// @Summary Поток изменений баланса кошелька (WebSocket)
+// @Description Те же сообщения, что и в /wallets/{id}/stream, по одному JSON (wallet.BalanceUpdate) в текстовом кадре;
+// @Description тип сообщения - в поле type. Для возобновления передайте seq последнего сообщения в ?afterSeq=.
+// @Tags wallet
+// @Param id path string true "ID кошелька"
+// @Param afterSeq query int false "seq, после которого продолжить поток"
+// @Success 101 {object} wallet.BalanceUpdate "Переключение на WebSocket"
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 404 {object} map[string]string "Кошелёк не найден"
+// @Router /wallets/{id}/ws [get]
+func (h *Handler) streamWalletWS(c *gin.Context) {
+	walletID, afterSeq, ok := streamParams(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	// Подписка до переключения протокола, чтобы ошибки (например, 404) вернулись обычным HTTP-ответом.
+	updates, err := h.service.Stream.Subscribe(ctx, walletID, afterSeq)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return // Upgrade уже ответил клиенту
+	}
+	defer conn.Close()
+
+	heartbeat := h.streamHeartbeat()
+
+	// Чтение нужно для обработки pong и закрытия соединения клиентом; сообщения клиента игнорируются.
+	conn.SetReadLimit(512)
+	conn.SetReadDeadline(time.Now().Add(2 * heartbeat))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * heartbeat))
+	})
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(heartbeat)
+	defer ping.Stop()
+
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(wsWriteTimeout))
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteJSON(update); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// streamParams разбирает ID кошелька и точку возобновления потока: Last-Event-ID или afterSeq, иначе -1 (со снимка).
+func streamParams(c *gin.Context) (uuid.UUID, int64, bool) {
+	var walletID uuid.UUID
+	if err := walletID.Scan(strings.TrimSpace(c.Param("id"))); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet id"})
+		return walletID, 0, false
+	}
+
+	resume := c.GetHeader("Last-Event-ID")
+	if q, ok := c.GetQuery("afterSeq"); ok {
+		resume = q
+	}
+	if resume == "" {
+		return walletID, -1, true
+	}
+
+	afterSeq, err := strconv.ParseInt(strings.TrimSpace(resume), 10, 64)
+	if err != nil || afterSeq < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid afterSeq"})
+		return walletID, 0, false
+	}
+	return walletID, afterSeq, true
+}
+
+func (h *Handler) streamHeartbeat() time.Duration {
+	if h.cfg.StreamHeartbeat > 0 {
+		return h.cfg.StreamHeartbeat
+	}
+	return defaultStreamHeartbeat
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	"github.com/KatenkaKet/wallet/pkg/service"
+	mock_service "github.com/KatenkaKet/wallet/pkg/service/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func streamUpdates(walletID string) chan wallet.BalanceUpdate {
+	id := uuidFromString(walletID)
+	updates := make(chan wallet.BalanceUpdate, 2)
+	updates <- wallet.BalanceUpdate{Type: wallet.UpdateSnapshot, ValletId: id, Seq: 3, Balance: 10}
+	updates <- wallet.BalanceUpdate{Type: wallet.UpdateTransaction, ValletId: id, Seq: 4, Balance: 15,
+		Transaction: &wallet.WalletTransactions{ValletId: id, OperationType: "DEPOSIT", Amount: 5, Seq: 4, BalanceAfter: 15}}
+	close(updates)
+	return updates
+}
+
+func TestHandler_streamWallet(t *testing.T) {
+	const walletID = "11111111-1111-1111-1111-111111111111"
+
+	testTable := []struct {
+		name               string
+		path               string
+		lastEventID        string
+		mockBehavior       func(s *mock_service.MockStream)
+		expectedStatusCode int
+		expectedBody       []string
+	}{
+		{
+			name: "from snapshot",
+			path: "/wallets/" + walletID + "/stream",
+			mockBehavior: func(s *mock_service.MockStream) {
+				s.EXPECT().Subscribe(gomock.Any(), uuidFromString(walletID), int64(-1)).Return(streamUpdates(walletID), nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: []string{
+				"id:3\nevent:snapshot\ndata:{\"type\":\"snapshot\",\"valletId\":\"" + walletID + "\",\"seq\":3,\"balance\":10}\n\n",
+				"id:4\nevent:transaction\n",
+			},
+		},
+		{
+			name:        "resume from Last-Event-ID",
+			path:        "/wallets/" + walletID + "/stream",
+			lastEventID: "7",
+			mockBehavior: func(s *mock_service.MockStream) {
+				s.EXPECT().Subscribe(gomock.Any(), uuidFromString(walletID), int64(7)).Return(streamUpdates(walletID), nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:        "afterSeq overrides Last-Event-ID",
+			path:        "/wallets/" + walletID + "/stream?afterSeq=9",
+			lastEventID: "7",
+			mockBehavior: func(s *mock_service.MockStream) {
+				s.EXPECT().Subscribe(gomock.Any(), uuidFromString(walletID), int64(9)).Return(streamUpdates(walletID), nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "invalid wallet id",
+			path:               "/wallets/nope/stream",
+			mockBehavior:       func(s *mock_service.MockStream) {},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       []string{`{"error":"invalid wallet id"}`},
+		},
+		{
+			name:               "invalid afterSeq",
+			path:               "/wallets/" + walletID + "/stream?afterSeq=-2",
+			mockBehavior:       func(s *mock_service.MockStream) {},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       []string{`{"error":"invalid afterSeq"}`},
+		},
+		{
+			name: "wallet not found",
+			path: "/wallets/" + walletID + "/stream",
+			mockBehavior: func(s *mock_service.MockStream) {
+				s.EXPECT().Subscribe(gomock.Any(), gomock.Any(), int64(-1)).Return(nil, repository.ErrWalletNotFound)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			stream := mock_service.NewMockStream(ctrl)
+			test.mockBehavior(stream)
+
+			h := NewHandler(&service.Service{Stream: stream}, Config{})
+			r := gin.New()
+			r.GET("/wallets/:id/stream", h.streamWallet)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", test.path, nil)
+			if test.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", test.lastEventID)
+			}
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			if w.Code == http.StatusOK {
+				assert.Contains(t, w.Header().Get("Content-Type"), "text/event-stream")
+			}
+			for _, part := range test.expectedBody {
+				assert.Contains(t, w.Body.String(), part)
+			}
+		})
+	}
+}
+
+func TestHandler_streamWalletWS(t *testing.T) {
+	const walletID = "11111111-1111-1111-1111-111111111111"
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	stream := mock_service.NewMockStream(ctrl)
+	stream.EXPECT().Subscribe(gomock.Any(), uuidFromString(walletID), int64(2)).Return(streamUpdates(walletID), nil)
+	stream.EXPECT().Subscribe(gomock.Any(), gomock.Any(), int64(-1)).Return(nil, repository.ErrWalletNotFound)
+
+	h := NewHandler(&service.Service{Stream: stream}, Config{})
+	r := gin.New()
+	r.GET("/wallets/:id/ws", h.streamWalletWS)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/wallets/" + walletID + "/ws"
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?afterSeq=2", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var first, second wallet.BalanceUpdate
+	require.NoError(t, conn.ReadJSON(&first))
+	require.NoError(t, conn.ReadJSON(&second))
+	assert.Equal(t, wallet.UpdateSnapshot, first.Type)
+	assert.Equal(t, int64(3), first.Seq)
+	assert.Equal(t, wallet.UpdateTransaction, second.Type)
+	require.NotNil(t, second.Transaction)
+	assert.Equal(t, 5.0, second.Transaction.Amount)
+
+	// Поток закончился - сервер закрывает соединение.
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+
+	// Ошибка подписки возвращается обычным HTTP-ответом, без переключения протокола.
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	"github.com/KatenkaKet/wallet/pkg/service"
+	"github.com/gin-gonic/gin"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Нагрузочный тест на гонки: тысячи параллельных пополнений и списаний одного "горячего" кошелька
+// через настоящий HTTP-стек (роутер, хендлеры, сервис, репозиторий в памяти).
+// Запуск с детектором гонок: go test -race -run Stress ./pkg/handler/
+
+const (
+	stressWallet     = "11111111-1111-1111-1111-111111111111"
+	stressInitial    = 1000.0
+	stressOperations = 4000
+	stressWorkers    = 64
+)
+
+type stressOp struct {
+	operationType string
+	amount        float64
+}
+
+func TestHandler_Stress_HotWallet(t *testing.T) {
+	if testing.Short() {
+		t.Skip("stress test is skipped in short mode")
+	}
+	gin.SetMode(gin.TestMode)
+
+	t.Run("row lock", func(t *testing.T) {
+		runStress(t, service.Config{})
+	})
+
+	t.Run("batched", func(t *testing.T) {
+		runStress(t, service.Config{
+			HotWallets:        []uuid.UUID{uuidFromString(stressWallet)},
+			HotWalletMaxBatch: 16,
+		})
+	})
+}
+
+func runStress(t *testing.T, cfg service.Config) {
+	mem := repository.NewWalletMemory()
+	mem.AddWallet(uuidFromString(stressWallet), stressInitial)
+	services := service.NewService(repository.NewMemoryRepository(mem), cfg)
+	defer services.Close()
+	h := NewHandler(services, Config{})
+
+	srv := httptest.NewServer(h.InitRoutes())
+	defer srv.Close()
+
+	client := &http.Client{Transport: &http.Transport{MaxIdleConnsPerHost: stressWorkers}}
+
+	ops := make(chan stressOp)
+	go func() {
+		defer close(ops)
+		for i := 0; i < stressOperations; i++ {
+			// Списаний больше, чем пополнений, чтобы баланс регулярно упирался в ноль.
+			switch i % 3 {
+			case 0:
+				ops <- stressOp{operationType: "DEPOSIT", amount: 7.5}
+			default:
+				ops <- stressOp{operationType: "WITHDRAW", amount: 5.25}
+			}
+		}
+	}()
+
+	var (
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+		expected   = stressInitial
+		succeeded  int64
+		rejected   int64
+		stopPoll   = make(chan struct{})
+		pollerDone = make(chan struct{})
+	)
+
+	// Параллельно читаем баланс: ни одно промежуточное состояние не должно быть отрицательным.
+	go func() {
+		defer close(pollerDone)
+		for {
+			select {
+			case <-stopPoll:
+				return
+			default:
+			}
+			balance, err := stressGetBalance(client, srv.URL)
+			if assert.NoError(t, err) {
+				assert.GreaterOrEqual(t, balance, 0.0)
+			}
+		}
+	}()
+
+	for i := 0; i < stressWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for op := range ops {
+				code, body, err := stressPostTransaction(client, srv.URL, op)
+				if !assert.NoError(t, err) {
+					continue
+				}
+
+				switch {
+				case code == http.StatusOK:
+					atomic.AddInt64(&succeeded, 1)
+					delta := op.amount
+					if op.operationType == "WITHDRAW" {
+						delta = -delta
+					}
+					mu.Lock()
+					expected += delta
+					mu.Unlock()
+				case op.operationType == "WITHDRAW" && strings.Contains(body, "insufficient funds"):
+					atomic.AddInt64(&rejected, 1)
+				default:
+					t.Errorf("unexpected response %d: %s", code, body)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(stopPoll)
+	<-pollerDone
+
+	balance, err := stressGetBalance(client, srv.URL)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(stressOperations), succeeded+rejected)
+	assert.Greater(t, rejected, int64(0), "the load should drive the wallet to zero at least once")
+	assert.InDelta(t, expected, balance, 0.001)
+	assert.GreaterOrEqual(t, balance, 0.0)
+}
+
+func stressPostTransaction(client *http.Client, baseURL string, op stressOp) (int, string, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"valletId":      stressWallet,
+		"operationType": op.operationType,
+		"amount":        op.amount,
+	})
+	if err != nil {
+		return 0, "", err
+	}
+
+	resp, err := client.Post(baseURL+"/api/v1/wallet", "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body), err
+}
+
+func stressGetBalance(client *http.Client, baseURL string) (float64, error) {
+	resp, err := client.Get(baseURL + "/api/v1/wallets/" + stressWallet)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Balance float64 `json:"balance"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, err
+	}
+	return body.Balance, nil
+}
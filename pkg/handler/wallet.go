@@ -2,9 +2,11 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/receipt"
 	"github.com/gin-gonic/gin"
 	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
 )
@@ -15,8 +17,12 @@ import (
 // @Accept json
 // @Produce json
 // @Param transaction body wallet.WalletTransactions true "Данные транзакции"
-// @Success 200 {object} map[string]string "status: success"
+// @Param If-Match header string false "Версия кошелька (ETag); операция выполнится, только если кошелёк не менялся"
+// @Success 200 {object} transactionResponse "status: success; receipt - подписанная квитанция, если настроен ключ"
+// @Header 200 {string} ETag "Версия кошелька после операции"
 // @Failure 400 {object} map[string]string "Ошибка валидации или неверные данные"
+// @Failure 412 {object} map[string]string "Версия кошелька не совпадает с If-Match"
+// @Failure 422 {object} map[string]string "Недостаточно средств"
 // @Failure 500 {object} map[string]string "Ошибка при обновлении баланса"
 // @Router /wallet [post]
 func (h *Handler) createWalletTransaction(c *gin.Context) {
@@ -32,14 +38,34 @@ func (h *Handler) createWalletTransaction(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.Wallet.UpdateBalance(c.Request.Context(), WT); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	expected, err := parseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	WT.ExpectedVersion = expected
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": "success",
-	})
+	recorded, err := h.service.Wallet.UpdateBalance(c.Request.Context(), WT)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := transactionResponse{Status: "success"}
+	if rc, ok := h.service.Receipt.Issue(recorded); ok {
+		resp.Receipt = &rc
+	}
+
+	// У повтора по idempotencyKey возвращается исходная операция, версия кошелька для неё неизвестна.
+	if recorded.Version != 0 {
+		c.Header("ETag", formatETag(recorded.Version))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+type transactionResponse struct {
+	Status  string           `json:"status"`
+	Receipt *receipt.Receipt `json:"receipt,omitempty"`
 }
 
 // getWalletBalance godoc
@@ -47,8 +73,12 @@ func (h *Handler) createWalletTransaction(c *gin.Context) {
 // @Tags wallet
 // @Produce json
 // @Param id path string true "ID кошелька"
-// @Success 200 {object} map[string]float64 "Баланс кошелька"
+// @Param If-None-Match header string false "ETag из предыдущего ответа; при совпадении вернётся 304 без тела"
+// @Success 200 {object} balanceResponse "Баланс кошелька, остатки корзин, кредитный лимит и доступная к списанию сумма"
+// @Header 200 {string} ETag "Версия кошелька"
+// @Success 304 "Кошелёк не изменился"
 // @Failure 400 {object} map[string]string "Неверный ID кошелька"
+// @Failure 404 {object} map[string]string "Кошелёк не найден"
 // @Failure 500 {object} map[string]string "Ошибка сервера"
 // @Router /wallets/{id} [get]
 func (h *Handler) getWalletBalance(c *gin.Context) {
@@ -62,13 +92,167 @@ func (h *Handler) getWalletBalance(c *gin.Context) {
 		return
 	}
 
-	balance, err := h.service.Wallet.GetBalance(c.Request.Context(), walletID)
+	wlt, err := h.service.Wallet.GetWallet(c.Request.Context(), walletID)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	etag := formatETag(wlt.Version)
+	c.Header("ETag", etag)
+	if ifNoneMatch(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.JSON(http.StatusOK, balanceResponse{Balance: wlt.Balance, CreditLimit: wlt.CreditLimit, Available: wlt.Available,
+		Buckets: wlt.Buckets})
+}
+
+// balanceResponse - баланс кошелька; при кредитном лимите Balance может быть отрицательным (овердрафт).
+// Buckets - остатки корзин, в сумме равные Balance.
+type balanceResponse struct {
+	Balance     float64            `json:"balance"`
+	CreditLimit float64            `json:"creditLimit"`
+	Available   float64            `json:"available"`
+	Buckets     map[string]float64 `json:"buckets,omitempty"`
+}
+
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+// listWalletTransactions godoc
+// @Summary История операций кошелька по порядковым номерам
+// @Description Транзакции кошелька нумеруются 1, 2, 3... без пропусков и содержат баланс после операции,
+// @Description поэтому клиент может проверить непрерывность истории и пересчитывать баланс инкрементально.
+// @Description С bucket возвращаются только транзакции, затронувшие корзину; остаток корзины после них - в buckets.
+// @Tags wallet
+// @Produce json
+// @Param id path string true "ID кошелька"
+// @Param bucket query string false "Корзина: main, bonus, cashback или promo"
+// @Param afterSeq query int false "Вернуть транзакции с seq больше указанного" default(0)
+// @Param limit query int false "Максимальное число транзакций (до 1000)" default(100)
+// @Success 200 {object} map[string][]wallet.WalletTransactions "transactions: история"
+// @Failure 400 {object} map[string]string "Неверные параметры"
+// @Failure 404 {object} map[string]string "Кошелёк не найден"
+// @Failure 500 {object} map[string]string "Ошибка сервера"
+// @Router /wallets/{id}/transactions [get]
+func (h *Handler) listWalletTransactions(c *gin.Context) {
+	var walletID uuid.UUID
+	if err := walletID.Scan(strings.TrimSpace(c.Param("id"))); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet id"})
+		return
+	}
+
+	afterSeq, err := strconv.ParseInt(c.DefaultQuery("afterSeq", "0"), 10, 64)
+	if err != nil || afterSeq < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid afterSeq"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultHistoryLimit)))
+	if err != nil || limit <= 0 || limit > maxHistoryLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	var history []wallet.WalletTransactions
+	if bucket := c.Query("bucket"); bucket != "" {
+		history, err = h.service.Wallet.ListBucketTransactions(c.Request.Context(), walletID, bucket, afterSeq, limit)
+	} else {
+		history, err = h.service.Wallet.ListTransactions(c.Request.Context(), walletID, afterSeq, limit)
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"balance": balance,
+		"transactions": history,
 	})
 }
+
+type tierRequest struct {
+	Tier string `json:"tier" binding:"required"`
+}
+
+// setWalletTier godoc
+// @Summary Изменить уровень кошелька
+// @Description По уровню выбирается расписание комиссий за снятие и переводы (FEE_SCHEDULE_FILE).
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param id path string true "ID кошелька"
+// @Param tier body tierRequest true "Уровень: 1-32 символа a-z, 0-9, '_' и '-'"
+// @Success 200 {object} wallet.Wallet
+// @Failure 400 {object} map[string]string "Неверные данные"
+// @Failure 401 {object} map[string]string "Неверный токен"
+// @Failure 404 {object} map[string]string "Кошелёк не найден"
+// @Router /admin/wallets/{id}/tier [put]
+func (h *Handler) setWalletTier(c *gin.Context) {
+	var walletID uuid.UUID
+	if err := walletID.Scan(strings.TrimSpace(c.Param("id"))); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet id"})
+		return
+	}
+
+	var req tierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	wlt, err := h.service.Wallet.SetTier(c.Request.Context(), walletID, req.Tier)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, wlt)
+}
+
+type creditLimitRequest struct {
+	CreditLimit *float64 `json:"creditLimit" binding:"required"`
+}
+
+// setWalletCreditLimit godoc
+// @Summary Изменить кредитный лимит кошелька
+// @Description Баланс кошелька может опускаться до -creditLimit; 0 - только предоплата. Лимит нельзя сделать меньше
+// @Description текущей задолженности. Изменение лимита меняет версию кошелька (ETag).
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param id path string true "ID кошелька"
+// @Param creditLimit body creditLimitRequest true "Кредитный лимит"
+// @Success 200 {object} wallet.Wallet
+// @Failure 400 {object} map[string]string "Неверные данные"
+// @Failure 401 {object} map[string]string "Неверный токен"
+// @Failure 404 {object} map[string]string "Кошелёк не найден"
+// @Failure 409 {object} map[string]string "Лимит меньше текущей задолженности"
+// @Router /admin/wallets/{id}/credit-limit [put]
+func (h *Handler) setWalletCreditLimit(c *gin.Context) {
+	var walletID uuid.UUID
+	if err := walletID.Scan(strings.TrimSpace(c.Param("id"))); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet id"})
+		return
+	}
+
+	var req creditLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	wlt, err := h.service.Wallet.SetCreditLimit(c.Request.Context(), walletID, *req.CreditLimit)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("ETag", formatETag(wlt.Version))
+	c.JSON(http.StatusOK, wlt)
+}
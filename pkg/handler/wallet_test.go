@@ -2,13 +2,16 @@ package handler
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
 	"github.com/KatenkaKet/wallet/pkg/service"
 	mock_service "github.com/KatenkaKet/wallet/pkg/service/mocks"
 	"github.com/gin-gonic/gin"
@@ -35,21 +38,101 @@ func TestHandler_getWalletBalance(t *testing.T) {
 	testTable := []struct {
 		name         string
 		inputWallet  wallet.Wallet
+		ifNoneMatch  string
 		mockBehavior mockBehavior
 		expectedCode int
 		expectedBody string
+		expectedETag string
 	}{
 		{
 			name: "success",
+			inputWallet: wallet.Wallet{
+				ValletId:  uuidFromString("11111111-1111-1111-1111-111111111111"),
+				Balance:   100.5,
+				Version:   3,
+				Available: 100.5,
+			},
+			mockBehavior: func(s *mock_service.MockWallet, w wallet.Wallet) {
+				s.EXPECT().GetWallet(gomock.Any(), w.ValletId).Return(w, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"balance":100.5,"creditLimit":0,"available":100.5}`,
+			expectedETag: `"3"`,
+		},
+		{
+			name: "overdraft",
+			inputWallet: wallet.Wallet{
+				ValletId:    uuidFromString("11111111-1111-1111-1111-111111111111"),
+				Balance:     -20,
+				Version:     5,
+				CreditLimit: 50,
+				Available:   30,
+			},
+			mockBehavior: func(s *mock_service.MockWallet, w wallet.Wallet) {
+				s.EXPECT().GetWallet(gomock.Any(), w.ValletId).Return(w, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"balance":-20,"creditLimit":50,"available":30}`,
+			expectedETag: `"5"`,
+		},
+		{
+			name: "buckets",
+			inputWallet: wallet.Wallet{
+				ValletId:  uuidFromString("11111111-1111-1111-1111-111111111111"),
+				Balance:   100,
+				Version:   6,
+				Available: 100,
+				Buckets:   map[string]float64{"main": 70, "bonus": 20, "cashback": 10, "promo": 0},
+			},
+			mockBehavior: func(s *mock_service.MockWallet, w wallet.Wallet) {
+				s.EXPECT().GetWallet(gomock.Any(), w.ValletId).Return(w, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"balance":100,"creditLimit":0,"available":100,"buckets":{"bonus":20,"cashback":10,"main":70,"promo":0}}`,
+			expectedETag: `"6"`,
+		},
+		{
+			name: "not modified",
 			inputWallet: wallet.Wallet{
 				ValletId: uuidFromString("11111111-1111-1111-1111-111111111111"),
 				Balance:  100.5,
+				Version:  3,
+			},
+			ifNoneMatch: `"2", "3"`,
+			mockBehavior: func(s *mock_service.MockWallet, w wallet.Wallet) {
+				s.EXPECT().GetWallet(gomock.Any(), w.ValletId).Return(w, nil)
 			},
+			expectedCode: http.StatusNotModified,
+			expectedBody: ``,
+			expectedETag: `"3"`,
+		},
+		{
+			name: "modified",
+			inputWallet: wallet.Wallet{
+				ValletId:  uuidFromString("11111111-1111-1111-1111-111111111111"),
+				Balance:   100.5,
+				Version:   4,
+				Available: 100.5,
+			},
+			ifNoneMatch: `"3"`,
 			mockBehavior: func(s *mock_service.MockWallet, w wallet.Wallet) {
-				s.EXPECT().GetBalance(gomock.Any(), w.ValletId).Return(w.Balance, nil)
+				s.EXPECT().GetWallet(gomock.Any(), w.ValletId).Return(w, nil)
 			},
 			expectedCode: http.StatusOK,
-			expectedBody: `{"balance":100.5}`,
+			expectedBody: `{"balance":100.5,"creditLimit":0,"available":100.5}`,
+			expectedETag: `"4"`,
+		},
+		{
+			name: "not found",
+			inputWallet: wallet.Wallet{
+				ValletId: uuidFromString("22222222-2222-2222-2222-222222222222"),
+			},
+			ifNoneMatch: `"3"`,
+			mockBehavior: func(s *mock_service.MockWallet, w wallet.Wallet) {
+				s.EXPECT().GetWallet(gomock.Any(), w.ValletId).Return(wallet.Wallet{}, repository.ErrWalletNotFound)
+			},
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"error":"wallet not found"}`,
 		},
 		{
 			name: "service error",
@@ -57,10 +140,10 @@ func TestHandler_getWalletBalance(t *testing.T) {
 				ValletId: uuidFromString("22222222-2222-2222-2222-222222222222"),
 			},
 			mockBehavior: func(s *mock_service.MockWallet, w wallet.Wallet) {
-				s.EXPECT().GetBalance(gomock.Any(), w.ValletId).Return(float64(0), errors.New("wallet not found"))
+				s.EXPECT().GetWallet(gomock.Any(), w.ValletId).Return(wallet.Wallet{}, errors.New("connection refused"))
 			},
 			expectedCode: http.StatusInternalServerError,
-			expectedBody: `{"error":"wallet not found"}`,
+			expectedBody: `{"error":"connection refused"}`,
 		},
 	}
 
@@ -76,7 +159,7 @@ func TestHandler_getWalletBalance(t *testing.T) {
 
 			// Создаём handler
 			srv := &service.Service{Wallet: mockWallet}
-			h := NewHandler(srv)
+			h := NewHandler(srv, Config{})
 
 			// Инициализируем роутер
 			r := gin.New()
@@ -87,6 +170,9 @@ func TestHandler_getWalletBalance(t *testing.T) {
 			//fmt.Println(url)
 
 			req := httptest.NewRequest("GET", url, nil)
+			if test.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", test.ifNoneMatch)
+			}
 			w := httptest.NewRecorder()
 
 			// Выполняем запрос
@@ -97,6 +183,7 @@ func TestHandler_getWalletBalance(t *testing.T) {
 			// Проверяем результаты
 			assert.Equal(t, test.expectedCode, w.Code)
 			assert.Equal(t, test.expectedBody, w.Body.String())
+			assert.Equal(t, test.expectedETag, w.Header().Get("ETag"))
 		})
 	}
 }
@@ -107,10 +194,12 @@ func TestHandler_createWalletTransaction(t *testing.T) {
 	testTable := []struct {
 		name         string
 		inputBody    string
+		ifMatch      string
 		inputWT      wallet.WalletTransactions
 		mockBehavior mockBehavior
 		expectedCode int
 		expectedBody string
+		expectedETag string
 	}{
 		{
 			name:      "success",
@@ -121,10 +210,97 @@ func TestHandler_createWalletTransaction(t *testing.T) {
 				Amount:        100.5,
 			},
 			mockBehavior: func(s *mock_service.MockWallet, WT wallet.WalletTransactions) {
-				s.EXPECT().UpdateBalance(gomock.Any(), WT).Return(nil)
+				recorded := WT
+				recorded.Version = 8
+				s.EXPECT().UpdateBalance(gomock.Any(), WT).Return(recorded, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"status":"success"}`,
+			expectedETag: `"8"`,
+		},
+		{
+			name:      "if-match",
+			inputBody: `{"valletId":"11111111-1111-1111-1111-111111111111","operationType":"WITHDRAW","amount":10}`,
+			ifMatch:   `"7"`,
+			inputWT: wallet.WalletTransactions{
+				ValletId:        uuidFromString("11111111-1111-1111-1111-111111111111"),
+				OperationType:   "WITHDRAW",
+				Amount:          10,
+				ExpectedVersion: 7,
+			},
+			mockBehavior: func(s *mock_service.MockWallet, WT wallet.WalletTransactions) {
+				recorded := WT
+				recorded.Version = 8
+				s.EXPECT().UpdateBalance(gomock.Any(), WT).Return(recorded, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"status":"success"}`,
+			expectedETag: `"8"`,
+		},
+		{
+			name:      "version mismatch",
+			inputBody: `{"valletId":"11111111-1111-1111-1111-111111111111","operationType":"WITHDRAW","amount":10}`,
+			ifMatch:   `"6"`,
+			inputWT: wallet.WalletTransactions{
+				ValletId:        uuidFromString("11111111-1111-1111-1111-111111111111"),
+				OperationType:   "WITHDRAW",
+				Amount:          10,
+				ExpectedVersion: 6,
+			},
+			mockBehavior: func(s *mock_service.MockWallet, WT wallet.WalletTransactions) {
+				s.EXPECT().UpdateBalance(gomock.Any(), WT).
+					Return(wallet.WalletTransactions{}, fmt.Errorf("%w: expected 6, actual 7", repository.ErrVersionMismatch))
+			},
+			expectedCode: http.StatusPreconditionFailed,
+			expectedBody: `{"error":"wallet version mismatch: expected 6, actual 7"}`,
+		},
+		{
+			name:      "insufficient funds",
+			inputBody: `{"valletId":"11111111-1111-1111-1111-111111111111","operationType":"WITHDRAW","amount":500}`,
+			inputWT: wallet.WalletTransactions{
+				ValletId:      uuidFromString("11111111-1111-1111-1111-111111111111"),
+				OperationType: "WITHDRAW",
+				Amount:        500,
+			},
+			mockBehavior: func(s *mock_service.MockWallet, WT wallet.WalletTransactions) {
+				s.EXPECT().UpdateBalance(gomock.Any(), WT).
+					Return(wallet.WalletTransactions{}, fmt.Errorf("failed to update wallet: %w", repository.ErrInsufficientFunds))
+			},
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"error":"failed to update wallet: insufficient funds"}`,
+		},
+		{
+			name:      "bucket",
+			inputBody: `{"valletId":"11111111-1111-1111-1111-111111111111","operationType":"WITHDRAW","amount":5,"bucket":"promo"}`,
+			inputWT: wallet.WalletTransactions{
+				ValletId:      uuidFromString("11111111-1111-1111-1111-111111111111"),
+				OperationType: "WITHDRAW",
+				Amount:        5,
+				Bucket:        wallet.BucketPromo,
+			},
+			mockBehavior: func(s *mock_service.MockWallet, WT wallet.WalletTransactions) {
+				recorded := WT
+				recorded.Version = 9
+				s.EXPECT().UpdateBalance(gomock.Any(), WT).Return(recorded, nil)
 			},
 			expectedCode: http.StatusOK,
 			expectedBody: `{"status":"success"}`,
+			expectedETag: `"9"`,
+		},
+		{
+			name:         "unknown bucket",
+			inputBody:    `{"valletId":"11111111-1111-1111-1111-111111111111","operationType":"WITHDRAW","amount":5,"bucket":"vip"}`,
+			mockBehavior: func(s *mock_service.MockWallet, WT wallet.WalletTransactions) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"Key: 'WalletTransactions.Bucket' Error:Field validation for 'Bucket' failed on the 'oneof' tag"}`,
+		},
+		{
+			name:         "invalid if-match",
+			inputBody:    `{"valletId":"11111111-1111-1111-1111-111111111111","operationType":"WITHDRAW","amount":10}`,
+			ifMatch:      `"abc"`,
+			mockBehavior: func(s *mock_service.MockWallet, WT wallet.WalletTransactions) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid If-Match header"}`,
 		},
 		{
 			name:      "service error",
@@ -135,7 +311,7 @@ func TestHandler_createWalletTransaction(t *testing.T) {
 				Amount:        50,
 			},
 			mockBehavior: func(s *mock_service.MockWallet, WT wallet.WalletTransactions) {
-				s.EXPECT().UpdateBalance(gomock.Any(), WT).Return(errors.New("update failed"))
+				s.EXPECT().UpdateBalance(gomock.Any(), WT).Return(wallet.WalletTransactions{}, errors.New("update failed"))
 			},
 			expectedCode: http.StatusInternalServerError,
 			expectedBody: `{"error":"update failed"}`,
@@ -170,14 +346,18 @@ func TestHandler_createWalletTransaction(t *testing.T) {
 				test.mockBehavior(mockWallet, test.inputWT)
 			}
 
-			srv := &service.Service{Wallet: mockWallet}
-			h := NewHandler(srv)
+			// Квитанции отключены: ключ подписи не настроен.
+			srv := &service.Service{Wallet: mockWallet, Receipt: service.NewReceiptService("", nil, nil)}
+			h := NewHandler(srv, Config{})
 
 			r := gin.New()
 			r.POST("/wallet", h.createWalletTransaction)
 
 			req := httptest.NewRequest("POST", "/wallet", strings.NewReader(test.inputBody))
 			req.Header.Set("Content-Type", "application/json")
+			if test.ifMatch != "" {
+				req.Header.Set("If-Match", test.ifMatch)
+			}
 			w := httptest.NewRecorder()
 
 			r.ServeHTTP(w, req)
@@ -188,6 +368,292 @@ func TestHandler_createWalletTransaction(t *testing.T) {
 				// Сравниваем JSON как строки
 				assert.Equal(t, test.expectedBody, w.Body.String())
 			}
+			assert.Equal(t, test.expectedETag, w.Header().Get("ETag"))
+		})
+	}
+}
+
+func TestHandler_listWalletTransactions(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockWallet, walletID uuid.UUID)
+
+	walletID := uuidFromString("11111111-1111-1111-1111-111111111111")
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	testTable := []struct {
+		name         string
+		query        string
+		mockBehavior mockBehavior
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:  "success",
+			query: "?afterSeq=4&limit=1",
+			mockBehavior: func(s *mock_service.MockWallet, walletID uuid.UUID) {
+				s.EXPECT().ListTransactions(gomock.Any(), walletID, int64(4), 1).Return([]wallet.WalletTransactions{
+					{Id: 7, ValletId: walletID, OperationType: "DEPOSIT", Amount: 10, Seq: 5, BalanceAfter: 110, Hash: "ab", CreatedAt: createdAt},
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"transactions":[{"id":7,"valletId":"11111111-1111-1111-1111-111111111111","operationType":"DEPOSIT",` +
+				`"amount":10,"seq":5,"balanceAfter":110,"hash":"ab","createdAt":"2025-01-02T03:04:05Z"}]}`,
+		},
+		{
+			name:  "fee linked to its operation",
+			query: "?afterSeq=5&limit=2",
+			mockBehavior: func(s *mock_service.MockWallet, walletID uuid.UUID) {
+				op := uuidFromString("33333333-3333-3333-3333-333333333333")
+				s.EXPECT().ListTransactions(gomock.Any(), walletID, int64(5), 2).Return([]wallet.WalletTransactions{
+					{Id: 8, ValletId: walletID, OperationType: "WITHDRAW", Amount: 50, Seq: 6, BalanceAfter: 60, Hash: "cd",
+						OperationId: &op, CreatedAt: createdAt},
+					{Id: 9, ValletId: walletID, OperationType: "WITHDRAW", Amount: 1.5, Seq: 7, BalanceAfter: 58.5, Hash: "ef",
+						Kind: wallet.KindFee, OperationId: &op, CreatedAt: createdAt},
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"transactions":[{"id":8,"valletId":"11111111-1111-1111-1111-111111111111","operationType":"WITHDRAW",` +
+				`"amount":50,"seq":6,"balanceAfter":60,"hash":"cd","operationId":"33333333-3333-3333-3333-333333333333",` +
+				`"createdAt":"2025-01-02T03:04:05Z"},{"id":9,"valletId":"11111111-1111-1111-1111-111111111111",` +
+				`"operationType":"WITHDRAW","amount":1.5,"seq":7,"balanceAfter":58.5,"hash":"ef","kind":"fee",` +
+				`"operationId":"33333333-3333-3333-3333-333333333333","createdAt":"2025-01-02T03:04:05Z"}]}`,
+		},
+		{
+			name:  "bucket history",
+			query: "?bucket=bonus&afterSeq=2&limit=1",
+			mockBehavior: func(s *mock_service.MockWallet, walletID uuid.UUID) {
+				s.EXPECT().ListBucketTransactions(gomock.Any(), walletID, "bonus", int64(2), 1).Return([]wallet.WalletTransactions{
+					{Id: 8, ValletId: walletID, OperationType: "WITHDRAW", Amount: 30, Seq: 4, BalanceAfter: 70, Hash: "cd", CreatedAt: createdAt,
+						Buckets: []wallet.BucketEntry{{Bucket: "main", Amount: 10, BalanceAfter: 70}, {Bucket: "bonus", Amount: 20}}},
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"transactions":[{"id":8,"valletId":"11111111-1111-1111-1111-111111111111","operationType":"WITHDRAW",` +
+				`"amount":30,"seq":4,"balanceAfter":70,"hash":"cd","buckets":[{"bucket":"main","amount":10,"balanceAfter":70},` +
+				`{"bucket":"bonus","amount":20,"balanceAfter":0}],"createdAt":"2025-01-02T03:04:05Z"}]}`,
+		},
+		{
+			name:  "unknown bucket",
+			query: "?bucket=vip",
+			mockBehavior: func(s *mock_service.MockWallet, walletID uuid.UUID) {
+				s.EXPECT().ListBucketTransactions(gomock.Any(), walletID, "vip", int64(0), defaultHistoryLimit).
+					Return(nil, fmt.Errorf("%w %q", repository.ErrInvalidBucket, "vip"))
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid bucket \"vip\""}`,
+		},
+		{
+			name:  "default paging",
+			query: "",
+			mockBehavior: func(s *mock_service.MockWallet, walletID uuid.UUID) {
+				s.EXPECT().ListTransactions(gomock.Any(), walletID, int64(0), defaultHistoryLimit).Return(nil, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"transactions":null}`,
+		},
+		{
+			name:         "invalid limit",
+			query:        "?limit=100000",
+			mockBehavior: func(s *mock_service.MockWallet, walletID uuid.UUID) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid limit"}`,
+		},
+		{
+			name:         "invalid afterSeq",
+			query:        "?afterSeq=-1",
+			mockBehavior: func(s *mock_service.MockWallet, walletID uuid.UUID) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid afterSeq"}`,
+		},
+		{
+			name:  "wallet not found",
+			query: "",
+			mockBehavior: func(s *mock_service.MockWallet, walletID uuid.UUID) {
+				s.EXPECT().ListTransactions(gomock.Any(), walletID, int64(0), defaultHistoryLimit).
+					Return(nil, repository.ErrWalletNotFound)
+			},
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"error":"wallet not found"}`,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockWallet := mock_service.NewMockWallet(ctrl)
+			test.mockBehavior(mockWallet, walletID)
+
+			h := NewHandler(&service.Service{Wallet: mockWallet}, Config{})
+
+			r := gin.New()
+			r.GET("/api/v1/wallets/:id/transactions", h.listWalletTransactions)
+
+			req := httptest.NewRequest("GET", "/api/v1/wallets/"+walletID.UUID.String()+"/transactions"+test.query, nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.Equal(t, test.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_setWalletTier(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockWallet, walletID uuid.UUID)
+
+	walletID := uuidFromString("11111111-1111-1111-1111-111111111111")
+
+	testTable := []struct {
+		name         string
+		body         string
+		mockBehavior mockBehavior
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "success",
+			body: `{"tier":"premium"}`,
+			mockBehavior: func(s *mock_service.MockWallet, walletID uuid.UUID) {
+				s.EXPECT().SetTier(gomock.Any(), walletID, "premium").
+					Return(wallet.Wallet{ValletId: walletID, Balance: 10, Version: 3, LastSeq: 2, LastHash: "ab", Tier: "premium", Available: 10}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"valletId":"11111111-1111-1111-1111-111111111111","balance":10,"version":3,"lastSeq":2,` +
+				`"lastHash":"ab","tier":"premium","creditLimit":0,"available":10}`,
+		},
+		{
+			name:         "missing tier",
+			body:         `{}`,
+			mockBehavior: func(s *mock_service.MockWallet, walletID uuid.UUID) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"Key: 'tierRequest.Tier' Error:Field validation for 'Tier' failed on the 'required' tag"}`,
+		},
+		{
+			name: "invalid tier",
+			body: `{"tier":"VIP!"}`,
+			mockBehavior: func(s *mock_service.MockWallet, walletID uuid.UUID) {
+				s.EXPECT().SetTier(gomock.Any(), walletID, "VIP!").
+					Return(wallet.Wallet{}, fmt.Errorf("%w: tier must be 1-32 characters of a-z, 0-9, '_' and '-'", service.ErrInvalidTier))
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid wallet tier: tier must be 1-32 characters of a-z, 0-9, '_' and '-'"}`,
+		},
+		{
+			name: "wallet not found",
+			body: `{"tier":"premium"}`,
+			mockBehavior: func(s *mock_service.MockWallet, walletID uuid.UUID) {
+				s.EXPECT().SetTier(gomock.Any(), walletID, "premium").Return(wallet.Wallet{}, repository.ErrWalletNotFound)
+			},
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"error":"wallet not found"}`,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockWallet := mock_service.NewMockWallet(ctrl)
+			test.mockBehavior(mockWallet, walletID)
+
+			h := NewHandler(&service.Service{Wallet: mockWallet}, Config{AdminToken: "token"})
+			r := h.InitRoutes()
+
+			req := httptest.NewRequest("PUT", "/api/v1/admin/wallets/"+walletID.UUID.String()+"/tier", strings.NewReader(test.body))
+			req.Header.Set("Authorization", "Bearer token")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.Equal(t, test.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_setWalletCreditLimit(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockWallet, walletID uuid.UUID)
+
+	walletID := uuidFromString("11111111-1111-1111-1111-111111111111")
+
+	testTable := []struct {
+		name         string
+		body         string
+		mockBehavior mockBehavior
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "success",
+			body: `{"creditLimit":500}`,
+			mockBehavior: func(s *mock_service.MockWallet, walletID uuid.UUID) {
+				s.EXPECT().SetCreditLimit(gomock.Any(), walletID, 500.0).
+					Return(wallet.Wallet{ValletId: walletID, Balance: -20, Version: 4, LastSeq: 2, LastHash: "ab", Tier: "standard",
+						CreditLimit: 500, Available: 480}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"valletId":"11111111-1111-1111-1111-111111111111","balance":-20,"version":4,"lastSeq":2,` +
+				`"lastHash":"ab","tier":"standard","creditLimit":500,"available":480}`,
+		},
+		{
+			name:         "missing credit limit",
+			body:         `{}`,
+			mockBehavior: func(s *mock_service.MockWallet, walletID uuid.UUID) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"Key: 'creditLimitRequest.CreditLimit' Error:Field validation for 'CreditLimit' failed on the 'required' tag"}`,
+		},
+		{
+			name: "negative credit limit",
+			body: `{"creditLimit":-1}`,
+			mockBehavior: func(s *mock_service.MockWallet, walletID uuid.UUID) {
+				s.EXPECT().SetCreditLimit(gomock.Any(), walletID, -1.0).
+					Return(wallet.Wallet{}, fmt.Errorf("%w: credit limit must be between 0 and 1000000000000", service.ErrInvalidCreditLimit))
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid credit limit: credit limit must be between 0 and 1000000000000"}`,
+		},
+		{
+			name: "below debt",
+			body: `{"creditLimit":10}`,
+			mockBehavior: func(s *mock_service.MockWallet, walletID uuid.UUID) {
+				s.EXPECT().SetCreditLimit(gomock.Any(), walletID, 10.0).Return(wallet.Wallet{}, repository.ErrCreditLimitBelowDebt)
+			},
+			expectedCode: http.StatusConflict,
+			expectedBody: `{"error":"credit limit is below current debt"}`,
+		},
+		{
+			name: "wallet not found",
+			body: `{"creditLimit":10}`,
+			mockBehavior: func(s *mock_service.MockWallet, walletID uuid.UUID) {
+				s.EXPECT().SetCreditLimit(gomock.Any(), walletID, 10.0).Return(wallet.Wallet{}, repository.ErrWalletNotFound)
+			},
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"error":"wallet not found"}`,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockWallet := mock_service.NewMockWallet(ctrl)
+			test.mockBehavior(mockWallet, walletID)
+
+			h := NewHandler(&service.Service{Wallet: mockWallet}, Config{AdminToken: "token"})
+			r := h.InitRoutes()
+
+			req := httptest.NewRequest("PUT", "/api/v1/admin/wallets/"+walletID.UUID.String()+"/credit-limit", strings.NewReader(test.body))
+			req.Header.Set("Authorization", "Bearer token")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.Equal(t, test.expectedBody, w.Body.String())
 		})
 	}
 }
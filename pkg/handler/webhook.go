@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultDeadDeliveriesLimit = 100
+	maxDeadDeliveriesLimit     = 1000
+)
+
+// createWebhook godoc
+// @Summary Подписаться на события кошельков
+// @Description Без valletId - события всех кошельков, без eventTypes - все типы событий
+// @Description (transaction.deposited, transaction.withdrawn, transaction.rejected).
+// @Description Доставки подписываются HMAC-SHA256 секретом подписки (заголовок X-Wallet-Signature, см. pkg/webhook).
+// @Description Если secret не передан, он генерируется; секрет возвращается только в этом ответе.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param subscription body wallet.WebhookSubscription true "Подписка"
+// @Success 201 {object} wallet.WebhookSubscription
+// @Failure 400 {object} map[string]string "Неверные данные"
+// @Failure 401 {object} map[string]string "Неверный токен"
+// @Failure 404 {object} map[string]string "Кошелёк не найден"
+// @Router /admin/webhooks [post]
+func (h *Handler) createWebhook(c *gin.Context) {
+	var sub wallet.WebhookSubscription
+	if err := c.ShouldBindJSON(&sub); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub, err := h.service.Webhook.Subscribe(c.Request.Context(), sub)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, sub)
+}
+
+// listWebhooks godoc
+// @Summary Список подписок на вебхуки
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} map[string][]wallet.WebhookSubscription "subscriptions: подписки (без секретов)"
+// @Failure 401 {object} map[string]string "Неверный токен"
+// @Router /admin/webhooks [get]
+func (h *Handler) listWebhooks(c *gin.Context) {
+	subs, err := h.service.Webhook.ListSubscriptions(c.Request.Context())
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"subscriptions": subs,
+	})
+}
+
+// deleteWebhook godoc
+// @Summary Удалить подписку вместе с её доставками
+// @Tags admin
+// @Security AdminToken
+// @Param id path int true "ID подписки"
+// @Success 204
+// @Failure 401 {object} map[string]string "Неверный токен"
+// @Failure 404 {object} map[string]string "Подписка не найдена"
+// @Router /admin/webhooks/{id} [delete]
+func (h *Handler) deleteWebhook(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid subscription id"})
+		return
+	}
+
+	if err := h.service.Webhook.Unsubscribe(c.Request.Context(), id); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// listDeadDeliveries godoc
+// @Summary Доставки в dead-letter
+// @Description Доставки, которые не удалось выполнить за все попытки; их можно отправить повторно через replay.
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param limit query int false "Максимальное число доставок (до 1000)" default(100)
+// @Success 200 {object} map[string][]wallet.WebhookDelivery "deliveries: доставки"
+// @Failure 401 {object} map[string]string "Неверный токен"
+// @Router /admin/webhooks/deliveries/dead [get]
+func (h *Handler) listDeadDeliveries(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultDeadDeliveriesLimit)))
+	if err != nil || limit <= 0 || limit > maxDeadDeliveriesLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	deliveries, err := h.service.Webhook.ListDeadDeliveries(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+	})
+}
+
+// replayDelivery godoc
+// @Summary Повторно отправить доставку из dead-letter
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param id path int true "ID доставки"
+// @Success 202 {object} map[string]string "status: queued"
+// @Failure 401 {object} map[string]string "Неверный токен"
+// @Failure 404 {object} map[string]string "Доставка не найдена или не в dead-letter"
+// @Router /admin/webhooks/deliveries/{id}/replay [post]
+func (h *Handler) replayDelivery(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid delivery id"})
+		return
+	}
+
+	if err := h.service.Webhook.ReplayDelivery(c.Request.Context(), id); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status": "queued",
+	})
+}
@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	"github.com/KatenkaKet/wallet/pkg/service"
+	mock_service "github.com/KatenkaKet/wallet/pkg/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestHandler_adminAuth(t *testing.T) {
+	testTable := []struct {
+		name         string
+		adminToken   string
+		header       string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "admin API disabled",
+			header:       "Bearer ",
+			expectedCode: http.StatusForbidden,
+			expectedBody: `{"error":"admin API is disabled"}`,
+		},
+		{
+			name:         "missing token",
+			adminToken:   "token",
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":"invalid admin token"}`,
+		},
+		{
+			name:         "wrong token",
+			adminToken:   "token",
+			header:       "Bearer other",
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":"invalid admin token"}`,
+		},
+		{
+			name:         "ok",
+			adminToken:   "token",
+			header:       "Bearer token",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"subscriptions":[]}`,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockWebhook := mock_service.NewMockWebhook(ctrl)
+			if test.expectedCode == http.StatusOK {
+				mockWebhook.EXPECT().ListSubscriptions(gomock.Any()).Return([]wallet.WebhookSubscription{}, nil)
+			}
+
+			h := NewHandler(&service.Service{Webhook: mockWebhook}, Config{AdminToken: test.adminToken})
+			r := h.InitRoutes()
+
+			req := httptest.NewRequest("GET", "/api/v1/admin/webhooks", nil)
+			if test.header != "" {
+				req.Header.Set("Authorization", test.header)
+			}
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.Equal(t, test.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_webhooks(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockWebhook)
+
+	walletID := uuidFromString("11111111-1111-1111-1111-111111111111")
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	testTable := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		mockBehavior mockBehavior
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:   "create subscription",
+			method: "POST",
+			path:   "/api/v1/admin/webhooks",
+			body:   `{"valletId":"11111111-1111-1111-1111-111111111111","url":"https://example.com/hook","eventTypes":["transaction.rejected"]}`,
+			mockBehavior: func(s *mock_service.MockWebhook) {
+				s.EXPECT().Subscribe(gomock.Any(), wallet.WebhookSubscription{
+					ValletId: &walletID, URL: "https://example.com/hook", EventTypes: []string{wallet.EventRejected},
+				}).Return(wallet.WebhookSubscription{
+					Id: 1, ValletId: &walletID, URL: "https://example.com/hook", EventTypes: []string{wallet.EventRejected},
+					Secret: "abc", CreatedAt: createdAt,
+				}, nil)
+			},
+			expectedCode: http.StatusCreated,
+			expectedBody: `{"id":1,"valletId":"11111111-1111-1111-1111-111111111111","url":"https://example.com/hook",` +
+				`"eventTypes":["transaction.rejected"],"secret":"abc","createdAt":"2025-01-02T03:04:05Z"}`,
+		},
+		{
+			name:         "create subscription without url",
+			method:       "POST",
+			path:         "/api/v1/admin/webhooks",
+			body:         `{"eventTypes":[]}`,
+			mockBehavior: func(s *mock_service.MockWebhook) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"Key: 'WebhookSubscription.URL' Error:Field validation for 'URL' failed on the 'required' tag"}`,
+		},
+		{
+			name:   "create subscription with unknown event type",
+			method: "POST",
+			path:   "/api/v1/admin/webhooks",
+			body:   `{"url":"https://example.com/hook","eventTypes":["wallet.closed"]}`,
+			mockBehavior: func(s *mock_service.MockWebhook) {
+				s.EXPECT().Subscribe(gomock.Any(), gomock.Any()).
+					Return(wallet.WebhookSubscription{}, fmt.Errorf("%w: unknown event type %q", service.ErrInvalidSubscription, "wallet.closed"))
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid webhook subscription: unknown event type \"wallet.closed\""}`,
+		},
+		{
+			name:   "delete subscription",
+			method: "DELETE",
+			path:   "/api/v1/admin/webhooks/1",
+			mockBehavior: func(s *mock_service.MockWebhook) {
+				s.EXPECT().Unsubscribe(gomock.Any(), int64(1)).Return(nil)
+			},
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:   "delete missing subscription",
+			method: "DELETE",
+			path:   "/api/v1/admin/webhooks/2",
+			mockBehavior: func(s *mock_service.MockWebhook) {
+				s.EXPECT().Unsubscribe(gomock.Any(), int64(2)).Return(repository.ErrSubscriptionNotFound)
+			},
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"error":"webhook subscription not found"}`,
+		},
+		{
+			name:   "list dead deliveries",
+			method: "GET",
+			path:   "/api/v1/admin/webhooks/deliveries/dead?limit=5",
+			mockBehavior: func(s *mock_service.MockWebhook) {
+				s.EXPECT().ListDeadDeliveries(gomock.Any(), 5).Return([]wallet.WebhookDelivery{{
+					Id: 3, SubscriptionId: 1, EventId: "e1", EventType: wallet.EventDeposited, Payload: []byte(`{"id":"e1"}`),
+					Status: wallet.DeliveryDead, Attempts: 8, LastError: "timeout", NextAttemptAt: createdAt, CreatedAt: createdAt,
+					URL: "https://example.com/hook", Secret: "abc",
+				}}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"deliveries":[{"id":3,"subscriptionId":1,"eventId":"e1","eventType":"transaction.deposited",` +
+				`"payload":{"id":"e1"},"status":"dead","attempts":8,"lastError":"timeout",` +
+				`"nextAttemptAt":"2025-01-02T03:04:05Z","createdAt":"2025-01-02T03:04:05Z"}]}`,
+		},
+		{
+			name:         "list dead deliveries with invalid limit",
+			method:       "GET",
+			path:         "/api/v1/admin/webhooks/deliveries/dead?limit=5000",
+			mockBehavior: func(s *mock_service.MockWebhook) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid limit"}`,
+		},
+		{
+			name:   "replay delivery",
+			method: "POST",
+			path:   "/api/v1/admin/webhooks/deliveries/3/replay",
+			mockBehavior: func(s *mock_service.MockWebhook) {
+				s.EXPECT().ReplayDelivery(gomock.Any(), int64(3)).Return(nil)
+			},
+			expectedCode: http.StatusAccepted,
+			expectedBody: `{"status":"queued"}`,
+		},
+		{
+			name:   "replay delivery that is not dead",
+			method: "POST",
+			path:   "/api/v1/admin/webhooks/deliveries/4/replay",
+			mockBehavior: func(s *mock_service.MockWebhook) {
+				s.EXPECT().ReplayDelivery(gomock.Any(), int64(4)).Return(repository.ErrDeliveryNotFound)
+			},
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"error":"webhook delivery not found"}`,
+		},
+		{
+			name:         "replay delivery with invalid id",
+			method:       "POST",
+			path:         "/api/v1/admin/webhooks/deliveries/x/replay",
+			mockBehavior: func(s *mock_service.MockWebhook) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid delivery id"}`,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockWebhook := mock_service.NewMockWebhook(ctrl)
+			test.mockBehavior(mockWebhook)
+
+			h := NewHandler(&service.Service{Webhook: mockWebhook}, Config{AdminToken: "token"})
+			r := h.InitRoutes()
+
+			req := httptest.NewRequest(test.method, test.path, bytes.NewBufferString(test.body))
+			req.Header.Set("Authorization", "Bearer token")
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.Equal(t, test.expectedBody, w.Body.String())
+		})
+	}
+}
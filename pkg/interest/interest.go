@@ -0,0 +1,120 @@
+// Package interest - процентные продукты кошельков: годовая ставка и соглашение о числе дней в году,
+// по которым считается дневное начисление процентов на остаток.
+package interest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"time"
+)
+
+var ErrInvalidProducts = errors.New("invalid interest products")
+
+// Соглашения о числе дней (day count): на сколько дней делится годовая ставка.
+const (
+	Actual365 = "ACT/365" // всегда 365 дней
+	Actual360 = "ACT/360" // всегда 360 дней
+	ActualAct = "ACT/ACT" // фактическое число дней в году начисления: 365 или 366
+)
+
+var namePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// Product - процентный продукт: AnnualRate - годовая ставка в процентах, DayCount - соглашение о числе дней.
+type Product struct {
+	Name       string  `json:"name"`
+	AnnualRate float64 `json:"annualRate"`
+	DayCount   string  `json:"dayCount"`
+}
+
+// DailyRate возвращает долю остатка, начисляемую за день day.
+func (p Product) DailyRate(day time.Time) float64 {
+	days := 365.0
+	switch p.DayCount {
+	case Actual360:
+		days = 360
+	case ActualAct:
+		if year := day.UTC().Year(); year%4 == 0 && (year%100 != 0 || year%400 == 0) {
+			days = 366
+		}
+	}
+	return p.AnnualRate / 100 / days
+}
+
+// Products - процентные продукты по имени. Нулевой (nil) Products не содержит ни одного продукта.
+type Products struct {
+	products map[string]Product
+}
+
+// New проверяет продукты и собирает их по имени.
+func New(products []Product) (*Products, error) {
+	ps := &Products{products: make(map[string]Product, len(products))}
+	for i, p := range products {
+		if !namePattern.MatchString(p.Name) {
+			return nil, fmt.Errorf("%w: product %d: name must be 1-32 characters of a-z, 0-9, '_' and '-'", ErrInvalidProducts, i+1)
+		}
+		if p.AnnualRate < 0 || p.AnnualRate > 100 || math.IsNaN(p.AnnualRate) {
+			return nil, fmt.Errorf("%w: product %s: annual rate must be between 0 and 100", ErrInvalidProducts, p.Name)
+		}
+		switch p.DayCount {
+		case "":
+			p.DayCount = Actual365
+		case Actual365, Actual360, ActualAct:
+		default:
+			return nil, fmt.Errorf("%w: product %s: day count must be %s, %s or %s",
+				ErrInvalidProducts, p.Name, Actual365, Actual360, ActualAct)
+		}
+		if _, ok := ps.products[p.Name]; ok {
+			return nil, fmt.Errorf("%w: duplicate product %s", ErrInvalidProducts, p.Name)
+		}
+		ps.products[p.Name] = p
+	}
+	return ps, nil
+}
+
+// Load читает продукты из JSON-файла вида {"products": [{"name": "savings", "annualRate": 4.5, "dayCount": "ACT/365"}]}.
+func Load(path string) (*Products, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read interest products: %w", err)
+	}
+
+	var file struct {
+		Products []Product `json:"products"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidProducts, err.Error())
+	}
+	return New(file.Products)
+}
+
+// Get возвращает продукт по имени.
+func (ps *Products) Get(name string) (Product, bool) {
+	if ps == nil {
+		return Product{}, false
+	}
+	p, ok := ps.products[name]
+	return p, ok
+}
+
+// Empty сообщает, что продуктов нет и начислять проценты не нужно.
+func (ps *Products) Empty() bool {
+	return ps == nil || len(ps.products) == 0
+}
+
+// DailyRates возвращает дневные ставки всех продуктов с ненулевой ставкой за день day: продукт -> доля остатка.
+func (ps *Products) DailyRates(day time.Time) map[string]float64 {
+	rates := make(map[string]float64)
+	if ps == nil {
+		return rates
+	}
+	for name, p := range ps.products {
+		if rate := p.DailyRate(day); rate > 0 {
+			rates[name] = rate
+		}
+	}
+	return rates
+}
@@ -0,0 +1,83 @@
+package interest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProduct_DailyRate(t *testing.T) {
+	day := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		require.NoError(t, err)
+		return d
+	}
+
+	testTable := []struct {
+		name     string
+		dayCount string
+		day      string
+		expected float64
+	}{
+		{name: "actual/365", dayCount: Actual365, day: "2028-03-01", expected: 0.0365 / 365},
+		{name: "actual/360", dayCount: Actual360, day: "2027-03-01", expected: 0.0365 / 360},
+		{name: "actual/actual in a common year", dayCount: ActualAct, day: "2027-03-01", expected: 0.0365 / 365},
+		{name: "actual/actual in a leap year", dayCount: ActualAct, day: "2028-03-01", expected: 0.0365 / 366},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			p := Product{Name: "savings", AnnualRate: 3.65, DayCount: test.dayCount}
+			assert.InDelta(t, test.expected, p.DailyRate(day(test.day)), 1e-15)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	ps, err := New([]Product{{Name: "savings", AnnualRate: 4}, {Name: "frozen"}})
+	require.NoError(t, err)
+
+	p, ok := ps.Get("savings")
+	require.True(t, ok)
+	assert.Equal(t, Actual365, p.DayCount)
+	_, ok = ps.Get("current")
+	assert.False(t, ok)
+
+	// Продукт с нулевой ставкой не начисляет проценты.
+	rates := ps.DailyRates(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Len(t, rates, 1)
+	assert.InDelta(t, 0.04/365, rates["savings"], 1e-15)
+
+	var none *Products
+	assert.True(t, none.Empty())
+	assert.Empty(t, none.DailyRates(time.Now()))
+
+	for name, products := range map[string][]Product{
+		"bad name":          {{Name: "Savings!", AnnualRate: 1}},
+		"negative rate":     {{Name: "savings", AnnualRate: -1}},
+		"unknown day count": {{Name: "savings", AnnualRate: 1, DayCount: "30/360"}},
+		"duplicate":         {{Name: "savings", AnnualRate: 1}, {Name: "savings", AnnualRate: 2}},
+	} {
+		_, err := New(products)
+		assert.ErrorIs(t, err, ErrInvalidProducts, name)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "interest.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"products": [{"name": "savings", "annualRate": 5, "dayCount": "ACT/360"}]}`), 0o600))
+
+	ps, err := Load(path)
+	require.NoError(t, err)
+	p, ok := ps.Get("savings")
+	require.True(t, ok)
+	assert.Equal(t, Actual360, p.DayCount)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"products": [{"name": 1}]}`), 0o600))
+	_, err = Load(path)
+	assert.ErrorIs(t, err, ErrInvalidProducts)
+}
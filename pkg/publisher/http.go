@@ -0,0 +1,50 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/webhook"
+)
+
+// HTTP отправляет каждое событие POST-запросом с JSON-телом на один адрес.
+// Идентификатор события передаётся в заголовке Idempotency-Key; событие считается принятым при ответе 2xx.
+type HTTP struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTP создаёт получателя для url; nil client - http.DefaultClient.
+func NewHTTP(url string, client *http.Client) *HTTP {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTP{url: url, client: client}
+}
+
+func (p *HTTP) Publish(ctx context.Context, event wallet.WalletEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event %s: %w", event.Id, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", event.Id)
+	req.Header.Set(webhook.HeaderEvent, event.Type)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp)
+}
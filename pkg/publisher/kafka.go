@@ -0,0 +1,88 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/KatenkaKet/wallet"
+)
+
+const (
+	kafkaRESTContentType = "application/vnd.kafka.json.v2+json"
+	kafkaRESTAccept      = "application/vnd.kafka.v2+json"
+)
+
+// KafkaREST публикует события в топик Kafka через Kafka REST Proxy (API v2).
+// Ключ сообщения - valletId, поэтому события одного кошелька попадают в одну партицию и читаются по порядку.
+type KafkaREST struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewKafkaREST создаёт получателя для REST Proxy по адресу baseURL; nil client - http.DefaultClient.
+func NewKafkaREST(baseURL, topic string, client *http.Client) *KafkaREST {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &KafkaREST{
+		endpoint: strings.TrimRight(baseURL, "/") + "/topics/" + url.PathEscape(topic),
+		client:   client,
+	}
+}
+
+type kafkaRecord struct {
+	Key   string             `json:"key"`
+	Value wallet.WalletEvent `json:"value"`
+}
+
+type kafkaOffset struct {
+	Partition int    `json:"partition"`
+	Offset    int64  `json:"offset"`
+	ErrorCode *int   `json:"error_code"`
+	Error     string `json:"error"`
+}
+
+func (p *KafkaREST) Publish(ctx context.Context, event wallet.WalletEvent) error {
+	body, err := json.Marshal(map[string][]kafkaRecord{
+		"records": {{Key: event.ValletId.UUID.String(), Value: event}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode event %s: %w", event.Id, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", kafkaRESTContentType)
+	req.Header.Set("Accept", kafkaRESTAccept)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to publish event %s to kafka: %w", event.Id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("failed to publish event %s to kafka: %w", event.Id, checkResponse(resp))
+	}
+
+	// REST Proxy отвечает 200 и тогда, когда запись не принята брокером; ошибка - в offsets.
+	var result struct {
+		Offsets []kafkaOffset `json:"offsets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to publish event %s to kafka: invalid response: %w", event.Id, err)
+	}
+	for _, o := range result.Offsets {
+		if o.ErrorCode != nil {
+			return fmt.Errorf("failed to publish event %s to kafka: error %d: %s", event.Id, *o.ErrorCode, o.Error)
+		}
+	}
+	return nil
+}
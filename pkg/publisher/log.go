@@ -0,0 +1,33 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/KatenkaKet/wallet"
+)
+
+// Log пишет события в журнал; подходит для отладки и разбора событий из логов.
+type Log struct {
+	logger *log.Logger
+}
+
+// NewLog создаёт получателя, пишущего в logger; nil - стандартный журнал.
+func NewLog(logger *log.Logger) *Log {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Log{logger: logger}
+}
+
+func (p *Log) Publish(ctx context.Context, event wallet.WalletEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event %s: %w", event.Id, err)
+	}
+
+	p.logger.Printf("event %s %s: %s", event.Type, event.Id, payload)
+	return nil
+}
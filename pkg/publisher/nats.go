@@ -0,0 +1,50 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/webhook"
+	"github.com/nats-io/nats.go"
+)
+
+// natsConn - часть *nats.Conn, которой пользуется NATS.
+type natsConn interface {
+	PublishMsg(m *nats.Msg) error
+	FlushWithContext(ctx context.Context) error
+}
+
+// NATS публикует события в subject <prefix>.<valletId>, так что подписчик может слушать
+// один кошелёк или все (<prefix>.*). Идентификатор события передаётся в заголовке Nats-Msg-Id:
+// если subject'ы захвачены потоком JetStream, повторы отбрасываются в пределах окна дедупликации.
+type NATS struct {
+	conn   natsConn
+	prefix string
+}
+
+func NewNATS(conn *nats.Conn, prefix string) *NATS {
+	return &NATS{conn: conn, prefix: prefix}
+}
+
+// Publish дожидается подтверждения сервером (flush), чтобы событие не осталось в буфере клиента.
+func (p *NATS) Publish(ctx context.Context, event wallet.WalletEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event %s: %w", event.Id, err)
+	}
+
+	msg := nats.NewMsg(p.prefix + "." + event.ValletId.UUID.String())
+	msg.Header.Set(nats.MsgIdHdr, event.Id)
+	msg.Header.Set(webhook.HeaderEvent, event.Type)
+	msg.Data = payload
+
+	if err := p.conn.PublishMsg(msg); err != nil {
+		return fmt.Errorf("failed to publish event %s to nats: %w", event.Id, err)
+	}
+	if err := p.conn.FlushWithContext(ctx); err != nil {
+		return fmt.Errorf("failed to publish event %s to nats: %w", event.Id, err)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+// Package publisher содержит получателей событий из transactional outbox (service.EventPublisher):
+// журнал, HTTP-приёмник, NATS и Kafka (через Kafka REST Proxy).
+//
+// Relay публикует события одного кошелька по порядку и повторяет неудачные, поэтому
+// получатель видит каждое событие как минимум один раз; дубликаты отбрасываются по WalletEvent.Id.
+package publisher
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// checkResponse считывает ответ и возвращает ошибку для статусов вне 2xx.
+func checkResponse(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("receiver responded with status %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
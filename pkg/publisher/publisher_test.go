@@ -0,0 +1,182 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/webhook"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEvent(t *testing.T) wallet.WalletEvent {
+	var walletID uuid.UUID
+	require.NoError(t, walletID.Scan("11111111-1111-1111-1111-111111111111"))
+
+	return wallet.WalletEvent{
+		Id:         "7c9e6679-7425-40de-944b-e07fc1f90ae7",
+		Type:       wallet.EventDeposited,
+		ValletId:   walletID,
+		OccurredAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Transaction: wallet.WalletTransactions{
+			Id: 1, ValletId: walletID, OperationType: "DEPOSIT", Amount: 10, Seq: 1, BalanceAfter: 10,
+		},
+	}
+}
+
+func TestLog_Publish(t *testing.T) {
+	var buf bytes.Buffer
+	event := testEvent(t)
+
+	require.NoError(t, NewLog(log.New(&buf, "", 0)).Publish(context.Background(), event))
+
+	payload, err := json.Marshal(event)
+	require.NoError(t, err)
+	assert.Equal(t, "event transaction.deposited "+event.Id+": "+string(payload)+"\n", buf.String())
+}
+
+func TestHTTP_Publish(t *testing.T) {
+	event := testEvent(t)
+
+	testTable := []struct {
+		name      string
+		status    int
+		expectErr bool
+	}{
+		{name: "accepted", status: http.StatusAccepted},
+		{name: "rejected", status: http.StatusServiceUnavailable, expectErr: true},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, event.Id, r.Header.Get("Idempotency-Key"))
+				assert.Equal(t, event.Type, r.Header.Get(webhook.HeaderEvent))
+
+				var got wallet.WalletEvent
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+				assert.Equal(t, event.Id, got.Id)
+				w.WriteHeader(test.status)
+			}))
+			defer srv.Close()
+
+			err := NewHTTP(srv.URL, nil).Publish(context.Background(), event)
+			if test.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+type fakeNATSConn struct {
+	msgs     []*nats.Msg
+	flushErr error
+}
+
+func (c *fakeNATSConn) PublishMsg(m *nats.Msg) error {
+	c.msgs = append(c.msgs, m)
+	return nil
+}
+
+func (c *fakeNATSConn) FlushWithContext(ctx context.Context) error {
+	return c.flushErr
+}
+
+func TestNATS_Publish(t *testing.T) {
+	event := testEvent(t)
+
+	t.Run("published", func(t *testing.T) {
+		conn := &fakeNATSConn{}
+		require.NoError(t, (&NATS{conn: conn, prefix: "wallet.events"}).Publish(context.Background(), event))
+
+		require.Len(t, conn.msgs, 1)
+		msg := conn.msgs[0]
+		assert.Equal(t, "wallet.events.11111111-1111-1111-1111-111111111111", msg.Subject)
+		assert.Equal(t, event.Id, msg.Header.Get(nats.MsgIdHdr))
+		assert.Equal(t, event.Type, msg.Header.Get(webhook.HeaderEvent))
+
+		var got wallet.WalletEvent
+		require.NoError(t, json.Unmarshal(msg.Data, &got))
+		assert.Equal(t, event.Transaction.Seq, got.Transaction.Seq)
+	})
+
+	t.Run("flush failed", func(t *testing.T) {
+		conn := &fakeNATSConn{flushErr: errors.New("connection closed")}
+		assert.Error(t, (&NATS{conn: conn, prefix: "wallet.events"}).Publish(context.Background(), event))
+	})
+}
+
+func TestKafkaREST_Publish(t *testing.T) {
+	event := testEvent(t)
+
+	testTable := []struct {
+		name      string
+		status    int
+		response  string
+		expectErr bool
+	}{
+		{
+			name:     "accepted",
+			status:   http.StatusOK,
+			response: `{"key_schema_id":null,"value_schema_id":null,"offsets":[{"partition":2,"offset":15,"error_code":null,"error":null}]}`,
+		},
+		{
+			name:      "record rejected",
+			status:    http.StatusOK,
+			response:  `{"offsets":[{"partition":null,"offset":null,"error_code":50003,"error":"Kafka error"}]}`,
+			expectErr: true,
+		},
+		{
+			name:      "topic not found",
+			status:    http.StatusNotFound,
+			response:  `{"error_code":40401,"message":"Topic not found"}`,
+			expectErr: true,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/topics/wallet-events", r.URL.Path)
+				assert.Equal(t, kafkaRESTContentType, r.Header.Get("Content-Type"))
+
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+				var records struct {
+					Records []kafkaRecord `json:"records"`
+				}
+				assert.NoError(t, json.Unmarshal(body, &records))
+				if assert.Len(t, records.Records, 1) {
+					assert.Equal(t, "11111111-1111-1111-1111-111111111111", records.Records[0].Key)
+					assert.Equal(t, event.Id, records.Records[0].Value.Id)
+				}
+
+				w.Header().Set("Content-Type", kafkaRESTAccept)
+				w.WriteHeader(test.status)
+				io.WriteString(w, test.response)
+			}))
+			defer srv.Close()
+
+			err := NewKafkaREST(srv.URL+"/", "wallet-events", nil).Publish(context.Background(), event)
+			if test.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+// Package receipt - подписанные квитанции об операциях с кошельком.
+//
+// Сервис подписывает каждую успешную операцию ключом Ed25519, а публичные ключи публикует
+// на GET /api/v1/receipts/keys. Пакет не зависит от остального кода сервиса, поэтому клиенты
+// могут использовать его для проверки квитанций офлайн:
+//
+//	keys, err := receipt.ParseKeySet(publishedKeys)
+//	...
+//	if err := receipt.Verify(rc, keys); err != nil {
+//		// квитанция подделана или подписана неизвестным ключом
+//	}
+package receipt
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Algorithm - алгоритм подписи квитанций, публикуется вместе с ключами.
+const Algorithm = "Ed25519"
+
+// payloadVersion - префикс подписываемых данных; меняется при изменении их формата.
+const payloadVersion = "wallet-receipt-v1"
+
+var (
+	ErrUnknownKey       = errors.New("receipt signed with unknown key")
+	ErrInvalidSignature = errors.New("invalid receipt signature")
+)
+
+// Receipt - квитанция об операции. Подпись покрывает все поля, кроме KeyId и Signature.
+type Receipt struct {
+	TransactionId int       `json:"transactionId"`
+	ValletId      string    `json:"valletId"`
+	OperationType string    `json:"operationType"`
+	Amount        float64   `json:"amount"`
+	BalanceAfter  float64   `json:"balanceAfter"`
+	Seq           int64     `json:"seq"`
+	Timestamp     time.Time `json:"timestamp"`
+	KeyId         string    `json:"keyId"`
+	Signature     string    `json:"signature"` // base64 (std)
+}
+
+// PublicKey - публичный ключ в том виде, в каком его публикует сервис.
+type PublicKey struct {
+	KeyId     string `json:"keyId"`
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"publicKey"` // base64 (std), 32 байта
+}
+
+// KeySet - публичные ключи по KeyId.
+type KeySet map[string]ed25519.PublicKey
+
+// Payload возвращает подписываемые данные - поля квитанции, разделённые переводом строки:
+//
+//	wallet-receipt-v1 \n transactionId \n valletId \n operationType \n amount \n balanceAfter \n seq \n timestamp
+//
+// Суммы записываются с двумя знаками после точки, время - в UTC в формате RFC 3339 с долями секунды.
+func (r Receipt) Payload() []byte {
+	return []byte(strings.Join([]string{
+		payloadVersion,
+		strconv.Itoa(r.TransactionId),
+		strings.ToLower(r.ValletId),
+		r.OperationType,
+		strconv.FormatFloat(r.Amount, 'f', 2, 64),
+		strconv.FormatFloat(r.BalanceAfter, 'f', 2, 64),
+		strconv.FormatInt(r.Seq, 10),
+		r.Timestamp.UTC().Format(time.RFC3339Nano),
+	}, "\n"))
+}
+
+// Sign подписывает квитанцию ключом keyID.
+func Sign(r Receipt, keyID string, key ed25519.PrivateKey) Receipt {
+	r.KeyId = keyID
+	r.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, r.Payload()))
+	return r
+}
+
+// Verify проверяет подпись квитанции ключом из keys с тем же KeyId.
+func Verify(r Receipt, keys KeySet) error {
+	pub, ok := keys[r.KeyId]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownKey, r.KeyId)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(r.Signature)
+	if err != nil || !ed25519.Verify(pub, r.Payload(), sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Public возвращает публичный ключ для публикации.
+func Public(keyID string, pub ed25519.PublicKey) PublicKey {
+	return PublicKey{KeyId: keyID, Algorithm: Algorithm, PublicKey: base64.StdEncoding.EncodeToString(pub)}
+}
+
+// ParseKeySet разбирает опубликованные ключи (ответ GET /api/v1/receipts/keys).
+func ParseKeySet(keys []PublicKey) (KeySet, error) {
+	set := make(KeySet, len(keys))
+	for _, k := range keys {
+		if k.Algorithm != Algorithm {
+			return nil, fmt.Errorf("key %q: unsupported algorithm %q", k.KeyId, k.Algorithm)
+		}
+
+		raw, err := base64.StdEncoding.DecodeString(k.PublicKey)
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("key %q: invalid public key", k.KeyId)
+		}
+		set[k.KeyId] = ed25519.PublicKey(raw)
+	}
+	return set, nil
+}
+
+// ParsePrivateKey разбирает закрытый ключ в base64: 32-байтный seed или 64-байтный ключ целиком.
+// Новый ключ можно получить так: head -c 32 /dev/urandom | base64
+func ParsePrivateKey(s string) (ed25519.PrivateKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	default:
+		return nil, fmt.Errorf("invalid private key: expected %d or %d bytes, got %d",
+			ed25519.SeedSize, ed25519.PrivateKeySize, len(raw))
+	}
+}
+
+// ParsePublicKeys разбирает список "keyId:base64,keyId:base64" - например, ключи, выведенные из ротации,
+// которыми подписаны ранее выданные квитанции.
+func ParsePublicKeys(list string) ([]PublicKey, error) {
+	var keys []PublicKey
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		id, key, ok := strings.Cut(item, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid public key entry %q: expected keyId:base64", item)
+		}
+		keys = append(keys, PublicKey{KeyId: id, Algorithm: Algorithm, PublicKey: key})
+	}
+
+	// Проверяем, что ключи корректны.
+	if _, err := ParseKeySet(keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
@@ -0,0 +1,137 @@
+package receipt
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(t *testing.T, seed byte) ed25519.PrivateKey {
+	t.Helper()
+	raw := make([]byte, ed25519.SeedSize)
+	for i := range raw {
+		raw[i] = seed
+	}
+	return ed25519.NewKeyFromSeed(raw)
+}
+
+func TestVerify(t *testing.T) {
+	key := testKey(t, 1)
+	other := testKey(t, 2)
+
+	keys, err := ParseKeySet([]PublicKey{
+		Public("k1", key.Public().(ed25519.PublicKey)),
+		Public("k2", other.Public().(ed25519.PublicKey)),
+	})
+	require.NoError(t, err)
+
+	signed := Sign(Receipt{
+		TransactionId: 42,
+		ValletId:      "11111111-1111-1111-1111-111111111111",
+		OperationType: "DEPOSIT",
+		Amount:        100.5,
+		BalanceAfter:  1100.5,
+		Seq:           7,
+		Timestamp:     time.Date(2025, 1, 2, 3, 4, 5, 123456000, time.UTC),
+	}, "k1", key)
+
+	testTable := []struct {
+		name     string
+		modify   func(r *Receipt)
+		expected error
+	}{
+		{
+			name:   "valid",
+			modify: func(r *Receipt) {},
+		},
+		{
+			name: "valid after JSON round trip",
+			modify: func(r *Receipt) {
+				body, err := json.Marshal(r)
+				require.NoError(t, err)
+				*r = Receipt{}
+				require.NoError(t, json.Unmarshal(body, r))
+			},
+		},
+		{
+			name:     "edited amount",
+			modify:   func(r *Receipt) { r.Amount = 1000.5 },
+			expected: ErrInvalidSignature,
+		},
+		{
+			name:     "edited timestamp",
+			modify:   func(r *Receipt) { r.Timestamp = r.Timestamp.Add(time.Microsecond) },
+			expected: ErrInvalidSignature,
+		},
+		{
+			name:     "signature of another key",
+			modify:   func(r *Receipt) { r.KeyId = "k2" },
+			expected: ErrInvalidSignature,
+		},
+		{
+			name:     "malformed signature",
+			modify:   func(r *Receipt) { r.Signature = "!!!" },
+			expected: ErrInvalidSignature,
+		},
+		{
+			name:     "unknown key",
+			modify:   func(r *Receipt) { r.KeyId = "k3" },
+			expected: ErrUnknownKey,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			r := signed
+			test.modify(&r)
+
+			err := Verify(r, keys)
+			if test.expected == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, test.expected)
+			}
+		})
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	key := testKey(t, 3)
+
+	fromSeed, err := ParsePrivateKey(base64.StdEncoding.EncodeToString(key.Seed()))
+	require.NoError(t, err)
+	assert.Equal(t, key, fromSeed)
+
+	full, err := ParsePrivateKey(base64.StdEncoding.EncodeToString(key))
+	require.NoError(t, err)
+	assert.Equal(t, key, full)
+
+	_, err = ParsePrivateKey(base64.StdEncoding.EncodeToString([]byte("short")))
+	assert.Error(t, err)
+
+	_, err = ParsePrivateKey("not base64")
+	assert.Error(t, err)
+}
+
+func TestParsePublicKeys(t *testing.T) {
+	pub := base64.StdEncoding.EncodeToString(testKey(t, 4).Public().(ed25519.PublicKey))
+
+	keys, err := ParsePublicKeys(" old-1:" + pub + ", ")
+	require.NoError(t, err)
+	assert.Equal(t, []PublicKey{{KeyId: "old-1", Algorithm: Algorithm, PublicKey: pub}}, keys)
+
+	keys, err = ParsePublicKeys("")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	_, err = ParsePublicKeys(pub)
+	assert.Error(t, err)
+
+	_, err = ParsePublicKeys("old-1:AAAA")
+	assert.Error(t, err)
+}
@@ -0,0 +1,141 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var cronMacros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+// cronSchedule - выражение cron "минута час день_месяца месяц день_недели".
+// Как в cron, если заданы и день месяца, и день недели, достаточно совпадения любого из них.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64 // биты допустимых значений
+	domAny, dowAny                bool
+	times                         []int
+}
+
+func parseCron(expr string) (cronSchedule, error) {
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return cronSchedule{}, fmt.Errorf("%w: cron expression must have 5 fields", ErrInvalidSchedule)
+	}
+
+	var (
+		c   cronSchedule
+		err error
+	)
+	bounds := []struct {
+		dst      *uint64
+		min, max int
+		name     string
+	}{
+		{&c.minute, 0, 59, "minute"},
+		{&c.hour, 0, 23, "hour"},
+		{&c.dom, 1, 31, "day of month"},
+		{&c.month, 1, 12, "month"},
+		{&c.dow, 0, 7, "day of week"},
+	}
+	for i, b := range bounds {
+		if *b.dst, err = parseCronField(fields[i], b.min, b.max); err != nil {
+			return cronSchedule{}, fmt.Errorf("%w: %s: %s", ErrInvalidSchedule, b.name, err.Error())
+		}
+	}
+	if c.dow&(1<<7) != 0 { // 7 - тоже воскресенье
+		c.dow |= 1
+	}
+	c.domAny = strings.HasPrefix(fields[2], "*")
+	c.dowAny = strings.HasPrefix(fields[4], "*")
+
+	for h := 0; h < 24; h++ {
+		for m := 0; m < 60; m++ {
+			if c.hour&(1<<h) != 0 && c.minute&(1<<m) != 0 {
+				c.times = append(c.times, h*60+m)
+			}
+		}
+	}
+	return c, nil
+}
+
+// parseCronField разбирает поле cron: "*", число, диапазон "a-b", список через запятую и шаг "/n".
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rng, step = part[:i], n
+		}
+
+		lo, hi := min, max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			a, b, _ := strings.Cut(rng, "-")
+			var errA, errB error
+			lo, errA = strconv.Atoi(a)
+			hi, errB = strconv.Atoi(b)
+			if errA != nil || errB != nil || lo > hi {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		default:
+			n, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", rng)
+			}
+			lo, hi = n, n
+			if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max {
+			return 0, fmt.Errorf("%q is out of range %d-%d", rng, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	if bits == 0 {
+		return 0, errors.New("no values")
+	}
+	return bits, nil
+}
+
+func (c cronSchedule) matchDay(d time.Time) bool {
+	if c.month&(1<<int(d.Month())) == 0 {
+		return false
+	}
+	domOK := c.dom&(1<<d.Day()) != 0
+	dowOK := c.dow&(1<<int(d.Weekday())) != 0
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dowOK
+	case c.dowAny:
+		return domOK
+	default:
+		return domOK || dowOK
+	}
+}
+
+func (c cronSchedule) Next(after time.Time) time.Time {
+	return nextInDay(after, c.times, c.matchDay)
+}
@@ -0,0 +1,91 @@
+// Package recurrence - расписания повторяющихся операций: выражения cron из пяти полей
+// и подмножество RRULE (RFC 5545).
+//
+// Все расчёты ведутся в UTC с точностью до минуты. Поиск следующего срабатывания ограничен
+// horizon: расписание, которое не срабатывает в ближайшие годы, считается исчерпанным.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// horizon - на сколько дней вперёд ищется следующее срабатывание; покрывает 29 февраля через вековой год.
+const horizon = 366*8 + 1
+
+// Schedule - расписание повторяющейся операции.
+type Schedule interface {
+	// Next возвращает первое срабатывание строго после after или нулевое время, если срабатываний больше нет.
+	Next(after time.Time) time.Time
+}
+
+// Parse разбирает расписание: правило RRULE (с префиксом "RRULE:" или без, например
+// "FREQ=MONTHLY;BYMONTHDAY=1;BYHOUR=9") или выражение cron ("0 9 1 * *", "@monthly").
+// start - начало действия расписания (DTSTART для RRULE): от него отсчитывается INTERVAL и берутся
+// день и время по умолчанию; срабатываний раньше start нет.
+func Parse(expr string, start time.Time) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, fmt.Errorf("%w: schedule is empty", ErrInvalidSchedule)
+	}
+
+	start = start.UTC()
+	if strings.HasPrefix(strings.ToUpper(expr), "RRULE:") || strings.Contains(strings.ToUpper(expr), "FREQ=") {
+		return parseRRule(strings.TrimPrefix(strings.TrimPrefix(expr, "RRULE:"), "rrule:"), start)
+	}
+
+	c, err := parseCron(expr)
+	if err != nil {
+		return nil, err
+	}
+	return bounded{Schedule: c, start: start}, nil
+}
+
+// bounded не даёт расписанию срабатывать раньше start.
+type bounded struct {
+	Schedule
+	start time.Time
+}
+
+func (b bounded) Next(after time.Time) time.Time {
+	if after.Before(b.start) {
+		after = b.start.Add(-time.Nanosecond)
+	}
+	return b.Schedule.Next(after)
+}
+
+// firstMinute возвращает начало первой целой минуты строго после after.
+func firstMinute(after time.Time) time.Time {
+	return after.UTC().Truncate(time.Minute).Add(time.Minute)
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// nextInDay перебирает дни начиная с дня after и возвращает первое время из times (минуты от начала суток,
+// по возрастанию) строго после after в день, для которого match возвращает true.
+func nextInDay(after time.Time, times []int, match func(day time.Time) bool) time.Time {
+	first := firstMinute(after)
+	day := startOfDay(first)
+	for i := 0; i < horizon; i++ {
+		d := day.AddDate(0, 0, i)
+		if !match(d) {
+			continue
+		}
+		from := 0
+		if i == 0 {
+			from = first.Hour()*60 + first.Minute()
+		}
+		for _, m := range times {
+			if m >= from {
+				return d.Add(time.Duration(m) * time.Minute)
+			}
+		}
+	}
+	return time.Time{}
+}
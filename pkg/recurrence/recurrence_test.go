@@ -0,0 +1,139 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParse_Next(t *testing.T) {
+	start := date("2026-01-15 10:30")
+
+	testTable := []struct {
+		name     string
+		expr     string
+		after    string
+		expected []string
+	}{
+		{
+			name:     "cron first of month",
+			expr:     "0 9 1 * *",
+			after:    "2026-01-15 10:30",
+			expected: []string{"2026-02-01 09:00", "2026-03-01 09:00", "2026-04-01 09:00"},
+		},
+		{
+			name:     "cron steps and ranges",
+			expr:     "*/20 9-10 * * *",
+			after:    "2026-01-20 10:15",
+			expected: []string{"2026-01-20 10:20", "2026-01-20 10:40", "2026-01-21 09:00"},
+		},
+		{
+			name:     "cron day of month or day of week",
+			expr:     "0 0 13 * 5",
+			after:    "2026-02-01 00:00",
+			expected: []string{"2026-02-06 00:00", "2026-02-13 00:00", "2026-02-20 00:00"},
+		},
+		{
+			name:     "cron sunday as 7",
+			expr:     "30 8 * * 7",
+			after:    "2026-02-01 08:30",
+			expected: []string{"2026-02-08 08:30"},
+		},
+		{
+			name:     "cron never before start",
+			expr:     "@daily",
+			after:    "2025-06-01 00:00",
+			expected: []string{"2026-01-16 00:00", "2026-01-17 00:00"},
+		},
+		{
+			name:     "cron leap day",
+			expr:     "0 0 29 2 *",
+			after:    "2026-03-01 00:00",
+			expected: []string{"2028-02-29 00:00", "2032-02-29 00:00"},
+		},
+		{
+			name:     "rrule monthly defaults to start day and time",
+			expr:     "RRULE:FREQ=MONTHLY",
+			after:    "2026-01-01 00:00",
+			expected: []string{"2026-01-15 10:30", "2026-02-15 10:30", "2026-03-15 10:30"},
+		},
+		{
+			name:     "rrule last day of month",
+			expr:     "FREQ=MONTHLY;BYMONTHDAY=-1;BYHOUR=18;BYMINUTE=0",
+			after:    "2026-01-20 00:00",
+			expected: []string{"2026-01-31 18:00", "2026-02-28 18:00", "2026-03-31 18:00"},
+		},
+		{
+			name:     "rrule skips missing days",
+			expr:     "FREQ=MONTHLY;BYMONTHDAY=31;BYHOUR=0;BYMINUTE=0",
+			after:    "2026-01-31 00:00",
+			expected: []string{"2026-03-31 00:00", "2026-05-31 00:00"},
+		},
+		{
+			name:     "rrule every other week",
+			expr:     "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;BYHOUR=9;BYMINUTE=0",
+			after:    "2026-01-15 10:30",
+			expected: []string{"2026-01-16 09:00", "2026-01-26 09:00", "2026-01-30 09:00", "2026-02-09 09:00"},
+		},
+		{
+			name:     "rrule daily interval",
+			expr:     "FREQ=DAILY;INTERVAL=3",
+			after:    "2026-01-15 10:30",
+			expected: []string{"2026-01-18 10:30", "2026-01-21 10:30"},
+		},
+		{
+			name:     "rrule until",
+			expr:     "FREQ=DAILY;UNTIL=20260117",
+			after:    "2026-01-15 10:30",
+			expected: []string{"2026-01-16 10:30", "2026-01-17 10:30", ""},
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := Parse(test.expr, start)
+			require.NoError(t, err)
+
+			after := date(test.after)
+			for _, want := range test.expected {
+				next := s.Next(after)
+				if want == "" {
+					assert.True(t, next.IsZero(), "unexpected %s", next)
+					return
+				}
+				assert.Equal(t, date(want), next)
+				after = next
+			}
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"0 9 1 *",
+		"60 * * * *",
+		"* * 0 * *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"FREQ=YEARLY",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=DAILY;COUNT=3",
+		"FREQ=DAILY;BYDAY=XX",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"INTERVAL=2",
+	} {
+		_, err := Parse(expr, date("2026-01-01 00:00"))
+		assert.ErrorIs(t, err, ErrInvalidSchedule, expr)
+	}
+}
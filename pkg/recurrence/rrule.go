@@ -0,0 +1,187 @@
+package recurrence
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// rruleSchedule - подмножество RRULE: FREQ=DAILY|WEEKLY|MONTHLY, INTERVAL, BYDAY (без номера недели),
+// BYMONTHDAY (отрицательные - от конца месяца), BYHOUR, BYMINUTE и UNTIL.
+// Без BYHOUR и BYMINUTE время берётся из DTSTART, секунды всегда нулевые.
+// Дни, которых нет в месяце (BYMONTHDAY=31 в апреле), пропускаются, как в RFC 5545.
+type rruleSchedule struct {
+	freq       string
+	interval   int
+	byDay      map[time.Weekday]bool
+	byMonthDay []int
+	times      []int
+	start      time.Time
+	until      time.Time
+}
+
+func parseRRule(rule string, start time.Time) (rruleSchedule, error) {
+	r := rruleSchedule{interval: 1, start: start}
+	var hours, minutes []int
+
+	for _, part := range strings.Split(rule, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return rruleSchedule{}, fmt.Errorf("%w: invalid rule part %q", ErrInvalidSchedule, part)
+		}
+
+		var err error
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			r.freq = strings.ToUpper(value)
+		case "INTERVAL":
+			r.interval, err = strconv.Atoi(value)
+			if err == nil && (r.interval < 1 || r.interval > 1000) {
+				err = fmt.Errorf("must be between 1 and 1000")
+			}
+		case "BYDAY":
+			r.byDay = make(map[time.Weekday]bool)
+			for _, day := range strings.Split(strings.ToUpper(value), ",") {
+				wd, ok := weekdays[day]
+				if !ok {
+					err = fmt.Errorf("unknown day %q", day)
+					break
+				}
+				r.byDay[wd] = true
+			}
+		case "BYMONTHDAY":
+			r.byMonthDay, err = parseInts(value, -31, 31)
+			for _, d := range r.byMonthDay {
+				if d == 0 {
+					err = fmt.Errorf("day 0 is out of range")
+				}
+			}
+		case "BYHOUR":
+			hours, err = parseInts(value, 0, 23)
+		case "BYMINUTE":
+			minutes, err = parseInts(value, 0, 59)
+		case "UNTIL":
+			r.until, err = parseUntil(value)
+		default:
+			err = fmt.Errorf("is not supported")
+		}
+		if err != nil {
+			return rruleSchedule{}, fmt.Errorf("%w: %s: %s", ErrInvalidSchedule, strings.ToUpper(name), err.Error())
+		}
+	}
+
+	switch r.freq {
+	case "DAILY", "MONTHLY":
+	case "WEEKLY":
+		if len(r.byMonthDay) > 0 {
+			return rruleSchedule{}, fmt.Errorf("%w: BYMONTHDAY is not allowed with FREQ=WEEKLY", ErrInvalidSchedule)
+		}
+		if r.byDay == nil {
+			r.byDay = map[time.Weekday]bool{start.Weekday(): true}
+		}
+	case "":
+		return rruleSchedule{}, fmt.Errorf("%w: FREQ is required", ErrInvalidSchedule)
+	default:
+		return rruleSchedule{}, fmt.Errorf("%w: FREQ must be DAILY, WEEKLY or MONTHLY", ErrInvalidSchedule)
+	}
+	if r.freq == "MONTHLY" && r.byDay == nil && r.byMonthDay == nil {
+		r.byMonthDay = []int{start.Day()}
+	}
+
+	if hours == nil {
+		hours = []int{start.Hour()}
+	}
+	if minutes == nil {
+		minutes = []int{start.Minute()}
+	}
+	for _, h := range hours {
+		for _, m := range minutes {
+			r.times = append(r.times, h*60+m)
+		}
+	}
+	sort.Ints(r.times)
+
+	return r, nil
+}
+
+func parseInts(value string, min, max int) ([]int, error) {
+	var ints []int
+	for _, s := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < min || n > max {
+			return nil, fmt.Errorf("%q is out of range %d..%d", s, min, max)
+		}
+		ints = append(ints, n)
+	}
+	return ints, nil
+}
+
+// parseUntil разбирает UNTIL в виде даты (20261231) или времени UTC (20261231T235959Z).
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
+
+func (r rruleSchedule) matchDay(d time.Time) bool {
+	start := startOfDay(r.start)
+
+	switch r.freq {
+	case "DAILY":
+		if int(d.Sub(start).Hours()/24)%r.interval != 0 {
+			return false
+		}
+	case "WEEKLY":
+		if int(weekStart(d).Sub(weekStart(start)).Hours()/24/7)%r.interval != 0 {
+			return false
+		}
+	case "MONTHLY":
+		months := (d.Year()-start.Year())*12 + int(d.Month()) - int(start.Month())
+		if months%r.interval != 0 {
+			return false
+		}
+	}
+
+	if r.byDay != nil && !r.byDay[d.Weekday()] {
+		return false
+	}
+	if r.byMonthDay != nil {
+		last := time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		for _, md := range r.byMonthDay {
+			if md == d.Day() || last+md+1 == d.Day() {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// weekStart возвращает понедельник недели дня d.
+func weekStart(d time.Time) time.Time {
+	return d.AddDate(0, 0, -(int(d.Weekday())+6)%7)
+}
+
+func (r rruleSchedule) Next(after time.Time) time.Time {
+	if after.Before(r.start) {
+		after = r.start.Add(-time.Nanosecond)
+	}
+	next := nextInDay(after, r.times, r.matchDay)
+	if !r.until.IsZero() && next.After(r.until) {
+		return time.Time{}
+	}
+	return next
+}
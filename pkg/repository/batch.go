@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// batchTimeout - ограничение на транзакцию ApplyAtomic: пакет может затрагивать тысячи кошельков.
+const batchTimeout = 30 * time.Second
+
+func (w *WalletPsql) ApplyAtomic(ctx context.Context, ops []wallet.WalletTransactions) ([]wallet.WalletTransactions, []error, error) {
+	ctx, cancel := context.WithTimeout(ctx, batchTimeout)
+	defer cancel()
+
+	var (
+		recorded []wallet.WalletTransactions
+		results  []error
+	)
+	err := w.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		states, err := lockWallets(ctx, tx, batchWallets(ops)...)
+		if err != nil {
+			return err
+		}
+		known, err := findIdempotent(ctx, tx, ops)
+		if err != nil {
+			return err
+		}
+
+		var groups map[string]*walletGroup
+		recorded, results, groups = applyAtomic(ops, states, known)
+		if !batchApplied(results) {
+			return nil // ничего не записано; блокировки снимаются вместе с транзакцией
+		}
+
+		events := make([]wallet.WalletEvent, 0, len(ops))
+		for _, id := range sortedKeys(groups) {
+			g := groups[id]
+			if len(g.applied) == 0 {
+				continue
+			}
+			if err := recordTransactions(ctx, tx, g.uid, *states[id], g.recorded, g.applied); err != nil {
+				return err
+			}
+			groupEvents, err := walletEvents(g.uid, g.ops, g.recorded, g.results, time.Now().UTC())
+			if err != nil {
+				return err
+			}
+			events = append(events, groupEvents...)
+			g.scatter(recorded)
+		}
+		fillDuplicates(ops, recorded, results)
+
+		return insertOutbox(ctx, tx, events)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return recorded, results, nil
+}
+
+// walletGroup - операции пакета одного кошелька в порядке пакета; index - их позиции в пакете.
+type walletGroup struct {
+	uid      uuid.UUID
+	index    []int
+	ops      []wallet.WalletTransactions
+	recorded []wallet.WalletTransactions
+	results  []error
+	applied  []int
+}
+
+// scatter переносит записанные транзакции группы (с id и created_at) на их позиции в пакете.
+func (g *walletGroup) scatter(recorded []wallet.WalletTransactions) {
+	for _, j := range g.applied {
+		recorded[g.index[j]] = g.recorded[j]
+	}
+}
+
+// applyAtomic применяет операции пакета к заблокированным кошелькам states (по ID) по порядку пакета.
+// Возвращает записи и ошибки по операциям, а также операции, сгруппированные по кошелькам, для записи в БД.
+func applyAtomic(ops []wallet.WalletTransactions, states map[string]*walletState,
+	known map[string]map[string]wallet.WalletTransactions) ([]wallet.WalletTransactions, []error, map[string]*walletGroup) {
+	recorded := make([]wallet.WalletTransactions, len(ops))
+	results := make([]error, len(ops))
+	groups := make(map[string]*walletGroup)
+
+	for i, WT := range ops {
+		id := WT.ValletId.UUID.String()
+		st, ok := states[id]
+		if !ok {
+			results[i] = fmt.Errorf("failed to lock wallet %s: %w", id, ErrWalletNotFound)
+			continue
+		}
+
+		if known[id] == nil {
+			known[id] = make(map[string]wallet.WalletTransactions)
+		}
+		one, res, applied := st.apply(WT.ValletId, []wallet.WalletTransactions{WT}, known[id])
+		recorded[i], results[i] = one[0], res[0]
+
+		g, ok := groups[id]
+		if !ok {
+			g = &walletGroup{uid: WT.ValletId}
+			groups[id] = g
+		}
+		if len(applied) > 0 {
+			g.applied = append(g.applied, len(g.ops))
+		}
+		g.index = append(g.index, i)
+		g.ops = append(g.ops, WT)
+		g.recorded = append(g.recorded, one[0])
+		g.results = append(g.results, res[0])
+	}
+
+	return recorded, results, groups
+}
+
+// batchApplied сообщает, что в пакете нет отказов (повторы по ключу идемпотентности отказом не считаются).
+func batchApplied(results []error) bool {
+	for _, err := range results {
+		if err != nil && !errors.Is(err, ErrDuplicateTransaction) {
+			return false
+		}
+	}
+	return true
+}
+
+// fillDuplicates подставляет повторам внутри одного пакета исходную транзакцию уже с id и created_at.
+func fillDuplicates(ops, recorded []wallet.WalletTransactions, results []error) {
+	first := make(map[[2]string]int)
+	for i, WT := range ops {
+		if WT.IdempotencyKey == "" {
+			continue
+		}
+		key := [2]string{WT.ValletId.UUID.String(), WT.IdempotencyKey}
+		switch {
+		case results[i] == nil:
+			first[key] = i
+		case errors.Is(results[i], ErrDuplicateTransaction) && recorded[i].Id == 0:
+			if j, ok := first[key]; ok {
+				recorded[i] = recorded[j]
+			}
+		}
+	}
+}
+
+func batchWallets(ops []wallet.WalletTransactions) []uuid.UUID {
+	seen := make(map[string]bool, len(ops))
+	ids := make([]uuid.UUID, 0, len(ops))
+	for _, WT := range ops {
+		if id := WT.ValletId.UUID.String(); !seen[id] {
+			seen[id] = true
+			ids = append(ids, WT.ValletId)
+		}
+	}
+	return ids
+}
+
+func sortedKeys(groups map[string]*walletGroup) []string {
+	keys := make([]string, 0, len(groups))
+	for id := range groups {
+		keys = append(keys, id)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (w *WalletPsql) FindIdempotent(ctx context.Context, ops []wallet.WalletTransactions) (map[string]map[string]wallet.WalletTransactions, error) {
+	ctx, cancel := context.WithTimeout(ctx, batchTimeout)
+	defer cancel()
+
+	return findIdempotent(ctx, w.db, ops)
+}
+
+// findIdempotent возвращает уже записанные транзакции с ключами идемпотентности из ops: кошелёк -> ключ -> транзакция.
+// При записи вызывается под блокировкой кошельков, поэтому параллельная запись того же ключа невозможна.
+func findIdempotent(ctx context.Context, q sqlx.QueryerContext, ops []wallet.WalletTransactions) (map[string]map[string]wallet.WalletTransactions, error) {
+	known := make(map[string]map[string]wallet.WalletTransactions)
+
+	var ids, keys []string
+	for _, WT := range ops {
+		if WT.IdempotencyKey != "" {
+			ids = append(ids, WT.ValletId.UUID.String())
+			keys = append(keys, WT.IdempotencyKey)
+		}
+	}
+	if len(keys) == 0 {
+		return known, nil
+	}
+
+	rows, err := q.QueryContext(ctx, fmt.Sprintf(`SELECT id, valletId, operation_type, amount, seq, balance_after, hash, created_at, idempotency_key,
+		COALESCE(kind, ''), operation_id FROM %s WHERE (valletId, idempotency_key) IN (SELECT * FROM unnest($1::uuid[], $2::text[]))`, walletTRXTable),
+		pq.Array(ids), pq.Array(keys))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up idempotency keys: %w", err)
+	}
+	defer rows.Close()
+
+	var found []wallet.WalletTransactions
+	for rows.Next() {
+		var WT wallet.WalletTransactions
+		if err := rows.Scan(&WT.Id, &WT.ValletId, &WT.OperationType, &WT.Amount, &WT.Seq, &WT.BalanceAfter, &WT.Hash,
+			&WT.CreatedAt, &WT.IdempotencyKey, &WT.Kind, &WT.OperationId); err != nil {
+			return nil, fmt.Errorf("failed to look up idempotency keys: %w", err)
+		}
+		found = append(found, WT)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to look up idempotency keys: %w", err)
+	}
+	rows.Close()
+
+	if err := loadBucketEntries(ctx, q, found); err != nil {
+		return nil, err
+	}
+	for _, WT := range found {
+		id := WT.ValletId.UUID.String()
+		if known[id] == nil {
+			known[id] = make(map[string]wallet.WalletTransactions)
+		}
+		known[id][WT.IdempotencyKey] = WT
+	}
+	return known, nil
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	sqlmock "github.com/zhashkevych/go-sqlxmock"
+)
+
+func TestWalletPsql_ApplyAtomic(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		log.Fatalf("unexpected error opening stub db: %s", err)
+	}
+	defer db.Close()
+
+	w := NewWalletPsql(db, NewTxRunner(db, TxOptions{}))
+	a := uuidFromString("11111111-1111-1111-1111-111111111111")
+	b := uuidFromString("22222222-2222-2222-2222-222222222222")
+
+	lockQuery := fmt.Sprintf(`SELECT valletid, balance, version, last_seq, last_hash, credit_limit, bonus_balance, cashback_balance, promo_balance
+		FROM %s WHERE valletid = ANY`, walletTable)
+	updateQuery := fmt.Sprintf(`UPDATE %s SET balance`, walletTable)
+	insertQuery := fmt.Sprintf(`INSERT INTO %s \(valletId, operation_type, amount, seq, balance_after, hash, idempotency_key, kind, operation_id\)`, walletTRXTable)
+	outboxQuery := fmt.Sprintf(`INSERT INTO %s \(event_id, valletId, event_type, payload\)`, outboxTable)
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	lockedRows := []string{"valletid", "balance", "version", "last_seq", "last_hash", "credit_limit", "bonus_balance", "cashback_balance", "promo_balance"}
+
+	testTable := []struct {
+		name           string
+		ops            []wallet.WalletTransactions
+		mockSetup      func()
+		expectedErrors []error
+		expectedIds    []int
+	}{
+		{
+			name: "success",
+			ops: []wallet.WalletTransactions{
+				{ValletId: b, OperationType: "DEPOSIT", Amount: 5},
+				{ValletId: a, OperationType: "WITHDRAW", Amount: 5},
+			},
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(pq.Array([]string{b.UUID.String(), a.UUID.String()})).
+					WillReturnRows(sqlmock.NewRows(lockedRows).
+						AddRow(a.UUID.String(), 10.0, 1, 0, "", 0.0, 0.0, 0.0, 0.0).
+						AddRow(b.UUID.String(), 0.0, 1, 0, "", 0.0, 0.0, 0.0, 0.0))
+				// Кошельки записываются в порядке ID, события - в том же порядке.
+				mock.ExpectExec(updateQuery).
+					WithArgs(5.0, 2, 1, sqlmock.AnyArg(), 0.0, 0.0, 0.0, a.UUID.String()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery(insertQuery).
+					WithArgs(a.UUID.String(), "WITHDRAW", 5.0, 1, 5.0, sqlmock.AnyArg(), "", "", nil,
+						pq.Array([]int64{1}), pq.Array([]string{"main"}), pq.Array([]float64{5}), pq.Array([]float64{5})).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(21, createdAt))
+				mock.ExpectExec(updateQuery).
+					WithArgs(5.0, 2, 1, sqlmock.AnyArg(), 0.0, 0.0, 0.0, b.UUID.String()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery(insertQuery).
+					WithArgs(b.UUID.String(), "DEPOSIT", 5.0, 1, 5.0, sqlmock.AnyArg(), "", "", nil,
+						pq.Array([]int64{1}), pq.Array([]string{"main"}), pq.Array([]float64{5}), pq.Array([]float64{5})).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(22, createdAt))
+				mock.ExpectExec(outboxQuery).
+					WithArgs(
+						sqlmock.AnyArg(), a.UUID.String(), wallet.EventWithdrawn, sqlmock.AnyArg(),
+						sqlmock.AnyArg(), b.UUID.String(), wallet.EventDeposited, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			},
+			expectedErrors: []error{nil, nil},
+			expectedIds:    []int{22, 21},
+		},
+		{
+			name: "one rejection aborts the batch",
+			ops: []wallet.WalletTransactions{
+				{ValletId: b, OperationType: "DEPOSIT", Amount: 5},
+				{ValletId: a, OperationType: "WITHDRAW", Amount: 50},
+			},
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WillReturnRows(sqlmock.NewRows(lockedRows).
+						AddRow(a.UUID.String(), 10.0, 1, 0, "", 0.0, 0.0, 0.0, 0.0).
+						AddRow(b.UUID.String(), 0.0, 1, 0, "", 0.0, 0.0, 0.0, 0.0))
+				mock.ExpectCommit()
+			},
+			expectedErrors: []error{nil, ErrInsufficientFunds},
+			expectedIds:    []int{0, 0},
+		},
+		{
+			name: "missing wallet aborts the batch",
+			ops: []wallet.WalletTransactions{
+				{ValletId: a, OperationType: "WITHDRAW", Amount: 5},
+				{ValletId: b, OperationType: "DEPOSIT", Amount: 5},
+			},
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WillReturnRows(sqlmock.NewRows(lockedRows).AddRow(a.UUID.String(), 10.0, 1, 0, "", 0.0, 0.0, 0.0, 0.0))
+				mock.ExpectCommit()
+			},
+			expectedErrors: []error{nil, ErrWalletNotFound},
+			expectedIds:    []int{0, 0},
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			test.mockSetup()
+
+			recorded, results, err := w.ApplyAtomic(context.Background(), test.ops)
+
+			assert.NoError(t, err)
+			for i, expected := range test.expectedErrors {
+				if expected == nil {
+					assert.NoError(t, results[i])
+				} else {
+					assert.ErrorIs(t, results[i], expected)
+				}
+				assert.Equal(t, test.expectedIds[i], recorded[i].Id)
+			}
+
+			err = mock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestWalletPsql_FindIdempotent(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		log.Fatalf("unexpected error opening stub db: %s", err)
+	}
+	defer db.Close()
+
+	w := NewWalletPsql(db, NewTxRunner(db, TxOptions{}))
+	a := uuidFromString("11111111-1111-1111-1111-111111111111")
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	mock.ExpectQuery(fmt.Sprintf(`SELECT id, valletId, operation_type, amount, seq, balance_after, hash, created_at, idempotency_key,
+		COALESCE\(kind, ''\), operation_id FROM %s WHERE \(valletId, idempotency_key\) IN`, walletTRXTable)).
+		WithArgs(pq.Array([]string{a.UUID.String(), a.UUID.String()}), pq.Array([]string{"k1", "k2"})).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "valletId", "operation_type", "amount", "seq", "balance_after", "hash", "created_at", "idempotency_key", "kind", "operation_id"}).
+			AddRow(7, a.UUID.String(), "DEPOSIT", 5.0, 3, 15.0, "h", createdAt, "k1", "", nil))
+	mock.ExpectQuery(fmt.Sprintf(`SELECT transaction_id, bucket, amount, balance_after FROM %s`, bucketEntryTable)).
+		WithArgs(pq.Array([]int64{7})).
+		WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "bucket", "amount", "balance_after"}).AddRow(7, "main", 5.0, 15.0))
+
+	// Операции без ключа в запрос не попадают.
+	found, err := w.FindIdempotent(context.Background(), []wallet.WalletTransactions{
+		{ValletId: a, IdempotencyKey: "k1"},
+		{ValletId: a},
+		{ValletId: a, IdempotencyKey: "k2"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, found, 1)
+	assert.Equal(t, 7, found[a.UUID.String()]["k1"].Id)
+	assert.Equal(t, int64(3), found[a.UUID.String()]["k1"].Seq)
+	assert.Equal(t, []wallet.BucketEntry{{Bucket: "main", Amount: 5, BalanceAfter: 15}}, found[a.UUID.String()]["k1"].Buckets)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// extraBuckets - корзины с собственным остатком в walletState.buckets и в колонках bucketColumns (в том же порядке).
+// Остаток main не хранится: это balance за вычетом остальных корзин.
+var extraBuckets = [...]string{wallet.BucketBonus, wallet.BucketCashback, wallet.BucketPromo}
+
+const bucketColumns = `bonus_balance, cashback_balance, promo_balance`
+
+func validBucket(bucket string) bool {
+	return slices.Contains(wallet.Buckets, bucket)
+}
+
+// bucket возвращает остаток корзины; bucket должна быть из wallet.Buckets.
+func (st *walletState) bucket(bucket string) float64 {
+	if i := slices.Index(extraBuckets[:], bucket); i >= 0 {
+		return st.buckets[i]
+	}
+
+	main := st.balance
+	for _, balance := range st.buckets {
+		main -= balance
+	}
+	return roundAmount(main)
+}
+
+// setBucket меняет остаток корзины, кроме main: он следует из balance.
+func (st *walletState) setBucket(bucket string, balance float64) {
+	if i := slices.Index(extraBuckets[:], bucket); i >= 0 {
+		st.buckets[i] = balance
+	}
+}
+
+// balances возвращает остатки всех корзин для wallet.Wallet.Buckets.
+func (st *walletState) balances() map[string]float64 {
+	balances := make(map[string]float64, len(wallet.Buckets))
+	for _, bucket := range wallet.Buckets {
+		balances[bucket] = st.bucket(bucket)
+	}
+	return balances
+}
+
+// available - сколько спишет WITHDRAW без явной корзины (см. split): остатки корзин wallet.WithdrawOrder
+// и кредитный лимит; промо-корзина сюда не входит.
+func (st *walletState) available() float64 {
+	available := st.creditLimit
+	for _, bucket := range wallet.WithdrawOrder {
+		available += st.bucket(bucket)
+	}
+	return roundAmount(available)
+}
+
+// split раскладывает операцию по корзинам, не меняя состояние. DEPOSIT зачисляется целиком в WT.Bucket
+// (по умолчанию main). WITHDRAW списывается из WT.Bucket или по порядку wallet.WithdrawOrder: сначала
+// из положительных остатков, а недостающее - в минус main в пределах creditLimit, если main среди корзин списания.
+// Записи возвращаются в порядке wallet.Buckets.
+func (st *walletState) split(uid uuid.UUID, WT wallet.WalletTransactions) ([]wallet.BucketEntry, error) {
+	if WT.Bucket != "" && !validBucket(WT.Bucket) {
+		return nil, fmt.Errorf("%w %q", ErrInvalidBucket, WT.Bucket)
+	}
+	amount := roundAmount(WT.Amount)
+
+	if WT.OperationType == "DEPOSIT" {
+		bucket := WT.Bucket
+		if bucket == "" {
+			bucket = wallet.BucketMain
+		}
+		return []wallet.BucketEntry{{Bucket: bucket, Amount: amount, BalanceAfter: roundAmount(st.bucket(bucket) + amount)}}, nil
+	}
+
+	order := wallet.WithdrawOrder
+	if WT.Bucket != "" {
+		order = []string{WT.Bucket}
+	}
+
+	taken := make(map[string]float64, len(order))
+	rest := amount
+	for _, bucket := range order {
+		if take := math.Min(rest, st.bucket(bucket)); take > 0 {
+			taken[bucket] = take
+			rest = roundAmount(rest - take)
+		}
+	}
+	if rest > 0 {
+		// Недостающее можно взять только в долг main.
+		main := roundAmount(st.bucket(wallet.BucketMain) - taken[wallet.BucketMain] - rest)
+		if !slices.Contains(order, wallet.BucketMain) || main < -st.creditLimit {
+			return nil, fmt.Errorf("%w for wallet %s", ErrInsufficientFunds, uid.UUID.String())
+		}
+		taken[wallet.BucketMain] = roundAmount(taken[wallet.BucketMain] + rest)
+	}
+
+	entries := make([]wallet.BucketEntry, 0, len(taken))
+	for _, bucket := range wallet.Buckets {
+		if take, ok := taken[bucket]; ok {
+			entries = append(entries, wallet.BucketEntry{Bucket: bucket, Amount: take, BalanceAfter: roundAmount(st.bucket(bucket) - take)})
+		}
+	}
+	return entries, nil
+}
+
+func (w *WalletPsql) ListBucketTransactions(ctx context.Context, uid uuid.UUID, bucket string, afterSeq int64, limit int) ([]wallet.WalletTransactions, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if !validBucket(bucket) {
+		return nil, fmt.Errorf("failed to list transactions for wallet %s: %w %q", uid.UUID.String(), ErrInvalidBucket, bucket)
+	}
+
+	var exists bool
+	err := w.db.GetContext(ctx, &exists, fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE valletId = $1)`, walletTable), uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transactions for wallet %s: %w", uid.UUID.String(), err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("failed to list transactions for wallet %s: %w", uid.UUID.String(), ErrWalletNotFound)
+	}
+
+	history, err := queryTransactions(ctx, w.db, fmt.Sprintf(`SELECT t.id, t.valletId, t.operation_type, t.amount, t.seq, t.balance_after,
+		t.hash, t.created_at, COALESCE(t.kind, ''), t.operation_id FROM %s e JOIN %s t ON t.id = e.transaction_id
+		WHERE e.valletId = $1 AND e.bucket = $2 AND e.seq > $3 ORDER BY e.seq LIMIT $4`, bucketEntryTable, walletTRXTable),
+		uid, bucket, afterSeq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transactions for wallet %s: %w", uid.UUID.String(), err)
+	}
+	return history, nil
+}
+
+// queryTransactions выбирает транзакции запросом query (колонки как в ListTransactions) вместе с их записями по корзинам.
+func queryTransactions(ctx context.Context, q sqlx.QueryerContext, query string, args ...any) ([]wallet.WalletTransactions, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make([]wallet.WalletTransactions, 0)
+	for rows.Next() {
+		var WT wallet.WalletTransactions
+		if err := rows.Scan(&WT.Id, &WT.ValletId, &WT.OperationType, &WT.Amount, &WT.Seq, &WT.BalanceAfter, &WT.Hash, &WT.CreatedAt,
+			&WT.Kind, &WT.OperationId); err != nil {
+			return nil, err
+		}
+		history = append(history, WT)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := loadBucketEntries(ctx, q, history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// loadBucketEntries заполняет Buckets у транзакций history. Вызывается после закрытия rows: в транзакции БД
+// нельзя начать новый запрос, пока не дочитан предыдущий.
+func loadBucketEntries(ctx context.Context, q sqlx.QueryerContext, history []wallet.WalletTransactions) error {
+	if len(history) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(history))
+	index := make(map[int]int, len(history))
+	for i, WT := range history {
+		ids[i] = int64(WT.Id)
+		index[WT.Id] = i
+	}
+
+	rows, err := q.QueryContext(ctx, fmt.Sprintf(`SELECT transaction_id, bucket, amount, balance_after FROM %s
+		WHERE transaction_id = ANY($1::integer[])`, bucketEntryTable), pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to load bucket entries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id    int
+			entry wallet.BucketEntry
+		)
+		if err := rows.Scan(&id, &entry.Bucket, &entry.Amount, &entry.BalanceAfter); err != nil {
+			return fmt.Errorf("failed to load bucket entries: %w", err)
+		}
+		if i, ok := index[id]; ok {
+			history[i].Buckets = append(history[i].Buckets, entry)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to load bucket entries: %w", err)
+	}
+
+	for _, WT := range history {
+		sort.Slice(WT.Buckets, func(i, j int) bool {
+			return slices.Index(wallet.Buckets, WT.Buckets[i].Bucket) < slices.Index(wallet.Buckets, WT.Buckets[j].Bucket)
+		})
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/lib/pq"
+)
+
+// changesChannel - канал NOTIFY, в который пишет триггер wallet_changes (schema/000007_wallet_changes.up.sql).
+const changesChannel = "wallet_changes"
+
+// ChangesPsql получает уведомления об изменениях кошельков через LISTEN на отдельном соединении.
+type ChangesPsql struct {
+	dsn string
+}
+
+func NewChangesPsql(dsn string) *ChangesPsql {
+	return &ChangesPsql{dsn: dsn}
+}
+
+func (c *ChangesPsql) ListenChanges(ctx context.Context, notify func(walletID uuid.UUID, seq int64)) error {
+	listener := pq.NewListener(c.dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("wallet changes listener: %s", err.Error())
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(changesChannel); err != nil {
+		return fmt.Errorf("failed to listen for wallet changes: %w", err)
+	}
+
+	// Проверка соединения: без неё обрыв может оставаться незамеченным, пока не придёт следующее уведомление.
+	ping := time.NewTicker(time.Minute)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ping.C:
+			go listener.Ping()
+		case n := <-listener.Notify:
+			if n == nil {
+				// Соединение восстановлено, уведомления за время обрыва потеряны.
+				notify(uuid.UUID{}, 0)
+				continue
+			}
+
+			walletID, seq, err := parseChange(n.Extra)
+			if err != nil {
+				log.Printf("wallet changes listener: %s", err.Error())
+				continue
+			}
+			notify(walletID, seq)
+		}
+	}
+}
+
+func parseChange(payload string) (uuid.UUID, int64, error) {
+	var walletID uuid.UUID
+
+	id, seqStr, ok := strings.Cut(payload, ":")
+	if !ok {
+		return walletID, 0, fmt.Errorf("invalid wallet change %q", payload)
+	}
+	if err := walletID.Scan(id); err != nil {
+		return walletID, 0, fmt.Errorf("invalid wallet change %q: %w", payload, err)
+	}
+	seq, err := strconv.ParseInt(seqStr, 10, 64)
+	if err != nil {
+		return walletID, 0, fmt.Errorf("invalid wallet change %q: %w", payload, err)
+	}
+	return walletID, seq, nil
+}
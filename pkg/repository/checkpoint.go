@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type CheckpointPsql struct {
+	db *sqlx.DB
+	tx *TxRunner
+}
+
+func NewCheckpointPsql(db *sqlx.DB, tx *TxRunner) *CheckpointPsql {
+	return &CheckpointPsql{db: db, tx: tx}
+}
+
+func (c *CheckpointPsql) ChainHeads(ctx context.Context) ([]wallet.Checkpoint, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	// last_seq и last_hash обновляются одним UPDATE, поэтому всегда согласованы между собой.
+	query := fmt.Sprintf(`SELECT w.valletId, w.last_seq, w.last_hash FROM %s w
+		WHERE w.last_seq > COALESCE((SELECT MAX(c.seq) FROM %s c WHERE c.valletId = w.valletId), 0)`,
+		walletTable, checkpointTable)
+	rows, err := c.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain heads: %w", err)
+	}
+	defer rows.Close()
+
+	var heads []wallet.Checkpoint
+	for rows.Next() {
+		var cp wallet.Checkpoint
+		if err := rows.Scan(&cp.ValletId, &cp.Seq, &cp.Hash); err != nil {
+			return nil, fmt.Errorf("failed to get chain heads: %w", err)
+		}
+		heads = append(heads, cp)
+	}
+	return heads, rows.Err()
+}
+
+func (c *CheckpointPsql) SaveCheckpoints(ctx context.Context, checkpoints []wallet.Checkpoint) error {
+	if len(checkpoints) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	values := make([]string, 0, len(checkpoints))
+	args := make([]interface{}, 0, 4*len(checkpoints))
+	for i, cp := range checkpoints {
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d)", 4*i+1, 4*i+2, 4*i+3, 4*i+4))
+		args = append(args, cp.ValletId, cp.Seq, cp.Hash, cp.Signature)
+	}
+
+	// Повторная запись той же точки (например, двумя экземплярами сервиса) не является ошибкой.
+	err := c.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(
+			`INSERT INTO %s (valletId, seq, hash, signature) VALUES %s ON CONFLICT (valletId, seq) DO NOTHING`,
+			checkpointTable, strings.Join(values, ", ")), args...)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoints: %w", err)
+	}
+	return nil
+}
+
+func (c *CheckpointPsql) ListCheckpoints(ctx context.Context, uid uuid.UUID) ([]wallet.Checkpoint, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var checkpoints []wallet.Checkpoint
+	query := fmt.Sprintf(`SELECT id, valletId, seq, hash, signature, created_at FROM %s WHERE valletId = $1 ORDER BY seq`,
+		checkpointTable)
+	rows, err := c.db.QueryContext(ctx, query, uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints for wallet %s: %w", uid.UUID.String(), err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cp wallet.Checkpoint
+		if err := rows.Scan(&cp.Id, &cp.ValletId, &cp.Seq, &cp.Hash, &cp.Signature, &cp.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to list checkpoints for wallet %s: %w", uid.UUID.String(), err)
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+	return checkpoints, rows.Err()
+}
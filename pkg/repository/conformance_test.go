@@ -0,0 +1,1363 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/jackc/pgtype"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Общий набор тестов для всех реализаций repository.Wallet и repository.Checkpoint.
+// walletFactory возвращает пустое хранилище с кошельками из balances (uuid -> баланс).
+type walletFactory func(t *testing.T, balances map[string]float64) *Repository
+
+const (
+	conformanceWalletA = "11111111-1111-1111-1111-111111111111"
+	conformanceWalletB = "22222222-2222-2222-2222-222222222222"
+	conformanceMissing = "99999999-9999-9999-9999-999999999999"
+)
+
+// Прогон того же набора на Postgres - TestWalletPsql_Conformance в postgres_integration_test.go (тег integration).
+func TestWalletMemory_Conformance(t *testing.T) {
+	runWalletConformance(t, func(t *testing.T, balances map[string]float64) *Repository {
+		mem := NewWalletMemory()
+		for id, balance := range balances {
+			mem.AddWallet(uuidFromString(id), balance)
+		}
+		return NewMemoryRepository(mem)
+	})
+}
+
+// applyOne применяет одну операцию и возвращает её результат, поднимая общую ошибку на уровень операции.
+func applyOne(repo Wallet, WT wallet.WalletTransactions) (wallet.WalletTransactions, error) {
+	recorded, results, err := repo.ApplyTransactions(context.Background(), WT.ValletId, []wallet.WalletTransactions{WT})
+	if err != nil {
+		return wallet.WalletTransactions{}, err
+	}
+	return recorded[0], results[0]
+}
+
+func deposit(repo Wallet, id uuid.UUID, amount float64) error {
+	_, err := applyOne(repo, wallet.WalletTransactions{ValletId: id, OperationType: "DEPOSIT", Amount: amount})
+	return err
+}
+
+func withdraw(repo Wallet, id uuid.UUID, amount float64) error {
+	_, err := applyOne(repo, wallet.WalletTransactions{ValletId: id, OperationType: "WITHDRAW", Amount: amount})
+	return err
+}
+
+func runWalletConformance(t *testing.T, newWallet walletFactory) {
+	ctx := context.Background()
+	a := uuidFromString(conformanceWalletA)
+	missing := uuidFromString(conformanceMissing)
+
+	t.Run("get balance", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 100.5})
+
+		balance, err := repo.GetBalance(ctx, a)
+		require.NoError(t, err)
+		assert.Equal(t, 100.5, balance)
+	})
+
+	t.Run("get balance of missing wallet", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 100})
+
+		_, err := repo.GetBalance(ctx, missing)
+		assert.ErrorIs(t, err, ErrWalletNotFound)
+	})
+
+	t.Run("get wallet", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 100.5})
+
+		wlt, err := repo.GetWallet(ctx, a)
+		require.NoError(t, err)
+		assert.Equal(t, a.UUID, wlt.ValletId.UUID)
+		assert.Equal(t, 100.5, wlt.Balance)
+		assert.Equal(t, int64(1), wlt.Version)
+
+		_, err = repo.GetWallet(ctx, missing)
+		assert.ErrorIs(t, err, ErrWalletNotFound)
+	})
+
+	t.Run("deposit and withdraw", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 100})
+
+		require.NoError(t, deposit(repo, a, 50.25))
+		require.NoError(t, withdraw(repo, a, 150.25))
+
+		balance, err := repo.GetBalance(ctx, a)
+		require.NoError(t, err)
+		assert.Equal(t, 0.0, balance)
+	})
+
+	t.Run("insufficient funds keeps balance", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10})
+
+		err := withdraw(repo, a, 10.01)
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+
+		wlt, err := repo.GetWallet(ctx, a)
+		require.NoError(t, err)
+		assert.Equal(t, 10.0, wlt.Balance)
+		assert.Equal(t, int64(1), wlt.Version)
+	})
+
+	t.Run("update missing wallet", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10})
+
+		err := deposit(repo, missing, 5)
+		assert.ErrorIs(t, err, ErrWalletNotFound)
+	})
+
+	t.Run("invalid operation type", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10})
+
+		_, err := applyOne(repo, wallet.WalletTransactions{ValletId: a, OperationType: "REFUND", Amount: 10})
+		assert.Error(t, err)
+	})
+
+	t.Run("recorded transaction", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10})
+
+		first, err := applyOne(repo, wallet.WalletTransactions{ValletId: a, OperationType: "DEPOSIT", Amount: 10.123})
+		require.NoError(t, err)
+		second, err := applyOne(repo, wallet.WalletTransactions{ValletId: a, OperationType: "WITHDRAW", Amount: 5})
+		require.NoError(t, err)
+
+		assert.NotZero(t, first.Id)
+		assert.Greater(t, second.Id, first.Id)
+		assert.Equal(t, 10.12, first.Amount)
+		assert.Equal(t, "WITHDRAW", second.OperationType)
+	})
+
+	t.Run("versions", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10})
+
+		recorded, err := applyOne(repo, wallet.WalletTransactions{ValletId: a, OperationType: "DEPOSIT", Amount: 1, ExpectedVersion: 1})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), recorded.Version)
+
+		_, err = applyOne(repo, wallet.WalletTransactions{ValletId: a, OperationType: "DEPOSIT", Amount: 1, ExpectedVersion: 1})
+		assert.ErrorIs(t, err, ErrVersionMismatch)
+
+		wlt, err := repo.GetWallet(ctx, a)
+		require.NoError(t, err)
+		assert.Equal(t, 11.0, wlt.Balance)
+		assert.Equal(t, int64(2), wlt.Version)
+	})
+
+	t.Run("concurrent updates are serialised", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 0, conformanceWalletB: 0})
+		b := uuidFromString(conformanceWalletB)
+
+		const workers = 50
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, deposit(repo, a, 1))
+				assert.NoError(t, deposit(repo, b, 2))
+			}()
+		}
+		wg.Wait()
+
+		wlt, err := repo.GetWallet(ctx, a)
+		require.NoError(t, err)
+		assert.Equal(t, float64(workers), wlt.Balance)
+		assert.Equal(t, int64(workers+1), wlt.Version)
+
+		balance, err := repo.GetBalance(ctx, b)
+		require.NoError(t, err)
+		assert.Equal(t, float64(2*workers), balance)
+	})
+
+	t.Run("concurrent withdrawals never overdraw", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10})
+
+		const workers = 30
+		var (
+			wg sync.WaitGroup
+			mu sync.Mutex
+			ok int
+		)
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := withdraw(repo, a, 1)
+				if err == nil {
+					mu.Lock()
+					ok++
+					mu.Unlock()
+					return
+				}
+				assert.ErrorIs(t, err, ErrInsufficientFunds)
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, 10, ok)
+		balance, err := repo.GetBalance(ctx, a)
+		require.NoError(t, err)
+		assert.Equal(t, 0.0, balance)
+	})
+
+	t.Run("apply transactions", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10})
+
+		recorded, results, err := repo.ApplyTransactions(ctx, a, []wallet.WalletTransactions{
+			{ValletId: a, OperationType: "WITHDRAW", Amount: 8},
+			{ValletId: a, OperationType: "WITHDRAW", Amount: 5},
+			{ValletId: a, OperationType: "DEPOSIT", Amount: 4.5},
+			{ValletId: a, OperationType: "WITHDRAW", Amount: 5, ExpectedVersion: 2},
+			{ValletId: a, OperationType: "WITHDRAW", Amount: 5},
+		})
+		require.NoError(t, err)
+		require.Len(t, results, 5)
+		assert.NoError(t, results[0])
+		assert.ErrorIs(t, results[1], ErrInsufficientFunds)
+		assert.NoError(t, results[2])
+		assert.ErrorIs(t, results[3], ErrVersionMismatch)
+		assert.NoError(t, results[4])
+		assert.Equal(t, int64(4), recorded[4].Version)
+
+		balance, err := repo.GetBalance(ctx, a)
+		require.NoError(t, err)
+		assert.Equal(t, 1.5, balance)
+	})
+
+	t.Run("apply transactions to missing wallet", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10})
+
+		_, _, err := repo.ApplyTransactions(ctx, missing, []wallet.WalletTransactions{
+			{ValletId: missing, OperationType: "DEPOSIT", Amount: 1},
+		})
+		assert.ErrorIs(t, err, ErrWalletNotFound)
+	})
+
+	t.Run("gapless history", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 5})
+
+		const workers = 40
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				if i%2 == 0 {
+					assert.NoError(t, deposit(repo, a, 2))
+					return
+				}
+				err := withdraw(repo, a, 3)
+				if err != nil {
+					assert.ErrorIs(t, err, ErrInsufficientFunds)
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		history, err := repo.ListTransactions(ctx, a, 0, 1000)
+		require.NoError(t, err)
+		require.NotEmpty(t, history)
+
+		// Номера идут подряд, а баланс пересчитывается инкрементально от начального.
+		balance := 5.0
+		for i, WT := range history {
+			assert.Equal(t, int64(i+1), WT.Seq)
+			if WT.OperationType == "DEPOSIT" {
+				balance += WT.Amount
+			} else {
+				balance -= WT.Amount
+			}
+			assert.InDelta(t, balance, WT.BalanceAfter, 0.001)
+			assert.GreaterOrEqual(t, WT.BalanceAfter, 0.0)
+			assert.False(t, WT.CreatedAt.IsZero())
+		}
+
+		current, err := repo.GetBalance(ctx, a)
+		require.NoError(t, err)
+		assert.InDelta(t, current, history[len(history)-1].BalanceAfter, 0.001)
+	})
+
+	t.Run("list transactions pages", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 0})
+		for i := 0; i < 5; i++ {
+			require.NoError(t, deposit(repo, a, 1))
+		}
+
+		page, err := repo.ListTransactions(ctx, a, 2, 2)
+		require.NoError(t, err)
+		require.Len(t, page, 2)
+		assert.Equal(t, int64(3), page[0].Seq)
+		assert.Equal(t, int64(4), page[1].Seq)
+		assert.Equal(t, 4.0, page[1].BalanceAfter)
+
+		page, err = repo.ListTransactions(ctx, a, 5, 10)
+		require.NoError(t, err)
+		assert.Empty(t, page)
+
+		_, err = repo.ListTransactions(ctx, missing, 0, 10)
+		assert.ErrorIs(t, err, ErrWalletNotFound)
+	})
+
+	t.Run("period seq", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 0})
+		var recorded []wallet.WalletTransactions
+		for i := 0; i < 4; i++ {
+			WT, err := applyOne(repo, wallet.WalletTransactions{ValletId: a, OperationType: "DEPOSIT", Amount: 1})
+			require.NoError(t, err)
+			recorded = append(recorded, WT)
+			time.Sleep(2 * time.Millisecond)
+		}
+
+		afterSeq, lastSeq, err := repo.PeriodSeq(ctx, a, recorded[1].CreatedAt, recorded[3].CreatedAt)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), afterSeq)
+		assert.Equal(t, int64(3), lastSeq)
+
+		afterSeq, lastSeq, err = repo.PeriodSeq(ctx, a, time.Time{}, time.Time{})
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), afterSeq)
+		assert.Equal(t, int64(4), lastSeq)
+
+		afterSeq, lastSeq, err = repo.PeriodSeq(ctx, a, recorded[0].CreatedAt.Add(-time.Hour), recorded[0].CreatedAt)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), afterSeq)
+		assert.Equal(t, int64(0), lastSeq)
+	})
+
+	t.Run("hash chain", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10})
+		require.NoError(t, deposit(repo, a, 5.5))
+		require.NoError(t, withdraw(repo, a, 0.25))
+		require.NoError(t, deposit(repo, a, 100))
+
+		history, err := repo.ListTransactions(ctx, a, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, history, 3)
+
+		prev := ""
+		for _, WT := range history {
+			assert.Equal(t, wallet.ChainHash(prev, WT), WT.Hash)
+			prev = WT.Hash
+		}
+
+		wlt, err := repo.GetWallet(ctx, a)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), wlt.LastSeq)
+		assert.Equal(t, prev, wlt.LastHash)
+	})
+
+	t.Run("checkpoints", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10, conformanceWalletB: 10})
+		b := uuidFromString(conformanceWalletB)
+		require.NoError(t, deposit(repo, a, 1))
+		require.NoError(t, deposit(repo, a, 1))
+
+		heads, err := repo.ChainHeads(ctx)
+		require.NoError(t, err)
+		require.Len(t, heads, 1, "wallets without transactions have nothing to anchor")
+		assert.Equal(t, a, heads[0].ValletId)
+		assert.Equal(t, int64(2), heads[0].Seq)
+
+		heads[0].Signature = "sig"
+		require.NoError(t, repo.SaveCheckpoints(ctx, heads))
+		// Повторное сохранение той же точки не создаёт дубликат.
+		require.NoError(t, repo.SaveCheckpoints(ctx, heads))
+
+		heads, err = repo.ChainHeads(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, heads)
+
+		require.NoError(t, deposit(repo, a, 1))
+		require.NoError(t, deposit(repo, b, 1))
+		heads, err = repo.ChainHeads(ctx)
+		require.NoError(t, err)
+		assert.Len(t, heads, 2)
+
+		checkpoints, err := repo.ListCheckpoints(ctx, a)
+		require.NoError(t, err)
+		require.Len(t, checkpoints, 1)
+		assert.Equal(t, int64(2), checkpoints[0].Seq)
+		assert.Equal(t, "sig", checkpoints[0].Signature)
+		assert.NotZero(t, checkpoints[0].Id)
+		assert.False(t, checkpoints[0].CreatedAt.IsZero())
+
+		history, err := repo.ListTransactions(ctx, a, 1, 1)
+		require.NoError(t, err)
+		assert.Equal(t, history[0].Hash, checkpoints[0].Hash)
+	})
+
+	t.Run("statements", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10, conformanceWalletB: 10})
+		b := uuidFromString(conformanceWalletB)
+		require.NoError(t, deposit(repo, a, 1))
+		require.NoError(t, deposit(repo, b, 1))
+
+		now := time.Now().UTC()
+		from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		to := from.AddDate(0, 1, 0)
+		start := uuidFromString("00000000-0000-0000-0000-000000000000")
+
+		pending, err := repo.PendingStatements(ctx, from, to, start, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{a, b}, pending)
+
+		pending, err = repo.PendingStatements(ctx, from, to, start, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{a}, pending)
+
+		pending, err = repo.PendingStatements(ctx, from.AddDate(0, -1, 0), from, start, 10)
+		require.NoError(t, err)
+		assert.Empty(t, pending, "no transactions in the previous month")
+
+		st := wallet.Statement{
+			ValletId:       a,
+			From:           from,
+			OpeningBalance: 10,
+			ClosingBalance: 11,
+			Deposits:       wallet.StatementTotal{Count: 1, Sum: 1},
+			Formats:        []string{"html", "csv"},
+		}
+		require.NoError(t, repo.SaveStatement(ctx, st))
+		// Повторное сохранение выписки за тот же период не является ошибкой.
+		require.NoError(t, repo.SaveStatement(ctx, st))
+
+		pending, err = repo.PendingStatements(ctx, from, to, start, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{b}, pending)
+
+		got, err := repo.GetStatement(ctx, a, from)
+		require.NoError(t, err)
+		assert.Equal(t, from.Format("2006-01"), got.Period)
+		assert.True(t, to.Equal(got.To))
+		assert.Equal(t, 11.0, got.ClosingBalance)
+		assert.Equal(t, int64(1), got.Deposits.Count)
+		assert.Equal(t, []string{"html", "csv"}, got.Formats)
+		assert.False(t, got.CreatedAt.IsZero())
+
+		_, err = repo.GetStatement(ctx, b, from)
+		assert.ErrorIs(t, err, ErrStatementNotFound)
+
+		statements, err := repo.ListStatements(ctx, a)
+		require.NoError(t, err)
+		assert.Len(t, statements, 1)
+
+		statements, err = repo.ListStatements(ctx, b)
+		require.NoError(t, err)
+		assert.Empty(t, statements)
+	})
+
+	t.Run("transaction stats", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10, conformanceWalletB: 10})
+		b := uuidFromString(conformanceWalletB)
+
+		var recorded []wallet.WalletTransactions
+		for _, WT := range []wallet.WalletTransactions{
+			{ValletId: a, OperationType: "DEPOSIT", Amount: 1.5},
+			{ValletId: a, OperationType: "WITHDRAW", Amount: 2},
+			{ValletId: b, OperationType: "DEPOSIT", Amount: 3},
+		} {
+			WT, err := applyOne(repo, WT)
+			require.NoError(t, err)
+			recorded = append(recorded, WT)
+			time.Sleep(2 * time.Millisecond)
+		}
+
+		day := wallet.StatsBucketStart(recorded[0].CreatedAt, wallet.StatsDay)
+		month := wallet.StatsBucketStart(day, wallet.StatsMonth)
+
+		buckets, err := repo.TransactionStats(ctx, a, day, day.AddDate(0, 0, 1), wallet.StatsDay)
+		require.NoError(t, err)
+		require.Len(t, buckets, 1)
+		assert.True(t, day.Equal(buckets[0].Start))
+		assert.Equal(t, wallet.StatementTotal{Count: 1, Sum: 1.5}, buckets[0].Deposits)
+		assert.Equal(t, wallet.StatementTotal{Count: 1, Sum: 2}, buckets[0].Withdrawals)
+
+		// По всем кошелькам, интервал - месяц.
+		buckets, err = repo.TransactionStats(ctx, uuid.UUID{}, month, month.AddDate(0, 1, 0), wallet.StatsMonth)
+		require.NoError(t, err)
+		require.Len(t, buckets, 1)
+		assert.True(t, month.Equal(buckets[0].Start))
+		assert.Equal(t, wallet.StatementTotal{Count: 2, Sum: 4.5}, buckets[0].Deposits)
+
+		// Границы не по началу дня: считается по самим транзакциям.
+		buckets, err = repo.TransactionStats(ctx, uuid.UUID{}, recorded[1].CreatedAt, recorded[2].CreatedAt, wallet.StatsDay)
+		require.NoError(t, err)
+		require.Len(t, buckets, 1)
+		assert.Equal(t, wallet.StatementTotal{}, buckets[0].Deposits)
+		assert.Equal(t, wallet.StatementTotal{Count: 1, Sum: 2}, buckets[0].Withdrawals)
+
+		buckets, err = repo.TransactionStats(ctx, a, day.AddDate(0, 0, -7), day, wallet.StatsDay)
+		require.NoError(t, err)
+		assert.Empty(t, buckets)
+	})
+
+	t.Run("scheduled transactions", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10, conformanceWalletB: 10})
+		b := uuidFromString(conformanceWalletB)
+		now := time.Now().UTC().Truncate(time.Second)
+
+		schedule := func(id uuid.UUID, executeAt time.Time) wallet.ScheduledTransaction {
+			st, err := repo.CreateScheduled(ctx, wallet.ScheduledTransaction{ValletId: id, OperationType: "WITHDRAW", Amount: 1.5, ExecuteAt: executeAt})
+			require.NoError(t, err)
+			return st
+		}
+		late := schedule(a, now.Add(-time.Minute))
+		early := schedule(a, now.Add(-time.Hour))
+		future := schedule(a, now.Add(time.Hour))
+		other := schedule(b, now.Add(-time.Second))
+
+		assert.NotZero(t, late.Id)
+		assert.Equal(t, wallet.ScheduledPending, late.Status)
+		assert.Equal(t, 1.5, late.Amount)
+		assert.True(t, now.Add(-time.Minute).Equal(late.ExecuteAt))
+		assert.Nil(t, late.FinishedAt)
+
+		claimed, err := repo.ClaimScheduled(ctx, now, time.Minute, 2)
+		require.NoError(t, err)
+		require.Len(t, claimed, 2)
+		assert.Equal(t, early.Id, claimed[0].Id)
+		assert.Equal(t, late.Id, claimed[1].Id)
+
+		// Закреплённые за обработчиком операции не берутся повторно и не отменяются, пока не истечёт аренда.
+		claimed, err = repo.ClaimScheduled(ctx, now, time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		assert.Equal(t, other.Id, claimed[0].Id)
+
+		_, err = repo.CancelScheduled(ctx, a, late.Id, now)
+		assert.ErrorIs(t, err, ErrScheduledNotPending)
+
+		claimed, err = repo.ClaimScheduled(ctx, now.Add(2*time.Minute), time.Minute, 10)
+		require.NoError(t, err)
+		assert.Len(t, claimed, 3, "expired leases are claimed again")
+
+		txID := 42
+		finishedAt := now.Add(2 * time.Minute)
+		require.NoError(t, repo.FinishScheduled(ctx, wallet.ScheduledTransaction{
+			Id: early.Id, Status: wallet.ScheduledSucceeded, TransactionId: &txID, FinishedAt: &finishedAt}))
+		require.NoError(t, repo.FinishScheduled(ctx, wallet.ScheduledTransaction{
+			Id: late.Id, Status: wallet.ScheduledFailed, Error: "insufficient funds", FinishedAt: &finishedAt}))
+		err = repo.FinishScheduled(ctx, wallet.ScheduledTransaction{Id: late.Id, Status: wallet.ScheduledSucceeded, FinishedAt: &finishedAt})
+		assert.ErrorIs(t, err, ErrScheduledNotPending)
+
+		cancelled, err := repo.CancelScheduled(ctx, a, future.Id, now)
+		require.NoError(t, err)
+		assert.Equal(t, wallet.ScheduledCancelled, cancelled.Status)
+		require.NotNil(t, cancelled.FinishedAt)
+
+		_, err = repo.CancelScheduled(ctx, a, future.Id, now)
+		assert.ErrorIs(t, err, ErrScheduledNotPending)
+		_, err = repo.CancelScheduled(ctx, b, future.Id, now)
+		assert.ErrorIs(t, err, ErrScheduledNotFound, "other wallet's operation")
+
+		list, err := repo.ListScheduled(ctx, a, "", 10)
+		require.NoError(t, err)
+		require.Len(t, list, 3)
+		assert.Equal(t, []int64{early.Id, late.Id, future.Id}, []int64{list[0].Id, list[1].Id, list[2].Id})
+		assert.Equal(t, wallet.ScheduledSucceeded, list[0].Status)
+		require.NotNil(t, list[0].TransactionId)
+		assert.Equal(t, 42, *list[0].TransactionId)
+		assert.Equal(t, "insufficient funds", list[1].Error)
+
+		list, err = repo.ListScheduled(ctx, a, wallet.ScheduledFailed, 10)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, late.Id, list[0].Id)
+	})
+
+	t.Run("recurring transfers", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10, conformanceWalletB: 10})
+		b := uuidFromString(conformanceWalletB)
+		now := time.Now().UTC().Truncate(time.Second)
+
+		create := func(next time.Time) wallet.RecurringTransfer {
+			r, err := repo.CreateRecurring(ctx, wallet.RecurringTransfer{FromWalletId: a, ToWalletId: b, Amount: 2.5,
+				Schedule: "0 9 1 * *", StartAt: now.Add(-time.Hour), CatchUp: wallet.CatchUpAll, MaxRetries: 2,
+				RetryIntervalSeconds: 60, Status: wallet.RecurringActive, OccurrenceAt: &next})
+			require.NoError(t, err)
+			return r
+		}
+		late := create(now.Add(-time.Minute))
+		early := create(now.Add(-time.Hour))
+		future := create(now.Add(time.Hour))
+
+		assert.NotZero(t, late.Id)
+		assert.Equal(t, wallet.RecurringActive, late.Status)
+		assert.Equal(t, 2.5, late.Amount)
+		require.NotNil(t, late.NextRunAt)
+		assert.True(t, now.Add(-time.Minute).Equal(*late.NextRunAt), "next run is the first occurrence")
+		assert.Zero(t, late.Attempt)
+
+		claimed, err := repo.ClaimRecurring(ctx, now, time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, claimed, 2)
+		assert.Equal(t, early.Id, claimed[0].Id)
+		assert.Equal(t, late.Id, claimed[1].Id)
+		require.NotNil(t, claimed[0].LockedUntil)
+
+		// Закреплённые правила не берутся повторно, пока не истечёт аренда.
+		again, err := repo.ClaimRecurring(ctx, now, time.Minute, 10)
+		require.NoError(t, err)
+		assert.Empty(t, again)
+
+		// Приостановка не ждёт обработчика; его результат не меняет статус приостановленного правила.
+		paused, err := repo.UpdateRecurringStatus(ctx, a, late.Id, []string{wallet.RecurringActive}, wallet.RecurringPaused, nil)
+		require.NoError(t, err)
+		assert.Equal(t, wallet.RecurringPaused, paused.Status)
+		assert.Nil(t, paused.NextRunAt)
+		_, err = repo.UpdateRecurringStatus(ctx, a, late.Id, []string{wallet.RecurringActive}, wallet.RecurringPaused, nil)
+		assert.ErrorIs(t, err, ErrRecurringStatus)
+		_, err = repo.UpdateRecurringStatus(ctx, b, late.Id, []string{wallet.RecurringActive}, wallet.RecurringPaused, nil)
+		assert.ErrorIs(t, err, ErrRecurringNotFound, "other wallet's rule")
+
+		txFrom, txTo := 41, 42
+		next := now.Add(24 * time.Hour)
+		r := claimed[0]
+		r.OccurrenceAt, r.NextRunAt = &next, &next
+		require.NoError(t, repo.FinishRecurring(ctx, r, &wallet.RecurringRun{OccurrenceAt: now.Add(-time.Hour), Attempt: 1,
+			Status: wallet.RecurringRunSucceeded, FromTransactionId: &txFrom, ToTransactionId: &txTo}))
+		assert.ErrorIs(t, repo.FinishRecurring(ctx, r, nil), ErrRecurringLeaseLost, "lease is released after finish")
+
+		retry := now.Add(time.Minute)
+		l := claimed[1]
+		l.NextRunAt, l.Attempt, l.Status = &retry, 1, wallet.RecurringFinished
+		require.NoError(t, repo.FinishRecurring(ctx, l, &wallet.RecurringRun{OccurrenceAt: now.Add(-time.Minute), Attempt: 1,
+			Status: wallet.RecurringRunRetrying, Error: "insufficient funds"}))
+
+		got, err := repo.GetRecurring(ctx, a, early.Id)
+		require.NoError(t, err)
+		require.NotNil(t, got.NextRunAt)
+		assert.True(t, next.Equal(*got.NextRunAt))
+		assert.Nil(t, got.LockedUntil)
+
+		got, err = repo.GetRecurring(ctx, a, late.Id)
+		require.NoError(t, err)
+		assert.Equal(t, wallet.RecurringPaused, got.Status)
+		assert.Equal(t, 1, got.Attempt)
+
+		resumed, err := repo.UpdateRecurringStatus(ctx, a, late.Id, []string{wallet.RecurringPaused}, wallet.RecurringActive, &next)
+		require.NoError(t, err)
+		assert.Equal(t, wallet.RecurringActive, resumed.Status)
+		assert.Zero(t, resumed.Attempt)
+		require.NotNil(t, resumed.OccurrenceAt)
+		assert.True(t, next.Equal(*resumed.OccurrenceAt))
+
+		runs, err := repo.ListRecurringRuns(ctx, a, early.Id, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, runs, 1)
+		assert.Equal(t, early.Id, runs[0].RuleId)
+		assert.Equal(t, wallet.RecurringRunSucceeded, runs[0].Status)
+		require.NotNil(t, runs[0].ToTransactionId)
+		assert.Equal(t, 42, *runs[0].ToTransactionId)
+		assert.True(t, now.Add(-time.Hour).Equal(runs[0].OccurrenceAt))
+
+		runs, err = repo.ListRecurringRuns(ctx, a, early.Id, runs[0].Id, 10)
+		require.NoError(t, err)
+		assert.Empty(t, runs)
+		_, err = repo.ListRecurringRuns(ctx, b, early.Id, 0, 10)
+		assert.ErrorIs(t, err, ErrRecurringNotFound)
+
+		rules, err := repo.ListRecurring(ctx, a, 10)
+		require.NoError(t, err)
+		require.Len(t, rules, 3)
+		assert.Equal(t, []int64{late.Id, early.Id, future.Id}, []int64{rules[0].Id, rules[1].Id, rules[2].Id})
+		rules, err = repo.ListRecurring(ctx, b, 10)
+		require.NoError(t, err)
+		assert.Empty(t, rules)
+	})
+
+	t.Run("webhook deliveries", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10, conformanceWalletB: 10})
+		b := uuidFromString(conformanceWalletB)
+		now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		global, err := repo.CreateSubscription(ctx, wallet.WebhookSubscription{
+			URL: "http://global", EventTypes: []string{}, Secret: "s1"})
+		require.NoError(t, err)
+		assert.NotZero(t, global.Id)
+		onlyA, err := repo.CreateSubscription(ctx, wallet.WebhookSubscription{
+			ValletId: &a, URL: "http://a", EventTypes: []string{wallet.EventRejected}, Secret: "s2"})
+		require.NoError(t, err)
+
+		subs, err := repo.ListSubscriptions(ctx)
+		require.NoError(t, err)
+		require.Len(t, subs, 2)
+		assert.Nil(t, subs[0].ValletId)
+		require.NotNil(t, subs[1].ValletId)
+		assert.Equal(t, a.UUID, subs[1].ValletId.UUID)
+		assert.Equal(t, []string{wallet.EventRejected}, subs[1].EventTypes)
+
+		enqueue := func(id, eventType string, walletID uuid.UUID) int {
+			n, err := repo.EnqueueEvent(ctx, wallet.WalletEvent{Id: id, Type: eventType, ValletId: walletID},
+				[]byte(`{"id":"`+id+`"}`), now)
+			require.NoError(t, err)
+			return n
+		}
+		assert.Equal(t, 2, enqueue("e1", wallet.EventRejected, a))
+		assert.Equal(t, 1, enqueue("e2", wallet.EventDeposited, a))
+		assert.Equal(t, 1, enqueue("e3", wallet.EventRejected, b))
+		assert.Equal(t, 0, enqueue("e1", wallet.EventRejected, a), "repeated event must not duplicate deliveries")
+
+		claimed, err := repo.ClaimDeliveries(ctx, now, time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, claimed, 4)
+		for _, d := range claimed {
+			assert.JSONEq(t, `{"id":"`+d.EventId+`"}`, string(d.Payload))
+			if d.SubscriptionId == onlyA.Id {
+				assert.Equal(t, "http://a", d.URL)
+				assert.Equal(t, "s2", d.Secret)
+			}
+		}
+
+		// Взятые доставки отложены на lease и не выдаются повторно.
+		again, err := repo.ClaimDeliveries(ctx, now.Add(30*time.Second), time.Minute, 10)
+		require.NoError(t, err)
+		assert.Empty(t, again)
+
+		require.NoError(t, repo.CompleteDelivery(ctx, claimed[0].Id, 1, now))
+		require.NoError(t, repo.FailDelivery(ctx, claimed[1].Id, 1, "boom", now.Add(time.Second), false))
+		require.NoError(t, repo.FailDelivery(ctx, claimed[2].Id, 8, "gone", now, true))
+
+		// После lease возвращается только доставка, которую не подтвердили и не отклонили.
+		again, err = repo.ClaimDeliveries(ctx, now.Add(2*time.Minute), time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, again, 2)
+		assert.Equal(t, claimed[1].Id, again[0].Id)
+		assert.Equal(t, 1, again[0].Attempts)
+		assert.Equal(t, "boom", again[0].LastError)
+		assert.Equal(t, claimed[3].Id, again[1].Id)
+
+		dead, err := repo.ListDeadDeliveries(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, dead, 1)
+		assert.Equal(t, claimed[2].Id, dead[0].Id)
+		assert.Equal(t, wallet.DeliveryDead, dead[0].Status)
+		assert.Equal(t, "gone", dead[0].LastError)
+
+		require.NoError(t, repo.ReplayDelivery(ctx, dead[0].Id, now.Add(150*time.Second)))
+		assert.ErrorIs(t, repo.ReplayDelivery(ctx, dead[0].Id, now), ErrDeliveryNotFound)
+		assert.ErrorIs(t, repo.ReplayDelivery(ctx, claimed[0].Id, now), ErrDeliveryNotFound)
+
+		replayed, err := repo.ClaimDeliveries(ctx, now.Add(150*time.Second), time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, replayed, 1)
+		assert.Equal(t, dead[0].Id, replayed[0].Id)
+		assert.Equal(t, 0, replayed[0].Attempts)
+
+		require.NoError(t, repo.DeleteSubscription(ctx, global.Id))
+		assert.ErrorIs(t, repo.DeleteSubscription(ctx, global.Id), ErrSubscriptionNotFound)
+	})
+
+	t.Run("idempotency keys", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10, conformanceWalletB: 10})
+		b := uuidFromString(conformanceWalletB)
+
+		first, err := applyOne(repo, wallet.WalletTransactions{ValletId: a, OperationType: "DEPOSIT", Amount: 5, IdempotencyKey: "k1"})
+		require.NoError(t, err)
+
+		// Повтор возвращает исходную транзакцию и не меняет баланс, в том числе внутри одной пачки.
+		recorded, results, err := repo.ApplyTransactions(ctx, a, []wallet.WalletTransactions{
+			{ValletId: a, OperationType: "DEPOSIT", Amount: 5, IdempotencyKey: "k1"},
+			{ValletId: a, OperationType: "WITHDRAW", Amount: 1, IdempotencyKey: "k2"},
+			{ValletId: a, OperationType: "WITHDRAW", Amount: 1, IdempotencyKey: "k2"},
+		})
+		require.NoError(t, err)
+		assert.ErrorIs(t, results[0], ErrDuplicateTransaction)
+		assert.Equal(t, first.Id, recorded[0].Id)
+		assert.Equal(t, first.Seq, recorded[0].Seq)
+		assert.NoError(t, results[1])
+		assert.ErrorIs(t, results[2], ErrDuplicateTransaction)
+		assert.Equal(t, recorded[1].Id, recorded[2].Id)
+		assert.NotZero(t, recorded[2].Id)
+
+		// Ключи действуют в пределах кошелька.
+		require.NoError(t, deposit(repo, b, 1))
+		_, err = applyOne(repo, wallet.WalletTransactions{ValletId: b, OperationType: "DEPOSIT", Amount: 5, IdempotencyKey: "k1"})
+		require.NoError(t, err)
+
+		wlt, err := repo.GetWallet(ctx, a)
+		require.NoError(t, err)
+		assert.Equal(t, 14.0, wlt.Balance)
+		assert.Equal(t, int64(2), wlt.LastSeq)
+
+		balance, err := repo.GetBalance(ctx, b)
+		require.NoError(t, err)
+		assert.Equal(t, 16.0, balance)
+	})
+
+	t.Run("find idempotent", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10, conformanceWalletB: 10})
+		b := uuidFromString(conformanceWalletB)
+
+		recorded, err := applyOne(repo, wallet.WalletTransactions{ValletId: a, OperationType: "DEPOSIT", Amount: 5, IdempotencyKey: "k1"})
+		require.NoError(t, err)
+
+		found, err := repo.FindIdempotent(ctx, []wallet.WalletTransactions{
+			{ValletId: a, IdempotencyKey: "k1"},
+			{ValletId: a, IdempotencyKey: "k2"},
+			{ValletId: b, IdempotencyKey: "k1"},
+			{ValletId: uuidFromString(conformanceMissing), IdempotencyKey: "k1"},
+		})
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		require.Len(t, found[conformanceWalletA], 1)
+		assert.Equal(t, recorded.Id, found[conformanceWalletA]["k1"].Id)
+		assert.Equal(t, recorded.Seq, found[conformanceWalletA]["k1"].Seq)
+	})
+
+	t.Run("wallet tier", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10})
+
+		wlt, err := repo.GetWallet(ctx, a)
+		require.NoError(t, err)
+		assert.Equal(t, wallet.DefaultTier, wlt.Tier)
+
+		wlt, err = repo.SetTier(ctx, a, "premium")
+		require.NoError(t, err)
+		assert.Equal(t, "premium", wlt.Tier)
+		assert.Equal(t, 10.0, wlt.Balance)
+		assert.Equal(t, int64(1), wlt.Version)
+
+		wlt, err = repo.GetWallet(ctx, a)
+		require.NoError(t, err)
+		assert.Equal(t, "premium", wlt.Tier)
+
+		_, err = repo.SetTier(ctx, missing, "premium")
+		assert.ErrorIs(t, err, ErrWalletNotFound)
+
+		tiers, err := repo.WalletTiers(ctx, []uuid.UUID{a, missing})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{a.UUID.String(): "premium"}, tiers)
+	})
+
+	t.Run("credit limit", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10})
+
+		assert.ErrorIs(t, withdraw(repo, a, 20), ErrInsufficientFunds)
+
+		wlt, err := repo.SetCreditLimit(ctx, a, 50)
+		require.NoError(t, err)
+		assert.Equal(t, 50.0, wlt.CreditLimit)
+		assert.Equal(t, 60.0, wlt.Available)
+		assert.Equal(t, int64(2), wlt.Version, "credit limit changes available, so the version moves")
+
+		require.NoError(t, withdraw(repo, a, 40))
+		assert.ErrorIs(t, withdraw(repo, a, 20.01), ErrInsufficientFunds)
+		require.NoError(t, withdraw(repo, a, 20))
+
+		wlt, err = repo.GetWallet(ctx, a)
+		require.NoError(t, err)
+		assert.Equal(t, -50.0, wlt.Balance)
+		assert.Equal(t, 0.0, wlt.Available)
+
+		// Лимит нельзя опустить ниже текущей задолженности.
+		_, err = repo.SetCreditLimit(ctx, a, 49.99)
+		assert.ErrorIs(t, err, ErrCreditLimitBelowDebt)
+		_, err = repo.SetCreditLimit(ctx, missing, 10)
+		assert.ErrorIs(t, err, ErrWalletNotFound)
+
+		require.NoError(t, deposit(repo, a, 60))
+		wlt, err = repo.SetCreditLimit(ctx, a, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 10.0, wlt.Available)
+
+		var types []string
+		_, err = repo.RelayOutbox(ctx, 10, func(ctx context.Context, events []wallet.OutboxEvent) []int64 {
+			ids := make([]int64, 0, len(events))
+			for _, e := range events {
+				types = append(types, e.Event.Type)
+				ids = append(ids, e.Id)
+			}
+			return ids
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{wallet.EventRejected, wallet.EventWithdrawn, wallet.EventOverdraftEntered,
+			wallet.EventRejected, wallet.EventWithdrawn, wallet.EventDeposited, wallet.EventOverdraftExited}, types)
+	})
+
+	t.Run("buckets", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 100})
+
+		for _, WT := range []wallet.WalletTransactions{
+			{ValletId: a, OperationType: "DEPOSIT", Amount: 30, Bucket: wallet.BucketBonus},
+			{ValletId: a, OperationType: "DEPOSIT", Amount: 10, Bucket: wallet.BucketCashback},
+			{ValletId: a, OperationType: "DEPOSIT", Amount: 20, Bucket: wallet.BucketPromo},
+		} {
+			_, err := applyOne(repo, WT)
+			require.NoError(t, err)
+		}
+
+		balance, err := repo.GetBalance(ctx, a)
+		require.NoError(t, err)
+		assert.Equal(t, 160.0, balance, "the total balance includes all buckets")
+		wlt, err := repo.GetWallet(ctx, a)
+		require.NoError(t, err)
+		assert.Equal(t, 140.0, wlt.Available, "promo is withdrawn only explicitly")
+
+		// Без корзины списание идёт по wallet.WithdrawOrder, promo не трогается.
+		recorded, err := applyOne(repo, wallet.WalletTransactions{ValletId: a, OperationType: "WITHDRAW", Amount: 115})
+		require.NoError(t, err)
+		assert.Equal(t, 45.0, recorded.BalanceAfter)
+		assert.Equal(t, []wallet.BucketEntry{
+			{Bucket: wallet.BucketMain, Amount: 100, BalanceAfter: 0},
+			{Bucket: wallet.BucketBonus, Amount: 5, BalanceAfter: 25},
+			{Bucket: wallet.BucketCashback, Amount: 10, BalanceAfter: 0},
+		}, recorded.Buckets)
+
+		_, err = applyOne(repo, wallet.WalletTransactions{ValletId: a, OperationType: "WITHDRAW", Amount: 21, Bucket: wallet.BucketPromo})
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+		_, err = applyOne(repo, wallet.WalletTransactions{ValletId: a, OperationType: "WITHDRAW", Amount: 1, Bucket: "vip"})
+		assert.ErrorIs(t, err, ErrInvalidBucket)
+		recorded, err = applyOne(repo, wallet.WalletTransactions{ValletId: a, OperationType: "WITHDRAW", Amount: 5, Bucket: wallet.BucketPromo})
+		require.NoError(t, err)
+		assert.Equal(t, []wallet.BucketEntry{{Bucket: wallet.BucketPromo, Amount: 5, BalanceAfter: 15}}, recorded.Buckets)
+
+		// В долг уходит только main.
+		_, err = repo.SetCreditLimit(ctx, a, 50)
+		require.NoError(t, err)
+		_, err = applyOne(repo, wallet.WalletTransactions{ValletId: a, OperationType: "WITHDRAW", Amount: 26, Bucket: wallet.BucketBonus})
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+		recorded, err = applyOne(repo, wallet.WalletTransactions{ValletId: a, OperationType: "WITHDRAW", Amount: 45})
+		require.NoError(t, err)
+		assert.Equal(t, []wallet.BucketEntry{
+			{Bucket: wallet.BucketMain, Amount: 20, BalanceAfter: -20},
+			{Bucket: wallet.BucketBonus, Amount: 25, BalanceAfter: 0},
+		}, recorded.Buckets)
+
+		wlt, err = repo.GetWallet(ctx, a)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]float64{wallet.BucketMain: -20, wallet.BucketBonus: 0, wallet.BucketCashback: 0, wallet.BucketPromo: 15},
+			wlt.Buckets)
+		assert.Equal(t, -5.0, wlt.Balance)
+		assert.Equal(t, 30.0, wlt.Available)
+		_, err = repo.SetCreditLimit(ctx, a, 19.99)
+		assert.ErrorIs(t, err, ErrCreditLimitBelowDebt, "the limit covers the main debt, not the total balance")
+
+		history, err := repo.ListTransactions(ctx, a, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, history, 6)
+		assert.Equal(t, recorded.Buckets, history[5].Buckets)
+
+		bonus, err := repo.ListBucketTransactions(ctx, a, wallet.BucketBonus, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, bonus, 3)
+		assert.Equal(t, []int64{1, 4, 6}, []int64{bonus[0].Seq, bonus[1].Seq, bonus[2].Seq})
+		page, err := repo.ListBucketTransactions(ctx, a, wallet.BucketBonus, 1, 1)
+		require.NoError(t, err)
+		require.Len(t, page, 1)
+		assert.Equal(t, int64(4), page[0].Seq)
+		promo, err := repo.ListBucketTransactions(ctx, a, wallet.BucketPromo, 0, 10)
+		require.NoError(t, err)
+		assert.Len(t, promo, 2)
+
+		_, err = repo.ListBucketTransactions(ctx, a, "vip", 0, 10)
+		assert.ErrorIs(t, err, ErrInvalidBucket)
+		_, err = repo.ListBucketTransactions(ctx, missing, wallet.BucketMain, 0, 10)
+		assert.ErrorIs(t, err, ErrWalletNotFound)
+
+		var types []string
+		_, err = repo.RelayOutbox(ctx, 20, func(ctx context.Context, events []wallet.OutboxEvent) []int64 {
+			ids := make([]int64, 0, len(events))
+			for _, e := range events {
+				types = append(types, e.Event.Type)
+				ids = append(ids, e.Id)
+			}
+			return ids
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{wallet.EventDeposited, wallet.EventDeposited, wallet.EventDeposited, wallet.EventWithdrawn,
+			wallet.EventRejected, wallet.EventWithdrawn, wallet.EventRejected, wallet.EventWithdrawn, wallet.EventOverdraftEntered}, types)
+	})
+
+	t.Run("fee kind and operation id", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10, conformanceWalletB: 0})
+		b := uuidFromString(conformanceWalletB)
+		op := uuidFromString("33333333-3333-3333-3333-333333333333")
+
+		_, results, err := repo.ApplyAtomic(ctx, []wallet.WalletTransactions{
+			{ValletId: a, OperationType: "WITHDRAW", Amount: 5, IdempotencyKey: "w1", OperationId: &op},
+			{ValletId: a, OperationType: "WITHDRAW", Amount: 1, IdempotencyKey: "fee:w1", Kind: wallet.KindFee, OperationId: &op},
+			{ValletId: b, OperationType: "DEPOSIT", Amount: 1, Kind: wallet.KindFee, OperationId: &op},
+		})
+		require.NoError(t, err)
+		for _, res := range results {
+			require.NoError(t, res)
+		}
+		require.NoError(t, deposit(repo, a, 1))
+
+		history, err := repo.ListTransactions(ctx, a, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, history, 3)
+		assert.Empty(t, history[0].Kind)
+		require.NotNil(t, history[0].OperationId)
+		assert.Equal(t, op.UUID, history[0].OperationId.UUID)
+		assert.Equal(t, wallet.KindFee, history[1].Kind)
+		require.NotNil(t, history[1].OperationId)
+		assert.Equal(t, op.UUID, history[1].OperationId.UUID)
+		assert.Empty(t, history[2].Kind)
+		assert.Nil(t, history[2].OperationId)
+
+		found, err := repo.FindIdempotent(ctx, []wallet.WalletTransactions{{ValletId: a, IdempotencyKey: "fee:w1"}})
+		require.NoError(t, err)
+		assert.Equal(t, wallet.KindFee, found[conformanceWalletA]["fee:w1"].Kind)
+
+		credited, err := repo.ListTransactions(ctx, b, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, credited, 1)
+		assert.Equal(t, wallet.KindFee, credited[0].Kind)
+		require.NotNil(t, credited[0].OperationId)
+		assert.Equal(t, op.UUID, credited[0].OperationId.UUID)
+	})
+
+	t.Run("interest", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 1000, conformanceWalletB: 1000})
+		b := uuidFromString(conformanceWalletB)
+		today := time.Now().UTC().Truncate(24 * time.Hour)
+		yesterday := today.AddDate(0, 0, -1)
+		rates := map[string]float64{"savings": 0.0001}
+
+		last, _, err := repo.LastInterestDay(ctx)
+		require.NoError(t, err)
+		assert.True(t, last.IsZero())
+
+		wlt, err := repo.SetProduct(ctx, a, "savings")
+		require.NoError(t, err)
+		assert.Equal(t, "savings", wlt.Product)
+		_, err = repo.SetProduct(ctx, missing, "savings")
+		assert.ErrorIs(t, err, ErrWalletNotFound)
+
+		// Операция после конца дня не влияет на начисление за этот день.
+		require.NoError(t, deposit(repo, a, 500))
+
+		n, err := repo.AccrueInterest(ctx, yesterday.AddDate(0, 0, -1), rates)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		n, err = repo.AccrueInterest(ctx, yesterday, rates)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		n, err = repo.AccrueInterest(ctx, yesterday, rates)
+		require.NoError(t, err)
+		assert.Zero(t, n, "day is accrued only once")
+
+		last, capitalised, err := repo.LastInterestDay(ctx)
+		require.NoError(t, err)
+		assert.True(t, last.Equal(yesterday))
+		assert.False(t, capitalised)
+
+		accruals, err := repo.ListInterestAccruals(ctx, a, yesterday.AddDate(0, 0, -1), today)
+		require.NoError(t, err)
+		require.Len(t, accruals, 2)
+		assert.True(t, accruals[1].Day.Equal(yesterday))
+		assert.Equal(t, "savings", accruals[1].Product)
+		assert.Equal(t, 1000.0, accruals[1].Balance)
+		assert.InDelta(t, 0.1, accruals[1].Amount, 1e-9)
+
+		wlt, err = repo.GetWallet(ctx, a)
+		require.NoError(t, err)
+		assert.InDelta(t, 0.2, wlt.AccruedInterest, 1e-9)
+		accruals, err = repo.ListInterestAccruals(ctx, b, time.Time{}, today)
+		require.NoError(t, err)
+		assert.Empty(t, accruals)
+
+		first := uuidFromString("00000000-0000-0000-0000-000000000000")
+		pending, err := repo.PendingInterest(ctx, first, 10)
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+		assert.Equal(t, a.UUID, pending[0].UUID)
+
+		recorded, err := repo.CapitaliseInterest(ctx, a, "interest:test")
+		require.NoError(t, err)
+		assert.Equal(t, 0.2, recorded.Amount)
+		assert.Equal(t, "DEPOSIT", recorded.OperationType)
+		assert.Equal(t, wallet.KindInterest, recorded.Kind)
+		assert.Equal(t, 1500.2, recorded.BalanceAfter)
+
+		replay, err := repo.CapitaliseInterest(ctx, a, "interest:test")
+		assert.ErrorIs(t, err, ErrDuplicateTransaction)
+		assert.Equal(t, recorded.Id, replay.Id)
+
+		none, err := repo.CapitaliseInterest(ctx, b, "interest:test")
+		require.NoError(t, err)
+		assert.Zero(t, none.Id)
+
+		wlt, err = repo.GetWallet(ctx, a)
+		require.NoError(t, err)
+		assert.Equal(t, 1500.2, wlt.Balance)
+		assert.InDelta(t, 0, wlt.AccruedInterest, 1e-9)
+		pending, err = repo.PendingInterest(ctx, first, 10)
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+
+		require.NoError(t, repo.MarkCapitalised(ctx, yesterday))
+		_, capitalised, err = repo.LastInterestDay(ctx)
+		require.NoError(t, err)
+		assert.True(t, capitalised)
+	})
+
+	t.Run("apply atomic", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10, conformanceWalletB: 0})
+		b := uuidFromString(conformanceWalletB)
+
+		recorded, results, err := repo.ApplyAtomic(ctx, []wallet.WalletTransactions{
+			{ValletId: b, OperationType: "DEPOSIT", Amount: 3, IdempotencyKey: "pay-b"},
+			{ValletId: a, OperationType: "WITHDRAW", Amount: 3},
+			{ValletId: b, OperationType: "DEPOSIT", Amount: 2},
+		})
+		require.NoError(t, err)
+		for _, res := range results {
+			assert.NoError(t, res)
+		}
+		assert.Equal(t, int64(1), recorded[0].Seq)
+		assert.Equal(t, 3.0, recorded[0].BalanceAfter)
+		assert.NotZero(t, recorded[0].Id)
+		assert.Equal(t, 7.0, recorded[1].BalanceAfter)
+		assert.Equal(t, int64(2), recorded[2].Seq)
+		assert.Equal(t, 5.0, recorded[2].BalanceAfter)
+
+		// Один отказ - и не применяется ничего, а повтор по ключу отказом не считается.
+		_, results, err = repo.ApplyAtomic(ctx, []wallet.WalletTransactions{
+			{ValletId: b, OperationType: "DEPOSIT", Amount: 3, IdempotencyKey: "pay-b"},
+			{ValletId: b, OperationType: "DEPOSIT", Amount: 1},
+			{ValletId: a, OperationType: "WITHDRAW", Amount: 100},
+			{ValletId: missing, OperationType: "DEPOSIT", Amount: 1},
+		})
+		require.NoError(t, err)
+		assert.ErrorIs(t, results[0], ErrDuplicateTransaction)
+		assert.NoError(t, results[1])
+		assert.ErrorIs(t, results[2], ErrInsufficientFunds)
+		assert.ErrorIs(t, results[3], ErrWalletNotFound)
+
+		for id, expected := range map[uuid.UUID]float64{a: 7, b: 5} {
+			balance, err := repo.GetBalance(ctx, id)
+			require.NoError(t, err)
+			assert.Equal(t, expected, balance)
+		}
+		history, err := repo.ListTransactions(ctx, b, 0, 10)
+		require.NoError(t, err)
+		assert.Len(t, history, 2)
+	})
+
+	t.Run("transfer", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10, conformanceWalletB: 5})
+		b := uuidFromString(conformanceWalletB)
+
+		transfer, err := repo.Transfer(ctx, a, b, 7.5)
+		require.NoError(t, err)
+		assert.Equal(t, "WITHDRAW", transfer.From.OperationType)
+		assert.Equal(t, a.UUID, transfer.From.ValletId.UUID)
+		assert.Equal(t, 2.5, transfer.From.BalanceAfter)
+		assert.Equal(t, int64(1), transfer.From.Seq)
+		assert.NotZero(t, transfer.From.Id)
+		assert.Equal(t, "DEPOSIT", transfer.To.OperationType)
+		assert.Equal(t, b.UUID, transfer.To.ValletId.UUID)
+		assert.Equal(t, 12.5, transfer.To.BalanceAfter)
+		assert.Equal(t, int64(1), transfer.To.Seq)
+
+		// Встречный перевод блокирует кошельки в том же порядке.
+		_, err = repo.Transfer(ctx, b, a, 2.5)
+		require.NoError(t, err)
+
+		_, err = repo.Transfer(ctx, a, b, 100)
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+		_, err = repo.Transfer(ctx, a, missing, 1)
+		assert.ErrorIs(t, err, ErrWalletNotFound)
+		_, err = repo.Transfer(ctx, missing, a, 1)
+		assert.ErrorIs(t, err, ErrWalletNotFound)
+
+		for id, expected := range map[uuid.UUID]float64{a: 5, b: 10} {
+			wlt, err := repo.GetWallet(ctx, id)
+			require.NoError(t, err)
+			assert.Equal(t, expected, wlt.Balance)
+			assert.Equal(t, int64(2), wlt.LastSeq)
+		}
+
+		var types []string
+		_, err = repo.RelayOutbox(ctx, 10, func(ctx context.Context, events []wallet.OutboxEvent) []int64 {
+			for _, e := range events {
+				types = append(types, e.Event.Type)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{wallet.EventWithdrawn, wallet.EventDeposited, wallet.EventWithdrawn, wallet.EventDeposited, wallet.EventRejected}, types)
+	})
+
+	t.Run("concurrent transfers", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 100, conformanceWalletB: 100})
+		b := uuidFromString(conformanceWalletB)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			from, to := a, b
+			if i%2 == 1 {
+				from, to = b, a
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := repo.Transfer(ctx, from, to, 10)
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		balanceA, err := repo.GetBalance(ctx, a)
+		require.NoError(t, err)
+		balanceB, err := repo.GetBalance(ctx, b)
+		require.NoError(t, err)
+		assert.Equal(t, 100.0, balanceA)
+		assert.Equal(t, 100.0, balanceB)
+	})
+
+	t.Run("outbox", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10, conformanceWalletB: 10})
+		b := uuidFromString(conformanceWalletB)
+
+		require.NoError(t, deposit(repo, a, 5))
+		assert.ErrorIs(t, withdraw(repo, a, 100), ErrInsufficientFunds)
+		require.NoError(t, withdraw(repo, b, 1))
+		require.NoError(t, withdraw(repo, a, 15))
+		assert.Error(t, deposit(repo, missing, 1))
+
+		var seen []wallet.OutboxEvent
+		n, err := repo.RelayOutbox(ctx, 10, func(ctx context.Context, events []wallet.OutboxEvent) []int64 {
+			seen = events
+
+			// Пока outbox разбирается, второй relay ничего не получает.
+			n, err := repo.RelayOutbox(ctx, 10, func(context.Context, []wallet.OutboxEvent) []int64 {
+				t.Error("concurrent relay must not get events")
+				return nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, 0, n)
+
+			// Подтверждаются только события кошелька B.
+			var ids []int64
+			for _, e := range events {
+				if e.Event.ValletId.UUID == b.UUID {
+					ids = append(ids, e.Id)
+				}
+			}
+			return ids
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		require.Len(t, seen, 4)
+		types := make([]string, 0, len(seen))
+		for i, e := range seen {
+			if i > 0 {
+				assert.Greater(t, e.Id, seen[i-1].Id)
+			}
+			assert.NotEmpty(t, e.Event.Id)
+			types = append(types, e.Event.Type)
+		}
+		assert.Equal(t, []string{wallet.EventDeposited, wallet.EventRejected, wallet.EventWithdrawn, wallet.EventWithdrawn}, types)
+
+		deposited := seen[0].Event
+		assert.Equal(t, a.UUID, deposited.ValletId.UUID)
+		assert.Equal(t, int64(1), deposited.Transaction.Seq)
+		assert.Equal(t, 15.0, deposited.Transaction.BalanceAfter)
+		assert.NotZero(t, deposited.Transaction.Id)
+		assert.NotEmpty(t, deposited.Transaction.Hash)
+
+		rejected := seen[1].Event
+		assert.Equal(t, 100.0, rejected.Transaction.Amount)
+		assert.Equal(t, int64(0), rejected.Transaction.Seq)
+		assert.Contains(t, rejected.Reason, "insufficient funds")
+
+		// Неподтверждённые события возвращаются в следующем проходе в том же порядке.
+		var again []wallet.OutboxEvent
+		n, err = repo.RelayOutbox(ctx, 2, func(ctx context.Context, events []wallet.OutboxEvent) []int64 {
+			again = events
+			return []int64{events[0].Id, events[1].Id}
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+		require.Len(t, again, 2)
+		assert.Equal(t, seen[0].Id, again[0].Id)
+		assert.Equal(t, seen[1].Id, again[1].Id)
+
+		purged, err := repo.PurgeOutbox(ctx, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), purged)
+
+		n, err = repo.RelayOutbox(ctx, 10, func(ctx context.Context, events []wallet.OutboxEvent) []int64 {
+			require.Len(t, events, 1)
+			assert.Equal(t, seen[3].Id, events[0].Id)
+			return []int64{events[0].Id}
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		n, err = repo.RelayOutbox(ctx, 10, func(ctx context.Context, events []wallet.OutboxEvent) []int64 {
+			t.Error("outbox must be empty")
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 0, n)
+	})
+
+	t.Run("change notifications", func(t *testing.T) {
+		repo := newWallet(t, map[string]float64{conformanceWalletA: 10})
+
+		type change struct {
+			walletID string
+			seq      int64
+		}
+		changes := make(chan change, 100)
+
+		listenCtx, stop := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func() {
+			done <- repo.ListenChanges(listenCtx, func(walletID uuid.UUID, seq int64) {
+				if walletID.Status == pgtype.Present {
+					changes <- change{walletID.UUID.String(), seq}
+				}
+			})
+		}()
+
+		// Подписка устанавливается асинхронно, поэтому операции повторяются, пока не придёт первое уведомление.
+		var first change
+		require.Eventually(t, func() bool {
+			assert.NoError(t, deposit(repo, a, 1))
+			select {
+			case first = <-changes:
+				return true
+			case <-time.After(50 * time.Millisecond):
+				return false
+			}
+		}, 5*time.Second, time.Millisecond)
+		assert.Equal(t, conformanceWalletA, first.walletID)
+
+		wlt, err := repo.GetWallet(ctx, a)
+		require.NoError(t, err)
+
+		// Отклонённая операция не меняет кошелёк и уведомления не порождает.
+		assert.ErrorIs(t, withdraw(repo, a, 1000), ErrInsufficientFunds)
+		require.NoError(t, withdraw(repo, a, 1))
+
+		last := first
+		for last.seq < wlt.LastSeq+1 {
+			select {
+			case last = <-changes:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("no notification for seq %d", wlt.LastSeq+1)
+			}
+		}
+		assert.Equal(t, wlt.LastSeq+1, last.seq)
+
+		stop()
+		assert.ErrorIs(t, <-done, context.Canceled)
+	})
+}
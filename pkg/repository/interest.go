@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// interestDay передаёт день в колонку DATE без зависимости от часового пояса соединения.
+func interestDay(day time.Time) string {
+	return day.UTC().Format("2006-01-02")
+}
+
+func (w *WalletPsql) SetProduct(ctx context.Context, uid uuid.UUID, product string) (wallet.Wallet, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	query := fmt.Sprintf(`UPDATE %s SET product = NULLIF($2, '') WHERE valletId = $1 RETURNING %s`, walletTable, walletColumns)
+	var wlt wallet.Wallet
+	err := w.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		wlt, err = scanWallet(tx.QueryRowContext(ctx, query, uid, product))
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return wlt, fmt.Errorf("failed to set product for wallet %s: %w", uid.UUID.String(), ErrWalletNotFound)
+	}
+	if err != nil {
+		return wlt, fmt.Errorf("failed to set product for wallet %s: %w", uid.UUID.String(), err)
+	}
+	return wlt, nil
+}
+
+func (w *WalletPsql) LastInterestDay(ctx context.Context) (time.Time, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var (
+		day         time.Time
+		capitalised bool
+	)
+	err := w.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT day, capitalised_at IS NOT NULL FROM %s ORDER BY day DESC LIMIT 1`,
+		interestDayTable)).Scan(&day, &capitalised)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to get last interest day: %w", err)
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC), capitalised, nil
+}
+
+func (w *WalletPsql) AccrueInterest(ctx context.Context, day time.Time, rates map[string]float64) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	products := make([]string, 0, len(rates))
+	dailyRates := make([]float64, 0, len(rates))
+	for product, rate := range rates {
+		products = append(products, product)
+		dailyRates = append(dailyRates, rate)
+	}
+
+	var accrued int
+	err := w.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		accrued = 0
+		res, err := tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (day) VALUES ($1::date) ON CONFLICT DO NOTHING`,
+			interestDayTable), interestDay(day))
+		if err != nil {
+			return fmt.Errorf("failed to accrue interest for %s: %w", interestDay(day), err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to accrue interest for %s: %w", interestDay(day), err)
+		}
+		if n == 0 || len(products) == 0 { // день уже начислен или начислять некому
+			return nil
+		}
+
+		// Остаток на конец дня - текущий баланс без операций, записанных после конца дня.
+		res, err = tx.ExecContext(ctx, fmt.Sprintf(`WITH rates AS (
+				SELECT * FROM unnest($2::text[], $3::float8[]) AS r(product, daily_rate)
+			), closing AS (
+				SELECT w.valletId, w.product, r.daily_rate::numeric AS daily_rate,
+					w.balance - COALESCE((SELECT SUM(CASE WHEN t.operation_type = 'DEPOSIT' THEN t.amount ELSE -t.amount END)
+						FROM %s t WHERE t.valletId = w.valletId AND t.created_at >= $4::timestamp), 0) AS balance
+				FROM %s w JOIN rates r ON r.product = w.product
+			), accrued AS (
+				INSERT INTO %s (valletId, day, product, balance, daily_rate, amount)
+				SELECT valletId, $1::date, product, balance, daily_rate, ROUND(balance * daily_rate, 8)
+				FROM closing WHERE balance > 0
+				ON CONFLICT DO NOTHING
+				RETURNING valletId, amount
+			)
+			UPDATE %s w SET interest_pending = w.interest_pending + a.amount FROM accrued a WHERE w.valletId = a.valletId`,
+			walletTRXTable, walletTable, accrualTable, walletTable),
+			interestDay(day), pq.Array(products), pq.Array(dailyRates), day.UTC().AddDate(0, 0, 1))
+		if err != nil {
+			return fmt.Errorf("failed to accrue interest for %s: %w", interestDay(day), err)
+		}
+		n, err = res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to accrue interest for %s: %w", interestDay(day), err)
+		}
+		accrued = int(n)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return accrued, nil
+}
+
+func (w *WalletPsql) PendingInterest(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var ids []uuid.UUID
+	err := w.db.SelectContext(ctx, &ids, fmt.Sprintf(`SELECT valletId FROM %s WHERE interest_pending >= 0.01 AND valletId > $1
+		ORDER BY valletId LIMIT $2`, walletTable), after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find pending interest: %w", err)
+	}
+	return ids, nil
+}
+
+func (w *WalletPsql) CapitaliseInterest(ctx context.Context, uid uuid.UUID, key string) (wallet.WalletTransactions, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var (
+		recorded wallet.WalletTransactions
+		opErr    error
+	)
+	err := w.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		recorded, opErr = wallet.WalletTransactions{}, nil
+
+		var (
+			st     walletState
+			amount float64
+		)
+		err := tx.QueryRowContext(ctx, fmt.Sprintf(
+			`SELECT %s, TRUNC(interest_pending, 2) FROM %s WHERE valletid = $1 FOR UPDATE`,
+			stateColumns, walletTable), uid).Scan(append(st.dest(), &amount)...)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to lock wallet %s: %w", uid.UUID.String(), ErrWalletNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock wallet %s: %w", uid.UUID.String(), err)
+		}
+
+		ops := []wallet.WalletTransactions{{ValletId: uid, OperationType: "DEPOSIT", Amount: amount,
+			IdempotencyKey: key, Kind: wallet.KindInterest}}
+		known, err := findIdempotent(ctx, tx, ops)
+		if err != nil {
+			return err
+		}
+		if _, ok := known[uid.UUID.String()][key]; !ok && amount < 0.01 {
+			return nil
+		}
+
+		done, results, applied := st.apply(uid, ops, known[uid.UUID.String()])
+		if len(applied) > 0 {
+			if err := recordTransactions(ctx, tx, uid, st, done, applied); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET interest_pending = interest_pending - $2 WHERE valletid = $1`,
+				walletTable), uid, amount)
+			if err != nil {
+				return fmt.Errorf("failed to capitalise interest for wallet %s: %w", uid.UUID.String(), err)
+			}
+		}
+
+		events, err := walletEvents(uid, ops, done, results, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if err := insertOutbox(ctx, tx, events); err != nil {
+			return err
+		}
+		recorded, opErr = done[0], results[0]
+		return nil
+	})
+	if err != nil {
+		return wallet.WalletTransactions{}, err
+	}
+	return recorded, opErr
+}
+
+func (w *WalletPsql) MarkCapitalised(ctx context.Context, day time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	err := w.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET capitalised_at = NOW() WHERE day = $1::date AND capitalised_at IS NULL`,
+			interestDayTable), interestDay(day))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark interest for %s capitalised: %w", interestDay(day), err)
+	}
+	return nil
+}
+
+func (w *WalletPsql) ListInterestAccruals(ctx context.Context, uid uuid.UUID, from, to time.Time) ([]wallet.InterestAccrual, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var exists bool
+	err := w.db.GetContext(ctx, &exists, fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE valletId = $1)`, walletTable), uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interest accruals for wallet %s: %w", uid.UUID.String(), err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("failed to list interest accruals for wallet %s: %w", uid.UUID.String(), ErrWalletNotFound)
+	}
+
+	rows, err := w.db.QueryContext(ctx, fmt.Sprintf(`SELECT valletId, day, product, balance, daily_rate, amount FROM %s
+		WHERE valletId = $1 AND day >= $2::date AND day < $3::date ORDER BY day`, accrualTable),
+		uid, interestDay(from), interestDay(to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interest accruals for wallet %s: %w", uid.UUID.String(), err)
+	}
+	defer rows.Close()
+
+	accruals := make([]wallet.InterestAccrual, 0)
+	for rows.Next() {
+		var a wallet.InterestAccrual
+		if err := rows.Scan(&a.ValletId, &a.Day, &a.Product, &a.Balance, &a.DailyRate, &a.Amount); err != nil {
+			return nil, fmt.Errorf("failed to list interest accruals for wallet %s: %w", uid.UUID.String(), err)
+		}
+		a.Day = time.Date(a.Day.Year(), a.Day.Month(), a.Day.Day(), 0, 0, 0, 0, time.UTC)
+		accruals = append(accruals, a)
+	}
+	return accruals, rows.Err()
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// interestUnits - число единиц накопителя процентов в рубле; в копейке - interestUnits / 100.
+const interestUnits = 1e8
+
+func (m *WalletMemory) SetProduct(ctx context.Context, uid uuid.UUID, product string) (wallet.Wallet, error) {
+	w, ok := m.wallet(uid)
+	if !ok {
+		return wallet.Wallet{}, fmt.Errorf("failed to set product for wallet %s: %w", uid.UUID.String(), ErrWalletNotFound)
+	}
+
+	w.mu.Lock()
+	w.product = product
+	w.mu.Unlock()
+
+	return m.GetWallet(ctx, uid)
+}
+
+func (m *WalletMemory) LastInterestDay(ctx context.Context) (time.Time, bool, error) {
+	m.interestMu.Lock()
+	defer m.interestMu.Unlock()
+
+	var last string
+	for day := range m.interestDays {
+		if day > last {
+			last = day
+		}
+	}
+	if last == "" {
+		return time.Time{}, false, nil
+	}
+
+	day, err := time.Parse("2006-01-02", last)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to get last interest day: %w", err)
+	}
+	return day, m.interestDays[last], nil
+}
+
+func (m *WalletMemory) AccrueInterest(ctx context.Context, day time.Time, rates map[string]float64) (int, error) {
+	// interestMu держится всё начисление, как транзакция с вставкой строки дня в WalletPsql.
+	m.interestMu.Lock()
+	defer m.interestMu.Unlock()
+
+	key := interestDay(day)
+	if _, ok := m.interestDays[key]; ok {
+		return 0, nil
+	}
+	m.interestDays[key] = false
+
+	start, _ := time.Parse("2006-01-02", key)
+	end := start.AddDate(0, 0, 1)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	accrued := 0
+	for id, w := range m.wallets {
+		w.mu.Lock()
+		rate, ok := rates[w.product]
+		if !ok || w.product == "" {
+			w.mu.Unlock()
+			continue
+		}
+
+		// Остаток на конец дня - текущий баланс без операций, записанных после конца дня.
+		balance := w.state.balance
+		for _, WT := range w.history {
+			if WT.CreatedAt.Before(end) {
+				continue
+			}
+			if WT.OperationType == "DEPOSIT" {
+				balance -= WT.Amount
+			} else {
+				balance += WT.Amount
+			}
+		}
+		balance = roundAmount(balance)
+		if balance <= 0 {
+			w.mu.Unlock()
+			continue
+		}
+
+		units := int64(math.Round(balance * rate * interestUnits))
+		w.pending += units
+		w.mu.Unlock()
+
+		a := wallet.InterestAccrual{Day: start, Product: w.product, Balance: balance, DailyRate: rate,
+			Amount: float64(units) / interestUnits}
+		if err := a.ValletId.Scan(id); err != nil {
+			return accrued, fmt.Errorf("failed to accrue interest for %s: %w", key, err)
+		}
+		m.accruals[id] = append(m.accruals[id], a)
+		accrued++
+	}
+	return accrued, nil
+}
+
+func (m *WalletMemory) PendingInterest(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	ids := make([]string, 0)
+
+	m.mu.RLock()
+	for id, w := range m.wallets {
+		if id <= after.UUID.String() {
+			continue
+		}
+		w.mu.Lock()
+		if w.pending >= interestUnits/100 {
+			ids = append(ids, id)
+		}
+		w.mu.Unlock()
+	}
+	m.mu.RUnlock()
+
+	sort.Strings(ids)
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	result := make([]uuid.UUID, len(ids))
+	for i, id := range ids {
+		if err := result[i].Scan(id); err != nil {
+			return nil, fmt.Errorf("failed to find pending interest: %w", err)
+		}
+	}
+	return result, nil
+}
+
+func (m *WalletMemory) CapitaliseInterest(ctx context.Context, uid uuid.UUID, key string) (wallet.WalletTransactions, error) {
+	w, ok := m.wallet(uid)
+	if !ok {
+		return wallet.WalletTransactions{}, fmt.Errorf("failed to lock wallet %s: %w", uid.UUID.String(), ErrWalletNotFound)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	cents := w.pending / (interestUnits / 100)
+	ops := []wallet.WalletTransactions{{ValletId: uid, OperationType: "DEPOSIT", Amount: float64(cents) / 100,
+		IdempotencyKey: key, Kind: wallet.KindInterest}}
+	known := w.known(ops)
+	if _, ok := known[key]; !ok && cents == 0 {
+		return wallet.WalletTransactions{}, nil
+	}
+
+	st := w.state
+	recorded, results, applied := st.apply(uid, ops, known)
+
+	now := time.Now()
+	m.stamp(recorded, applied, now)
+	events, err := walletEvents(uid, ops, recorded, results, now.UTC())
+	if err != nil {
+		return wallet.WalletTransactions{}, err
+	}
+	m.commit(uid, w, st, recorded, applied, events)
+	if len(applied) > 0 {
+		w.pending -= cents * (interestUnits / 100)
+	}
+
+	return recorded[0], results[0]
+}
+
+func (m *WalletMemory) MarkCapitalised(ctx context.Context, day time.Time) error {
+	m.interestMu.Lock()
+	defer m.interestMu.Unlock()
+
+	if _, ok := m.interestDays[interestDay(day)]; ok {
+		m.interestDays[interestDay(day)] = true
+	}
+	return nil
+}
+
+func (m *WalletMemory) ListInterestAccruals(ctx context.Context, uid uuid.UUID, from, to time.Time) ([]wallet.InterestAccrual, error) {
+	if _, ok := m.wallet(uid); !ok {
+		return nil, fmt.Errorf("failed to list interest accruals for wallet %s: %w", uid.UUID.String(), ErrWalletNotFound)
+	}
+
+	m.interestMu.Lock()
+	defer m.interestMu.Unlock()
+
+	accruals := make([]wallet.InterestAccrual, 0)
+	for _, a := range m.accruals[uid.UUID.String()] {
+		if day := interestDay(a.Day); day >= interestDay(from) && day < interestDay(to) {
+			accruals = append(accruals, a)
+		}
+	}
+	sort.Slice(accruals, func(i, j int) bool { return accruals[i].Day.Before(accruals[j].Day) })
+	return accruals, nil
+}
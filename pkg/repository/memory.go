@@ -0,0 +1,702 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/jackc/pgtype"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// memoryWallet - строка таблицы wallets. mu играет роль блокировки строки (SELECT ... FOR UPDATE).
+type memoryWallet struct {
+	mu      sync.Mutex
+	state   walletState
+	history []wallet.WalletTransactions // история в порядке seq
+	keys    map[string]int              // ключ идемпотентности -> индекс в history
+	tier    string
+	product string
+	pending int64 // накопитель процентов в единицах 1e-8, как NUMERIC(20, 8)
+}
+
+// known возвращает записанные транзакции кошелька с ключами идемпотентности из ops.
+func (w *memoryWallet) known(ops []wallet.WalletTransactions) map[string]wallet.WalletTransactions {
+	known := make(map[string]wallet.WalletTransactions)
+	for _, WT := range ops {
+		if idx, ok := w.keys[WT.IdempotencyKey]; ok && WT.IdempotencyKey != "" {
+			known[WT.IdempotencyKey] = w.history[idx]
+		}
+	}
+	return known
+}
+
+// WalletMemory - реализация repository.Wallet в памяти процесса с той же семантикой, что и WalletPsql:
+// блокировка кошелька на время изменения баланса, запрет отрицательного баланса и
+// проверка существования кошелька при записи транзакции (аналог внешнего ключа).
+type WalletMemory struct {
+	mu      sync.RWMutex
+	wallets map[string]*memoryWallet
+	lastID  atomic.Int64 // аналог SERIAL wallet_transactions.id
+
+	cpMu        sync.Mutex
+	checkpoints map[string][]wallet.Checkpoint // контрольные точки кошелька в порядке seq
+	lastCpID    int64
+
+	stMu       sync.Mutex
+	statements map[string]map[string]wallet.Statement // ID кошелька -> период YYYY-MM -> выписка
+
+	outboxMu     sync.Mutex
+	outbox       []memoryOutboxEvent // в порядке id
+	lastOutboxID int64
+	relayMu      sync.Mutex // аналог advisory-блокировки relay
+
+	changesMu    sync.Mutex
+	listeners    map[int]func(walletID uuid.UUID, seq int64) // аналог LISTEN wallet_changes
+	lastListener int
+
+	interestMu   sync.Mutex
+	interestDays map[string]bool                     // день YYYY-MM-DD -> проценты за месяц капитализированы
+	accruals     map[string][]wallet.InterestAccrual // ID кошелька -> начисления
+}
+
+type memoryOutboxEvent struct {
+	wallet.OutboxEvent
+	publishedAt time.Time
+}
+
+func NewWalletMemory() *WalletMemory {
+	return &WalletMemory{
+		wallets:      make(map[string]*memoryWallet),
+		checkpoints:  make(map[string][]wallet.Checkpoint),
+		statements:   make(map[string]map[string]wallet.Statement),
+		listeners:    make(map[int]func(walletID uuid.UUID, seq int64)),
+		interestDays: make(map[string]bool),
+		accruals:     make(map[string][]wallet.InterestAccrual),
+	}
+}
+
+// AddWallet создаёт кошелёк с начальным балансом (аналог INSERT INTO wallets).
+func (m *WalletMemory) AddWallet(uid uuid.UUID, balance float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.wallets[uid.UUID.String()] = &memoryWallet{state: walletState{balance: roundAmount(balance), version: 1}, tier: wallet.DefaultTier}
+}
+
+func (m *WalletMemory) wallet(uid uuid.UUID) (*memoryWallet, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	w, ok := m.wallets[uid.UUID.String()]
+	return w, ok
+}
+
+func (m *WalletMemory) GetBalance(ctx context.Context, uid uuid.UUID) (float64, error) {
+	w, ok := m.wallet(uid)
+	if !ok {
+		return 0, fmt.Errorf("failed to get balance for wallet %s: %w", uid.UUID.String(), ErrWalletNotFound)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.state.balance, nil
+}
+
+func (m *WalletMemory) GetWallet(ctx context.Context, uid uuid.UUID) (wallet.Wallet, error) {
+	w, ok := m.wallet(uid)
+	if !ok {
+		return wallet.Wallet{}, fmt.Errorf("failed to get wallet %s: %w", uid.UUID.String(), ErrWalletNotFound)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return wallet.Wallet{
+		ValletId:        uid,
+		Balance:         w.state.balance,
+		Version:         w.state.version,
+		LastSeq:         w.state.lastSeq,
+		LastHash:        w.state.lastHash,
+		Tier:            w.tier,
+		Product:         w.product,
+		AccruedInterest: float64(w.pending) / interestUnits,
+		CreditLimit:     w.state.creditLimit,
+		Available:       w.state.available(),
+		Buckets:         w.state.balances(),
+	}, nil
+}
+
+func (m *WalletMemory) WalletTiers(ctx context.Context, ids []uuid.UUID) (map[string]string, error) {
+	tiers := make(map[string]string, len(ids))
+	for _, id := range ids {
+		w, ok := m.wallet(id)
+		if !ok {
+			continue
+		}
+		w.mu.Lock()
+		tiers[id.UUID.String()] = w.tier
+		w.mu.Unlock()
+	}
+	return tiers, nil
+}
+
+func (m *WalletMemory) SetTier(ctx context.Context, uid uuid.UUID, tier string) (wallet.Wallet, error) {
+	w, ok := m.wallet(uid)
+	if !ok {
+		return wallet.Wallet{}, fmt.Errorf("failed to set tier for wallet %s: %w", uid.UUID.String(), ErrWalletNotFound)
+	}
+
+	w.mu.Lock()
+	w.tier = tier
+	w.mu.Unlock()
+
+	return m.GetWallet(ctx, uid)
+}
+
+func (m *WalletMemory) SetCreditLimit(ctx context.Context, uid uuid.UUID, limit float64) (wallet.Wallet, error) {
+	w, ok := m.wallet(uid)
+	if !ok {
+		return wallet.Wallet{}, fmt.Errorf("failed to set credit limit for wallet %s: %w", uid.UUID.String(), ErrWalletNotFound)
+	}
+
+	limit = roundAmount(limit)
+	w.mu.Lock()
+	if w.state.bucket(wallet.BucketMain) < -limit {
+		w.mu.Unlock()
+		return wallet.Wallet{}, fmt.Errorf("failed to set credit limit for wallet %s: %w", uid.UUID.String(), ErrCreditLimitBelowDebt)
+	}
+	w.state.creditLimit = limit
+	w.state.version++
+	w.mu.Unlock()
+
+	return m.GetWallet(ctx, uid)
+}
+
+func (m *WalletMemory) ApplyTransactions(ctx context.Context, uid uuid.UUID, ops []wallet.WalletTransactions) ([]wallet.WalletTransactions, []error, error) {
+	w, ok := m.wallet(uid)
+	if !ok {
+		return nil, nil, fmt.Errorf("failed to lock wallet %s: %w", uid.UUID.String(), ErrWalletNotFound)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	st := w.state
+	recorded, results, applied := st.apply(uid, ops, w.known(ops))
+
+	now := time.Now()
+	m.stamp(recorded, applied, now)
+	fillDuplicates(ops, recorded, results)
+	events, err := walletEvents(uid, ops, recorded, results, now.UTC())
+	if err != nil {
+		return nil, nil, err
+	}
+	m.commit(uid, w, st, recorded, applied, events)
+
+	return recorded, results, nil
+}
+
+func (m *WalletMemory) ApplyAtomic(ctx context.Context, ops []wallet.WalletTransactions) ([]wallet.WalletTransactions, []error, error) {
+	// Блокировки берутся в порядке ID кошельков, как в WalletPsql.
+	ids := batchWallets(ops)
+	sort.Slice(ids, func(i, j int) bool { return ids[i].UUID.String() < ids[j].UUID.String() })
+
+	locked := make(map[string]*memoryWallet, len(ids))
+	states := make(map[string]*walletState, len(ids))
+	known := make(map[string]map[string]wallet.WalletTransactions, len(ids))
+	for _, uid := range ids {
+		w, ok := m.wallet(uid)
+		if !ok {
+			continue
+		}
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		id := uid.UUID.String()
+		st := w.state
+		locked[id], states[id], known[id] = w, &st, w.known(ops)
+	}
+
+	recorded, results, groups := applyAtomic(ops, states, known)
+	if !batchApplied(results) {
+		return recorded, results, nil
+	}
+
+	now := time.Now()
+	events := make(map[string][]wallet.WalletEvent, len(groups))
+	for id, g := range groups {
+		m.stamp(g.recorded, g.applied, now)
+		groupEvents, err := walletEvents(g.uid, g.ops, g.recorded, g.results, now.UTC())
+		if err != nil {
+			return nil, nil, err
+		}
+		events[id] = groupEvents
+	}
+	for _, id := range sortedKeys(groups) {
+		g := groups[id]
+		m.commit(g.uid, locked[id], *states[id], g.recorded, g.applied, events[id])
+		g.scatter(recorded)
+	}
+	fillDuplicates(ops, recorded, results)
+
+	return recorded, results, nil
+}
+
+func (m *WalletMemory) Transfer(ctx context.Context, from, to uuid.UUID, amount float64) (wallet.Transfer, error) {
+	src, ok := m.wallet(from)
+	if !ok {
+		return wallet.Transfer{}, fmt.Errorf("failed to lock wallet %s: %w", from.UUID.String(), ErrWalletNotFound)
+	}
+	dst, ok := m.wallet(to)
+	if !ok {
+		return wallet.Transfer{}, fmt.Errorf("failed to lock wallet %s: %w", to.UUID.String(), ErrWalletNotFound)
+	}
+
+	// Блокировки берутся в порядке ID кошельков, как в WalletPsql.
+	first, second := src, dst
+	if to.UUID.String() < from.UUID.String() {
+		first, second = dst, src
+	}
+	first.mu.Lock()
+	defer first.mu.Unlock()
+	second.mu.Lock()
+	defer second.mu.Unlock()
+
+	debit := []wallet.WalletTransactions{{ValletId: from, OperationType: "WITHDRAW", Amount: amount}}
+	credit := []wallet.WalletTransactions{{ValletId: to, OperationType: "DEPOSIT", Amount: amount}}
+	now := time.Now()
+
+	srcState := src.state
+	debited, results, applied := srcState.apply(from, debit, nil)
+	m.stamp(debited, applied, now)
+	events, err := walletEvents(from, debit, debited, results, now.UTC())
+	if err != nil {
+		return wallet.Transfer{}, err
+	}
+	if results[0] != nil {
+		m.commit(from, src, src.state, debited, nil, events)
+		return wallet.Transfer{}, results[0]
+	}
+
+	dstState := dst.state
+	credited, creditResults, creditApplied := dstState.apply(to, credit, nil)
+	if creditResults[0] != nil {
+		return wallet.Transfer{}, creditResults[0]
+	}
+	m.stamp(credited, creditApplied, now)
+	creditEvents, err := walletEvents(to, credit, credited, creditResults, now.UTC())
+	if err != nil {
+		return wallet.Transfer{}, err
+	}
+
+	m.commit(from, src, srcState, debited, applied, events)
+	m.commit(to, dst, dstState, credited, creditApplied, creditEvents)
+
+	return wallet.Transfer{From: debited[0], To: credited[0]}, nil
+}
+
+// stamp выдаёт применённым транзакциям id и время записи (аналог RETURNING id, created_at).
+func (m *WalletMemory) stamp(recorded []wallet.WalletTransactions, applied []int, now time.Time) {
+	for _, idx := range applied {
+		recorded[idx].Id = int(m.lastID.Add(1))
+		recorded[idx].CreatedAt = now
+	}
+}
+
+// commit сохраняет новое состояние кошелька, историю и события. Вызывается под блокировкой кошелька w.
+func (m *WalletMemory) commit(uid uuid.UUID, w *memoryWallet, st walletState,
+	recorded []wallet.WalletTransactions, applied []int, events []wallet.WalletEvent) {
+	w.state = st
+	for _, idx := range applied {
+		if key := recorded[idx].IdempotencyKey; key != "" {
+			if w.keys == nil {
+				w.keys = make(map[string]int)
+			}
+			w.keys[key] = len(w.history)
+		}
+		w.history = append(w.history, recorded[idx])
+	}
+
+	// События пишутся под блокировкой кошелька, поэтому их порядок в outbox совпадает с порядком операций.
+	m.outboxMu.Lock()
+	for _, event := range events {
+		m.lastOutboxID++
+		m.outbox = append(m.outbox, memoryOutboxEvent{OutboxEvent: wallet.OutboxEvent{Id: m.lastOutboxID, Event: event}})
+	}
+	m.outboxMu.Unlock()
+
+	if len(applied) > 0 {
+		m.notifyChange(uid, w.state.lastSeq)
+	}
+}
+
+func (m *WalletMemory) FindIdempotent(ctx context.Context, ops []wallet.WalletTransactions) (map[string]map[string]wallet.WalletTransactions, error) {
+	byWallet := make(map[string][]wallet.WalletTransactions)
+	for _, WT := range ops {
+		if WT.IdempotencyKey != "" {
+			id := WT.ValletId.UUID.String()
+			byWallet[id] = append(byWallet[id], WT)
+		}
+	}
+
+	found := make(map[string]map[string]wallet.WalletTransactions)
+	for id, group := range byWallet {
+		w, ok := m.wallet(group[0].ValletId)
+		if !ok {
+			continue
+		}
+
+		w.mu.Lock()
+		if known := w.known(group); len(known) > 0 {
+			found[id] = known
+		}
+		w.mu.Unlock()
+	}
+	return found, nil
+}
+
+func (m *WalletMemory) ListTransactions(ctx context.Context, uid uuid.UUID, afterSeq int64, limit int) ([]wallet.WalletTransactions, error) {
+	w, ok := m.wallet(uid)
+	if !ok {
+		return nil, fmt.Errorf("failed to list transactions for wallet %s: %w", uid.UUID.String(), ErrWalletNotFound)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// seq начинается с 1 и идёт без пропусков, поэтому транзакция с seq = n лежит в history[n-1].
+	if afterSeq < 0 {
+		afterSeq = 0
+	}
+	if afterSeq >= int64(len(w.history)) {
+		return []wallet.WalletTransactions{}, nil
+	}
+
+	page := w.history[afterSeq:]
+	if len(page) > limit {
+		page = page[:limit]
+	}
+	return append([]wallet.WalletTransactions(nil), page...), nil
+}
+
+func (m *WalletMemory) ListBucketTransactions(ctx context.Context, uid uuid.UUID, bucket string, afterSeq int64, limit int) ([]wallet.WalletTransactions, error) {
+	if !validBucket(bucket) {
+		return nil, fmt.Errorf("failed to list transactions for wallet %s: %w %q", uid.UUID.String(), ErrInvalidBucket, bucket)
+	}
+	w, ok := m.wallet(uid)
+	if !ok {
+		return nil, fmt.Errorf("failed to list transactions for wallet %s: %w", uid.UUID.String(), ErrWalletNotFound)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	history := make([]wallet.WalletTransactions, 0)
+	for _, WT := range w.history {
+		if len(history) == limit {
+			break
+		}
+		if WT.Seq <= afterSeq {
+			continue
+		}
+		if slices.ContainsFunc(WT.Buckets, func(entry wallet.BucketEntry) bool { return entry.Bucket == bucket }) {
+			history = append(history, WT)
+		}
+	}
+	return history, nil
+}
+
+func (m *WalletMemory) PeriodSeq(ctx context.Context, uid uuid.UUID, from, to time.Time) (int64, int64, error) {
+	w, ok := m.wallet(uid)
+	if !ok {
+		return 0, 0, nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var afterSeq, lastSeq int64
+	for _, WT := range w.history {
+		if !from.IsZero() && WT.CreatedAt.Before(from) {
+			afterSeq = WT.Seq
+		}
+		if to.IsZero() || WT.CreatedAt.Before(to) {
+			lastSeq = WT.Seq
+		}
+	}
+	return afterSeq, lastSeq, nil
+}
+
+func (m *WalletMemory) ChainHeads(ctx context.Context) ([]wallet.Checkpoint, error) {
+	m.mu.RLock()
+	heads := make([]wallet.Checkpoint, 0, len(m.wallets))
+	for id, w := range m.wallets {
+		w.mu.Lock()
+		head := wallet.Checkpoint{Seq: w.state.lastSeq, Hash: w.state.lastHash}
+		w.mu.Unlock()
+
+		if err := head.ValletId.Scan(id); err != nil {
+			m.mu.RUnlock()
+			return nil, fmt.Errorf("failed to get chain heads: %w", err)
+		}
+		heads = append(heads, head)
+	}
+	m.mu.RUnlock()
+
+	m.cpMu.Lock()
+	defer m.cpMu.Unlock()
+
+	pending := heads[:0]
+	for _, head := range heads {
+		var checkpointed int64
+		if cps := m.checkpoints[head.ValletId.UUID.String()]; len(cps) > 0 {
+			checkpointed = cps[len(cps)-1].Seq
+		}
+		if head.Seq > checkpointed {
+			pending = append(pending, head)
+		}
+	}
+	return pending, nil
+}
+
+func (m *WalletMemory) SaveCheckpoints(ctx context.Context, checkpoints []wallet.Checkpoint) error {
+	for _, cp := range checkpoints {
+		if _, ok := m.wallet(cp.ValletId); !ok {
+			return fmt.Errorf("failed to save checkpoints: %w", ErrWalletNotFound)
+		}
+	}
+
+	m.cpMu.Lock()
+	defer m.cpMu.Unlock()
+
+	now := time.Now()
+	for _, cp := range checkpoints {
+		id := cp.ValletId.UUID.String()
+		existing := m.checkpoints[id]
+		if n := len(existing); n > 0 && existing[n-1].Seq >= cp.Seq {
+			continue // аналог ON CONFLICT DO NOTHING; точки пишутся только вперёд
+		}
+
+		m.lastCpID++
+		cp.Id = m.lastCpID
+		cp.CreatedAt = now
+		m.checkpoints[id] = append(existing, cp)
+	}
+	return nil
+}
+
+func (m *WalletMemory) ListCheckpoints(ctx context.Context, uid uuid.UUID) ([]wallet.Checkpoint, error) {
+	m.cpMu.Lock()
+	defer m.cpMu.Unlock()
+
+	return append([]wallet.Checkpoint(nil), m.checkpoints[uid.UUID.String()]...), nil
+}
+
+func (m *WalletMemory) PendingStatements(ctx context.Context, from, to time.Time, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	period := from.UTC().Format("2006-01")
+	ids := make([]string, 0)
+
+	m.mu.RLock()
+	for id, w := range m.wallets {
+		if id <= after.UUID.String() {
+			continue
+		}
+		w.mu.Lock()
+		active := false
+		for _, WT := range w.history {
+			if !WT.CreatedAt.Before(from) && WT.CreatedAt.Before(to) {
+				active = true
+				break
+			}
+		}
+		w.mu.Unlock()
+		if active {
+			ids = append(ids, id)
+		}
+	}
+	m.mu.RUnlock()
+
+	m.stMu.Lock()
+	pending := ids[:0]
+	for _, id := range ids {
+		if _, ok := m.statements[id][period]; !ok {
+			pending = append(pending, id)
+		}
+	}
+	m.stMu.Unlock()
+
+	// Строковое представление UUID сравнивается так же, как сам UUID в Postgres.
+	sort.Strings(pending)
+	if len(pending) > limit {
+		pending = pending[:limit]
+	}
+
+	result := make([]uuid.UUID, len(pending))
+	for i, id := range pending {
+		if err := result[i].Scan(id); err != nil {
+			return nil, fmt.Errorf("failed to find pending statements: %w", err)
+		}
+	}
+	return result, nil
+}
+
+func (m *WalletMemory) SaveStatement(ctx context.Context, st wallet.Statement) error {
+	id := st.ValletId.UUID.String()
+	if _, ok := m.wallet(st.ValletId); !ok {
+		return fmt.Errorf("failed to save statement for wallet %s: %w", id, ErrWalletNotFound)
+	}
+
+	m.stMu.Lock()
+	defer m.stMu.Unlock()
+
+	setStatementPeriod(&st)
+	if _, ok := m.statements[id][st.Period]; ok {
+		return nil // аналог ON CONFLICT DO NOTHING
+	}
+	if m.statements[id] == nil {
+		m.statements[id] = make(map[string]wallet.Statement)
+	}
+	st.Formats = append([]string{}, st.Formats...)
+	st.CreatedAt = time.Now()
+	m.statements[id][st.Period] = st
+	return nil
+}
+
+func (m *WalletMemory) ListStatements(ctx context.Context, uid uuid.UUID) ([]wallet.Statement, error) {
+	m.stMu.Lock()
+	defer m.stMu.Unlock()
+
+	statements := make([]wallet.Statement, 0, len(m.statements[uid.UUID.String()]))
+	for _, st := range m.statements[uid.UUID.String()] {
+		statements = append(statements, st)
+	}
+	sort.Slice(statements, func(i, j int) bool { return statements[i].Period > statements[j].Period })
+	return statements, nil
+}
+
+func (m *WalletMemory) GetStatement(ctx context.Context, uid uuid.UUID, from time.Time) (wallet.Statement, error) {
+	m.stMu.Lock()
+	defer m.stMu.Unlock()
+
+	st, ok := m.statements[uid.UUID.String()][from.UTC().Format("2006-01")]
+	if !ok {
+		return wallet.Statement{}, fmt.Errorf("failed to get statement for wallet %s: %w", uid.UUID.String(), ErrStatementNotFound)
+	}
+	return st, nil
+}
+
+func (m *WalletMemory) TransactionStats(ctx context.Context, walletID uuid.UUID, from, to time.Time, interval string) ([]wallet.StatsBucket, error) {
+	byStart := make(map[time.Time]*wallet.StatsBucket)
+
+	m.mu.RLock()
+	for id, w := range m.wallets {
+		if walletID.Status == pgtype.Present && id != walletID.UUID.String() {
+			continue
+		}
+		w.mu.Lock()
+		for _, WT := range w.history {
+			if WT.CreatedAt.Before(from) || !WT.CreatedAt.Before(to) {
+				continue
+			}
+			start := wallet.StatsBucketStart(WT.CreatedAt, interval)
+			if byStart[start] == nil {
+				byStart[start] = &wallet.StatsBucket{Start: start}
+			}
+			byStart[start].Add(WT)
+		}
+		w.mu.Unlock()
+	}
+	m.mu.RUnlock()
+
+	buckets := make([]wallet.StatsBucket, 0, len(byStart))
+	for _, b := range byStart {
+		buckets = append(buckets, *b)
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Start.Before(buckets[j].Start) })
+	return buckets, nil
+}
+
+func (m *WalletMemory) RelayOutbox(ctx context.Context, limit int, publish func(ctx context.Context, events []wallet.OutboxEvent) []int64) (int, error) {
+	if !m.relayMu.TryLock() {
+		return 0, nil
+	}
+	defer m.relayMu.Unlock()
+
+	m.outboxMu.Lock()
+	var pending []wallet.OutboxEvent
+	for _, e := range m.outbox {
+		if len(pending) == limit {
+			break
+		}
+		if e.publishedAt.IsZero() {
+			pending = append(pending, e.OutboxEvent)
+		}
+	}
+	m.outboxMu.Unlock()
+
+	if len(pending) == 0 {
+		return 0, nil
+	}
+	published := publish(ctx, pending)
+
+	m.outboxMu.Lock()
+	defer m.outboxMu.Unlock()
+
+	ids := make(map[int64]bool, len(published))
+	for _, id := range published {
+		ids[id] = true
+	}
+	now := time.Now()
+	for i := range m.outbox {
+		if ids[m.outbox[i].Id] {
+			m.outbox[i].publishedAt = now
+		}
+	}
+	return len(published), nil
+}
+
+func (m *WalletMemory) PurgeOutbox(ctx context.Context, before time.Time) (int64, error) {
+	m.outboxMu.Lock()
+	defer m.outboxMu.Unlock()
+
+	kept := m.outbox[:0]
+	for _, e := range m.outbox {
+		if e.publishedAt.IsZero() || !e.publishedAt.Before(before) {
+			kept = append(kept, e)
+		}
+	}
+	purged := int64(len(m.outbox) - len(kept))
+	m.outbox = kept
+	return purged, nil
+}
+
+func (m *WalletMemory) ListenChanges(ctx context.Context, notify func(walletID uuid.UUID, seq int64)) error {
+	m.changesMu.Lock()
+	m.lastListener++
+	id := m.lastListener
+	m.listeners[id] = notify
+	m.changesMu.Unlock()
+
+	<-ctx.Done()
+
+	m.changesMu.Lock()
+	delete(m.listeners, id)
+	m.changesMu.Unlock()
+	return ctx.Err()
+}
+
+// notifyChange - аналог триггера wallet_changes. Вызывается под блокировкой кошелька,
+// поэтому уведомления одного кошелька приходят в порядке seq.
+func (m *WalletMemory) notifyChange(uid uuid.UUID, seq int64) {
+	m.changesMu.Lock()
+	defer m.changesMu.Unlock()
+
+	for _, notify := range m.listeners {
+		notify(uid, seq)
+	}
+}
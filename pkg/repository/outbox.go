@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	gofrs "github.com/gofrs/uuid"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// outboxRelayLock - ключ advisory-блокировки, под которой outbox разбирает один relay на все экземпляры сервиса.
+const outboxRelayLock = 0x77616c6c6574 // "wallet"
+
+// walletEvents собирает события о пачке операций: о применённых и об отклонённых из-за баланса или версии.
+// Остальные отказы (например, неизвестный тип операции) событий не порождают. Если применённая операция
+// перевела остаток main через ноль, за её событием следует событие о входе в овердрафт или выходе из него.
+func walletEvents(uid uuid.UUID, ops, recorded []wallet.WalletTransactions, results []error, now time.Time) ([]wallet.WalletEvent, error) {
+	events := make([]wallet.WalletEvent, 0, len(ops))
+	for i, opErr := range results {
+		event := wallet.WalletEvent{ValletId: uid, OccurredAt: now, Transaction: recorded[i]}
+		switch {
+		case opErr == nil && recorded[i].OperationType == "DEPOSIT":
+			event.Type = wallet.EventDeposited
+		case opErr == nil:
+			event.Type = wallet.EventWithdrawn
+		case errors.Is(opErr, ErrInsufficientFunds), errors.Is(opErr, ErrVersionMismatch):
+			event.Type = wallet.EventRejected
+			event.Transaction = ops[i]
+			event.Transaction.ValletId = uid
+			event.Reason = opErr.Error()
+		default:
+			continue
+		}
+		if err := appendEvent(&events, event); err != nil {
+			return nil, err
+		}
+
+		if opErr != nil {
+			continue
+		}
+		if overdraft := overdraftEvent(recorded[i]); overdraft != "" {
+			event.Type = overdraft
+			if err := appendEvent(&events, event); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return events, nil
+}
+
+// overdraftEvent возвращает тип события, если транзакция WT перевела остаток main через ноль, иначе пустую строку.
+// В минус уходит только main, поэтому овердрафт - это отрицательный остаток main.
+func overdraftEvent(WT wallet.WalletTransactions) string {
+	i := slices.IndexFunc(WT.Buckets, func(entry wallet.BucketEntry) bool { return entry.Bucket == wallet.BucketMain })
+	if i < 0 {
+		return ""
+	}
+	main := WT.Buckets[i]
+
+	before := main.BalanceAfter + main.Amount
+	if WT.OperationType == "DEPOSIT" {
+		before = main.BalanceAfter - main.Amount
+	}
+	before = roundAmount(before)
+
+	switch {
+	case before >= 0 && main.BalanceAfter < 0:
+		return wallet.EventOverdraftEntered
+	case before < 0 && main.BalanceAfter >= 0:
+		return wallet.EventOverdraftExited
+	default:
+		return ""
+	}
+}
+
+// appendEvent выдаёт событию id и добавляет его в events.
+func appendEvent(events *[]wallet.WalletEvent, event wallet.WalletEvent) error {
+	id, err := gofrs.NewV4()
+	if err != nil {
+		return fmt.Errorf("failed to generate event id: %w", err)
+	}
+	event.Id = id.String()
+	*events = append(*events, event)
+	return nil
+}
+
+func insertOutbox(ctx context.Context, tx *sqlx.Tx, events []wallet.WalletEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(events))
+	args := make([]interface{}, 0, 4*len(events))
+	for i, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to encode event %s: %w", event.Id, err)
+		}
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d)", 4*i+1, 4*i+2, 4*i+3, 4*i+4))
+		args = append(args, event.Id, event.ValletId, event.Type, string(payload))
+	}
+
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (event_id, valletId, event_type, payload) VALUES %s`,
+		outboxTable, strings.Join(values, ", ")), args...)
+	if err != nil {
+		return fmt.Errorf("failed to write events to outbox: %w", err)
+	}
+	return nil
+}
+
+// RelayOutbox держит транзакцию с advisory-блокировкой, пока publish отправляет события,
+// поэтому длительность вызова определяется ctx вызывающего.
+// Транзакция открывается напрямую, а не через TxRunner: publish отправляет события наружу, и повтор
+// после ошибки сериализации опубликовал бы их ещё раз. Неудачный проход просто повторит следующий вызов.
+func (w *WalletPsql) RelayOutbox(ctx context.Context, limit int, publish func(ctx context.Context, events []wallet.OutboxEvent) []int64) (int, error) {
+	tx, err := w.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, outboxRelayLock).Scan(&locked); err != nil {
+		return 0, fmt.Errorf("failed to lock outbox: %w", err)
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	events, err := pendingOutbox(ctx, tx, limit)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	published := publish(ctx, events)
+	if len(published) == 0 {
+		return 0, nil
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET published_at = $1 WHERE id = ANY($2)`, outboxTable),
+		time.Now(), pq.Array(published))
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark outbox events published: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit tx: %w", err)
+	}
+
+	return len(published), nil
+}
+
+func pendingOutbox(ctx context.Context, tx *sqlx.Tx, limit int) ([]wallet.OutboxEvent, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(
+		`SELECT id, payload FROM %s WHERE published_at IS NULL ORDER BY id LIMIT $1`, outboxTable), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	defer rows.Close()
+
+	var events []wallet.OutboxEvent
+	for rows.Next() {
+		var (
+			e       wallet.OutboxEvent
+			payload []byte
+		)
+		if err := rows.Scan(&e.Id, &payload); err != nil {
+			return nil, fmt.Errorf("failed to read outbox: %w", err)
+		}
+		if err := json.Unmarshal(payload, &e.Event); err != nil {
+			return nil, fmt.Errorf("failed to decode outbox event %d: %w", e.Id, err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (w *WalletPsql) PurgeOutbox(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var n int64
+	err := w.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		res, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE published_at < $1`, outboxTable), before)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge outbox: %w", err)
+	}
+	return n, nil
+}
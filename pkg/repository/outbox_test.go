@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sqlmock "github.com/zhashkevych/go-sqlxmock"
+)
+
+func TestWalletPsql_RelayOutbox(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		log.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	r := NewWalletPsql(db, NewTxRunner(db, TxOptions{}))
+	payload := `{"id": "e1", "type": "transaction.deposited"}`
+
+	// Ошибка сериализации при отметке событий не повторяет проход: иначе события ушли бы получателям дважды.
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock`).WithArgs(outboxRelayLock).
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+	mock.ExpectQuery(fmt.Sprintf(`SELECT id, payload FROM %s WHERE published_at IS NULL`, outboxTable)).WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "payload"}).AddRow(1, payload))
+	mock.ExpectExec(fmt.Sprintf(`UPDATE %s SET published_at`, outboxTable)).WillReturnError(&pq.Error{Code: "40001"})
+	mock.ExpectRollback()
+
+	calls := 0
+	_, err = r.RelayOutbox(context.Background(), 10, func(ctx context.Context, events []wallet.OutboxEvent) []int64 {
+		calls++
+		require.Len(t, events, 1)
+		assert.Equal(t, "e1", events[0].Event.Id)
+		return []int64{events[0].Id}
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
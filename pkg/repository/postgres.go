@@ -7,8 +7,20 @@ import (
 )
 
 const (
-	walletTable    = "wallets"
-	walletTRXTable = "wallet_transactions"
+	walletTable       = "wallets"
+	walletTRXTable    = "wallet_transactions"
+	checkpointTable   = "wallet_checkpoints"
+	statementTable    = "wallet_statements"
+	dailyStatsTable   = "wallet_daily_stats"
+	scheduledTable    = "scheduled_transactions"
+	recurringTable    = "recurring_transfers"
+	recurringRunTable = "recurring_transfer_runs"
+	webhookTable      = "webhook_subscriptions"
+	deliveryTable     = "webhook_deliveries"
+	outboxTable       = "wallet_outbox"
+	accrualTable      = "interest_accruals"
+	interestDayTable  = "interest_days"
+	bucketEntryTable  = "wallet_bucket_entries"
 )
 
 type Config struct {
@@ -20,9 +32,13 @@ type Config struct {
 	SSLMode  string
 }
 
+func (cfg Config) DSN() string {
+	return fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=%s",
+		cfg.Host, cfg.Port, cfg.Username, cfg.DBName, cfg.Password, cfg.SSLMode)
+}
+
 func NewPostgresDB(cfg Config) (*sqlx.DB, error) {
-	db, err := sqlx.Open("postgres", fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.Username, cfg.DBName, cfg.Password, cfg.SSLMode))
+	db, err := sqlx.Open("postgres", cfg.DSN())
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type RecurringPsql struct {
+	db *sqlx.DB
+	tx *TxRunner
+}
+
+func NewRecurringPsql(db *sqlx.DB, tx *TxRunner) *RecurringPsql {
+	return &RecurringPsql{db: db, tx: tx}
+}
+
+const recurringColumns = `id, from_valletId, to_valletId, amount, schedule, start_at, end_at, catch_up, max_retries,
+	retry_interval_seconds, status, occurrence_at, next_run_at, attempt, locked_until, created_at, updated_at`
+
+const recurringRunColumns = `id, rule_id, occurrence_at, attempt, status, error, from_transaction_id, to_transaction_id, created_at`
+
+func scanRecurring(row interface{ Scan(dest ...any) error }) (wallet.RecurringTransfer, error) {
+	var r wallet.RecurringTransfer
+	err := row.Scan(&r.Id, &r.FromWalletId, &r.ToWalletId, &r.Amount, &r.Schedule, &r.StartAt, &r.EndAt, &r.CatchUp,
+		&r.MaxRetries, &r.RetryIntervalSeconds, &r.Status, &r.OccurrenceAt, &r.NextRunAt, &r.Attempt, &r.LockedUntil,
+		&r.CreatedAt, &r.UpdatedAt)
+	return r, err
+}
+
+// utcOrNil передаёт необязательное время в колонку TIMESTAMP (без часового пояса, в UTC).
+func utcOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+func (s *RecurringPsql) CreateRecurring(ctx context.Context, r wallet.RecurringTransfer) (wallet.RecurringTransfer, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var created wallet.RecurringTransfer
+	err := s.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		created, err = scanRecurring(tx.QueryRowContext(ctx, fmt.Sprintf(`INSERT INTO %s (from_valletId, to_valletId, amount,
+				schedule, start_at, end_at, catch_up, max_retries, retry_interval_seconds, status, occurrence_at, next_run_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $11) RETURNING %s`, recurringTable, recurringColumns),
+			r.FromWalletId, r.ToWalletId, r.Amount, r.Schedule, r.StartAt.UTC(), utcOrNil(r.EndAt),
+			r.CatchUp, r.MaxRetries, r.RetryIntervalSeconds, r.Status, utcOrNil(r.OccurrenceAt)))
+		return err
+	})
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23503" { // foreign_key_violation
+			return wallet.RecurringTransfer{}, fmt.Errorf("failed to create recurring transfer: %w", ErrWalletNotFound)
+		}
+		return wallet.RecurringTransfer{}, fmt.Errorf("failed to create recurring transfer: %w", err)
+	}
+	return created, nil
+}
+
+func (s *RecurringPsql) GetRecurring(ctx context.Context, walletID uuid.UUID, id int64) (wallet.RecurringTransfer, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT %s FROM %s WHERE id = $1 AND from_valletId = $2`,
+		recurringColumns, recurringTable), id, walletID)
+	r, err := scanRecurring(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return wallet.RecurringTransfer{}, fmt.Errorf("failed to get recurring transfer %d: %w", id, ErrRecurringNotFound)
+	}
+	if err != nil {
+		return wallet.RecurringTransfer{}, fmt.Errorf("failed to get recurring transfer %d: %w", id, err)
+	}
+	return r, nil
+}
+
+func (s *RecurringPsql) ListRecurring(ctx context.Context, walletID uuid.UUID, limit int) ([]wallet.RecurringTransfer, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`SELECT %s FROM %s WHERE from_valletId = $1 ORDER BY id LIMIT $2`,
+		recurringColumns, recurringTable), walletID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recurring transfers for wallet %s: %w", walletID.UUID.String(), err)
+	}
+	defer rows.Close()
+
+	rules := make([]wallet.RecurringTransfer, 0)
+	for rows.Next() {
+		r, err := scanRecurring(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list recurring transfers for wallet %s: %w", walletID.UUID.String(), err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+func (s *RecurringPsql) ListRecurringRuns(ctx context.Context, walletID uuid.UUID, id int64, afterID int64, limit int) ([]wallet.RecurringRun, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var exists bool
+	err := s.db.GetContext(ctx, &exists, fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE id = $1 AND from_valletId = $2)`,
+		recurringTable), id, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs of recurring transfer %d: %w", id, err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("failed to list runs of recurring transfer %d: %w", id, ErrRecurringNotFound)
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`SELECT %s FROM %s WHERE rule_id = $1 AND id > $2 ORDER BY id LIMIT $3`,
+		recurringRunColumns, recurringRunTable), id, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs of recurring transfer %d: %w", id, err)
+	}
+	defer rows.Close()
+
+	runs := make([]wallet.RecurringRun, 0)
+	for rows.Next() {
+		var run wallet.RecurringRun
+		if err := rows.Scan(&run.Id, &run.RuleId, &run.OccurrenceAt, &run.Attempt, &run.Status, &run.Error,
+			&run.FromTransactionId, &run.ToTransactionId, &run.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to list runs of recurring transfer %d: %w", id, err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+func (s *RecurringPsql) UpdateRecurringStatus(ctx context.Context, walletID uuid.UUID, id int64, from []string, status string,
+	next *time.Time) (wallet.RecurringTransfer, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var r wallet.RecurringTransfer
+	err := s.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		r, err = scanRecurring(tx.QueryRowContext(ctx, fmt.Sprintf(`UPDATE %s SET status = $4, occurrence_at = $5,
+				next_run_at = $5, attempt = 0, updated_at = NOW()
+			WHERE id = $1 AND from_valletId = $2 AND status = ANY($3::text[]) RETURNING %s`, recurringTable, recurringColumns),
+			id, walletID, pq.Array(from), status, utcOrNil(next)))
+		return err
+	})
+	if err == nil {
+		return r, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return wallet.RecurringTransfer{}, fmt.Errorf("failed to update recurring transfer %d: %w", id, err)
+	}
+
+	if _, err := s.GetRecurring(ctx, walletID, id); err != nil {
+		return wallet.RecurringTransfer{}, err
+	}
+	return wallet.RecurringTransfer{}, fmt.Errorf("failed to update recurring transfer %d: %w", id, ErrRecurringStatus)
+}
+
+func (s *RecurringPsql) ClaimRecurring(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]wallet.RecurringTransfer, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Аренда сравнивается в FinishRecurring на равенство, поэтому округляется до точности TIMESTAMP.
+	lockedUntil := now.Add(lease).UTC().Truncate(time.Microsecond)
+	query := fmt.Sprintf(`WITH claimed AS (
+			UPDATE %[1]s SET locked_until = $1
+			WHERE id IN (
+				SELECT id FROM %[1]s WHERE status = 'active' AND next_run_at <= $2 AND (locked_until IS NULL OR locked_until <= $2)
+				ORDER BY next_run_at, id LIMIT $3 FOR UPDATE SKIP LOCKED)
+			RETURNING %[2]s)
+		SELECT * FROM claimed ORDER BY next_run_at, id`, recurringTable, recurringColumns)
+	var rules []wallet.RecurringTransfer
+	err := s.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		rules = nil
+		rows, err := tx.QueryContext(ctx, query, lockedUntil, now.UTC(), limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			r, err := scanRecurring(rows)
+			if err != nil {
+				return err
+			}
+			rules = append(rules, r)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim recurring transfers: %w", err)
+	}
+	return rules, nil
+}
+
+func (s *RecurringPsql) FinishRecurring(ctx context.Context, r wallet.RecurringTransfer, run *wallet.RecurringRun) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if r.LockedUntil == nil {
+		return fmt.Errorf("failed to finish recurring transfer %d: %w", r.Id, ErrRecurringLeaseLost)
+	}
+
+	return s.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		res, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET occurrence_at = $1, next_run_at = $2, attempt = $3,
+				status = CASE WHEN status = 'active' THEN $4 ELSE status END, locked_until = NULL, updated_at = NOW()
+			WHERE id = $5 AND locked_until = $6`, recurringTable),
+			utcOrNil(r.OccurrenceAt), utcOrNil(r.NextRunAt), r.Attempt, r.Status, r.Id, r.LockedUntil.UTC())
+		if err != nil {
+			return fmt.Errorf("failed to finish recurring transfer %d: %w", r.Id, err)
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return fmt.Errorf("failed to finish recurring transfer %d: %w", r.Id, ErrRecurringLeaseLost)
+		}
+
+		if run == nil {
+			return nil
+		}
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (rule_id, occurrence_at, attempt, status, error,
+				from_transaction_id, to_transaction_id) VALUES ($1, $2, $3, $4, $5, $6, $7)`, recurringRunTable),
+			r.Id, run.OccurrenceAt.UTC(), run.Attempt, run.Status, run.Error, run.FromTransactionId, run.ToTransactionId)
+		if err != nil {
+			return fmt.Errorf("failed to record run of recurring transfer %d: %w", r.Id, err)
+		}
+		return nil
+	})
+}
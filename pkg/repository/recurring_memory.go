@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// RecurringMemory - реализация repository.Recurring в памяти процесса (DB_DRIVER=memory).
+type RecurringMemory struct {
+	mu        sync.Mutex
+	rules     map[int64]*wallet.RecurringTransfer
+	runs      map[int64][]wallet.RecurringRun // ID правила -> запуски в порядке id
+	lastID    int64
+	lastRunID int64
+}
+
+func NewRecurringMemory() *RecurringMemory {
+	return &RecurringMemory{
+		rules: make(map[int64]*wallet.RecurringTransfer),
+		runs:  make(map[int64][]wallet.RecurringRun),
+	}
+}
+
+func (m *RecurringMemory) CreateRecurring(ctx context.Context, r wallet.RecurringTransfer) (wallet.RecurringTransfer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastID++
+	now := time.Now()
+	r.Id = m.lastID
+	r.NextRunAt = r.OccurrenceAt
+	r.Attempt = 0
+	r.LockedUntil = nil
+	r.CreatedAt, r.UpdatedAt = now, now
+	m.rules[r.Id] = &r
+	return r, nil
+}
+
+// rule возвращает правило с кошельком-источником walletID. Вызывается под m.mu.
+func (m *RecurringMemory) rule(walletID uuid.UUID, id int64) (*wallet.RecurringTransfer, bool) {
+	r, ok := m.rules[id]
+	if !ok || r.FromWalletId.UUID != walletID.UUID {
+		return nil, false
+	}
+	return r, true
+}
+
+func (m *RecurringMemory) GetRecurring(ctx context.Context, walletID uuid.UUID, id int64) (wallet.RecurringTransfer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.rule(walletID, id)
+	if !ok {
+		return wallet.RecurringTransfer{}, fmt.Errorf("failed to get recurring transfer %d: %w", id, ErrRecurringNotFound)
+	}
+	return *r, nil
+}
+
+func (m *RecurringMemory) ListRecurring(ctx context.Context, walletID uuid.UUID, limit int) ([]wallet.RecurringTransfer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rules := make([]wallet.RecurringTransfer, 0)
+	for _, r := range m.rules {
+		if r.FromWalletId.UUID == walletID.UUID {
+			rules = append(rules, *r)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Id < rules[j].Id })
+	if len(rules) > limit {
+		rules = rules[:limit]
+	}
+	return rules, nil
+}
+
+func (m *RecurringMemory) ListRecurringRuns(ctx context.Context, walletID uuid.UUID, id int64, afterID int64, limit int) ([]wallet.RecurringRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.rule(walletID, id); !ok {
+		return nil, fmt.Errorf("failed to list runs of recurring transfer %d: %w", id, ErrRecurringNotFound)
+	}
+
+	runs := make([]wallet.RecurringRun, 0)
+	for _, run := range m.runs[id] {
+		if run.Id > afterID && len(runs) < limit {
+			runs = append(runs, run)
+		}
+	}
+	return runs, nil
+}
+
+func (m *RecurringMemory) UpdateRecurringStatus(ctx context.Context, walletID uuid.UUID, id int64, from []string, status string,
+	next *time.Time) (wallet.RecurringTransfer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.rule(walletID, id)
+	if !ok {
+		return wallet.RecurringTransfer{}, fmt.Errorf("failed to get recurring transfer %d: %w", id, ErrRecurringNotFound)
+	}
+	if !slices.Contains(from, r.Status) {
+		return wallet.RecurringTransfer{}, fmt.Errorf("failed to update recurring transfer %d: %w", id, ErrRecurringStatus)
+	}
+
+	r.Status = status
+	r.OccurrenceAt, r.NextRunAt = next, next
+	r.Attempt = 0
+	r.UpdatedAt = time.Now()
+	return *r, nil
+}
+
+func (m *RecurringMemory) ClaimRecurring(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]wallet.RecurringTransfer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []*wallet.RecurringTransfer
+	for _, r := range m.rules {
+		if r.Status == wallet.RecurringActive && r.NextRunAt != nil && !r.NextRunAt.After(now) &&
+			(r.LockedUntil == nil || !r.LockedUntil.After(now)) {
+			due = append(due, r)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextRunAt.Equal(*due[j].NextRunAt) {
+			return due[i].NextRunAt.Before(*due[j].NextRunAt)
+		}
+		return due[i].Id < due[j].Id
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]wallet.RecurringTransfer, 0, len(due))
+	for _, r := range due {
+		lockedUntil := now.Add(lease)
+		r.LockedUntil = &lockedUntil
+		claimed = append(claimed, *r)
+	}
+	return claimed, nil
+}
+
+func (m *RecurringMemory) FinishRecurring(ctx context.Context, r wallet.RecurringTransfer, run *wallet.RecurringRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.rules[r.Id]
+	if !ok || r.LockedUntil == nil || existing.LockedUntil == nil || !existing.LockedUntil.Equal(*r.LockedUntil) {
+		return fmt.Errorf("failed to finish recurring transfer %d: %w", r.Id, ErrRecurringLeaseLost)
+	}
+
+	now := time.Now()
+	existing.OccurrenceAt, existing.NextRunAt = r.OccurrenceAt, r.NextRunAt
+	existing.Attempt = r.Attempt
+	if existing.Status == wallet.RecurringActive {
+		existing.Status = r.Status
+	}
+	existing.LockedUntil = nil
+	existing.UpdatedAt = now
+
+	if run != nil {
+		m.lastRunID++
+		recorded := *run
+		recorded.Id = m.lastRunID
+		recorded.RuleId = r.Id
+		recorded.CreatedAt = now
+		m.runs[r.Id] = append(m.runs[r.Id], recorded)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/stretchr/testify/assert"
+	sqlmock "github.com/zhashkevych/go-sqlxmock"
+)
+
+func TestRecurringPsql_FinishRecurring(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		log.Fatalf("unexpected error opening stub db: %s", err)
+	}
+	defer db.Close()
+
+	s := NewRecurringPsql(db, NewTxRunner(db, TxOptions{}))
+	lockedUntil := time.Date(2025, 1, 1, 12, 2, 0, 0, time.UTC)
+	occurrence := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	next := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+	r := wallet.RecurringTransfer{Id: 3, Status: wallet.RecurringActive, OccurrenceAt: &next, NextRunAt: &next, LockedUntil: &lockedUntil}
+	txFrom, txTo := 10, 11
+	run := &wallet.RecurringRun{OccurrenceAt: occurrence, Attempt: 1, Status: wallet.RecurringRunSucceeded,
+		FromTransactionId: &txFrom, ToTransactionId: &txTo}
+
+	update := fmt.Sprintf(`UPDATE %s SET occurrence_at = \$1, next_run_at = \$2, attempt = \$3`, recurringTable)
+	insert := fmt.Sprintf(`INSERT INTO %s`, recurringRunTable)
+
+	testTable := []struct {
+		name        string
+		mockSetup   func()
+		expectedErr error
+	}{
+		{
+			name: "recorded",
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(update).WithArgs(next, next, 0, wallet.RecurringActive, int64(3), lockedUntil).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(insert).WithArgs(int64(3), occurrence, 1, wallet.RecurringRunSucceeded, "", 10, 11).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "lease lost",
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(update).WithArgs(next, next, 0, wallet.RecurringActive, int64(3), lockedUntil).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrRecurringLeaseLost,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			test.mockSetup()
+
+			err := s.FinishRecurring(context.Background(), r, run)
+			if test.expectedErr != nil {
+				assert.ErrorIs(t, err, test.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
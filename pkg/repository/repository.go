@@ -2,24 +2,253 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"math"
+	"time"
 
 	"github.com/KatenkaKet/wallet"
 	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
 	"github.com/jmoiron/sqlx"
 )
 
+var (
+	ErrWalletNotFound    = errors.New("wallet not found")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrVersionMismatch   = errors.New("wallet version mismatch")
+	// ErrDuplicateTransaction - операция с этим ключом идемпотентности уже записана; вместе с ошибкой
+	// возвращается исходная транзакция.
+	ErrDuplicateTransaction = errors.New("duplicate transaction")
+	// ErrCreditLimitBelowDebt - кредитный лимит нельзя сделать меньше текущей задолженности кошелька.
+	ErrCreditLimitBelowDebt = errors.New("credit limit is below current debt")
+	// ErrInvalidBucket - неизвестная корзина кошелька (см. wallet.Buckets).
+	ErrInvalidBucket = errors.New("invalid bucket")
+
+	ErrStatementNotFound = errors.New("statement not found")
+	ErrScheduledNotFound = errors.New("scheduled transaction not found")
+	// ErrScheduledNotPending - отложенная операция уже выполнена, отменена или выполняется прямо сейчас.
+	ErrScheduledNotPending = errors.New("scheduled transaction is not pending")
+	ErrRecurringNotFound   = errors.New("recurring transfer not found")
+	// ErrRecurringStatus - действие недопустимо в текущем статусе правила (например, возобновление активного).
+	ErrRecurringStatus = errors.New("recurring transfer status does not allow this action")
+	// ErrRecurringLeaseLost - аренда правила истекла и его забрал другой обработчик; результат не записан.
+	ErrRecurringLeaseLost   = errors.New("recurring transfer lease lost")
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+)
+
 type Wallet interface {
 	GetBalance(ctx context.Context, uuid uuid.UUID) (float64, error)
-	UpdateBalance(ctx context.Context, uuid uuid.UUID, amount float64) error
-	CreateTransaction(ctx context.Context, WT wallet.WalletTransactions) error
+	GetWallet(ctx context.Context, uuid uuid.UUID) (wallet.Wallet, error)
+	// WalletTiers возвращает уровни кошельков ids одним запросом: кошелёк -> уровень. Неизвестных кошельков в ответе нет.
+	WalletTiers(ctx context.Context, ids []uuid.UUID) (map[string]string, error)
+	// SetTier меняет уровень кошелька (не меняя его версию) и возвращает кошелёк.
+	SetTier(ctx context.Context, uuid uuid.UUID, tier string) (wallet.Wallet, error)
+	// SetCreditLimit меняет кредитный лимит кошелька и его версию (меняется доступная сумма) и возвращает кошелёк.
+	// Если остаток main ниже -limit, ничего не меняет и возвращает ErrCreditLimitBelowDebt.
+	SetCreditLimit(ctx context.Context, uuid uuid.UUID, limit float64) (wallet.Wallet, error)
+	// ApplyTransactions применяет операции одного кошелька по порядку в одной транзакции БД с одной блокировкой строки
+	// и записывает историю. Для каждой операции возвращает записанную транзакцию и ошибку
+	// (nil - применена; ErrInsufficientFunds, ErrVersionMismatch, ErrInvalidBucket - отклонена; ErrDuplicateTransaction - повтор
+	// по ключу идемпотентности, вместо записанной возвращается исходная транзакция),
+	// а также общую ошибку, при которой не применена ни одна операция.
+	// В той же транзакции в outbox записываются события о применённых и отклонённых операциях.
+	ApplyTransactions(ctx context.Context, uuid uuid.UUID, ops []wallet.WalletTransactions) ([]wallet.WalletTransactions, []error, error)
+	// ApplyAtomic применяет операции разных кошельков в одной транзакции БД по принципу "всё или ничего":
+	// строки кошельков блокируются в порядке их ID, и если хоть одна операция отклонена (в том числе из-за
+	// отсутствующего кошелька), не применяется ни одна и событий не пишется. Повторы по ключу идемпотентности
+	// отказом не считаются. Результаты по операциям - как у ApplyTransactions.
+	ApplyAtomic(ctx context.Context, ops []wallet.WalletTransactions) ([]wallet.WalletTransactions, []error, error)
+	// Transfer списывает amount с кошелька from и зачисляет на to в одной транзакции БД; строки кошельков
+	// блокируются в порядке их ID. При нехватке средств возвращает ErrInsufficientFunds и ничего не меняет,
+	// кроме события об отклонённом списании в outbox.
+	Transfer(ctx context.Context, from, to uuid.UUID, amount float64) (wallet.Transfer, error)
+	// ListTransactions возвращает до limit транзакций кошелька с seq > afterSeq в порядке seq.
+	ListTransactions(ctx context.Context, uuid uuid.UUID, afterSeq int64, limit int) ([]wallet.WalletTransactions, error)
+	// ListBucketTransactions возвращает до limit транзакций кошелька с seq > afterSeq, затронувших корзину bucket,
+	// в порядке seq; остаток корзины после каждой - в её записи в Buckets.
+	ListBucketTransactions(ctx context.Context, uuid uuid.UUID, bucket string, afterSeq int64, limit int) ([]wallet.WalletTransactions, error)
+	// PeriodSeq возвращает границы периода [from, to) в истории кошелька: afterSeq - последний seq до from,
+	// lastSeq - последний seq до to (0 - таких транзакций нет), то есть транзакции периода - seq из (afterSeq, lastSeq].
+	// Нулевые from и to - без ограничения.
+	PeriodSeq(ctx context.Context, uuid uuid.UUID, from, to time.Time) (afterSeq, lastSeq int64, err error)
+	// FindIdempotent возвращает уже записанные транзакции с ключами идемпотентности из ops: кошелёк -> ключ -> транзакция.
+	// Без блокировки кошельков, поэтому годится только для предварительной проверки.
+	FindIdempotent(ctx context.Context, ops []wallet.WalletTransactions) (map[string]map[string]wallet.WalletTransactions, error)
+}
+
+type Checkpoint interface {
+	// ChainHeads возвращает головы цепочек хешей кошельков, у которых появились транзакции после последней контрольной точки.
+	ChainHeads(ctx context.Context) ([]wallet.Checkpoint, error)
+	SaveCheckpoints(ctx context.Context, checkpoints []wallet.Checkpoint) error
+	// ListCheckpoints возвращает контрольные точки кошелька в порядке seq.
+	ListCheckpoints(ctx context.Context, uuid uuid.UUID) ([]wallet.Checkpoint, error)
+}
+
+type Statement interface {
+	// PendingStatements возвращает до limit кошельков с ID больше after, у которых были транзакции в периоде
+	// [from, to), но ещё нет выписки за период, начинающийся с from. Кошельки - в порядке ID.
+	PendingStatements(ctx context.Context, from, to time.Time, after uuid.UUID, limit int) ([]uuid.UUID, error)
+	// SaveStatement сохраняет выписку; повторная запись выписки за тот же период не является ошибкой.
+	SaveStatement(ctx context.Context, st wallet.Statement) error
+	// ListStatements возвращает выписки кошелька, последние первыми.
+	ListStatements(ctx context.Context, uuid uuid.UUID) ([]wallet.Statement, error)
+	// GetStatement возвращает выписку кошелька за период, начинающийся с from, или ErrStatementNotFound.
+	GetStatement(ctx context.Context, uuid uuid.UUID, from time.Time) (wallet.Statement, error)
+}
+
+type Stats interface {
+	// TransactionStats возвращает итоги операций за период [from, to) по интервалам interval (wallet.StatsDay и т.д.)
+	// в порядке начала интервала; интервалы без операций не возвращаются.
+	// walletID без значения (Status не Present) - по всем кошелькам.
+	TransactionStats(ctx context.Context, walletID uuid.UUID, from, to time.Time, interval string) ([]wallet.StatsBucket, error)
+}
+
+type Scheduled interface {
+	CreateScheduled(ctx context.Context, st wallet.ScheduledTransaction) (wallet.ScheduledTransaction, error)
+	// ListScheduled возвращает до limit отложенных операций кошелька в порядке execute_at; пустой status - в любом статусе.
+	ListScheduled(ctx context.Context, walletID uuid.UUID, status string, limit int) ([]wallet.ScheduledTransaction, error)
+	// ClaimScheduled забирает до limit ожидающих операций, время которых наступило, в порядке execute_at и
+	// закрепляет их за обработчиком на lease; при падении обработчика операция вернётся в очередь после lease.
+	ClaimScheduled(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]wallet.ScheduledTransaction, error)
+	// FinishScheduled записывает результат ожидающей операции: Status, Error, TransactionId и FinishedAt из st.
+	FinishScheduled(ctx context.Context, st wallet.ScheduledTransaction) error
+	// CancelScheduled отменяет ожидающую операцию кошелька, если её не выполняет обработчик.
+	CancelScheduled(ctx context.Context, walletID uuid.UUID, id int64, now time.Time) (wallet.ScheduledTransaction, error)
+}
+
+type Recurring interface {
+	CreateRecurring(ctx context.Context, r wallet.RecurringTransfer) (wallet.RecurringTransfer, error)
+	// GetRecurring возвращает правило с кошельком-источником walletID.
+	GetRecurring(ctx context.Context, walletID uuid.UUID, id int64) (wallet.RecurringTransfer, error)
+	// ListRecurring возвращает до limit правил с кошельком-источником walletID в порядке id.
+	ListRecurring(ctx context.Context, walletID uuid.UUID, limit int) ([]wallet.RecurringTransfer, error)
+	// ListRecurringRuns возвращает до limit запусков правила с id больше afterID в порядке id.
+	ListRecurringRuns(ctx context.Context, walletID uuid.UUID, id int64, afterID int64, limit int) ([]wallet.RecurringRun, error)
+	// UpdateRecurringStatus переводит правило из одного из статусов from в status с новым сроком next
+	// (nil - без срока) и обнуляет счётчик попыток. Аренда обработчика не снимается: начатый перевод завершится.
+	UpdateRecurringStatus(ctx context.Context, walletID uuid.UUID, id int64, from []string, status string,
+		next *time.Time) (wallet.RecurringTransfer, error)
+	// ClaimRecurring забирает до limit активных правил, срок попытки которых наступил, в порядке next_run_at
+	// и закрепляет их за обработчиком на lease (LockedUntil в результате).
+	ClaimRecurring(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]wallet.RecurringTransfer, error)
+	// FinishRecurring записывает запуск run (если не nil) и новое состояние расписания из r: OccurrenceAt,
+	// NextRunAt, Attempt и Status, снимая аренду. Если правило уже не закреплено за обработчиком с арендой
+	// r.LockedUntil, ничего не записывается (ErrRecurringLeaseLost). Приостановленное или отменённое
+	// за это время правило сохраняет свой статус.
+	FinishRecurring(ctx context.Context, r wallet.RecurringTransfer, run *wallet.RecurringRun) error
+}
+
+// Interest - проценты на остаток: дневные начисления в накопитель кошелька и их ежемесячная капитализация.
+type Interest interface {
+	// SetProduct меняет процентный продукт кошелька (пустой - проценты не начисляются) и возвращает кошелёк.
+	SetProduct(ctx context.Context, uuid uuid.UUID, product string) (wallet.Wallet, error)
+	// LastInterestDay возвращает последний день, за который выполнено начисление (нулевое время - начислений
+	// ещё не было), и капитализированы ли проценты за месяц, закончившийся этим днём.
+	LastInterestDay(ctx context.Context) (day time.Time, capitalised bool, err error)
+	// AccrueInterest в одной транзакции БД начисляет проценты за день day кошелькам с продуктами из rates
+	// (продукт -> дневная ставка) и положительным остатком на конец дня и добавляет их в накопитель кошелька.
+	// Остаток на конец дня восстанавливается по истории транзакций. День отмечается выполненным; повторный вызов
+	// за тот же день ничего не начисляет. Возвращает число начислений.
+	AccrueInterest(ctx context.Context, day time.Time, rates map[string]float64) (int, error)
+	// PendingInterest возвращает до limit кошельков с ID больше after, в накопителе которых не меньше копейки,
+	// в порядке ID.
+	PendingInterest(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error)
+	// CapitaliseInterest зачисляет на кошелёк целые копейки из накопителя операцией DEPOSIT вида wallet.KindInterest
+	// с ключом идемпотентности key и уменьшает накопитель на ту же сумму в одной транзакции БД; остаток меньше
+	// копейки переходит на следующий месяц. Если копейки не набралось, возвращает транзакцию с нулевым Id.
+	// Повтор по ключу возвращает исходную транзакцию и ErrDuplicateTransaction и накопитель не меняет.
+	CapitaliseInterest(ctx context.Context, uuid uuid.UUID, key string) (wallet.WalletTransactions, error)
+	// MarkCapitalised отмечает, что проценты за месяц, закончившийся днём day, капитализированы.
+	MarkCapitalised(ctx context.Context, day time.Time) error
+	// ListInterestAccruals возвращает начисления кошелька за дни из [from, to) в порядке дня.
+	ListInterestAccruals(ctx context.Context, uuid uuid.UUID, from, to time.Time) ([]wallet.InterestAccrual, error)
+}
+
+type Webhook interface {
+	CreateSubscription(ctx context.Context, sub wallet.WebhookSubscription) (wallet.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]wallet.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id int64) error
+	// EnqueueEvent создаёт доставку события каждой подходящей подписке и возвращает число новых доставок.
+	// Для уже поставленного в очередь события (по event.Id) доставки повторно не создаются.
+	EnqueueEvent(ctx context.Context, event wallet.WalletEvent, payload []byte, now time.Time) (int, error)
+	// ClaimDeliveries забирает до limit доставок, время которых наступило, и откладывает их на lease,
+	// чтобы параллельные обработчики не взяли те же доставки; при падении обработчика доставка вернётся после lease.
+	ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]wallet.WebhookDelivery, error)
+	CompleteDelivery(ctx context.Context, id int64, attempts int, now time.Time) error
+	// FailDelivery записывает неудачную попытку: доставка повторится в nextAttemptAt или, если dead, уходит в dead-letter.
+	FailDelivery(ctx context.Context, id int64, attempts int, lastError string, nextAttemptAt time.Time, dead bool) error
+	ListDeadDeliveries(ctx context.Context, limit int) ([]wallet.WebhookDelivery, error)
+	// ReplayDelivery возвращает доставку из dead-letter в очередь с обнулённым счётчиком попыток.
+	ReplayDelivery(ctx context.Context, id int64, now time.Time) error
+}
+
+// Outbox - события об операциях, записанные ApplyTransactions в той же транзакции БД, что и изменение баланса.
+type Outbox interface {
+	// RelayOutbox передаёт publish до limit неопубликованных событий в порядке записи и отмечает опубликованными
+	// события, id которых вернул publish. Одновременно outbox разбирает только один вызов на все экземпляры
+	// сервиса, остальные сразу возвращают 0. Возвращает число опубликованных событий.
+	RelayOutbox(ctx context.Context, limit int, publish func(ctx context.Context, events []wallet.OutboxEvent) []int64) (int, error)
+	// PurgeOutbox удаляет события, опубликованные раньше before.
+	PurgeOutbox(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Changes - уведомления о зафиксированных изменениях кошельков, в том числе сделанных другими экземплярами сервиса.
+type Changes interface {
+	// ListenChanges вызывает notify с кошельком и его новым last_seq после каждого изменения, пока не отменён ctx.
+	// Если уведомления могли быть потеряны (переподключение к БД), notify вызывается с пустым walletID.
+	// notify не должна блокироваться.
+	ListenChanges(ctx context.Context, notify func(walletID uuid.UUID, seq int64)) error
 }
 
 type Repository struct {
 	Wallet
+	Checkpoint
+	Statement
+	Stats
+	Scheduled
+	Recurring
+	Interest
+	Webhook
+	Outbox
+	Changes
 }
 
-func NewRepository(db *sqlx.DB) *Repository {
+// NewRepository собирает репозиторий поверх Postgres; dsn нужен для отдельного соединения LISTEN.
+func NewRepository(db *sqlx.DB, dsn string, txOpts TxOptions) *Repository {
+	tx := NewTxRunner(db, txOpts)
+	wallets := NewWalletPsql(db, tx)
+
 	return &Repository{
-		Wallet: NewWalletPsql(db),
+		Wallet:     wallets,
+		Checkpoint: NewCheckpointPsql(db, tx),
+		Statement:  NewStatementPsql(db, tx),
+		Stats:      NewStatsPsql(db),
+		Scheduled:  NewScheduledPsql(db, tx),
+		Recurring:  NewRecurringPsql(db, tx),
+		Interest:   wallets,
+		Webhook:    NewWebhookPsql(db, tx),
+		Outbox:     wallets,
+		Changes:    NewChangesPsql(dsn),
 	}
 }
+
+// NewMemoryRepository собирает репозиторий поверх хранилища в памяти (DB_DRIVER=memory).
+func NewMemoryRepository(mem *WalletMemory) *Repository {
+	return &Repository{
+		Wallet:     mem,
+		Checkpoint: mem,
+		Statement:  mem,
+		Stats:      mem,
+		Scheduled:  NewScheduledMemory(),
+		Recurring:  NewRecurringMemory(),
+		Interest:   mem,
+		Webhook:    NewWebhookMemory(),
+		Outbox:     mem,
+		Changes:    mem,
+	}
+}
+
+// roundAmount округляет сумму до копеек, как это делает NUMERIC(18, 2).
+func roundAmount(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type ScheduledPsql struct {
+	db *sqlx.DB
+	tx *TxRunner
+}
+
+func NewScheduledPsql(db *sqlx.DB, tx *TxRunner) *ScheduledPsql {
+	return &ScheduledPsql{db: db, tx: tx}
+}
+
+const scheduledColumns = `id, valletId, operation_type, amount, execute_at, status, error, transaction_id, created_at, finished_at`
+
+func scanScheduled(row interface{ Scan(dest ...any) error }) (wallet.ScheduledTransaction, error) {
+	var st wallet.ScheduledTransaction
+	err := row.Scan(&st.Id, &st.ValletId, &st.OperationType, &st.Amount, &st.ExecuteAt, &st.Status, &st.Error,
+		&st.TransactionId, &st.CreatedAt, &st.FinishedAt)
+	return st, err
+}
+
+func (s *ScheduledPsql) CreateScheduled(ctx context.Context, st wallet.ScheduledTransaction) (wallet.ScheduledTransaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var created wallet.ScheduledTransaction
+	err := s.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		created, err = scanScheduled(tx.QueryRowContext(ctx, fmt.Sprintf(`INSERT INTO %s (valletId, operation_type, amount, execute_at)
+			VALUES ($1, $2, $3, $4) RETURNING %s`, scheduledTable, scheduledColumns),
+			st.ValletId, st.OperationType, st.Amount, st.ExecuteAt.UTC()))
+		return err
+	})
+	if err != nil {
+		return wallet.ScheduledTransaction{}, fmt.Errorf("failed to schedule transaction for wallet %s: %w", st.ValletId.UUID.String(), err)
+	}
+	return created, nil
+}
+
+func (s *ScheduledPsql) ListScheduled(ctx context.Context, walletID uuid.UUID, status string, limit int) ([]wallet.ScheduledTransaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`SELECT %s FROM %s WHERE valletId = $1 AND ($2 = '' OR status = $2)
+		ORDER BY execute_at, id LIMIT $3`, scheduledColumns, scheduledTable), walletID, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scheduled transactions for wallet %s: %w", walletID.UUID.String(), err)
+	}
+	defer rows.Close()
+
+	scheduled := make([]wallet.ScheduledTransaction, 0)
+	for rows.Next() {
+		st, err := scanScheduled(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list scheduled transactions for wallet %s: %w", walletID.UUID.String(), err)
+		}
+		scheduled = append(scheduled, st)
+	}
+	return scheduled, rows.Err()
+}
+
+func (s *ScheduledPsql) ClaimScheduled(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]wallet.ScheduledTransaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// SKIP LOCKED позволяет нескольким экземплярам сервиса разбирать очередь параллельно.
+	query := fmt.Sprintf(`WITH claimed AS (
+			UPDATE %[1]s SET locked_until = $1
+			WHERE id IN (
+				SELECT id FROM %[1]s WHERE status = 'pending' AND execute_at <= $2 AND (locked_until IS NULL OR locked_until <= $2)
+				ORDER BY execute_at, id LIMIT $3 FOR UPDATE SKIP LOCKED)
+			RETURNING %[2]s)
+		SELECT * FROM claimed ORDER BY execute_at, id`, scheduledTable, scheduledColumns)
+	var scheduled []wallet.ScheduledTransaction
+	err := s.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		scheduled = nil
+		rows, err := tx.QueryContext(ctx, query, now.Add(lease).UTC(), now.UTC(), limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			st, err := scanScheduled(rows)
+			if err != nil {
+				return err
+			}
+			scheduled = append(scheduled, st)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim scheduled transactions: %w", err)
+	}
+	return scheduled, nil
+}
+
+func (s *ScheduledPsql) FinishScheduled(ctx context.Context, st wallet.ScheduledTransaction) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var finishedAt any
+	if st.FinishedAt != nil {
+		finishedAt = st.FinishedAt.UTC()
+	}
+
+	err := s.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		res, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET status = $1, error = $2, transaction_id = $3, finished_at = $4,
+			locked_until = NULL WHERE id = $5 AND status = 'pending'`, scheduledTable),
+			st.Status, st.Error, st.TransactionId, finishedAt, st.Id)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return ErrScheduledNotPending
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to finish scheduled transaction %d: %w", st.Id, err)
+	}
+	return nil
+}
+
+func (s *ScheduledPsql) CancelScheduled(ctx context.Context, walletID uuid.UUID, id int64, now time.Time) (wallet.ScheduledTransaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var st wallet.ScheduledTransaction
+	err := s.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		st, err = scanScheduled(tx.QueryRowContext(ctx, fmt.Sprintf(`UPDATE %s SET status = 'cancelled', finished_at = $3
+			WHERE id = $1 AND valletId = $2 AND status = 'pending' AND (locked_until IS NULL OR locked_until <= $3)
+			RETURNING %s`, scheduledTable, scheduledColumns), id, walletID, now.UTC()))
+		return err
+	})
+	if err == nil {
+		return st, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return wallet.ScheduledTransaction{}, fmt.Errorf("failed to cancel scheduled transaction %d: %w", id, err)
+	}
+
+	var status string
+	err = s.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT status FROM %s WHERE id = $1 AND valletId = $2`, scheduledTable),
+		id, walletID).Scan(&status)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return wallet.ScheduledTransaction{}, fmt.Errorf("failed to cancel scheduled transaction %d: %w", id, ErrScheduledNotFound)
+	case err != nil:
+		return wallet.ScheduledTransaction{}, fmt.Errorf("failed to cancel scheduled transaction %d: %w", id, err)
+	default:
+		return wallet.ScheduledTransaction{}, fmt.Errorf("failed to cancel scheduled transaction %d: %w", id, ErrScheduledNotPending)
+	}
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// ScheduledMemory - реализация repository.Scheduled в памяти процесса (DB_DRIVER=memory).
+type ScheduledMemory struct {
+	mu          sync.Mutex
+	scheduled   map[int64]*wallet.ScheduledTransaction
+	lockedUntil map[int64]time.Time
+	lastID      int64
+}
+
+func NewScheduledMemory() *ScheduledMemory {
+	return &ScheduledMemory{
+		scheduled:   make(map[int64]*wallet.ScheduledTransaction),
+		lockedUntil: make(map[int64]time.Time),
+	}
+}
+
+func (m *ScheduledMemory) CreateScheduled(ctx context.Context, st wallet.ScheduledTransaction) (wallet.ScheduledTransaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastID++
+	st.Id = m.lastID
+	st.Status = wallet.ScheduledPending
+	st.Error = ""
+	st.TransactionId = nil
+	st.FinishedAt = nil
+	st.CreatedAt = time.Now()
+	m.scheduled[st.Id] = &st
+	return st, nil
+}
+
+// sorted возвращает операции, для которых keep возвращает true, в порядке execute_at, id.
+func (m *ScheduledMemory) sorted(keep func(st *wallet.ScheduledTransaction) bool) []*wallet.ScheduledTransaction {
+	var found []*wallet.ScheduledTransaction
+	for _, st := range m.scheduled {
+		if keep(st) {
+			found = append(found, st)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if !found[i].ExecuteAt.Equal(found[j].ExecuteAt) {
+			return found[i].ExecuteAt.Before(found[j].ExecuteAt)
+		}
+		return found[i].Id < found[j].Id
+	})
+	return found
+}
+
+func (m *ScheduledMemory) ListScheduled(ctx context.Context, walletID uuid.UUID, status string, limit int) ([]wallet.ScheduledTransaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	found := m.sorted(func(st *wallet.ScheduledTransaction) bool {
+		return st.ValletId.UUID == walletID.UUID && (status == "" || st.Status == status)
+	})
+	if len(found) > limit {
+		found = found[:limit]
+	}
+
+	scheduled := make([]wallet.ScheduledTransaction, 0, len(found))
+	for _, st := range found {
+		scheduled = append(scheduled, *st)
+	}
+	return scheduled, nil
+}
+
+// claimable сообщает, что операция ожидает выполнения и не закреплена за обработчиком на момент now.
+func (m *ScheduledMemory) claimable(st *wallet.ScheduledTransaction, now time.Time) bool {
+	return st.Status == wallet.ScheduledPending && !m.lockedUntil[st.Id].After(now)
+}
+
+func (m *ScheduledMemory) ClaimScheduled(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]wallet.ScheduledTransaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	due := m.sorted(func(st *wallet.ScheduledTransaction) bool {
+		return m.claimable(st, now) && !st.ExecuteAt.After(now)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]wallet.ScheduledTransaction, 0, len(due))
+	for _, st := range due {
+		m.lockedUntil[st.Id] = now.Add(lease)
+		claimed = append(claimed, *st)
+	}
+	return claimed, nil
+}
+
+func (m *ScheduledMemory) FinishScheduled(ctx context.Context, st wallet.ScheduledTransaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.scheduled[st.Id]
+	if !ok || existing.Status != wallet.ScheduledPending {
+		return fmt.Errorf("failed to finish scheduled transaction %d: %w", st.Id, ErrScheduledNotPending)
+	}
+	existing.Status = st.Status
+	existing.Error = st.Error
+	existing.TransactionId = st.TransactionId
+	existing.FinishedAt = st.FinishedAt
+	delete(m.lockedUntil, st.Id)
+	return nil
+}
+
+func (m *ScheduledMemory) CancelScheduled(ctx context.Context, walletID uuid.UUID, id int64, now time.Time) (wallet.ScheduledTransaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st, ok := m.scheduled[id]
+	if !ok || st.ValletId.UUID != walletID.UUID {
+		return wallet.ScheduledTransaction{}, fmt.Errorf("failed to cancel scheduled transaction %d: %w", id, ErrScheduledNotFound)
+	}
+	if !m.claimable(st, now) {
+		return wallet.ScheduledTransaction{}, fmt.Errorf("failed to cancel scheduled transaction %d: %w", id, ErrScheduledNotPending)
+	}
+
+	st.Status = wallet.ScheduledCancelled
+	st.FinishedAt = &now
+	return *st, nil
+}
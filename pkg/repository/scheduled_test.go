@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	sqlmock "github.com/zhashkevych/go-sqlxmock"
+)
+
+func TestScheduledPsql_CancelScheduled(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		log.Fatalf("unexpected error opening stub db: %s", err)
+	}
+	defer db.Close()
+
+	s := NewScheduledPsql(db, NewTxRunner(db, TxOptions{}))
+	uid := uuidFromString("11111111-1111-1111-1111-111111111111")
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	update := fmt.Sprintf(`UPDATE %s SET status = 'cancelled', finished_at = \$3 WHERE id = \$1 AND valletId = \$2 AND status = 'pending'`, scheduledTable)
+	lookup := fmt.Sprintf(`SELECT status FROM %s WHERE id = \$1 AND valletId = \$2`, scheduledTable)
+
+	testTable := []struct {
+		name        string
+		mockSetup   func()
+		expectedErr error
+	}{
+		{
+			name: "cancelled",
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(update).WithArgs(int64(7), uid.UUID.String(), now).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "valletId", "operation_type", "amount", "execute_at", "status",
+						"error", "transaction_id", "created_at", "finished_at"}).
+						AddRow(7, uid.UUID.String(), "DEPOSIT", 5.0, now.Add(time.Hour), "cancelled", "", nil, now.Add(-time.Hour), now))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "not found",
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(update).WithArgs(int64(7), uid.UUID.String(), now).WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+				mock.ExpectQuery(lookup).WithArgs(int64(7), uid.UUID.String()).WillReturnError(sql.ErrNoRows)
+			},
+			expectedErr: ErrScheduledNotFound,
+		},
+		{
+			name: "already executed",
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(update).WithArgs(int64(7), uid.UUID.String(), now).WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+				mock.ExpectQuery(lookup).WithArgs(int64(7), uid.UUID.String()).
+					WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("succeeded"))
+			},
+			expectedErr: ErrScheduledNotPending,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			test.mockSetup()
+
+			st, err := s.CancelScheduled(context.Background(), uid, 7, now)
+			if test.expectedErr != nil {
+				assert.ErrorIs(t, err, test.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "cancelled", st.Status)
+				assert.Nil(t, st.TransactionId)
+				assert.NotNil(t, st.FinishedAt)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type StatementPsql struct {
+	db *sqlx.DB
+	tx *TxRunner
+}
+
+func NewStatementPsql(db *sqlx.DB, tx *TxRunner) *StatementPsql {
+	return &StatementPsql{db: db, tx: tx}
+}
+
+func (s *StatementPsql) PendingStatements(ctx context.Context, from, to time.Time, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	// created_at хранится без часового пояса в UTC, см. PeriodSeq.
+	query := fmt.Sprintf(`SELECT DISTINCT t.valletId FROM %s t
+		WHERE t.created_at >= $1::timestamp AND t.created_at < $2::timestamp AND t.valletId > $3
+			AND NOT EXISTS (SELECT 1 FROM %s s WHERE s.valletId = t.valletId AND s.period = $1::date)
+		ORDER BY t.valletId LIMIT $4`, walletTRXTable, statementTable)
+	rows, err := s.db.QueryContext(ctx, query, from.UTC(), to.UTC(), after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find pending statements: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to find pending statements: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (s *StatementPsql) SaveStatement(ctx context.Context, st wallet.Statement) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	err := s.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (valletId, period, opening_balance, closing_balance,
+				deposits_count, deposits_sum, withdrawals_count, withdrawals_sum, formats)
+			VALUES ($1, $2::date, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (valletId, period) DO NOTHING`, statementTable),
+			st.ValletId, st.From.UTC(), st.OpeningBalance, st.ClosingBalance,
+			st.Deposits.Count, st.Deposits.Sum, st.Withdrawals.Count, st.Withdrawals.Sum, pq.Array(st.Formats))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save statement for wallet %s: %w", st.ValletId.UUID.String(), err)
+	}
+	return nil
+}
+
+const statementColumns = `valletId, period, opening_balance, closing_balance,
+	deposits_count, deposits_sum, withdrawals_count, withdrawals_sum, formats, created_at`
+
+func (s *StatementPsql) ListStatements(ctx context.Context, uid uuid.UUID) ([]wallet.Statement, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`SELECT %s FROM %s WHERE valletId = $1 ORDER BY period DESC`,
+		statementColumns, statementTable), uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statements for wallet %s: %w", uid.UUID.String(), err)
+	}
+	defer rows.Close()
+
+	statements := make([]wallet.Statement, 0)
+	for rows.Next() {
+		st, err := scanStatement(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list statements for wallet %s: %w", uid.UUID.String(), err)
+		}
+		statements = append(statements, st)
+	}
+	return statements, rows.Err()
+}
+
+func (s *StatementPsql) GetStatement(ctx context.Context, uid uuid.UUID, from time.Time) (wallet.Statement, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT %s FROM %s WHERE valletId = $1 AND period = $2::date`,
+		statementColumns, statementTable), uid, from.UTC())
+	st, err := scanStatement(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return wallet.Statement{}, fmt.Errorf("failed to get statement for wallet %s: %w", uid.UUID.String(), ErrStatementNotFound)
+	}
+	if err != nil {
+		return wallet.Statement{}, fmt.Errorf("failed to get statement for wallet %s: %w", uid.UUID.String(), err)
+	}
+	return st, nil
+}
+
+func scanStatement(row interface{ Scan(dest ...any) error }) (wallet.Statement, error) {
+	var st wallet.Statement
+	err := row.Scan(&st.ValletId, &st.From, &st.OpeningBalance, &st.ClosingBalance,
+		&st.Deposits.Count, &st.Deposits.Sum, &st.Withdrawals.Count, &st.Withdrawals.Sum, pq.Array(&st.Formats), &st.CreatedAt)
+	if err != nil {
+		return st, err
+	}
+	setStatementPeriod(&st)
+	return st, nil
+}
+
+// setStatementPeriod заполняет Period и To по началу периода From.
+func setStatementPeriod(st *wallet.Statement) {
+	st.From = st.From.UTC()
+	st.Period = st.From.Format("2006-01")
+	st.To = st.From.AddDate(0, 1, 0)
+}
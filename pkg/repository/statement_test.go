@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	sqlmock "github.com/zhashkevych/go-sqlxmock"
+)
+
+func TestStatementPsql_SaveStatement(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		log.Fatalf("unexpected error opening stub db: %s", err)
+	}
+	defer db.Close()
+
+	s := NewStatementPsql(db, NewTxRunner(db, TxOptions{}))
+	uid := uuidFromString("11111111-1111-1111-1111-111111111111")
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(fmt.Sprintf(`INSERT INTO %s .+ ON CONFLICT \(valletId, period\) DO NOTHING`, statementTable)).
+		WithArgs(uid.UUID.String(), from, 10.0, 15.0, int64(2), 7.0, int64(1), 2.0, pq.Array([]string{"html", "csv"})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = s.SaveStatement(context.Background(), wallet.Statement{
+		ValletId:       uid,
+		From:           from,
+		OpeningBalance: 10,
+		ClosingBalance: 15,
+		Deposits:       wallet.StatementTotal{Count: 2, Sum: 7},
+		Withdrawals:    wallet.StatementTotal{Count: 1, Sum: 2},
+		Formats:        []string{"html", "csv"},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStatementPsql_GetStatement(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		log.Fatalf("unexpected error opening stub db: %s", err)
+	}
+	defer db.Close()
+
+	s := NewStatementPsql(db, NewTxRunner(db, TxOptions{}))
+	uid := uuidFromString("11111111-1111-1111-1111-111111111111")
+	from := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	query := fmt.Sprintf(`SELECT .+ FROM %s WHERE valletId = \$1 AND period = \$2::date`, statementTable)
+
+	t.Run("found", func(t *testing.T) {
+		mock.ExpectQuery(query).WithArgs(uid.UUID.String(), from).
+			WillReturnRows(sqlmock.NewRows([]string{"valletId", "period", "opening_balance", "closing_balance",
+				"deposits_count", "deposits_sum", "withdrawals_count", "withdrawals_sum", "formats", "created_at"}).
+				AddRow(uid.UUID.String(), from, 10.0, 15.0, 2, 7.0, 1, 2.0, "{html,csv}", from.AddDate(0, 1, 0)))
+
+		st, err := s.GetStatement(context.Background(), uid, from)
+		assert.NoError(t, err)
+		assert.Equal(t, "2025-02", st.Period)
+		assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), st.To)
+		assert.Equal(t, []string{"html", "csv"}, st.Formats)
+		assert.Equal(t, wallet.StatementTotal{Count: 2, Sum: 7}, st.Deposits)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectQuery(query).WithArgs(uid.UUID.String(), from).WillReturnError(sql.ErrNoRows)
+
+		_, err := s.GetStatement(context.Background(), uid, from)
+		assert.ErrorIs(t, err, ErrStatementNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
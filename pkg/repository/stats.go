@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/jackc/pgtype"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// StatsPsql считает статистику по дневным итогам wallet_daily_stats, если границы периода совпадают с началом дня (UTC),
+// и по самим транзакциям - если нет.
+type StatsPsql struct {
+	db *sqlx.DB
+}
+
+func NewStatsPsql(db *sqlx.DB) *StatsPsql {
+	return &StatsPsql{db: db}
+}
+
+func (s *StatsPsql) TransactionStats(ctx context.Context, walletID uuid.UUID, from, to time.Time, interval string) ([]wallet.StatsBucket, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var query string
+	if isDayStart(from) && isDayStart(to) {
+		query = fmt.Sprintf(`SELECT date_trunc($3, day::timestamp) AS bucket,
+			SUM(deposits_count), SUM(deposits_sum), SUM(withdrawals_count), SUM(withdrawals_sum)
+			FROM %s WHERE day >= $1::date AND day < $2::date`, dailyStatsTable)
+	} else {
+		// created_at хранится без часового пояса в UTC, см. PeriodSeq.
+		query = fmt.Sprintf(`SELECT date_trunc($3, created_at) AS bucket,
+			COUNT(*) FILTER (WHERE operation_type = 'DEPOSIT'),
+			COALESCE(SUM(amount) FILTER (WHERE operation_type = 'DEPOSIT'), 0),
+			COUNT(*) FILTER (WHERE operation_type = 'WITHDRAW'),
+			COALESCE(SUM(amount) FILTER (WHERE operation_type = 'WITHDRAW'), 0)
+			FROM %s WHERE created_at >= $1::timestamp AND created_at < $2::timestamp`, walletTRXTable)
+	}
+
+	args := []any{from.UTC(), to.UTC(), interval}
+	if walletID.Status == pgtype.Present {
+		query += " AND valletId = $4"
+		args = append(args, walletID)
+	}
+	query += " GROUP BY bucket ORDER BY bucket"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction stats: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := make([]wallet.StatsBucket, 0)
+	for rows.Next() {
+		var b wallet.StatsBucket
+		if err := rows.Scan(&b.Start, &b.Deposits.Count, &b.Deposits.Sum, &b.Withdrawals.Count, &b.Withdrawals.Sum); err != nil {
+			return nil, fmt.Errorf("failed to get transaction stats: %w", err)
+		}
+		b.Start = b.Start.UTC()
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+func isDayStart(t time.Time) bool {
+	return t.UTC().Equal(wallet.StatsBucketStart(t, wallet.StatsDay))
+}
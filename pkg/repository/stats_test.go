@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/stretchr/testify/assert"
+	sqlmock "github.com/zhashkevych/go-sqlxmock"
+)
+
+func TestStatsPsql_TransactionStats(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		log.Fatalf("unexpected error opening stub db: %s", err)
+	}
+	defer db.Close()
+
+	s := NewStatsPsql(db)
+	uid := uuidFromString("11111111-1111-1111-1111-111111111111")
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	columns := []string{"bucket", "deposits_count", "deposits_sum", "withdrawals_count", "withdrawals_sum"}
+
+	t.Run("day-aligned period uses daily rollup", func(t *testing.T) {
+		mock.ExpectQuery(fmt.Sprintf(`SELECT date_trunc\(\$3, day::timestamp\) .+ FROM %s WHERE day >= \$1::date AND day < \$2::date AND valletId = \$4 GROUP BY bucket ORDER BY bucket`, dailyStatsTable)).
+			WithArgs(jan, jan.AddDate(0, 1, 0), wallet.StatsWeek, uid.UUID.String()).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(jan.AddDate(0, 0, -2), 2, 7.5, 0, 0).
+				AddRow(jan.AddDate(0, 0, 5), 1, 1, 3, 4.25))
+
+		buckets, err := s.TransactionStats(context.Background(), uid, jan, jan.AddDate(0, 1, 0), wallet.StatsWeek)
+		assert.NoError(t, err)
+		assert.Equal(t, []wallet.StatsBucket{
+			{Start: jan.AddDate(0, 0, -2), Deposits: wallet.StatementTotal{Count: 2, Sum: 7.5}},
+			{Start: jan.AddDate(0, 0, 5), Deposits: wallet.StatementTotal{Count: 1, Sum: 1}, Withdrawals: wallet.StatementTotal{Count: 3, Sum: 4.25}},
+		}, buckets)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("other periods use transactions", func(t *testing.T) {
+		from := jan.Add(90 * time.Minute)
+		mock.ExpectQuery(fmt.Sprintf(`SELECT date_trunc\(\$3, created_at\) .+ FROM %s WHERE created_at >= \$1::timestamp AND created_at < \$2::timestamp GROUP BY bucket ORDER BY bucket`, walletTRXTable)).
+			WithArgs(from, jan.AddDate(0, 0, 1), wallet.StatsDay).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		buckets, err := s.TransactionStats(context.Background(), uuid.UUID{}, from, jan.AddDate(0, 0, 1), wallet.StatsDay)
+		assert.NoError(t, err)
+		assert.Empty(t, buckets)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
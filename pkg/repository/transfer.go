@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+func (w *WalletPsql) Transfer(ctx context.Context, from, to uuid.UUID, amount float64) (wallet.Transfer, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var (
+		transfer wallet.Transfer
+		opErr    error
+	)
+	err := w.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		states, err := lockWallets(ctx, tx, from, to)
+		if err != nil {
+			return err
+		}
+		src, dst := states[from.UUID.String()], states[to.UUID.String()]
+		if src == nil {
+			return fmt.Errorf("failed to lock wallet %s: %w", from.UUID.String(), ErrWalletNotFound)
+		}
+		if dst == nil {
+			return fmt.Errorf("failed to lock wallet %s: %w", to.UUID.String(), ErrWalletNotFound)
+		}
+
+		debit := []wallet.WalletTransactions{{ValletId: from, OperationType: "WITHDRAW", Amount: amount}}
+		credit := []wallet.WalletTransactions{{ValletId: to, OperationType: "DEPOSIT", Amount: amount}}
+		now := time.Now().UTC()
+
+		debited, results, _ := src.apply(from, debit, nil)
+		if opErr = results[0]; opErr != nil {
+			// Отклонённый перевод оставляет только событие об отклонённом списании.
+			events, err := walletEvents(from, debit, debited, results, now)
+			if err != nil {
+				return err
+			}
+			return insertOutbox(ctx, tx, events)
+		}
+		credited, creditResults, _ := dst.apply(to, credit, nil)
+		if creditResults[0] != nil {
+			return creditResults[0]
+		}
+
+		if err := recordTransactions(ctx, tx, from, *src, debited, []int{0}); err != nil {
+			return err
+		}
+		if err := recordTransactions(ctx, tx, to, *dst, credited, []int{0}); err != nil {
+			return err
+		}
+
+		events, err := walletEvents(from, debit, debited, results, now)
+		if err != nil {
+			return err
+		}
+		creditEvents, err := walletEvents(to, credit, credited, creditResults, now)
+		if err != nil {
+			return err
+		}
+		if err := insertOutbox(ctx, tx, append(events, creditEvents...)); err != nil {
+			return err
+		}
+
+		transfer = wallet.Transfer{From: debited[0], To: credited[0]}
+		return nil
+	})
+	if err != nil {
+		return wallet.Transfer{}, err
+	}
+	if opErr != nil {
+		return wallet.Transfer{}, opErr
+	}
+
+	return transfer, nil
+}
+
+// lockWallets блокирует строки кошельков в порядке valletId, чтобы встречные переводы и пакеты
+// не приводили к взаимоблокировке. Отсутствующих кошельков в результате нет.
+func lockWallets(ctx context.Context, tx *sqlx.Tx, ids ...uuid.UUID) (map[string]*walletState, error) {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = id.UUID.String()
+	}
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(
+		`SELECT valletid, %s FROM %s WHERE valletid = ANY($1::uuid[]) ORDER BY valletid FOR UPDATE`,
+		stateColumns, walletTable), pq.Array(keys))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock wallets: %w", err)
+	}
+	defer rows.Close()
+
+	states := make(map[string]*walletState, len(ids))
+	for rows.Next() {
+		var (
+			id uuid.UUID
+			st walletState
+		)
+		if err := rows.Scan(append([]any{&id}, st.dest()...)...); err != nil {
+			return nil, fmt.Errorf("failed to lock wallets: %w", err)
+		}
+		states[id.UUID.String()] = &st
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to lock wallets: %w", err)
+	}
+	return states, nil
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	sqlmock "github.com/zhashkevych/go-sqlxmock"
+)
+
+func TestWalletPsql_Transfer(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		log.Fatalf("unexpected error opening stub db: %s", err)
+	}
+	defer db.Close()
+
+	w := NewWalletPsql(db, NewTxRunner(db, TxOptions{}))
+	from := uuidFromString("22222222-2222-2222-2222-222222222222")
+	to := uuidFromString("11111111-1111-1111-1111-111111111111")
+	ids := pq.Array([]string{from.UUID.String(), to.UUID.String()})
+
+	lockQuery := fmt.Sprintf(
+		`SELECT valletid, balance, version, last_seq, last_hash, credit_limit, bonus_balance, cashback_balance, promo_balance FROM %s WHERE valletid = ANY\(\$1::uuid\[\]\)\s+ORDER BY valletid FOR UPDATE`,
+		walletTable)
+	updateQuery := fmt.Sprintf(`UPDATE %s SET balance`, walletTable)
+	insertQuery := fmt.Sprintf(`INSERT INTO %s \(valletId, operation_type, amount, seq, balance_after, hash, idempotency_key, kind, operation_id\)`, walletTRXTable)
+	outboxQuery := fmt.Sprintf(`INSERT INTO %s \(event_id, valletId, event_type, payload\) VALUES \(\$1, \$2, \$3, \$4\)`, outboxTable)
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	lockedRows := []string{"valletid", "balance", "version", "last_seq", "last_hash", "credit_limit", "bonus_balance", "cashback_balance", "promo_balance"}
+
+	testTable := []struct {
+		name        string
+		amount      float64
+		mockSetup   func()
+		expected    wallet.Transfer
+		expectErr   bool
+		expectErrIs error
+	}{
+		{
+			name:   "success",
+			amount: 30,
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(ids).
+					WillReturnRows(sqlmock.NewRows(lockedRows).
+						AddRow(to.UUID.String(), 5.0, 1, 0, "", 0.0, 0.0, 0.0, 0.0).
+						AddRow(from.UUID.String(), 50.0, 3, 2, "abc", 0.0, 30.0, 0.0, 0.0))
+				// Списание забирает 20 из main и недостающие 10 из бонусов.
+				mock.ExpectExec(updateQuery).
+					WithArgs(20.0, 4, 3, sqlmock.AnyArg(), 20.0, 0.0, 0.0, from.UUID.String()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery(insertQuery).
+					WithArgs(from.UUID.String(), "WITHDRAW", 30.0, 3, 20.0, sqlmock.AnyArg(), "", "", nil,
+						pq.Array([]int64{3, 3}), pq.Array([]string{"main", "bonus"}), pq.Array([]float64{20, 10}), pq.Array([]float64{0, 20})).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, createdAt))
+				mock.ExpectExec(updateQuery).
+					WithArgs(35.0, 2, 1, sqlmock.AnyArg(), 0.0, 0.0, 0.0, to.UUID.String()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery(insertQuery).
+					WithArgs(to.UUID.String(), "DEPOSIT", 30.0, 1, 35.0, sqlmock.AnyArg(), "", "", nil,
+						pq.Array([]int64{1}), pq.Array([]string{"main"}), pq.Array([]float64{30}), pq.Array([]float64{35})).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(8, createdAt))
+				mock.ExpectExec(outboxQuery+`, \(\$5, \$6, \$7, \$8\)$`).
+					WithArgs(
+						sqlmock.AnyArg(), from.UUID.String(), wallet.EventWithdrawn, sqlmock.AnyArg(),
+						sqlmock.AnyArg(), to.UUID.String(), wallet.EventDeposited, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			},
+			expected: wallet.Transfer{
+				From: wallet.WalletTransactions{Id: 7, Seq: 3, BalanceAfter: 20},
+				To:   wallet.WalletTransactions{Id: 8, Seq: 1, BalanceAfter: 35},
+			},
+		},
+		{
+			name:   "insufficient funds",
+			amount: 80,
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(ids).
+					WillReturnRows(sqlmock.NewRows(lockedRows).
+						AddRow(to.UUID.String(), 5.0, 1, 0, "", 0.0, 0.0, 0.0, 0.0).
+						AddRow(from.UUID.String(), 50.0, 3, 2, "abc", 0.0, 30.0, 0.0, 0.0))
+				// Балансы не меняются, но событие об отклонённом списании фиксируется.
+				mock.ExpectExec(outboxQuery+`$`).
+					WithArgs(sqlmock.AnyArg(), from.UUID.String(), wallet.EventRejected, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expectErr:   true,
+			expectErrIs: ErrInsufficientFunds,
+		},
+		{
+			name:   "wallet not found",
+			amount: 1,
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(ids).
+					WillReturnRows(sqlmock.NewRows(lockedRows).AddRow(from.UUID.String(), 50.0, 3, 2, "abc", 0.0, 30.0, 0.0, 0.0))
+				mock.ExpectRollback()
+			},
+			expectErr:   true,
+			expectErrIs: ErrWalletNotFound,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			test.mockSetup()
+
+			transfer, err := w.Transfer(context.Background(), from, to, test.amount)
+
+			if test.expectErr {
+				assert.ErrorIs(t, err, test.expectErrIs)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected.From.Id, transfer.From.Id)
+				assert.Equal(t, test.expected.From.Seq, transfer.From.Seq)
+				assert.Equal(t, test.expected.From.BalanceAfter, transfer.From.BalanceAfter)
+				assert.Equal(t, test.expected.To.Id, transfer.To.Id)
+				assert.Equal(t, test.expected.To.Seq, transfer.To.Seq)
+				assert.Equal(t, test.expected.To.BalanceAfter, transfer.To.BalanceAfter)
+			}
+
+			err = mock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
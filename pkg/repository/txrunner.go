@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"expvar"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Метрики раннера публикуются через expvar (GET /debug/vars).
+var (
+	txAttempts        = expvar.NewInt("repository_tx_attempts")
+	txRetries         = expvar.NewMap("repository_tx_retries")           // по SQLSTATE
+	txRetryExhausted  = expvar.NewMap("repository_tx_retries_exhausted") // по SQLSTATE
+	retryableSQLState = map[pq.ErrorCode]bool{
+		"40001": true, // serialization_failure
+		"40P01": true, // deadlock_detected
+	}
+)
+
+const defaultTxMaxRetries = 3
+
+type TxOptions struct {
+	Isolation  sql.IsolationLevel
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// TxRunner выполняет единицу работы в транзакции и повторяет её при ошибках сериализации и взаимоблокировках.
+// Повторы делаются с экспоненциальной задержкой со случайным разбросом и только пока укладываются в дедлайн контекста.
+type TxRunner struct {
+	db   *sqlx.DB
+	opts TxOptions
+}
+
+func NewTxRunner(db *sqlx.DB, opts TxOptions) *TxRunner {
+	switch {
+	case opts.MaxRetries == 0:
+		opts.MaxRetries = defaultTxMaxRetries
+	case opts.MaxRetries < 0: // повторы отключены
+		opts.MaxRetries = 0
+	}
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = 5 * time.Millisecond
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = 200 * time.Millisecond
+	}
+
+	return &TxRunner{db: db, opts: opts}
+}
+
+// Run выполняет fn в новой транзакции. Если fn вернула ошибку, транзакция откатывается.
+// fn может быть вызвана несколько раз, поэтому не должна иметь побочных эффектов вне транзакции.
+func (r *TxRunner) Run(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	for attempt := 0; ; attempt++ {
+		err := r.runOnce(ctx, fn)
+		if err == nil {
+			return nil
+		}
+
+		code, retryable := retryableCode(err)
+		if !retryable {
+			return err
+		}
+
+		delay := r.backoff(attempt)
+		if attempt >= r.opts.MaxRetries || !fitsDeadline(ctx, delay) {
+			txRetryExhausted.Add(string(code), 1)
+			return err
+		}
+		txRetries.Add(string(code), 1)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+func (r *TxRunner) runOnce(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	txAttempts.Add(1)
+
+	tx, err := r.db.BeginTxx(ctx, &sql.TxOptions{Isolation: r.opts.Isolation})
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tx: %w", err)
+	}
+	return nil
+}
+
+// backoff - случайная задержка в диапазоне [0, min(MaxDelay, BaseDelay * 2^attempt)] ("full jitter").
+func (r *TxRunner) backoff(attempt int) time.Duration {
+	ceiling := r.opts.MaxDelay
+	if attempt < 16 {
+		if d := r.opts.BaseDelay << attempt; d < ceiling {
+			ceiling = d
+		}
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+func fitsDeadline(ctx context.Context, delay time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) > delay
+}
+
+func retryableCode(err error) (pq.ErrorCode, bool) {
+	var pgErr *pq.Error
+	if errors.As(err, &pgErr) && retryableSQLState[pgErr.Code] {
+		return pgErr.Code, true
+	}
+	return "", false
+}
+
+// ParseIsolationLevel переводит значение из конфига (read_committed, repeatable_read, serializable) в уровень изоляции.
+func ParseIsolationLevel(level string) (sql.IsolationLevel, error) {
+	switch level {
+	case "", "default":
+		return sql.LevelDefault, nil
+	case "read_committed":
+		return sql.LevelReadCommitted, nil
+	case "repeatable_read":
+		return sql.LevelRepeatableRead, nil
+	case "serializable":
+		return sql.LevelSerializable, nil
+	default:
+		return sql.LevelDefault, fmt.Errorf("unknown isolation level %q", level)
+	}
+}
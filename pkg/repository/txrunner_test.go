@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	sqlmock "github.com/zhashkevych/go-sqlxmock"
+)
+
+// expiredDeadline сообщает уже наступивший дедлайн, но не отменяется сам,
+// поэтому первая попытка выполняется, а на повтор времени не остаётся.
+type expiredDeadline struct {
+	context.Context
+}
+
+func (expiredDeadline) Deadline() (time.Time, bool) {
+	return time.Now().Add(-time.Millisecond), true
+}
+
+func TestTxRunner_Run(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		log.Fatalf("unexpected error opening stub db: %s", err)
+	}
+	defer db.Close()
+
+	r := NewTxRunner(db, TxOptions{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+	work := func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(`UPDATE wallets SET balance = 0`)
+		return err
+	}
+
+	testTable := []struct {
+		name      string
+		ctx       func() (context.Context, context.CancelFunc)
+		mockSetup func()
+		expectErr bool
+	}{
+		{
+			name: "success",
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE wallets`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "retry on serialization failure",
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE wallets`).WillReturnError(&pq.Error{Code: "40001"})
+				mock.ExpectRollback()
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE wallets`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "retry on deadlock at commit",
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE wallets`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit().WillReturnError(&pq.Error{Code: "40P01"})
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE wallets`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "retries exhausted",
+			mockSetup: func() {
+				for i := 0; i < 3; i++ {
+					mock.ExpectBegin()
+					mock.ExpectExec(`UPDATE wallets`).WillReturnError(&pq.Error{Code: "40001"})
+					mock.ExpectRollback()
+				}
+			},
+			expectErr: true,
+		},
+		{
+			name: "no retry on other errors",
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE wallets`).WillReturnError(errors.New("boom"))
+				mock.ExpectRollback()
+			},
+			expectErr: true,
+		},
+		{
+			name: "no retry past deadline",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				return expiredDeadline{ctx}, cancel
+			},
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE wallets`).WillReturnError(&pq.Error{Code: "40001"})
+				mock.ExpectRollback()
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			test.mockSetup()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if test.ctx != nil {
+				ctx, cancel = test.ctx()
+			}
+			defer cancel()
+
+			err := r.Run(ctx, work)
+
+			if test.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			err = mock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
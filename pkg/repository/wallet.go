@@ -2,7 +2,10 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/KatenkaKet/wallet"
@@ -13,68 +16,398 @@ import (
 
 type WalletPsql struct {
 	db *sqlx.DB
+	tx *TxRunner
 }
 
-func NewWalletPsql(db *sqlx.DB) *WalletPsql {
-	return &WalletPsql{db: db}
+func NewWalletPsql(db *sqlx.DB, tx *TxRunner) *WalletPsql {
+	return &WalletPsql{db: db, tx: tx}
+}
+
+const walletColumns = `valletId, balance, version, last_seq, last_hash, tier, COALESCE(product, ''), interest_pending, credit_limit, ` +
+	bucketColumns
+
+func scanWallet(row interface{ Scan(dest ...any) error }) (wallet.Wallet, error) {
+	var (
+		wlt wallet.Wallet
+		st  walletState
+	)
+	dest := []any{&wlt.ValletId, &wlt.Balance, &wlt.Version, &wlt.LastSeq, &wlt.LastHash, &wlt.Tier, &wlt.Product, &wlt.AccruedInterest,
+		&wlt.CreditLimit}
+	for i := range st.buckets {
+		dest = append(dest, &st.buckets[i])
+	}
+	if err := row.Scan(dest...); err != nil {
+		return wlt, err
+	}
+
+	st.balance, st.creditLimit = wlt.Balance, wlt.CreditLimit
+	wlt.Available = st.available()
+	wlt.Buckets = st.balances()
+	return wlt, nil
 }
 
 func (w *WalletPsql) GetBalance(ctx context.Context, uid uuid.UUID) (float64, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 	var balance float64
 
 	query := fmt.Sprintf("SELECT balance FROM %s WHERE ValletId=$1", walletTable)
 	err := w.db.QueryRowContext(ctx, query, uid).Scan(&balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("failed to get balance for wallet %s: %w", uid.UUID.String(), ErrWalletNotFound)
+	}
 	if err != nil {
 		return 0, fmt.Errorf("failed to get balance for wallet %s: %w", uid.UUID.String(), err)
 	}
 	return balance, nil
 }
 
-func (w *WalletPsql) UpdateBalance(ctx context.Context, uid uuid.UUID, amount float64) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+func (w *WalletPsql) GetWallet(ctx context.Context, uid uuid.UUID) (wallet.Wallet, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE ValletId=$1", walletColumns, walletTable)
+	wlt, err := scanWallet(w.db.QueryRowContext(ctx, query, uid))
+	if errors.Is(err, sql.ErrNoRows) {
+		return wlt, fmt.Errorf("failed to get wallet %s: %w", uid.UUID.String(), ErrWalletNotFound)
+	}
+	if err != nil {
+		return wlt, fmt.Errorf("failed to get wallet %s: %w", uid.UUID.String(), err)
+	}
+	return wlt, nil
+}
+
+func (w *WalletPsql) WalletTiers(ctx context.Context, ids []uuid.UUID) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	tiers := make(map[string]string, len(ids))
+	if len(ids) == 0 {
+		return tiers, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = id.UUID.String()
+	}
+	rows, err := w.db.QueryContext(ctx, fmt.Sprintf(`SELECT valletId, tier FROM %s WHERE valletId = ANY($1::uuid[])`, walletTable),
+		pq.Array(keys))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet tiers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id   uuid.UUID
+			tier string
+		)
+		if err := rows.Scan(&id, &tier); err != nil {
+			return nil, fmt.Errorf("failed to get wallet tiers: %w", err)
+		}
+		tiers[id.UUID.String()] = tier
+	}
+	return tiers, rows.Err()
+}
+
+func (w *WalletPsql) SetTier(ctx context.Context, uid uuid.UUID, tier string) (wallet.Wallet, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	tx, err := w.db.BeginTx(ctx, nil)
+	query := fmt.Sprintf(`UPDATE %s SET tier = $2 WHERE valletId = $1 RETURNING %s`, walletTable, walletColumns)
+	var wlt wallet.Wallet
+	err := w.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		wlt, err = scanWallet(tx.QueryRowContext(ctx, query, uid, tier))
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return wlt, fmt.Errorf("failed to set tier for wallet %s: %w", uid.UUID.String(), ErrWalletNotFound)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to begin tx: %w", err)
+		return wlt, fmt.Errorf("failed to set tier for wallet %s: %w", uid.UUID.String(), err)
 	}
+	return wlt, nil
+}
+
+func (w *WalletPsql) SetCreditLimit(ctx context.Context, uid uuid.UUID, limit float64) (wallet.Wallet, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	// Задолженность бывает только у main.
+	query := fmt.Sprintf(`UPDATE %s SET credit_limit = $2, version = version + 1
+		WHERE valletId = $1 AND balance - bonus_balance - cashback_balance - promo_balance >= -$2 RETURNING %s`, walletTable, walletColumns)
+	var wlt wallet.Wallet
+	err := w.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		wlt, err = scanWallet(tx.QueryRowContext(ctx, query, uid, limit))
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
 
-	_, err = tx.ExecContext(ctx, fmt.Sprintf(
-		`SELECT 1 FROM %s WHERE valletid = $1 FOR UPDATE`, walletTable), uid)
+		var exists bool
+		err = tx.GetContext(ctx, &exists, fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE valletId = $1)`, walletTable), uid)
+		switch {
+		case err != nil:
+			return err
+		case exists:
+			return ErrCreditLimitBelowDebt
+		default:
+			return ErrWalletNotFound
+		}
+	})
 	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to lock wallet %s: %w", uid.UUID.String(), err)
+		return wallet.Wallet{}, fmt.Errorf("failed to set credit limit for wallet %s: %w", uid.UUID.String(), err)
 	}
+	return wlt, nil
+}
 
-	_, err = tx.ExecContext(ctx, fmt.Sprintf(
-		`UPDATE %s SET balance = balance + $1 WHERE valletid = $2`, walletTable), amount, uid)
+func (w *WalletPsql) ApplyTransactions(ctx context.Context, uid uuid.UUID, ops []wallet.WalletTransactions) ([]wallet.WalletTransactions, []error, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var (
+		recorded []wallet.WalletTransactions
+		results  []error
+	)
+	err := w.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		var st walletState
+		err := tx.QueryRowContext(ctx, fmt.Sprintf(
+			`SELECT %s FROM %s WHERE valletid = $1 FOR UPDATE`, stateColumns, walletTable), uid).Scan(st.dest()...)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to lock wallet %s: %w", uid.UUID.String(), ErrWalletNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock wallet %s: %w", uid.UUID.String(), err)
+		}
+
+		known, err := findIdempotent(ctx, tx, ops)
+		if err != nil {
+			return err
+		}
+
+		var applied []int
+		recorded, results, applied = st.apply(uid, ops, known[uid.UUID.String()])
+		if len(applied) > 0 {
+			if err := recordTransactions(ctx, tx, uid, st, recorded, applied); err != nil {
+				return err
+			}
+		}
+		fillDuplicates(ops, recorded, results)
+
+		events, err := walletEvents(uid, ops, recorded, results, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		return insertOutbox(ctx, tx, events)
+	})
 	if err != nil {
-		tx.Rollback()
+		return nil, nil, err
+	}
 
+	return recorded, results, nil
+}
+
+// recordTransactions сохраняет состояние кошелька и записывает применённые транзакции, заполняя их id и created_at.
+func recordTransactions(ctx context.Context, tx *sqlx.Tx, uid uuid.UUID, st walletState,
+	recorded []wallet.WalletTransactions, applied []int) error {
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET balance = $1, version = $2, last_seq = $3, last_hash = $4,
+		bonus_balance = $5, cashback_balance = $6, promo_balance = $7 WHERE valletid = $8`, walletTable),
+		st.balance, st.version, st.lastSeq, st.lastHash, st.buckets[0], st.buckets[1], st.buckets[2], uid)
+	if err != nil {
 		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23514" { // check_violation
-			return fmt.Errorf("insufficient funds for wallet %s", uid.UUID.String())
+			return fmt.Errorf("%w for wallet %s", ErrInsufficientFunds, uid.UUID.String())
 		}
 
 		return fmt.Errorf("failed to update balance for wallet %s: %w", uid.UUID.String(), err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit tx for wallet %s: %w", uid.UUID.String(), err)
+	values := make([]string, 0, len(applied))
+	args := make([]interface{}, 0, 9*len(applied)+4)
+	var (
+		entrySeqs     []int64
+		entryBuckets  []string
+		entryAmounts  []float64
+		entryBalances []float64
+	)
+	for i, idx := range applied {
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, NULLIF($%d, ''), NULLIF($%d, ''), $%d)",
+			9*i+1, 9*i+2, 9*i+3, 9*i+4, 9*i+5, 9*i+6, 9*i+7, 9*i+8, 9*i+9))
+		WT := recorded[idx]
+		args = append(args, uid, WT.OperationType, WT.Amount, WT.Seq, WT.BalanceAfter, WT.Hash, WT.IdempotencyKey,
+			WT.Kind, WT.OperationId)
+		for _, entry := range WT.Buckets {
+			entrySeqs = append(entrySeqs, WT.Seq)
+			entryBuckets = append(entryBuckets, entry.Bucket)
+			entryAmounts = append(entryAmounts, entry.Amount)
+			entryBalances = append(entryBalances, entry.BalanceAfter)
+		}
+	}
+	n := len(args)
+	args = append(args, pq.Array(entrySeqs), pq.Array(entryBuckets), pq.Array(entryAmounts), pq.Array(entryBalances))
+
+	// Записи по корзинам вставляются тем же запросом: id транзакций известны только после вставки.
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`WITH inserted AS (
+			INSERT INTO %s (valletId, operation_type, amount, seq, balance_after, hash, idempotency_key, kind, operation_id)
+			VALUES %s RETURNING id, seq, created_at
+		), entries AS (
+			INSERT INTO %s (transaction_id, valletId, bucket, seq, amount, balance_after)
+			SELECT i.id, $1, e.bucket, e.seq, e.amount, e.balance_after FROM inserted i
+			JOIN unnest($%d::bigint[], $%d::text[], $%d::numeric[], $%d::numeric[]) AS e(seq, bucket, amount, balance_after) ON e.seq = i.seq
+		)
+		SELECT id, created_at FROM inserted ORDER BY seq`,
+		walletTRXTable, strings.Join(values, ", "), bucketEntryTable, n+1, n+2, n+3, n+4), args...)
+	if err != nil {
+		return fmt.Errorf("failed to insert transactions for wallet %s: %w", uid.UUID.String(), err)
 	}
+	defer rows.Close()
 
-	return nil
+	// seq применённых транзакций растёт в порядке applied.
+	for _, idx := range applied {
+		if !rows.Next() {
+			return fmt.Errorf("failed to insert transactions for wallet %s: missing returned id", uid.UUID.String())
+		}
+		if err := rows.Scan(&recorded[idx].Id, &recorded[idx].CreatedAt); err != nil {
+			return fmt.Errorf("failed to insert transactions for wallet %s: %w", uid.UUID.String(), err)
+		}
+	}
+	return rows.Err()
 }
 
-func (w *WalletPsql) CreateTransaction(ctx context.Context, WT wallet.WalletTransactions) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+func (w *WalletPsql) ListTransactions(ctx context.Context, uid uuid.UUID, afterSeq int64, limit int) ([]wallet.WalletTransactions, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	query := fmt.Sprintf(`INSERT INTO %s (valletId, operation_type, amount) VALUES ($1, $2, $3)`, walletTRXTable)
-	_, err := w.db.ExecContext(ctx, query, WT.ValletId, WT.OperationType, WT.Amount)
+	var exists bool
+	err := w.db.GetContext(ctx, &exists, fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE valletId = $1)`, walletTable), uid)
 	if err != nil {
-		return fmt.Errorf("failed to insert transaction for wallet %s: %w", WT.ValletId.UUID.String(), err)
+		return nil, fmt.Errorf("failed to list transactions for wallet %s: %w", uid.UUID.String(), err)
 	}
-	return nil
+	if !exists {
+		return nil, fmt.Errorf("failed to list transactions for wallet %s: %w", uid.UUID.String(), ErrWalletNotFound)
+	}
+
+	query := fmt.Sprintf(`SELECT id, valletId, operation_type, amount, seq, balance_after, hash, created_at,
+		COALESCE(kind, ''), operation_id FROM %s WHERE valletId = $1 AND seq > $2 ORDER BY seq LIMIT $3`, walletTRXTable)
+	history, err := queryTransactions(ctx, w.db, query, uid, afterSeq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transactions for wallet %s: %w", uid.UUID.String(), err)
+	}
+	return history, nil
+}
+
+func (w *WalletPsql) PeriodSeq(ctx context.Context, uid uuid.UUID, from, to time.Time) (int64, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	// created_at хранится без часового пояса в UTC, поэтому границы сравниваются как timestamp в UTC.
+	var bounds [2]sql.NullTime
+	for i, t := range []time.Time{from, to} {
+		if !t.IsZero() {
+			bounds[i] = sql.NullTime{Time: t.UTC(), Valid: true}
+		}
+	}
+
+	var afterSeq, lastSeq int64
+	err := w.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT
+			COALESCE(MAX(seq) FILTER (WHERE created_at < $2::timestamp), 0),
+			COALESCE(MAX(seq) FILTER (WHERE $3::timestamp IS NULL OR created_at < $3::timestamp), 0)
+		FROM %s WHERE valletId = $1`, walletTRXTable), uid, bounds[0], bounds[1]).Scan(&afterSeq, &lastSeq)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to find period for wallet %s: %w", uid.UUID.String(), err)
+	}
+	return afterSeq, lastSeq, nil
+}
+
+// walletState - заблокированная строка кошелька, к которой применяются операции.
+type walletState struct {
+	balance  float64
+	version  int64
+	lastSeq  int64
+	lastHash string
+	// creditLimit - одобренный кредитный лимит: остаток main может опускаться до -creditLimit.
+	creditLimit float64
+	// buckets - остатки корзин extraBuckets.
+	buckets [len(extraBuckets)]float64
+}
+
+// stateColumns - колонки wallets, из которых собирается walletState, в порядке walletState.dest.
+const stateColumns = `balance, version, last_seq, last_hash, credit_limit, ` + bucketColumns
+
+// dest возвращает адреса полей для Scan строки с колонками stateColumns.
+func (st *walletState) dest() []any {
+	dest := []any{&st.balance, &st.version, &st.lastSeq, &st.lastHash, &st.creditLimit}
+	for i := range st.buckets {
+		dest = append(dest, &st.buckets[i])
+	}
+	return dest
+}
+
+// apply последовательно применяет операции, раскладывая их по корзинам (см. split), и отклоняет те, что увели бы
+// корзину в минус (main - ниже -creditLimit) или ожидают другую версию. CHECK в таблице wallets - только последняя линия защиты.
+// known - уже записанные транзакции кошелька по ключам идемпотентности: повторы не применяются,
+// а получают ErrDuplicateTransaction и исходную транзакцию; применённые операции с ключом добавляются в known.
+// Возвращает записи транзакций (параллельно ops), ошибки по операциям и индексы применённых операций.
+func (st *walletState) apply(uid uuid.UUID, ops []wallet.WalletTransactions,
+	known map[string]wallet.WalletTransactions) ([]wallet.WalletTransactions, []error, []int) {
+	recorded := make([]wallet.WalletTransactions, len(ops))
+	results := make([]error, len(ops))
+	applied := make([]int, 0, len(ops))
+
+	for i, WT := range ops {
+		if original, ok := known[WT.IdempotencyKey]; ok && WT.IdempotencyKey != "" {
+			recorded[i] = original
+			results[i] = fmt.Errorf("%w for wallet %s: idempotency key %q", ErrDuplicateTransaction, uid.UUID.String(), WT.IdempotencyKey)
+			continue
+		}
+
+		delta := WT.Amount
+		switch WT.OperationType {
+		case "DEPOSIT":
+		case "WITHDRAW":
+			delta = -delta
+		default:
+			results[i] = fmt.Errorf("invalid operation type %q", WT.OperationType)
+			continue
+		}
+
+		if WT.ExpectedVersion != 0 && WT.ExpectedVersion != st.version {
+			results[i] = fmt.Errorf("%w for wallet %s: expected %d, actual %d",
+				ErrVersionMismatch, uid.UUID.String(), WT.ExpectedVersion, st.version)
+			continue
+		}
+
+		entries, err := st.split(uid, WT)
+		if err != nil {
+			results[i] = err
+			continue
+		}
+
+		st.balance = roundAmount(st.balance + delta)
+		for _, entry := range entries {
+			st.setBucket(entry.Bucket, entry.BalanceAfter)
+		}
+		st.version++
+		st.lastSeq++
+
+		WT.ValletId = uid
+		WT.Amount = roundAmount(WT.Amount)
+		WT.Version = st.version
+		WT.Seq = st.lastSeq
+		WT.BalanceAfter = st.balance
+		WT.Buckets = entries
+		WT.Hash = wallet.ChainHash(st.lastHash, WT)
+		st.lastHash = WT.Hash
+		recorded[i] = WT
+		applied = append(applied, i)
+
+		if WT.IdempotencyKey != "" {
+			if known == nil {
+				known = make(map[string]wallet.WalletTransactions)
+			}
+			known[WT.IdempotencyKey] = WT
+		}
+	}
+
+	return recorded, results, applied
 }
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log"
 	"testing"
+	"time"
 
 	"github.com/KatenkaKet/wallet"
 	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
@@ -31,7 +32,7 @@ func TestWalletPsql_GetBalance(t *testing.T) {
 
 	defer db.Close()
 
-	r := NewWalletPsql(db)
+	r := NewWalletPsql(db, NewTxRunner(db, TxOptions{}))
 	uid := uuidFromString("11111111-1111-1111-1111-111111111111")
 
 	testTable := []struct {
@@ -82,51 +83,44 @@ func TestWalletPsql_GetBalance(t *testing.T) {
 	}
 }
 
-func TestWalletPsql_CreateTransaction(t *testing.T) {
+func TestWalletPsql_GetWallet(t *testing.T) {
 	db, mock, err := sqlmock.Newx()
 	if err != nil {
 		log.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
 	}
 	defer db.Close()
 
-	w := NewWalletPsql(db)
+	r := NewWalletPsql(db, NewTxRunner(db, TxOptions{}))
 	uid := uuidFromString("11111111-1111-1111-1111-111111111111")
 
 	testTable := []struct {
-		name      string
-		inputWT   wallet.WalletTransactions
-		mockSetup func()
-		expectErr bool
+		name           string
+		mockSetup      func()
+		expectedWallet wallet.Wallet
+		expectError    error
 	}{
 		{
 			name: "success",
-			inputWT: wallet.WalletTransactions{
-				ValletId:      uid,
-				OperationType: "DEPOSIT",
-				Amount:        100.5,
-			},
 			mockSetup: func() {
-				mock.ExpectExec(
-					fmt.Sprintf(`INSERT INTO %s \(valletId, operation_type, amount\) VALUES \(\$1, \$2, \$3\)`, walletTRXTable)).
-					WithArgs(uid, "DEPOSIT", 100.5).
-					WillReturnResult(sqlmock.NewResult(1, 1))
+				rows := sqlmock.NewRows([]string{"valletId", "balance", "version", "last_seq", "last_hash", "tier", "product", "interest_pending",
+					"credit_limit", "bonus_balance", "cashback_balance", "promo_balance"}).
+					AddRow(uid.UUID.String(), 100.5, 3, 2, "abc", "premium", "savings", 0.0123, 50.0, 20.0, 5.5, 10.0)
+				mock.ExpectQuery(fmt.Sprintf(`SELECT valletId, balance, version, last_seq, last_hash, tier, COALESCE\(product, ''\), interest_pending, credit_limit,\s+bonus_balance, cashback_balance, promo_balance FROM %s WHERE ValletId=\$1`, walletTable)).
+					WithArgs(uid).
+					WillReturnRows(rows)
 			},
-			expectErr: false,
+			expectedWallet: wallet.Wallet{ValletId: uid, Balance: 100.5, Version: 3, LastSeq: 2, LastHash: "abc", Tier: "premium",
+				Product: "savings", AccruedInterest: 0.0123, CreditLimit: 50, Available: 140.5,
+				Buckets: map[string]float64{"main": 65, "bonus": 20, "cashback": 5.5, "promo": 10}},
 		},
 		{
-			name: "insert error",
-			inputWT: wallet.WalletTransactions{
-				ValletId:      uid,
-				OperationType: "WITHDRAW",
-				Amount:        50,
-			},
+			name: "wallet not found",
 			mockSetup: func() {
-				mock.ExpectExec(
-					fmt.Sprintf(`INSERT INTO %s \(valletId, operation_type, amount\) VALUES \(\$1, \$2, \$3\)`, walletTRXTable)).
-					WithArgs(uid, "WITHDRAW", 50).
-					WillReturnError(errors.New("insert failed"))
+				mock.ExpectQuery(fmt.Sprintf(`SELECT valletId, balance, version, last_seq, last_hash, tier, COALESCE\(product, ''\), interest_pending, credit_limit,\s+bonus_balance, cashback_balance, promo_balance FROM %s WHERE ValletId=\$1`, walletTable)).
+					WithArgs(uid).
+					WillReturnError(sql.ErrNoRows)
 			},
-			expectErr: true,
+			expectError: ErrWalletNotFound,
 		},
 	}
 
@@ -134,13 +128,13 @@ func TestWalletPsql_CreateTransaction(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			test.mockSetup()
 
-			err := w.CreateTransaction(context.Background(), test.inputWT)
+			wlt, err := r.GetWallet(context.Background(), uid)
 
-			if test.expectErr {
-				assert.Error(t, err)
-				return
+			if test.expectError != nil {
+				assert.ErrorIs(t, err, test.expectError)
 			} else {
 				assert.NoError(t, err)
+				assert.Equal(t, test.expectedWallet, wlt)
 			}
 
 			err = mock.ExpectationsWereMet()
@@ -149,45 +143,250 @@ func TestWalletPsql_CreateTransaction(t *testing.T) {
 	}
 }
 
-func TestWalletPsql_UpdateBalance(t *testing.T) {
+func TestWalletPsql_WalletTiers(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		log.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	r := NewWalletPsql(db, NewTxRunner(db, TxOptions{}))
+	a := uuidFromString("11111111-1111-1111-1111-111111111111")
+	missing := uuidFromString("99999999-9999-9999-9999-999999999999")
+
+	mock.ExpectQuery(fmt.Sprintf(`SELECT valletId, tier FROM %s WHERE valletId = ANY\(\$1::uuid\[\]\)`, walletTable)).
+		WithArgs(pq.Array([]string{a.UUID.String(), missing.UUID.String()})).
+		WillReturnRows(sqlmock.NewRows([]string{"valletId", "tier"}).AddRow(a.UUID.String(), "premium"))
+
+	tiers, err := r.WalletTiers(context.Background(), []uuid.UUID{a, missing})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{a.UUID.String(): "premium"}, tiers)
+
+	// Пустой список не ходит в базу.
+	tiers, err = r.WalletTiers(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, tiers)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWalletPsql_SetCreditLimit(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		log.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	r := NewWalletPsql(db, NewTxRunner(db, TxOptions{BaseDelay: time.Millisecond}))
+	uid := uuidFromString("11111111-1111-1111-1111-111111111111")
+	update := fmt.Sprintf(`UPDATE %s SET credit_limit = \$2, version = version \+ 1`, walletTable)
+	exists := fmt.Sprintf(`SELECT EXISTS \(SELECT 1 FROM %s WHERE valletId = \$1\)`, walletTable)
+
+	t.Run("retried after a serialization failure", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(update).WithArgs(uid, 50.0).WillReturnError(&pq.Error{Code: "40001"})
+		mock.ExpectRollback()
+		mock.ExpectBegin()
+		mock.ExpectQuery(update).WithArgs(uid, 50.0).
+			WillReturnRows(sqlmock.NewRows([]string{"valletId", "balance", "version", "last_seq", "last_hash", "tier", "product",
+				"interest_pending", "credit_limit", "bonus_balance", "cashback_balance", "promo_balance"}).
+				AddRow(uid.UUID.String(), -20.0, 4, 3, "abc", "standard", "", 0.0, 50.0, 0.0, 0.0, 0.0))
+		mock.ExpectCommit()
+
+		wlt, err := r.SetCreditLimit(context.Background(), uid, 50)
+		assert.NoError(t, err)
+		assert.Equal(t, 50.0, wlt.CreditLimit)
+		assert.Equal(t, 30.0, wlt.Available)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("below the debt", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(update).WithArgs(uid, 10.0).WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery(exists).WithArgs(uid).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		_, err := r.SetCreditLimit(context.Background(), uid, 10)
+		assert.ErrorIs(t, err, ErrCreditLimitBelowDebt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestWalletPsql_ApplyTransactions(t *testing.T) {
 	db, mock, err := sqlmock.Newx()
 	if err != nil {
 		log.Fatalf("unexpected error opening stub db: %s", err)
 	}
 	defer db.Close()
 
-	w := NewWalletPsql(db)
+	w := NewWalletPsql(db, NewTxRunner(db, TxOptions{}))
 	uid := uuidFromString("11111111-1111-1111-1111-111111111111")
 
+	lockQuery := fmt.Sprintf(`SELECT balance, version, last_seq, last_hash, credit_limit, bonus_balance, cashback_balance, promo_balance
+		FROM %s WHERE valletid = \$1 FOR UPDATE`, walletTable)
+	updateQuery := fmt.Sprintf(`UPDATE %s SET balance = \$1, version = \$2, last_seq = \$3, last_hash = \$4,\s+`+
+		`bonus_balance = \$5, cashback_balance = \$6, promo_balance = \$7 WHERE valletid = \$8`, walletTable)
+	insertQuery := fmt.Sprintf(
+		`WITH inserted AS \(\s+INSERT INTO %s \(valletId, operation_type, amount, seq, balance_after, hash, idempotency_key, kind, operation_id\)\s+VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, NULLIF\(\$7, ''\), NULLIF\(\$8, ''\), \$9\)`,
+		walletTRXTable)
+	outboxQuery := fmt.Sprintf(`INSERT INTO %s \(event_id, valletId, event_type, payload\) VALUES \(\$1, \$2, \$3, \$4\)`, outboxTable)
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	lockedRow := []string{"balance", "version", "last_seq", "last_hash", "credit_limit", "bonus_balance", "cashback_balance", "promo_balance"}
+
+	// Ожидаемые звенья цепочки для успешных сценариев.
+	depositHash := wallet.ChainHash("abc",
+		wallet.WalletTransactions{ValletId: uid, OperationType: "DEPOSIT", Amount: 100, Seq: 5, BalanceAfter: 150})
+	batchHash1 := wallet.ChainHash("",
+		wallet.WalletTransactions{ValletId: uid, OperationType: "WITHDRAW", Amount: 80, Seq: 1, BalanceAfter: 20})
+	batchHash2 := wallet.ChainHash(batchHash1,
+		wallet.WalletTransactions{ValletId: uid, OperationType: "DEPOSIT", Amount: 10, Seq: 2, BalanceAfter: 30})
+
 	testTable := []struct {
-		name      string
-		amount    float64
-		mockSetup func()
-		expectErr bool
+		name           string
+		ops            []wallet.WalletTransactions
+		mockSetup      func()
+		expectedErrors []error
+		expectedIds    []int
+		expectedSeqs   []int64
+		expectedHashes []string
+		expectErr      bool
+		expectErrIs    error
 	}{
 		{
-			name:   "success",
-			amount: 100.0,
+			name: "success",
+			ops: []wallet.WalletTransactions{
+				{ValletId: uid, OperationType: "DEPOSIT", Amount: 100},
+			},
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(uid.UUID.String()).
+					WillReturnRows(sqlmock.NewRows(lockedRow).AddRow(50.0, 1, 4, "abc", 0.0, 0.0, 0.0, 0.0))
+				mock.ExpectExec(updateQuery).
+					WithArgs(150.0, 2, 5, depositHash, 0.0, 0.0, 0.0, uid.UUID.String()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery(insertQuery+` RETURNING id, seq, created_at`).
+					WithArgs(uid.UUID.String(), "DEPOSIT", 100.0, 5, 150.0, depositHash, "", "", nil,
+						pq.Array([]int64{5}), pq.Array([]string{"main"}), pq.Array([]float64{100}), pq.Array([]float64{150})).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(10, createdAt))
+				mock.ExpectExec(outboxQuery+`$`).
+					WithArgs(sqlmock.AnyArg(), uid.UUID.String(), wallet.EventDeposited, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expectedErrors: []error{nil},
+			expectedIds:    []int{10},
+			expectedSeqs:   []int64{5},
+			expectedHashes: []string{depositHash},
+		},
+		{
+			name: "batch with rejected operations",
+			ops: []wallet.WalletTransactions{
+				{ValletId: uid, OperationType: "WITHDRAW", Amount: 80},
+				{ValletId: uid, OperationType: "WITHDRAW", Amount: 50},
+				{ValletId: uid, OperationType: "DEPOSIT", Amount: 10, ExpectedVersion: 1},
+				{ValletId: uid, OperationType: "DEPOSIT", Amount: 10, ExpectedVersion: 2},
+			},
 			mockSetup: func() {
 				mock.ExpectBegin()
-				mock.ExpectExec(fmt.Sprintf(`SELECT 1 FROM %s WHERE valletid = \$1 FOR UPDATE`, walletTable)).
+				mock.ExpectQuery(lockQuery).
 					WithArgs(uid.UUID.String()).
+					WillReturnRows(sqlmock.NewRows(lockedRow).AddRow(100.0, 1, 0, "", 0.0, 0.0, 0.0, 0.0))
+				mock.ExpectExec(updateQuery).
+					WithArgs(30.0, 3, 2, batchHash2, 0.0, 0.0, 0.0, uid.UUID.String()).
 					WillReturnResult(sqlmock.NewResult(1, 1))
-
-				mock.ExpectExec(fmt.Sprintf(`UPDATE %s SET balance = balance \+ \$1 WHERE valletid = \$2`, walletTable)).
-					WithArgs(100.0, uid.UUID.String()).
+				mock.ExpectQuery(insertQuery+`, \(\$10, \$11, \$12, \$13, \$14, \$15, NULLIF\(\$16, ''\), NULLIF\(\$17, ''\), \$18\) RETURNING id, seq, created_at`).
+					WithArgs(uid.UUID.String(), "WITHDRAW", 80.0, 1, 20.0, batchHash1, "", "", nil,
+						uid.UUID.String(), "DEPOSIT", 10.0, 2, 30.0, batchHash2, "", "", nil,
+						pq.Array([]int64{1, 2}), pq.Array([]string{"main", "main"}), pq.Array([]float64{80, 10}), pq.Array([]float64{20, 30})).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(11, createdAt).AddRow(12, createdAt))
+				// События пишутся в порядке операций, включая отклонённые.
+				mock.ExpectExec(outboxQuery+`, \(\$5, \$6, \$7, \$8\), \(\$9, \$10, \$11, \$12\), \(\$13, \$14, \$15, \$16\)$`).
+					WithArgs(
+						sqlmock.AnyArg(), uid.UUID.String(), wallet.EventWithdrawn, sqlmock.AnyArg(),
+						sqlmock.AnyArg(), uid.UUID.String(), wallet.EventRejected, sqlmock.AnyArg(),
+						sqlmock.AnyArg(), uid.UUID.String(), wallet.EventRejected, sqlmock.AnyArg(),
+						sqlmock.AnyArg(), uid.UUID.String(), wallet.EventDeposited, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 4))
+				mock.ExpectCommit()
+			},
+			expectedErrors: []error{nil, ErrInsufficientFunds, ErrVersionMismatch, nil},
+			expectedIds:    []int{11, 0, 0, 12},
+			expectedSeqs:   []int64{1, 0, 0, 2},
+			expectedHashes: []string{batchHash1, "", "", batchHash2},
+		},
+		{
+			name: "bucket withdrawals",
+			ops: []wallet.WalletTransactions{
+				{ValletId: uid, OperationType: "WITHDRAW", Amount: 20, Bucket: wallet.BucketBonus},
+				{ValletId: uid, OperationType: "WITHDRAW", Amount: 1, Bucket: wallet.BucketPromo},
+				{ValletId: uid, OperationType: "WITHDRAW", Amount: 1, Bucket: "vip"},
+			},
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(uid.UUID.String()).
+					WillReturnRows(sqlmock.NewRows(lockedRow).AddRow(100.0, 1, 0, "", 0.0, 30.0, 0.0, 0.0))
+				mock.ExpectExec(updateQuery).
+					WithArgs(80.0, 2, 1, sqlmock.AnyArg(), 10.0, 0.0, 0.0, uid.UUID.String()).
 					WillReturnResult(sqlmock.NewResult(1, 1))
-
+				mock.ExpectQuery(insertQuery).
+					WithArgs(uid.UUID.String(), "WITHDRAW", 20.0, 1, 80.0, sqlmock.AnyArg(), "", "", nil,
+						pq.Array([]int64{1}), pq.Array([]string{"bonus"}), pq.Array([]float64{20}), pq.Array([]float64{10})).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(14, createdAt))
+				// Неизвестная корзина - не отказ по балансу, события о ней нет.
+				mock.ExpectExec(outboxQuery+`, \(\$5, \$6, \$7, \$8\)$`).
+					WithArgs(
+						sqlmock.AnyArg(), uid.UUID.String(), wallet.EventWithdrawn, sqlmock.AnyArg(),
+						sqlmock.AnyArg(), uid.UUID.String(), wallet.EventRejected, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			},
+			expectedErrors: []error{nil, ErrInsufficientFunds, ErrInvalidBucket},
+			expectedIds:    []int{14, 0, 0},
+			expectedSeqs:   []int64{1, 0, 0},
+		},
+		{
+			name: "nothing applied",
+			ops: []wallet.WalletTransactions{
+				{ValletId: uid, OperationType: "WITHDRAW", Amount: 200},
+			},
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(uid.UUID.String()).
+					WillReturnRows(sqlmock.NewRows(lockedRow).AddRow(100.0, 1, 0, "", 0.0, 0.0, 0.0, 0.0))
+				mock.ExpectExec(outboxQuery+`$`).
+					WithArgs(sqlmock.AnyArg(), uid.UUID.String(), wallet.EventRejected, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
 				mock.ExpectCommit()
 			},
-			expectErr: false,
+			expectedErrors: []error{ErrInsufficientFunds},
+			expectedIds:    []int{0},
+			expectedSeqs:   []int64{0},
+		},
+		{
+			name: "wallet not found",
+			ops: []wallet.WalletTransactions{
+				{ValletId: uid, OperationType: "DEPOSIT", Amount: 1},
+			},
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(uid.UUID.String()).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			expectErr:   true,
+			expectErrIs: ErrWalletNotFound,
 		},
 		{
-			name:   "lock error",
-			amount: 50.0,
+			name: "lock error",
+			ops: []wallet.WalletTransactions{
+				{ValletId: uid, OperationType: "DEPOSIT", Amount: 1},
+			},
 			mockSetup: func() {
 				mock.ExpectBegin()
-				mock.ExpectExec(fmt.Sprintf(`SELECT 1 FROM %s WHERE valletid = \$1 FOR UPDATE`, walletTable)).
+				mock.ExpectQuery(lockQuery).
 					WithArgs(uid.UUID.String()).
 					WillReturnError(errors.New("lock failed"))
 				mock.ExpectRollback()
@@ -195,19 +394,86 @@ func TestWalletPsql_UpdateBalance(t *testing.T) {
 			expectErr: true,
 		},
 		{
-			name:   "insufficient funds",
-			amount: -200.0,
+			name: "check constraint violation",
+			ops: []wallet.WalletTransactions{
+				{ValletId: uid, OperationType: "WITHDRAW", Amount: 10},
+			},
 			mockSetup: func() {
 				mock.ExpectBegin()
-				mock.ExpectExec(fmt.Sprintf(`SELECT 1 FROM %s WHERE valletid = \$1 FOR UPDATE`, walletTable)).
+				mock.ExpectQuery(lockQuery).
 					WithArgs(uid.UUID.String()).
-					WillReturnResult(sqlmock.NewResult(1, 1))
-
+					WillReturnRows(sqlmock.NewRows(lockedRow).AddRow(100.0, 1, 0, "", 0.0, 0.0, 0.0, 0.0))
 				// эмулируем ошибку postgres check constraint violation (23514)
-				mock.ExpectExec(fmt.Sprintf(`UPDATE %s SET balance = balance \+ \$1 WHERE valletid = \$2`, walletTable)).
-					WithArgs(-200.0, uid.UUID.String()).
+				mock.ExpectExec(updateQuery).
+					WithArgs(90.0, 2, 1, sqlmock.AnyArg(), 0.0, 0.0, 0.0, uid.UUID.String()).
 					WillReturnError(&pq.Error{Code: "23514"})
-
+				mock.ExpectRollback()
+			},
+			expectErr:   true,
+			expectErrIs: ErrInsufficientFunds,
+		},
+		{
+			name: "insert error rolls back",
+			ops: []wallet.WalletTransactions{
+				{ValletId: uid, OperationType: "DEPOSIT", Amount: 1},
+			},
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(uid.UUID.String()).
+					WillReturnRows(sqlmock.NewRows(lockedRow).AddRow(100.0, 1, 0, "", 0.0, 0.0, 0.0, 0.0))
+				mock.ExpectExec(updateQuery).
+					WithArgs(101.0, 2, 1, sqlmock.AnyArg(), 0.0, 0.0, 0.0, uid.UUID.String()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery(insertQuery).
+					WillReturnError(errors.New("insert failed"))
+				mock.ExpectRollback()
+			},
+			expectErr: true,
+		},
+		{
+			name: "duplicate idempotency key",
+			ops: []wallet.WalletTransactions{
+				{ValletId: uid, OperationType: "DEPOSIT", Amount: 100, IdempotencyKey: "payroll-1"},
+			},
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(uid.UUID.String()).
+					WillReturnRows(sqlmock.NewRows(lockedRow).AddRow(150.0, 2, 5, depositHash, 0.0, 0.0, 0.0, 0.0))
+				mock.ExpectQuery(fmt.Sprintf(`SELECT id, valletId, operation_type, amount, seq, balance_after, hash, created_at, idempotency_key,
+		COALESCE\(kind, ''\), operation_id FROM %s WHERE \(valletId, idempotency_key\) IN \(SELECT \* FROM unnest\(\$1::uuid\[\], \$2::text\[\]\)\)`, walletTRXTable)).
+					WithArgs(pq.Array([]string{uid.UUID.String()}), pq.Array([]string{"payroll-1"})).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "valletId", "operation_type", "amount", "seq", "balance_after", "hash", "created_at", "idempotency_key", "kind", "operation_id"}).
+						AddRow(10, uid.UUID.String(), "DEPOSIT", 100.0, 5, 150.0, depositHash, createdAt, "payroll-1", "", nil))
+				mock.ExpectQuery(fmt.Sprintf(`SELECT transaction_id, bucket, amount, balance_after FROM %s`, bucketEntryTable)).
+					WithArgs(pq.Array([]int64{10})).
+					WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "bucket", "amount", "balance_after"}).AddRow(10, "main", 100.0, 150.0))
+				// Повтор не меняет кошелёк и не порождает событий.
+				mock.ExpectCommit()
+			},
+			expectedErrors: []error{ErrDuplicateTransaction},
+			expectedIds:    []int{10},
+			expectedSeqs:   []int64{5},
+			expectedHashes: []string{depositHash},
+		},
+		{
+			name: "outbox error rolls back",
+			ops: []wallet.WalletTransactions{
+				{ValletId: uid, OperationType: "DEPOSIT", Amount: 1},
+			},
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(uid.UUID.String()).
+					WillReturnRows(sqlmock.NewRows(lockedRow).AddRow(100.0, 1, 0, "", 0.0, 0.0, 0.0, 0.0))
+				mock.ExpectExec(updateQuery).
+					WithArgs(101.0, 2, 1, sqlmock.AnyArg(), 0.0, 0.0, 0.0, uid.UUID.String()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery(insertQuery).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(13, createdAt))
+				mock.ExpectExec(outboxQuery).
+					WillReturnError(errors.New("outbox failed"))
 				mock.ExpectRollback()
 			},
 			expectErr: true,
@@ -218,17 +484,126 @@ func TestWalletPsql_UpdateBalance(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			test.mockSetup()
 
-			err := w.UpdateBalance(context.Background(), uid, test.amount)
+			recorded, results, err := w.ApplyTransactions(context.Background(), uid, test.ops)
 
 			if test.expectErr {
 				assert.Error(t, err)
+				if test.expectErrIs != nil {
+					assert.ErrorIs(t, err, test.expectErrIs)
+				}
+			} else {
+				assert.NoError(t, err)
+				for i, expected := range test.expectedErrors {
+					if expected == nil {
+						assert.NoError(t, results[i])
+					} else {
+						assert.ErrorIs(t, results[i], expected)
+					}
+					assert.Equal(t, test.expectedIds[i], recorded[i].Id)
+					assert.Equal(t, test.expectedSeqs[i], recorded[i].Seq)
+					if test.expectedHashes != nil {
+						assert.Equal(t, test.expectedHashes[i], recorded[i].Hash)
+					}
+				}
+			}
+
+			err = mock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestWalletPsql_ListTransactions(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		log.Fatalf("unexpected error opening stub db: %s", err)
+	}
+	defer db.Close()
+
+	w := NewWalletPsql(db, NewTxRunner(db, TxOptions{}))
+	uid := uuidFromString("11111111-1111-1111-1111-111111111111")
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	existsQuery := fmt.Sprintf(`SELECT EXISTS \(SELECT 1 FROM %s WHERE valletId = \$1\)`, walletTable)
+	listQuery := fmt.Sprintf(`SELECT id, valletId, operation_type, amount, seq, balance_after, hash, created_at,\s+COALESCE\(kind, ''\), operation_id FROM %s`, walletTRXTable)
+
+	testTable := []struct {
+		name        string
+		mockSetup   func()
+		expected    []wallet.WalletTransactions
+		expectErrIs error
+	}{
+		{
+			name: "success",
+			mockSetup: func() {
+				mock.ExpectQuery(existsQuery).WithArgs(uid.UUID.String()).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectQuery(listQuery).WithArgs(uid.UUID.String(), 3, 2).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "valletId", "operation_type", "amount", "seq", "balance_after", "hash", "created_at", "kind", "operation_id"}).
+						AddRow(7, uid.UUID.String(), "DEPOSIT", 10.0, 4, 110.0, "h4", createdAt, "", nil).
+						AddRow(9, uid.UUID.String(), "WITHDRAW", 5.0, 5, 105.0, "h5", createdAt, "", nil))
+				mock.ExpectQuery(fmt.Sprintf(`SELECT transaction_id, bucket, amount, balance_after FROM %s`, bucketEntryTable)).
+					WithArgs(pq.Array([]int64{7, 9})).
+					WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "bucket", "amount", "balance_after"}).
+						AddRow(7, "main", 10.0, 110.0).
+						AddRow(9, "bonus", 3.0, 0.0).
+						AddRow(9, "main", 2.0, 105.0))
+			},
+			expected: []wallet.WalletTransactions{
+				{Id: 7, ValletId: uid, OperationType: "DEPOSIT", Amount: 10, Seq: 4, BalanceAfter: 110, Hash: "h4", CreatedAt: createdAt,
+					Buckets: []wallet.BucketEntry{{Bucket: "main", Amount: 10, BalanceAfter: 110}}},
+				{Id: 9, ValletId: uid, OperationType: "WITHDRAW", Amount: 5, Seq: 5, BalanceAfter: 105, Hash: "h5", CreatedAt: createdAt,
+					Buckets: []wallet.BucketEntry{{Bucket: "main", Amount: 2, BalanceAfter: 105}, {Bucket: "bonus", Amount: 3}}},
+			},
+		},
+		{
+			name: "wallet not found",
+			mockSetup: func() {
+				mock.ExpectQuery(existsQuery).WithArgs(uid.UUID.String()).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+			},
+			expectErrIs: ErrWalletNotFound,
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			test.mockSetup()
+
+			history, err := w.ListTransactions(context.Background(), uid, 3, 2)
+
+			if test.expectErrIs != nil {
+				assert.ErrorIs(t, err, test.expectErrIs)
 			} else {
 				assert.NoError(t, err)
+				assert.Equal(t, test.expected, history)
 			}
 
-			// проверяем, что моковые ожидания выполнены
 			err = mock.ExpectationsWereMet()
 			assert.NoError(t, err)
 		})
 	}
 }
+
+func TestWalletPsql_PeriodSeq(t *testing.T) {
+	db, mock, err := sqlmock.Newx()
+	if err != nil {
+		log.Fatalf("unexpected error opening stub db: %s", err)
+	}
+	defer db.Close()
+
+	w := NewWalletPsql(db, NewTxRunner(db, TxOptions{}))
+	uid := uuidFromString("11111111-1111-1111-1111-111111111111")
+	from := time.Date(2025, 1, 1, 3, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+
+	// Границы передаются в UTC, отсутствующая граница - NULL.
+	mock.ExpectQuery(fmt.Sprintf(`SELECT\s+COALESCE\(MAX\(seq\) FILTER .+ FROM %s WHERE valletId = \$1`, walletTRXTable)).
+		WithArgs(uid.UUID.String(), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"after_seq", "last_seq"}).AddRow(4, 9))
+
+	afterSeq, lastSeq, err := w.PeriodSeq(context.Background(), uid, from, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), afterSeq)
+	assert.Equal(t, int64(9), lastSeq)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/jackc/pgtype"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type WebhookPsql struct {
+	db *sqlx.DB
+	tx *TxRunner
+}
+
+func NewWebhookPsql(db *sqlx.DB, tx *TxRunner) *WebhookPsql {
+	return &WebhookPsql{db: db, tx: tx}
+}
+
+func (w *WebhookPsql) CreateSubscription(ctx context.Context, sub wallet.WebhookSubscription) (wallet.WebhookSubscription, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	query := fmt.Sprintf(`INSERT INTO %s (valletId, url, event_types, secret) VALUES ($1, $2, $3, $4) RETURNING id, created_at`,
+		webhookTable)
+	err := w.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		return tx.QueryRowContext(ctx, query, sub.ValletId, sub.URL, pq.Array(sub.EventTypes), sub.Secret).
+			Scan(&sub.Id, &sub.CreatedAt)
+	})
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23503" { // foreign_key_violation
+			return sub, fmt.Errorf("failed to create webhook subscription: %w", ErrWalletNotFound)
+		}
+		return sub, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	return sub, nil
+}
+
+func (w *WebhookPsql) ListSubscriptions(ctx context.Context) ([]wallet.WebhookSubscription, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	rows, err := w.db.QueryContext(ctx, fmt.Sprintf(
+		`SELECT id, valletId, url, event_types, secret, created_at FROM %s ORDER BY id`, webhookTable))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []wallet.WebhookSubscription
+	for rows.Next() {
+		var (
+			sub      wallet.WebhookSubscription
+			walletID uuid.UUID
+		)
+		if err := rows.Scan(&sub.Id, &walletID, &sub.URL, pq.Array(&sub.EventTypes), &sub.Secret, &sub.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+		}
+		if walletID.Status == pgtype.Present {
+			sub.ValletId = &walletID
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+func (w *WebhookPsql) DeleteSubscription(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	err := w.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		res, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, webhookTable), id)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return ErrSubscriptionNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription %d: %w", id, err)
+	}
+	return nil
+}
+
+func (w *WebhookPsql) EnqueueEvent(ctx context.Context, event wallet.WalletEvent, payload []byte, now time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	// Подписки подбираются и доставки создаются одним запросом.
+	query := fmt.Sprintf(`INSERT INTO %s (subscription_id, event_id, event_type, payload, next_attempt_at, created_at)
+		SELECT id, $1, $2, $3, $4, $4 FROM %s
+		WHERE (valletId IS NULL OR valletId = $5) AND (cardinality(event_types) = 0 OR $2 = ANY(event_types))
+		ON CONFLICT (subscription_id, event_id) DO NOTHING`,
+		deliveryTable, webhookTable)
+	var n int64
+	err := w.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		res, err := tx.ExecContext(ctx, query, event.Id, event.Type, string(payload), now, event.ValletId)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue event %s: %w", event.Id, err)
+	}
+	return int(n), nil
+}
+
+func (w *WebhookPsql) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]wallet.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// SKIP LOCKED позволяет нескольким экземплярам сервиса разбирать очередь параллельно.
+	query := fmt.Sprintf(`WITH claimed AS (
+			UPDATE %[1]s d SET next_attempt_at = $1
+			FROM %[2]s s
+			WHERE s.id = d.subscription_id AND d.id IN (
+				SELECT id FROM %[1]s WHERE status = 'pending' AND next_attempt_at <= $2
+				ORDER BY next_attempt_at, id LIMIT $3 FOR UPDATE SKIP LOCKED)
+			RETURNING d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status, d.attempts, d.last_error,
+				d.next_attempt_at, d.created_at, s.url, s.secret)
+		SELECT * FROM claimed ORDER BY id`, deliveryTable, webhookTable)
+	var deliveries []wallet.WebhookDelivery
+	err := w.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		deliveries = nil
+		rows, err := tx.QueryContext(ctx, query, now.Add(lease), now, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var d wallet.WebhookDelivery
+			if err := rows.Scan(&d.Id, &d.SubscriptionId, &d.EventId, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+				&d.LastError, &d.NextAttemptAt, &d.CreatedAt, &d.URL, &d.Secret); err != nil {
+				return err
+			}
+			deliveries = append(deliveries, d)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (w *WebhookPsql) CompleteDelivery(ctx context.Context, id int64, attempts int, now time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	err := w.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(
+			`UPDATE %s SET status = 'delivered', attempts = $1, last_error = '', delivered_at = $2 WHERE id = $3`, deliveryTable),
+			attempts, now, id)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete webhook delivery %d: %w", id, err)
+	}
+	return nil
+}
+
+func (w *WebhookPsql) FailDelivery(ctx context.Context, id int64, attempts int, lastError string, nextAttemptAt time.Time, dead bool) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	status := wallet.DeliveryPending
+	if dead {
+		status = wallet.DeliveryDead
+	}
+
+	err := w.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(
+			`UPDATE %s SET status = $1, attempts = $2, last_error = $3, next_attempt_at = $4 WHERE id = $5`, deliveryTable),
+			status, attempts, lastError, nextAttemptAt, id)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery %d failure: %w", id, err)
+	}
+	return nil
+}
+
+func (w *WebhookPsql) ListDeadDeliveries(ctx context.Context, limit int) ([]wallet.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	query := fmt.Sprintf(`SELECT id, subscription_id, event_id, event_type, payload, status, attempts, last_error,
+		next_attempt_at, created_at FROM %s WHERE status = 'dead' ORDER BY id LIMIT $1`, deliveryTable)
+	rows, err := w.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]wallet.WebhookDelivery, 0)
+	for rows.Next() {
+		var d wallet.WebhookDelivery
+		if err := rows.Scan(&d.Id, &d.SubscriptionId, &d.EventId, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+			&d.LastError, &d.NextAttemptAt, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to list dead webhook deliveries: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (w *WebhookPsql) ReplayDelivery(ctx context.Context, id int64, now time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	err := w.tx.Run(ctx, func(tx *sqlx.Tx) error {
+		res, err := tx.ExecContext(ctx, fmt.Sprintf(
+			`UPDATE %s SET status = 'pending', attempts = 0, next_attempt_at = $1 WHERE id = $2 AND status = 'dead'`, deliveryTable),
+			now, id)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return ErrDeliveryNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to replay webhook delivery %d: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+)
+
+// WebhookMemory - реализация repository.Webhook в памяти процесса (DB_DRIVER=memory).
+type WebhookMemory struct {
+	mu         sync.Mutex
+	subs       map[int64]wallet.WebhookSubscription
+	deliveries map[int64]*wallet.WebhookDelivery
+	enqueued   map[string]bool // аналог уникального индекса (subscription_id, event_id)
+	lastSubID  int64
+	lastID     int64
+}
+
+func NewWebhookMemory() *WebhookMemory {
+	return &WebhookMemory{
+		subs:       make(map[int64]wallet.WebhookSubscription),
+		deliveries: make(map[int64]*wallet.WebhookDelivery),
+		enqueued:   make(map[string]bool),
+	}
+}
+
+func (m *WebhookMemory) CreateSubscription(ctx context.Context, sub wallet.WebhookSubscription) (wallet.WebhookSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastSubID++
+	sub.Id = m.lastSubID
+	sub.CreatedAt = time.Now()
+	sub.EventTypes = append([]string{}, sub.EventTypes...)
+	m.subs[sub.Id] = sub
+	return sub, nil
+}
+
+func (m *WebhookMemory) ListSubscriptions(ctx context.Context) ([]wallet.WebhookSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	subs := make([]wallet.WebhookSubscription, 0, len(m.subs))
+	for _, sub := range m.subs {
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].Id < subs[j].Id })
+	return subs, nil
+}
+
+func (m *WebhookMemory) DeleteSubscription(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.subs[id]; !ok {
+		return fmt.Errorf("failed to delete webhook subscription %d: %w", id, ErrSubscriptionNotFound)
+	}
+	delete(m.subs, id)
+
+	// Аналог ON DELETE CASCADE.
+	for did, d := range m.deliveries {
+		if d.SubscriptionId == id {
+			delete(m.deliveries, did)
+		}
+	}
+	return nil
+}
+
+func (m *WebhookMemory) EnqueueEvent(ctx context.Context, event wallet.WalletEvent, payload []byte, now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for _, sub := range m.subs {
+		key := fmt.Sprintf("%d/%s", sub.Id, event.Id)
+		if !subscriptionMatches(sub, event) || m.enqueued[key] {
+			continue
+		}
+		m.enqueued[key] = true
+
+		m.lastID++
+		m.deliveries[m.lastID] = &wallet.WebhookDelivery{
+			Id:             m.lastID,
+			SubscriptionId: sub.Id,
+			EventId:        event.Id,
+			EventType:      event.Type,
+			Payload:        append([]byte(nil), payload...),
+			Status:         wallet.DeliveryPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		}
+		n++
+	}
+	return n, nil
+}
+
+func subscriptionMatches(sub wallet.WebhookSubscription, event wallet.WalletEvent) bool {
+	if sub.ValletId != nil && sub.ValletId.UUID != event.ValletId.UUID {
+		return false
+	}
+	if len(sub.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range sub.EventTypes {
+		if t == event.Type {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *WebhookMemory) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]wallet.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []*wallet.WebhookDelivery
+	for _, d := range m.deliveries {
+		if d.Status == wallet.DeliveryPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+		}
+		return due[i].Id < due[j].Id
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]wallet.WebhookDelivery, 0, len(due))
+	for _, d := range due {
+		d.NextAttemptAt = now.Add(lease)
+
+		c := *d
+		sub := m.subs[d.SubscriptionId]
+		c.URL, c.Secret = sub.URL, sub.Secret
+		claimed = append(claimed, c)
+	}
+	sort.Slice(claimed, func(i, j int) bool { return claimed[i].Id < claimed[j].Id })
+	return claimed, nil
+}
+
+func (m *WebhookMemory) CompleteDelivery(ctx context.Context, id int64, attempts int, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if d, ok := m.deliveries[id]; ok {
+		d.Status = wallet.DeliveryDelivered
+		d.Attempts = attempts
+		d.LastError = ""
+	}
+	return nil
+}
+
+func (m *WebhookMemory) FailDelivery(ctx context.Context, id int64, attempts int, lastError string, nextAttemptAt time.Time, dead bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if d, ok := m.deliveries[id]; ok {
+		d.Status = wallet.DeliveryPending
+		if dead {
+			d.Status = wallet.DeliveryDead
+		}
+		d.Attempts = attempts
+		d.LastError = lastError
+		d.NextAttemptAt = nextAttemptAt
+	}
+	return nil
+}
+
+func (m *WebhookMemory) ListDeadDeliveries(ctx context.Context, limit int) ([]wallet.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dead := make([]wallet.WebhookDelivery, 0)
+	for _, d := range m.deliveries {
+		if d.Status == wallet.DeliveryDead {
+			dead = append(dead, *d)
+		}
+	}
+	sort.Slice(dead, func(i, j int) bool { return dead[i].Id < dead[j].Id })
+	if len(dead) > limit {
+		dead = dead[:limit]
+	}
+	return dead, nil
+}
+
+func (m *WebhookMemory) ReplayDelivery(ctx context.Context, id int64, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d, ok := m.deliveries[id]
+	if !ok || d.Status != wallet.DeliveryDead {
+		return fmt.Errorf("failed to replay webhook delivery %d: %w", id, ErrDeliveryNotFound)
+	}
+	d.Status = wallet.DeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = now
+	return nil
+}
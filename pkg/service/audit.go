@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// verifyPageSize - сколько транзакций читается из истории за один запрос при проверке цепочки.
+const verifyPageSize = 1000
+
+type AuditService struct {
+	wallets     repository.Wallet
+	checkpoints repository.Checkpoint
+	key         []byte
+}
+
+func NewAuditService(wallets repository.Wallet, checkpoints repository.Checkpoint, key []byte) *AuditService {
+	return &AuditService{wallets: wallets, checkpoints: checkpoints, key: key}
+}
+
+// VerifyChain проходит историю кошелька по порядку seq и пересчитывает цепочку хешей.
+// Проверяется непрерывность seq, хеш и баланс после каждой операции, совпадение с подписанными
+// контрольными точками и с головой цепочки в строке кошелька. Первое нарушение возвращается в отчёте,
+// ошибка - только если проверку не удалось провести.
+func (s *AuditService) VerifyChain(ctx context.Context, walletID uuid.UUID) (wallet.ChainReport, error) {
+	report := wallet.ChainReport{ValletId: walletID}
+
+	// Контрольные точки читаются до кошелька: так все они гарантированно не новее прочитанной головы цепочки,
+	// а транзакции, записанные во время проверки, просто не попадают в неё.
+	checkpoints, err := s.checkpoints.ListCheckpoints(ctx, walletID)
+	if err != nil {
+		return report, err
+	}
+	report.Checkpoints = len(checkpoints)
+
+	wlt, err := s.wallets.GetWallet(ctx, walletID)
+	if err != nil {
+		return report, err
+	}
+
+	for _, cp := range checkpoints {
+		if len(s.key) > 0 && !hmac.Equal([]byte(cp.Signature), []byte(s.sign(cp))) {
+			return broken(report, cp.Seq, "invalid checkpoint signature"), nil
+		}
+		if cp.Seq > wlt.LastSeq {
+			return broken(report, cp.Seq, "signed checkpoint is beyond the wallet chain head"), nil
+		}
+	}
+
+	var (
+		prev     wallet.WalletTransactions
+		nextCp   int
+		afterSeq int64
+	)
+	for prev.Seq < wlt.LastSeq {
+		page, err := s.wallets.ListTransactions(ctx, walletID, afterSeq, verifyPageSize)
+		if err != nil {
+			return report, err
+		}
+		if len(page) == 0 {
+			break
+		}
+
+		for _, WT := range page {
+			if WT.Seq > wlt.LastSeq {
+				break
+			}
+			if WT.Seq != prev.Seq+1 {
+				return broken(report, prev.Seq+1, fmt.Sprintf("missing transaction: next seq is %d", WT.Seq)), nil
+			}
+			if prev.Seq > 0 && !balanceFollows(prev.BalanceAfter, WT) {
+				return broken(report, WT.Seq, "balance after does not follow previous transaction"), nil
+			}
+			if WT.Hash != wallet.ChainHash(prev.Hash, WT) {
+				return broken(report, WT.Seq, "hash mismatch"), nil
+			}
+
+			for nextCp < len(checkpoints) && checkpoints[nextCp].Seq == WT.Seq {
+				if checkpoints[nextCp].Hash != WT.Hash {
+					return broken(report, WT.Seq, "hash differs from signed checkpoint"), nil
+				}
+				nextCp++
+			}
+
+			prev = WT
+			report.Checked++
+			report.Head = WT.Hash
+		}
+		afterSeq = page[len(page)-1].Seq
+	}
+
+	if prev.Seq != wlt.LastSeq {
+		return broken(report, prev.Seq+1, "history ends before the wallet chain head"), nil
+	}
+	if prev.Hash != wlt.LastHash {
+		return broken(report, prev.Seq, "hash differs from the wallet chain head"), nil
+	}
+
+	report.Valid = true
+	return report, nil
+}
+
+// CreateCheckpoints подписывает и сохраняет текущие головы цепочек всех кошельков с новыми транзакциями.
+// Возвращает число созданных контрольных точек.
+func (s *AuditService) CreateCheckpoints(ctx context.Context) (int, error) {
+	if len(s.key) == 0 {
+		return 0, fmt.Errorf("checkpoint signing key is not configured")
+	}
+
+	heads, err := s.checkpoints.ChainHeads(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for i := range heads {
+		heads[i].Signature = s.sign(heads[i])
+	}
+
+	if err := s.checkpoints.SaveCheckpoints(ctx, heads); err != nil {
+		return 0, err
+	}
+	return len(heads), nil
+}
+
+// sign - HMAC-SHA256 (hex) от "valletId\nseq\nhash".
+func (s *AuditService) sign(cp wallet.Checkpoint) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(cp.ValletId.UUID.String() + "\n" + strconv.FormatInt(cp.Seq, 10) + "\n" + cp.Hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func balanceFollows(before float64, WT wallet.WalletTransactions) bool {
+	delta := WT.Amount
+	if WT.OperationType == "WITHDRAW" {
+		delta = -delta
+	}
+	// Суммы хранятся в копейках (NUMERIC(18, 2)), сравниваем с точностью до половины копейки.
+	diff := before + delta - WT.BalanceAfter
+	return diff > -0.005 && diff < 0.005
+}
+
+func broken(report wallet.ChainReport, seq int64, reason string) wallet.ChainReport {
+	report.Valid = false
+	report.BrokenSeq = seq
+	report.Reason = reason
+	return report
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tamperedHistory подменяет историю кошелька при чтении, имитируя правку строк wallet_transactions в обход сервиса.
+type tamperedHistory struct {
+	repository.Wallet
+	tamper func(history []wallet.WalletTransactions) []wallet.WalletTransactions
+}
+
+func (r tamperedHistory) ListTransactions(ctx context.Context, uid uuid.UUID, afterSeq int64, limit int) ([]wallet.WalletTransactions, error) {
+	history, err := r.Wallet.ListTransactions(ctx, uid, afterSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	return r.tamper(history), nil
+}
+
+func TestAuditService_VerifyChain(t *testing.T) {
+	ctx := context.Background()
+	key := []byte("secret")
+
+	var walletID uuid.UUID
+	require.NoError(t, walletID.Scan("11111111-1111-1111-1111-111111111111"))
+
+	// Кошелёк с пятью транзакциями и контрольной точкой на seq = 3.
+	newRepo := func(t *testing.T) *repository.Repository {
+		mem := repository.NewWalletMemory()
+		mem.AddWallet(walletID, 100)
+		repo := repository.NewMemoryRepository(mem)
+
+		apply := func(op string, amount float64) {
+			_, results, err := repo.ApplyTransactions(ctx, walletID, []wallet.WalletTransactions{{OperationType: op, Amount: amount}})
+			require.NoError(t, err)
+			require.NoError(t, results[0])
+		}
+		apply("DEPOSIT", 10)
+		apply("WITHDRAW", 20)
+		apply("DEPOSIT", 1.5)
+
+		n, err := NewAuditService(repo.Wallet, repo.Checkpoint, key).CreateCheckpoints(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+
+		apply("WITHDRAW", 50)
+		apply("DEPOSIT", 0.01)
+		return repo
+	}
+
+	testTable := []struct {
+		name      string
+		tamper    func(history []wallet.WalletTransactions) []wallet.WalletTransactions
+		key       []byte
+		valid     bool
+		brokenSeq int64
+		reason    string
+	}{
+		{
+			name:  "valid",
+			key:   key,
+			valid: true,
+		},
+		{
+			name: "edited amount",
+			tamper: func(history []wallet.WalletTransactions) []wallet.WalletTransactions {
+				history[3].Amount = 5
+				return history
+			},
+			key:       key,
+			brokenSeq: 4,
+			reason:    "balance after does not follow previous transaction",
+		},
+		{
+			name: "edited row with recomputed hash",
+			tamper: func(history []wallet.WalletTransactions) []wallet.WalletTransactions {
+				history[3].OperationType = "DEPOSIT"
+				history[3].BalanceAfter = history[2].BalanceAfter + history[3].Amount
+				history[3].Hash = wallet.ChainHash(history[2].Hash, history[3])
+				return history
+			},
+			key:       key,
+			brokenSeq: 5,
+			reason:    "balance after does not follow previous transaction",
+		},
+		{
+			name: "whole chain rewritten",
+			tamper: func(history []wallet.WalletTransactions) []wallet.WalletTransactions {
+				prev, balance := "", 100.0
+				for i := range history {
+					history[i].Amount++
+					if history[i].OperationType == "DEPOSIT" {
+						balance += history[i].Amount
+					} else {
+						balance -= history[i].Amount
+					}
+					history[i].BalanceAfter = balance
+					history[i].Hash = wallet.ChainHash(prev, history[i])
+					prev = history[i].Hash
+				}
+				return history
+			},
+			key:       key,
+			brokenSeq: 3,
+			reason:    "hash differs from signed checkpoint",
+		},
+		{
+			name: "deleted row",
+			tamper: func(history []wallet.WalletTransactions) []wallet.WalletTransactions {
+				return append(history[:1], history[2:]...)
+			},
+			key:       key,
+			brokenSeq: 2,
+			reason:    "missing transaction: next seq is 3",
+		},
+		{
+			name: "truncated history",
+			tamper: func(history []wallet.WalletTransactions) []wallet.WalletTransactions {
+				// Последняя транзакция удалена: её нет ни на одной странице.
+				kept := history[:0]
+				for _, WT := range history {
+					if WT.Seq != 5 {
+						kept = append(kept, WT)
+					}
+				}
+				return kept
+			},
+			key:       key,
+			brokenSeq: 5,
+			reason:    "history ends before the wallet chain head",
+		},
+		{
+			name:      "wrong checkpoint key",
+			key:       []byte("other"),
+			brokenSeq: 3,
+			reason:    "invalid checkpoint signature",
+		},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			repo := newRepo(t)
+
+			var wallets repository.Wallet = repo.Wallet
+			if test.tamper != nil {
+				wallets = tamperedHistory{Wallet: repo.Wallet, tamper: test.tamper}
+			}
+
+			report, err := NewAuditService(wallets, repo.Checkpoint, test.key).VerifyChain(ctx, walletID)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.valid, report.Valid)
+			assert.Equal(t, test.brokenSeq, report.BrokenSeq)
+			assert.Equal(t, test.reason, report.Reason)
+			assert.Equal(t, 1, report.Checkpoints)
+			if test.valid {
+				assert.Equal(t, int64(5), report.Checked)
+			}
+		})
+	}
+}
+
+func TestAuditService_VerifyChain_MissingWallet(t *testing.T) {
+	repo := repository.NewMemoryRepository(repository.NewWalletMemory())
+
+	var walletID uuid.UUID
+	require.NoError(t, walletID.Scan("99999999-9999-9999-9999-999999999999"))
+
+	_, err := NewAuditService(repo.Wallet, repo.Checkpoint, nil).VerifyChain(context.Background(), walletID)
+	assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/fee"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// batchWorkers - сколько кошельков пакета best-effort применяется параллельно.
+const batchWorkers = 8
+
+// ApplyBatch применяет пакет операций разных кошельков (например, начисление зарплат).
+// В режиме atomic пакет применяется в одной транзакции БД целиком или не применяется вовсе
+// (тогда возвращается ErrBatchAborted и причины в результатах). Иначе операции применяются
+// по кошелькам независимо, и результат у каждой операции свой.
+// Операции должны быть проверены вызывающим (тип, сумма), как и для UpdateBalance.
+// Комиссия за снятие списывается вместе с операцией, как в UpdateBalance: если не хватает средств на операцию
+// или комиссию, операция отклоняется целиком.
+func (s *WalletService) ApplyBatch(ctx context.Context, ops []wallet.WalletTransactions, atomic bool) ([]wallet.BatchResult, error) {
+	for i := range ops {
+		ops[i].Kind, ops[i].OperationId = "", nil
+	}
+
+	charges, err := s.batchFees(ctx, ops)
+	if err != nil {
+		return nil, err
+	}
+
+	if atomic {
+		return s.applyAtomic(ctx, ops, charges)
+	}
+
+	results := make([]wallet.BatchResult, len(ops))
+
+	// Операции одного кошелька идут одной пачкой в исходном порядке, кошельки - параллельно.
+	groups := make(map[string][]int)
+	order := make([]string, 0)
+	for i, WT := range ops {
+		id := WT.ValletId.UUID.String()
+		if _, ok := groups[id]; !ok {
+			order = append(order, id)
+		}
+		groups[id] = append(groups[id], i)
+	}
+
+	jobs := make(chan []int)
+	var wg sync.WaitGroup
+	for w := 0; w < batchWorkers && w < len(order); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				s.applyGroup(ctx, ops, charges, idx, results)
+			}
+		}()
+	}
+	for _, id := range order {
+		jobs <- groups[id]
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, nil
+}
+
+// batchFees возвращает комиссию за каждую операцию пакета (0 - без комиссии).
+// Для неизвестного кошелька комиссия не считается: операция будет отклонена при применении.
+func (s *WalletService) batchFees(ctx context.Context, ops []wallet.WalletTransactions) ([]float64, error) {
+	charges := make([]float64, len(ops))
+	if !s.fees.Charges(fee.Withdraw) {
+		return charges, nil
+	}
+
+	// Уровни всех плательщиков пачки читаются одним запросом.
+	payers := make([]uuid.UUID, 0)
+	seen := make(map[string]bool)
+	for _, WT := range ops {
+		id := WT.ValletId.UUID.String()
+		if WT.OperationType == "WITHDRAW" && WT.ValletId.UUID != s.feeWallet.UUID && !seen[id] {
+			seen[id] = true
+			payers = append(payers, WT.ValletId)
+		}
+	}
+	if len(payers) == 0 {
+		return charges, nil
+	}
+	tiers, err := s.repo.WalletTiers(ctx, payers)
+	if err != nil {
+		return nil, err
+	}
+
+	charged := make([]wallet.WalletTransactions, 0)
+	for i, WT := range ops {
+		tier, ok := tiers[WT.ValletId.UUID.String()]
+		if WT.OperationType != "WITHDRAW" || WT.ValletId.UUID == s.feeWallet.UUID || !ok {
+			continue
+		}
+		charge := s.fees.Fee(tier, fee.Withdraw, WT.Amount)
+		charges[i] = charge
+		if charge > 0 && WT.IdempotencyKey != "" {
+			charged = append(charged, WT)
+		}
+	}
+	if len(charged) == 0 {
+		return charges, nil
+	}
+
+	// Повтор операции, записанной до введения комиссии, не должен списать комиссию задним числом.
+	known, err := s.repo.FindIdempotent(ctx, charged)
+	if err != nil {
+		return nil, err
+	}
+	for i, WT := range ops {
+		if _, ok := known[WT.ValletId.UUID.String()][WT.IdempotencyKey]; ok && WT.IdempotencyKey != "" {
+			charges[i] = 0
+		}
+	}
+	return charges, nil
+}
+
+// applyGroup применяет операции одного кошелька с позициями idx в исходном порядке. Подряд идущие операции
+// без комиссии идут одной пачкой, операция с комиссией - вместе со своей комиссией через applyWithFee.
+func (s *WalletService) applyGroup(ctx context.Context, ops []wallet.WalletTransactions, charges []float64, idx []int,
+	results []wallet.BatchResult) {
+	for start := 0; start < len(idx); {
+		if i := idx[start]; charges[i] > 0 {
+			recorded, err := s.applyWithFee(ctx, ops[i], charges[i])
+			results[i] = toBatchResult(i, recorded, err)
+			start++
+			continue
+		}
+
+		end := start
+		for end < len(idx) && charges[idx[end]] == 0 {
+			end++
+		}
+		group := make([]wallet.WalletTransactions, end-start)
+		for j, i := range idx[start:end] {
+			group[j] = ops[i]
+		}
+
+		recorded, errs, err := s.repo.ApplyTransactions(ctx, group[0].ValletId, group)
+		for j, i := range idx[start:end] {
+			if err != nil {
+				results[i] = toBatchResult(i, wallet.WalletTransactions{}, err)
+				continue
+			}
+			results[i] = toBatchResult(i, recorded[j], errs[j])
+		}
+		start = end
+	}
+}
+
+func (s *WalletService) applyAtomic(ctx context.Context, ops []wallet.WalletTransactions, charges []float64) ([]wallet.BatchResult, error) {
+	// Комиссии добавляются в ту же транзакцию БД сразу за своей операцией; pos - позиция операции в all.
+	all := make([]wallet.WalletTransactions, 0, len(ops))
+	pos := make([]int, len(ops))
+	for i, WT := range ops {
+		pos[i] = len(all)
+		if charges[i] == 0 {
+			all = append(all, WT)
+			continue
+		}
+
+		operationID, err := newOperationID()
+		if err != nil {
+			return nil, err
+		}
+		WT.OperationId = operationID
+		all = append(all, WT)
+		all = append(all, s.feeLegs(WT.ValletId, WT.IdempotencyKey, charges[i], operationID)...)
+	}
+
+	recorded, errs, err := s.repo.ApplyAtomic(ctx, all)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]wallet.BatchResult, len(ops))
+	aborted := false
+	for i := range ops {
+		p := pos[i]
+		WT, err := recorded[p], errs[p]
+		if charges[i] > 0 {
+			if feeErr := rejection(errs[p+1 : p+3]); err == nil && feeErr != nil {
+				err = feeErr
+			}
+			if err == nil {
+				// ETag должен соответствовать кошельку уже после списания комиссии.
+				WT.Version = recorded[p+1].Version
+			}
+		}
+		results[i] = toBatchResult(i, WT, err)
+		if results[i].Status == wallet.BatchRejected {
+			aborted = true
+		}
+	}
+	if !aborted {
+		return results, nil
+	}
+
+	for i := range results {
+		if results[i].Status == wallet.BatchApplied {
+			results[i] = wallet.BatchResult{Index: i, Status: wallet.BatchAborted}
+		}
+	}
+	return results, ErrBatchAborted
+}
+
+func toBatchResult(i int, WT wallet.WalletTransactions, err error) wallet.BatchResult {
+	switch {
+	case err == nil:
+		return wallet.BatchResult{Index: i, Status: wallet.BatchApplied, Transaction: &WT}
+	case errors.Is(err, repository.ErrDuplicateTransaction):
+		return wallet.BatchResult{Index: i, Status: wallet.BatchDuplicate, Transaction: &WT}
+	default:
+		return wallet.BatchResult{Index: i, Status: wallet.BatchRejected, Error: err.Error()}
+	}
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+const defaultHotWalletMaxBatch = 100
+
+type batchRequest struct {
+	WT   wallet.WalletTransactions
+	done chan batchResult
+}
+
+type batchResult struct {
+	WT  wallet.WalletTransactions
+	err error
+}
+
+// walletBatcher - групповая запись для "горячего" кошелька.
+// Пока одна пачка операций применяется в БД, новые запросы копятся в очереди и уходят следующей пачкой
+// в одной транзакции с одной блокировкой строки. Проверка на овердрафт выполняется для каждой операции
+// по порядку, и каждый вызывающий получает свой собственный результат.
+type walletBatcher struct {
+	repo     repository.Wallet
+	walletID uuid.UUID
+	maxBatch int
+	queue    chan batchRequest
+	// stop закрывается в Close, done - когда run завершился.
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newWalletBatcher(repo repository.Wallet, walletID uuid.UUID, maxBatch int) *walletBatcher {
+	if maxBatch <= 0 {
+		maxBatch = defaultHotWalletMaxBatch
+	}
+
+	b := &walletBatcher{
+		repo:     repo,
+		walletID: walletID,
+		maxBatch: maxBatch,
+		queue:    make(chan batchRequest, maxBatch),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go b.run()
+
+	return b
+}
+
+// submit ставит операцию в очередь и ждёт её результата.
+// Если контекст отменён после постановки в очередь, операция всё равно может быть применена.
+func (b *walletBatcher) submit(ctx context.Context, WT wallet.WalletTransactions) (wallet.WalletTransactions, error) {
+	req := batchRequest{WT: WT, done: make(chan batchResult, 1)}
+
+	select {
+	case b.queue <- req:
+	case <-b.done:
+		return wallet.WalletTransactions{}, ErrClosed
+	case <-ctx.Done():
+		return wallet.WalletTransactions{}, ctx.Err()
+	}
+
+	select {
+	case res := <-req.done:
+		return res.WT, res.err
+	case <-b.done:
+		// Пачка, начатая до Close, успевает записать результат до закрытия done.
+		select {
+		case res := <-req.done:
+			return res.WT, res.err
+		default:
+			return wallet.WalletTransactions{}, ErrClosed
+		}
+	case <-ctx.Done():
+		return wallet.WalletTransactions{}, ctx.Err()
+	}
+}
+
+// Close останавливает обработку очереди и ждёт, пока текущая пачка будет применена.
+// Операции, не попавшие в пачку, не применяются и завершаются с ErrClosed.
+func (b *walletBatcher) Close() {
+	b.closeOnce.Do(func() { close(b.stop) })
+	<-b.done
+}
+
+func (b *walletBatcher) run() {
+	defer close(b.done)
+
+	batch := make([]batchRequest, 0, b.maxBatch)
+	ops := make([]wallet.WalletTransactions, 0, b.maxBatch)
+
+	for {
+		var first batchRequest
+		select {
+		case first = <-b.queue:
+		case <-b.stop:
+			return
+		}
+		batch = append(batch[:0], first)
+	drain:
+		for len(batch) < b.maxBatch {
+			select {
+			case req := <-b.queue:
+				batch = append(batch, req)
+			default:
+				break drain
+			}
+		}
+
+		ops = ops[:0]
+		for _, req := range batch {
+			ops = append(ops, req.WT)
+		}
+
+		// Запрос применяется от имени всей пачки, поэтому не зависит от контекста отдельного вызывающего.
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		recorded, results, err := b.repo.ApplyTransactions(ctx, b.walletID, ops)
+		cancel()
+
+		if err != nil {
+			log.Printf("hot wallet %s: batch of %d failed: %s", b.walletID.UUID.String(), len(batch), err.Error())
+		}
+		for i, req := range batch {
+			if err != nil {
+				req.done <- batchResult{err: err}
+				continue
+			}
+			req.done <- batchResult{WT: recorded[i], err: results[i]}
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/KatenkaKet/wallet"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// maxCreditLimit - наибольший кредитный лимит; с запасом помещается в NUMERIC(18, 2).
+const maxCreditLimit = 1e12
+
+// SetCreditLimit меняет кредитный лимит кошелька: баланс может опускаться до -limit. Лимит округляется до копеек
+// и не может быть меньше текущей задолженности (repository.ErrCreditLimitBelowDebt).
+func (s *WalletService) SetCreditLimit(ctx context.Context, walletID uuid.UUID, limit float64) (wallet.Wallet, error) {
+	if math.IsNaN(limit) || limit < 0 || limit > maxCreditLimit {
+		return wallet.Wallet{}, fmt.Errorf("%w: credit limit must be between 0 and %.0f", ErrInvalidCreditLimit, maxCreditLimit)
+	}
+	return s.repo.SetCreditLimit(ctx, walletID, math.Round(limit*100)/100)
+}
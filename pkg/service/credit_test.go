@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalletService_SetCreditLimit(t *testing.T) {
+	ctx := context.Background()
+
+	var a uuid.UUID
+	require.NoError(t, a.Scan("11111111-1111-1111-1111-111111111111"))
+
+	mem := repository.NewWalletMemory()
+	mem.AddWallet(a, 10)
+	wallets := NewWalletService(mem, Config{})
+
+	for _, limit := range []float64{-1, math.NaN(), math.Inf(1), 2e12} {
+		_, err := wallets.SetCreditLimit(ctx, a, limit)
+		assert.ErrorIs(t, err, ErrInvalidCreditLimit, "limit %v", limit)
+	}
+
+	wlt, err := wallets.SetCreditLimit(ctx, a, 100.004)
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, wlt.CreditLimit)
+	assert.Equal(t, 110.0, wlt.Available)
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/export"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// exportPage - сколько транзакций читается из БД за раз при выгрузке истории.
+const exportPage = 500
+
+// ExportTransactions выгружает историю кошелька за период [from, to) в w: начальный баланс, транзакции
+// в порядке seq и конечный баланс. История читается страницами по exportPage и сразу пишется в w.
+// Нулевые from и to - с первой транзакции и по текущий момент.
+// Если ошибка возвращена до вызова w.Begin, в w ещё ничего не записано.
+func (s *WalletService) ExportTransactions(ctx context.Context, walletID uuid.UUID, from, to time.Time, w export.Writer) error {
+	wlt, err := s.repo.GetWallet(ctx, walletID)
+	if err != nil {
+		return err
+	}
+
+	afterSeq, lastSeq, err := s.repo.PeriodSeq(ctx, walletID, from, to)
+	if err != nil {
+		return err
+	}
+
+	page, err := s.repo.ListTransactions(ctx, walletID, afterSeq, exportPage)
+	if err != nil {
+		return err
+	}
+
+	// Начальный баланс - баланс после транзакции afterSeq, то есть до первой транзакции из page.
+	opening := wlt.Balance
+	switch {
+	case len(page) > 0:
+		opening = balanceBefore(page[0])
+	case afterSeq > 0:
+		last, err := s.repo.ListTransactions(ctx, walletID, afterSeq-1, 1)
+		if err != nil {
+			return err
+		}
+		if len(last) > 0 {
+			opening = last[0].BalanceAfter
+		}
+	}
+
+	h := export.Header{ValletId: walletID, From: from, To: to, OpeningBalance: opening}
+	if h.To.IsZero() {
+		h.To = time.Now()
+	}
+	if h.From.IsZero() {
+		h.From = h.To
+		if len(page) > 0 && page[0].Seq <= lastSeq {
+			h.From = page[0].CreatedAt
+		}
+	}
+
+	if err := w.Begin(h); err != nil {
+		return err
+	}
+
+	closing := opening
+	for {
+		for _, WT := range page {
+			if WT.Seq > lastSeq {
+				return w.End(closing)
+			}
+			if err := w.Transaction(WT); err != nil {
+				return err
+			}
+			closing = WT.BalanceAfter
+		}
+		if len(page) < exportPage {
+			return w.End(closing)
+		}
+
+		page, err = s.repo.ListTransactions(ctx, walletID, page[len(page)-1].Seq, exportPage)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// balanceBefore возвращает баланс кошелька до транзакции WT.
+func balanceBefore(WT wallet.WalletTransactions) float64 {
+	delta := WT.Amount
+	if WT.OperationType == "WITHDRAW" {
+		delta = -delta
+	}
+	return math.Round((WT.BalanceAfter-delta)*100) / 100
+}
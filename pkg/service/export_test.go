@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/export"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingWriter запоминает, что было передано в export.Writer.
+type recordingWriter struct {
+	header  *export.Header
+	seqs    []int64
+	closing *float64
+}
+
+func (w *recordingWriter) Begin(h export.Header) error {
+	w.header = &h
+	return nil
+}
+
+func (w *recordingWriter) Transaction(WT wallet.WalletTransactions) error {
+	w.seqs = append(w.seqs, WT.Seq)
+	return nil
+}
+
+func (w *recordingWriter) End(closingBalance float64) error {
+	w.closing = &closingBalance
+	return nil
+}
+
+func TestWalletService_ExportTransactions(t *testing.T) {
+	ctx := context.Background()
+
+	var a, b, empty, missing uuid.UUID
+	require.NoError(t, a.Scan("11111111-1111-1111-1111-111111111111"))
+	require.NoError(t, b.Scan("22222222-2222-2222-2222-222222222222"))
+	require.NoError(t, empty.Scan("33333333-3333-3333-3333-333333333333"))
+	require.NoError(t, missing.Scan("99999999-9999-9999-9999-999999999999"))
+
+	mem := repository.NewWalletMemory()
+	mem.AddWallet(a, 100)
+	mem.AddWallet(b, 0)
+	mem.AddWallet(empty, 7)
+	wallets := NewWalletService(mem, Config{})
+
+	var recorded []wallet.WalletTransactions
+	for _, WT := range []wallet.WalletTransactions{
+		{ValletId: a, OperationType: "DEPOSIT", Amount: 10},
+		{ValletId: a, OperationType: "WITHDRAW", Amount: 5},
+		{ValletId: a, OperationType: "DEPOSIT", Amount: 1},
+	} {
+		WT, err := wallets.UpdateBalance(ctx, WT)
+		require.NoError(t, err)
+		recorded = append(recorded, WT)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	t.Run("period", func(t *testing.T) {
+		var w recordingWriter
+		require.NoError(t, wallets.ExportTransactions(ctx, a, recorded[1].CreatedAt, recorded[2].CreatedAt, &w))
+
+		require.NotNil(t, w.header)
+		assert.Equal(t, 110.0, w.header.OpeningBalance)
+		assert.Equal(t, recorded[1].CreatedAt, w.header.From)
+		assert.Equal(t, []int64{2}, w.seqs)
+		require.NotNil(t, w.closing)
+		assert.Equal(t, 105.0, *w.closing)
+	})
+
+	t.Run("period without transactions", func(t *testing.T) {
+		var w recordingWriter
+		from := recorded[2].CreatedAt.Add(time.Hour)
+		require.NoError(t, wallets.ExportTransactions(ctx, a, from, time.Time{}, &w))
+
+		assert.Equal(t, 106.0, w.header.OpeningBalance)
+		assert.Empty(t, w.seqs)
+		assert.Equal(t, 106.0, *w.closing)
+	})
+
+	t.Run("whole history in pages", func(t *testing.T) {
+		ops := make([]wallet.WalletTransactions, 2*exportPage+1)
+		for i := range ops {
+			ops[i] = wallet.WalletTransactions{ValletId: b, OperationType: "DEPOSIT", Amount: 1}
+		}
+		_, _, err := mem.ApplyTransactions(ctx, b, ops)
+		require.NoError(t, err)
+
+		var w recordingWriter
+		require.NoError(t, wallets.ExportTransactions(ctx, b, time.Time{}, time.Time{}, &w))
+
+		assert.Equal(t, 0.0, w.header.OpeningBalance)
+		require.Len(t, w.seqs, len(ops))
+		assert.Equal(t, int64(len(ops)), w.seqs[len(ops)-1])
+		assert.Equal(t, float64(len(ops)), *w.closing)
+	})
+
+	t.Run("wallet without transactions", func(t *testing.T) {
+		var w recordingWriter
+		require.NoError(t, wallets.ExportTransactions(ctx, empty, time.Time{}, time.Time{}, &w))
+
+		assert.Equal(t, 7.0, w.header.OpeningBalance)
+		assert.Equal(t, 7.0, *w.closing)
+	})
+
+	t.Run("missing wallet", func(t *testing.T) {
+		var w recordingWriter
+		err := wallets.ExportTransactions(ctx, missing, time.Time{}, time.Time{}, &w)
+		assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+		assert.Nil(t, w.header)
+	})
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	gofrs "github.com/gofrs/uuid"
+	"github.com/jackc/pgtype"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+var tierPattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// SetTier меняет уровень кошелька, по которому выбирается расписание комиссий.
+func (s *WalletService) SetTier(ctx context.Context, walletID uuid.UUID, tier string) (wallet.Wallet, error) {
+	if !tierPattern.MatchString(tier) {
+		return wallet.Wallet{}, fmt.Errorf("%w: tier must be 1-32 characters of a-z, 0-9, '_' and '-'", ErrInvalidTier)
+	}
+	return s.repo.SetTier(ctx, walletID, tier)
+}
+
+// feeFor возвращает комиссию за операцию operation на сумму amount с кошелька walletID по его уровню.
+// С самого кошелька комиссий комиссия не берётся.
+func (s *WalletService) feeFor(ctx context.Context, walletID uuid.UUID, operation string, amount float64) (float64, error) {
+	if !s.fees.Charges(operation) || walletID.UUID == s.feeWallet.UUID {
+		return 0, nil
+	}
+
+	wlt, err := s.repo.GetWallet(ctx, walletID)
+	if err != nil {
+		return 0, err
+	}
+	return s.fees.Fee(wlt.Tier, operation, amount), nil
+}
+
+// feeLegs - списание комиссии amount с кошелька payer и её зачисление на кошелёк комиссий.
+// Ключи идемпотентности комиссии выводятся из ключа операции key, чтобы повтор операции не списал комиссию дважды.
+func (s *WalletService) feeLegs(payer uuid.UUID, key string, amount float64, operationID *uuid.UUID) []wallet.WalletTransactions {
+	debit := wallet.WalletTransactions{ValletId: payer, OperationType: "WITHDRAW", Amount: amount,
+		Kind: wallet.KindFee, OperationId: operationID}
+	credit := wallet.WalletTransactions{ValletId: s.feeWallet, OperationType: "DEPOSIT", Amount: amount,
+		Kind: wallet.KindFee, OperationId: operationID}
+	if key != "" {
+		debit.IdempotencyKey = "fee:" + key
+		credit.IdempotencyKey = "fee:" + payer.UUID.String() + ":" + key
+	}
+	return []wallet.WalletTransactions{debit, credit}
+}
+
+// applyWithFee применяет операцию WT вместе с комиссией charge в одной транзакции БД: если не хватает средств
+// на операцию или комиссию, не применяется ничего. Горячие кошельки в этом случае обходят пачки.
+func (s *WalletService) applyWithFee(ctx context.Context, WT wallet.WalletTransactions, charge float64) (wallet.WalletTransactions, error) {
+	// Повтор операции, записанной до введения комиссии, не должен списать комиссию задним числом.
+	if WT.IdempotencyKey != "" {
+		known, err := s.repo.FindIdempotent(ctx, []wallet.WalletTransactions{WT})
+		if err != nil {
+			return wallet.WalletTransactions{}, err
+		}
+		if original, ok := known[WT.ValletId.UUID.String()][WT.IdempotencyKey]; ok {
+			return original, fmt.Errorf("%w for wallet %s: idempotency key %q",
+				repository.ErrDuplicateTransaction, WT.ValletId.UUID.String(), WT.IdempotencyKey)
+		}
+	}
+
+	operationID, err := newOperationID()
+	if err != nil {
+		return wallet.WalletTransactions{}, err
+	}
+	WT.OperationId = operationID
+
+	ops := append([]wallet.WalletTransactions{WT}, s.feeLegs(WT.ValletId, WT.IdempotencyKey, charge, operationID)...)
+	recorded, results, err := s.repo.ApplyAtomic(ctx, ops)
+	if err != nil {
+		return wallet.WalletTransactions{}, err
+	}
+	if err := rejection(results); err != nil {
+		return wallet.WalletTransactions{}, err
+	}
+
+	if results[0] != nil {
+		return recorded[0], results[0]
+	}
+	// ETag должен соответствовать кошельку уже после списания комиссии.
+	recorded[0].Version = recorded[1].Version
+	return recorded[0], nil
+}
+
+// transferWithFee переводит amount с from на to и списывает с from комиссию charge в одной транзакции БД.
+func (s *WalletService) transferWithFee(ctx context.Context, from, to uuid.UUID, amount, charge float64) (wallet.Transfer, error) {
+	operationID, err := newOperationID()
+	if err != nil {
+		return wallet.Transfer{}, err
+	}
+
+	ops := append([]wallet.WalletTransactions{
+		{ValletId: from, OperationType: "WITHDRAW", Amount: amount, OperationId: operationID},
+		{ValletId: to, OperationType: "DEPOSIT", Amount: amount, OperationId: operationID},
+	}, s.feeLegs(from, "", charge, operationID)...)
+	recorded, results, err := s.repo.ApplyAtomic(ctx, ops)
+	if err != nil {
+		return wallet.Transfer{}, err
+	}
+	if err := rejection(results); err != nil {
+		return wallet.Transfer{}, err
+	}
+
+	return wallet.Transfer{From: recorded[0], To: recorded[1], Fee: &recorded[2]}, nil
+}
+
+// rejection возвращает первую причину, по которой ApplyAtomic не применил операции; повторы причиной не считаются.
+func rejection(results []error) error {
+	for _, err := range results {
+		if err != nil && !errors.Is(err, repository.ErrDuplicateTransaction) {
+			return err
+		}
+	}
+	return nil
+}
+
+func newOperationID() (*uuid.UUID, error) {
+	id, err := gofrs.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate operation id: %w", err)
+	}
+	return &uuid.UUID{UUID: id, Status: pgtype.Present}, nil
+}
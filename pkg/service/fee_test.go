@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/fee"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalletService_Fees(t *testing.T) {
+	ctx := context.Background()
+
+	var a, b, fees uuid.UUID
+	require.NoError(t, a.Scan("11111111-1111-1111-1111-111111111111"))
+	require.NoError(t, b.Scan("22222222-2222-2222-2222-222222222222"))
+	require.NoError(t, fees.Scan("00000000-0000-0000-0000-000000000fee"))
+
+	mem := repository.NewWalletMemory()
+	mem.AddWallet(a, 100)
+	mem.AddWallet(b, 100)
+	mem.AddWallet(fees, 0)
+
+	schedule, err := fee.New([]fee.Rule{
+		{Operation: fee.Withdraw, Fixed: 1, Percent: 1, Max: 1.5},
+		{Operation: fee.Transfer, Fixed: 0.5},
+		{Tier: "premium", Operation: fee.Withdraw},
+	})
+	require.NoError(t, err)
+	// Горячий кошелёк с комиссией обходит пачки.
+	wallets := NewWalletService(mem, Config{HotWallets: []uuid.UUID{a}, Fees: schedule, FeeWallet: fees})
+	defer wallets.Close()
+
+	balance := func(id uuid.UUID) float64 {
+		t.Helper()
+		balance, err := mem.GetBalance(ctx, id)
+		require.NoError(t, err)
+		return balance
+	}
+
+	t.Run("withdrawal with fee", func(t *testing.T) {
+		recorded, err := wallets.UpdateBalance(ctx, wallet.WalletTransactions{ValletId: a, OperationType: "WITHDRAW", Amount: 20,
+			IdempotencyKey: "w1", Kind: wallet.KindFee})
+		require.NoError(t, err)
+		assert.Equal(t, 80.0, recorded.BalanceAfter)
+		assert.Empty(t, recorded.Kind)
+		require.NotNil(t, recorded.OperationId)
+		assert.Equal(t, 78.8, balance(a))
+		assert.Equal(t, 1.2, balance(fees))
+
+		wlt, err := mem.GetWallet(ctx, a)
+		require.NoError(t, err)
+		assert.Equal(t, wlt.Version, recorded.Version)
+
+		history, err := mem.ListTransactions(ctx, a, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		assert.Equal(t, wallet.KindFee, history[1].Kind)
+		assert.Equal(t, "WITHDRAW", history[1].OperationType)
+		assert.Equal(t, 1.2, history[1].Amount)
+		assert.Equal(t, recorded.OperationId, history[1].OperationId)
+
+		credited, err := mem.ListTransactions(ctx, fees, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, credited, 1)
+		assert.Equal(t, recorded.OperationId, credited[0].OperationId)
+
+		// Повтор по ключу не списывает ни операцию, ни комиссию.
+		replay, err := wallets.UpdateBalance(ctx, wallet.WalletTransactions{ValletId: a, OperationType: "WITHDRAW", Amount: 20,
+			IdempotencyKey: "w1"})
+		require.NoError(t, err)
+		assert.Equal(t, recorded.Id, replay.Id)
+		assert.Equal(t, 78.8, balance(a))
+		assert.Equal(t, 1.2, balance(fees))
+	})
+
+	t.Run("fee is capped and deposits are free", func(t *testing.T) {
+		_, err := wallets.UpdateBalance(ctx, wallet.WalletTransactions{ValletId: b, OperationType: "WITHDRAW", Amount: 90})
+		require.NoError(t, err)
+		assert.Equal(t, 8.5, balance(b))
+
+		_, err = wallets.UpdateBalance(ctx, wallet.WalletTransactions{ValletId: b, OperationType: "DEPOSIT", Amount: 91.5})
+		require.NoError(t, err)
+		assert.Equal(t, 100.0, balance(b))
+		assert.Equal(t, 2.7, balance(fees))
+	})
+
+	t.Run("not enough for the fee", func(t *testing.T) {
+		_, err := wallets.UpdateBalance(ctx, wallet.WalletTransactions{ValletId: b, OperationType: "WITHDRAW", Amount: 99})
+		assert.ErrorIs(t, err, repository.ErrInsufficientFunds)
+		assert.Equal(t, 100.0, balance(b))
+		assert.Equal(t, 2.7, balance(fees))
+	})
+
+	t.Run("transfer with fee", func(t *testing.T) {
+		transfer, err := wallets.Transfer(ctx, b, a, 10)
+		require.NoError(t, err)
+		require.NotNil(t, transfer.Fee)
+		assert.Equal(t, 0.5, transfer.Fee.Amount)
+		assert.Equal(t, wallet.KindFee, transfer.Fee.Kind)
+		assert.Equal(t, 89.5, transfer.Fee.BalanceAfter)
+		assert.Equal(t, transfer.From.OperationId, transfer.Fee.OperationId)
+		assert.Equal(t, transfer.From.OperationId, transfer.To.OperationId)
+		assert.Equal(t, 88.8, balance(a))
+		assert.Equal(t, 3.2, balance(fees))
+
+		_, err = wallets.Transfer(ctx, b, a, 89.5)
+		assert.ErrorIs(t, err, repository.ErrInsufficientFunds)
+		assert.Equal(t, 89.5, balance(b))
+	})
+
+	t.Run("tier without fee", func(t *testing.T) {
+		_, err := wallets.SetTier(ctx, a, "Premium!")
+		assert.ErrorIs(t, err, ErrInvalidTier)
+
+		wlt, err := wallets.SetTier(ctx, a, "premium")
+		require.NoError(t, err)
+		assert.Equal(t, "premium", wlt.Tier)
+
+		recorded, err := wallets.UpdateBalance(ctx, wallet.WalletTransactions{ValletId: a, OperationType: "WITHDRAW", Amount: 8.8})
+		require.NoError(t, err)
+		assert.Nil(t, recorded.OperationId)
+		assert.Equal(t, 80.0, balance(a))
+		assert.Equal(t, 3.2, balance(fees))
+	})
+
+	t.Run("no fee from the fee wallet", func(t *testing.T) {
+		_, err := wallets.UpdateBalance(ctx, wallet.WalletTransactions{ValletId: fees, OperationType: "WITHDRAW", Amount: 3.2})
+		require.NoError(t, err)
+		assert.Zero(t, balance(fees))
+	})
+}
+
+func TestWalletService_BatchFees(t *testing.T) {
+	ctx := context.Background()
+
+	var a, b, fees uuid.UUID
+	require.NoError(t, a.Scan("11111111-1111-1111-1111-111111111111"))
+	require.NoError(t, b.Scan("22222222-2222-2222-2222-222222222222"))
+	require.NoError(t, fees.Scan("00000000-0000-0000-0000-000000000fee"))
+
+	mem := repository.NewWalletMemory()
+	mem.AddWallet(a, 100)
+	mem.AddWallet(b, 10)
+	mem.AddWallet(fees, 0)
+
+	schedule, err := fee.New([]fee.Rule{{Operation: fee.Withdraw, Fixed: 1}, {Tier: "premium", Operation: fee.Withdraw}})
+	require.NoError(t, err)
+	wallets := NewWalletService(mem, Config{Fees: schedule, FeeWallet: fees})
+
+	balance := func(id uuid.UUID) float64 {
+		t.Helper()
+		balance, err := mem.GetBalance(ctx, id)
+		require.NoError(t, err)
+		return balance
+	}
+
+	t.Run("best effort", func(t *testing.T) {
+		results, err := wallets.ApplyBatch(ctx, []wallet.WalletTransactions{
+			{ValletId: a, OperationType: "WITHDRAW", Amount: 10, IdempotencyKey: "b1"},
+			{ValletId: a, OperationType: "DEPOSIT", Amount: 5},
+			// Хватает на операцию, но не на комиссию: не списывается ни то, ни другое.
+			{ValletId: b, OperationType: "WITHDRAW", Amount: 10},
+		}, false)
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		assert.Equal(t, wallet.BatchApplied, results[0].Status)
+		require.NotNil(t, results[0].Transaction.OperationId)
+		assert.Equal(t, wallet.BatchApplied, results[1].Status)
+		assert.Equal(t, wallet.BatchRejected, results[2].Status)
+
+		assert.Equal(t, 94.0, balance(a))
+		assert.Equal(t, 10.0, balance(b))
+		assert.Equal(t, 1.0, balance(fees))
+
+		// Повтор по ключу не списывает комиссию второй раз.
+		results, err = wallets.ApplyBatch(ctx, []wallet.WalletTransactions{
+			{ValletId: a, OperationType: "WITHDRAW", Amount: 10, IdempotencyKey: "b1"},
+		}, false)
+		require.NoError(t, err)
+		assert.Equal(t, wallet.BatchDuplicate, results[0].Status)
+		assert.Equal(t, 94.0, balance(a))
+		assert.Equal(t, 1.0, balance(fees))
+	})
+
+	t.Run("atomic", func(t *testing.T) {
+		results, err := wallets.ApplyBatch(ctx, []wallet.WalletTransactions{
+			{ValletId: a, OperationType: "WITHDRAW", Amount: 4},
+			{ValletId: b, OperationType: "WITHDRAW", Amount: 10},
+		}, true)
+		assert.ErrorIs(t, err, ErrBatchAborted)
+		assert.Equal(t, wallet.BatchAborted, results[0].Status)
+		assert.Equal(t, wallet.BatchRejected, results[1].Status)
+		assert.Equal(t, 94.0, balance(a))
+		assert.Equal(t, 10.0, balance(b))
+
+		results, err = wallets.ApplyBatch(ctx, []wallet.WalletTransactions{
+			{ValletId: a, OperationType: "WITHDRAW", Amount: 4},
+			{ValletId: b, OperationType: "WITHDRAW", Amount: 9},
+		}, true)
+		require.NoError(t, err)
+		assert.Equal(t, wallet.BatchApplied, results[0].Status)
+		assert.Equal(t, wallet.BatchApplied, results[1].Status)
+		assert.Equal(t, 89.0, balance(a))
+		assert.Zero(t, balance(b))
+		assert.Equal(t, 3.0, balance(fees))
+
+		wlt, err := mem.GetWallet(ctx, a)
+		require.NoError(t, err)
+		assert.Equal(t, wlt.Version, results[0].Transaction.Version)
+	})
+
+	t.Run("fee depends on the payer tier", func(t *testing.T) {
+		var missing uuid.UUID
+		require.NoError(t, missing.Scan("99999999-9999-9999-9999-999999999999"))
+		_, err := mem.SetTier(ctx, b, "premium")
+		require.NoError(t, err)
+		_, err = wallets.UpdateBalance(ctx, wallet.WalletTransactions{ValletId: b, OperationType: "DEPOSIT", Amount: 10})
+		require.NoError(t, err)
+
+		results, err := wallets.ApplyBatch(ctx, []wallet.WalletTransactions{
+			{ValletId: a, OperationType: "WITHDRAW", Amount: 1},
+			{ValletId: b, OperationType: "WITHDRAW", Amount: 10},
+			{ValletId: missing, OperationType: "WITHDRAW", Amount: 1},
+			{ValletId: a, OperationType: "WITHDRAW", Amount: 1},
+		}, false)
+		require.NoError(t, err)
+		assert.Equal(t, wallet.BatchApplied, results[0].Status)
+		assert.Equal(t, wallet.BatchApplied, results[1].Status)
+		assert.Equal(t, wallet.BatchRejected, results[2].Status)
+		assert.Equal(t, wallet.BatchApplied, results[3].Status)
+
+		assert.Equal(t, 85.0, balance(a))
+		assert.Zero(t, balance(b))
+		assert.Equal(t, 5.0, balance(fees))
+	})
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+)
+
+const (
+	// importChunk - сколько строк файла применяется одним пакетом.
+	importChunk = 500
+	// importKeyPrefix - префикс ключа идемпотентности строк импорта, чтобы reference не пересекался с ключами клиентов API.
+	importKeyPrefix = "import:"
+	// maxReferenceLen - ключ идемпотентности ограничен 128 символами вместе с префиксом.
+	maxReferenceLen = 128 - len(importKeyPrefix)
+)
+
+// ImportService - массовая корректировка балансов из CSV: пробный прогон с отчётом и применение через WalletService.
+type ImportService struct {
+	wallets Wallet
+	repo    repository.Wallet
+}
+
+func NewImportService(wallets Wallet, repo repository.Wallet) *ImportService {
+	return &ImportService{wallets: wallets, repo: repo}
+}
+
+// Validate - пробный прогон: ничего не записывает и для каждой строки сообщает, что с ней будет: ok, invalid,
+// unknown_wallet, duplicate (reference уже встречался в файле или корректировка уже применена)
+// или would_overdraft (с учётом текущего баланса и предыдущих строк файла).
+// До применения балансы могут измениться, поэтому окончательный результат даёт только Apply.
+func (s *ImportService) Validate(ctx context.Context, rows []wallet.ImportRow) (wallet.ImportReport, error) {
+	report := newImportReport(rows, true)
+
+	ops := make([]wallet.WalletTransactions, len(rows))
+	pending := make([]wallet.WalletTransactions, 0, len(rows))
+	for i, row := range rows {
+		WT, err := parseImportRow(row)
+		if err != nil {
+			report.Results[i].Status = wallet.ImportInvalid
+			report.Results[i].Error = err.Error()
+			continue
+		}
+		ops[i] = WT
+		pending = append(pending, WT)
+	}
+
+	balances := make(map[string]float64)
+	unknown := make(map[string]bool)
+	for _, WT := range pending {
+		id := WT.ValletId.UUID.String()
+		if _, ok := balances[id]; ok || unknown[id] {
+			continue
+		}
+
+		wlt, err := s.wallets.GetWallet(ctx, WT.ValletId)
+		if errors.Is(err, repository.ErrWalletNotFound) {
+			unknown[id] = true
+			continue
+		}
+		if err != nil {
+			return wallet.ImportReport{}, err
+		}
+		balances[id] = wlt.Balance
+	}
+
+	applied, err := s.repo.FindIdempotent(ctx, pending)
+	if err != nil {
+		return wallet.ImportReport{}, err
+	}
+
+	seen := make(map[string]int) // кошелёк и reference -> строка файла
+	for i := range rows {
+		res := &report.Results[i]
+		if res.Status != "" {
+			continue
+		}
+		WT := ops[i]
+		id := WT.ValletId.UUID.String()
+
+		if unknown[id] {
+			res.Status = wallet.ImportUnknownWallet
+			res.Error = repository.ErrWalletNotFound.Error()
+			continue
+		}
+		if original, ok := applied[id][WT.IdempotencyKey]; ok {
+			res.Status = wallet.BatchDuplicate
+			res.Error = fmt.Sprintf("already applied as transaction %d", original.Id)
+			res.Transaction = &original
+			continue
+		}
+		if line, ok := seen[id+" "+WT.IdempotencyKey]; ok {
+			res.Status = wallet.BatchDuplicate
+			res.Error = fmt.Sprintf("duplicate of line %d", line)
+			continue
+		}
+		seen[id+" "+WT.IdempotencyKey] = res.Line
+
+		delta := WT.Amount
+		if WT.OperationType == "WITHDRAW" {
+			delta = -delta
+		}
+		next := math.Round((balances[id]+delta)*100) / 100
+		if next < 0 {
+			res.Status = wallet.ImportWouldOverdraft
+			res.Error = fmt.Sprintf("balance %.2f is less than %.2f", balances[id], WT.Amount)
+			continue
+		}
+		balances[id] = next
+		res.Status = wallet.ImportOK
+	}
+
+	countStatuses(&report)
+	return report, nil
+}
+
+// Apply применяет строки файла через WalletService пакетами best-effort по importChunk строк в порядке файла.
+// Строки с ошибками формата не применяются. Ключ идемпотентности строки - её reference, поэтому повторная
+// загрузка того же файла (например, после сбоя посередине) безопасна: применённые строки получат статус duplicate.
+func (s *ImportService) Apply(ctx context.Context, rows []wallet.ImportRow) (wallet.ImportReport, error) {
+	report := newImportReport(rows, false)
+
+	ops := make([]wallet.WalletTransactions, 0, len(rows))
+	index := make([]int, 0, len(rows)) // позиции ops в файле
+	for i, row := range rows {
+		WT, err := parseImportRow(row)
+		if err != nil {
+			report.Results[i].Status = wallet.ImportInvalid
+			report.Results[i].Error = err.Error()
+			continue
+		}
+		ops = append(ops, WT)
+		index = append(index, i)
+	}
+
+	for start := 0; start < len(ops); start += importChunk {
+		end := min(start+importChunk, len(ops))
+		results, err := s.wallets.ApplyBatch(ctx, ops[start:end], false)
+		if err != nil {
+			return wallet.ImportReport{}, fmt.Errorf("failed to apply rows from line %d: %w", rows[index[start]].Line, err)
+		}
+
+		for j, res := range results {
+			r := &report.Results[index[start+j]]
+			r.Status = res.Status
+			r.Error = res.Error
+			r.Transaction = res.Transaction
+		}
+	}
+
+	countStatuses(&report)
+	return report, nil
+}
+
+// parseImportRow проверяет строку файла так же, как обработчик проверяет одиночную операцию.
+func parseImportRow(row wallet.ImportRow) (wallet.WalletTransactions, error) {
+	var WT wallet.WalletTransactions
+	if err := WT.ValletId.Scan(row.WalletID); err != nil {
+		return WT, fmt.Errorf("invalid wallet id %q", row.WalletID)
+	}
+
+	WT.OperationType = strings.ToUpper(row.OperationType)
+	if WT.OperationType != "DEPOSIT" && WT.OperationType != "WITHDRAW" {
+		return WT, fmt.Errorf("operation type must be DEPOSIT or WITHDRAW, got %q", row.OperationType)
+	}
+
+	// Excel с русской локалью сохраняет дробную часть через запятую.
+	amount, err := strconv.ParseFloat(strings.Replace(row.Amount, ",", ".", 1), 64)
+	if err != nil || math.IsInf(amount, 0) || math.IsNaN(amount) {
+		return WT, fmt.Errorf("invalid amount %q", row.Amount)
+	}
+	if amount <= 0 {
+		return WT, errors.New("amount must be positive")
+	}
+	if math.Round(amount*100)/100 != amount {
+		return WT, fmt.Errorf("amount %q has more than 2 decimal places", row.Amount)
+	}
+	WT.Amount = amount
+
+	switch {
+	case row.Reference == "":
+		return WT, errors.New("reference is required")
+	case len(row.Reference) > maxReferenceLen:
+		return WT, fmt.Errorf("reference is longer than %d characters", maxReferenceLen)
+	}
+	WT.IdempotencyKey = importKeyPrefix + row.Reference
+
+	return WT, nil
+}
+
+func newImportReport(rows []wallet.ImportRow, dryRun bool) wallet.ImportReport {
+	report := wallet.ImportReport{
+		DryRun:  dryRun,
+		Rows:    len(rows),
+		Results: make([]wallet.ImportResult, len(rows)),
+	}
+	for i, row := range rows {
+		report.Results[i].ImportRow = row
+	}
+	return report
+}
+
+func countStatuses(report *wallet.ImportReport) {
+	report.Counts = make(map[string]int)
+	for _, res := range report.Results {
+		report.Counts[res.Status]++
+	}
+}
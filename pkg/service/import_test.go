@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportService(t *testing.T) {
+	ctx := context.Background()
+
+	const (
+		a       = "11111111-1111-1111-1111-111111111111"
+		b       = "22222222-2222-2222-2222-222222222222"
+		missing = "99999999-9999-9999-9999-999999999999"
+	)
+	var aID, bID uuid.UUID
+	require.NoError(t, aID.Scan(a))
+	require.NoError(t, bID.Scan(b))
+
+	mem := repository.NewWalletMemory()
+	mem.AddWallet(aID, 10)
+	mem.AddWallet(bID, 0)
+	imports := NewImportService(NewWalletService(mem, Config{}), mem)
+
+	rows := []wallet.ImportRow{
+		{Line: 2, WalletID: a, OperationType: "withdraw", Amount: "4", Reference: "R1"},
+		{Line: 3, WalletID: a, OperationType: "WITHDRAW", Amount: "7", Reference: "R2"},
+		{Line: 4, WalletID: b, OperationType: "DEPOSIT", Amount: "2,50", Reference: "R1"},
+		{Line: 5, WalletID: a, OperationType: "DEPOSIT", Amount: "1", Reference: "R1"},
+		{Line: 6, WalletID: missing, OperationType: "DEPOSIT", Amount: "1", Reference: "R3"},
+		{Line: 7, WalletID: "not-a-uuid", OperationType: "DEPOSIT", Amount: "1", Reference: "R4"},
+		{Line: 8, WalletID: a, OperationType: "REFUND", Amount: "1", Reference: "R5"},
+		{Line: 9, WalletID: a, OperationType: "DEPOSIT", Amount: "0.001", Reference: "R6"},
+		{Line: 10, WalletID: a, OperationType: "DEPOSIT", Amount: "1", Reference: ""},
+	}
+	statuses := func(report wallet.ImportReport) []string {
+		res := make([]string, len(report.Results))
+		for i, r := range report.Results {
+			res[i] = r.Status
+		}
+		return res
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		report, err := imports.Validate(ctx, rows)
+		require.NoError(t, err)
+
+		assert.True(t, report.DryRun)
+		assert.Equal(t, []string{wallet.ImportOK, wallet.ImportWouldOverdraft, wallet.ImportOK, wallet.BatchDuplicate,
+			wallet.ImportUnknownWallet, wallet.ImportInvalid, wallet.ImportInvalid, wallet.ImportInvalid, wallet.ImportInvalid},
+			statuses(report))
+		assert.Equal(t, "balance 6.00 is less than 7.00", report.Results[1].Error)
+		assert.Equal(t, "duplicate of line 2", report.Results[3].Error)
+		assert.Equal(t, 2, report.Counts[wallet.ImportOK])
+		assert.Equal(t, 4, report.Counts[wallet.ImportInvalid])
+
+		// Пробный прогон ничего не записывает.
+		balance, err := mem.GetBalance(ctx, aID)
+		require.NoError(t, err)
+		assert.Equal(t, 10.0, balance)
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		report, err := imports.Apply(ctx, rows)
+		require.NoError(t, err)
+
+		assert.False(t, report.DryRun)
+		assert.Equal(t, []string{wallet.BatchApplied, wallet.BatchRejected, wallet.BatchApplied, wallet.BatchDuplicate,
+			wallet.BatchRejected, wallet.ImportInvalid, wallet.ImportInvalid, wallet.ImportInvalid, wallet.ImportInvalid},
+			statuses(report))
+		require.NotNil(t, report.Results[2].Transaction)
+		assert.Equal(t, 2.5, report.Results[2].Transaction.BalanceAfter)
+
+		balance, err := mem.GetBalance(ctx, aID)
+		require.NoError(t, err)
+		assert.Equal(t, 6.0, balance)
+	})
+
+	t.Run("corrected file is applied once", func(t *testing.T) {
+		// Повторная загрузка с исправленной строкой 3: уже применённые строки не применяются снова.
+		retry := []wallet.ImportRow{
+			rows[0],
+			{Line: 3, WalletID: a, OperationType: "WITHDRAW", Amount: "6", Reference: "R2"},
+			rows[2],
+		}
+
+		report, err := imports.Validate(ctx, retry)
+		require.NoError(t, err)
+		assert.Equal(t, []string{wallet.BatchDuplicate, wallet.ImportOK, wallet.BatchDuplicate}, statuses(report))
+		require.NotNil(t, report.Results[0].Transaction)
+		assert.Equal(t, int64(1), report.Results[0].Transaction.Seq)
+
+		report, err = imports.Apply(ctx, retry)
+		require.NoError(t, err)
+		assert.Equal(t, []string{wallet.BatchDuplicate, wallet.BatchApplied, wallet.BatchDuplicate}, statuses(report))
+
+		balance, err := mem.GetBalance(ctx, aID)
+		require.NoError(t, err)
+		assert.Equal(t, 0.0, balance)
+		balance, err = mem.GetBalance(ctx, bID)
+		require.NoError(t, err)
+		assert.Equal(t, 2.5, balance)
+	})
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/interest"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+const (
+	// interestPage - сколько кошельков выбирается за раз при капитализации процентов.
+	interestPage = 100
+	// maxAccrualDays - наибольший период в одном запросе начислений.
+	maxAccrualDays = 366
+)
+
+// InterestService начисляет проценты на остаток кошельков с процентным продуктом: каждый день - в накопитель
+// кошелька, в конце месяца - капитализация накопленного операцией DEPOSIT.
+type InterestService struct {
+	interest repository.Interest
+	products *interest.Products
+	now      func() time.Time
+}
+
+func NewInterestService(interest repository.Interest, products *interest.Products) *InterestService {
+	return &InterestService{interest: interest, products: products, now: time.Now}
+}
+
+// SetProduct назначает кошельку процентный продукт из INTEREST_PRODUCTS_FILE; пустой product отключает начисление.
+// Уже накопленные проценты капитализируются в конце месяца в любом случае.
+func (s *InterestService) SetProduct(ctx context.Context, walletID uuid.UUID, product string) (wallet.Wallet, error) {
+	if _, ok := s.products.Get(product); !ok && product != "" {
+		return wallet.Wallet{}, fmt.Errorf("%w: unknown interest product %q", ErrInvalidInterest, product)
+	}
+	return s.interest.SetProduct(ctx, walletID, product)
+}
+
+// ListAccruals возвращает дневные начисления кошелька за дни из [from, to) (UTC);
+// без to - по текущий день включительно, без from - последние 31 день до to.
+func (s *InterestService) ListAccruals(ctx context.Context, walletID uuid.UUID, from, to time.Time) ([]wallet.InterestAccrual, error) {
+	if to.IsZero() {
+		to = startOfDay(s.now()).AddDate(0, 0, 1)
+	}
+	if from.IsZero() {
+		from = to.AddDate(0, 0, -31)
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidInterest)
+	}
+	if to.Sub(from) > maxAccrualDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: period must not exceed %d days", ErrInvalidInterest, maxAccrualDays)
+	}
+	return s.interest.ListInterestAccruals(ctx, walletID, from, to)
+}
+
+// RunInterest догоняет начисления до вчерашнего (относительно now, UTC) дня включительно: день за днём
+// начисляет проценты, а после последнего дня месяца капитализирует накопленное за месяц. Первый запуск
+// начинает со вчерашнего дня. Каждый шаг идемпотентен, поэтому прерванный запуск безопасно повторить.
+// Возвращает число дней, за которые выполнено начисление.
+func (s *InterestService) RunInterest(ctx context.Context, now time.Time) (int, error) {
+	if s.products.Empty() {
+		return 0, nil
+	}
+
+	yesterday := startOfDay(now).AddDate(0, 0, -1)
+	accrued := 0
+	for ctx.Err() == nil {
+		last, capitalised, err := s.interest.LastInterestDay(ctx)
+		if err != nil {
+			return accrued, err
+		}
+
+		if !last.IsZero() && last.AddDate(0, 0, 1).Day() == 1 && !capitalised {
+			if err := s.capitalise(ctx, last); err != nil {
+				return accrued, err
+			}
+			continue
+		}
+
+		day := yesterday
+		if !last.IsZero() {
+			day = last.AddDate(0, 0, 1)
+		}
+		if day.After(yesterday) {
+			return accrued, nil
+		}
+
+		if _, err := s.interest.AccrueInterest(ctx, day, s.products.DailyRates(day)); err != nil {
+			return accrued, err
+		}
+		accrued++
+	}
+	return accrued, ctx.Err()
+}
+
+// capitalise зачисляет проценты, накопленные к концу месяца monthEnd, и отмечает месяц капитализированным.
+// Ошибка по одному кошельку пишется в лог и не останавливает остальные: его накопитель сохранится
+// и будет капитализирован в следующем месяце.
+func (s *InterestService) capitalise(ctx context.Context, monthEnd time.Time) error {
+	key := "interest:" + monthEnd.Format("2006-01")
+
+	var after uuid.UUID
+	if err := after.Scan("00000000-0000-0000-0000-000000000000"); err != nil {
+		return err
+	}
+
+	for {
+		ids, err := s.interest.PendingInterest(ctx, after, interestPage)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			// Повтор по ключу - месяц для кошелька уже капитализирован в прерванном запуске.
+			_, err := s.interest.CapitaliseInterest(ctx, id, key)
+			if err != nil && !errors.Is(err, repository.ErrDuplicateTransaction) {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				log.Printf("interest %s for wallet %s: %s", monthEnd.Format("2006-01"), id.UUID.String(), err.Error())
+			}
+		}
+
+		if len(ids) < interestPage {
+			break
+		}
+		after = ids[len(ids)-1]
+	}
+
+	return s.interest.MarkCapitalised(ctx, monthEnd)
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
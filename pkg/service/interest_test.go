@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/interest"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterestService_RunInterest(t *testing.T) {
+	ctx := context.Background()
+
+	var a, b uuid.UUID
+	require.NoError(t, a.Scan("11111111-1111-1111-1111-111111111111"))
+	require.NoError(t, b.Scan("22222222-2222-2222-2222-222222222222"))
+
+	mem := repository.NewWalletMemory()
+	mem.AddWallet(a, 1000)
+	mem.AddWallet(b, 1000)
+
+	products, err := interest.New([]interest.Product{{Name: "savings", AnnualRate: 3.65}})
+	require.NoError(t, err)
+	s := NewInterestService(mem, products)
+
+	_, err = s.SetProduct(ctx, a, "deposit")
+	assert.ErrorIs(t, err, ErrInvalidInterest)
+	wlt, err := s.SetProduct(ctx, a, "savings")
+	require.NoError(t, err)
+	assert.Equal(t, "savings", wlt.Product)
+
+	// Первый запуск начинает со вчерашнего дня.
+	n, err := s.RunInterest(ctx, time.Date(2026, 1, 30, 3, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// Догоняет 30 и 31 января, капитализирует январь и начисляет 1 февраля.
+	n, err = s.RunInterest(ctx, time.Date(2026, 2, 2, 3, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	n, err = s.RunInterest(ctx, time.Date(2026, 2, 2, 4, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Zero(t, n, "days are accrued only once")
+
+	history, err := mem.ListTransactions(ctx, a, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, wallet.KindInterest, history[0].Kind)
+	assert.Equal(t, "DEPOSIT", history[0].OperationType)
+	assert.Equal(t, 0.3, history[0].Amount)
+	assert.Equal(t, "interest:2026-01", history[0].IdempotencyKey)
+
+	wlt, err = mem.GetWallet(ctx, a)
+	require.NoError(t, err)
+	assert.Equal(t, 1000.3, wlt.Balance)
+	assert.InDelta(t, 0.1, wlt.AccruedInterest, 1e-9)
+
+	jan := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	accruals, err := s.ListAccruals(ctx, a, jan, jan.AddDate(0, 1, 0))
+	require.NoError(t, err)
+	require.Len(t, accruals, 3)
+	assert.True(t, accruals[0].Day.Equal(time.Date(2026, 1, 29, 0, 0, 0, 0, time.UTC)))
+	assert.InDelta(t, 0.1, accruals[0].Amount, 1e-9)
+
+	_, err = s.ListAccruals(ctx, a, jan.AddDate(0, 1, 0), jan)
+	assert.ErrorIs(t, err, ErrInvalidInterest)
+
+	// Кошелёк без продукта процентов не получает.
+	balance, err := mem.GetBalance(ctx, b)
+	require.NoError(t, err)
+	assert.Equal(t, 1000.0, balance)
+}
+
+func TestInterestService_WithoutProducts(t *testing.T) {
+	s := NewInterestService(repository.NewWalletMemory(), nil)
+
+	n, err := s.RunInterest(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
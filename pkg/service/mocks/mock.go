@@ -6,11 +6,15 @@ package mock_service
 
 import (
 	context "context"
+	io "io"
 	reflect "reflect"
+	time "time"
 
 	wallet "github.com/KatenkaKet/wallet"
+	export "github.com/KatenkaKet/wallet/pkg/export"
+	receipt "github.com/KatenkaKet/wallet/pkg/receipt"
 	gomock "github.com/golang/mock/gomock"
-	gofrs_uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
 )
 
 // MockWallet is a mock of Wallet interface.
@@ -36,8 +40,49 @@ func (m *MockWallet) EXPECT() *MockWalletMockRecorder {
 	return m.recorder
 }
 
+// ApplyBatch mocks base method.
+func (m *MockWallet) ApplyBatch(ctx context.Context, ops []wallet.WalletTransactions, atomic bool) ([]wallet.BatchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyBatch", ctx, ops, atomic)
+	ret0, _ := ret[0].([]wallet.BatchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApplyBatch indicates an expected call of ApplyBatch.
+func (mr *MockWalletMockRecorder) ApplyBatch(ctx, ops, atomic interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyBatch", reflect.TypeOf((*MockWallet)(nil).ApplyBatch), ctx, ops, atomic)
+}
+
+// Close mocks base method.
+func (m *MockWallet) Close() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Close")
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockWalletMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockWallet)(nil).Close))
+}
+
+// ExportTransactions mocks base method.
+func (m *MockWallet) ExportTransactions(ctx context.Context, walletID uuid.UUID, from, to time.Time, w export.Writer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportTransactions", ctx, walletID, from, to, w)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportTransactions indicates an expected call of ExportTransactions.
+func (mr *MockWalletMockRecorder) ExportTransactions(ctx, walletID, from, to, w interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportTransactions", reflect.TypeOf((*MockWallet)(nil).ExportTransactions), ctx, walletID, from, to, w)
+}
+
 // GetBalance mocks base method.
-func (m *MockWallet) GetBalance(ctx context.Context, walletID gofrs_uuid.UUID) (float64, error) {
+func (m *MockWallet) GetBalance(ctx context.Context, walletID uuid.UUID) (float64, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetBalance", ctx, walletID)
 	ret0, _ := ret[0].(float64)
@@ -51,12 +96,103 @@ func (mr *MockWalletMockRecorder) GetBalance(ctx, walletID interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalance", reflect.TypeOf((*MockWallet)(nil).GetBalance), ctx, walletID)
 }
 
+// GetWallet mocks base method.
+func (m *MockWallet) GetWallet(ctx context.Context, walletID uuid.UUID) (wallet.Wallet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWallet", ctx, walletID)
+	ret0, _ := ret[0].(wallet.Wallet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWallet indicates an expected call of GetWallet.
+func (mr *MockWalletMockRecorder) GetWallet(ctx, walletID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWallet", reflect.TypeOf((*MockWallet)(nil).GetWallet), ctx, walletID)
+}
+
+// ListBucketTransactions mocks base method.
+func (m *MockWallet) ListBucketTransactions(ctx context.Context, walletID uuid.UUID, bucket string, afterSeq int64, limit int) ([]wallet.WalletTransactions, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListBucketTransactions", ctx, walletID, bucket, afterSeq, limit)
+	ret0, _ := ret[0].([]wallet.WalletTransactions)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListBucketTransactions indicates an expected call of ListBucketTransactions.
+func (mr *MockWalletMockRecorder) ListBucketTransactions(ctx, walletID, bucket, afterSeq, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListBucketTransactions", reflect.TypeOf((*MockWallet)(nil).ListBucketTransactions), ctx, walletID, bucket, afterSeq, limit)
+}
+
+// ListTransactions mocks base method.
+func (m *MockWallet) ListTransactions(ctx context.Context, walletID uuid.UUID, afterSeq int64, limit int) ([]wallet.WalletTransactions, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTransactions", ctx, walletID, afterSeq, limit)
+	ret0, _ := ret[0].([]wallet.WalletTransactions)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTransactions indicates an expected call of ListTransactions.
+func (mr *MockWalletMockRecorder) ListTransactions(ctx, walletID, afterSeq, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTransactions", reflect.TypeOf((*MockWallet)(nil).ListTransactions), ctx, walletID, afterSeq, limit)
+}
+
+// SetCreditLimit mocks base method.
+func (m *MockWallet) SetCreditLimit(ctx context.Context, walletID uuid.UUID, limit float64) (wallet.Wallet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetCreditLimit", ctx, walletID, limit)
+	ret0, _ := ret[0].(wallet.Wallet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetCreditLimit indicates an expected call of SetCreditLimit.
+func (mr *MockWalletMockRecorder) SetCreditLimit(ctx, walletID, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCreditLimit", reflect.TypeOf((*MockWallet)(nil).SetCreditLimit), ctx, walletID, limit)
+}
+
+// SetTier mocks base method.
+func (m *MockWallet) SetTier(ctx context.Context, walletID uuid.UUID, tier string) (wallet.Wallet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTier", ctx, walletID, tier)
+	ret0, _ := ret[0].(wallet.Wallet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetTier indicates an expected call of SetTier.
+func (mr *MockWalletMockRecorder) SetTier(ctx, walletID, tier interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTier", reflect.TypeOf((*MockWallet)(nil).SetTier), ctx, walletID, tier)
+}
+
+// Transfer mocks base method.
+func (m *MockWallet) Transfer(ctx context.Context, from, to uuid.UUID, amount float64) (wallet.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transfer", ctx, from, to, amount)
+	ret0, _ := ret[0].(wallet.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Transfer indicates an expected call of Transfer.
+func (mr *MockWalletMockRecorder) Transfer(ctx, from, to, amount interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfer", reflect.TypeOf((*MockWallet)(nil).Transfer), ctx, from, to, amount)
+}
+
 // UpdateBalance mocks base method.
-func (m *MockWallet) UpdateBalance(ctx context.Context, WT wallet.WalletTransactions) error {
+func (m *MockWallet) UpdateBalance(ctx context.Context, WT wallet.WalletTransactions) (wallet.WalletTransactions, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UpdateBalance", ctx, WT)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(wallet.WalletTransactions)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // UpdateBalance indicates an expected call of UpdateBalance.
@@ -64,3 +200,792 @@ func (mr *MockWalletMockRecorder) UpdateBalance(ctx, WT interface{}) *gomock.Cal
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateBalance", reflect.TypeOf((*MockWallet)(nil).UpdateBalance), ctx, WT)
 }
+
+// MockImport is a mock of Import interface.
+type MockImport struct {
+	ctrl     *gomock.Controller
+	recorder *MockImportMockRecorder
+}
+
+// MockImportMockRecorder is the mock recorder for MockImport.
+type MockImportMockRecorder struct {
+	mock *MockImport
+}
+
+// NewMockImport creates a new mock instance.
+func NewMockImport(ctrl *gomock.Controller) *MockImport {
+	mock := &MockImport{ctrl: ctrl}
+	mock.recorder = &MockImportMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockImport) EXPECT() *MockImportMockRecorder {
+	return m.recorder
+}
+
+// Apply mocks base method.
+func (m *MockImport) Apply(ctx context.Context, rows []wallet.ImportRow) (wallet.ImportReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Apply", ctx, rows)
+	ret0, _ := ret[0].(wallet.ImportReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Apply indicates an expected call of Apply.
+func (mr *MockImportMockRecorder) Apply(ctx, rows interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Apply", reflect.TypeOf((*MockImport)(nil).Apply), ctx, rows)
+}
+
+// Validate mocks base method.
+func (m *MockImport) Validate(ctx context.Context, rows []wallet.ImportRow) (wallet.ImportReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Validate", ctx, rows)
+	ret0, _ := ret[0].(wallet.ImportReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Validate indicates an expected call of Validate.
+func (mr *MockImportMockRecorder) Validate(ctx, rows interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Validate", reflect.TypeOf((*MockImport)(nil).Validate), ctx, rows)
+}
+
+// MockAudit is a mock of Audit interface.
+type MockAudit struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditMockRecorder
+}
+
+// MockAuditMockRecorder is the mock recorder for MockAudit.
+type MockAuditMockRecorder struct {
+	mock *MockAudit
+}
+
+// NewMockAudit creates a new mock instance.
+func NewMockAudit(ctrl *gomock.Controller) *MockAudit {
+	mock := &MockAudit{ctrl: ctrl}
+	mock.recorder = &MockAuditMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAudit) EXPECT() *MockAuditMockRecorder {
+	return m.recorder
+}
+
+// CreateCheckpoints mocks base method.
+func (m *MockAudit) CreateCheckpoints(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCheckpoints", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateCheckpoints indicates an expected call of CreateCheckpoints.
+func (mr *MockAuditMockRecorder) CreateCheckpoints(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCheckpoints", reflect.TypeOf((*MockAudit)(nil).CreateCheckpoints), ctx)
+}
+
+// VerifyChain mocks base method.
+func (m *MockAudit) VerifyChain(ctx context.Context, walletID uuid.UUID) (wallet.ChainReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyChain", ctx, walletID)
+	ret0, _ := ret[0].(wallet.ChainReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyChain indicates an expected call of VerifyChain.
+func (mr *MockAuditMockRecorder) VerifyChain(ctx, walletID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyChain", reflect.TypeOf((*MockAudit)(nil).VerifyChain), ctx, walletID)
+}
+
+// MockStatement is a mock of Statement interface.
+type MockStatement struct {
+	ctrl     *gomock.Controller
+	recorder *MockStatementMockRecorder
+}
+
+// MockStatementMockRecorder is the mock recorder for MockStatement.
+type MockStatementMockRecorder struct {
+	mock *MockStatement
+}
+
+// NewMockStatement creates a new mock instance.
+func NewMockStatement(ctrl *gomock.Controller) *MockStatement {
+	mock := &MockStatement{ctrl: ctrl}
+	mock.recorder = &MockStatementMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStatement) EXPECT() *MockStatementMockRecorder {
+	return m.recorder
+}
+
+// GenerateMonthly mocks base method.
+func (m *MockStatement) GenerateMonthly(ctx context.Context, now time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateMonthly", ctx, now)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GenerateMonthly indicates an expected call of GenerateMonthly.
+func (mr *MockStatementMockRecorder) GenerateMonthly(ctx, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateMonthly", reflect.TypeOf((*MockStatement)(nil).GenerateMonthly), ctx, now)
+}
+
+// ListStatements mocks base method.
+func (m *MockStatement) ListStatements(ctx context.Context, walletID uuid.UUID) ([]wallet.Statement, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListStatements", ctx, walletID)
+	ret0, _ := ret[0].([]wallet.Statement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListStatements indicates an expected call of ListStatements.
+func (mr *MockStatementMockRecorder) ListStatements(ctx, walletID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListStatements", reflect.TypeOf((*MockStatement)(nil).ListStatements), ctx, walletID)
+}
+
+// OpenStatement mocks base method.
+func (m *MockStatement) OpenStatement(ctx context.Context, walletID uuid.UUID, from time.Time, format string) (io.ReadCloser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenStatement", ctx, walletID, from, format)
+	ret0, _ := ret[0].(io.ReadCloser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenStatement indicates an expected call of OpenStatement.
+func (mr *MockStatementMockRecorder) OpenStatement(ctx, walletID, from, format interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStatement", reflect.TypeOf((*MockStatement)(nil).OpenStatement), ctx, walletID, from, format)
+}
+
+// MockStats is a mock of Stats interface.
+type MockStats struct {
+	ctrl     *gomock.Controller
+	recorder *MockStatsMockRecorder
+}
+
+// MockStatsMockRecorder is the mock recorder for MockStats.
+type MockStatsMockRecorder struct {
+	mock *MockStats
+}
+
+// NewMockStats creates a new mock instance.
+func NewMockStats(ctrl *gomock.Controller) *MockStats {
+	mock := &MockStats{ctrl: ctrl}
+	mock.recorder = &MockStatsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStats) EXPECT() *MockStatsMockRecorder {
+	return m.recorder
+}
+
+// GlobalStats mocks base method.
+func (m *MockStats) GlobalStats(ctx context.Context, from, to time.Time, interval string) (wallet.Stats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GlobalStats", ctx, from, to, interval)
+	ret0, _ := ret[0].(wallet.Stats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GlobalStats indicates an expected call of GlobalStats.
+func (mr *MockStatsMockRecorder) GlobalStats(ctx, from, to, interval interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GlobalStats", reflect.TypeOf((*MockStats)(nil).GlobalStats), ctx, from, to, interval)
+}
+
+// WalletStats mocks base method.
+func (m *MockStats) WalletStats(ctx context.Context, walletID uuid.UUID, from, to time.Time, interval string) (wallet.Stats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WalletStats", ctx, walletID, from, to, interval)
+	ret0, _ := ret[0].(wallet.Stats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WalletStats indicates an expected call of WalletStats.
+func (mr *MockStatsMockRecorder) WalletStats(ctx, walletID, from, to, interval interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WalletStats", reflect.TypeOf((*MockStats)(nil).WalletStats), ctx, walletID, from, to, interval)
+}
+
+// MockScheduled is a mock of Scheduled interface.
+type MockScheduled struct {
+	ctrl     *gomock.Controller
+	recorder *MockScheduledMockRecorder
+}
+
+// MockScheduledMockRecorder is the mock recorder for MockScheduled.
+type MockScheduledMockRecorder struct {
+	mock *MockScheduled
+}
+
+// NewMockScheduled creates a new mock instance.
+func NewMockScheduled(ctrl *gomock.Controller) *MockScheduled {
+	mock := &MockScheduled{ctrl: ctrl}
+	mock.recorder = &MockScheduledMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockScheduled) EXPECT() *MockScheduledMockRecorder {
+	return m.recorder
+}
+
+// Cancel mocks base method.
+func (m *MockScheduled) Cancel(ctx context.Context, walletID uuid.UUID, id int64) (wallet.ScheduledTransaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Cancel", ctx, walletID, id)
+	ret0, _ := ret[0].(wallet.ScheduledTransaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Cancel indicates an expected call of Cancel.
+func (mr *MockScheduledMockRecorder) Cancel(ctx, walletID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Cancel", reflect.TypeOf((*MockScheduled)(nil).Cancel), ctx, walletID, id)
+}
+
+// ExecuteDue mocks base method.
+func (m *MockScheduled) ExecuteDue(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExecuteDue", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExecuteDue indicates an expected call of ExecuteDue.
+func (mr *MockScheduledMockRecorder) ExecuteDue(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecuteDue", reflect.TypeOf((*MockScheduled)(nil).ExecuteDue), ctx)
+}
+
+// ListScheduled mocks base method.
+func (m *MockScheduled) ListScheduled(ctx context.Context, walletID uuid.UUID, status string, limit int) ([]wallet.ScheduledTransaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListScheduled", ctx, walletID, status, limit)
+	ret0, _ := ret[0].([]wallet.ScheduledTransaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListScheduled indicates an expected call of ListScheduled.
+func (mr *MockScheduledMockRecorder) ListScheduled(ctx, walletID, status, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListScheduled", reflect.TypeOf((*MockScheduled)(nil).ListScheduled), ctx, walletID, status, limit)
+}
+
+// Schedule mocks base method.
+func (m *MockScheduled) Schedule(ctx context.Context, st wallet.ScheduledTransaction) (wallet.ScheduledTransaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Schedule", ctx, st)
+	ret0, _ := ret[0].(wallet.ScheduledTransaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Schedule indicates an expected call of Schedule.
+func (mr *MockScheduledMockRecorder) Schedule(ctx, st interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Schedule", reflect.TypeOf((*MockScheduled)(nil).Schedule), ctx, st)
+}
+
+// MockRecurring is a mock of Recurring interface.
+type MockRecurring struct {
+	ctrl     *gomock.Controller
+	recorder *MockRecurringMockRecorder
+}
+
+// MockRecurringMockRecorder is the mock recorder for MockRecurring.
+type MockRecurringMockRecorder struct {
+	mock *MockRecurring
+}
+
+// NewMockRecurring creates a new mock instance.
+func NewMockRecurring(ctrl *gomock.Controller) *MockRecurring {
+	mock := &MockRecurring{ctrl: ctrl}
+	mock.recorder = &MockRecurringMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRecurring) EXPECT() *MockRecurringMockRecorder {
+	return m.recorder
+}
+
+// CancelRecurring mocks base method.
+func (m *MockRecurring) CancelRecurring(ctx context.Context, walletID uuid.UUID, id int64) (wallet.RecurringTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelRecurring", ctx, walletID, id)
+	ret0, _ := ret[0].(wallet.RecurringTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelRecurring indicates an expected call of CancelRecurring.
+func (mr *MockRecurringMockRecorder) CancelRecurring(ctx, walletID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelRecurring", reflect.TypeOf((*MockRecurring)(nil).CancelRecurring), ctx, walletID, id)
+}
+
+// CreateRecurring mocks base method.
+func (m *MockRecurring) CreateRecurring(ctx context.Context, r wallet.RecurringTransfer) (wallet.RecurringTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRecurring", ctx, r)
+	ret0, _ := ret[0].(wallet.RecurringTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRecurring indicates an expected call of CreateRecurring.
+func (mr *MockRecurringMockRecorder) CreateRecurring(ctx, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRecurring", reflect.TypeOf((*MockRecurring)(nil).CreateRecurring), ctx, r)
+}
+
+// ExecuteDueRecurring mocks base method.
+func (m *MockRecurring) ExecuteDueRecurring(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExecuteDueRecurring", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExecuteDueRecurring indicates an expected call of ExecuteDueRecurring.
+func (mr *MockRecurringMockRecorder) ExecuteDueRecurring(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecuteDueRecurring", reflect.TypeOf((*MockRecurring)(nil).ExecuteDueRecurring), ctx)
+}
+
+// GetRecurring mocks base method.
+func (m *MockRecurring) GetRecurring(ctx context.Context, walletID uuid.UUID, id int64) (wallet.RecurringTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRecurring", ctx, walletID, id)
+	ret0, _ := ret[0].(wallet.RecurringTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRecurring indicates an expected call of GetRecurring.
+func (mr *MockRecurringMockRecorder) GetRecurring(ctx, walletID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRecurring", reflect.TypeOf((*MockRecurring)(nil).GetRecurring), ctx, walletID, id)
+}
+
+// ListRecurring mocks base method.
+func (m *MockRecurring) ListRecurring(ctx context.Context, walletID uuid.UUID, limit int) ([]wallet.RecurringTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRecurring", ctx, walletID, limit)
+	ret0, _ := ret[0].([]wallet.RecurringTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRecurring indicates an expected call of ListRecurring.
+func (mr *MockRecurringMockRecorder) ListRecurring(ctx, walletID, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRecurring", reflect.TypeOf((*MockRecurring)(nil).ListRecurring), ctx, walletID, limit)
+}
+
+// ListRecurringRuns mocks base method.
+func (m *MockRecurring) ListRecurringRuns(ctx context.Context, walletID uuid.UUID, id, afterID int64, limit int) ([]wallet.RecurringRun, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRecurringRuns", ctx, walletID, id, afterID, limit)
+	ret0, _ := ret[0].([]wallet.RecurringRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRecurringRuns indicates an expected call of ListRecurringRuns.
+func (mr *MockRecurringMockRecorder) ListRecurringRuns(ctx, walletID, id, afterID, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRecurringRuns", reflect.TypeOf((*MockRecurring)(nil).ListRecurringRuns), ctx, walletID, id, afterID, limit)
+}
+
+// PauseRecurring mocks base method.
+func (m *MockRecurring) PauseRecurring(ctx context.Context, walletID uuid.UUID, id int64) (wallet.RecurringTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PauseRecurring", ctx, walletID, id)
+	ret0, _ := ret[0].(wallet.RecurringTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PauseRecurring indicates an expected call of PauseRecurring.
+func (mr *MockRecurringMockRecorder) PauseRecurring(ctx, walletID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PauseRecurring", reflect.TypeOf((*MockRecurring)(nil).PauseRecurring), ctx, walletID, id)
+}
+
+// ResumeRecurring mocks base method.
+func (m *MockRecurring) ResumeRecurring(ctx context.Context, walletID uuid.UUID, id int64) (wallet.RecurringTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResumeRecurring", ctx, walletID, id)
+	ret0, _ := ret[0].(wallet.RecurringTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResumeRecurring indicates an expected call of ResumeRecurring.
+func (mr *MockRecurringMockRecorder) ResumeRecurring(ctx, walletID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResumeRecurring", reflect.TypeOf((*MockRecurring)(nil).ResumeRecurring), ctx, walletID, id)
+}
+
+// MockInterest is a mock of Interest interface.
+type MockInterest struct {
+	ctrl     *gomock.Controller
+	recorder *MockInterestMockRecorder
+}
+
+// MockInterestMockRecorder is the mock recorder for MockInterest.
+type MockInterestMockRecorder struct {
+	mock *MockInterest
+}
+
+// NewMockInterest creates a new mock instance.
+func NewMockInterest(ctrl *gomock.Controller) *MockInterest {
+	mock := &MockInterest{ctrl: ctrl}
+	mock.recorder = &MockInterestMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInterest) EXPECT() *MockInterestMockRecorder {
+	return m.recorder
+}
+
+// ListAccruals mocks base method.
+func (m *MockInterest) ListAccruals(ctx context.Context, walletID uuid.UUID, from, to time.Time) ([]wallet.InterestAccrual, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccruals", ctx, walletID, from, to)
+	ret0, _ := ret[0].([]wallet.InterestAccrual)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccruals indicates an expected call of ListAccruals.
+func (mr *MockInterestMockRecorder) ListAccruals(ctx, walletID, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccruals", reflect.TypeOf((*MockInterest)(nil).ListAccruals), ctx, walletID, from, to)
+}
+
+// RunInterest mocks base method.
+func (m *MockInterest) RunInterest(ctx context.Context, now time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunInterest", ctx, now)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RunInterest indicates an expected call of RunInterest.
+func (mr *MockInterestMockRecorder) RunInterest(ctx, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunInterest", reflect.TypeOf((*MockInterest)(nil).RunInterest), ctx, now)
+}
+
+// SetProduct mocks base method.
+func (m *MockInterest) SetProduct(ctx context.Context, walletID uuid.UUID, product string) (wallet.Wallet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetProduct", ctx, walletID, product)
+	ret0, _ := ret[0].(wallet.Wallet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetProduct indicates an expected call of SetProduct.
+func (mr *MockInterestMockRecorder) SetProduct(ctx, walletID, product interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetProduct", reflect.TypeOf((*MockInterest)(nil).SetProduct), ctx, walletID, product)
+}
+
+// MockReceipt is a mock of Receipt interface.
+type MockReceipt struct {
+	ctrl     *gomock.Controller
+	recorder *MockReceiptMockRecorder
+}
+
+// MockReceiptMockRecorder is the mock recorder for MockReceipt.
+type MockReceiptMockRecorder struct {
+	mock *MockReceipt
+}
+
+// NewMockReceipt creates a new mock instance.
+func NewMockReceipt(ctrl *gomock.Controller) *MockReceipt {
+	mock := &MockReceipt{ctrl: ctrl}
+	mock.recorder = &MockReceiptMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReceipt) EXPECT() *MockReceiptMockRecorder {
+	return m.recorder
+}
+
+// Issue mocks base method.
+func (m *MockReceipt) Issue(WT wallet.WalletTransactions) (receipt.Receipt, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Issue", WT)
+	ret0, _ := ret[0].(receipt.Receipt)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// Issue indicates an expected call of Issue.
+func (mr *MockReceiptMockRecorder) Issue(WT interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Issue", reflect.TypeOf((*MockReceipt)(nil).Issue), WT)
+}
+
+// PublicKeys mocks base method.
+func (m *MockReceipt) PublicKeys() []receipt.PublicKey {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PublicKeys")
+	ret0, _ := ret[0].([]receipt.PublicKey)
+	return ret0
+}
+
+// PublicKeys indicates an expected call of PublicKeys.
+func (mr *MockReceiptMockRecorder) PublicKeys() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublicKeys", reflect.TypeOf((*MockReceipt)(nil).PublicKeys))
+}
+
+// MockWebhook is a mock of Webhook interface.
+type MockWebhook struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhookMockRecorder
+}
+
+// MockWebhookMockRecorder is the mock recorder for MockWebhook.
+type MockWebhookMockRecorder struct {
+	mock *MockWebhook
+}
+
+// NewMockWebhook creates a new mock instance.
+func NewMockWebhook(ctrl *gomock.Controller) *MockWebhook {
+	mock := &MockWebhook{ctrl: ctrl}
+	mock.recorder = &MockWebhookMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhook) EXPECT() *MockWebhookMockRecorder {
+	return m.recorder
+}
+
+// DispatchDue mocks base method.
+func (m *MockWebhook) DispatchDue(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DispatchDue", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DispatchDue indicates an expected call of DispatchDue.
+func (mr *MockWebhookMockRecorder) DispatchDue(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DispatchDue", reflect.TypeOf((*MockWebhook)(nil).DispatchDue), ctx)
+}
+
+// ListDeadDeliveries mocks base method.
+func (m *MockWebhook) ListDeadDeliveries(ctx context.Context, limit int) ([]wallet.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeadDeliveries", ctx, limit)
+	ret0, _ := ret[0].([]wallet.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeadDeliveries indicates an expected call of ListDeadDeliveries.
+func (mr *MockWebhookMockRecorder) ListDeadDeliveries(ctx, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeadDeliveries", reflect.TypeOf((*MockWebhook)(nil).ListDeadDeliveries), ctx, limit)
+}
+
+// ListSubscriptions mocks base method.
+func (m *MockWebhook) ListSubscriptions(ctx context.Context) ([]wallet.WebhookSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSubscriptions", ctx)
+	ret0, _ := ret[0].([]wallet.WebhookSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSubscriptions indicates an expected call of ListSubscriptions.
+func (mr *MockWebhookMockRecorder) ListSubscriptions(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSubscriptions", reflect.TypeOf((*MockWebhook)(nil).ListSubscriptions), ctx)
+}
+
+// ReplayDelivery mocks base method.
+func (m *MockWebhook) ReplayDelivery(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplayDelivery", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplayDelivery indicates an expected call of ReplayDelivery.
+func (mr *MockWebhookMockRecorder) ReplayDelivery(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplayDelivery", reflect.TypeOf((*MockWebhook)(nil).ReplayDelivery), ctx, id)
+}
+
+// Subscribe mocks base method.
+func (m *MockWebhook) Subscribe(ctx context.Context, sub wallet.WebhookSubscription) (wallet.WebhookSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Subscribe", ctx, sub)
+	ret0, _ := ret[0].(wallet.WebhookSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Subscribe indicates an expected call of Subscribe.
+func (mr *MockWebhookMockRecorder) Subscribe(ctx, sub interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockWebhook)(nil).Subscribe), ctx, sub)
+}
+
+// Unsubscribe mocks base method.
+func (m *MockWebhook) Unsubscribe(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unsubscribe", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unsubscribe indicates an expected call of Unsubscribe.
+func (mr *MockWebhookMockRecorder) Unsubscribe(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unsubscribe", reflect.TypeOf((*MockWebhook)(nil).Unsubscribe), ctx, id)
+}
+
+// MockOutbox is a mock of Outbox interface.
+type MockOutbox struct {
+	ctrl     *gomock.Controller
+	recorder *MockOutboxMockRecorder
+}
+
+// MockOutboxMockRecorder is the mock recorder for MockOutbox.
+type MockOutboxMockRecorder struct {
+	mock *MockOutbox
+}
+
+// NewMockOutbox creates a new mock instance.
+func NewMockOutbox(ctrl *gomock.Controller) *MockOutbox {
+	mock := &MockOutbox{ctrl: ctrl}
+	mock.recorder = &MockOutboxMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOutbox) EXPECT() *MockOutboxMockRecorder {
+	return m.recorder
+}
+
+// Purge mocks base method.
+func (m *MockOutbox) Purge(ctx context.Context, before time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", ctx, before)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockOutboxMockRecorder) Purge(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockOutbox)(nil).Purge), ctx, before)
+}
+
+// Relay mocks base method.
+func (m *MockOutbox) Relay(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Relay", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Relay indicates an expected call of Relay.
+func (mr *MockOutboxMockRecorder) Relay(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Relay", reflect.TypeOf((*MockOutbox)(nil).Relay), ctx)
+}
+
+// MockStream is a mock of Stream interface.
+type MockStream struct {
+	ctrl     *gomock.Controller
+	recorder *MockStreamMockRecorder
+}
+
+// MockStreamMockRecorder is the mock recorder for MockStream.
+type MockStreamMockRecorder struct {
+	mock *MockStream
+}
+
+// NewMockStream creates a new mock instance.
+func NewMockStream(ctrl *gomock.Controller) *MockStream {
+	mock := &MockStream{ctrl: ctrl}
+	mock.recorder = &MockStreamMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStream) EXPECT() *MockStreamMockRecorder {
+	return m.recorder
+}
+
+// Run mocks base method.
+func (m *MockStream) Run(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Run", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Run indicates an expected call of Run.
+func (mr *MockStreamMockRecorder) Run(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Run", reflect.TypeOf((*MockStream)(nil).Run), ctx)
+}
+
+// Subscribe mocks base method.
+func (m *MockStream) Subscribe(ctx context.Context, walletID uuid.UUID, afterSeq int64) (<-chan wallet.BalanceUpdate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Subscribe", ctx, walletID, afterSeq)
+	ret0, _ := ret[0].(<-chan wallet.BalanceUpdate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Subscribe indicates an expected call of Subscribe.
+func (mr *MockStreamMockRecorder) Subscribe(ctx, walletID, afterSeq interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockStream)(nil).Subscribe), ctx, walletID, afterSeq)
+}
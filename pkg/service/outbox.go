@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+)
+
+const defaultOutboxBatchSize = 100
+
+// EventPublisher - получатель событий из outbox: брокер сообщений, HTTP-приёмник, журнал (см. pkg/publisher).
+// Publish должен вернуть nil только после того, как получатель принял событие; иначе оно будет отправлено повторно.
+// Получатель должен быть готов к повторам: доставка - как минимум один раз, с дедупликацией по WalletEvent.Id.
+type EventPublisher interface {
+	Publish(ctx context.Context, event wallet.WalletEvent) error
+}
+
+// OutboxService - relay transactional outbox: читает события в порядке записи и передаёт их всем publishers.
+// События одного кошелька публикуются строго по порядку: если событие не удалось опубликовать,
+// следующие события этого кошелька ждут следующего прохода, а события других кошельков публикуются дальше.
+type OutboxService struct {
+	repo       repository.Outbox
+	publishers []EventPublisher
+	batchSize  int
+}
+
+func NewOutboxService(repo repository.Outbox, publishers []EventPublisher, batchSize int) *OutboxService {
+	if batchSize <= 0 {
+		batchSize = defaultOutboxBatchSize
+	}
+
+	return &OutboxService{repo: repo, publishers: publishers, batchSize: batchSize}
+}
+
+// Relay публикует очередную пачку событий и возвращает число опубликованных.
+func (s *OutboxService) Relay(ctx context.Context) (int, error) {
+	return s.repo.RelayOutbox(ctx, s.batchSize, s.publish)
+}
+
+// Purge удаляет события, опубликованные раньше before.
+func (s *OutboxService) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return s.repo.PurgeOutbox(ctx, before)
+}
+
+func (s *OutboxService) publish(ctx context.Context, events []wallet.OutboxEvent) []int64 {
+	published := make([]int64, 0, len(events))
+	blocked := make(map[string]bool)
+
+	for _, e := range events {
+		walletID := e.Event.ValletId.UUID.String()
+		if blocked[walletID] {
+			continue
+		}
+
+		if err := s.publishEvent(ctx, e.Event); err != nil {
+			log.Printf("failed to publish %s event %s for wallet %s: %s", e.Event.Type, e.Event.Id, walletID, err.Error())
+			blocked[walletID] = true
+			continue
+		}
+		published = append(published, e.Id)
+	}
+	return published
+}
+
+// publishEvent отправляет событие всем получателям. При ошибке одного из них событие будет отправлено повторно
+// всем, поэтому получатели, уже принявшие его, увидят дубликат.
+func (s *OutboxService) publishEvent(ctx context.Context, event wallet.WalletEvent) error {
+	for _, p := range s.publishers {
+		if err := p.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
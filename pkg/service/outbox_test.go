@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingPublisher запоминает опубликованные события и отказывает, пока fail возвращает true.
+type recordingPublisher struct {
+	mu     sync.Mutex
+	fail   func(event wallet.WalletEvent) bool
+	events []wallet.WalletEvent
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event wallet.WalletEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.fail != nil && p.fail(event) {
+		return errors.New("broker unavailable")
+	}
+	p.events = append(p.events, event)
+	return nil
+}
+
+// seqs возвращает seq транзакций из опубликованных событий кошелька в порядке публикации.
+func (p *recordingPublisher) seqs(walletID uuid.UUID) []int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var seqs []int64
+	for _, e := range p.events {
+		if e.ValletId.UUID == walletID.UUID {
+			seqs = append(seqs, e.Transaction.Seq)
+		}
+	}
+	return seqs
+}
+
+func TestOutboxService_Relay(t *testing.T) {
+	ctx := context.Background()
+
+	var a, b uuid.UUID
+	require.NoError(t, a.Scan("11111111-1111-1111-1111-111111111111"))
+	require.NoError(t, b.Scan("22222222-2222-2222-2222-222222222222"))
+
+	mem := repository.NewWalletMemory()
+	mem.AddWallet(a, 0)
+	mem.AddWallet(b, 0)
+	repo := repository.NewMemoryRepository(mem)
+	wallets := NewWalletService(repo.Wallet, Config{})
+
+	for i := 0; i < 3; i++ {
+		for _, id := range []uuid.UUID{a, b} {
+			_, err := wallets.UpdateBalance(ctx, wallet.WalletTransactions{ValletId: id, OperationType: "DEPOSIT", Amount: 1})
+			require.NoError(t, err)
+		}
+	}
+
+	// Брокер отказывает второму событию кошелька A: остальные события A ждут, события B публикуются.
+	down := true
+	failing := &recordingPublisher{fail: func(e wallet.WalletEvent) bool {
+		return down && e.ValletId.UUID == a.UUID && e.Transaction.Seq == 2
+	}}
+	logged := &recordingPublisher{}
+	outbox := NewOutboxService(repo.Outbox, []EventPublisher{logged, failing}, 0)
+
+	n, err := outbox.Relay(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, []int64{1}, failing.seqs(a))
+	assert.Equal(t, []int64{1, 2, 3}, failing.seqs(b))
+
+	down = false
+	n, err = outbox.Relay(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []int64{1, 2, 3}, failing.seqs(a))
+
+	// Получатель, принявший событие до отказа другого, получает его повторно (как минимум один раз).
+	assert.Equal(t, []int64{1, 2, 2, 3}, logged.seqs(a))
+
+	n, err = outbox.Relay(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"crypto/ed25519"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/receipt"
+)
+
+type ReceiptService struct {
+	keyID string
+	key   ed25519.PrivateKey
+	keys  []receipt.PublicKey
+}
+
+// NewReceiptService создаёт сервис квитанций. Без закрытого ключа квитанции не выдаются,
+// но ранее опубликованные ключи (retired) по-прежнему отдаются для проверки старых квитанций.
+func NewReceiptService(keyID string, key ed25519.PrivateKey, retired []receipt.PublicKey) *ReceiptService {
+	s := &ReceiptService{keyID: keyID, key: key}
+	if key != nil {
+		s.keys = append(s.keys, receipt.Public(keyID, key.Public().(ed25519.PublicKey)))
+	}
+	for _, k := range retired {
+		if k.KeyId != keyID {
+			s.keys = append(s.keys, k)
+		}
+	}
+	return s
+}
+
+// Issue подписывает квитанцию о записанной транзакции. Возвращает false, если ключ подписи не настроен.
+func (s *ReceiptService) Issue(WT wallet.WalletTransactions) (receipt.Receipt, bool) {
+	if s.key == nil {
+		return receipt.Receipt{}, false
+	}
+
+	return receipt.Sign(receipt.Receipt{
+		TransactionId: WT.Id,
+		ValletId:      WT.ValletId.UUID.String(),
+		OperationType: WT.OperationType,
+		Amount:        WT.Amount,
+		BalanceAfter:  WT.BalanceAfter,
+		Seq:           WT.Seq,
+		Timestamp:     WT.CreatedAt.UTC(),
+	}, s.keyID, s.key), true
+}
+
+func (s *ReceiptService) PublicKeys() []receipt.PublicKey {
+	return append([]receipt.PublicKey{}, s.keys...)
+}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/fee"
+	"github.com/KatenkaKet/wallet/pkg/recurrence"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+const (
+	// recurringBatch - сколько правил забирается за один проход обработчика.
+	recurringBatch = 100
+	// recurringLease - на сколько правила закрепляются за обработчиком; с запасом покрывает выполнение всей пачки.
+	recurringLease = 2 * time.Minute
+	// recurringGrace - насколько может опоздать срабатывание, чтобы при catchUp=skip не считаться пропущенным.
+	recurringGrace = 15 * time.Minute
+	// maxRecurringRetries - предел числа повторных попыток после нехватки средств.
+	maxRecurringRetries = 100
+	// defaultRecurringRetryInterval - пауза между попытками, если правило не задаёт свою.
+	defaultRecurringRetryInterval = time.Hour
+)
+
+// RecurringService - регулярные переводы между кошельками по расписанию.
+type RecurringService struct {
+	// wallets считает комиссию за перевод по тому же расписанию, что и Transfer.
+	wallets   *WalletService
+	repo      repository.Wallet
+	recurring repository.Recurring
+	now       func() time.Time
+}
+
+func NewRecurringService(wallets *WalletService, repo repository.Wallet, recurring repository.Recurring) *RecurringService {
+	return &RecurringService{wallets: wallets, repo: repo, recurring: recurring, now: time.Now}
+}
+
+// CreateRecurring проверяет и сохраняет правило. Пустой StartAt - с текущего момента, пустой CatchUp - latest;
+// первое срабатывание - не раньше текущего момента.
+func (s *RecurringService) CreateRecurring(ctx context.Context, r wallet.RecurringTransfer) (wallet.RecurringTransfer, error) {
+	now := s.now()
+	if r.StartAt.IsZero() {
+		r.StartAt = now
+	}
+	if r.CatchUp == "" {
+		r.CatchUp = wallet.CatchUpLatest
+	}
+	if r.MaxRetries > 0 && r.RetryIntervalSeconds == 0 {
+		r.RetryIntervalSeconds = int64(defaultRecurringRetryInterval / time.Second)
+	}
+
+	switch {
+	case r.FromWalletId.UUID == r.ToWalletId.UUID:
+		return wallet.RecurringTransfer{}, fmt.Errorf("%w: source and destination wallets must differ", ErrInvalidRecurring)
+	case r.Amount <= 0:
+		return wallet.RecurringTransfer{}, fmt.Errorf("%w: amount must be positive", ErrInvalidRecurring)
+	case r.EndAt != nil && !r.EndAt.After(r.StartAt):
+		return wallet.RecurringTransfer{}, fmt.Errorf("%w: endAt must be after startAt", ErrInvalidRecurring)
+	case r.CatchUp != wallet.CatchUpAll && r.CatchUp != wallet.CatchUpLatest && r.CatchUp != wallet.CatchUpSkip:
+		return wallet.RecurringTransfer{}, fmt.Errorf("%w: catchUp must be all, latest or skip", ErrInvalidRecurring)
+	case r.MaxRetries < 0 || r.MaxRetries > maxRecurringRetries:
+		return wallet.RecurringTransfer{}, fmt.Errorf("%w: maxRetries must be between 0 and %d", ErrInvalidRecurring, maxRecurringRetries)
+	case r.RetryIntervalSeconds < 0:
+		return wallet.RecurringTransfer{}, fmt.Errorf("%w: retryIntervalSeconds must not be negative", ErrInvalidRecurring)
+	}
+
+	schedule, err := recurrence.Parse(r.Schedule, r.StartAt)
+	if err != nil {
+		return wallet.RecurringTransfer{}, fmt.Errorf("%w: %s", ErrInvalidRecurring, err.Error())
+	}
+	first := s.occurrenceAfter(r, schedule, now.Add(-time.Nanosecond))
+	if first == nil {
+		return wallet.RecurringTransfer{}, fmt.Errorf("%w: schedule has no occurrences", ErrInvalidRecurring)
+	}
+
+	for _, id := range []uuid.UUID{r.FromWalletId, r.ToWalletId} {
+		if _, err := s.repo.GetWallet(ctx, id); err != nil {
+			return wallet.RecurringTransfer{}, err
+		}
+	}
+
+	r.Status = wallet.RecurringActive
+	r.OccurrenceAt = first
+	return s.recurring.CreateRecurring(ctx, r)
+}
+
+// occurrenceAfter возвращает первое срабатывание правила после after или nil, если срабатываний больше нет.
+func (s *RecurringService) occurrenceAfter(r wallet.RecurringTransfer, schedule recurrence.Schedule, after time.Time) *time.Time {
+	next := schedule.Next(after)
+	if next.IsZero() || (r.EndAt != nil && next.After(*r.EndAt)) {
+		return nil
+	}
+	return &next
+}
+
+func (s *RecurringService) GetRecurring(ctx context.Context, walletID uuid.UUID, id int64) (wallet.RecurringTransfer, error) {
+	return s.recurring.GetRecurring(ctx, walletID, id)
+}
+
+func (s *RecurringService) ListRecurring(ctx context.Context, walletID uuid.UUID, limit int) ([]wallet.RecurringTransfer, error) {
+	if _, err := s.repo.GetWallet(ctx, walletID); err != nil {
+		return nil, err
+	}
+	return s.recurring.ListRecurring(ctx, walletID, limit)
+}
+
+func (s *RecurringService) ListRecurringRuns(ctx context.Context, walletID uuid.UUID, id int64, afterID int64, limit int) ([]wallet.RecurringRun, error) {
+	return s.recurring.ListRecurringRuns(ctx, walletID, id, afterID, limit)
+}
+
+// PauseRecurring приостанавливает активное правило. Перевод, который обработчик уже начал, завершится.
+func (s *RecurringService) PauseRecurring(ctx context.Context, walletID uuid.UUID, id int64) (wallet.RecurringTransfer, error) {
+	return s.recurring.UpdateRecurringStatus(ctx, walletID, id, []string{wallet.RecurringActive}, wallet.RecurringPaused, nil)
+}
+
+// ResumeRecurring возобновляет приостановленное правило со следующего срабатывания после текущего момента:
+// сроки, прошедшие за время паузы, не выполняются. Если срабатываний больше нет, правило завершается.
+func (s *RecurringService) ResumeRecurring(ctx context.Context, walletID uuid.UUID, id int64) (wallet.RecurringTransfer, error) {
+	r, err := s.recurring.GetRecurring(ctx, walletID, id)
+	if err != nil {
+		return wallet.RecurringTransfer{}, err
+	}
+	schedule, err := recurrence.Parse(r.Schedule, r.StartAt)
+	if err != nil {
+		return wallet.RecurringTransfer{}, err
+	}
+
+	next := s.occurrenceAfter(r, schedule, s.now())
+	status := wallet.RecurringActive
+	if next == nil {
+		status = wallet.RecurringFinished
+	}
+	return s.recurring.UpdateRecurringStatus(ctx, walletID, id, []string{wallet.RecurringPaused}, status, next)
+}
+
+// CancelRecurring окончательно останавливает активное или приостановленное правило.
+func (s *RecurringService) CancelRecurring(ctx context.Context, walletID uuid.UUID, id int64) (wallet.RecurringTransfer, error) {
+	return s.recurring.UpdateRecurringStatus(ctx, walletID, id, []string{wallet.RecurringActive, wallet.RecurringPaused},
+		wallet.RecurringCancelled, nil)
+}
+
+// ExecuteDueRecurring выполняет правила, срок попытки которых наступил, и возвращает число обработанных.
+// Каждый срок переводится в одной транзакции БД с ключом идемпотентности "recurring:<id>:<срок>" у обеих
+// операций, поэтому повтор после падения обработчика не переведёт деньги второй раз.
+func (s *RecurringService) ExecuteDueRecurring(ctx context.Context) (int, error) {
+	due, err := s.recurring.ClaimRecurring(ctx, s.now(), recurringLease, recurringBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, r := range due {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		s.execute(ctx, r)
+	}
+	return len(due), nil
+}
+
+func (s *RecurringService) execute(ctx context.Context, r wallet.RecurringTransfer) {
+	schedule, err := recurrence.Parse(r.Schedule, r.StartAt)
+	if err != nil || r.OccurrenceAt == nil {
+		log.Printf("recurring transfer %d: invalid schedule %q", r.Id, r.Schedule)
+		return
+	}
+	now := s.now()
+	occurrence := *r.OccurrenceAt
+
+	// Политика пропусков применяется к новому сроку, а не к повторным попыткам уже начатого.
+	if r.Attempt == 0 {
+		switch r.CatchUp {
+		case wallet.CatchUpSkip:
+			if now.Sub(occurrence) > recurringGrace {
+				s.advance(&r, schedule, now)
+				s.finish(ctx, r, nil)
+				return
+			}
+		case wallet.CatchUpLatest:
+			for next := s.occurrenceAfter(r, schedule, occurrence); next != nil && !next.After(now); {
+				occurrence = *next
+				next = s.occurrenceAfter(r, schedule, occurrence)
+			}
+		}
+	}
+
+	key := fmt.Sprintf("recurring:%d:%d", r.Id, occurrence.Unix())
+	ops, err := s.transferOps(ctx, r, key)
+	if err != nil {
+		log.Printf("recurring transfer %d: %s", r.Id, err.Error())
+		return
+	}
+	recorded, results, err := s.repo.ApplyAtomic(ctx, ops)
+	if err != nil {
+		log.Printf("recurring transfer %d: %s", r.Id, err.Error())
+		return
+	}
+
+	run := &wallet.RecurringRun{OccurrenceAt: occurrence, Attempt: r.Attempt + 1, Status: wallet.RecurringRunSucceeded}
+	for _, opErr := range results {
+		if opErr != nil && !errors.Is(opErr, repository.ErrDuplicateTransaction) {
+			run.Status, run.Error = wallet.RecurringRunFailed, opErr.Error()
+			if errors.Is(opErr, repository.ErrInsufficientFunds) && r.Attempt < r.MaxRetries {
+				run.Status = wallet.RecurringRunRetrying
+			}
+			break
+		}
+	}
+
+	switch run.Status {
+	case wallet.RecurringRunSucceeded:
+		run.FromTransactionId, run.ToTransactionId = &recorded[0].Id, &recorded[1].Id
+		s.advance(&r, schedule, occurrence)
+	case wallet.RecurringRunRetrying:
+		retryAt := now.Add(time.Duration(r.RetryIntervalSeconds) * time.Second)
+		r.OccurrenceAt, r.NextRunAt = &occurrence, &retryAt
+		r.Attempt++
+	default:
+		s.advance(&r, schedule, occurrence)
+	}
+	s.finish(ctx, r, run)
+}
+
+// transferOps - операции перевода по правилу r с ключом идемпотентности key и, если она положена, комиссией
+// за перевод, которая списывается с кошелька-источника в той же транзакции БД.
+func (s *RecurringService) transferOps(ctx context.Context, r wallet.RecurringTransfer, key string) ([]wallet.WalletTransactions, error) {
+	ops := []wallet.WalletTransactions{
+		{ValletId: r.FromWalletId, OperationType: "WITHDRAW", Amount: r.Amount, IdempotencyKey: key},
+		{ValletId: r.ToWalletId, OperationType: "DEPOSIT", Amount: r.Amount, IdempotencyKey: key},
+	}
+
+	charge, err := s.wallets.feeFor(ctx, r.FromWalletId, fee.Transfer, r.Amount)
+	if err != nil || charge == 0 {
+		return ops, err
+	}
+
+	// Повтор срока, переведённого до введения комиссии, не должен списать комиссию задним числом.
+	known, err := s.repo.FindIdempotent(ctx, ops[:1])
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := known[r.FromWalletId.UUID.String()][key]; ok {
+		return ops, nil
+	}
+
+	operationID, err := newOperationID()
+	if err != nil {
+		return nil, err
+	}
+	ops[0].OperationId, ops[1].OperationId = operationID, operationID
+	return append(ops, s.wallets.feeLegs(r.FromWalletId, key, charge, operationID)...), nil
+}
+
+// advance переводит правило на первый срок после after или завершает его, если сроков больше нет.
+func (s *RecurringService) advance(r *wallet.RecurringTransfer, schedule recurrence.Schedule, after time.Time) {
+	next := s.occurrenceAfter(*r, schedule, after)
+	r.OccurrenceAt, r.NextRunAt = next, next
+	r.Attempt = 0
+	if next == nil {
+		r.Status = wallet.RecurringFinished
+	}
+}
+
+func (s *RecurringService) finish(ctx context.Context, r wallet.RecurringTransfer, run *wallet.RecurringRun) {
+	// Результат записывается даже при отмене ctx, иначе срок будет обработан ещё раз после аренды.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 2*time.Second)
+	defer cancel()
+
+	if err := s.recurring.FinishRecurring(ctx, r, run); err != nil {
+		log.Printf("recurring transfer %d: %s", r.Id, err.Error())
+	}
+}
@@ -0,0 +1,305 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/fee"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecurringService(t *testing.T) {
+	ctx := context.Background()
+
+	var a, b, missing uuid.UUID
+	require.NoError(t, a.Scan("11111111-1111-1111-1111-111111111111"))
+	require.NoError(t, b.Scan("22222222-2222-2222-2222-222222222222"))
+	require.NoError(t, missing.Scan("99999999-9999-9999-9999-999999999999"))
+
+	mem := repository.NewWalletMemory()
+	mem.AddWallet(a, 150)
+	mem.AddWallet(b, 10)
+	recurring := repository.NewRecurringMemory()
+	s := NewRecurringService(NewWalletService(mem, Config{}), mem, recurring)
+
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	balances := func() (float64, float64) {
+		balanceA, err := mem.GetBalance(ctx, a)
+		require.NoError(t, err)
+		balanceB, err := mem.GetBalance(ctx, b)
+		require.NoError(t, err)
+		return balanceA, balanceB
+	}
+	execute := func(expected int) {
+		t.Helper()
+		n, err := s.ExecuteDueRecurring(ctx)
+		require.NoError(t, err)
+		require.Equal(t, expected, n)
+	}
+
+	t.Run("validation", func(t *testing.T) {
+		valid := wallet.RecurringTransfer{FromWalletId: a, ToWalletId: b, Amount: 1, Schedule: "@monthly"}
+		for name, change := range map[string]func(r *wallet.RecurringTransfer){
+			"same wallet":    func(r *wallet.RecurringTransfer) { r.ToWalletId = a },
+			"zero amount":    func(r *wallet.RecurringTransfer) { r.Amount = 0 },
+			"bad schedule":   func(r *wallet.RecurringTransfer) { r.Schedule = "every month" },
+			"bad catch-up":   func(r *wallet.RecurringTransfer) { r.CatchUp = "some" },
+			"too many tries": func(r *wallet.RecurringTransfer) { r.MaxRetries = 1000 },
+			"end before start": func(r *wallet.RecurringTransfer) {
+				end := now.Add(-time.Hour)
+				r.EndAt = &end
+			},
+			"no occurrences": func(r *wallet.RecurringTransfer) { r.Schedule = "FREQ=DAILY;UNTIL=20240101" },
+		} {
+			r := valid
+			change(&r)
+			_, err := s.CreateRecurring(ctx, r)
+			assert.ErrorIs(t, err, ErrInvalidRecurring, name)
+		}
+
+		r := valid
+		r.ToWalletId = missing
+		_, err := s.CreateRecurring(ctx, r)
+		assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+	})
+
+	monthly, err := s.CreateRecurring(ctx, wallet.RecurringTransfer{FromWalletId: a, ToWalletId: b, Amount: 100,
+		Schedule: "0 9 1 * *", CatchUp: wallet.CatchUpAll, MaxRetries: 1})
+	require.NoError(t, err)
+	assert.Equal(t, wallet.RecurringActive, monthly.Status)
+	assert.Equal(t, int64(3600), monthly.RetryIntervalSeconds)
+	require.NotNil(t, monthly.NextRunAt)
+	assert.Equal(t, time.Date(2025, 2, 1, 9, 0, 0, 0, time.UTC), *monthly.NextRunAt)
+
+	t.Run("transfer on schedule", func(t *testing.T) {
+		execute(0)
+
+		now = time.Date(2025, 2, 1, 9, 0, 30, 0, time.UTC)
+		execute(1)
+		balanceA, balanceB := balances()
+		assert.Equal(t, 50.0, balanceA)
+		assert.Equal(t, 110.0, balanceB)
+
+		r, err := s.GetRecurring(ctx, a, monthly.Id)
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC), *r.NextRunAt)
+
+		runs, err := s.ListRecurringRuns(ctx, a, monthly.Id, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, runs, 1)
+		assert.Equal(t, wallet.RecurringRunSucceeded, runs[0].Status)
+		require.NotNil(t, runs[0].FromTransactionId)
+		require.NotNil(t, runs[0].ToTransactionId)
+
+		history, err := mem.ListTransactions(ctx, b, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		assert.Equal(t, *runs[0].ToTransactionId, history[0].Id)
+		assert.Equal(t, fmt.Sprintf("recurring:%d:%d", monthly.Id, time.Date(2025, 2, 1, 9, 0, 0, 0, time.UTC).Unix()),
+			history[0].IdempotencyKey)
+	})
+
+	t.Run("retry on insufficient funds", func(t *testing.T) {
+		now = time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+		execute(1)
+
+		r, err := s.GetRecurring(ctx, a, monthly.Id)
+		require.NoError(t, err)
+		assert.Equal(t, 1, r.Attempt)
+		assert.Equal(t, now.Add(time.Hour), *r.NextRunAt)
+		assert.Equal(t, time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC), *r.OccurrenceAt)
+
+		_, _, err = mem.ApplyTransactions(ctx, a, []wallet.WalletTransactions{{OperationType: "DEPOSIT", Amount: 100}})
+		require.NoError(t, err)
+		now = now.Add(time.Hour)
+		execute(1)
+		balanceA, _ := balances()
+		assert.Equal(t, 50.0, balanceA)
+
+		runs, err := s.ListRecurringRuns(ctx, a, monthly.Id, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, runs, 3)
+		assert.Equal(t, wallet.RecurringRunRetrying, runs[1].Status)
+		assert.Contains(t, runs[1].Error, "insufficient funds")
+		assert.Equal(t, wallet.RecurringRunSucceeded, runs[2].Status)
+		assert.Equal(t, 2, runs[2].Attempt)
+		assert.Equal(t, runs[1].OccurrenceAt, runs[2].OccurrenceAt)
+
+		// Попытки исчерпаны: срок записывается как неудачный, и правило переходит к следующему.
+		now = time.Date(2025, 4, 1, 9, 0, 0, 0, time.UTC)
+		execute(1)
+		now = now.Add(time.Hour)
+		execute(1)
+		r, err = s.GetRecurring(ctx, a, monthly.Id)
+		require.NoError(t, err)
+		assert.Zero(t, r.Attempt)
+		assert.Equal(t, time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC), *r.NextRunAt)
+		runs, err = s.ListRecurringRuns(ctx, a, monthly.Id, runs[2].Id, 10)
+		require.NoError(t, err)
+		require.Len(t, runs, 2)
+		assert.Equal(t, wallet.RecurringRunFailed, runs[1].Status)
+	})
+
+	t.Run("pause and resume skip missed occurrences", func(t *testing.T) {
+		paused, err := s.PauseRecurring(ctx, a, monthly.Id)
+		require.NoError(t, err)
+		assert.Equal(t, wallet.RecurringPaused, paused.Status)
+		_, err = s.PauseRecurring(ctx, a, monthly.Id)
+		assert.ErrorIs(t, err, repository.ErrRecurringStatus)
+
+		now = time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)
+		execute(0)
+
+		resumed, err := s.ResumeRecurring(ctx, a, monthly.Id)
+		require.NoError(t, err)
+		assert.Equal(t, wallet.RecurringActive, resumed.Status)
+		assert.Equal(t, time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC), *resumed.NextRunAt)
+
+		cancelled, err := s.CancelRecurring(ctx, a, monthly.Id)
+		require.NoError(t, err)
+		assert.Equal(t, wallet.RecurringCancelled, cancelled.Status)
+		_, err = s.ResumeRecurring(ctx, a, monthly.Id)
+		assert.ErrorIs(t, err, repository.ErrRecurringStatus)
+		_, err = s.CancelRecurring(ctx, b, monthly.Id)
+		assert.ErrorIs(t, err, repository.ErrRecurringNotFound)
+	})
+
+	t.Run("catch-up after downtime", func(t *testing.T) {
+		now = time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+		daily := func(catchUp string) wallet.RecurringTransfer {
+			r, err := s.CreateRecurring(ctx, wallet.RecurringTransfer{FromWalletId: b, ToWalletId: a, Amount: 1,
+				Schedule: "0 0 * * *", CatchUp: catchUp})
+			require.NoError(t, err)
+			return r
+		}
+		all, latest, skip := daily(wallet.CatchUpAll), daily(wallet.CatchUpLatest), daily(wallet.CatchUpSkip)
+
+		// Сервис не работал три дня: прошли сроки 16, 17 и 18 июня.
+		now = time.Date(2025, 6, 18, 6, 0, 0, 0, time.UTC)
+		for n := 1; n > 0; {
+			n, err = s.ExecuteDueRecurring(ctx)
+			require.NoError(t, err)
+		}
+
+		count := func(r wallet.RecurringTransfer) []time.Time {
+			runs, err := s.ListRecurringRuns(ctx, b, r.Id, 0, 10)
+			require.NoError(t, err)
+			var occurrences []time.Time
+			for _, run := range runs {
+				assert.Equal(t, wallet.RecurringRunSucceeded, run.Status)
+				occurrences = append(occurrences, run.OccurrenceAt)
+			}
+			return occurrences
+		}
+		day := func(d int) time.Time { return time.Date(2025, 6, d, 0, 0, 0, 0, time.UTC) }
+
+		assert.Equal(t, []time.Time{day(16), day(17), day(18)}, count(all))
+		assert.Equal(t, []time.Time{day(18)}, count(latest))
+		assert.Empty(t, count(skip))
+
+		for _, r := range []wallet.RecurringTransfer{all, latest, skip} {
+			got, err := s.GetRecurring(ctx, b, r.Id)
+			require.NoError(t, err)
+			assert.Equal(t, day(19), *got.NextRunAt, r.CatchUp)
+		}
+	})
+
+	t.Run("replay after a crash does not transfer twice", func(t *testing.T) {
+		r, err := s.CreateRecurring(ctx, wallet.RecurringTransfer{FromWalletId: b, ToWalletId: a, Amount: 5,
+			Schedule: "30 6 * * *", CatchUp: wallet.CatchUpAll})
+		require.NoError(t, err)
+
+		// Обработчик перевёл деньги, но упал до записи результата: после аренды срок выполняется повторно.
+		now = time.Date(2025, 6, 18, 6, 30, 0, 0, time.UTC)
+		_, err = recurring.ClaimRecurring(ctx, now, time.Minute, 10)
+		require.NoError(t, err)
+		key := fmt.Sprintf("recurring:%d:%d", r.Id, now.Unix())
+		_, _, err = mem.ApplyAtomic(ctx, []wallet.WalletTransactions{
+			{ValletId: b, OperationType: "WITHDRAW", Amount: 5, IdempotencyKey: key},
+			{ValletId: a, OperationType: "DEPOSIT", Amount: 5, IdempotencyKey: key},
+		})
+		require.NoError(t, err)
+		balanceA, balanceB := balances()
+
+		now = now.Add(2 * time.Minute)
+		execute(1)
+		afterA, afterB := balances()
+		assert.Equal(t, balanceA, afterA)
+		assert.Equal(t, balanceB, afterB)
+
+		runs, err := s.ListRecurringRuns(ctx, b, r.Id, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, runs, 1)
+		assert.Equal(t, wallet.RecurringRunSucceeded, runs[0].Status)
+		assert.NotNil(t, runs[0].FromTransactionId)
+	})
+}
+
+func TestRecurringService_Fee(t *testing.T) {
+	ctx := context.Background()
+
+	var a, b, fees uuid.UUID
+	require.NoError(t, a.Scan("11111111-1111-1111-1111-111111111111"))
+	require.NoError(t, b.Scan("22222222-2222-2222-2222-222222222222"))
+	require.NoError(t, fees.Scan("00000000-0000-0000-0000-000000000fee"))
+
+	mem := repository.NewWalletMemory()
+	mem.AddWallet(a, 100)
+	mem.AddWallet(b, 0)
+	mem.AddWallet(fees, 0)
+
+	schedule, err := fee.New([]fee.Rule{{Operation: fee.Transfer, Fixed: 0.5}})
+	require.NoError(t, err)
+	s := NewRecurringService(NewWalletService(mem, Config{Fees: schedule, FeeWallet: fees}), mem, repository.NewRecurringMemory())
+
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	r, err := s.CreateRecurring(ctx, wallet.RecurringTransfer{FromWalletId: a, ToWalletId: b, Amount: 60,
+		Schedule: "0 9 1 * *", MaxRetries: 1})
+	require.NoError(t, err)
+
+	balance := func(id uuid.UUID) float64 {
+		t.Helper()
+		balance, err := mem.GetBalance(ctx, id)
+		require.NoError(t, err)
+		return balance
+	}
+
+	now = time.Date(2025, 2, 1, 9, 0, 0, 0, time.UTC)
+	n, err := s.ExecuteDueRecurring(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	assert.Equal(t, 39.5, balance(a))
+	assert.Equal(t, 60.0, balance(b))
+	assert.Equal(t, 0.5, balance(fees))
+
+	history, err := mem.ListTransactions(ctx, a, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, wallet.KindFee, history[1].Kind)
+	require.NotNil(t, history[0].OperationId)
+	assert.Equal(t, history[0].OperationId, history[1].OperationId)
+
+	// На перевод хватает, но не на комиссию: срок уходит на повтор, ничего не списывается.
+	_, _, err = mem.ApplyTransactions(ctx, a, []wallet.WalletTransactions{{ValletId: a, OperationType: "DEPOSIT", Amount: 20.5}})
+	require.NoError(t, err)
+	now = time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	_, err = s.ExecuteDueRecurring(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 60.0, balance(a))
+	assert.Equal(t, 0.5, balance(fees))
+
+	runs, err := s.ListRecurringRuns(ctx, a, r.Id, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, wallet.RecurringRunRetrying, runs[1].Status)
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+const (
+	// scheduledBatch - сколько отложенных операций забирается за один проход обработчика.
+	scheduledBatch = 100
+	// scheduledLease - на сколько операции закрепляются за обработчиком; с запасом покрывает выполнение всей пачки.
+	scheduledLease = 2 * time.Minute
+	// scheduledKeyPrefix - префикс ключа идемпотентности, с которым выполняется отложенная операция.
+	scheduledKeyPrefix = "scheduled:"
+)
+
+// ScheduledService - отложенные операции: создание, отмена и выполнение через WalletService после наступления срока.
+type ScheduledService struct {
+	wallets   Wallet
+	repo      repository.Wallet
+	scheduled repository.Scheduled
+	now       func() time.Time
+}
+
+func NewScheduledService(wallets Wallet, repo repository.Wallet, scheduled repository.Scheduled) *ScheduledService {
+	return &ScheduledService{wallets: wallets, repo: repo, scheduled: scheduled, now: time.Now}
+}
+
+// Schedule создаёт отложенную операцию кошелька st.ValletId; ExecuteAt должен быть в будущем.
+func (s *ScheduledService) Schedule(ctx context.Context, st wallet.ScheduledTransaction) (wallet.ScheduledTransaction, error) {
+	if st.OperationType != "DEPOSIT" && st.OperationType != "WITHDRAW" {
+		return wallet.ScheduledTransaction{}, fmt.Errorf("%w: operationType must be DEPOSIT or WITHDRAW", ErrInvalidScheduled)
+	}
+	if st.Amount <= 0 {
+		return wallet.ScheduledTransaction{}, fmt.Errorf("%w: amount must be positive", ErrInvalidScheduled)
+	}
+	if !st.ExecuteAt.After(s.now()) {
+		return wallet.ScheduledTransaction{}, fmt.Errorf("%w: executeAt must be in the future", ErrInvalidScheduled)
+	}
+
+	if _, err := s.repo.GetWallet(ctx, st.ValletId); err != nil {
+		return wallet.ScheduledTransaction{}, err
+	}
+	return s.scheduled.CreateScheduled(ctx, st)
+}
+
+func (s *ScheduledService) ListScheduled(ctx context.Context, walletID uuid.UUID, status string, limit int) ([]wallet.ScheduledTransaction, error) {
+	switch status {
+	case "", wallet.ScheduledPending, wallet.ScheduledSucceeded, wallet.ScheduledFailed, wallet.ScheduledCancelled:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidScheduled, status)
+	}
+
+	if _, err := s.repo.GetWallet(ctx, walletID); err != nil {
+		return nil, err
+	}
+	return s.scheduled.ListScheduled(ctx, walletID, status, limit)
+}
+
+// Cancel отменяет ожидающую операцию. Операцию, которую обработчик уже начал выполнять, отменить нельзя.
+func (s *ScheduledService) Cancel(ctx context.Context, walletID uuid.UUID, id int64) (wallet.ScheduledTransaction, error) {
+	return s.scheduled.CancelScheduled(ctx, walletID, id, s.now())
+}
+
+// ExecuteDue выполняет операции, срок которых наступил, по порядку execute_at и возвращает число обработанных.
+// Нехватка средств и удалённый кошелёк - окончательный результат (failed); при других ошибках операция
+// остаётся ожидающей и будет повторена после истечения аренды. Повтор безопасен: операция выполняется
+// с ключом идемпотентности, поэтому уже записанная транзакция не применится второй раз.
+func (s *ScheduledService) ExecuteDue(ctx context.Context) (int, error) {
+	due, err := s.scheduled.ClaimScheduled(ctx, s.now(), scheduledLease, scheduledBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, st := range due {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		s.execute(ctx, st)
+	}
+	return len(due), nil
+}
+
+func (s *ScheduledService) execute(ctx context.Context, st wallet.ScheduledTransaction) {
+	recorded, err := s.wallets.UpdateBalance(ctx, wallet.WalletTransactions{
+		ValletId:       st.ValletId,
+		OperationType:  st.OperationType,
+		Amount:         st.Amount,
+		IdempotencyKey: scheduledKeyPrefix + strconv.FormatInt(st.Id, 10),
+	})
+	switch {
+	case err == nil:
+		st.Status = wallet.ScheduledSucceeded
+		st.TransactionId = &recorded.Id
+	case errors.Is(err, repository.ErrInsufficientFunds), errors.Is(err, repository.ErrWalletNotFound):
+		st.Status = wallet.ScheduledFailed
+		st.Error = err.Error()
+	default:
+		log.Printf("scheduled transaction %d: %s", st.Id, err.Error())
+		return
+	}
+
+	// Результат записывается даже при отмене ctx, иначе операция будет выполнена ещё раз после аренды.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 2*time.Second)
+	defer cancel()
+
+	now := s.now()
+	st.FinishedAt = &now
+	if err := s.scheduled.FinishScheduled(ctx, st); err != nil {
+		log.Printf("scheduled transaction %d: %s", st.Id, err.Error())
+	}
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduledService(t *testing.T) {
+	ctx := context.Background()
+
+	var a, missing uuid.UUID
+	require.NoError(t, a.Scan("11111111-1111-1111-1111-111111111111"))
+	require.NoError(t, missing.Scan("99999999-9999-9999-9999-999999999999"))
+
+	mem := repository.NewWalletMemory()
+	mem.AddWallet(a, 10)
+	scheduled := repository.NewScheduledMemory()
+	s := NewScheduledService(NewWalletService(mem, Config{}), mem, scheduled)
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	schedule := func(operationType string, amount float64, executeAt time.Time) wallet.ScheduledTransaction {
+		st, err := s.Schedule(ctx, wallet.ScheduledTransaction{ValletId: a, OperationType: operationType, Amount: amount, ExecuteAt: executeAt})
+		require.NoError(t, err)
+		return st
+	}
+
+	t.Run("validation", func(t *testing.T) {
+		_, err := s.Schedule(ctx, wallet.ScheduledTransaction{ValletId: a, OperationType: "REFUND", Amount: 1, ExecuteAt: now.Add(time.Hour)})
+		assert.ErrorIs(t, err, ErrInvalidScheduled)
+		_, err = s.Schedule(ctx, wallet.ScheduledTransaction{ValletId: a, OperationType: "DEPOSIT", Amount: 0, ExecuteAt: now.Add(time.Hour)})
+		assert.ErrorIs(t, err, ErrInvalidScheduled)
+		_, err = s.Schedule(ctx, wallet.ScheduledTransaction{ValletId: a, OperationType: "DEPOSIT", Amount: 1, ExecuteAt: now})
+		assert.ErrorIs(t, err, ErrInvalidScheduled)
+		_, err = s.Schedule(ctx, wallet.ScheduledTransaction{ValletId: missing, OperationType: "DEPOSIT", Amount: 1, ExecuteAt: now.Add(time.Hour)})
+		assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+		_, err = s.ListScheduled(ctx, a, "done", 10)
+		assert.ErrorIs(t, err, ErrInvalidScheduled)
+	})
+
+	withdraw := schedule("WITHDRAW", 15, now.Add(time.Minute))
+	deposit := schedule("DEPOSIT", 10, now.Add(2*time.Minute))
+	tooMuch := schedule("WITHDRAW", 100, now.Add(3*time.Minute))
+	cancelled := schedule("DEPOSIT", 1, now.Add(3*time.Minute))
+	later := schedule("DEPOSIT", 1, now.Add(time.Hour))
+
+	t.Run("cancel", func(t *testing.T) {
+		st, err := s.Cancel(ctx, a, cancelled.Id)
+		require.NoError(t, err)
+		assert.Equal(t, wallet.ScheduledCancelled, st.Status)
+
+		_, err = s.Cancel(ctx, a, cancelled.Id)
+		assert.ErrorIs(t, err, repository.ErrScheduledNotPending)
+	})
+
+	t.Run("nothing is due yet", func(t *testing.T) {
+		n, err := s.ExecuteDue(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, n)
+	})
+
+	t.Run("due operations are executed in order", func(t *testing.T) {
+		now = now.Add(5 * time.Minute)
+		n, err := s.ExecuteDue(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 3, n)
+
+		balance, err := mem.GetBalance(ctx, a)
+		require.NoError(t, err)
+		assert.Equal(t, 20.0, balance, "withdrawal of 15 before deposit of 10 fails, deposit succeeds")
+
+		list, err := s.ListScheduled(ctx, a, "", 10)
+		require.NoError(t, err)
+		require.Len(t, list, 5)
+
+		byID := make(map[int64]wallet.ScheduledTransaction)
+		for _, st := range list {
+			byID[st.Id] = st
+		}
+		assert.Equal(t, wallet.ScheduledFailed, byID[withdraw.Id].Status)
+		assert.Contains(t, byID[withdraw.Id].Error, "insufficient funds")
+		assert.Equal(t, wallet.ScheduledSucceeded, byID[deposit.Id].Status)
+		require.NotNil(t, byID[deposit.Id].TransactionId)
+		require.NotNil(t, byID[deposit.Id].FinishedAt)
+		assert.Equal(t, now, *byID[deposit.Id].FinishedAt)
+		assert.Equal(t, wallet.ScheduledFailed, byID[tooMuch.Id].Status)
+		assert.Equal(t, wallet.ScheduledCancelled, byID[cancelled.Id].Status)
+		assert.Equal(t, wallet.ScheduledPending, byID[later.Id].Status)
+
+		history, err := mem.ListTransactions(ctx, a, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		assert.Equal(t, *byID[deposit.Id].TransactionId, history[0].Id)
+		assert.Equal(t, "scheduled:"+strconv.FormatInt(deposit.Id, 10), history[0].IdempotencyKey)
+
+		n, err = s.ExecuteDue(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, n, "finished operations are not executed again")
+	})
+
+	t.Run("replay after a crash does not apply twice", func(t *testing.T) {
+		now = now.Add(time.Hour)
+		// Обработчик применил операцию, но упал до записи результата: после аренды она выполняется повторно.
+		_, err := scheduled.ClaimScheduled(ctx, now, time.Minute, 10)
+		require.NoError(t, err)
+		_, err = s.wallets.UpdateBalance(ctx, wallet.WalletTransactions{ValletId: a, OperationType: "DEPOSIT", Amount: 1,
+			IdempotencyKey: "scheduled:" + strconv.FormatInt(later.Id, 10)})
+		require.NoError(t, err)
+
+		now = now.Add(2 * time.Minute)
+		n, err := s.ExecuteDue(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		balance, err := mem.GetBalance(ctx, a)
+		require.NoError(t, err)
+		assert.Equal(t, 21.0, balance)
+
+		list, err := s.ListScheduled(ctx, a, wallet.ScheduledPending, 10)
+		require.NoError(t, err)
+		assert.Empty(t, list)
+	})
+}
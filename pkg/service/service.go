@@ -2,25 +2,185 @@ package service
 
 import (
 	"context"
+	"crypto/ed25519"
+	"errors"
+	"io"
+	"time"
 
 	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/export"
+	"github.com/KatenkaKet/wallet/pkg/fee"
+	"github.com/KatenkaKet/wallet/pkg/interest"
+	"github.com/KatenkaKet/wallet/pkg/receipt"
 	"github.com/KatenkaKet/wallet/pkg/repository"
+	"github.com/KatenkaKet/wallet/pkg/storage"
 	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
 )
 
 //go:generate mockgen -source=service.go -destination=mocks/mock.go
 
+var (
+	ErrInvalidSubscription = errors.New("invalid webhook subscription")
+	ErrInvalidTransfer     = errors.New("invalid transfer")
+	// ErrBatchAborted - пакет "всё или ничего" не применён, причины - в результатах операций.
+	ErrBatchAborted       = errors.New("batch aborted")
+	ErrInvalidStats       = errors.New("invalid stats request")
+	ErrInvalidScheduled   = errors.New("invalid scheduled transaction")
+	ErrInvalidRecurring   = errors.New("invalid recurring transfer")
+	ErrInvalidTier        = errors.New("invalid wallet tier")
+	ErrInvalidInterest    = errors.New("invalid interest request")
+	ErrInvalidCreditLimit = errors.New("invalid credit limit")
+	// ErrClosed - сервис остановлен через Close и операцию уже не примет.
+	ErrClosed = errors.New("wallet service is closed")
+)
+
 type Wallet interface {
 	GetBalance(ctx context.Context, walletID uuid.UUID) (float64, error)
-	UpdateBalance(ctx context.Context, WT wallet.WalletTransactions) error
+	GetWallet(ctx context.Context, walletID uuid.UUID) (wallet.Wallet, error)
+	SetTier(ctx context.Context, walletID uuid.UUID, tier string) (wallet.Wallet, error)
+	SetCreditLimit(ctx context.Context, walletID uuid.UUID, limit float64) (wallet.Wallet, error)
+	UpdateBalance(ctx context.Context, WT wallet.WalletTransactions) (wallet.WalletTransactions, error)
+	ListTransactions(ctx context.Context, walletID uuid.UUID, afterSeq int64, limit int) ([]wallet.WalletTransactions, error)
+	ListBucketTransactions(ctx context.Context, walletID uuid.UUID, bucket string, afterSeq int64, limit int) ([]wallet.WalletTransactions, error)
+	Transfer(ctx context.Context, from, to uuid.UUID, amount float64) (wallet.Transfer, error)
+	ApplyBatch(ctx context.Context, ops []wallet.WalletTransactions, atomic bool) ([]wallet.BatchResult, error)
+	ExportTransactions(ctx context.Context, walletID uuid.UUID, from, to time.Time, w export.Writer) error
+	// Close останавливает фоновую запись пачками горячих кошельков.
+	Close()
+}
+
+type Import interface {
+	Validate(ctx context.Context, rows []wallet.ImportRow) (wallet.ImportReport, error)
+	Apply(ctx context.Context, rows []wallet.ImportRow) (wallet.ImportReport, error)
+}
+
+type Audit interface {
+	VerifyChain(ctx context.Context, walletID uuid.UUID) (wallet.ChainReport, error)
+	CreateCheckpoints(ctx context.Context) (int, error)
+}
+
+type Statement interface {
+	GenerateMonthly(ctx context.Context, now time.Time) (int, error)
+	ListStatements(ctx context.Context, walletID uuid.UUID) ([]wallet.Statement, error)
+	OpenStatement(ctx context.Context, walletID uuid.UUID, from time.Time, format string) (io.ReadCloser, error)
+}
+
+type Stats interface {
+	WalletStats(ctx context.Context, walletID uuid.UUID, from, to time.Time, interval string) (wallet.Stats, error)
+	GlobalStats(ctx context.Context, from, to time.Time, interval string) (wallet.Stats, error)
+}
+
+type Scheduled interface {
+	Schedule(ctx context.Context, st wallet.ScheduledTransaction) (wallet.ScheduledTransaction, error)
+	ListScheduled(ctx context.Context, walletID uuid.UUID, status string, limit int) ([]wallet.ScheduledTransaction, error)
+	Cancel(ctx context.Context, walletID uuid.UUID, id int64) (wallet.ScheduledTransaction, error)
+	ExecuteDue(ctx context.Context) (int, error)
+}
+
+type Recurring interface {
+	CreateRecurring(ctx context.Context, r wallet.RecurringTransfer) (wallet.RecurringTransfer, error)
+	GetRecurring(ctx context.Context, walletID uuid.UUID, id int64) (wallet.RecurringTransfer, error)
+	ListRecurring(ctx context.Context, walletID uuid.UUID, limit int) ([]wallet.RecurringTransfer, error)
+	ListRecurringRuns(ctx context.Context, walletID uuid.UUID, id int64, afterID int64, limit int) ([]wallet.RecurringRun, error)
+	PauseRecurring(ctx context.Context, walletID uuid.UUID, id int64) (wallet.RecurringTransfer, error)
+	ResumeRecurring(ctx context.Context, walletID uuid.UUID, id int64) (wallet.RecurringTransfer, error)
+	CancelRecurring(ctx context.Context, walletID uuid.UUID, id int64) (wallet.RecurringTransfer, error)
+	ExecuteDueRecurring(ctx context.Context) (int, error)
+}
+
+type Interest interface {
+	SetProduct(ctx context.Context, walletID uuid.UUID, product string) (wallet.Wallet, error)
+	ListAccruals(ctx context.Context, walletID uuid.UUID, from, to time.Time) ([]wallet.InterestAccrual, error)
+	RunInterest(ctx context.Context, now time.Time) (int, error)
+}
+
+type Receipt interface {
+	Issue(WT wallet.WalletTransactions) (receipt.Receipt, bool)
+	PublicKeys() []receipt.PublicKey
+}
+
+type Webhook interface {
+	Subscribe(ctx context.Context, sub wallet.WebhookSubscription) (wallet.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]wallet.WebhookSubscription, error)
+	Unsubscribe(ctx context.Context, id int64) error
+	ListDeadDeliveries(ctx context.Context, limit int) ([]wallet.WebhookDelivery, error)
+	ReplayDelivery(ctx context.Context, id int64) error
+	DispatchDue(ctx context.Context) (int, error)
+}
+
+type Outbox interface {
+	Relay(ctx context.Context) (int, error)
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+type Stream interface {
+	Subscribe(ctx context.Context, walletID uuid.UUID, afterSeq int64) (<-chan wallet.BalanceUpdate, error)
+	Run(ctx context.Context) error
 }
 
 type Service struct {
 	Wallet
+	Import
+	Audit
+	Statement
+	Stats
+	Scheduled
+	Recurring
+	Interest
+	Receipt
+	Webhook
+	Outbox
+	Stream
+}
+
+type Config struct {
+	// HotWallets - кошельки с высокой нагрузкой на запись, операции которых применяются пачками.
+	HotWallets []uuid.UUID
+	// HotWalletMaxBatch - максимальное число операций в одной транзакции БД для горячего кошелька.
+	HotWalletMaxBatch int
+	// CheckpointKey - ключ HMAC для подписи контрольных точек цепочки хешей; пустой - точки не создаются.
+	CheckpointKey []byte
+	// ReceiptKeyID и ReceiptKey - ключ Ed25519 для подписи квитанций; без ключа квитанции не выдаются.
+	ReceiptKeyID string
+	ReceiptKey   ed25519.PrivateKey
+	// ReceiptRetiredKeys - публичные ключи из прошлых ротаций, которые продолжают публиковаться.
+	ReceiptRetiredKeys []receipt.PublicKey
+	Webhooks           WebhookConfig
+	// EventPublishers - получатели событий из outbox в дополнение к вебхукам.
+	EventPublishers []EventPublisher
+	// OutboxBatchSize - сколько событий outbox публикуется за один проход relay.
+	OutboxBatchSize int
+	// StatementStorage - хранилище файлов месячных выписок; без него выписки не формируются.
+	StatementStorage storage.Storage
+	// Currency - код валюты ISO 4217 в выписках.
+	Currency string
+	// Fees - расписание комиссий за снятие и переводы; nil - комиссии не берутся.
+	Fees *fee.Schedule
+	// FeeWallet - системный кошелёк, на который зачисляются комиссии.
+	FeeWallet uuid.UUID
+	// InterestProducts - процентные продукты кошельков; nil - проценты не начисляются.
+	InterestProducts *interest.Products
 }
 
-func NewService(repo *repository.Repository) *Service {
+func NewService(repo *repository.Repository, cfg Config) *Service {
+	webhooks := NewWebhookService(repo.Webhook, repo.Wallet, cfg.Webhooks)
+
+	publishers := append([]EventPublisher{webhooks}, cfg.EventPublishers...)
+
+	wallets := NewWalletService(repo.Wallet, cfg)
+
 	return &Service{
-		Wallet: NewWalletService(repo.Wallet),
+		Wallet:    wallets,
+		Import:    NewImportService(wallets, repo.Wallet),
+		Audit:     NewAuditService(repo.Wallet, repo.Checkpoint, cfg.CheckpointKey),
+		Statement: NewStatementService(wallets, repo.Wallet, repo.Statement, cfg.StatementStorage, cfg.Currency),
+		Stats:     NewStatsService(repo.Wallet, repo.Stats),
+		Scheduled: NewScheduledService(wallets, repo.Wallet, repo.Scheduled),
+		Recurring: NewRecurringService(wallets, repo.Wallet, repo.Recurring),
+		Interest:  NewInterestService(repo.Interest, cfg.InterestProducts),
+		Receipt:   NewReceiptService(cfg.ReceiptKeyID, cfg.ReceiptKey, cfg.ReceiptRetiredKeys),
+		Webhook:   webhooks,
+		Outbox:    NewOutboxService(repo.Outbox, publishers, cfg.OutboxBatchSize),
+		Stream:    NewStreamService(repo.Wallet, repo.Changes),
 	}
 }
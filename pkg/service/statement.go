@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/export"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	"github.com/KatenkaKet/wallet/pkg/storage"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// statementPage - сколько кошельков выбирается за раз при генерации выписок.
+const statementPage = 100
+
+// statementFormats - форматы файлов, в которых сохраняется каждая выписка.
+var statementFormats = []string{export.FormatHTML, export.FormatCSV}
+
+// StatementService формирует месячные выписки кошельков и отдаёт сохранённые файлы.
+// Файлы пишутся в storage, сведения о выписке (балансы и итоги) - в repository.Statement.
+type StatementService struct {
+	wallets    Wallet
+	repo       repository.Wallet
+	statements repository.Statement
+	storage    storage.Storage
+	currency   string
+}
+
+func NewStatementService(wallets Wallet, repo repository.Wallet, statements repository.Statement, store storage.Storage, currency string) *StatementService {
+	return &StatementService{wallets: wallets, repo: repo, statements: statements, storage: store, currency: currency}
+}
+
+// GenerateMonthly формирует выписки за предыдущий (относительно now) календарный месяц в UTC для всех кошельков,
+// у которых были транзакции в этом месяце и ещё нет выписки. Ошибка по одному кошельку пишется в лог
+// и не останавливает остальные: такой кошелёк останется в очереди до следующего запуска.
+// Возвращает число сформированных выписок.
+func (s *StatementService) GenerateMonthly(ctx context.Context, now time.Time) (int, error) {
+	if s.storage == nil {
+		return 0, fmt.Errorf("statement storage is not configured")
+	}
+
+	now = now.UTC()
+	to := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, -1, 0)
+
+	var after uuid.UUID
+	if err := after.Scan("00000000-0000-0000-0000-000000000000"); err != nil {
+		return 0, err
+	}
+
+	generated := 0
+	for {
+		ids, err := s.statements.PendingStatements(ctx, from, to, after, statementPage)
+		if err != nil {
+			return generated, err
+		}
+
+		for _, id := range ids {
+			if _, err := s.Generate(ctx, id, from); err != nil {
+				if ctx.Err() != nil {
+					return generated, ctx.Err()
+				}
+				log.Printf("statement %s for wallet %s: %s", from.Format("2006-01"), id.UUID.String(), err.Error())
+				continue
+			}
+			generated++
+		}
+
+		if len(ids) < statementPage {
+			return generated, nil
+		}
+		after = ids[len(ids)-1]
+	}
+}
+
+// Generate формирует выписку кошелька за месяц, начинающийся с from: сохраняет файлы всех форматов,
+// а затем запись о выписке. Если запись не удалась, файлы перезапишутся при следующей попытке.
+func (s *StatementService) Generate(ctx context.Context, walletID uuid.UUID, from time.Time) (wallet.Statement, error) {
+	if s.storage == nil {
+		return wallet.Statement{}, fmt.Errorf("statement storage is not configured")
+	}
+
+	from = from.UTC()
+	to := from.AddDate(0, 1, 0)
+	period := from.Format("2006-01")
+
+	objects := make([]storage.Object, 0, len(statementFormats))
+	abort := func() {
+		for _, obj := range objects {
+			obj.Abort()
+		}
+	}
+
+	totals := &export.Totals{}
+	writers := []export.Writer{totals}
+	for _, format := range statementFormats {
+		obj, err := s.storage.Create(ctx, statementKey(walletID, period, format))
+		if err != nil {
+			abort()
+			return wallet.Statement{}, err
+		}
+		objects = append(objects, obj)
+
+		w, err := export.NewWriter(format, obj, s.currency)
+		if err != nil {
+			abort()
+			return wallet.Statement{}, err
+		}
+		writers = append(writers, w)
+	}
+
+	if err := s.wallets.ExportTransactions(ctx, walletID, from, to, export.MultiWriter(writers...)); err != nil {
+		abort()
+		return wallet.Statement{}, err
+	}
+
+	for i, obj := range objects {
+		if err := obj.Close(); err != nil {
+			for _, rest := range objects[i+1:] {
+				rest.Abort()
+			}
+			return wallet.Statement{}, err
+		}
+	}
+
+	st := wallet.Statement{
+		ValletId:       walletID,
+		From:           from,
+		OpeningBalance: totals.OpeningBalance,
+		ClosingBalance: totals.ClosingBalance,
+		Deposits:       totals.Deposits,
+		Withdrawals:    totals.Withdrawals,
+		Formats:        statementFormats,
+	}
+	if err := s.statements.SaveStatement(ctx, st); err != nil {
+		return wallet.Statement{}, err
+	}
+
+	st.Period, st.To = period, to
+	return st, nil
+}
+
+// ListStatements возвращает выписки кошелька, последние первыми.
+func (s *StatementService) ListStatements(ctx context.Context, walletID uuid.UUID) ([]wallet.Statement, error) {
+	if _, err := s.repo.GetWallet(ctx, walletID); err != nil {
+		return nil, err
+	}
+	return s.statements.ListStatements(ctx, walletID)
+}
+
+// OpenStatement открывает файл выписки кошелька за месяц, начинающийся с from, в формате format.
+// Если выписки или файла в этом формате нет, возвращается repository.ErrStatementNotFound.
+func (s *StatementService) OpenStatement(ctx context.Context, walletID uuid.UUID, from time.Time, format string) (io.ReadCloser, error) {
+	if s.storage == nil {
+		return nil, fmt.Errorf("statement storage is not configured")
+	}
+
+	st, err := s.statements.GetStatement(ctx, walletID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	for _, f := range st.Formats {
+		found = found || f == format
+	}
+	if !found {
+		return nil, fmt.Errorf("%s statement %s for wallet %s: %w", format, st.Period, walletID.UUID.String(), repository.ErrStatementNotFound)
+	}
+
+	r, err := s.storage.Open(ctx, statementKey(walletID, st.Period, format))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("%s: %w", err.Error(), repository.ErrStatementNotFound)
+	}
+	return r, err
+}
+
+func statementKey(walletID uuid.UUID, period, format string) string {
+	return "statements/" + walletID.UUID.String() + "/" + period + "." + format
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	"github.com/KatenkaKet/wallet/pkg/storage"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyStorage отказывает в создании объектов, пока failing = true.
+type flakyStorage struct {
+	storage.Storage
+	failing bool
+}
+
+func (s *flakyStorage) Create(ctx context.Context, key string) (storage.Object, error) {
+	if s.failing {
+		return nil, errors.New("disk is full")
+	}
+	return s.Storage.Create(ctx, key)
+}
+
+func TestStatementService(t *testing.T) {
+	ctx := context.Background()
+
+	var a, idle, missing uuid.UUID
+	require.NoError(t, a.Scan("11111111-1111-1111-1111-111111111111"))
+	require.NoError(t, idle.Scan("22222222-2222-2222-2222-222222222222"))
+	require.NoError(t, missing.Scan("99999999-9999-9999-9999-999999999999"))
+
+	mem := repository.NewWalletMemory()
+	mem.AddWallet(a, 100)
+	mem.AddWallet(idle, 5)
+	wallets := NewWalletService(mem, Config{})
+
+	for _, WT := range []wallet.WalletTransactions{
+		{ValletId: a, OperationType: "DEPOSIT", Amount: 10},
+		{ValletId: a, OperationType: "WITHDRAW", Amount: 5},
+		{ValletId: a, OperationType: "DEPOSIT", Amount: 1.5},
+	} {
+		_, err := wallets.UpdateBalance(ctx, WT)
+		require.NoError(t, err)
+	}
+
+	disk, err := storage.NewDisk(t.TempDir())
+	require.NoError(t, err)
+	store := &flakyStorage{Storage: disk, failing: true}
+	s := NewStatementService(wallets, mem, mem, store, "EUR")
+
+	// Выписки за текущий месяц формируются в начале следующего.
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	runAt := from.AddDate(0, 1, 0).Add(time.Hour)
+
+	t.Run("failed wallet is retried on the next run", func(t *testing.T) {
+		generated, err := s.GenerateMonthly(ctx, runAt)
+		require.NoError(t, err)
+		assert.Equal(t, 0, generated)
+
+		store.failing = false
+		generated, err = s.GenerateMonthly(ctx, runAt)
+		require.NoError(t, err)
+		assert.Equal(t, 1, generated, "only wallets with transactions in the period get a statement")
+
+		generated, err = s.GenerateMonthly(ctx, runAt)
+		require.NoError(t, err)
+		assert.Equal(t, 0, generated, "statement is generated once")
+	})
+
+	t.Run("list", func(t *testing.T) {
+		statements, err := s.ListStatements(ctx, a)
+		require.NoError(t, err)
+		require.Len(t, statements, 1)
+
+		st := statements[0]
+		assert.Equal(t, from.Format("2006-01"), st.Period)
+		assert.Equal(t, 100.0, st.OpeningBalance)
+		assert.Equal(t, 106.5, st.ClosingBalance)
+		assert.Equal(t, wallet.StatementTotal{Count: 2, Sum: 11.5}, st.Deposits)
+		assert.Equal(t, wallet.StatementTotal{Count: 1, Sum: 5}, st.Withdrawals)
+		assert.Equal(t, []string{"html", "csv"}, st.Formats)
+
+		statements, err = s.ListStatements(ctx, idle)
+		require.NoError(t, err)
+		assert.Empty(t, statements)
+
+		_, err = s.ListStatements(ctx, missing)
+		assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+	})
+
+	t.Run("open", func(t *testing.T) {
+		r, err := s.OpenStatement(ctx, a, from, "csv")
+		require.NoError(t, err)
+		body, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+
+		lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+		require.Len(t, lines, 6)
+		assert.True(t, strings.HasPrefix(lines[1], "opening_balance,"+from.Format(time.RFC3339)))
+		assert.True(t, strings.HasSuffix(lines[5], ",106.50"))
+
+		r, err = s.OpenStatement(ctx, a, from, "html")
+		require.NoError(t, err)
+		body, err = io.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+		assert.Contains(t, string(body), "Closing balance: 106.50")
+		assert.Contains(t, string(body), "Currency: EUR")
+
+		_, err = s.OpenStatement(ctx, a, from, "ofx")
+		assert.ErrorIs(t, err, repository.ErrStatementNotFound)
+
+		_, err = s.OpenStatement(ctx, a, from.AddDate(0, -1, 0), "csv")
+		assert.ErrorIs(t, err, repository.ErrStatementNotFound)
+	})
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+// maxStatsBuckets - наибольшее число интервалов в одном ответе статистики.
+const maxStatsBuckets = 1000
+
+// StatsService - статистика операций для графиков: итоги по дням, неделям или месяцам.
+type StatsService struct {
+	repo  repository.Wallet
+	stats repository.Stats
+	now   func() time.Time
+}
+
+func NewStatsService(repo repository.Wallet, stats repository.Stats) *StatsService {
+	return &StatsService{repo: repo, stats: stats, now: time.Now}
+}
+
+// WalletStats возвращает статистику операций кошелька. Пустой interval - по дням;
+// без to - по конец текущего дня (UTC), без from - последние 30 дней, 12 недель или 12 месяцев до to.
+func (s *StatsService) WalletStats(ctx context.Context, walletID uuid.UUID, from, to time.Time, interval string) (wallet.Stats, error) {
+	if _, err := s.repo.GetWallet(ctx, walletID); err != nil {
+		return wallet.Stats{}, err
+	}
+	return s.collect(ctx, walletID, from, to, interval)
+}
+
+// GlobalStats возвращает статистику операций по всем кошелькам; параметры - как у WalletStats.
+func (s *StatsService) GlobalStats(ctx context.Context, from, to time.Time, interval string) (wallet.Stats, error) {
+	return s.collect(ctx, uuid.UUID{}, from, to, interval)
+}
+
+func (s *StatsService) collect(ctx context.Context, walletID uuid.UUID, from, to time.Time, interval string) (wallet.Stats, error) {
+	if interval == "" {
+		interval = wallet.StatsDay
+	}
+	if !wallet.ValidStatsInterval(interval) {
+		return wallet.Stats{}, fmt.Errorf("%w: interval must be day, week or month", ErrInvalidStats)
+	}
+
+	if to.IsZero() {
+		to = wallet.StatsBucketStart(s.now(), wallet.StatsDay).AddDate(0, 0, 1)
+	}
+	if from.IsZero() {
+		switch interval {
+		case wallet.StatsDay:
+			from = to.AddDate(0, 0, -30)
+		case wallet.StatsWeek:
+			from = wallet.StatsBucketStart(to.AddDate(0, 0, -7*12), wallet.StatsWeek)
+		case wallet.StatsMonth:
+			from = wallet.StatsBucketStart(to.AddDate(0, -12, 0), wallet.StatsMonth)
+		}
+	}
+	from, to = from.UTC(), to.UTC()
+	if !from.Before(to) {
+		return wallet.Stats{}, fmt.Errorf("%w: from must be before to", ErrInvalidStats)
+	}
+
+	// Интервалы без операций тоже попадают в ответ, чтобы на графике не было разрывов.
+	starts := make([]time.Time, 0)
+	for start := wallet.StatsBucketStart(from, interval); start.Before(to); start = wallet.NextStatsBucket(start, interval) {
+		if len(starts) == maxStatsBuckets {
+			return wallet.Stats{}, fmt.Errorf("%w: period is longer than %d intervals", ErrInvalidStats, maxStatsBuckets)
+		}
+		starts = append(starts, start)
+	}
+
+	found, err := s.stats.TransactionStats(ctx, walletID, from, to, interval)
+	if err != nil {
+		return wallet.Stats{}, err
+	}
+
+	stats := wallet.Stats{From: from, To: to, Interval: interval, Buckets: make([]wallet.StatsBucket, len(starts))}
+	j := 0
+	for i, start := range starts {
+		stats.Buckets[i].Start = start
+		for ; j < len(found) && !found[j].Start.After(start); j++ {
+			if found[j].Start.Equal(start) {
+				stats.Buckets[i].Deposits = roundTotal(found[j].Deposits)
+				stats.Buckets[i].Withdrawals = roundTotal(found[j].Withdrawals)
+			}
+		}
+		stats.Deposits.Count += stats.Buckets[i].Deposits.Count
+		stats.Deposits.Sum += stats.Buckets[i].Deposits.Sum
+		stats.Withdrawals.Count += stats.Buckets[i].Withdrawals.Count
+		stats.Withdrawals.Sum += stats.Buckets[i].Withdrawals.Sum
+	}
+	stats.Deposits = roundTotal(stats.Deposits)
+	stats.Withdrawals = roundTotal(stats.Withdrawals)
+	return stats, nil
+}
+
+// roundTotal округляет сумму до копеек, убирая погрешность сложения float64.
+func roundTotal(t wallet.StatementTotal) wallet.StatementTotal {
+	t.Sum = math.Round(t.Sum*100) / 100
+	return t
+}
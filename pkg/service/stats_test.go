@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsService(t *testing.T) {
+	ctx := context.Background()
+
+	var a, b, missing uuid.UUID
+	require.NoError(t, a.Scan("11111111-1111-1111-1111-111111111111"))
+	require.NoError(t, b.Scan("22222222-2222-2222-2222-222222222222"))
+	require.NoError(t, missing.Scan("99999999-9999-9999-9999-999999999999"))
+
+	mem := repository.NewWalletMemory()
+	mem.AddWallet(a, 100)
+	mem.AddWallet(b, 100)
+	wallets := NewWalletService(mem, Config{})
+
+	for _, WT := range []wallet.WalletTransactions{
+		{ValletId: a, OperationType: "DEPOSIT", Amount: 0.1},
+		{ValletId: a, OperationType: "DEPOSIT", Amount: 0.2},
+		{ValletId: a, OperationType: "WITHDRAW", Amount: 5},
+		{ValletId: b, OperationType: "DEPOSIT", Amount: 3},
+	} {
+		_, err := wallets.UpdateBalance(ctx, WT)
+		require.NoError(t, err)
+	}
+
+	s := NewStatsService(mem, mem)
+	now := time.Now().UTC()
+	today := wallet.StatsBucketStart(now, wallet.StatsDay)
+
+	t.Run("defaults to the last 30 days", func(t *testing.T) {
+		stats, err := s.WalletStats(ctx, a, time.Time{}, time.Time{}, "")
+		require.NoError(t, err)
+
+		assert.Equal(t, wallet.StatsDay, stats.Interval)
+		assert.Equal(t, today.AddDate(0, 0, -29), stats.From)
+		assert.Equal(t, today.AddDate(0, 0, 1), stats.To)
+		require.Len(t, stats.Buckets, 30)
+		assert.Equal(t, stats.From, stats.Buckets[0].Start)
+
+		last := stats.Buckets[29]
+		assert.Equal(t, today, last.Start)
+		assert.Equal(t, wallet.StatementTotal{Count: 2, Sum: 0.3}, last.Deposits)
+		assert.Equal(t, wallet.StatementTotal{Count: 1, Sum: 5}, last.Withdrawals)
+		assert.Equal(t, wallet.StatsBucket{Start: today.AddDate(0, 0, -1)}, stats.Buckets[28])
+
+		assert.Equal(t, wallet.StatementTotal{Count: 2, Sum: 0.3}, stats.Deposits)
+		assert.Equal(t, wallet.StatementTotal{Count: 1, Sum: 5}, stats.Withdrawals)
+	})
+
+	t.Run("global by month", func(t *testing.T) {
+		stats, err := s.GlobalStats(ctx, time.Time{}, time.Time{}, wallet.StatsMonth)
+		require.NoError(t, err)
+
+		require.Len(t, stats.Buckets, 13)
+		assert.Equal(t, wallet.StatsBucketStart(now, wallet.StatsMonth), stats.Buckets[12].Start)
+		assert.Equal(t, wallet.StatementTotal{Count: 3, Sum: 3.3}, stats.Deposits)
+	})
+
+	t.Run("weeks start on monday", func(t *testing.T) {
+		stats, err := s.GlobalStats(ctx, today.AddDate(0, 0, -14), today.AddDate(0, 0, 1), wallet.StatsWeek)
+		require.NoError(t, err)
+
+		for _, bucket := range stats.Buckets {
+			assert.Equal(t, time.Monday, bucket.Start.Weekday())
+		}
+		assert.Equal(t, wallet.StatementTotal{Count: 1, Sum: 5}, stats.Buckets[len(stats.Buckets)-1].Withdrawals)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		_, err := s.GlobalStats(ctx, time.Time{}, time.Time{}, "hour")
+		assert.ErrorIs(t, err, ErrInvalidStats)
+
+		_, err = s.GlobalStats(ctx, today, today, wallet.StatsDay)
+		assert.ErrorIs(t, err, ErrInvalidStats)
+
+		_, err = s.GlobalStats(ctx, today.AddDate(-5, 0, 0), today, wallet.StatsDay)
+		assert.ErrorIs(t, err, ErrInvalidStats, "too many buckets")
+
+		_, err = s.WalletStats(ctx, missing, time.Time{}, time.Time{}, "")
+		assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+	})
+}
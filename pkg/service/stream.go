@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	"github.com/jackc/pgtype"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+)
+
+const (
+	streamPageSize = 100
+	// streamResync - как часто поток перечитывает историю без уведомления, на случай потерянного NOTIFY.
+	streamResync = 30 * time.Second
+)
+
+// StreamService раздаёт изменения кошельков подписчикам потоков (SSE, WebSocket).
+// Уведомления об изменениях (repository.Changes) только будят подписчиков, а сами транзакции
+// читаются из истории по seq, поэтому поток не теряет и не переставляет операции и может быть возобновлён.
+type StreamService struct {
+	wallets repository.Wallet
+	changes repository.Changes
+
+	mu   sync.Mutex
+	subs map[string]map[chan struct{}]struct{}
+}
+
+func NewStreamService(wallets repository.Wallet, changes repository.Changes) *StreamService {
+	return &StreamService{
+		wallets: wallets,
+		changes: changes,
+		subs:    make(map[string]map[chan struct{}]struct{}),
+	}
+}
+
+// Run слушает уведомления об изменениях кошельков, пока не отменён ctx.
+func (s *StreamService) Run(ctx context.Context) error {
+	return s.changes.ListenChanges(ctx, s.wake)
+}
+
+// Subscribe возвращает канал обновлений кошелька. При afterSeq < 0 поток начинается со снимка текущего баланса,
+// иначе - с транзакций после afterSeq. Канал закрывается, когда отменён ctx или история не читается.
+func (s *StreamService) Subscribe(ctx context.Context, walletID uuid.UUID, afterSeq int64) (<-chan wallet.BalanceUpdate, error) {
+	// Подписка регистрируется до чтения кошелька, чтобы не пропустить изменение между ними.
+	wake := s.register(walletID)
+
+	wlt, err := s.wallets.GetWallet(ctx, walletID)
+	if err != nil {
+		s.unregister(walletID, wake)
+		return nil, err
+	}
+
+	updates := make(chan wallet.BalanceUpdate)
+	go func() {
+		defer close(updates)
+		defer s.unregister(walletID, wake)
+
+		if afterSeq < 0 {
+			snapshot := wallet.BalanceUpdate{Type: wallet.UpdateSnapshot, ValletId: walletID, Seq: wlt.LastSeq, Balance: wlt.Balance}
+			if !send(ctx, updates, snapshot) {
+				return
+			}
+			afterSeq = wlt.LastSeq
+		}
+
+		resync := time.NewTicker(streamResync)
+		defer resync.Stop()
+
+		for {
+			var err error
+			if afterSeq, err = s.forward(ctx, walletID, afterSeq, updates); err != nil {
+				if ctx.Err() == nil {
+					log.Printf("wallet %s stream: %s", walletID.UUID.String(), err.Error())
+				}
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-wake:
+			case <-resync.C:
+			}
+		}
+	}()
+
+	return updates, nil
+}
+
+// forward отправляет транзакции кошелька после afterSeq и возвращает seq последней отправленной.
+func (s *StreamService) forward(ctx context.Context, walletID uuid.UUID, afterSeq int64, updates chan<- wallet.BalanceUpdate) (int64, error) {
+	for {
+		page, err := s.wallets.ListTransactions(ctx, walletID, afterSeq, streamPageSize)
+		if err != nil {
+			return afterSeq, err
+		}
+
+		for i := range page {
+			WT := page[i]
+			update := wallet.BalanceUpdate{
+				Type:        wallet.UpdateTransaction,
+				ValletId:    walletID,
+				Seq:         WT.Seq,
+				Balance:     WT.BalanceAfter,
+				Transaction: &WT,
+			}
+			if !send(ctx, updates, update) {
+				return afterSeq, ctx.Err()
+			}
+			afterSeq = WT.Seq
+		}
+
+		if len(page) < streamPageSize {
+			return afterSeq, nil
+		}
+	}
+}
+
+func send(ctx context.Context, updates chan<- wallet.BalanceUpdate, update wallet.BalanceUpdate) bool {
+	select {
+	case updates <- update:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (s *StreamService) register(walletID uuid.UUID) chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wake := make(chan struct{}, 1)
+	id := walletID.UUID.String()
+	if s.subs[id] == nil {
+		s.subs[id] = make(map[chan struct{}]struct{})
+	}
+	s.subs[id][wake] = struct{}{}
+	return wake
+}
+
+func (s *StreamService) unregister(walletID uuid.UUID, wake chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := walletID.UUID.String()
+	delete(s.subs[id], wake)
+	if len(s.subs[id]) == 0 {
+		delete(s.subs, id)
+	}
+}
+
+// wake будит подписчиков кошелька, а при пустом walletID (уведомления могли быть потеряны) - всех.
+func (s *StreamService) wake(walletID uuid.UUID, seq int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if walletID.Status != pgtype.Present {
+		for _, subs := range s.subs {
+			wakeAll(subs)
+		}
+		return
+	}
+	wakeAll(s.subs[walletID.UUID.String()])
+}
+
+func wakeAll(subs map[chan struct{}]struct{}) {
+	for wake := range subs {
+		select {
+		case wake <- struct{}{}:
+		default: // подписчик уже разбужен и перечитает историю
+		}
+	}
+}
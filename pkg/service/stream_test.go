@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamService_Subscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var walletID uuid.UUID
+	require.NoError(t, walletID.Scan("11111111-1111-1111-1111-111111111111"))
+
+	mem := repository.NewWalletMemory()
+	mem.AddWallet(walletID, 100)
+	repo := repository.NewMemoryRepository(mem)
+	wallets := NewWalletService(repo.Wallet, Config{})
+	streams := NewStreamService(repo.Wallet, repo.Changes)
+
+	go streams.Run(ctx)
+
+	apply := func(op string, amount float64) {
+		_, err := wallets.UpdateBalance(ctx, wallet.WalletTransactions{ValletId: walletID, OperationType: op, Amount: amount})
+		assert.NoError(t, err)
+	}
+	next := func(t *testing.T, updates <-chan wallet.BalanceUpdate) wallet.BalanceUpdate {
+		t.Helper()
+		select {
+		case u, ok := <-updates:
+			require.True(t, ok, "stream closed")
+			return u
+		case <-time.After(5 * time.Second):
+			t.Fatal("no update")
+			return wallet.BalanceUpdate{}
+		}
+	}
+
+	// Run начинает слушать уведомления асинхронно: операции повторяются, пока пробный поток не получит уведомление
+	// быстрее, чем через streamResync.
+	probe, err := streams.Subscribe(ctx, walletID, -1)
+	require.NoError(t, err)
+	require.Equal(t, wallet.UpdateSnapshot, next(t, probe).Type)
+	require.Eventually(t, func() bool {
+		apply("DEPOSIT", 10)
+		select {
+		case <-probe:
+			return true
+		case <-time.After(50 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, time.Millisecond)
+
+	t.Run("snapshot and live updates", func(t *testing.T) {
+		subCtx, stop := context.WithCancel(ctx)
+		updates, err := streams.Subscribe(subCtx, walletID, -1)
+		require.NoError(t, err)
+
+		snapshot := next(t, updates)
+		assert.Equal(t, wallet.UpdateSnapshot, snapshot.Type)
+		assert.NotZero(t, snapshot.Seq)
+		assert.Greater(t, snapshot.Balance, 100.0)
+		assert.Nil(t, snapshot.Transaction)
+
+		apply("WITHDRAW", 30)
+		apply("DEPOSIT", 5)
+
+		for i, expected := range []struct {
+			op      string
+			balance float64
+		}{{"WITHDRAW", snapshot.Balance - 30}, {"DEPOSIT", snapshot.Balance - 25}} {
+			u := next(t, updates)
+			assert.Equal(t, wallet.UpdateTransaction, u.Type)
+			assert.Equal(t, snapshot.Seq+int64(i)+1, u.Seq)
+			assert.Equal(t, expected.balance, u.Balance)
+			require.NotNil(t, u.Transaction)
+			assert.Equal(t, expected.op, u.Transaction.OperationType)
+			assert.Equal(t, u.Seq, u.Transaction.Seq)
+		}
+
+		stop()
+		for range updates {
+		}
+	})
+
+	t.Run("resume after seq", func(t *testing.T) {
+		wlt, err := wallets.GetWallet(ctx, walletID)
+		require.NoError(t, err)
+
+		updates, err := streams.Subscribe(ctx, walletID, wlt.LastSeq-2)
+		require.NoError(t, err)
+
+		assert.Equal(t, wlt.LastSeq-1, next(t, updates).Seq)
+		assert.Equal(t, wlt.LastSeq, next(t, updates).Seq)
+
+		apply("DEPOSIT", 1)
+		u := next(t, updates)
+		assert.Equal(t, wlt.LastSeq+1, u.Seq)
+		assert.Equal(t, wlt.Balance+1, u.Balance)
+	})
+
+	t.Run("missing wallet", func(t *testing.T) {
+		var missing uuid.UUID
+		require.NoError(t, missing.Scan("22222222-2222-2222-2222-222222222222"))
+
+		_, err := streams.Subscribe(ctx, missing, -1)
+		assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+	})
+}
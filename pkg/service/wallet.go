@@ -2,42 +2,131 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/fee"
 	"github.com/KatenkaKet/wallet/pkg/repository"
 	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
 )
 
 type WalletService struct {
 	repo repository.Wallet
+	hot  map[string]*walletBatcher
+	// fees - расписание комиссий за снятие и переводы, зачисляемых на кошелёк feeWallet; nil - без комиссий.
+	fees      *fee.Schedule
+	feeWallet uuid.UUID
 }
 
-func NewWalletService(repo repository.Wallet) *WalletService {
-	return &WalletService{repo: repo}
+func NewWalletService(repo repository.Wallet, cfg Config) *WalletService {
+	s := &WalletService{
+		repo:      repo,
+		hot:       make(map[string]*walletBatcher, len(cfg.HotWallets)),
+		fees:      cfg.Fees,
+		feeWallet: cfg.FeeWallet,
+	}
+
+	for _, id := range cfg.HotWallets {
+		s.hot[id.UUID.String()] = newWalletBatcher(repo, id, cfg.HotWalletMaxBatch)
+	}
+
+	return s
+}
+
+// Close останавливает пачки горячих кошельков, дождавшись применения текущих пачек.
+// Вызывается при остановке приложения, когда новые запросы уже не принимаются.
+func (s *WalletService) Close() {
+	for _, b := range s.hot {
+		b.Close()
+	}
 }
 
 func (s *WalletService) GetBalance(ctx context.Context, walletID uuid.UUID) (float64, error) {
 	return s.repo.GetBalance(ctx, walletID)
 }
 
-func (s *WalletService) UpdateBalance(ctx context.Context, WT wallet.WalletTransactions) error {
+func (s *WalletService) GetWallet(ctx context.Context, walletID uuid.UUID) (wallet.Wallet, error) {
+	return s.repo.GetWallet(ctx, walletID)
+}
+
+// UpdateBalance атомарно применяет операцию (проверка версии, изменение баланса и запись в историю)
+// и возвращает записанную транзакцию с новой версией кошелька.
+// Повтор операции с тем же ключом идемпотентности возвращает исходную транзакцию (без версии кошелька).
+// Событие об операции записывается в outbox в той же транзакции БД и публикуется OutboxService.
+// Комиссия за снятие по расписанию списывается вместе с операцией и видна в истории отдельной транзакцией
+// с тем же operationId.
+func (s *WalletService) UpdateBalance(ctx context.Context, WT wallet.WalletTransactions) (wallet.WalletTransactions, error) {
+	WT.Kind, WT.OperationId = "", nil
+
+	var charge float64
 	if WT.OperationType == "WITHDRAW" {
-		WT.Amount *= -1
+		var err error
+		if charge, err = s.feeFor(ctx, WT.ValletId, fee.Withdraw, WT.Amount); err != nil {
+			return wallet.WalletTransactions{}, err
+		}
 	}
 
-	err := s.repo.UpdateBalance(ctx, WT.ValletId, WT.Amount)
+	var (
+		recorded wallet.WalletTransactions
+		err      error
+	)
+	if charge > 0 {
+		recorded, err = s.applyWithFee(ctx, WT, charge)
+	} else {
+		recorded, err = s.apply(ctx, WT)
+	}
+	if errors.Is(err, repository.ErrDuplicateTransaction) {
+		return recorded, nil
+	}
 	if err != nil {
-		return err
+		return wallet.WalletTransactions{}, err
 	}
 
-	if WT.OperationType == "WITHDRAW" {
-		WT.Amount *= -1
+	return recorded, nil
+}
+
+func (s *WalletService) apply(ctx context.Context, WT wallet.WalletTransactions) (wallet.WalletTransactions, error) {
+	if b, ok := s.hot[WT.ValletId.UUID.String()]; ok {
+		return b.submit(ctx, WT)
 	}
 
-	err = s.repo.CreateTransaction(ctx, WT)
+	recorded, results, err := s.repo.ApplyTransactions(ctx, WT.ValletId, []wallet.WalletTransactions{WT})
 	if err != nil {
-		return err
+		return wallet.WalletTransactions{}, err
+	}
+	return recorded[0], results[0]
+}
+
+func (s *WalletService) ListTransactions(ctx context.Context, walletID uuid.UUID, afterSeq int64, limit int) ([]wallet.WalletTransactions, error) {
+	return s.repo.ListTransactions(ctx, walletID, afterSeq, limit)
+}
+
+// ListBucketTransactions возвращает транзакции, затронувшие корзину bucket, в порядке seq кошелька.
+// У каждой транзакции в Buckets - её доля по корзинам и остаток корзины после неё.
+func (s *WalletService) ListBucketTransactions(ctx context.Context, walletID uuid.UUID, bucket string, afterSeq int64,
+	limit int) ([]wallet.WalletTransactions, error) {
+	return s.repo.ListBucketTransactions(ctx, walletID, bucket, afterSeq, limit)
+}
+
+// Transfer атомарно переводит amount с кошелька from на кошелёк to.
+// Переводы с горячих кошельков не проходят через пачки: обе строки блокируются напрямую.
+// Комиссия за перевод по расписанию списывается с from в той же транзакции БД и возвращается в Fee.
+func (s *WalletService) Transfer(ctx context.Context, from, to uuid.UUID, amount float64) (wallet.Transfer, error) {
+	if from.UUID == to.UUID {
+		return wallet.Transfer{}, fmt.Errorf("%w: source and destination wallets must differ", ErrInvalidTransfer)
+	}
+	if amount <= 0 {
+		return wallet.Transfer{}, fmt.Errorf("%w: amount must be positive", ErrInvalidTransfer)
+	}
+
+	charge, err := s.feeFor(ctx, from, fee.Transfer, amount)
+	if err != nil {
+		return wallet.Transfer{}, err
+	}
+	if charge > 0 {
+		return s.transferWithFee(ctx, from, to, amount, charge)
 	}
 
-	return nil
+	return s.repo.Transfer(ctx, from, to, amount)
 }
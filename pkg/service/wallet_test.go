@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalletService_Transfer(t *testing.T) {
+	ctx := context.Background()
+
+	var a, b uuid.UUID
+	require.NoError(t, a.Scan("11111111-1111-1111-1111-111111111111"))
+	require.NoError(t, b.Scan("22222222-2222-2222-2222-222222222222"))
+
+	mem := repository.NewWalletMemory()
+	mem.AddWallet(a, 10)
+	mem.AddWallet(b, 0)
+	// Горячий кошелёк переводит напрямую, минуя пачки.
+	wallets := NewWalletService(mem, Config{HotWallets: []uuid.UUID{a}})
+	defer wallets.Close()
+
+	_, err := wallets.Transfer(ctx, a, a, 1)
+	assert.ErrorIs(t, err, ErrInvalidTransfer)
+	_, err = wallets.Transfer(ctx, a, b, 0)
+	assert.ErrorIs(t, err, ErrInvalidTransfer)
+	_, err = wallets.Transfer(ctx, a, b, -1)
+	assert.ErrorIs(t, err, ErrInvalidTransfer)
+
+	transfer, err := wallets.Transfer(ctx, a, b, 4)
+	require.NoError(t, err)
+	assert.Equal(t, 6.0, transfer.From.BalanceAfter)
+	assert.Equal(t, 4.0, transfer.To.BalanceAfter)
+
+	_, err = wallets.Transfer(ctx, a, b, 7)
+	assert.ErrorIs(t, err, repository.ErrInsufficientFunds)
+
+	balance, err := wallets.GetBalance(ctx, b)
+	require.NoError(t, err)
+	assert.Equal(t, 4.0, balance)
+}
+
+func TestWalletService_ApplyBatch(t *testing.T) {
+	ctx := context.Background()
+
+	var a, b, missing uuid.UUID
+	require.NoError(t, a.Scan("11111111-1111-1111-1111-111111111111"))
+	require.NoError(t, b.Scan("22222222-2222-2222-2222-222222222222"))
+	require.NoError(t, missing.Scan("99999999-9999-9999-9999-999999999999"))
+
+	mem := repository.NewWalletMemory()
+	mem.AddWallet(a, 10)
+	mem.AddWallet(b, 0)
+	wallets := NewWalletService(mem, Config{})
+
+	ops := []wallet.WalletTransactions{
+		{ValletId: b, OperationType: "DEPOSIT", Amount: 5, IdempotencyKey: "pay-1"},
+		{ValletId: a, OperationType: "WITHDRAW", Amount: 50},
+		{ValletId: missing, OperationType: "DEPOSIT", Amount: 1},
+		{ValletId: a, OperationType: "WITHDRAW", Amount: 5},
+	}
+
+	t.Run("atomic batch is aborted by a rejection", func(t *testing.T) {
+		results, err := wallets.ApplyBatch(ctx, ops, true)
+		assert.ErrorIs(t, err, ErrBatchAborted)
+
+		statuses := make([]string, len(results))
+		for i, res := range results {
+			assert.Equal(t, i, res.Index)
+			statuses[i] = res.Status
+		}
+		assert.Equal(t, []string{wallet.BatchAborted, wallet.BatchRejected, wallet.BatchRejected, wallet.BatchAborted}, statuses)
+		assert.Nil(t, results[0].Transaction)
+		assert.Contains(t, results[1].Error, "insufficient funds")
+
+		balance, err := wallets.GetBalance(ctx, b)
+		require.NoError(t, err)
+		assert.Equal(t, 0.0, balance)
+	})
+
+	t.Run("best effort applies what it can", func(t *testing.T) {
+		results, err := wallets.ApplyBatch(ctx, ops, false)
+		require.NoError(t, err)
+
+		statuses := make([]string, len(results))
+		for i, res := range results {
+			assert.Equal(t, i, res.Index)
+			statuses[i] = res.Status
+		}
+		assert.Equal(t, []string{wallet.BatchApplied, wallet.BatchRejected, wallet.BatchRejected, wallet.BatchApplied}, statuses)
+		require.NotNil(t, results[3].Transaction)
+		assert.Equal(t, 5.0, results[3].Transaction.BalanceAfter)
+	})
+
+	t.Run("atomic batch with a replayed key", func(t *testing.T) {
+		results, err := wallets.ApplyBatch(ctx, []wallet.WalletTransactions{
+			{ValletId: b, OperationType: "DEPOSIT", Amount: 5, IdempotencyKey: "pay-1"},
+			{ValletId: b, OperationType: "DEPOSIT", Amount: 1},
+		}, true)
+		require.NoError(t, err)
+		assert.Equal(t, wallet.BatchDuplicate, results[0].Status)
+		require.NotNil(t, results[0].Transaction)
+		assert.Equal(t, int64(1), results[0].Transaction.Seq)
+		assert.Equal(t, wallet.BatchApplied, results[1].Status)
+
+		balance, err := wallets.GetBalance(ctx, b)
+		require.NoError(t, err)
+		assert.Equal(t, 6.0, balance)
+	})
+
+	t.Run("single operation replay succeeds", func(t *testing.T) {
+		recorded, err := wallets.UpdateBalance(ctx, wallet.WalletTransactions{ValletId: b, OperationType: "DEPOSIT", Amount: 5, IdempotencyKey: "pay-1"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), recorded.Seq)
+	})
+}
+
+func TestWalletService_Close(t *testing.T) {
+	ctx := context.Background()
+
+	var a uuid.UUID
+	require.NoError(t, a.Scan("11111111-1111-1111-1111-111111111111"))
+
+	mem := repository.NewWalletMemory()
+	mem.AddWallet(a, 10)
+	wallets := NewWalletService(mem, Config{HotWallets: []uuid.UUID{a}})
+
+	_, err := wallets.UpdateBalance(ctx, wallet.WalletTransactions{ValletId: a, OperationType: "DEPOSIT", Amount: 1})
+	require.NoError(t, err)
+
+	wallets.Close()
+	wallets.Close()
+
+	_, err = wallets.UpdateBalance(ctx, wallet.WalletTransactions{ValletId: a, OperationType: "DEPOSIT", Amount: 1})
+	assert.ErrorIs(t, err, ErrClosed)
+
+	balance, err := wallets.GetBalance(ctx, a)
+	require.NoError(t, err)
+	assert.Equal(t, 11.0, balance)
+}
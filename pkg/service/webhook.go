@@ -0,0 +1,240 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	"github.com/KatenkaKet/wallet/pkg/webhook"
+	gofrs "github.com/gofrs/uuid"
+)
+
+// Значения по умолчанию для WebhookConfig.
+const (
+	defaultWebhookMaxAttempts = 8
+	defaultWebhookBaseDelay   = time.Second
+	defaultWebhookMaxDelay    = time.Hour
+	defaultWebhookTimeout     = 10 * time.Second
+	defaultWebhookBatchSize   = 50
+)
+
+type WebhookConfig struct {
+	// MaxAttempts - число попыток доставки, после которого она уходит в dead-letter.
+	MaxAttempts int
+	// Задержка перед n-й повторной попыткой: min(MaxDelay, BaseDelay * 2^(n-1)).
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Timeout - таймаут одного HTTP-запроса к получателю.
+	Timeout time.Duration
+	// BatchSize - сколько доставок забирается и отправляется параллельно за один проход.
+	BatchSize int
+}
+
+type WebhookService struct {
+	repo    repository.Webhook
+	wallets repository.Wallet
+	cfg     WebhookConfig
+	client  *http.Client
+	now     func() time.Time
+}
+
+func NewWebhookService(repo repository.Webhook, wallets repository.Wallet, cfg WebhookConfig) *WebhookService {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultWebhookMaxAttempts
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = defaultWebhookBaseDelay
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = defaultWebhookMaxDelay
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultWebhookTimeout
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultWebhookBatchSize
+	}
+
+	return &WebhookService{
+		repo:    repo,
+		wallets: wallets,
+		cfg:     cfg,
+		client:  &http.Client{Timeout: cfg.Timeout},
+		now:     time.Now,
+	}
+}
+
+// Subscribe создаёт подписку. Если секрет не задан, он генерируется и возвращается в ответе - единственный раз.
+func (s *WebhookService) Subscribe(ctx context.Context, sub wallet.WebhookSubscription) (wallet.WebhookSubscription, error) {
+	for _, t := range sub.EventTypes {
+		if !knownEventType(t) {
+			return sub, fmt.Errorf("%w: unknown event type %q", ErrInvalidSubscription, t)
+		}
+	}
+	if sub.EventTypes == nil {
+		sub.EventTypes = []string{}
+	}
+
+	if sub.ValletId != nil {
+		if _, err := s.wallets.GetWallet(ctx, *sub.ValletId); err != nil {
+			return sub, err
+		}
+	}
+
+	if sub.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return sub, fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+		sub.Secret = hex.EncodeToString(secret)
+	}
+
+	return s.repo.CreateSubscription(ctx, sub)
+}
+
+// ListSubscriptions возвращает подписки без секретов.
+func (s *WebhookService) ListSubscriptions(ctx context.Context) ([]wallet.WebhookSubscription, error) {
+	subs, err := s.repo.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+	return subs, nil
+}
+
+func (s *WebhookService) Unsubscribe(ctx context.Context, id int64) error {
+	return s.repo.DeleteSubscription(ctx, id)
+}
+
+func (s *WebhookService) ListDeadDeliveries(ctx context.Context, limit int) ([]wallet.WebhookDelivery, error) {
+	return s.repo.ListDeadDeliveries(ctx, limit)
+}
+
+func (s *WebhookService) ReplayDelivery(ctx context.Context, id int64) error {
+	return s.repo.ReplayDelivery(ctx, id, s.now())
+}
+
+// Publish ставит событие в очередь доставки всем подходящим подпискам (WebhookService - один из EventPublisher relay).
+// Повторная публикация того же события не создаёт дублей доставок.
+func (s *WebhookService) Publish(ctx context.Context, event wallet.WalletEvent) error {
+	if event.Id == "" {
+		id, err := gofrs.NewV4()
+		if err != nil {
+			return fmt.Errorf("failed to generate event id: %w", err)
+		}
+		event.Id = id.String()
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = s.now().UTC()
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event %s: %w", event.Id, err)
+	}
+
+	_, err = s.repo.EnqueueEvent(ctx, event, payload, s.now())
+	return err
+}
+
+// DispatchDue отправляет доставки, время которых наступило, и возвращает число обработанных.
+// Успешной считается доставка с ответом 2xx; иначе она повторяется с экспоненциальной задержкой,
+// а после MaxAttempts попыток попадает в dead-letter.
+func (s *WebhookService) DispatchDue(ctx context.Context) (int, error) {
+	// Пока доставки отправляются, другие обработчики их не возьмут; lease с запасом покрывает таймаут запроса.
+	deliveries, err := s.repo.ClaimDeliveries(ctx, s.now(), 2*s.cfg.Timeout, s.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range deliveries {
+		wg.Add(1)
+		go func(d wallet.WebhookDelivery) {
+			defer wg.Done()
+			s.deliver(ctx, d)
+		}(d)
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+func (s *WebhookService) deliver(ctx context.Context, d wallet.WebhookDelivery) {
+	attempts := d.Attempts + 1
+	sendErr := s.send(ctx, d)
+
+	// Результат записывается даже при отмене ctx, иначе доставка будет отправлена ещё раз после lease.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 2*time.Second)
+	defer cancel()
+
+	if sendErr == nil {
+		if err := s.repo.CompleteDelivery(ctx, d.Id, attempts, s.now()); err != nil {
+			log.Printf("webhook delivery %d: %s", d.Id, err.Error())
+		}
+		return
+	}
+
+	dead := attempts >= s.cfg.MaxAttempts
+	if err := s.repo.FailDelivery(ctx, d.Id, attempts, sendErr.Error(), s.now().Add(s.backoff(attempts)), dead); err != nil {
+		log.Printf("webhook delivery %d: %s", d.Id, err.Error())
+	}
+	if dead {
+		log.Printf("webhook delivery %d moved to dead-letter after %d attempts: %s", d.Id, attempts, sendErr.Error())
+	}
+}
+
+func (s *WebhookService) send(ctx context.Context, d wallet.WebhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.HeaderEvent, d.EventType)
+	req.Header.Set(webhook.HeaderDelivery, fmt.Sprint(d.Id))
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(d.Secret, s.now(), d.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// backoff - задержка перед следующей попыткой после attempts неудачных.
+func (s *WebhookService) backoff(attempts int) time.Duration {
+	if attempts > 30 {
+		return s.cfg.MaxDelay
+	}
+	if d := s.cfg.BaseDelay << (attempts - 1); d > 0 && d < s.cfg.MaxDelay {
+		return d
+	}
+	return s.cfg.MaxDelay
+}
+
+func knownEventType(t string) bool {
+	for _, known := range wallet.EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
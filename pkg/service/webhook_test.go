@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KatenkaKet/wallet"
+	"github.com/KatenkaKet/wallet/pkg/repository"
+	"github.com/KatenkaKet/wallet/pkg/webhook"
+	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiver - получатель вебхуков, проверяющий подпись каждого запроса.
+type receiver struct {
+	t      *testing.T
+	secret string
+	now    func() time.Time
+
+	mu     sync.Mutex
+	status int
+	events []wallet.WalletEvent
+}
+
+func (rv *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	require.NoError(rv.t, err)
+	assert.NoError(rv.t, webhook.Verify(rv.secret, r.Header.Get(webhook.HeaderSignature), body, rv.now(), time.Minute))
+
+	var event wallet.WalletEvent
+	require.NoError(rv.t, json.Unmarshal(body, &event))
+	assert.Equal(rv.t, event.Type, r.Header.Get(webhook.HeaderEvent))
+
+	rv.mu.Lock()
+	defer rv.mu.Unlock()
+	rv.events = append(rv.events, event)
+	w.WriteHeader(rv.status)
+}
+
+func (rv *receiver) setStatus(status int) {
+	rv.mu.Lock()
+	rv.status = status
+	rv.mu.Unlock()
+}
+
+func (rv *receiver) received() []wallet.WalletEvent {
+	rv.mu.Lock()
+	defer rv.mu.Unlock()
+	return append([]wallet.WalletEvent(nil), rv.events...)
+}
+
+type relayedWebhooks struct {
+	*WebhookService
+	outbox *OutboxService
+}
+
+func (w *relayedWebhooks) DispatchDue(ctx context.Context) (int, error) {
+	if _, err := w.outbox.Relay(ctx); err != nil {
+		return 0, err
+	}
+	return w.WebhookService.DispatchDue(ctx)
+}
+
+func TestWebhookService_Delivery(t *testing.T) {
+	ctx := context.Background()
+
+	var walletID uuid.UUID
+	require.NoError(t, walletID.Scan("11111111-1111-1111-1111-111111111111"))
+
+	clock := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	now := func() time.Time { return clock }
+
+	// Вебхуки получают события через relay outbox; relayed оборачивает WebhookService так,
+	// чтобы перед каждой отправкой outbox был разобран.
+	newServices := func(t *testing.T, cfg WebhookConfig) (*WalletService, *relayedWebhooks) {
+		mem := repository.NewWalletMemory()
+		mem.AddWallet(walletID, 100)
+		repo := repository.NewMemoryRepository(mem)
+
+		webhooks := NewWebhookService(repo.Webhook, repo.Wallet, cfg)
+		webhooks.now = now
+		outbox := NewOutboxService(repo.Outbox, []EventPublisher{webhooks}, 0)
+		return NewWalletService(repo.Wallet, Config{}), &relayedWebhooks{WebhookService: webhooks, outbox: outbox}
+	}
+
+	t.Run("signed events for matching subscriptions", func(t *testing.T) {
+		wallets, webhooks := newServices(t, WebhookConfig{})
+		rv := &receiver{t: t, now: now, status: http.StatusNoContent}
+		srv := httptest.NewServer(rv)
+		defer srv.Close()
+
+		sub, err := webhooks.Subscribe(ctx, wallet.WebhookSubscription{
+			ValletId: &walletID, URL: srv.URL, EventTypes: []string{wallet.EventDeposited, wallet.EventRejected}})
+		require.NoError(t, err)
+		require.Len(t, sub.Secret, 64)
+		rv.secret = sub.Secret
+
+		_, err = wallets.UpdateBalance(ctx, wallet.WalletTransactions{ValletId: walletID, OperationType: "DEPOSIT", Amount: 10})
+		require.NoError(t, err)
+		_, err = wallets.UpdateBalance(ctx, wallet.WalletTransactions{ValletId: walletID, OperationType: "WITHDRAW", Amount: 5})
+		require.NoError(t, err)
+		_, err = wallets.UpdateBalance(ctx, wallet.WalletTransactions{ValletId: walletID, OperationType: "WITHDRAW", Amount: 500})
+		require.ErrorIs(t, err, repository.ErrInsufficientFunds)
+
+		n, err := webhooks.DispatchDue(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		events := rv.received()
+		require.Len(t, events, 2)
+		byType := map[string]wallet.WalletEvent{}
+		for _, e := range events {
+			assert.NotEmpty(t, e.Id)
+			assert.Equal(t, walletID.UUID, e.ValletId.UUID)
+			byType[e.Type] = e
+		}
+		assert.Equal(t, int64(1), byType[wallet.EventDeposited].Transaction.Seq)
+		assert.Equal(t, 110.0, byType[wallet.EventDeposited].Transaction.BalanceAfter)
+		assert.Equal(t, 500.0, byType[wallet.EventRejected].Transaction.Amount)
+		assert.NotEmpty(t, byType[wallet.EventRejected].Reason)
+
+		// Доставленные события повторно не отправляются.
+		n, err = webhooks.DispatchDue(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, n)
+
+		subs, err := webhooks.ListSubscriptions(ctx)
+		require.NoError(t, err)
+		require.Len(t, subs, 1)
+		assert.Empty(t, subs[0].Secret)
+	})
+
+	t.Run("retries, dead-letter and replay", func(t *testing.T) {
+		wallets, webhooks := newServices(t, WebhookConfig{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Minute})
+		rv := &receiver{t: t, now: now, status: http.StatusInternalServerError}
+		srv := httptest.NewServer(rv)
+		defer srv.Close()
+
+		sub, err := webhooks.Subscribe(ctx, wallet.WebhookSubscription{URL: srv.URL, Secret: "secret"})
+		require.NoError(t, err)
+		rv.secret = sub.Secret
+
+		_, err = wallets.UpdateBalance(ctx, wallet.WalletTransactions{ValletId: walletID, OperationType: "DEPOSIT", Amount: 1})
+		require.NoError(t, err)
+
+		// Повторы идут с задержкой 1s, 2s; третья неудача отправляет доставку в dead-letter.
+		for _, wait := range []time.Duration{0, time.Second, 2 * time.Second} {
+			clock = clock.Add(wait)
+			n, err := webhooks.DispatchDue(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, 1, n)
+
+			n, err = webhooks.DispatchDue(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, 0, n, "delivery must wait for backoff")
+		}
+		assert.Len(t, rv.received(), 3)
+
+		clock = clock.Add(time.Hour)
+		n, err := webhooks.DispatchDue(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, n)
+
+		dead, err := webhooks.ListDeadDeliveries(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, dead, 1)
+		assert.Equal(t, 3, dead[0].Attempts)
+		assert.Contains(t, dead[0].LastError, "500")
+
+		rv.setStatus(http.StatusOK)
+		require.NoError(t, webhooks.ReplayDelivery(ctx, dead[0].Id))
+		assert.ErrorIs(t, webhooks.ReplayDelivery(ctx, dead[0].Id), repository.ErrDeliveryNotFound)
+
+		n, err = webhooks.DispatchDue(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Len(t, rv.received(), 4)
+
+		dead, err = webhooks.ListDeadDeliveries(ctx, 10)
+		require.NoError(t, err)
+		assert.Empty(t, dead)
+	})
+
+	t.Run("invalid subscription", func(t *testing.T) {
+		_, webhooks := newServices(t, WebhookConfig{})
+
+		_, err := webhooks.Subscribe(ctx, wallet.WebhookSubscription{URL: "http://example.com", EventTypes: []string{"wallet.closed"}})
+		assert.ErrorIs(t, err, ErrInvalidSubscription)
+
+		var missing uuid.UUID
+		require.NoError(t, missing.Scan("22222222-2222-2222-2222-222222222222"))
+		_, err = webhooks.Subscribe(ctx, wallet.WebhookSubscription{ValletId: &missing, URL: "http://example.com"})
+		assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+	})
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Disk хранит объекты файлами в каталоге root. Запись идёт во временный файл рядом с целевым,
+// который переименовывается в целевой при Close.
+type Disk struct {
+	root string
+}
+
+func NewDisk(root string) (*Disk, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage dir %s: %w", root, err)
+	}
+	return &Disk{root: root}, nil
+}
+
+// path переводит ключ в путь внутри root, отвергая ключи, которые выходят за его пределы.
+func (d *Disk) path(key string) (string, error) {
+	if !fs.ValidPath(key) || key == "." || strings.Contains(key, "\\") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return filepath.Join(d.root, filepath.FromSlash(key)), nil
+}
+
+func (d *Disk) Create(ctx context.Context, key string) (Object, error) {
+	name, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create object %s: %w", key, err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create object %s: %w", key, err)
+	}
+	return &diskObject{f: f, name: name}, nil
+}
+
+func (d *Disk) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	name, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to open object %s: %w", key, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open object %s: %w", key, err)
+	}
+	return f, nil
+}
+
+type diskObject struct {
+	f    *os.File
+	name string
+}
+
+func (o *diskObject) Write(p []byte) (int, error) {
+	return o.f.Write(p)
+}
+
+func (o *diskObject) Close() error {
+	if err := o.f.Sync(); err != nil {
+		o.Abort()
+		return err
+	}
+	if err := o.f.Close(); err != nil {
+		os.Remove(o.f.Name())
+		return err
+	}
+	if err := os.Rename(o.f.Name(), o.name); err != nil {
+		os.Remove(o.f.Name())
+		return err
+	}
+	return nil
+}
+
+func (o *diskObject) Abort() {
+	o.f.Close()
+	os.Remove(o.f.Name())
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisk(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	d, err := NewDisk(root)
+	require.NoError(t, err)
+
+	read := func(key string) string {
+		r, err := d.Open(ctx, key)
+		require.NoError(t, err)
+		defer r.Close()
+		body, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	t.Run("create and open", func(t *testing.T) {
+		obj, err := d.Create(ctx, "statements/a/2024-01.csv")
+		require.NoError(t, err)
+		_, err = io.WriteString(obj, "first")
+		require.NoError(t, err)
+
+		// До Close объект не виден.
+		_, err = d.Open(ctx, "statements/a/2024-01.csv")
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		require.NoError(t, obj.Close())
+		assert.Equal(t, "first", read("statements/a/2024-01.csv"))
+	})
+
+	t.Run("abort keeps previous object", func(t *testing.T) {
+		obj, err := d.Create(ctx, "statements/a/2024-01.csv")
+		require.NoError(t, err)
+		_, err = io.WriteString(obj, "second")
+		require.NoError(t, err)
+		obj.Abort()
+
+		assert.Equal(t, "first", read("statements/a/2024-01.csv"))
+
+		entries, err := os.ReadDir(filepath.Join(root, "statements", "a"))
+		require.NoError(t, err)
+		assert.Len(t, entries, 1, "temporary file is removed")
+	})
+
+	t.Run("missing object", func(t *testing.T) {
+		_, err := d.Open(ctx, "statements/b/2024-01.csv")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("invalid keys", func(t *testing.T) {
+		for _, key := range []string{"", "/etc/passwd", "../outside", "a/../../outside", "a//b", "a\\b", "."} {
+			_, err := d.Create(ctx, key)
+			assert.ErrorIs(t, err, ErrInvalidKey, key)
+			_, err = d.Open(ctx, key)
+			assert.ErrorIs(t, err, ErrInvalidKey, key)
+		}
+	})
+}
@@ -0,0 +1,33 @@
+// Package storage - хранилище файлов (выписок и других артефактов) по ключу.
+//
+// Ключ - путь из сегментов через "/", например "statements/<id>/2024-01.html". Запись атомарна:
+// объект становится виден через Open только после успешного Close, а Abort отменяет запись.
+// Реализация на локальном диске - Disk; объектное хранилище подключается реализацией того же интерфейса.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var (
+	ErrNotFound   = errors.New("object not found")
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+type Storage interface {
+	// Create начинает запись объекта key. Существующий объект заменяется после Close.
+	Create(ctx context.Context, key string) (Object, error)
+	// Open открывает объект key на чтение или возвращает ErrNotFound.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// Object - записываемый объект. После записи нужно вызвать ровно один из методов: Close или Abort.
+type Object interface {
+	io.Writer
+	// Close завершает запись и публикует объект.
+	Close() error
+	// Abort отменяет запись; ранее сохранённый объект с тем же ключом не меняется.
+	Abort()
+}
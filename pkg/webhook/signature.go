@@ -0,0 +1,72 @@
+// Package webhook - подпись доставок вебхуков. Получатели могут использовать Verify для проверки запросов.
+//
+// Каждая доставка - POST с JSON-событием в теле и заголовками:
+//
+//	X-Wallet-Event:     тип события
+//	X-Wallet-Delivery:  id доставки (одинаковый при повторах - для дедупликации)
+//	X-Wallet-Signature: t=<unix time>,v1=<hex HMAC-SHA256(secret, "<t>.<тело>")>
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderEvent     = "X-Wallet-Event"
+	HeaderDelivery  = "X-Wallet-Delivery"
+	HeaderSignature = "X-Wallet-Signature"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrExpiredSignature = errors.New("webhook signature timestamp is outside the tolerance")
+)
+
+// Sign возвращает значение заголовка X-Wallet-Signature для тела body, отправленного в момент ts.
+func Sign(secret string, ts time.Time, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + t + ",v1=" + mac(secret, t, body)
+}
+
+// Verify проверяет заголовок X-Wallet-Signature. tolerance ограничивает возраст подписи
+// (защита от повторной отправки перехваченного запроса); 0 - без ограничения.
+func Verify(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var t, v1 string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			t = v
+		case "v1":
+			v1 = v
+		}
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || v1 == "" {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(v1), []byte(mac(secret, t, body))) {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 {
+		if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+			return ErrExpiredSignature
+		}
+	}
+	return nil
+}
+
+func mac(secret, t string, body []byte) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(t))
+	m.Write([]byte("."))
+	m.Write(body)
+	return hex.EncodeToString(m.Sum(nil))
+}
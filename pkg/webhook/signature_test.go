@@ -0,0 +1,40 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	body := []byte(`{"type":"transaction.deposited"}`)
+	header := Sign("secret", ts, body)
+
+	testTable := []struct {
+		name     string
+		secret   string
+		header   string
+		body     []byte
+		now      time.Time
+		expected error
+	}{
+		{name: "valid", secret: "secret", header: header, body: body, now: ts.Add(time.Minute)},
+		{name: "wrong secret", secret: "other", header: header, body: body, now: ts, expected: ErrInvalidSignature},
+		{name: "modified body", secret: "secret", header: header, body: []byte(`{}`), now: ts, expected: ErrInvalidSignature},
+		{name: "malformed header", secret: "secret", header: "v1=abc", body: body, now: ts, expected: ErrInvalidSignature},
+		{name: "too old", secret: "secret", header: header, body: body, now: ts.Add(time.Hour), expected: ErrExpiredSignature},
+	}
+
+	for _, test := range testTable {
+		t.Run(test.name, func(t *testing.T) {
+			err := Verify(test.secret, test.header, test.body, test.now, 5*time.Minute)
+			if test.expected == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,2 @@
+-- Версия кошелька для оптимистичной блокировки (ETag / If-Match): увеличивается на каждой операции
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
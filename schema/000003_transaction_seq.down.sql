@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_wallet_transactions_valletId_seq;
+
+ALTER TABLE IF EXISTS wallet_transactions DROP COLUMN IF EXISTS balance_after;
+ALTER TABLE IF EXISTS wallet_transactions DROP COLUMN IF EXISTS seq;
+
+ALTER TABLE IF EXISTS wallets DROP COLUMN IF EXISTS last_seq;
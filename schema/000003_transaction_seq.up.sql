@@ -0,0 +1,29 @@
+-- Порядковый номер транзакции внутри кошелька (без пропусков) и баланс после операции.
+-- Номер выдаётся под блокировкой строки кошелька, последний выданный хранится в wallets.last_seq.
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS last_seq BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE wallet_transactions ADD COLUMN IF NOT EXISTS seq BIGINT;
+ALTER TABLE wallet_transactions ADD COLUMN IF NOT EXISTS balance_after NUMERIC(18, 2);
+
+-- Существующая история: номера по порядку id, баланс восстанавливается от текущего назад.
+UPDATE wallet_transactions t
+SET seq = s.seq, balance_after = s.balance_after
+FROM (
+    SELECT wt.id,
+           row_number() OVER (PARTITION BY wt.valletId ORDER BY wt.id) AS seq,
+           w.balance - COALESCE(SUM(CASE wt.operation_type WHEN 'DEPOSIT' THEN wt.amount ELSE -wt.amount END)
+               OVER (PARTITION BY wt.valletId ORDER BY wt.id DESC ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING), 0) AS balance_after
+    FROM wallet_transactions wt
+    JOIN wallets w ON w.valletId = wt.valletId
+) s
+WHERE t.id = s.id AND t.seq IS NULL;
+
+UPDATE wallets w
+SET last_seq = s.last_seq
+FROM (SELECT valletId, MAX(seq) AS last_seq FROM wallet_transactions GROUP BY valletId) s
+WHERE w.valletId = s.valletId;
+
+ALTER TABLE wallet_transactions ALTER COLUMN seq SET NOT NULL;
+ALTER TABLE wallet_transactions ALTER COLUMN balance_after SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_wallet_transactions_valletId_seq ON wallet_transactions(valletId, seq);
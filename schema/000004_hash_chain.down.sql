@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS wallet_checkpoints;
+
+ALTER TABLE IF EXISTS wallet_transactions DROP COLUMN IF EXISTS hash;
+
+ALTER TABLE IF EXISTS wallets DROP COLUMN IF EXISTS last_hash;
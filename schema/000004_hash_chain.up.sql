@@ -0,0 +1,48 @@
+-- Цепочка хешей по истории кошелька: hash каждой транзакции = sha256(hash предыдущей + канонические поля),
+-- формула совпадает с wallet.ChainHash. Голова цепочки хранится в wallets.last_hash рядом с last_seq.
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS last_hash TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE wallet_transactions ADD COLUMN IF NOT EXISTS hash TEXT;
+
+-- Существующая история: строим цепочку по порядку seq.
+DO $$
+DECLARE
+    r    RECORD;
+    cur  UUID;
+    prev TEXT;
+BEGIN
+    FOR r IN SELECT id, valletId, seq, operation_type, amount, balance_after
+             FROM wallet_transactions ORDER BY valletId, seq
+    LOOP
+        IF cur IS DISTINCT FROM r.valletId THEN
+            cur := r.valletId;
+            prev := '';
+        END IF;
+
+        prev := encode(sha256(convert_to(concat_ws(E'\n',
+            prev, r.valletId::text, r.seq::text, r.operation_type, r.amount::text, r.balance_after::text), 'UTF8')), 'hex');
+        UPDATE wallet_transactions SET hash = prev WHERE id = r.id;
+    END LOOP;
+END $$;
+
+UPDATE wallets w
+SET last_hash = t.hash
+FROM wallet_transactions t
+WHERE t.valletId = w.valletId AND t.seq = w.last_seq;
+
+ALTER TABLE wallet_transactions ALTER COLUMN hash SET NOT NULL;
+
+-- Подписанные контрольные точки: периодически фиксируют голову цепочки каждого кошелька.
+-- Подпись (HMAC-SHA256 ключом вне БД) не даёт незаметно пересчитать всю цепочку после правки истории.
+CREATE TABLE IF NOT EXISTS wallet_checkpoints (
+    id BIGSERIAL PRIMARY KEY,
+    valletId UUID NOT NULL,
+    seq BIGINT NOT NULL,
+    hash TEXT NOT NULL,
+    signature TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_checkpoint_wallet
+    FOREIGN KEY(valletId) REFERENCES wallets(valletId) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_wallet_checkpoints_valletId_seq ON wallet_checkpoints(valletId, seq);
@@ -0,0 +1,18 @@
+-- Transactional outbox: события об операциях пишутся в одной транзакции с изменением баланса,
+-- relay публикует их в порядке id и отмечает published_at.
+-- id выдаются под блокировкой строки кошелька, поэтому внутри кошелька порядок id совпадает с порядком операций.
+CREATE TABLE IF NOT EXISTS wallet_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_id UUID NOT NULL UNIQUE,
+    valletId UUID NOT NULL,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_outbox_pending ON wallet_outbox(id) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_wallet_outbox_published ON wallet_outbox(published_at) WHERE published_at IS NOT NULL;
+
+-- Relay публикует событие как минимум один раз; повторная публикация не должна дублировать доставки вебхуков.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_event ON webhook_deliveries(subscription_id, event_id);
@@ -0,0 +1,15 @@
+-- Уведомление об изменении кошелька для потоков /wallets/:id/stream на всех экземплярах сервиса.
+-- Отправляется при фиксации транзакции; payload - "<valletId>:<last_seq>".
+CREATE OR REPLACE FUNCTION notify_wallet_change() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('wallet_changes', NEW.valletId::text || ':' || NEW.last_seq::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS wallet_changes ON wallets;
+CREATE TRIGGER wallet_changes
+    AFTER UPDATE OF last_seq ON wallets
+    FOR EACH ROW
+    WHEN (NEW.last_seq IS DISTINCT FROM OLD.last_seq)
+    EXECUTE FUNCTION notify_wallet_change();
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_wallet_transactions_idempotency;
+
+ALTER TABLE IF EXISTS wallet_transactions DROP COLUMN IF EXISTS idempotency_key;
@@ -0,0 +1,6 @@
+-- Ключ идемпотентности операции: повтор операции с тем же ключом для того же кошелька не применяется,
+-- а возвращает уже записанную транзакцию.
+ALTER TABLE wallet_transactions ADD COLUMN IF NOT EXISTS idempotency_key TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_wallet_transactions_idempotency
+    ON wallet_transactions(valletId, idempotency_key) WHERE idempotency_key IS NOT NULL;
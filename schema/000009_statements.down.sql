@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_wallet_transactions_created_at;
+DROP TABLE IF EXISTS wallet_statements;
@@ -0,0 +1,20 @@
+-- Месячные выписки кошельков: итоги периода [period, period + 1 месяц) и форматы сохранённых файлов.
+-- Сами файлы лежат в хранилище выписок (STATEMENTS_DIR), ключ - statements/<valletId>/<YYYY-MM>.<format>.
+CREATE TABLE IF NOT EXISTS wallet_statements (
+    valletId UUID NOT NULL,
+    period DATE NOT NULL, -- первое число месяца
+    opening_balance NUMERIC(18, 2) NOT NULL,
+    closing_balance NUMERIC(18, 2) NOT NULL,
+    deposits_count BIGINT NOT NULL,
+    deposits_sum NUMERIC(18, 2) NOT NULL,
+    withdrawals_count BIGINT NOT NULL,
+    withdrawals_sum NUMERIC(18, 2) NOT NULL,
+    formats TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (valletId, period),
+    CONSTRAINT fk_statement_wallet
+    FOREIGN KEY(valletId) REFERENCES wallets(valletId) ON DELETE CASCADE
+);
+
+-- Поиск кошельков с операциями за период.
+CREATE INDEX IF NOT EXISTS idx_wallet_transactions_created_at ON wallet_transactions(created_at);
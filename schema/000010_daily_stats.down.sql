@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS wallet_daily_stats ON wallet_transactions;
+DROP FUNCTION IF EXISTS add_wallet_daily_stats();
+DROP TABLE IF EXISTS wallet_daily_stats;
@@ -0,0 +1,56 @@
+-- Дневные итоги операций кошелька для статистики /wallets/:id/stats и /admin/stats.
+-- Поддерживаются триггером при каждой записи в wallet_transactions; день - по created_at (UTC).
+CREATE TABLE IF NOT EXISTS wallet_daily_stats (
+    valletId UUID NOT NULL,
+    day DATE NOT NULL,
+    deposits_count BIGINT NOT NULL DEFAULT 0,
+    deposits_sum NUMERIC(18, 2) NOT NULL DEFAULT 0,
+    withdrawals_count BIGINT NOT NULL DEFAULT 0,
+    withdrawals_sum NUMERIC(18, 2) NOT NULL DEFAULT 0,
+    PRIMARY KEY (valletId, day),
+    CONSTRAINT fk_daily_stats_wallet
+    FOREIGN KEY(valletId) REFERENCES wallets(valletId) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_daily_stats_day ON wallet_daily_stats(day);
+
+CREATE OR REPLACE FUNCTION add_wallet_daily_stats() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO wallet_daily_stats AS s (valletId, day, deposits_count, deposits_sum, withdrawals_count, withdrawals_sum)
+    VALUES (NEW.valletId, NEW.created_at::date,
+            CASE WHEN NEW.operation_type = 'DEPOSIT' THEN 1 ELSE 0 END,
+            CASE WHEN NEW.operation_type = 'DEPOSIT' THEN NEW.amount ELSE 0 END,
+            CASE WHEN NEW.operation_type = 'WITHDRAW' THEN 1 ELSE 0 END,
+            CASE WHEN NEW.operation_type = 'WITHDRAW' THEN NEW.amount ELSE 0 END)
+    ON CONFLICT (valletId, day) DO UPDATE SET
+        deposits_count = s.deposits_count + EXCLUDED.deposits_count,
+        deposits_sum = s.deposits_sum + EXCLUDED.deposits_sum,
+        withdrawals_count = s.withdrawals_count + EXCLUDED.withdrawals_count,
+        withdrawals_sum = s.withdrawals_sum + EXCLUDED.withdrawals_sum;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Блокировка не даёт новым транзакциям проскочить между созданием триггера и заполнением итогов по истории.
+-- LOCK TABLE работает только внутри транзакции, а initdb выполняет файл в режиме autocommit.
+BEGIN;
+
+LOCK TABLE wallet_transactions IN SHARE MODE;
+
+DROP TRIGGER IF EXISTS wallet_daily_stats ON wallet_transactions;
+CREATE TRIGGER wallet_daily_stats
+    AFTER INSERT ON wallet_transactions
+    FOR EACH ROW
+    EXECUTE FUNCTION add_wallet_daily_stats();
+
+INSERT INTO wallet_daily_stats (valletId, day, deposits_count, deposits_sum, withdrawals_count, withdrawals_sum)
+SELECT valletId, created_at::date,
+       COUNT(*) FILTER (WHERE operation_type = 'DEPOSIT'),
+       COALESCE(SUM(amount) FILTER (WHERE operation_type = 'DEPOSIT'), 0),
+       COUNT(*) FILTER (WHERE operation_type = 'WITHDRAW'),
+       COALESCE(SUM(amount) FILTER (WHERE operation_type = 'WITHDRAW'), 0)
+FROM wallet_transactions
+GROUP BY valletId, created_at::date
+ON CONFLICT (valletId, day) DO NOTHING;
+
+COMMIT;
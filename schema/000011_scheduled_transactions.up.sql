@@ -0,0 +1,21 @@
+-- Отложенные операции: выполняются обработчиком после execute_at.
+-- pending -> succeeded | failed (недостаточно средств) | cancelled.
+-- locked_until - аренда обработчика: пока она не истекла, операцию не возьмёт другой экземпляр сервиса и её нельзя отменить.
+CREATE TABLE IF NOT EXISTS scheduled_transactions (
+    id BIGSERIAL PRIMARY KEY,
+    valletId UUID NOT NULL,
+    operation_type VARCHAR(10) NOT NULL CHECK (operation_type IN ('DEPOSIT', 'WITHDRAW')),
+    amount NUMERIC(18, 2) NOT NULL CHECK (amount > 0),
+    execute_at TIMESTAMP NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed', 'cancelled')),
+    error TEXT NOT NULL DEFAULT '',
+    transaction_id INT,
+    locked_until TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP,
+    CONSTRAINT fk_scheduled_wallet
+    FOREIGN KEY(valletId) REFERENCES wallets(valletId) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_transactions_due ON scheduled_transactions(execute_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_scheduled_transactions_valletId ON scheduled_transactions(valletId, execute_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS recurring_transfer_runs;
+DROP TABLE IF EXISTS recurring_transfers;
@@ -0,0 +1,48 @@
+-- Регулярные переводы между кошельками по расписанию (cron или RRULE) и история их запусков.
+-- active -> paused -> active, active | paused -> cancelled, active -> finished (срабатываний больше нет).
+-- occurrence_at - текущий срок перевода, next_run_at - время следующей попытки (после нехватки средств
+-- откладывается на retry_interval_seconds), attempt - число неудачных попыток текущего срока.
+-- locked_until - аренда обработчика, как у scheduled_transactions.
+CREATE TABLE IF NOT EXISTS recurring_transfers (
+    id BIGSERIAL PRIMARY KEY,
+    from_valletId UUID NOT NULL,
+    to_valletId UUID NOT NULL,
+    amount NUMERIC(18, 2) NOT NULL CHECK (amount > 0),
+    schedule TEXT NOT NULL,
+    start_at TIMESTAMP NOT NULL,
+    end_at TIMESTAMP,
+    catch_up VARCHAR(10) NOT NULL CHECK (catch_up IN ('all', 'latest', 'skip')),
+    max_retries INT NOT NULL DEFAULT 0 CHECK (max_retries >= 0),
+    retry_interval_seconds BIGINT NOT NULL DEFAULT 0 CHECK (retry_interval_seconds >= 0),
+    status VARCHAR(10) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paused', 'finished', 'cancelled')),
+    occurrence_at TIMESTAMP,
+    next_run_at TIMESTAMP,
+    attempt INT NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (from_valletId <> to_valletId),
+    CONSTRAINT fk_recurring_from_wallet
+    FOREIGN KEY(from_valletId) REFERENCES wallets(valletId) ON DELETE CASCADE,
+    CONSTRAINT fk_recurring_to_wallet
+    FOREIGN KEY(to_valletId) REFERENCES wallets(valletId) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_recurring_transfers_due ON recurring_transfers(next_run_at) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_recurring_transfers_from ON recurring_transfers(from_valletId, id);
+
+CREATE TABLE IF NOT EXISTS recurring_transfer_runs (
+    id BIGSERIAL PRIMARY KEY,
+    rule_id BIGINT NOT NULL,
+    occurrence_at TIMESTAMP NOT NULL,
+    attempt INT NOT NULL,
+    status VARCHAR(10) NOT NULL CHECK (status IN ('succeeded', 'retrying', 'failed')),
+    error TEXT NOT NULL DEFAULT '',
+    from_transaction_id INT,
+    to_transaction_id INT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_recurring_run_rule
+    FOREIGN KEY(rule_id) REFERENCES recurring_transfers(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_recurring_transfer_runs_rule ON recurring_transfer_runs(rule_id, id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_wallet_transactions_operation;
+
+ALTER TABLE IF EXISTS wallet_transactions DROP COLUMN IF EXISTS operation_id;
+ALTER TABLE IF EXISTS wallet_transactions DROP COLUMN IF EXISTS kind;
+
+ALTER TABLE IF EXISTS wallets DROP COLUMN IF EXISTS tier;
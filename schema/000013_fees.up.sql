@@ -0,0 +1,14 @@
+-- Уровень кошелька: по нему выбирается расписание комиссий.
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS tier VARCHAR(32) NOT NULL DEFAULT 'standard';
+
+-- Комиссии за операции: kind = 'fee' у списания комиссии и её зачисления на кошелёк комиссий,
+-- operation_id связывает их с операцией, за которую взята комиссия.
+ALTER TABLE wallet_transactions ADD COLUMN IF NOT EXISTS kind VARCHAR(16);
+ALTER TABLE wallet_transactions ADD COLUMN IF NOT EXISTS operation_id UUID;
+
+CREATE INDEX IF NOT EXISTS idx_wallet_transactions_operation
+    ON wallet_transactions(operation_id) WHERE operation_id IS NOT NULL;
+
+-- Системный кошелёк комиссий (FEE_WALLET)
+INSERT INTO wallets (valletId, balance) VALUES ('00000000-0000-0000-0000-000000000fee', 0.00)
+    ON CONFLICT (valletId) DO NOTHING;
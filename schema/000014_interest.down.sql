@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS interest_days;
+DROP TABLE IF EXISTS interest_accruals;
+
+ALTER TABLE IF EXISTS wallets DROP COLUMN IF EXISTS interest_pending;
+ALTER TABLE IF EXISTS wallets DROP COLUMN IF EXISTS product;
@@ -0,0 +1,26 @@
+-- Проценты на остаток. product - процентный продукт кошелька (INTEREST_PRODUCTS_FILE), NULL - проценты не начисляются.
+-- interest_pending - начисленные, но ещё не капитализированные проценты; при капитализации на кошелёк зачисляются
+-- целые копейки, остаток переходит на следующий месяц.
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS product VARCHAR(32);
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS interest_pending NUMERIC(20, 8) NOT NULL DEFAULT 0;
+
+-- Дневные начисления: не больше одного на кошелёк за день, поэтому повторный расчёт дня ничего не добавляет.
+CREATE TABLE IF NOT EXISTS interest_accruals (
+    valletId UUID NOT NULL,
+    day DATE NOT NULL,
+    product VARCHAR(32) NOT NULL,
+    balance NUMERIC(18, 2) NOT NULL,
+    daily_rate NUMERIC(24, 20) NOT NULL,
+    amount NUMERIC(20, 8) NOT NULL,
+    PRIMARY KEY (valletId, day),
+    CONSTRAINT fk_interest_accrual_wallet
+    FOREIGN KEY(valletId) REFERENCES wallets(valletId) ON DELETE CASCADE
+);
+
+-- Дни, за которые начисление выполнено для всех кошельков. capitalised_at у последнего дня месяца -
+-- проценты за этот месяц капитализированы.
+CREATE TABLE IF NOT EXISTS interest_days (
+    day DATE PRIMARY KEY,
+    accrued_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    capitalised_at TIMESTAMP
+);
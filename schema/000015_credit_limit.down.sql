@@ -0,0 +1,5 @@
+-- Откат не пройдёт, пока есть кошельки в овердрафте: их нужно погасить до отката.
+ALTER TABLE IF EXISTS wallets DROP CONSTRAINT IF EXISTS wallets_balance_check;
+ALTER TABLE IF EXISTS wallets ADD CONSTRAINT wallets_balance_check CHECK (balance >= 0);
+
+ALTER TABLE IF EXISTS wallets DROP COLUMN IF EXISTS credit_limit;
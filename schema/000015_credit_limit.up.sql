@@ -0,0 +1,8 @@
+-- Кредитный лимит (овердрафт): баланс кошелька может опускаться до -credit_limit.
+-- Лимит проверяется в репозитории при применении операций; CHECK - последняя линия защиты.
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS credit_limit NUMERIC(18, 2) NOT NULL DEFAULT 0;
+ALTER TABLE wallets DROP CONSTRAINT IF EXISTS wallets_credit_limit_check;
+ALTER TABLE wallets ADD CONSTRAINT wallets_credit_limit_check CHECK (credit_limit >= 0);
+
+ALTER TABLE wallets DROP CONSTRAINT IF EXISTS wallets_balance_check;
+ALTER TABLE wallets ADD CONSTRAINT wallets_balance_check CHECK (balance >= -credit_limit);
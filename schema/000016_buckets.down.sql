@@ -0,0 +1,7 @@
+-- Остатки корзин уже входят в balance, поэтому после отката они становятся обычными деньгами кошелька.
+DROP TABLE IF EXISTS wallet_bucket_entries;
+
+ALTER TABLE IF EXISTS wallets DROP CONSTRAINT IF EXISTS wallets_buckets_check;
+ALTER TABLE IF EXISTS wallets DROP COLUMN IF EXISTS promo_balance;
+ALTER TABLE IF EXISTS wallets DROP COLUMN IF EXISTS cashback_balance;
+ALTER TABLE IF EXISTS wallets DROP COLUMN IF EXISTS bonus_balance;
@@ -0,0 +1,31 @@
+-- Корзины кошелька. Остатки bonus, cashback и promo хранятся отдельно, main - остаток баланса за их вычетом,
+-- поэтому сумма корзин всегда равна balance, а существующие деньги оказываются в main.
+-- В минус (до -credit_limit) может уходить только main.
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS bonus_balance NUMERIC(18, 2) NOT NULL DEFAULT 0;
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS cashback_balance NUMERIC(18, 2) NOT NULL DEFAULT 0;
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS promo_balance NUMERIC(18, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE wallets DROP CONSTRAINT IF EXISTS wallets_buckets_check;
+ALTER TABLE wallets ADD CONSTRAINT wallets_buckets_check CHECK (bonus_balance >= 0 AND cashback_balance >= 0 AND promo_balance >= 0
+    AND balance - bonus_balance - cashback_balance - promo_balance >= -credit_limit);
+
+-- Движение по корзинам: часть транзакции, пришедшаяся на корзину, и остаток корзины после неё.
+-- seq повторяет wallet_transactions.seq, чтобы историю корзины можно было листать без соединения.
+CREATE TABLE IF NOT EXISTS wallet_bucket_entries (
+    transaction_id INTEGER NOT NULL,
+    valletId UUID NOT NULL,
+    bucket VARCHAR(16) NOT NULL CHECK (bucket IN ('main', 'bonus', 'cashback', 'promo')),
+    seq BIGINT NOT NULL,
+    amount NUMERIC(18, 2) NOT NULL,
+    balance_after NUMERIC(18, 2) NOT NULL,
+    PRIMARY KEY (transaction_id, bucket),
+    CONSTRAINT fk_bucket_entry_transaction
+    FOREIGN KEY(transaction_id) REFERENCES wallet_transactions(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_bucket_entries_bucket ON wallet_bucket_entries(valletId, bucket, seq);
+
+-- До появления корзин все деньги были в main.
+INSERT INTO wallet_bucket_entries (transaction_id, valletId, bucket, seq, amount, balance_after)
+SELECT id, valletId, 'main', seq, amount, balance_after FROM wallet_transactions
+ON CONFLICT DO NOTHING;
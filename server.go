@@ -2,8 +2,12 @@ package wallet
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
 )
 
 type Server struct {
@@ -11,16 +15,59 @@ type Server struct {
 }
 
 func (s *Server) Run(port string, handler http.Handler) error {
+	// Контекст запросов отменяется при Shutdown, чтобы долгие запросы (потоки SSE и WebSocket) завершились,
+	// а не держали остановку сервера.
+	base, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	s.httpServer = &http.Server{
 		Addr:           ":" + port,
 		Handler:        handler,
 		MaxHeaderBytes: 1 << 20, // Ограничение на размер заголовка: 1 Мб
 		ReadTimeout:    10 * time.Second,
 		WriteTimeout:   10 * time.Second,
+		BaseContext:    func(net.Listener) context.Context { return base },
 	}
+	s.httpServer.RegisterOnShutdown(cancel)
 	return s.httpServer.ListenAndServe()
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
 	return s.httpServer.Shutdown(ctx)
 }
+
+// GRPCServer - gRPC-сервер, работающий рядом с HTTP-сервером на своём порту.
+type GRPCServer struct {
+	grpcServer *grpc.Server
+}
+
+// Run регистрирует сервисы через register и обслуживает вызовы, пока сервер не остановлен.
+func (s *GRPCServer) Run(port string, register func(*grpc.Server)) error {
+	lis, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return err
+	}
+
+	s.grpcServer = grpc.NewServer()
+	register(s.grpcServer)
+	reflection.Register(s.grpcServer) // для grpcurl и подобных клиентов
+
+	return s.grpcServer.Serve(lis)
+}
+
+// Shutdown дожидается завершения текущих вызовов, а если ctx истёк раньше - обрывает их.
+func (s *GRPCServer) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+		return ctx.Err()
+	}
+}
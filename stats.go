@@ -0,0 +1,69 @@
+package wallet
+
+import "time"
+
+// Интервалы статистики операций. Границы интервалов - в UTC, неделя начинается с понедельника (как date_trunc в Postgres).
+const (
+	StatsDay   = "day"
+	StatsWeek  = "week"
+	StatsMonth = "month"
+)
+
+// Stats - число и сумма операций каждого типа за период [From, To) по интервалам Interval.
+type Stats struct {
+	From        time.Time      `json:"from"`
+	To          time.Time      `json:"to"`
+	Interval    string         `json:"interval"`
+	Deposits    StatementTotal `json:"deposits"`
+	Withdrawals StatementTotal `json:"withdrawals"`
+	// Buckets - интервалы по порядку, включая интервалы без операций. Первый интервал может начинаться раньше From.
+	Buckets []StatsBucket `json:"buckets"`
+}
+
+// StatsBucket - итоги операций за интервал, начинающийся со Start.
+type StatsBucket struct {
+	Start       time.Time      `json:"start"`
+	Deposits    StatementTotal `json:"deposits"`
+	Withdrawals StatementTotal `json:"withdrawals"`
+}
+
+// Add добавляет операцию WT к итогам интервала.
+func (b *StatsBucket) Add(WT WalletTransactions) {
+	total := &b.Deposits
+	if WT.OperationType == "WITHDRAW" {
+		total = &b.Withdrawals
+	}
+	total.Count++
+	total.Sum += WT.Amount
+}
+
+// ValidStatsInterval сообщает, поддерживается ли интервал статистики.
+func ValidStatsInterval(interval string) bool {
+	return interval == StatsDay || interval == StatsWeek || interval == StatsMonth
+}
+
+// StatsBucketStart возвращает начало интервала, в который попадает t.
+func StatsBucketStart(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case StatsWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case StatsMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// NextStatsBucket возвращает начало интервала, следующего за интервалом, начинающимся со start.
+func NextStatsBucket(start time.Time, interval string) time.Time {
+	switch interval {
+	case StatsWeek:
+		return start.AddDate(0, 0, 7)
+	case StatsMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
@@ -1,6 +1,8 @@
 package wallet
 
 import (
+	"time"
+
 	uuid "github.com/jackc/pgtype/ext/gofrs-uuid"
 )
 
@@ -8,6 +10,64 @@ type Wallet struct {
 	//Id       int       `json:"id"`
 	ValletId uuid.UUID `json:"valletId"`
 	Balance  float64   `json:"balance"`
+	Version  int64     `json:"version"`
+	// LastSeq и LastHash - номер и хеш последней транзакции (голова цепочки хешей).
+	LastSeq  int64  `json:"lastSeq"`
+	LastHash string `json:"lastHash"`
+	// Tier - уровень кошелька, по которому выбирается расписание комиссий.
+	Tier string `json:"tier"`
+	// Product - процентный продукт кошелька; пустой - проценты не начисляются.
+	Product string `json:"product,omitempty"`
+	// AccruedInterest - начисленные, но ещё не капитализированные проценты (с точностью до 1e-8).
+	AccruedInterest float64 `json:"accruedInterest,omitempty"`
+	// CreditLimit - одобренный кредитный лимит: баланс может опускаться до -CreditLimit (овердрафт).
+	CreditLimit float64 `json:"creditLimit"`
+	// Available - сколько можно списать без явной корзины: остатки корзин WithdrawOrder + CreditLimit.
+	Available float64 `json:"available"`
+	// Buckets - остатки корзин кошелька; их сумма равна Balance.
+	Buckets map[string]float64 `json:"buckets,omitempty"`
+}
+
+// DefaultTier - уровень новых кошельков.
+const DefaultTier = "standard"
+
+// Виды транзакций (WalletTransactions.Kind), записываемых сервисом.
+const (
+	KindFee      = "fee"      // комиссия за операцию
+	KindInterest = "interest" // капитализация процентов на остаток
+)
+
+// Корзины кошелька (WalletTransactions.Bucket). Деньги, не попавшие в другие корзины, лежат в main;
+// уходить в минус (в пределах кредитного лимита) может только main.
+const (
+	BucketMain     = "main"
+	BucketBonus    = "bonus"
+	BucketCashback = "cashback"
+	BucketPromo    = "promo"
+)
+
+// Buckets - все корзины кошелька в порядке вывода.
+var Buckets = []string{BucketMain, BucketBonus, BucketCashback, BucketPromo}
+
+// WithdrawOrder - из каких корзин и в каком порядке списывает WITHDRAW без явной корзины: сначала основные
+// деньги, затем кешбэк и бонусы. Промо-корзина списывается только явно (Bucket = promo).
+var WithdrawOrder = []string{BucketMain, BucketCashback, BucketBonus}
+
+// BucketEntry - часть транзакции, пришедшаяся на корзину Bucket, и остаток корзины после неё.
+type BucketEntry struct {
+	Bucket       string  `json:"bucket"`
+	Amount       float64 `json:"amount"`
+	BalanceAfter float64 `json:"balanceAfter"`
+}
+
+// InterestAccrual - начисление процентов на остаток кошелька за день Day: Amount = Balance * DailyRate.
+type InterestAccrual struct {
+	ValletId  uuid.UUID `json:"valletId"`
+	Day       time.Time `json:"day"`
+	Product   string    `json:"product"`
+	Balance   float64   `json:"balance"`
+	DailyRate float64   `json:"dailyRate"`
+	Amount    float64   `json:"amount"`
 }
 
 type WalletTransactions struct {
@@ -15,4 +75,229 @@ type WalletTransactions struct {
 	ValletId      uuid.UUID `json:"valletId" binding:"required"`
 	OperationType string    `json:"operationType" binding:"required,oneof=DEPOSIT WITHDRAW"`
 	Amount        float64   `json:"amount" binding:"required"`
+	// Bucket - корзина операции: DEPOSIT зачисляется в неё, WITHDRAW списывается только из неё.
+	// Без корзины DEPOSIT зачисляется в main, а WITHDRAW списывается по порядку WithdrawOrder.
+	Bucket string `json:"bucket,omitempty" binding:"omitempty,oneof=main bonus cashback promo"`
+	// ExpectedVersion - версия кошелька из If-Match; 0 - без проверки.
+	ExpectedVersion int64 `json:"-"`
+	// IdempotencyKey - необязательный ключ идемпотентности: повтор операции с тем же ключом для того же кошелька
+	// не применяется, а возвращает уже записанную транзакцию.
+	IdempotencyKey string `json:"idempotencyKey,omitempty" binding:"max=128"`
+	// Version - версия кошелька после применения операции.
+	Version int64 `json:"-"`
+	// Seq - порядковый номер транзакции внутри кошелька: 1, 2, 3... без пропусков.
+	Seq          int64   `json:"seq"`
+	BalanceAfter float64 `json:"balanceAfter"`
+	// Hash - звено цепочки хешей истории кошелька, см. ChainHash.
+	Hash string `json:"hash"`
+	// Buckets - как применённая операция разложилась по корзинам, в порядке Buckets.
+	Buckets []BucketEntry `json:"buckets,omitempty"`
+	// Kind - вид транзакции, заполняется сервисом: пусто - операция клиента, KindFee - комиссия, KindInterest - проценты.
+	Kind string `json:"kind,omitempty"`
+	// OperationId связывает транзакции одной операции с комиссией: саму операцию, списание комиссии
+	// и её зачисление на кошелёк комиссий. Заполняется сервисом.
+	OperationId *uuid.UUID `json:"operationId,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+// Transfer - перевод между кошельками: списание (From) и зачисление (To), записанные в одной транзакции БД.
+// Fee - списание комиссии за перевод с кошелька From, если она взята.
+type Transfer struct {
+	From WalletTransactions  `json:"from"`
+	To   WalletTransactions  `json:"to"`
+	Fee  *WalletTransactions `json:"fee,omitempty"`
+}
+
+// Статусы операций пакета (POST /wallets/batch).
+const (
+	BatchApplied   = "applied"
+	BatchDuplicate = "duplicate" // повтор по ключу идемпотентности, transaction - ранее записанная
+	BatchRejected  = "rejected"
+	BatchAborted   = "aborted" // операция допустима, но пакет "всё или ничего" не применён из-за других операций
+)
+
+// BatchResult - результат одной операции пакета; Index - её позиция в запросе.
+type BatchResult struct {
+	Index       int                 `json:"index"`
+	Status      string              `json:"status"`
+	Transaction *WalletTransactions `json:"transaction,omitempty"`
+	Error       string              `json:"error,omitempty"`
+}
+
+// Статусы строк массовой корректировки из CSV, кроме статусов пакета (applied, duplicate, rejected).
+const (
+	ImportOK             = "ok" // проверка пройдена, строка будет применена
+	ImportInvalid        = "invalid"
+	ImportUnknownWallet  = "unknown_wallet"
+	ImportWouldOverdraft = "would_overdraft"
+)
+
+// ImportRow - строка CSV массовой корректировки в том виде, как она записана в файле; Line - номер строки файла.
+// Reference - внешний номер корректировки, по нему повторная загрузка того же файла не применяется дважды.
+type ImportRow struct {
+	Line          int    `json:"line"`
+	WalletID      string `json:"walletId"`
+	OperationType string `json:"operationType"`
+	Amount        string `json:"amount"`
+	Reference     string `json:"reference"`
+}
+
+// ImportResult - результат проверки или применения строки; Transaction - записанная (или ранее записанная) транзакция.
+type ImportResult struct {
+	ImportRow
+	Status      string              `json:"status"`
+	Error       string              `json:"error,omitempty"`
+	Transaction *WalletTransactions `json:"transaction,omitempty"`
+}
+
+// ImportReport - отчёт о проверке (DryRun) или применении файла корректировок; Counts - число строк по статусам.
+type ImportReport struct {
+	DryRun  bool           `json:"dryRun"`
+	Rows    int            `json:"rows"`
+	Counts  map[string]int `json:"counts"`
+	Results []ImportResult `json:"results"`
+}
+
+// Statement - месячная выписка кошелька за период [From, To): балансы на начало и конец, итоги по типам операций
+// и форматы сохранённых файлов выписки.
+type Statement struct {
+	ValletId       uuid.UUID      `json:"valletId"`
+	Period         string         `json:"period"` // YYYY-MM
+	From           time.Time      `json:"from"`
+	To             time.Time      `json:"to"`
+	OpeningBalance float64        `json:"openingBalance"`
+	ClosingBalance float64        `json:"closingBalance"`
+	Deposits       StatementTotal `json:"deposits"`
+	Withdrawals    StatementTotal `json:"withdrawals"`
+	Formats        []string       `json:"formats"`
+	CreatedAt      time.Time      `json:"createdAt"`
+}
+
+// StatementTotal - число и сумма операций одного типа за период.
+type StatementTotal struct {
+	Count int64   `json:"count"`
+	Sum   float64 `json:"sum"`
+}
+
+// Статусы отложенной операции.
+const (
+	ScheduledPending   = "pending"
+	ScheduledSucceeded = "succeeded"
+	ScheduledFailed    = "failed" // недостаточно средств или кошелёк удалён
+	ScheduledCancelled = "cancelled"
+)
+
+// ScheduledTransaction - операция, которая будет выполнена в ExecuteAt или вскоре после.
+type ScheduledTransaction struct {
+	Id            int64     `json:"id"`
+	ValletId      uuid.UUID `json:"valletId"`
+	OperationType string    `json:"operationType"`
+	Amount        float64   `json:"amount"`
+	ExecuteAt     time.Time `json:"executeAt"`
+	Status        string    `json:"status"`
+	Error         string    `json:"error,omitempty"`
+	// TransactionId - ID записанной транзакции у выполненной операции.
+	TransactionId *int       `json:"transactionId,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	FinishedAt    *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Статусы правила регулярного перевода.
+const (
+	RecurringActive    = "active"
+	RecurringPaused    = "paused"
+	RecurringFinished  = "finished" // срабатываний больше нет (истёк EndAt или UNTIL)
+	RecurringCancelled = "cancelled"
+)
+
+// Политики пропущенных срабатываний: что делать со сроками, прошедшими, пока сервис не работал.
+const (
+	CatchUpAll    = "all"    // выполнить каждое пропущенное срабатывание по порядку
+	CatchUpLatest = "latest" // выполнить только последнее из пропущенных
+	CatchUpSkip   = "skip"   // пропустить все и ждать следующего срока
+)
+
+// Статусы запуска регулярного перевода.
+const (
+	RecurringRunSucceeded = "succeeded"
+	RecurringRunRetrying  = "retrying" // недостаточно средств, будет повторная попытка
+	RecurringRunFailed    = "failed"
+)
+
+// RecurringTransfer - правило регулярного перевода (постоянное поручение, подписка): Amount с кошелька
+// FromWalletId на ToWalletId по расписанию Schedule (cron или RRULE, в UTC) начиная с StartAt.
+// OccurrenceAt - текущий срок перевода, NextRunAt - когда будет следующая попытка: совпадает с OccurrenceAt
+// или отложена на RetryIntervalSeconds после нехватки средств; Attempt - число неудачных попыток текущего срока.
+type RecurringTransfer struct {
+	Id                   int64      `json:"id"`
+	FromWalletId         uuid.UUID  `json:"fromWalletId"`
+	ToWalletId           uuid.UUID  `json:"toWalletId"`
+	Amount               float64    `json:"amount"`
+	Schedule             string     `json:"schedule"`
+	StartAt              time.Time  `json:"startAt"`
+	EndAt                *time.Time `json:"endAt,omitempty"`
+	CatchUp              string     `json:"catchUp"`
+	MaxRetries           int        `json:"maxRetries"`
+	RetryIntervalSeconds int64      `json:"retryIntervalSeconds"`
+	Status               string     `json:"status"`
+	OccurrenceAt         *time.Time `json:"occurrenceAt,omitempty"`
+	NextRunAt            *time.Time `json:"nextRunAt,omitempty"`
+	Attempt              int        `json:"attempt"`
+	// LockedUntil - аренда обработчика, забравшего правило; по ней проверяется, что результат пишет он же.
+	LockedUntil *time.Time `json:"-"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// RecurringRun - попытка перевода по правилу RuleId за срок OccurrenceAt; у выполненной - ID обеих транзакций.
+type RecurringRun struct {
+	Id                int64     `json:"id"`
+	RuleId            int64     `json:"ruleId"`
+	OccurrenceAt      time.Time `json:"occurrenceAt"`
+	Attempt           int       `json:"attempt"`
+	Status            string    `json:"status"`
+	Error             string    `json:"error,omitempty"`
+	FromTransactionId *int      `json:"fromTransactionId,omitempty"`
+	ToTransactionId   *int      `json:"toTransactionId,omitempty"`
+	CreatedAt         time.Time `json:"createdAt"`
+}
+
+// Checkpoint - подписанная контрольная точка: голова цепочки хешей кошелька на момент seq.
+type Checkpoint struct {
+	Id        int64     `json:"id"`
+	ValletId  uuid.UUID `json:"valletId"`
+	Seq       int64     `json:"seq"`
+	Hash      string    `json:"hash"`
+	Signature string    `json:"signature"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ChainReport - результат проверки цепочки хешей кошелька.
+type ChainReport struct {
+	ValletId uuid.UUID `json:"valletId"`
+	Valid    bool      `json:"valid"`
+	// Checked - число проверенных транзакций, Head - хеш последней из них.
+	Checked     int64  `json:"checked"`
+	Head        string `json:"head"`
+	Checkpoints int    `json:"checkpoints"`
+	// BrokenSeq - seq первого нарушенного звена, Reason - что именно не сошлось.
+	BrokenSeq int64  `json:"brokenSeq,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Типы сообщений потока изменений кошелька.
+const (
+	UpdateSnapshot    = "snapshot"
+	UpdateTransaction = "transaction"
+)
+
+// BalanceUpdate - сообщение потока изменений кошелька: баланс после транзакции и сама транзакция.
+// Первое сообщение нового потока - снимок (snapshot) текущего баланса без транзакции.
+// Seq - номер последней учтённой транзакции; с него поток можно возобновить после переподключения.
+type BalanceUpdate struct {
+	Type        string              `json:"type"`
+	ValletId    uuid.UUID           `json:"valletId"`
+	Seq         int64               `json:"seq"`
+	Balance     float64             `json:"balance"`
+	Transaction *WalletTransactions `json:"transaction,omitempty"`
 }